@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/loadtest"
+)
+
+// runLoadtest runs `mcp-manager loadtest`: it creates synthetic instances,
+// drives proxy traffic and prints a report. It returns the exit code.
+func runLoadtest(args []string) int {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	backendType := flags.String("backend", string(backends.BackendTypeFake), "backend to create instances on: fake or docker")
+	instances := flags.Int("instances", 50, "synthetic instances to create")
+	concurrency := flags.Int("concurrency", 10, "parallel creates and proxy clients")
+	image := flags.String("image", "busybox:latest", "image of the instances (docker)")
+	command := flags.String("command", "httpd -f -p 8000", "command of the instances, split on spaces (docker)")
+	port := flags.Int("port", 8000, "container port of the instances")
+	requests := flags.Int("requests", 20, "proxy requests per instance")
+	healthRounds := flags.Int("health-rounds", 3, "health check passes over all instances")
+	readyTimeout := flags.Duration("ready-timeout", 0, "how long an instance may take to run (default 2m)")
+	keep := flags.Bool("keep", false, "leave the instances running after the run")
+	jsonOutput := flags.Bool("json", false, "print the report as JSON")
+	logLevel := flags.String("log-level", "WARN", "log level of the manager components")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg := config.Load()
+	// Logs go to stderr so the report on stdout stays parseable
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: getLogLevel(*logLevel)}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var backend backends.Backend
+	switch backends.BackendType(*backendType) {
+	case backends.BackendTypeFake:
+		backend = backends.NewFakeBackend(cfg.Fake, component(logger, "backend"))
+	case backends.BackendTypeDocker:
+		backend = backends.NewDockerBackend(cfg, component(logger, "backend"))
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported backend %q, use fake or docker\n", *backendType)
+		return 2
+	}
+	if err := backend.Initialize(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize %s backend: %v\n", *backendType, err)
+		return 1
+	}
+	defer backend.Shutdown(context.Background())
+
+	runner := loadtest.NewRunner(backend, component(logger, "loadtest"))
+	report, err := runner.Run(ctx, loadtest.Options{
+		Instances:    *instances,
+		Concurrency:  *concurrency,
+		Image:        *image,
+		Command:      strings.Fields(*command),
+		Port:         *port,
+		ReadyTimeout: *readyTimeout,
+		Requests:     *requests,
+		HealthRounds: *healthRounds,
+		Keep:         *keep,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load test failed: %v\n", err)
+		return 1
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(report)
+	} else {
+		printReport(os.Stdout, *backendType, report)
+	}
+
+	if report.Ready == 0 {
+		return 1
+	}
+	return 0
+}
+
+// printReport writes a report for people
+func printReport(w io.Writer, backendType string, r *loadtest.Report) {
+	latency := func(name string, p loadtest.Percentiles) {
+		fmt.Fprintf(w, "%-16s n=%-6d p50=%.2fms p90=%.2fms p99=%.2fms max=%.2fms\n", name, p.Count, p.P50, p.P90, p.P99, p.Max)
+	}
+
+	fmt.Fprintf(w, "Load test %s on %s backend in %.1fs\n", r.RunID, backendType, r.Duration)
+	fmt.Fprintf(w, "Instances        requested=%d created=%d ready=%d failed=%d\n", r.Instances, r.Created, r.Ready, r.Failed)
+	latency("Create", r.CreateLatency)
+	latency("Ready", r.ReadyLatency)
+	latency("Proxy", r.ProxyLatency)
+	fmt.Fprintf(w, "Proxy            %.0f req/s, %d errors\n", r.ProxyThroughput, r.ProxyErrors)
+	latency("Health round", r.HealthRound)
+	fmt.Fprintf(w, "Health check     %.3fms per instance\n", r.HealthPerCheck)
+	fmt.Fprintf(w, "Heap             %d -> %d bytes (%+d, %+d per instance)\n",
+		r.Memory.HeapBefore, r.Memory.HeapAfter, r.Memory.HeapGrowth, r.Memory.HeapPerInstance)
+	fmt.Fprintf(w, "Goroutines       %d -> %d (%+d)\n", r.Memory.GoroutinesBefore, r.Memory.GoroutinesAfter, r.Memory.GoroutineGrowth)
+	for _, err := range r.Errors {
+		fmt.Fprintf(w, "Error            %s\n", err)
+	}
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,31 +19,95 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/agentarea/mcp-manager/internal/api"
+	"github.com/agentarea/mcp-manager/internal/auth"
 	"github.com/agentarea/mcp-manager/internal/backends"
 	"github.com/agentarea/mcp-manager/internal/config"
 	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/egress"
 	"github.com/agentarea/mcp-manager/internal/environment"
 	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/federation"
+	"github.com/agentarea/mcp-manager/internal/fernet"
+	"github.com/agentarea/mcp-manager/internal/handoff"
+	"github.com/agentarea/mcp-manager/internal/logging"
+	"github.com/agentarea/mcp-manager/internal/metrics"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/notify"
+	"github.com/agentarea/mcp-manager/internal/oauth"
+	"github.com/agentarea/mcp-manager/internal/operations"
+	"github.com/agentarea/mcp-manager/internal/outbound"
 	"github.com/agentarea/mcp-manager/internal/providers"
 	"github.com/agentarea/mcp-manager/internal/proxy"
+	"github.com/agentarea/mcp-manager/internal/redisconn"
+	"github.com/agentarea/mcp-manager/internal/registry"
 	"github.com/agentarea/mcp-manager/internal/secrets"
+	"github.com/agentarea/mcp-manager/internal/store"
+	"github.com/agentarea/mcp-manager/internal/supervisor"
 	"github.com/agentarea/mcp-manager/internal/templates"
+	"github.com/agentarea/mcp-manager/internal/variables"
 )
 
 const version = "0.1.0"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		os.Exit(runLoadtest(os.Args[2:]))
+	}
+
+	// Runs after every other deferred cleanup
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	// Load configuration
 	cfg := config.Load()
 
 	// Setup logging
-	logger := setupLogging(cfg)
+	logger, logControl := setupLogging(cfg)
+
+	// The corporate proxy and CA bundle must be in place before the first
+	// outbound connection
+	outboundSettings, err := outbound.Configure(cfg.Outbound)
+	if err != nil {
+		logger.Error("Invalid outbound proxy configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if outboundSettings.Active() {
+		logger.Info("Outbound traffic uses the corporate proxy settings",
+			slog.Bool("proxy", cfg.Outbound.HTTPProxy != "" || cfg.Outbound.HTTPSProxy != ""),
+			slog.String("ca_bundle", cfg.Outbound.CABundle),
+			slog.Bool("inject", cfg.Outbound.Inject))
+	}
+
+	// Cost label aliases are added by both backends; reject mistakes up front
+	if _, err := container.ParseCostLabelAliases(cfg.Container.CostLabelAliases); err != nil {
+		logger.Error("Invalid COST_LABEL_ALIASES", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	if cfg.Traefik.IPFamily != container.IPv4 && cfg.Traefik.IPFamily != container.IPv6 {
+		logger.Error("Invalid NETWORK_IP_FAMILY: use ipv4 or ipv6", slog.String("value", cfg.Traefik.IPFamily))
+		os.Exit(1)
+	}
+
+	// A CA file that cannot be read would otherwise fail every health check
+	if _, err := container.NewHealthClient(cfg); err != nil {
+		logger.Error("Invalid health check client configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	// Initialize template loader
-	templateLoader := templates.NewLoader(cfg.MCPProvidersPath)
-	if err := templateLoader.Load(); err != nil {
+	templateLoader := templates.NewLoader(cfg.MCPProvidersPaths...)
+	_, err = templateLoader.Load()
+	for _, d := range templateLoader.Diagnostics().Diagnostics {
+		logger.Warn("MCP templates "+d.Severity, slog.String("diagnostic", d.String()))
+	}
+	if err != nil {
 		logger.Warn("Failed to load MCP templates",
-			slog.String("path", cfg.MCPProvidersPath),
+			slog.String("paths", strings.Join(cfg.MCPProvidersPaths, ",")),
 			slog.String("error", err.Error()))
 	} else {
 		logger.Info("Loaded MCP templates",
@@ -49,9 +118,87 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Background components are restarted when they fail; a critical one
+	// that keeps failing shuts the process down
+	sup := supervisor.New(ctx, component(logger, "supervisor"))
+
+	// Metadata store shared by features that persist state
+	metadataStore, err := store.Open(ctx, cfg.Store.Driver, cfg.Store.DSN)
+	if err != nil {
+		logger.Error("Failed to open metadata store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer metadataStore.Close()
+	logger.Info("Metadata store opened", slog.String("driver", cfg.Store.Driver))
+
+	// Environment values and credentials in stored specs are encrypted at rest
+	var encryptedStore *store.Encrypted
+	if cfg.Store.EncryptionKeys != "" {
+		keys, err := fernet.ParseKeyring(cfg.Store.EncryptionKeys)
+		if err != nil {
+			logger.Error("Invalid store encryption keys", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		encryptedStore = store.NewEncrypted(metadataStore, keys, logger)
+		metadataStore = encryptedStore
+		logger.Info("Metadata store encryption enabled", slog.Int("keys", keys.Len()))
+	} else if cfg.Store.Driver != store.DriverMemory {
+		logger.Warn("STORE_ENCRYPTION_KEYS not set, sensitive spec values are stored unencrypted")
+	}
+
+	// Lifecycle steps of instances are kept in the audit log for
+	// GET /instances/:id/timeline, from before the backend starts
+	timeline := events.NewTimelineRecorder(metadataStore.Audit(), component(logger, "timeline"))
+	events.SetTimelineHandler(timeline.HandleStep)
+	sup.Go("timeline", supervisor.Policy{}, func(ctx context.Context) error {
+		timeline.Run(ctx)
+		return nil
+	})
+
+	// Redis connection settings (TLS, ACL auth, Sentinel, cluster) are checked
+	// up front; each component opens its own client
+	redisOptions, err := redisconn.Parse(cfg.Redis)
+	if err != nil {
+		logger.Error("Invalid Redis configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Published events are signed so consumers can reject spoofed ones
+	if cfg.EventSigning.Algorithm != "" {
+		signer, err := events.NewSigner(cfg.EventSigning.Algorithm, cfg.EventSigning.KeyID, cfg.EventSigning.Key)
+		if err != nil {
+			logger.Error("Invalid event signing configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		events.SetSigner(signer)
+		logger.Info("Event signing enabled",
+			slog.String("algorithm", signer.Algorithm()),
+			slog.String("key_id", signer.KeyID()),
+			slog.String("public_key", signer.PublicKey()))
+	}
+
+	// Instances with an egress allow-list resolve names through the filtering
+	// resolver
+	var egressFilter *egress.Filter
+	if cfg.Egress.Enabled {
+		egressFilter, err = egress.NewFilter(cfg.Egress, component(logger, "egress"))
+		if err != nil {
+			logger.Error("Invalid egress DNS filter configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		sup.Go("egress-dns", supervisor.Policy{}, egressFilter.ListenAndServe)
+	}
+
 	// Detect environment and initialize appropriate backend
 	var backend backends.Backend
 	var containerManager *container.Manager
+	var fakeBackend *backends.FakeBackend
+	var handoffClient *handoff.Client
+	var handoffState *handoff.State
+
+	// Listeners of a manager that can be replaced are shared with its
+	// replacement until it exits
+	reusePort := cfg.Handoff.Token != ""
 
 	if cfg.Environment != "" {
 		logger.Info("Using forced environment", slog.String("environment", cfg.Environment))
@@ -63,12 +210,16 @@ func main() {
 	switch envType {
 	case "kubernetes":
 		logger.Info("Initializing Kubernetes backend")
-		k8sBackend, err := backends.NewKubernetesBackend(cfg, logger)
+		k8sBackend, err := backends.NewKubernetesBackend(cfg, component(logger, "backend"))
 		if err != nil {
 			logger.Error("Failed to create Kubernetes backend", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
 		backend = k8sBackend
+		k8sBackend.SetOutbound(outboundSettings)
+		if egressFilter != nil {
+			k8sBackend.SetEgressFilter(egressFilter)
+		}
 
 		// Initialize Kubernetes backend
 		if err := backend.Initialize(ctx); err != nil {
@@ -76,17 +227,85 @@ func main() {
 			os.Exit(1)
 		}
 
+		// Remove resources left behind when creations were interrupted
+		if cfg.Kubernetes.GCInterval > 0 {
+			sup.Go("kubernetes-gc", supervisor.Policy{}, func(ctx context.Context) error {
+				k8sBackend.RunGarbageCollection(ctx)
+				return nil
+			})
+		}
+
 	case "docker":
 		logger.Info("Initializing Docker backend")
-		dockerBackend := backends.NewDockerBackend(cfg, logger)
+
+		// CONTAINER_RUNTIME_API=api without a reachable socket would otherwise
+		// silently shell out to the runtime
+		runtime, err := container.NewRuntime(cfg, component(logger, "runtime"))
+		if err != nil {
+			logger.Error("Invalid container runtime API configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if _, err := container.NewPortAllocator(cfg.Container.HostPortRange); err != nil {
+			logger.Error("Invalid host port configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		dockerBackend := backends.NewDockerBackend(cfg, component(logger, "backend"))
 		backend = dockerBackend
 
 		// Get the container manager from the docker backend for compatibility
 		containerManager = dockerBackend.GetManager()
+		containerManager.SetRuntime(runtime)
+		containerManager.SetInstanceStore(metadataStore.Instances())
+		containerManager.SetStateStore(metadataStore.Containers())
+		containerManager.SetSupervisor(sup)
+		containerManager.SetOutbound(outboundSettings)
+		if egressFilter != nil {
+			containerManager.SetEgressFilter(egressFilter)
+		}
+
+		// Routes are served by the internal proxy started below; without a
+		// mounted Traefik config path the manager runs proxy-only
+		if _, err := containerManager.ConfigureRouting(true); err != nil {
+			logger.Error("No usable routing path", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		// Freeze the manager being replaced before discovering its containers
+		if cfg.Handoff.From != "" {
+			handoffClient = handoff.NewClient(cfg.Handoff.From, cfg.Handoff.Token, cfg.Handoff.SettleTimeout+30*time.Second)
+			handoffState, err = handoffClient.Begin(ctx)
+			if err != nil {
+				logger.Error("Failed to start handoff from the running manager",
+					slog.String("from", cfg.Handoff.From),
+					slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			logger.Info("Handoff started",
+				slog.String("from", cfg.Handoff.From),
+				slog.String("version", handoffState.Version),
+				slog.Int("containers", len(handoffState.Containers)),
+				slog.Int("routes", len(handoffState.Routes)),
+				slog.Int("operations", len(handoffState.Operations)))
+		}
 
 		// Initialize Docker backend
 		if err := backend.Initialize(ctx); err != nil {
 			logger.Error("Failed to initialize Docker backend", slog.String("error", err.Error()))
+			if handoffClient != nil {
+				if abortErr := handoffClient.Abort(ctx); abortErr != nil {
+					logger.Error("Failed to abort handoff", slog.String("error", abortErr.Error()))
+				}
+			}
+			os.Exit(1)
+		}
+
+	case "fake":
+		logger.Warn("Initializing fake backend; instances are simulated and no containers run")
+		fakeBackend = backends.NewFakeBackend(cfg.Fake, component(logger, "backend"))
+		backend = fakeBackend
+
+		if err := backend.Initialize(ctx); err != nil {
+			logger.Error("Failed to initialize fake backend", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
 
@@ -98,6 +317,7 @@ func main() {
 	// Start internal proxy server in background only for Docker environments
 	var proxyServer *proxy.ProxyServer
 	var routeManager *proxy.RouteManager
+	var proxyBinding *proxy.Binding
 	if envType == "docker" {
 		proxyConfig := proxy.ProxyConfig{
 			Port:              80,
@@ -105,84 +325,394 @@ func main() {
 			ReadTimeout:       15 * time.Second,
 			WriteTimeout:      15 * time.Second,
 			IdleTimeout:       60 * time.Second,
+
+			MaxIdleConns:        cfg.Proxy.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.Proxy.MaxIdleConnsPerHost,
+			MaxConnsPerHost:     cfg.Proxy.MaxConnsPerHost,
+			IdleConnTimeout:     cfg.Proxy.IdleConnTimeout,
+			DialTimeout:         cfg.Proxy.DialTimeout,
+			KeepAlive:           cfg.Proxy.KeepAlive,
+			Health: proxy.HealthPolicy{
+				MaxFailures:   uint32(cfg.Proxy.EjectFailures),
+				EjectDuration: cfg.Proxy.EjectDuration,
+			},
+			Quota: proxy.QuotaPolicy{
+				Workspace: proxy.QuotaLimits{Daily: cfg.Proxy.WorkspaceDailyQuota, Monthly: cfg.Proxy.WorkspaceMonthlyQuota},
+				Client:    proxy.QuotaLimits{Daily: cfg.Proxy.ClientDailyQuota, Monthly: cfg.Proxy.ClientMonthlyQuota},
+			},
+			Compression: proxy.CompressionPolicy{
+				Encodings: cfg.Proxy.Compression,
+				MinSize:   int64(cfg.Proxy.CompressionMinSize),
+			},
+			Thresholds: proxy.RequestThresholds{
+				Slow:         cfg.Proxy.SlowRequestThreshold,
+				LargePayload: cfg.Proxy.LargePayloadBytes,
+			},
 		}
-		proxyServer = proxy.NewProxyServer(proxyConfig, logger)
-		routeManager = proxy.NewRouteManager(proxyServer, cfg, logger)
+		if err := proxyConfig.Compression.Validate(); err != nil {
+			logger.Error("Invalid proxy compression", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		proxyLogger := component(logger, "proxy")
+		proxyServer = proxy.NewProxyServer(proxyConfig, proxyLogger)
+		routeManager = proxy.NewRouteManager(proxyServer, cfg, proxyLogger)
 
 		// Set the route manager in the container manager for route registration
 		if containerManager != nil {
 			containerManager.SetRouteManager(routeManager)
 		}
 
-		// Start proxy server in background
-		go func() {
-			if err := proxyServer.Start(); err != nil && err != http.ErrServerClosed {
-				logger.Error("Proxy server failed", slog.String("error", err.Error()))
+		// Serve the routes of the manager being replaced from the start
+		if handoffState != nil {
+			if err := routeManager.ImportRoutes(handoffState.Routes); err != nil {
+				logger.Warn("Some handed over routes were not imported", slog.String("error", err.Error()))
 			}
-		}()
+			if len(handoffState.SkippedRoutes) > 0 {
+				logger.Warn("Routes with brokered credentials were not handed over",
+					slog.Any("slugs", handoffState.SkippedRoutes))
+			}
+			if missing := containerManager.ImportState(handoffState.HandoffState); len(missing) > 0 {
+				logger.Warn("Handed over containers were not discovered", slog.Any("services", missing))
+			}
+		}
+
+		// Start proxy server in background. A port that is taken now, or a
+		// listener that fails later, is bound again with backoff.
+		proxyListener, err := handoff.Listen(ctx, proxyServer.Addr(), reusePort)
+		if err != nil {
+			logger.Warn("Failed to listen for the proxy server, retrying", slog.String("error", err.Error()))
+		}
+		proxyBinding = proxy.NewBinding(proxyServer.Addr(), proxyListener,
+			func(ctx context.Context, addr string) (net.Listener, error) {
+				return handoff.Listen(ctx, addr, reusePort)
+			},
+			proxyServer.Serve,
+			proxy.BindingPolicy{
+				FallbackPort:  cfg.Proxy.FallbackPort,
+				FallbackAfter: cfg.Proxy.FallbackAfter,
+				MaxBackoff:    cfg.Proxy.RebindMaxBackoff,
+			},
+			proxyLogger)
+		sup.Go("proxy", supervisor.Policy{Critical: true, MaxRestarts: 3}, proxyBinding.Run)
 	}
 
 	// Initialize secret resolver with Infisical SDK
-	secretResolver, err := secrets.NewSecretResolver(logger)
+	secretResolver, err := secrets.NewSecretResolver(component(logger, "secrets"))
 	if err != nil {
 		logger.Error("Failed to initialize secret resolver", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 	defer secretResolver.Close()
 
+	// Workspace variable sets shared by the API and event-driven provisioning
+	variableStore := variables.NewStore(redisOptions)
+	defer variableStore.Close()
+
+	// Initialize OAuth token broker for MCP servers that need user credentials
+	tokenBroker := oauth.NewBroker(cfg.OAuth, secretResolver, component(logger, "oauth"))
+
 	// Initialize providers based on environment
 	var providerManager *providers.ProviderManager
+	var dockerProvider *providers.DockerProvider
+	providerLogger := component(logger, "providers")
+	urlProvider := providers.NewURLProvider(providerLogger)
+	urlProvider.SetTokenBroker(tokenBroker)
+	if routeManager != nil {
+		// Allow URL instances to be exposed through the internal proxy
+		urlProvider.SetRouteRegistrar(routeManager, secretResolver)
+	}
 	if envType == "docker" && containerManager != nil {
-		dockerProvider := providers.NewDockerProvider(secretResolver, containerManager, logger)
-		urlProvider := providers.NewURLProvider(logger)
+		dockerProvider = providers.NewDockerProvider(secretResolver, containerManager, providerLogger)
+		dockerProvider.SetTokenBroker(tokenBroker)
+		dockerProvider.SetVariableStore(variableStore)
 		providerManager = providers.NewProviderManager(dockerProvider, urlProvider)
 	} else {
 		// For Kubernetes, we'll use the backend directly through the API
-		urlProvider := providers.NewURLProvider(logger)
 		providerManager = providers.NewProviderManager(nil, urlProvider)
 	}
 
 	// Initialize event subscriber
-	eventSubscriber := events.NewEventSubscriber(cfg.Redis.URL, providerManager, logger)
+	eventLogger := component(logger, "events")
+	eventSubscriber := events.NewEventSubscriber(redisOptions, providerManager, eventLogger)
+	eventSubscriber.SetCreateConcurrency(cfg.Redis.CreateWorkers, cfg.Redis.CreateQueueSize)
+	eventSubscriber.SetLogPayloads(cfg.Logging.EventPayloads)
+	eventSubscriber.SetPanicReporter(sup)
+
+	// Creates and updates from the API and events can be cancelled under
+	// /operations while they pull images or wait for instances
+	operationTracker := operations.NewTracker()
+	eventSubscriber.SetOperations(operationTracker)
+
+	// Start event subscriber, reconnecting for as long as Redis is unavailable.
+	// It stops on its own context when this manager hands over, and a
+	// replacement starts it once the manager it replaces has stopped.
+	eventsCtx, stopEvents := context.WithCancel(ctx)
+	defer stopEvents()
+	startEvents := func() {
+		sup.Go("event-subscriber", supervisor.Policy{MaxRestarts: -1, MaxBackoff: 30 * time.Second}, func(context.Context) error {
+			if err := eventSubscriber.Start(eventsCtx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+	if handoffClient == nil {
+		startEvents()
+	}
 
-	// Start event subscriber in a goroutine
-	go func() {
-		if err := eventSubscriber.Start(ctx); err != nil && err != context.Canceled {
-			logger.Error("Event subscriber failed", slog.String("error", err.Error()))
+	// Instance gauges are read from the backend on each scrape
+	metrics.SetInstanceLister(func(ctx context.Context) ([]string, error) {
+		instances, err := backend.ListInstances(ctx)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		statuses := make([]string, 0, len(instances))
+		for _, instance := range instances {
+			statuses = append(statuses, instance.Status)
+		}
+		return statuses, nil
+	})
 
 	// Setup HTTP router
-	router := setupRouter(cfg, logger)
-	handler := api.NewHandler(backend, containerManager, templateLoader, logger, version)
+	router := setupRouter(cfg, component(logger, "http"))
+	handler := api.NewHandler(backend, containerManager, templateLoader, component(logger, "api"), version)
+	handler.SetLogControl(logControl)
+	handler.SetReadOnly(cfg.Server.ReadOnly, cfg.Server.BreakGlassToken)
+	handler.SetLegacyAPIDisabled(cfg.Server.LegacyAPIDisabled)
+	handler.SetExecEnabled(cfg.Server.ExecEnabled)
+	if cfg.Server.ReadOnly {
+		logger.Warn("Starting in read-only mode", slog.Bool("break_glass", cfg.Server.BreakGlassToken != ""))
+	}
+	if encryptedStore != nil {
+		handler.SetStoreEncryption(encryptedStore)
+	}
+	if routeManager != nil {
+		handler.SetRouteManager(routeManager)
+	}
+	handler.SetEventSubscriber(eventSubscriber)
+	if checker, ok := secretResolver.(secrets.HealthChecker); ok {
+		handler.SetSecretHealth(checker)
+	}
+	handler.SetSupervisor(sup)
+	handler.SetVariableStore(variableStore)
+	handler.SetOutbound(outboundSettings)
+	if egressFilter != nil {
+		handler.SetEgressFilter(egressFilter)
+	}
+
+	// Host devices and sockets are only passed through, and sockets only
+	// proxied to, when allow-listed
+	if len(cfg.Container.AllowedDevices) > 0 || len(cfg.Container.AllowedHostSockets) > 0 {
+		hostAccess := container.NewHostAccessPolicy(cfg.Container.AllowedDevices, cfg.Container.AllowedHostSockets)
+		hostAccess.SetRuntimeSocket(container.RuntimeSocket(cfg.Container))
+		handler.SetHostAccessPolicy(hostAccess)
+		urlProvider.SetSocketPolicy(hostAccess)
+		if containerManager != nil {
+			containerManager.SetHostAccessPolicy(hostAccess)
+		}
+	}
+
+	// Template updates are announced with the instances left on older versions
+	// and rolled out to instances that track their template
+	eventPublisher := events.NewEventPublisher(redisOptions, eventLogger)
+	defer eventPublisher.Close()
+	handler.SetEventPublisher(eventPublisher)
+	sup.SetErrorPublisher(eventPublisher)
+	if fakeBackend != nil {
+		// Simulated status changes reach the Core API like real ones
+		fakeBackend.SetStatusPublisher(eventPublisher)
+	}
+	if proxyBinding != nil {
+		// Consumers routing to the proxy learn when it moves to another port
+		handler.SetProxyBinding(proxyBinding)
+		proxyBinding.OnRebind(func(status models.ProxyStatus) {
+			_, port, _ := net.SplitHostPort(status.Address)
+			portNumber, _ := strconv.Atoi(port)
+			publishCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			_ = eventPublisher.PublishProxyRebound(publishCtx, events.ProxyReboundEvent{
+				Address:   status.Address,
+				Port:      portNumber,
+				Fallback:  status.Fallback,
+				Rebinds:   status.Rebinds,
+				LastError: status.LastError,
+				Timestamp: time.Now(),
+			})
+		})
+	}
+
+	// Failed, crash looping and quarantined instances are announced on the
+	// notification channels their workspace and severity route to
+	notifier := notify.NewNotifier(redisOptions, cfg.Notifications, component(logger, "notify"))
+	defer notifier.Close()
+	notifier.SetWorkspaceLookup(func(ctx context.Context, instanceID string) string {
+		status, err := backend.GetInstanceStatus(ctx, instanceID)
+		if err != nil {
+			return ""
+		}
+		if workspaceID := status.Labels[container.WorkspaceLabel]; workspaceID != "" {
+			return workspaceID
+		}
+		return status.Environment[container.WorkspaceIDEnv]
+	})
+	events.SetAlertHandler(notifier.HandleAlert)
+	sup.Go("notifications", supervisor.Policy{}, func(ctx context.Context) error {
+		notifier.Run(ctx)
+		return nil
+	})
+	handler.SetNotifier(notifier)
+	handler.SetTimeline(timeline)
+	handler.SetOperations(operationTracker)
+
+	// Aggregate the instances of the managers of other regions or clusters
+	if len(cfg.Federation.Peers) > 0 {
+		peers, err := federation.ParsePeers(cfg.Federation.Peers)
+		if err != nil {
+			logger.Error("Invalid federation peers", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		handler.SetFederation(federation.NewClient(peers, cfg.Federation.Token, cfg.Federation.Timeout), cfg.Federation.Name)
+		logger.Info("Federation enabled",
+			slog.String("name", cfg.Federation.Name),
+			slog.Int("peers", len(peers)))
+	}
+
+	handler.SetTemplateUpgradePolicy(cfg.TemplateUpgrade.MaxUnavailable, cfg.TemplateUpgrade.HealthTimeout)
+	templateLoader.OnUpdate(handler.NotifyTemplateUpdated)
+
+	// Poll for rotated secrets so running instances do not keep stale credentials
+	if dockerProvider != nil && cfg.SecretWatch.Interval > 0 {
+		dockerProvider.SetSecretWatch(eventPublisher, cfg.SecretWatch.Restart)
+		sup.Go("secret-watch", supervisor.Policy{}, func(ctx context.Context) error {
+			dockerProvider.WatchSecrets(ctx, cfg.SecretWatch.Interval)
+			return nil
+		})
+	}
+
+	// Scheduled restarts wait for instances reserved by agent sessions
+	if containerManager != nil {
+		containerManager.SetReservationCheck(handler.Reserved)
+	}
+
+	if containerManager != nil {
+		// Templates with warm_pool keep pre-started containers for fast creation
+		containerManager.SetWarmPoolSource(func() []container.WarmPoolSpec {
+			var specs []container.WarmPoolSpec
+			for key, template := range templateLoader.ByKey() {
+				if template.WarmPool > 0 {
+					specs = append(specs, container.WarmPoolSpec{Template: key, Image: template.DockerImage, Size: template.WarmPool})
+				}
+			}
+			return specs
+		})
+
+		// Instances created from a template inherit its default environment
+		containerManager.SetTemplateEnvLookup(func(templateID string) []models.MCPEnvVar {
+			template, _ := templateLoader.Get(templateID)
+			return template.EnvVars
+		})
+
+		// Instances without their own SLO use the SLO of their template
+		containerManager.SetTemplateSLOLookup(func(templateID string) *container.SLO {
+			template, ok := templateLoader.Get(templateID)
+			if !ok {
+				return nil
+			}
+			slo, err := container.SLOFromTemplate(template.SLO)
+			if err != nil {
+				logger.Warn("Ignoring invalid template SLO",
+					slog.String("template", templateID),
+					slog.String("error", err.Error()))
+				return nil
+			}
+			return slo
+		})
+	}
+
+	if cfg.Registry.Enabled {
+		handler.SetRegistryClient(registry.NewClient(cfg.Registry.URL, cfg.Registry.Timeout))
+	}
+
+	// A replacement manager takes over through the handoff endpoints; this
+	// process exits once it serves
+	handoffDone := make(chan struct{})
+	if cfg.Handoff.Token != "" && containerManager != nil {
+		var once sync.Once
+		handler.SetHandoff(cfg.Handoff.Token, cfg.Handoff.SettleTimeout, cfg.Handoff.Deadline, func() {
+			once.Do(func() {
+				stopEvents()
+				close(handoffDone)
+			})
+		})
+	}
+	if cfg.Server.Auth.Enabled() {
+		handler.SetAuthenticator(auth.New(cfg.Server.Auth, component(logger, "auth")), cfg.Server.Auth.ExemptPaths)
+		logger.Info("API authentication enabled",
+			slog.Int("api_keys", len(cfg.Server.Auth.APIKeys)),
+			slog.Bool("jwt", cfg.Server.Auth.JWKSURL != ""),
+			slog.Any("exempt_paths", cfg.Server.Auth.ExemptPaths))
+	} else {
+		logger.Warn("API_KEYS and JWT_JWKS_URL not set, the API is unauthenticated")
+	}
 	handler.SetupRoutes(router)
 
+	// Prometheus metrics, registered after the routes so authentication
+	// applies unless /metrics is exempt
+	if cfg.Server.MetricsEnabled {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
 	// Start HTTP server
 	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Addr:         net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)),
 		Handler:      router,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
-	// Start server in a goroutine
-	go func() {
-		logger.Info("Starting MCP Manager",
-			slog.String("version", version),
-			slog.String("address", server.Addr))
+	// Start server in background
+	logger.Info("Starting MCP Manager",
+		slog.String("version", version),
+		slog.String("address", server.Addr))
+	serverListener, err := handoff.Listen(ctx, server.Addr, reusePort)
+	if err != nil {
+		logger.Error("Failed to listen for the API server", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	sup.Go("http", supervisor.Policy{Critical: true, MaxRestarts: 3},
+		serveOn(serverListener, server.Addr, reusePort, server.Serve))
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Server failed to start", slog.String("error", err.Error()))
-			os.Exit(1)
-		}
-	}()
+	// Both listeners are bound, so the manager being replaced can exit
+	handoffFailed := make(chan error, 1)
+	if handoffClient != nil {
+		go func() {
+			if err := handoffClient.Complete(ctx); err != nil {
+				if abortErr := handoffClient.Abort(ctx); abortErr != nil {
+					logger.Error("Failed to abort handoff", slog.String("error", abortErr.Error()))
+				}
+				handoffFailed <- err
+				return
+			}
+			logger.Info("Took over from the previous manager", slog.String("from", cfg.Handoff.From))
+			startEvents()
+		}()
+	}
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, a critical component to fail or a handoff
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	logger.Info("Shutting down server...")
+	select {
+	case <-quit:
+		logger.Info("Shutting down server...")
+	case <-handoffDone:
+		logger.Info("Shutting down after handing off to a new manager...")
+	case err := <-handoffFailed:
+		logger.Error("Shutting down after the handoff failed", slog.String("error", err.Error()))
+		exitCode = 1
+	case <-sup.Done():
+		logger.Error("Shutting down after a critical component failed", slog.String("error", fmt.Sprint(sup.Err())))
+		exitCode = 1
+	}
 
 	// Graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -199,6 +729,9 @@ func main() {
 		}
 	}
 
+	// Stop supervised components before closing what they use
+	cancel()
+
 	// Close event subscriber
 	if err := eventSubscriber.Close(); err != nil {
 		logger.Error("Failed to close event subscriber", slog.String("error", err.Error()))
@@ -216,15 +749,48 @@ func main() {
 		}
 	}
 
+	// Wait for supervised components to return
+	stopped := make(chan struct{})
+	go func() {
+		_ = sup.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		logger.Warn("Timed out waiting for background components to stop")
+	}
+
 	logger.Info("Server shutdown complete")
 }
 
-// setupLogging configures structured logging
-func setupLogging(cfg *config.Config) *slog.Logger {
+// serveOn returns a supervised function that serves on ln, opening a new
+// listener on addr when the supervisor restarts it
+func serveOn(ln net.Listener, addr string, reusePort bool, serve func(net.Listener) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if ln == nil {
+			var err error
+			if ln, err = handoff.Listen(ctx, addr, reusePort); err != nil {
+				return err
+			}
+		}
+		current := ln
+		ln = nil
+		if err := serve(current); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// setupLogging configures structured logging. Levels are enforced by the
+// returned control so they can be changed at runtime per component.
+func setupLogging(cfg *config.Config) (*slog.Logger, *logging.Control) {
 	var handler slog.Handler
 
+	// The control filters levels; the output handler accepts everything
 	opts := &slog.HandlerOptions{
-		Level: getLogLevel(cfg.Logging.Level),
+		Level: slog.LevelDebug,
 	}
 
 	if cfg.Logging.Format == "json" {
@@ -233,7 +799,24 @@ func setupLogging(cfg *config.Config) *slog.Logger {
 		handler = slog.NewTextHandler(os.Stdout, opts)
 	}
 
-	return slog.New(handler)
+	control := logging.NewControl(getLogLevel(cfg.Logging.Level))
+	control.SetSampling(cfg.Logging.SampleInitial, cfg.Logging.SampleThereafter)
+	logger := slog.New(logging.NewHandler(handler, control))
+
+	levels, err := logging.ParseComponentLevels(cfg.Logging.ComponentLevels)
+	if err != nil {
+		logger.Warn("Ignoring invalid LOG_LEVELS", slog.String("error", err.Error()))
+	}
+	for name, level := range levels {
+		control.SetLevel(name, level)
+	}
+
+	return logger, control
+}
+
+// component returns a logger for one component, whose level can be set separately
+func component(logger *slog.Logger, name string) *slog.Logger {
+	return logger.With(slog.String(logging.ComponentKey, name))
 }
 
 // setupRouter configures the HTTP router
@@ -270,7 +853,7 @@ func setupRouter(cfg *config.Config, logger *slog.Logger) *gin.Engine {
 			corsConfig.AllowAllOrigins = true
 		}
 		corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-		corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+		corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Break-Glass-Token"}
 		corsConfig.ExposeHeaders = []string{"Content-Length"}
 		corsConfig.AllowCredentials = true
 
@@ -285,16 +868,9 @@ func setupRouter(cfg *config.Config, logger *slog.Logger) *gin.Engine {
 
 // getLogLevel converts string log level to slog.Level
 func getLogLevel(level string) slog.Level {
-	switch level {
-	case "DEBUG":
-		return slog.LevelDebug
-	case "INFO":
-		return slog.LevelInfo
-	case "WARN":
-		return slog.LevelWarn
-	case "ERROR":
-		return slog.LevelError
-	default:
+	parsed, err := logging.ParseLevel(level)
+	if err != nil {
 		return slog.LevelInfo
 	}
+	return parsed
 }
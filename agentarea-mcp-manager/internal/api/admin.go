@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/logging"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/secrets"
+	"github.com/agentarea/mcp-manager/internal/store"
+)
+
+// SetSecretHealth adds the secret backend to /admin/doctor
+func (h *Handler) SetSecretHealth(checker secrets.HealthChecker) {
+	h.secretHealth = checker
+}
+
+// SetLogControl enables runtime log level changes under /admin
+func (h *Handler) SetLogControl(control *logging.Control) {
+	h.logControl = control
+}
+
+// SetStoreEncryption enables re-encryption of the metadata store under /admin
+func (h *Handler) SetStoreEncryption(encrypted *store.Encrypted) {
+	h.storeEncryption = encrypted
+}
+
+// reencryptStore rewrites stored specs with the primary encryption key
+func (h *Handler) reencryptStore(c *gin.Context) {
+	result, err := h.storeEncryption.Reencrypt(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Store re-encryption failed",
+			slog.Int("instances", result.Instances),
+			slog.Int("containers", result.Containers),
+			slog.Int("desired_state", result.DesiredState),
+			slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "reencrypt_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Warn("Store re-encrypted",
+		slog.Int("instances", result.Instances),
+		slog.Int("containers", result.Containers),
+		slog.Int("desired_state", result.DesiredState))
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getGarbage reports the backend resources a garbage collection pass would
+// delete, without deleting them
+func (h *Handler) getGarbage(c *gin.Context) {
+	h.collectGarbageWith(c, true)
+}
+
+// collectGarbage deletes orphaned backend resources now
+func (h *Handler) collectGarbage(c *gin.Context) {
+	h.collectGarbageWith(c, false)
+}
+
+func (h *Handler) collectGarbageWith(c *gin.Context, dryRun bool) {
+	collector, ok := h.backend.(backends.GarbageCollector)
+	if !ok {
+		notSupported(c, backends.CapabilityGC)
+		return
+	}
+	report, err := collector.CollectGarbage(c.Request.Context(), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "gc_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !dryRun {
+		h.logger.Warn("Garbage collection run",
+			slog.Int("orphans", len(report.Orphans)),
+			slog.Int("deleted", report.Deleted),
+			slog.String("client_ip", c.ClientIP()))
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// getRouteSweep reports the routes a sweep would remove or repair, without
+// changing them
+func (h *Handler) getRouteSweep(c *gin.Context) {
+	h.sweepRoutesWith(c, true)
+}
+
+// sweepRoutes removes routes of vanished containers and repairs routes of
+// containers whose address changed
+func (h *Handler) sweepRoutes(c *gin.Context) {
+	h.sweepRoutesWith(c, false)
+}
+
+// getNetwork reports the container network and the containers attached to it
+func (h *Handler) getNetwork(c *gin.Context) {
+	status, err := h.containerManager.NetworkStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "network_inspect_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) sweepRoutesWith(c *gin.Context, dryRun bool) {
+	sweep, err := h.containerManager.SweepRoutes(c.Request.Context(), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "route_sweep_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !dryRun {
+		h.logger.Warn("Route sweep run",
+			slog.Int("removed", len(sweep.Removed)),
+			slog.Int("repaired", len(sweep.Repaired)),
+			slog.String("client_ip", c.ClientIP()))
+	}
+	c.JSON(http.StatusOK, sweep)
+}
+
+// getSecurityReport lists the security posture findings of every managed
+// instance as a remediation checklist
+func (h *Handler) getSecurityReport(c *gin.Context) {
+	c.JSON(http.StatusOK, h.containerManager.SecurityReport(c.Request.Context()))
+}
+
+// getLegacyContainers lists containers created through the legacy
+// /containers API that have not been adopted yet
+func (h *Handler) getLegacyContainers(c *gin.Context) {
+	legacy := h.containerManager.LegacyContainers()
+	c.JSON(http.StatusOK, gin.H{
+		"containers": legacy,
+		"total":      len(legacy),
+	})
+}
+
+// migrateLegacyContainers adopts legacy containers into the instance model,
+// giving each an instance ID and registering it with the Core API
+func (h *Handler) migrateLegacyContainers(c *gin.Context) {
+	var req struct {
+		WorkspaceID string `json:"workspace_id"`
+		Register    *bool  `json:"register"`
+		DryRun      bool   `json:"dry_run"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	opts := container.AdoptOptions{
+		WorkspaceID: req.WorkspaceID,
+		Register:    req.Register == nil || *req.Register,
+		DryRun:      req.DryRun,
+	}
+	adoptions := h.containerManager.AdoptLegacyContainers(c.Request.Context(), opts)
+
+	failed := 0
+	for _, adoption := range adoptions {
+		if adoption.Error != "" {
+			failed++
+		}
+	}
+	h.logger.Info("Migrated legacy containers",
+		slog.Int("adopted", len(adoptions)-failed),
+		slog.Int("failed", failed),
+		slog.Bool("dry_run", req.DryRun),
+		slog.String("client_ip", c.ClientIP()))
+
+	c.JSON(http.StatusOK, gin.H{
+		"adoptions": adoptions,
+		"adopted":   len(adoptions) - failed,
+		"failed":    failed,
+		"dry_run":   req.DryRun,
+	})
+}
+
+// getLogLevels returns the base log level, component overrides and sampling
+func (h *Handler) getLogLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, h.logLevelsResponse())
+}
+
+// setLogLevel changes the base level or the level of one component. An
+// empty level resets a component to the base level.
+func (h *Handler) setLogLevel(c *gin.Context) {
+	var req struct {
+		Component string `json:"component"`
+		Level     string `json:"level"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.Level == "" {
+		if req.Component == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: "level is required unless resetting a component",
+			})
+			return
+		}
+		h.logControl.ResetLevel(req.Component)
+		h.logger.Info("Log level reset", slog.String("target", req.Component))
+		c.JSON(http.StatusOK, h.logLevelsResponse())
+		return
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_log_level",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logControl.SetLevel(req.Component, level)
+	target := req.Component
+	if target == "" {
+		target = "base"
+	}
+	// Logged at Warn so the change is visible whatever the new level is
+	h.logger.Warn("Log level changed",
+		slog.String("target", target),
+		slog.String("level", level.String()))
+
+	c.JSON(http.StatusOK, h.logLevelsResponse())
+}
+
+// logLevelsResponse describes the current log levels
+func (h *Handler) logLevelsResponse() gin.H {
+	base, overrides := h.logControl.Levels()
+	components := make(map[string]string, len(overrides))
+	for name, level := range overrides {
+		components[name] = level.String()
+	}
+	return gin.H{
+		"level":      base.String(),
+		"components": components,
+		"sampling":   h.logControl.Sampling(),
+	}
+}
+
+// doctorTimeout bounds the checks of one /admin/doctor request
+const doctorTimeout = 10 * time.Second
+
+// getDoctor checks the connections the manager depends on. The overall
+// status is the worst check result.
+func (h *Handler) getDoctor(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), doctorTimeout)
+	defer cancel()
+
+	checks := []models.DoctorCheck{}
+	if h.eventSubscriber != nil {
+		checks = append(checks, h.redisCheck(ctx))
+	}
+	if h.secretHealth != nil {
+		checks = append(checks, h.secretsCheck(ctx))
+	}
+	if h.containerManager != nil {
+		checks = append(checks, h.routingCheck())
+	}
+
+	status := models.DoctorOK
+	for _, check := range checks {
+		switch {
+		case check.Status == models.DoctorFail:
+			status = models.DoctorFail
+		case check.Status == models.DoctorWarn && status == models.DoctorOK:
+			status = models.DoctorWarn
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// routingCheck reports how instance routes are served
+func (h *Handler) routingCheck() models.DoctorCheck {
+	routing := h.containerManager.Routing()
+	check := models.DoctorCheck{
+		Name:    "routing",
+		Status:  models.DoctorOK,
+		Message: "routing mode " + routing.Mode,
+		Details: routing,
+	}
+	if routing.Mode == container.RoutingProxyOnly {
+		check.Status = models.DoctorWarn
+		check.Message = "Traefik config path unavailable, routing through the internal proxy only: " + routing.TraefikError
+	}
+	return check
+}
+
+// redisCheck reports the Redis connection used for events
+func (h *Handler) redisCheck(ctx context.Context) models.DoctorCheck {
+	health := h.eventSubscriber.CheckRedis(ctx)
+	subscription := h.eventSubscriber.PipelineStats().Redis
+	check := models.DoctorCheck{
+		Name:    "redis",
+		Status:  models.DoctorOK,
+		Details: gin.H{"connection": health, "subscription": subscription},
+	}
+	switch {
+	case !health.Connected:
+		check.Status = models.DoctorFail
+		check.Message = "cannot reach Redis: " + health.Error
+	case health.FailedNodes > 0:
+		check.Status = models.DoctorWarn
+		check.Message = fmt.Sprintf("%d of %d cluster masters not answering", health.FailedNodes, health.Nodes)
+	case subscription.State != events.RedisConnected:
+		check.Status = models.DoctorWarn
+		check.Message = "Redis answers but the event subscription is not connected"
+	case !health.TLS:
+		check.Message = "connected without TLS"
+	default:
+		check.Message = "connected"
+	}
+	return check
+}
+
+// secretsCheck reports whether the secret backend can serve secrets
+func (h *Handler) secretsCheck(ctx context.Context) models.DoctorCheck {
+	health := h.secretHealth.Health(ctx)
+	check := models.DoctorCheck{
+		Name:    "secrets",
+		Status:  models.DoctorOK,
+		Message: health.Backend + " reachable",
+		Details: health,
+	}
+	switch {
+	case !health.Available:
+		check.Status = models.DoctorFail
+		check.Message = "cannot reach the " + health.Backend + " secret backend: " + health.Error
+	case health.Pool != nil && health.Pool.AcquiredConns == health.Pool.MaxConns:
+		check.Status = models.DoctorWarn
+		check.Message = fmt.Sprintf("all %d pooled connections in use", health.Pool.MaxConns)
+	}
+	return check
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/auth"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// authAlwaysExempt are routes that carry their own credentials: handoff
+// requests come from a replacement manager holding only the handoff token
+var authAlwaysExempt = map[string]bool{
+	"/admin/handoff":          true,
+	"/admin/handoff/complete": true,
+	"/admin/handoff/abort":    true,
+}
+
+// SetAuthenticator requires an API key or JWT on every route except the
+// exempt ones, given as paths or route patterns such as /health
+func (h *Handler) SetAuthenticator(authenticator *auth.Authenticator, exempt []string) {
+	h.authenticator = authenticator
+	h.authExempt = make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		h.authExempt[path] = true
+	}
+}
+
+// authGuard rejects requests without valid credentials with 401, or 503 if
+// JWT signing keys cannot be fetched. It does nothing without an
+// authenticator.
+func (h *Handler) authGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.authenticator == nil || authAlwaysExempt[c.FullPath()] ||
+			h.authExempt[c.FullPath()] || h.authExempt[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		_, err := h.authenticator.Authenticate(c.Request)
+		if err == nil {
+			c.Next()
+			return
+		}
+
+		if errors.Is(err, auth.ErrKeysUnavailable) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.ErrorResponse{
+				Error:   "auth_unavailable",
+				Code:    http.StatusServiceUnavailable,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		h.logger.Debug("Rejected unauthenticated request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("error", err.Error()))
+		c.Header("WWW-Authenticate", `Bearer realm="mcp-manager"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:   "unauthorized",
+			Code:    http.StatusUnauthorized,
+			Message: err.Error(),
+		})
+	}
+}
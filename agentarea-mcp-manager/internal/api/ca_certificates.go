@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/outbound"
+)
+
+// SetOutbound enables registering CA certificates trusted by instances
+func (h *Handler) SetOutbound(settings *outbound.Settings) {
+	h.outbound = settings
+}
+
+// listCACertificates returns the registered CA certificates
+func (h *Handler) listCACertificates(c *gin.Context) {
+	certificates := h.outbound.Certificates()
+	c.JSON(http.StatusOK, gin.H{
+		"certificates": certificates,
+		"count":        len(certificates),
+	})
+}
+
+// putCACertificate registers or replaces a CA certificate; instances started
+// from now on trust it
+func (h *Handler) putCACertificate(c *gin.Context) {
+	name := c.Param("name")
+
+	var req struct {
+		PEM string `json:"pem" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	certificate, err := h.outbound.AddCertificate(name, []byte(req.PEM))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_certificate",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Logged at Warn so every change of the trusted roots leaves an audit trail
+	h.logger.Warn("CA certificate registered",
+		slog.String("name", name),
+		slog.String("subject", certificate.Subject),
+		slog.String("fingerprint", certificate.Fingerprint),
+		slog.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, certificate)
+}
+
+// deleteCACertificate unregisters a CA certificate; running instances keep
+// trusting it until they are recreated
+func (h *Handler) deleteCACertificate(c *gin.Context) {
+	name := c.Param("name")
+
+	if err := h.outbound.RemoveCertificate(name); err != nil {
+		status, code := http.StatusInternalServerError, "certificate_remove_failed"
+		if errors.Is(err, outbound.ErrCertificateNotFound) {
+			status, code = http.StatusNotFound, "certificate_not_found"
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   code,
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Warn("CA certificate removed",
+		slog.String("name", name),
+		slog.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, gin.H{
+		"name":    name,
+		"message": "CA certificate removed",
+	})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// checkpointInstance checkpoints the process state of an instance with CRIU,
+// optionally exporting it for a restore on another host
+func (h *Handler) checkpointInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	var opts container.CheckpointOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// A checkpoint stops the instance unless it is left running
+	if !opts.LeaveRunning && !h.checkReservation(c, instance) {
+		return
+	}
+
+	checkpoint, err := h.containerManager.CheckpointContainer(c.Request.Context(), instance.ServiceName, opts)
+	if err != nil {
+		h.logger.Error("Failed to checkpoint instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "checkpoint_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"checkpoint":  checkpoint,
+		"timestamp":   time.Now(),
+	})
+}
+
+// restoreInstance restores a checkpointed instance in place, or imports an
+// exported checkpoint archive when one is given
+func (h *Handler) restoreInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	var req struct {
+		Archive        string `json:"archive"`
+		Slug           string `json:"slug"`
+		TCPEstablished bool   `json:"tcp_established"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	var restored *models.Container
+	var err error
+	if req.Archive != "" {
+		// The instance is usually unknown here: it was checkpointed on another host
+		restored, err = h.containerManager.ImportCheckpoint(c.Request.Context(), req.Archive, req.Slug, req.TCPEstablished)
+	} else {
+		instance, lookupErr := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+		if lookupErr != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "instance_not_found",
+				Code:    http.StatusNotFound,
+				Message: lookupErr.Error(),
+			})
+			return
+		}
+		restored, err = h.containerManager.RestoreContainer(c.Request.Context(), instance.ServiceName)
+	}
+
+	switch {
+	case errors.Is(err, container.ErrNotCheckpointed):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "not_checkpointed",
+			Code:    http.StatusConflict,
+			Message: "instance has no checkpoint to restore",
+		})
+		return
+	case errors.Is(err, container.ErrContainerExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "instance_exists",
+			Code:    http.StatusConflict,
+			Message: "instance already has a container on this host",
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to restore instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "restore_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id":  instanceID,
+		"service_name": restored.ServiceName,
+		"container_id": restored.ID,
+		"status":       restored.Status,
+		"url":          restored.URL,
+		"timestamp":    time.Now(),
+	})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// deleteIntentTTL is how long a delete confirmation token stays valid
+const deleteIntentTTL = 5 * time.Minute
+
+// deleteIntent is a pending confirmation to delete a protected instance
+type deleteIntent struct {
+	token     string
+	expiresAt time.Time
+}
+
+// deleteIntentStore holds single-use delete confirmation tokens per instance
+type deleteIntentStore struct {
+	mu      sync.Mutex
+	intents map[string]deleteIntent // instance ID -> intent
+}
+
+// newDeleteIntentStore creates an empty delete intent store
+func newDeleteIntentStore() *deleteIntentStore {
+	return &deleteIntentStore{intents: make(map[string]deleteIntent)}
+}
+
+// issue creates a new confirmation token for an instance, replacing any previous one
+func (s *deleteIntentStore) issue(instanceID string) (deleteIntent, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return deleteIntent{}, err
+	}
+
+	intent := deleteIntent{
+		token:     hex.EncodeToString(buf),
+		expiresAt: time.Now().Add(deleteIntentTTL),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.intents[instanceID] = intent
+
+	return intent, nil
+}
+
+// consume validates and invalidates the confirmation token for an instance
+func (s *deleteIntentStore) consume(instanceID, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intent, exists := s.intents[instanceID]
+	if !exists || token == "" {
+		return false
+	}
+	if time.Now().After(intent.expiresAt) {
+		delete(s.intents, instanceID)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(intent.token), []byte(token)) != 1 {
+		return false
+	}
+
+	delete(s.intents, instanceID)
+	return true
+}
+
+// createDeleteIntent issues a confirmation token required to delete a protected instance
+func (h *Handler) createDeleteIntent(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	intent, err := h.deleteIntents.issue(instanceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "delete_intent_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"instance_id":        instanceID,
+		"protected":          backends.IsProtected(instance.Labels),
+		"confirmation_token": intent.token,
+		"expires_at":         intent.expiresAt,
+	})
+}
+
+// confirmationToken reads the delete confirmation token from the request
+func confirmationToken(c *gin.Context) string {
+	if token := c.GetHeader("X-Confirmation-Token"); token != "" {
+		return token
+	}
+	return c.Query("confirmation_token")
+}
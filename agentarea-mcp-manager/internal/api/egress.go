@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/egress"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// SetEgressFilter enables egress allow-lists and their query logs
+func (h *Handler) SetEgressFilter(filter *egress.Filter) {
+	h.egress = filter
+}
+
+// getEgressLog returns the recent DNS queries of an instance with an egress
+// allow-list, oldest first
+func (h *Handler) getEgressLog(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	entries := h.egress.Log(instance.ServiceName)
+	denied := 0
+	for _, entry := range entries {
+		if !entry.Allowed {
+			denied++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"entries":     entries,
+		"count":       len(entries),
+		"denied":      denied,
+		"timestamp":   time.Now(),
+	})
+}
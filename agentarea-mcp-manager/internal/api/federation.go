@@ -0,0 +1,74 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/federation"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// SetFederation enables the aggregated instance view of this manager, named
+// name, and its peers
+func (h *Handler) SetFederation(client *federation.Client, name string) {
+	h.federation = client
+	h.federationName = name
+}
+
+// getFederatedInstances lists the instances of every manager in the
+// federation, labelled with their origin. Label selectors and ?outdated
+// apply to all managers; ?origin= keeps the instances of one manager. Unreachable peers
+// are reported and the view is marked partial.
+func (h *Handler) getFederatedInstances(c *gin.Context) {
+	selector, err := backends.ParseLabelSelector(c.QueryArray("label"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_label_selector",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	local := federation.PeerStatus{Name: h.federationName, Status: federation.PeerOK, Health: "healthy", Version: h.version}
+	if h.supervisor != nil {
+		local.Health = componentHealth(h.supervisor.Statuses())
+	}
+	instances, err := h.backend.ListInstances(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list local instances for federation", slog.String("error", err.Error()))
+		local.Status, local.Error = federation.PeerUnreachable, err.Error()
+	}
+	instances = backends.FilterByLabels(instances, selector)
+	if c.Query("outdated") == "true" {
+		outdated := make([]*backends.InstanceStatus, 0)
+		for _, instance := range instances {
+			if h.isOutdated(instance) {
+				outdated = append(outdated, instance)
+			}
+		}
+		instances = outdated
+	}
+	local.Instances = len(instances)
+
+	query := c.Request.URL.Query()
+	origin := query.Get("origin")
+	query.Del("origin")
+
+	view := h.federation.Aggregate(c.Request.Context(), local, instances, query)
+	view.Partial = view.Partial || local.Status != federation.PeerOK
+	if origin != "" {
+		filtered := []federation.Instance{}
+		for _, instance := range view.Instances {
+			if instance.Origin == origin {
+				filtered = append(filtered, instance)
+			}
+		}
+		view.Instances, view.Total = filtered, len(filtered)
+	}
+
+	c.JSON(http.StatusOK, view)
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Defaults and bounds of GET /monitoring/fleet
+const (
+	defaultFleetTop    = 5
+	maxFleetTop        = 50
+	defaultFleetWindow = time.Hour
+	maxFleetFailures   = 20
+)
+
+// fleetInstance is the dashboard row of one instance
+type fleetInstance struct {
+	ID          string  `json:"id"`
+	ServiceName string  `json:"service_name"`
+	Status      string  `json:"status"`
+	WorkspaceID string  `json:"workspace_id,omitempty"`
+	Requests    uint64  `json:"requests,omitempty"`
+	Errors      uint64  `json:"errors,omitempty"`
+	ErrorRate   float64 `json:"error_rate,omitempty"`
+	LatencyMS   int64   `json:"latency_ms,omitempty"` // Response time of the last health check
+	Restarts    int     `json:"restarts,omitempty"`   // Within the window
+}
+
+// fleetFailure is a recent failure of an instance
+type fleetFailure struct {
+	InstanceID  string    `json:"instance_id,omitempty"`
+	ServiceName string    `json:"service_name"`
+	Reason      string    `json:"reason,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// fleetHeadroom is what can still be admitted on the host
+type fleetHeadroom struct {
+	Containers  *int    `json:"containers,omitempty"` // Unset without MAX_CONTAINERS
+	MemoryBytes int64   `json:"memory_bytes"`
+	CPUs        float64 `json:"cpus"`
+	Waiting     int     `json:"waiting"`
+}
+
+// getFleet returns one payload for fleet dashboards: instance counts by
+// status and workspace, the top instances by proxy error rate, health check
+// latency and restarts, recent failures and capacity headroom. Parts whose
+// source is not running are left out.
+func (h *Handler) getFleet(c *gin.Context) {
+	top := defaultFleetTop
+	if value := c.Query("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxFleetTop {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: "top must be an integer from 1 to " + strconv.Itoa(maxFleetTop),
+			})
+			return
+		}
+		top = parsed
+	}
+	window := defaultFleetWindow
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: "window must be a positive duration such as 1h",
+			})
+			return
+		}
+		window = parsed
+	}
+
+	ctx := c.Request.Context()
+	instances, err := h.backend.ListInstances(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list instances for fleet overview", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "fleet_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	rows := make([]*fleetInstance, 0, len(instances))
+	byService := make(map[string]*fleetInstance, len(instances))
+	byStatus := make(map[string]int)
+	byWorkspace := make(map[string]int)
+	for _, instance := range instances {
+		row := fleetRow(instance)
+		rows = append(rows, row)
+		byService[row.ServiceName] = row
+		byStatus[row.Status]++
+		byWorkspace[row.WorkspaceID]++
+	}
+
+	// Proxy error rates, by the slug of each managed container
+	if h.routeManager != nil && h.containerManager != nil {
+		routes := make(map[string]int)
+		stats := h.routeManager.GetRouteStats()
+		for i, route := range stats {
+			routes[route.Slug] = i
+		}
+		for _, ctr := range h.containerManager.ListContainers() {
+			row, ok := byService[ctr.ServiceName]
+			i, routed := routes[ctr.Slug]
+			if !ok || !routed || stats[i].Requests == 0 {
+				continue
+			}
+			row.Requests = stats[i].Requests
+			row.Errors = stats[i].Errors
+			row.ErrorRate = float64(stats[i].Errors) / float64(stats[i].Requests)
+		}
+	}
+
+	response := gin.H{
+		"total":        len(rows),
+		"by_status":    byStatus,
+		"by_workspace": byWorkspace,
+		"window":       window.String(),
+		"timestamp":    time.Now(),
+	}
+
+	// Restarts and failures come from the lifecycle timeline
+	if h.timeline != nil {
+		steps, err := h.timeline.Recent(ctx, time.Now().Add(-window))
+		if err != nil {
+			h.logger.Warn("Failed to load lifecycle steps for fleet overview", slog.String("error", err.Error()))
+		} else {
+			failures := []fleetFailure{}
+			for _, step := range steps {
+				switch step.Step {
+				case events.StepRestarted:
+					if row, ok := byService[step.Name]; ok {
+						row.Restarts++
+					}
+				case "failed":
+					if len(failures) < maxFleetFailures {
+						failures = append(failures, fleetFailure{InstanceID: step.InstanceID, ServiceName: step.Name, Reason: step.Message, Timestamp: step.Timestamp})
+					}
+				}
+			}
+			response["recent_failures"] = failures
+		}
+	}
+
+	response["top"] = gin.H{
+		"error_rate": topFleet(rows, top, func(row *fleetInstance) float64 { return row.ErrorRate }),
+		"latency":    topFleet(rows, top, func(row *fleetInstance) float64 { return float64(row.LatencyMS) }),
+		"restarts":   topFleet(rows, top, func(row *fleetInstance) float64 { return float64(row.Restarts) }),
+	}
+
+	if h.containerManager != nil {
+		if capacity, err := h.containerManager.CapacityStatus(); err != nil {
+			h.logger.Warn("Failed to read capacity for fleet overview", slog.String("error", err.Error()))
+		} else {
+			headroom := fleetHeadroom{
+				MemoryBytes: capacity.Allocatable.MemoryBytes - capacity.Reserved.MemoryBytes,
+				CPUs:        capacity.Allocatable.CPUs - capacity.Reserved.CPUs,
+				Waiting:     capacity.Waiting,
+			}
+			if capacity.MaxContainers > 0 {
+				containers := capacity.MaxContainers - capacity.Containers
+				headroom.Containers = &containers
+			}
+			response["headroom"] = headroom
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// fleetRow starts the dashboard row of an instance from its status
+func fleetRow(instance *backends.InstanceStatus) *fleetInstance {
+	row := &fleetInstance{
+		ID:          instance.ID,
+		ServiceName: instance.ServiceName,
+		Status:      instance.Status,
+		WorkspaceID: instance.Labels[container.WorkspaceLabel],
+	}
+	if row.WorkspaceID == "" {
+		row.WorkspaceID = instance.Environment[container.WorkspaceIDEnv]
+	}
+	if instance.HealthStatus != nil {
+		row.LatencyMS = instance.HealthStatus.ResponseTime.Milliseconds()
+	}
+	return row
+}
+
+// topFleet returns up to n rows with the highest non-zero value, highest first
+func topFleet(rows []*fleetInstance, n int, value func(*fleetInstance) float64) []fleetInstance {
+	ranked := make([]*fleetInstance, 0, len(rows))
+	for _, row := range rows {
+		if value(row) > 0 {
+			ranked = append(ranked, row)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return value(ranked[i]) > value(ranked[j]) })
+
+	result := make([]fleetInstance, 0, n)
+	for _, row := range ranked {
+		if len(result) == n {
+			break
+		}
+		result = append(result, *row)
+	}
+	return result
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/variables"
+)
+
+const (
+	defaultGroupHealthTimeout = 60 * time.Second
+	groupHealthPollInterval   = 2 * time.Second
+)
+
+// groupRolloutOptions controls how a group operation proceeds through the group
+type groupRolloutOptions struct {
+	MaxUnavailable int    `json:"max_unavailable,omitempty"`
+	HealthTimeout  string `json:"health_timeout,omitempty"`
+	Drain          string `json:"drain,omitempty"`
+}
+
+// listGroups returns the instance groups with their member counts
+func (h *Handler) listGroups(c *gin.Context) {
+	instances, err := h.backend.ListInstances(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list instances", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_instances_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	groups := backends.ListGroups(instances)
+	c.JSON(http.StatusOK, gin.H{
+		"groups": groups,
+		"total":  len(groups),
+	})
+}
+
+// getGroup returns the instances of a group
+func (h *Handler) getGroup(c *gin.Context) {
+	group := c.Param("group")
+
+	members, ok := h.groupMembers(c, group)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group":     group,
+		"instances": members,
+		"total":     len(members),
+	})
+}
+
+// restartGroup recreates every instance of a group with an unchanged spec
+func (h *Handler) restartGroup(c *gin.Context) {
+	var req groupRolloutOptions
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	now := time.Now()
+	h.runGroupOperation(c, "restart", req, func(spec *backends.InstanceSpec) error {
+		spec.Labels = backends.MarkRestarted(spec.Labels, now)
+		return nil
+	})
+}
+
+// setGroupEnvironment sets and removes environment variables on every
+// instance of a group
+func (h *Handler) setGroupEnvironment(c *gin.Context) {
+	var req struct {
+		groupRolloutOptions
+		Environment map[string]string `json:"environment,omitempty"`
+		Remove      []string          `json:"remove,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(req.Environment) == 0 && len(req.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "environment or remove must be set",
+		})
+		return
+	}
+
+	// Auth tokens are per instance and only change through token rotation
+	_, setsToken := req.Environment[container.AuthTokenEnv]
+	for _, name := range req.Remove {
+		setsToken = setsToken || name == container.AuthTokenEnv
+	}
+	if setsToken {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: container.AuthTokenEnv + " cannot be changed for a group; rotate tokens per instance",
+		})
+		return
+	}
+
+	h.runGroupOperation(c, "set_env", req.groupRolloutOptions, func(spec *backends.InstanceSpec) error {
+		env := make(map[string]string, len(spec.Environment)+len(req.Environment))
+		for k, v := range spec.Environment {
+			env[k] = v
+		}
+		for _, name := range req.Remove {
+			delete(env, name)
+		}
+		for k, v := range req.Environment {
+			env[k] = v
+		}
+		spec.Environment = env
+		return nil
+	})
+}
+
+// rollGroupImage moves every instance of a group to a new image
+func (h *Handler) rollGroupImage(c *gin.Context) {
+	var req struct {
+		groupRolloutOptions
+		Image string `json:"image" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.runGroupOperation(c, "roll_image", req.groupRolloutOptions, func(spec *backends.InstanceSpec) error {
+		spec.Image = req.Image
+		return nil
+	})
+}
+
+// runGroupOperation updates the instances of the group in the path, at most
+// max_unavailable at a time. Each instance must report healthy within
+// health_timeout before the next batch starts; the first failure stops the rollout.
+func (h *Handler) runGroupOperation(c *gin.Context, operation string, opts groupRolloutOptions, mutate func(spec *backends.InstanceSpec) error) {
+	group := c.Param("group")
+
+	if opts.MaxUnavailable < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "max_unavailable must be at least 1",
+		})
+		return
+	}
+	if opts.MaxUnavailable == 0 {
+		opts.MaxUnavailable = 1
+	}
+	healthTimeout, ok := parseGroupDuration(c, "health_timeout", opts.HealthTimeout, defaultGroupHealthTimeout)
+	if !ok {
+		return
+	}
+	drain, ok := parseGroupDuration(c, "drain", opts.Drain, 0)
+	if !ok {
+		return
+	}
+
+	members, ok := h.groupMembers(c, group)
+	if !ok {
+		return
+	}
+	// Instances reserved by agent sessions are left alone
+	members, reserved := h.unreserved(members)
+
+	h.logger.Info("Starting group operation",
+		slog.String("group", group),
+		slog.String("operation", operation),
+		slog.Int("instances", len(members)),
+		slog.Int("max_unavailable", opts.MaxUnavailable))
+
+	results := backends.RollOut(c.Request.Context(), members, opts.MaxUnavailable, func(ctx context.Context, instance *backends.InstanceStatus) error {
+		return h.rollInstance(ctx, instance, mutate, drain, healthTimeout)
+	})
+	results = append(results, reserved...)
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Result]++
+		if result.Result == backends.GroupResultFailed {
+			h.logger.Error("Group operation failed for instance",
+				slog.String("group", group),
+				slog.String("operation", operation),
+				slog.String("instance_id", result.InstanceID),
+				slog.String("error", result.Error))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group":           group,
+		"operation":       operation,
+		"max_unavailable": opts.MaxUnavailable,
+		"completed":       counts[backends.GroupResultUpdated] == len(results),
+		"updated":         counts[backends.GroupResultUpdated],
+		"failed":          counts[backends.GroupResultFailed],
+		"skipped":         counts[backends.GroupResultSkipped],
+		"results":         results,
+	})
+}
+
+// rollInstance applies mutate to the instance spec, updates the instance and
+// waits for it to report healthy. Connections are drained first if drain is set.
+func (h *Handler) rollInstance(ctx context.Context, instance *backends.InstanceStatus, mutate func(spec *backends.InstanceSpec) error, drain, healthTimeout time.Duration) error {
+	spec := unchangedSpec(instance)
+	spec.ResourceVersion = instance.ResourceVersion
+	if err := mutate(spec); err != nil {
+		return err
+	}
+	if h.variables != nil && variables.HasReferences(spec.Environment) {
+		env, err := h.variables.Resolve(ctx, spec.WorkspaceID, spec.Environment)
+		if err != nil {
+			return err
+		}
+		spec.Environment = env
+	}
+
+	// Give in-flight requests a chance to finish; the instance is being replaced anyway
+	if drain > 0 && h.containerManager != nil {
+		if _, err := h.containerManager.DrainInstance(ctx, instance.ServiceName, drain, true); err == nil {
+			defer h.containerManager.ResumeInstance(instance.ServiceName)
+		}
+	}
+
+	if err := h.backend.UpdateInstance(ctx, instance.ID, spec); err != nil {
+		return err
+	}
+	return h.waitForHealthy(ctx, instance.ID, healthTimeout)
+}
+
+// groupMembers looks up the instances of a group, responding 404 if it has none
+func (h *Handler) groupMembers(c *gin.Context, group string) ([]*backends.InstanceStatus, bool) {
+	instances, err := h.backend.ListInstances(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list instances", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_instances_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	members := backends.GroupMembers(instances, group)
+	if len(members) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "group_not_found",
+			Code:    http.StatusNotFound,
+			Message: "no instances in group " + group,
+		})
+		return nil, false
+	}
+	return members, true
+}
+
+// waitForHealthy polls the instance health check until it passes or the
+// timeout expires
+func (h *Handler) waitForHealthy(ctx context.Context, instanceID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(groupHealthPollInterval)
+	defer ticker.Stop()
+
+	lastErr := "no health check completed"
+	for {
+		result, err := h.backend.PerformHealthCheck(ctx, instanceID)
+		switch {
+		case err != nil:
+			lastErr = err.Error()
+		case result.Healthy:
+			return nil
+		case result.Error != "":
+			lastErr = result.Error
+		default:
+			lastErr = "status " + result.Status
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("instance not healthy after %s: %s", timeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// unchangedSpec builds an update spec that keeps the instance as it is,
+// including its delete protection, group and template
+func unchangedSpec(instance *backends.InstanceStatus) *backends.InstanceSpec {
+	spec := specFromInstance(instance)
+	spec.Labels = backends.SetProtected(spec.Labels, backends.IsProtected(instance.Labels))
+	spec.Labels = backends.SetGroup(spec.Labels, backends.GroupOf(instance.Labels))
+	spec.Labels = setTemplate(spec.Labels, instance.Labels[container.TemplateLabel], instance.Labels[container.TemplateVersionLabel])
+	spec.Labels = setTracking(spec.Labels, tracksTemplate(instance.Labels))
+	return spec
+}
+
+// parseGroupDuration parses an optional duration field of a group operation
+func parseGroupDuration(c *gin.Context, field, value string, fallback time.Duration) (time.Duration, bool) {
+	if value == "" {
+		return fallback, true
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: field + " must be a duration such as 30s",
+		})
+		return 0, false
+	}
+	return d, true
+}
+
+// bindOptionalJSON binds the request body if there is one
+func bindOptionalJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -1,23 +1,74 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/agentarea/mcp-manager/internal/auth"
 	"github.com/agentarea/mcp-manager/internal/backends"
 	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/egress"
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/federation"
+	"github.com/agentarea/mcp-manager/internal/logging"
+	"github.com/agentarea/mcp-manager/internal/metrics"
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/naming"
+	"github.com/agentarea/mcp-manager/internal/notify"
+	"github.com/agentarea/mcp-manager/internal/operations"
+	"github.com/agentarea/mcp-manager/internal/outbound"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/registry"
+	"github.com/agentarea/mcp-manager/internal/schemas"
+	"github.com/agentarea/mcp-manager/internal/secrets"
+	"github.com/agentarea/mcp-manager/internal/store"
+	"github.com/agentarea/mcp-manager/internal/supervisor"
 	"github.com/agentarea/mcp-manager/internal/templates"
+	"github.com/agentarea/mcp-manager/internal/variables"
 )
 
 // Handler holds the HTTP handlers and dependencies
 type Handler struct {
 	backend          backends.Backend
-	containerManager *container.Manager // Keep for backward compatibility
+	containerManager *container.Manager  // Keep for backward compatibility
+	routeManager     *proxy.RouteManager // Only set in Docker mode with the internal proxy
+	proxyBinding     *proxy.Binding      // Internal proxy listener (Docker only)
+	eventSubscriber  *events.EventSubscriber
+	eventPublisher   *events.EventPublisher
+	variables        *variables.Store            // Workspace variable sets (optional)
+	registry         *registry.Client            // Public MCP registry (optional)
+	logControl       *logging.Control            // Runtime log levels (optional)
+	supervisor       *supervisor.Supervisor      // Background components (optional)
+	storeEncryption  *store.Encrypted            // Encrypted metadata store (optional)
+	secretHealth     secrets.HealthChecker       // Secret backend health (optional)
+	hostAccess       *container.HostAccessPolicy // Host device and socket pass-through (optional)
+	outbound         *outbound.Settings          // Registered CA certificates (optional)
+	egress           *egress.Filter              // Egress DNS filtering (optional)
 	templateLoader   *templates.Loader
+	deleteIntents    *deleteIntentStore
+	upgrades         *templateUpgrades
+	reservations     *reservationStore
+	readOnly         readOnlyMode
+	handoff          *handoffCoordinator      // Coordinated restarts (optional)
+	notifier         *notify.Notifier         // Failure notifications (optional)
+	timeline         *events.TimelineRecorder // Lifecycle history of instances (optional)
+	operations       *operations.Tracker      // In-flight creates and updates (optional)
+	authenticator    *auth.Authenticator      // API keys and JWTs (optional)
+	authExempt       map[string]bool          // Routes served without credentials
+	federation       *federation.Client       // Peer managers (optional)
+	federationName   string
+	legacy           *legacyUsage
+	execEnabled      bool
 	logger           *slog.Logger
 	startTime        time.Time
 	version          string
@@ -29,55 +80,271 @@ func NewHandler(backend backends.Backend, containerManager *container.Manager, t
 		backend:          backend,
 		containerManager: containerManager,
 		templateLoader:   templateLoader,
+		deleteIntents:    newDeleteIntentStore(),
+		reservations:     newReservationStore(),
+		upgrades:         newTemplateUpgrades(),
+		legacy:           newLegacyUsage(),
 		logger:           logger,
 		startTime:        time.Now(),
 		version:          version,
 	}
 }
 
+// SetEventSubscriber exposes event processing metrics
+func (h *Handler) SetEventSubscriber(s *events.EventSubscriber) {
+	h.eventSubscriber = s
+}
+
+// SetSupervisor reports the supervised background components in /health
+func (h *Handler) SetSupervisor(s *supervisor.Supervisor) {
+	h.supervisor = s
+}
+
+// SetProxyBinding reports the internal proxy listener in /health and /ready
+func (h *Handler) SetProxyBinding(b *proxy.Binding) {
+	h.proxyBinding = b
+}
+
+// SetRouteManager sets the internal proxy route manager (Docker environments only)
+func (h *Handler) SetRouteManager(rm *proxy.RouteManager) {
+	h.routeManager = rm
+}
+
 // SetupRoutes sets up the HTTP routes
 func (h *Handler) SetupRoutes(router *gin.Engine) {
+	// Credentials are checked on every route registered below but the exempt ones
+	router.Use(h.authGuard())
+
+	// Read-only mode applies to every route registered below
+	router.Use(h.readOnlyGuard())
+
 	// OpenAPI documentation routes
 	h.SetupOpenAPIRoutes(router)
 
 	// Health check
 	router.GET("/health", h.healthCheck)
+	router.GET("/ready", h.readyCheck)
+	router.GET("/capabilities", h.getCapabilities)
 
 	// Templates
 	router.GET("/templates", h.listTemplates)
+	router.POST("/templates/reload", h.reloadTemplates)
+	router.GET("/templates/diagnostics", h.templateDiagnostics)
+	router.GET("/templates/upgrades", h.listTemplateUpgrades)
+	router.GET("/templates/warm-pools", h.listWarmPools)
+	router.POST("/templates/:id/upgrade/resume", h.resumeTemplateUpgrade)
+
+	// Template discovery from a public MCP registry
+	if h.registry != nil {
+		router.GET("/registry/search", h.searchRegistry)
+		router.POST("/registry/import", h.importFromRegistry)
+	}
+
+	// Read-only mode toggle
+	router.GET("/admin/read-only", h.getReadOnly)
+	router.PUT("/admin/read-only", h.setReadOnly)
+
+	// Connection diagnostics
+	router.GET("/admin/doctor", h.getDoctor)
+
+	// Resources left behind by interrupted creations (backends without the
+	// capability answer 501)
+	router.GET("/admin/gc", h.getGarbage)
+	router.POST("/admin/gc", h.collectGarbage)
+
+	// Runtime log levels
+	if h.logControl != nil {
+		router.GET("/admin/loglevel", h.getLogLevels)
+		router.PUT("/admin/loglevel", h.setLogLevel)
+	}
+	if h.storeEncryption != nil {
+		router.POST("/admin/store/reencrypt", h.reencryptStore)
+	}
+	if h.hostAccess != nil {
+		router.GET("/admin/host-access", h.getHostAccess)
+		router.PUT("/admin/host-access/:workspace_id", h.setHostAccessGrant)
+		router.DELETE("/admin/host-access/:workspace_id", h.deleteHostAccessGrant)
+	}
+	if h.outbound != nil {
+		router.GET("/admin/ca-certificates", h.listCACertificates)
+		router.PUT("/admin/ca-certificates/:name", h.putCACertificate)
+		router.DELETE("/admin/ca-certificates/:name", h.deleteCACertificate)
+	}
+	if h.containerManager != nil {
+		router.GET("/admin/security-report", h.getSecurityReport)
+		router.GET("/admin/legacy-containers", h.getLegacyContainers)
+		router.POST("/admin/migrate-legacy", h.migrateLegacyContainers)
+		router.GET("/admin/legacy-usage", h.getLegacyUsage)
+		router.GET("/admin/routes/sweep", h.getRouteSweep)
+		router.POST("/admin/routes/sweep", h.sweepRoutes)
+		router.GET("/admin/network", h.getNetwork)
+		router.GET("/admin/ports", h.getHostPorts)
+	}
+	if h.handoff != nil && h.containerManager != nil {
+		handoffRoutes := router.Group("/admin/handoff", h.handoffAuth)
+		handoffRoutes.POST("", h.beginHandoff)
+		handoffRoutes.POST("/complete", h.completeHandoff)
+		handoffRoutes.POST("/abort", h.abortHandoff)
+	}
+
+	// Published JSON Schemas
+	router.GET("/schemas", h.listSchemas)
+	router.GET("/schemas/:name", h.getSchema)
+	router.GET("/schemas/:name/:version", h.getSchema)
 
 	// Instance management (backend-agnostic)
 	router.GET("/instances", h.listInstances)
 	router.POST("/instances", h.createInstance)
 	router.GET("/instances/:id", h.getInstance)
+	router.GET("/instances/:id/spec", h.getInstanceSpec)
 	router.PUT("/instances/:id", h.updateInstance)
+	router.POST("/instances/:id/update", h.updateInstance)
 	router.DELETE("/instances/:id", h.deleteInstance)
+	router.POST("/instances/:id/delete-intent", h.createDeleteIntent)
+	router.POST("/instances/:id/token/rotate", h.rotateInstanceToken)
+	router.POST("/instances/:id/stop", h.stopInstance)
+	router.POST("/instances/:id/start", h.startInstance)
+	router.POST("/instances/:id/restart", h.restartInstance)
+	router.POST("/instances/:id/reserve", h.reserveInstance)
+	router.GET("/instances/:id/reserve", h.getReservation)
+	router.DELETE("/instances/:id/reserve", h.releaseReservation)
+
+	// In-flight creates and updates, cancellable while waiting
+	if h.operations != nil {
+		router.GET("/operations", h.listOperations)
+		router.GET("/operations/:id", h.getOperation)
+		router.DELETE("/operations/:id", h.cancelOperation)
+	}
+
+	// Logs, exec, stats, drift and events; backends without a capability answer 501
+	router.GET("/instances/:id/logs", h.getInstanceLogs)
+	router.POST("/instances/:id/exec", h.execInstance)
+	router.GET("/instances/:id/stats", h.getInstanceStats)
+	router.GET("/instances/:id/drift", h.getInstanceDrift)
+	router.GET("/instances/:id/events", h.watchInstance)
+
+	// Live proxied connections (only with the internal proxy)
+	if h.routeManager != nil && h.containerManager != nil {
+		router.GET("/instances/:id/connections", h.getInstanceConnections)
+	}
+
+	// DNS queries of instances with an egress allow-list
+	if h.egress != nil {
+		router.GET("/instances/:id/egress-log", h.getEgressLog)
+	}
+
+	// Lifecycle history, kept after the instance is deleted
+	if h.timeline != nil {
+		router.GET("/instances/:id/timeline", h.getInstanceTimeline)
+	}
+
+	// Health SLO compliance (computed from managed container health checks)
+	if h.containerManager != nil {
+		router.GET("/instances/:id/slo", h.getInstanceSLO)
+	}
+
+	// CRIU checkpoint and restore of managed containers
+	if h.containerManager != nil {
+		router.POST("/instances/:id/checkpoint", h.checkpointInstance)
+		router.POST("/instances/:id/restore", h.restoreInstance)
+	}
+
+	// Isolation of possibly compromised managed containers
+	if h.containerManager != nil {
+		router.POST("/instances/:id/quarantine", h.quarantineInstance)
+		router.POST("/instances/:id/unquarantine", h.unquarantineInstance)
+	}
 
 	// Instance validation
 	router.POST("/instances/validate", h.validateInstance)
 
+	// Instance groups for fleet operations
+	router.GET("/groups", h.listGroups)
+	router.GET("/groups/:group", h.getGroup)
+	router.POST("/groups/:group/restart", h.restartGroup)
+	router.POST("/groups/:group/env", h.setGroupEnvironment)
+	router.POST("/groups/:group/image", h.rollGroupImage)
+
+	// Workspace variable sets referenced as ${workspace.NAME} in instance env
+	if h.variables != nil {
+		router.GET("/workspaces/:workspace_id/variables", h.listVariables)
+		router.PUT("/workspaces/:workspace_id/variables", h.replaceVariables)
+		router.PUT("/workspaces/:workspace_id/variables/:name", h.setVariable)
+		router.DELETE("/workspaces/:workspace_id/variables/:name", h.deleteVariable)
+	}
+
+	// Slack, email and webhook notifications of instance failures
+	if h.notifier != nil {
+		router.GET("/notifications", h.getNotifications)
+		router.PUT("/notifications", h.setNotifications)
+		router.POST("/notifications/test", h.testNotification)
+	}
+
+	// Instances of this manager and its federation peers
+	if h.federation != nil {
+		router.GET("/federation/instances", h.getFederatedInstances)
+	}
+
+	// Proxied request quotas and usage per workspace (only with the internal proxy)
+	if h.routeManager != nil {
+		router.GET("/workspaces/:workspace_id/usage", h.getWorkspaceUsage)
+		router.PUT("/workspaces/:workspace_id/quota", h.setWorkspaceQuota)
+		router.DELETE("/workspaces/:workspace_id/quota", h.resetWorkspaceQuota)
+	}
+
 	// Instance monitoring and health checks
 	router.GET("/instances/:id/health", h.checkInstanceHealth)
 	router.POST("/instances/:id/health", h.healthCheckInstance)
 	router.GET("/instances/:id/health/detailed", h.getDetailedInstanceHealth)
 	router.GET("/instances/health", h.healthCheckInstances)
 	router.GET("/monitoring/status", h.getMonitoringStatus)
+	router.GET("/monitoring/fleet", h.getFleet)
 	router.GET("/monitoring/health-summary", h.getHealthSummary)
 
-	// Legacy container endpoints for backward compatibility (only when container manager is available)
+	// Internal proxy statistics (only when the internal proxy is running)
+	if h.routeManager != nil {
+		router.GET("/monitoring/proxy", h.getProxyStats)
+		router.GET("/monitoring/usage", h.getUsage)
+	}
+
+	// Host capacity and reservations used for admission
+	if h.containerManager != nil {
+		router.GET("/monitoring/capacity", h.getCapacity)
+		router.GET("/monitoring/disk", h.getDiskUsage)
+	}
+
+	// Event pipeline and creation queue metrics
+	if h.eventSubscriber != nil {
+		router.GET("/monitoring/events", h.getEventQueueStats)
+	}
+
+	// Legacy container endpoints for backward compatibility (only when container manager is available).
+	// The legacy shim counts their calls and marks them deprecated.
 	if h.containerManager != nil {
-		router.GET("/containers", h.listContainers)
-		router.POST("/containers", h.createContainer)
-		router.GET("/containers/:service", h.getContainer)
-		router.DELETE("/containers/:service", h.deleteContainer)
-		router.POST("/containers/validate", h.validateContainer)
-		router.GET("/containers/:service/health", h.checkContainerHealth)
-		router.POST("/containers/:service/health", h.healthCheckContainer)
-		router.GET("/containers/:service/health/detailed", h.getDetailedContainerHealth)
-		router.GET("/containers/health", h.healthCheckContainers)
+		legacy := router.Group("", h.legacyShim())
+		legacy.GET("/containers", h.listContainers)
+		legacy.POST("/containers", h.createContainer)
+		legacy.GET("/containers/:service", h.getContainer)
+		legacy.DELETE("/containers/:service", h.deleteContainer)
+		legacy.POST("/containers/validate", h.validateContainer)
+		legacy.GET("/containers/:service/health", h.checkContainerHealth)
+		legacy.POST("/containers/:service/health", h.healthCheckContainer)
+		legacy.GET("/containers/:service/health/detailed", h.getDetailedContainerHealth)
+		legacy.GET("/containers/health", h.healthCheckContainers)
 	}
 }
 
+// getCapabilities returns the optional capabilities of the backend and, in
+// Docker mode, how instance routes are served and the runtime is reached
+func (h *Handler) getCapabilities(c *gin.Context) {
+	response := gin.H{"capabilities": backends.Capabilities(h.backend)}
+	if h.containerManager != nil {
+		response["routing"] = h.containerManager.Routing()
+		response["runtime_client"] = h.containerManager.RuntimeClient()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 // healthCheck returns the health status of the service
 func (h *Handler) healthCheck(c *gin.Context) {
 	// Get instance count from backend
@@ -98,12 +365,76 @@ func (h *Handler) healthCheck(c *gin.Context) {
 		ContainersRunning: instancesRunning, // Keep field name for backward compatibility
 		Timestamp:         time.Now(),
 		Uptime:            uptime,
+		Capabilities:      backends.Capabilities(h.backend),
 	}
 
-	c.JSON(http.StatusOK, response)
+	code := http.StatusOK
+	if h.supervisor != nil {
+		response.Components = h.supervisor.Statuses()
+		response.Status = componentHealth(response.Components)
+		if response.Status == "unhealthy" {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	if h.proxyBinding != nil {
+		// Routing is down while rebinding and has moved while on the fallback port
+		status := h.proxyBinding.Status()
+		response.Proxy = &status
+		if response.Status == "healthy" && (status.State != models.ProxyServing || status.Fallback) {
+			response.Status = "degraded"
+		}
+	}
+
+	c.JSON(code, response)
+}
+
+// readyCheck reports whether the manager can serve traffic: no critical
+// component has failed and, in Docker mode, the proxy is listening
+func (h *Handler) readyCheck(c *gin.Context) {
+	reasons := []string{}
+	if h.supervisor != nil {
+		for _, component := range h.supervisor.Statuses() {
+			if component.Critical && component.State == models.ComponentFailed {
+				reasons = append(reasons, "component "+component.Name+" failed: "+component.LastError)
+			}
+		}
+	}
+	if h.proxyBinding != nil {
+		if status := h.proxyBinding.Status(); status.State != models.ProxyServing {
+			reason := "proxy is " + string(status.State)
+			if status.LastError != "" {
+				reason += ": " + status.LastError
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if len(reasons) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ready": false, "reasons": reasons})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ready": true})
+}
+
+// componentHealth is the overall status given by background components. A
+// failed critical component is about to stop the process; other failures
+// leave the service running without the component.
+func componentHealth(components []models.ComponentStatus) string {
+	status := "healthy"
+	for _, component := range components {
+		switch {
+		case component.State == models.ComponentFailed && component.Critical:
+			return "unhealthy"
+		case component.State == models.ComponentFailed || component.State == models.ComponentRestarting:
+			status = "degraded"
+		}
+	}
+	return status
 }
 
-// listTemplates returns a list of available MCP templates
+// listTemplates returns a list of available MCP templates. With
+// ?image_metadata=true each template carries the mcp.* labels of its image,
+// for images present locally.
 func (h *Handler) listTemplates(c *gin.Context) {
 	if h.templateLoader == nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -114,6 +445,11 @@ func (h *Handler) listTemplates(c *gin.Context) {
 	}
 
 	templates := h.templateLoader.List()
+	if c.Query("image_metadata") == "true" {
+		for i := range templates {
+			templates[i].ImageMetadata = h.imageMetadata(c, templates[i].DockerImage, false)
+		}
+	}
 	response := gin.H{
 		"templates": templates,
 		"total":     len(templates),
@@ -124,132 +460,914 @@ func (h *Handler) listTemplates(c *gin.Context) {
 
 // Backend-agnostic instance management methods
 
+// listSchemas returns the published JSON Schemas
+func (h *Handler) listSchemas(c *gin.Context) {
+	list := schemas.List()
+	c.JSON(http.StatusOK, gin.H{
+		"schemas": list,
+		"total":   len(list),
+	})
+}
+
+// getSchema returns a JSON Schema document, defaulting to the current version
+func (h *Handler) getSchema(c *gin.Context) {
+	name, version := c.Param("name"), c.Param("version")
+	if version == "" {
+		version = schemas.CurrentVersion
+	}
+
+	raw, ok := schemas.Raw(name, version)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "schema_not_found",
+			Code:    http.StatusNotFound,
+			Message: "unknown schema " + name + "/" + version,
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/schema+json", raw)
+}
+
 // listInstances returns a list of all managed instances
 func (h *Handler) listInstances(c *gin.Context) {
+	// Optional label selector, e.g. ?label=team%3Dml&label=env!%3Dprod
+	selector, err := backends.ParseLabelSelector(c.QueryArray("label"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_label_selector",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	instances, err := h.backend.ListInstances(c.Request.Context())
 	if err != nil {
-		h.logger.Error("Failed to list instances", slog.String("error", err.Error()))
+		h.logger.Error("Failed to list instances", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_instances_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	instances = backends.FilterByLabels(instances, selector)
+
+	// ?outdated=true keeps instances created from an older template version
+	if c.Query("outdated") == "true" {
+		outdated := make([]*backends.InstanceStatus, 0)
+		for _, instance := range instances {
+			if h.isOutdated(instance) {
+				outdated = append(outdated, instance)
+			}
+		}
+		instances = outdated
+	}
+
+	response := gin.H{
+		"instances": instances,
+		"total":     len(instances),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// createInstance creates a new MCP server instance
+func (h *Handler) createInstance(c *gin.Context) {
+	var req struct {
+		InstanceID  string            `json:"instance_id" binding:"required"`
+		Name        string            `json:"name" binding:"required"`
+		ServiceName string            `json:"service_name" binding:"required"`
+		Image       string            `json:"image" binding:"required"`
+		Port        int               `json:"port"`
+		Command     []string          `json:"command,omitempty"`
+		Environment map[string]string `json:"environment,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Protected   bool              `json:"protected,omitempty"`
+		Group       string            `json:"group,omitempty"`
+		Template    string            `json:"template,omitempty"`
+		// TrackTemplate opts into automatic rolling upgrades when the template changes
+		TrackTemplate bool   `json:"track_template,omitempty"`
+		Auth          *bool  `json:"auth,omitempty"`
+		WorkspaceID   string `json:"workspace_id" binding:"required"`
+		Resources     struct {
+			Requests backends.ResourceList `json:"requests,omitempty"`
+			Limits   backends.ResourceList `json:"limits,omitempty"`
+		} `json:"resources,omitempty"`
+		Bandwidth struct {
+			Ingress string `json:"ingress,omitempty"`
+			Egress  string `json:"egress,omitempty"`
+		} `json:"bandwidth,omitempty"`
+		container.RuntimeOptions
+		container.DNSOptions
+		container.HostAccess
+		container.Attribution
+		EgressAllow []string `json:"egress_allow,omitempty"`
+		// HostPort publishes the instance's port on the host; 0 picks a free one
+		HostPort *int `json:"host_port,omitempty"`
+		// Compression false opts the instance out of proxy response compression
+		Compression  *bool                   `json:"compression,omitempty"`
+		StartupProbe *container.StartupProbe `json:"startup_probe,omitempty"`
+		// Scratch is an empty, size-limited writable directory for the instance
+		Scratch *container.ScratchVolume `json:"scratch,omitempty"`
+		// Warmup requests run once the instance is ready, before its route is published
+		Warmup []container.WarmupRequest `json:"warmup,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for _, name := range []string{req.Name, req.ServiceName} {
+		if err := naming.Validate(name); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_name",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := backends.ValidateLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_labels",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.Group != "" {
+		if err := backends.ValidateGroupName(req.Group); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_group",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+		req.Labels = backends.SetGroup(req.Labels, req.Group)
+	}
+
+	if req.Template != "" {
+		version, ok := h.templateVersion(req.Template)
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "unknown_template",
+				Code:    http.StatusBadRequest,
+				Message: "template " + req.Template + " is not in the catalog",
+			})
+			return
+		}
+		req.Labels = setTemplate(req.Labels, req.Template, version)
+		req.Labels = setTracking(req.Labels, req.TrackTemplate)
+	} else if req.TrackTemplate {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "track_template requires template",
+		})
+		return
+	}
+
+	if req.Protected {
+		req.Labels = backends.SetProtected(req.Labels, true)
+	}
+	req.Labels = container.SetAttribution(req.Labels, req.WorkspaceID, req.Attribution)
+
+	var err error
+	if req.Labels, err = container.SetBandwidth(req.Labels, req.Bandwidth.Ingress, req.Bandwidth.Egress); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_bandwidth",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.Labels, err = container.SetRuntimeOptions(req.Labels, req.RuntimeOptions); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_runtime_options",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.Labels, err = container.SetDNSOptions(req.Labels, req.DNSOptions); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_dns",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.Labels, err = container.SetScratch(req.Labels, req.Scratch); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_scratch",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !req.HostAccess.IsZero() {
+		if err := h.hostAccess.Check(req.WorkspaceID, req.HostAccess); err != nil {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "host_access_denied",
+				Code:    http.StatusForbidden,
+				Message: err.Error(),
+			})
+			return
+		}
+		req.Labels = container.SetHostAccess(req.Labels, req.HostAccess)
+	}
+	if req.HostPort != nil {
+		if !h.checkHostPort(c, req.ServiceName, req.HostPort) {
+			return
+		}
+		req.Labels, _ = container.SetHostPort(req.Labels, req.HostPort)
+	}
+	req.Labels = container.SetCompression(req.Labels, req.Compression)
+	if req.Labels, err = container.SetStartupProbe(req.Labels, req.StartupProbe); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_startup_probe",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.Labels, err = container.SetWarmup(req.Labels, req.Warmup); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_warmup",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(req.EgressAllow) > 0 {
+		if h.egress == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "egress_filter_disabled",
+				Code:    http.StatusBadRequest,
+				Message: container.ErrEgressFilterDisabled.Error(),
+			})
+			return
+		}
+		if req.Labels, err = container.SetEgressAllow(req.Labels, req.EgressAllow); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_egress_allow",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Fill in what the image declares in its mcp.* labels and configuration
+	metadata := h.imageMetadata(c, req.Image, true)
+	if req.Port == 0 {
+		req.Port, _ = container.DefaultPort(metadata)
+	}
+	if metadata != nil && container.HealthPath(req.Labels) == "" {
+		req.Labels = container.SetHealthPath(req.Labels, metadata.HealthPath)
+	}
+
+	// Every instance gets a bearer token unless auth is explicitly disabled
+	if req.Auth == nil || *req.Auth {
+		if req.Environment == nil {
+			req.Environment = make(map[string]string)
+		}
+		if err := container.EnsureAuthToken(req.Environment); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "instance_creation_failed",
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Create instance spec
+	spec := &backends.InstanceSpec{
+		InstanceID:  req.InstanceID,
+		Name:        req.Name,
+		ServiceName: req.ServiceName,
+		Image:       req.Image,
+		Port:        req.Port,
+		Command:     req.Command,
+		Environment: req.Environment,
+		Labels:      req.Labels,
+		WorkspaceID: req.WorkspaceID,
+		Resources: backends.ResourceRequirements{
+			Requests: req.Resources.Requests,
+			Limits:   req.Resources.Limits,
+		},
+	}
+
+	if !h.resolveVariables(c, spec) {
+		return
+	}
+	if err := container.CheckImageRequirements(metadata, spec.Environment); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "image_requirements_not_met",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx, op, done, ok := h.startOperation(c, operations.KindCreate, spec.InstanceID, spec.ServiceName)
+	if !ok {
+		return
+	}
+	defer done()
+
+	start := time.Now()
+	result, err := h.backend.CreateInstance(ctx, spec)
+	metrics.ObserveInstanceOperation("create", metrics.SourceAPI, start, err)
+	if err != nil && operations.Cancelled(ctx) {
+		h.rollbackOperation(c, op, nil)
+		return
+	}
+	if errors.Is(err, container.ErrInsufficientCapacity) {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "insufficient_capacity",
+			Code:    http.StatusServiceUnavailable,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, container.ErrInvalidCommand) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_command",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, container.ErrHostPortConflict) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "host_port_conflict",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, container.ErrHostPortsDisabled) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "host_ports_disabled",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create instance", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "instance_creation_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// The token is redacted from every other response, so this is the one
+	// place the client learns it (besides rotating it)
+	result.AuthToken = spec.Environment[container.AuthTokenEnv]
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// getInstance returns details of a specific instance
+func (h *Handler) getInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		h.logger.Error("Failed to get instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	setETag(c, instance.ResourceVersion)
+	c.JSON(http.StatusOK, instance)
+}
+
+// updateInstance updates an existing instance
+func (h *Handler) updateInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+	dryRun := c.Query("dry_run") == "true"
+
+	// Updates must be based on a known version to avoid lost updates.
+	// Dry runs apply nothing, so the precondition is optional there.
+	expectedVersion := ifMatchVersion(c)
+	if expectedVersion == "" && !dryRun {
+		c.JSON(http.StatusPreconditionRequired, models.ErrorResponse{
+			Error:   "precondition_required",
+			Code:    http.StatusPreconditionRequired,
+			Message: "If-Match header with the instance resource_version (ETag) is required",
+		})
+		return
+	}
+
+	var req struct {
+		Image       string            `json:"image,omitempty"`
+		Port        int               `json:"port,omitempty"`
+		Command     []string          `json:"command,omitempty"`
+		Environment map[string]string `json:"environment,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Protected   *bool             `json:"protected,omitempty"`
+		Group       *string           `json:"group,omitempty"`
+		// UpgradeTemplate moves the instance to the current version of its
+		// template, including the template image unless image is set
+		UpgradeTemplate bool  `json:"upgrade_template,omitempty"`
+		TrackTemplate   *bool `json:"track_template,omitempty"`
+		Compression     *bool `json:"compression,omitempty"`
+		Resources       struct {
+			Requests backends.ResourceList `json:"requests,omitempty"`
+			Limits   backends.ResourceList `json:"limits,omitempty"`
+		} `json:"resources,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := backends.ValidateLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_labels",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// An empty group removes the instance from its group
+	if req.Group != nil && *req.Group != "" {
+		if err := backends.ValidateGroupName(*req.Group); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_group",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Get current instance to fill in missing fields
+	currentInstance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Instances reserved by an agent session are only updated by that session
+	if !dryRun && !h.checkReservation(c, currentInstance) {
+		return
+	}
+
+	if expectedVersion != "" && expectedVersion != currentInstance.ResourceVersion {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "resource_version_conflict",
+			Code:    http.StatusConflict,
+			Message: "instance has been modified; current resource_version is " + currentInstance.ResourceVersion,
+		})
+		return
+	}
+
+	// Create update spec with current values as defaults
+	spec := specFromInstance(currentInstance)
+	spec.ResourceVersion = expectedVersion
+	// What a cancelled update is rolled back to
+	previous := specFromInstance(currentInstance)
+	previous.Environment = maps.Clone(previous.Environment)
+
+	// Apply updates
+	if req.Image != "" {
+		spec.Image = req.Image
+	}
+	if req.Port != 0 {
+		spec.Port = req.Port
+	}
+	if req.Command != nil {
+		spec.Command = req.Command
+	}
+	if req.Environment != nil {
+		// Replacing the environment must not silently drop or rotate the auth
+		// token, including when the redacted value of a GET is sent back
+		if token := currentInstance.Environment[container.AuthTokenEnv]; token != "" {
+			if value, set := req.Environment[container.AuthTokenEnv]; !set || value == redact.Placeholder {
+				req.Environment[container.AuthTokenEnv] = token
+			}
+		}
+		spec.Environment = req.Environment
+	}
+	if req.Labels != nil {
+		spec.Labels = req.Labels
+	}
+
+	// Delete protection is kept across updates unless explicitly changed
+	protected := backends.IsProtected(currentInstance.Labels)
+	if req.Protected != nil {
+		protected = *req.Protected
+	}
+	spec.Labels = backends.SetProtected(spec.Labels, protected)
+
+	// Likewise group membership
+	group := backends.GroupOf(currentInstance.Labels)
+	if req.Group != nil {
+		group = *req.Group
+	}
+	spec.Labels = backends.SetGroup(spec.Labels, group)
+
+	// And the template the instance was created from
+	templateKey := currentInstance.Labels[container.TemplateLabel]
+	templateVersion := currentInstance.Labels[container.TemplateVersionLabel]
+	if req.UpgradeTemplate {
+		var template models.MCPProviderTemplate
+		ok := templateKey != "" && h.templateLoader != nil
+		if ok {
+			template, ok = h.templateLoader.Get(templateKey)
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "unknown_template",
+				Code:    http.StatusBadRequest,
+				Message: "instance was not created from a template in the catalog",
+			})
+			return
+		}
+		templateVersion = templates.VersionOf(template)
+		if req.Image == "" && template.DockerImage != "" {
+			spec.Image = template.DockerImage
+		}
+	}
+	spec.Labels = setTemplate(spec.Labels, templateKey, templateVersion)
+
+	track := tracksTemplate(currentInstance.Labels)
+	if req.TrackTemplate != nil {
+		track = *req.TrackTemplate
+	}
+	spec.Labels = setTracking(spec.Labels, track && templateKey != "")
+
+	// And the cost attribution, following the template
+	workspace, attribution := container.AttributionFromLabels(currentInstance.Labels)
+	spec.Labels = container.SetAttribution(spec.Labels, workspace, attribution)
+
+	// And the compression switch
+	compression := container.CompressionFromLabels(currentInstance.Labels)
+	if req.Compression != nil {
+		compression = *req.Compression
+	}
+	spec.Labels = container.SetCompression(spec.Labels, &compression)
+
+	// Update resources
+	spec.Resources = backends.ResourceRequirements{
+		Requests: req.Resources.Requests,
+		Limits:   req.Resources.Limits,
+	}
+
+	if !h.resolveVariables(c, spec) {
+		return
+	}
+
+	if dryRun {
+		h.respondUpdatePlan(c, currentInstance, spec)
+		return
+	}
+
+	resume, ok := h.drainForLifecycle(c, instanceID)
+	if !ok {
+		return
+	}
+	defer resume()
+
+	ctx, op, done, ok := h.startOperation(c, operations.KindUpdate, instanceID, currentInstance.ServiceName)
+	if !ok {
+		return
+	}
+	defer done()
+
+	changes := backends.DiffInstance(currentInstance, spec)
+	err = h.backend.UpdateInstance(ctx, instanceID, spec)
+	if err != nil && operations.Cancelled(ctx) {
+		h.rollbackOperation(c, op, previous)
+		return
+	}
+	if errors.Is(err, backends.ErrConflict) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "resource_version_conflict",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "instance_update_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	events.RecordStep(currentInstance.Environment["MCP_INSTANCE_ID"], currentInstance.ServiceName, events.StepUpdated, changedFields(changes))
+
+	response := gin.H{
+		"message":     "Instance updated successfully",
+		"instance_id": instanceID,
+	}
+	if updated, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID); err == nil {
+		setETag(c, updated.ResourceVersion)
+		response["resource_version"] = updated.ResourceVersion
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// specFromInstance builds an update spec that keeps the instance as it is
+func specFromInstance(instance *backends.InstanceStatus) *backends.InstanceSpec {
+	return &backends.InstanceSpec{
+		InstanceID:  instance.ID,
+		Name:        instance.Name,
+		ServiceName: instance.ServiceName,
+		Image:       instance.Image,
+		Port:        instance.Port,
+		Environment: instance.Environment,
+		Labels:      backends.UserLabels(instance.Labels),
+		WorkspaceID: instance.Environment[container.WorkspaceIDEnv],
+	}
+}
+
+// rotateInstanceToken replaces the instance bearer token. The old token stops
+// working once the instance has been updated; Docker containers are recreated.
+func (h *Handler) rotateInstanceToken(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	currentInstance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !h.checkReservation(c, currentInstance) {
+		return
+	}
+
+	token, err := container.NewAuthToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "token_rotation_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	spec := unchangedSpec(currentInstance)
+	spec.ResourceVersion = currentInstance.ResourceVersion
+	spec.Environment = make(map[string]string, len(currentInstance.Environment)+1)
+	for k, v := range currentInstance.Environment {
+		spec.Environment[k] = v
+	}
+	spec.Environment[container.AuthTokenEnv] = token
+
+	resume, ok := h.drainForLifecycle(c, instanceID)
+	if !ok {
+		return
+	}
+	defer resume()
+
+	err = h.backend.UpdateInstance(c.Request.Context(), instanceID, spec)
+	if errors.Is(err, backends.ErrConflict) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "resource_version_conflict",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to rotate instance token", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "list_instances_failed",
+			Error:   "token_rotation_failed",
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
+	h.logger.Info("Rotated instance auth token", slog.String("instance_id", instanceID))
+	events.RecordStep(currentInstance.Environment["MCP_INSTANCE_ID"], currentInstance.ServiceName, events.StepUpdated, "auth token rotated")
+
 	response := gin.H{
-		"instances": instances,
-		"total":     len(instances),
+		"instance_id": instanceID,
+		"auth_token":  token,
+	}
+	if updated, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID); err == nil {
+		setETag(c, updated.ResourceVersion)
+		response["resource_version"] = updated.ResourceVersion
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// createInstance creates a new MCP server instance
-func (h *Handler) createInstance(c *gin.Context) {
-	var req struct {
-		InstanceID  string            `json:"instance_id" binding:"required"`
-		Name        string            `json:"name" binding:"required"`
-		ServiceName string            `json:"service_name" binding:"required"`
-		Image       string            `json:"image" binding:"required"`
-		Port        int               `json:"port"`
-		Command     []string          `json:"command,omitempty"`
-		Environment map[string]string `json:"environment,omitempty"`
-		WorkspaceID string            `json:"workspace_id" binding:"required"`
-		Resources   struct {
-			Requests backends.ResourceList `json:"requests,omitempty"`
-			Limits   backends.ResourceList `json:"limits,omitempty"`
-		} `json:"resources,omitempty"`
+// respondUpdatePlan reports what an update would change without applying it
+func (h *Handler) respondUpdatePlan(c *gin.Context, current *backends.InstanceStatus, spec *backends.InstanceSpec) {
+	changes := backends.DiffInstance(current, spec)
+
+	impact := backends.UpdateImpact{Strategy: "unknown", RestartRequired: len(changes) > 0}
+	if planner, ok := h.backend.(backends.UpdatePlanner); ok {
+		impact = planner.PlanUpdate(current, spec, changes)
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Code:    http.StatusBadRequest,
+	setETag(c, current.ResourceVersion)
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":                    true,
+		"instance_id":                current.ID,
+		"resource_version":           current.ResourceVersion,
+		"changes":                    changes,
+		"strategy":                   impact.Strategy,
+		"restart_required":           impact.RestartRequired,
+		"recreate":                   impact.Recreate,
+		"estimated_downtime_seconds": int(impact.EstimatedDowntime.Seconds()),
+	})
+}
+
+// setETag sets the ETag header from an instance resource version
+func setETag(c *gin.Context, version string) {
+	if version != "" {
+		c.Header("ETag", strconv.Quote(version))
+	}
+}
+
+// ifMatchVersion extracts the resource version from the If-Match header
+func ifMatchVersion(c *gin.Context) string {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	value = strings.TrimPrefix(value, "W/")
+	return strings.Trim(value, "\"")
+}
+
+// deleteInstance removes an instance
+func (h *Handler) deleteInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	// The reservation and protection checks need the instance, so a failed
+	// lookup must not fall through to the delete
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	switch {
+	case errors.Is(err, backends.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to look up instance for deletion", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "instance_lookup_failed",
+			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
+	if !h.checkReservation(c, instance) {
+		return
+	}
 
-	// Set default port if not specified
-	if req.Port == 0 {
-		req.Port = 8000
+	// Protected instances require a token from POST /instances/:id/delete-intent
+	if backends.IsProtected(instance.Labels) {
+		if !h.deleteIntents.consume(instanceID, confirmationToken(c)) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "instance_protected",
+				Code:    http.StatusConflict,
+				Message: "instance is protected; obtain a confirmation token from POST /instances/" + instanceID + "/delete-intent and pass it as X-Confirmation-Token",
+			})
+			return
+		}
+		h.logger.Info("Deleting protected instance with confirmation token",
+			slog.String("instance_id", instanceID))
 	}
 
-	// Create instance spec
-	spec := &backends.InstanceSpec{
-		InstanceID:  req.InstanceID,
-		Name:        req.Name,
-		ServiceName: req.ServiceName,
-		Image:       req.Image,
-		Port:        req.Port,
-		Command:     req.Command,
-		Environment: req.Environment,
-		WorkspaceID: req.WorkspaceID,
-		Resources: backends.ResourceRequirements{
-			Requests: req.Resources.Requests,
-			Limits:   req.Resources.Limits,
-		},
+	resume, ok := h.drainForLifecycle(c, instanceID)
+	if !ok {
+		return
 	}
+	defer resume()
 
-	result, err := h.backend.CreateInstance(c.Request.Context(), spec)
+	start := time.Now()
+	err = h.backend.DeleteInstance(c.Request.Context(), instanceID)
+	metrics.ObserveInstanceOperation("delete", metrics.SourceAPI, start, err)
 	if err != nil {
-		h.logger.Error("Failed to create instance", slog.String("error", err.Error()))
+		h.logger.Error("Failed to delete instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "instance_creation_failed",
+			Error:   "instance_deletion_failed",
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, result)
+	h.reservations.release(instance.ServiceName, "")
+	events.RecordStep(instance.Environment["MCP_INSTANCE_ID"], instance.ServiceName, events.StepDeleted, "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Instance deleted successfully",
+		"instance_id": instanceID,
+	})
 }
 
-// getInstance returns details of a specific instance
-func (h *Handler) getInstance(c *gin.Context) {
-	instanceID := c.Param("id")
+// drainForLifecycle drains an instance's proxied connections before a
+// restart, update or delete when the request sets ?drain=<duration>. Unless
+// ?force=true closes the stragglers, it responds 409 and returns false if
+// connections are still open after the timeout. The returned func re-admits
+// traffic and must be called once the operation is done.
+func (h *Handler) drainForLifecycle(c *gin.Context, instanceID string) (func(), bool) {
+	noop := func() {}
+
+	drain := c.Query("drain")
+	if drain == "" {
+		return noop, true
+	}
+	timeout, err := time.ParseDuration(drain)
+	if err != nil || timeout < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "drain must be a duration such as 30s",
+		})
+		return noop, false
+	}
 
+	// Connections are only tracked by the internal proxy
+	if h.containerManager == nil {
+		return noop, true
+	}
 	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
 	if err != nil {
-		h.logger.Error("Failed to get instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error:   "instance_not_found",
-			Code:    http.StatusNotFound,
-			Message: err.Error(),
+		// Let the operation itself report the missing instance
+		return noop, true
+	}
+
+	force := c.Query("force") == "true"
+	result, err := h.containerManager.DrainInstance(c.Request.Context(), instance.ServiceName, timeout, force)
+	if err != nil {
+		return noop, true
+	}
+	resume := func() { h.containerManager.ResumeInstance(instance.ServiceName) }
+
+	if !result.Drained && !force {
+		resume()
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "connections_active",
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("%d connection(s) still open after %s; retry later or pass force=true to close them", result.Remaining, timeout),
 		})
-		return
+		return noop, false
 	}
 
-	c.JSON(http.StatusOK, instance)
+	return resume, true
 }
 
-// updateInstance updates an existing instance
-func (h *Handler) updateInstance(c *gin.Context) {
+// getInstanceConnections lists the in-flight proxied requests and streams of an instance
+func (h *Handler) getInstanceConnections(c *gin.Context) {
 	instanceID := c.Param("id")
 
-	var req struct {
-		Image       string            `json:"image,omitempty"`
-		Port        int               `json:"port,omitempty"`
-		Command     []string          `json:"command,omitempty"`
-		Environment map[string]string `json:"environment,omitempty"`
-		Resources   struct {
-			Requests backends.ResourceList `json:"requests,omitempty"`
-			Limits   backends.ResourceList `json:"limits,omitempty"`
-		} `json:"resources,omitempty"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Code:    http.StatusBadRequest,
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Get current instance to fill in missing fields
-	currentInstance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	ctr, err := h.containerManager.GetContainer(instance.ServiceName)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{
 			Error:   "instance_not_found",
@@ -259,72 +1377,60 @@ func (h *Handler) updateInstance(c *gin.Context) {
 		return
 	}
 
-	// Create update spec with current values as defaults
-	spec := &backends.InstanceSpec{
-		InstanceID:  currentInstance.ID,
-		Name:        currentInstance.Name,
-		ServiceName: currentInstance.ServiceName,
-		Image:       currentInstance.Image,
-		Port:        currentInstance.Port,
-		Environment: currentInstance.Environment,
-		WorkspaceID: "", // This should come from the current instance context
+	connections := h.routeManager.Connections(ctr.Slug)
+	streams := 0
+	for _, conn := range connections {
+		if conn.Stream {
+			streams++
+		}
 	}
 
-	// Apply updates
-	if req.Image != "" {
-		spec.Image = req.Image
-	}
-	if req.Port != 0 {
-		spec.Port = req.Port
-	}
-	if req.Command != nil {
-		spec.Command = req.Command
-	}
-	if req.Environment != nil {
-		spec.Environment = req.Environment
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"slug":        ctr.Slug,
+		"connections": connections,
+		"total":       len(connections),
+		"streams":     streams,
+		"timestamp":   time.Now(),
+	})
+}
 
-	// Update resources
-	spec.Resources = backends.ResourceRequirements{
-		Requests: req.Resources.Requests,
-		Limits:   req.Resources.Limits,
-	}
+// getInstanceSLO reports how an instance meets its health SLO
+func (h *Handler) getInstanceSLO(c *gin.Context) {
+	instanceID := c.Param("id")
 
-	err = h.backend.UpdateInstance(c.Request.Context(), instanceID, spec)
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
 	if err != nil {
-		h.logger.Error("Failed to update instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "instance_update_failed",
-			Code:    http.StatusInternalServerError,
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Instance updated successfully",
-		"instance_id": instanceID,
-	})
-}
-
-// deleteInstance removes an instance
-func (h *Handler) deleteInstance(c *gin.Context) {
-	instanceID := c.Param("id")
-
-	err := h.backend.DeleteInstance(c.Request.Context(), instanceID)
+	status, err := h.containerManager.SLOStatus(instance.ServiceName)
+	if errors.Is(err, container.ErrNoSLO) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "slo_not_defined",
+			Code:    http.StatusNotFound,
+			Message: "instance and its template define no slo",
+		})
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to delete instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "instance_deletion_failed",
-			Code:    http.StatusInternalServerError,
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
 			Message: err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "Instance deleted successfully",
 		"instance_id": instanceID,
+		"slo":         status,
+		"timestamp":   time.Now(),
 	})
 }
 
@@ -338,6 +1444,8 @@ func (h *Handler) validateInstance(c *gin.Context) {
 		Port        int               `json:"port"`
 		Command     []string          `json:"command,omitempty"`
 		Environment map[string]string `json:"environment,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Protected   bool              `json:"protected,omitempty"`
 		WorkspaceID string            `json:"workspace_id" binding:"required"`
 		DryRun      bool              `json:"dry_run"`
 	}
@@ -356,23 +1464,61 @@ func (h *Handler) validateInstance(c *gin.Context) {
 	errors := []string{}
 	warnings := []string{}
 
+	metadata := h.imageMetadata(c, req.Image, false)
+	var defaults *container.SpecDefaults
 	if req.Port == 0 {
-		req.Port = 8000
-		warnings = append(warnings, "Port not specified, using default port 8000")
+		defaults = &container.SpecDefaults{}
+		defaults.Port, defaults.PortSource = container.DefaultPort(metadata)
+		req.Port = defaults.Port
+		if defaults.PortSource == container.PortSourceDefault {
+			warnings = append(warnings, fmt.Sprintf("Port not specified, using default port %d", req.Port))
+		}
+	} else if metadata != nil && len(metadata.ExposedPorts) > 0 && !slices.Contains(metadata.ExposedPorts, req.Port) {
+		warnings = append(warnings, fmt.Sprintf("Port %d is not exposed by image %s", req.Port, req.Image))
+	}
+	if len(req.Command) == 0 && metadata != nil {
+		if defaults == nil {
+			defaults = &container.SpecDefaults{}
+		}
+		defaults.Command = append(append([]string{}, metadata.Entrypoint...), metadata.Command...)
 	}
 
 	if req.Port < 1 || req.Port > 65535 {
 		errors = append(errors, "Port must be between 1 and 65535")
 	}
 
+	for _, name := range []string{req.Name, req.ServiceName} {
+		if err := naming.Validate(name); err != nil {
+			errors = append(errors, err.Error())
+		} else if naming.Shortened(name) {
+			warnings = append(warnings, fmt.Sprintf("Name %q will be shortened to %d characters with a hash suffix", name, naming.MaxLabelLength))
+		}
+	}
+
 	// Basic image validation
 	if req.Image == "" {
 		errors = append(errors, "Image is required")
 	}
 
+	environment := make(map[string]string, len(req.Environment)+4)
+	for name, value := range req.Environment {
+		environment[name] = value
+	}
+	environment["MCP_INSTANCE_ID"] = req.InstanceID
+	environment["MCP_SERVICE_NAME"] = req.ServiceName
+	environment["MCP_CONTAINER_PORT"] = strconv.Itoa(req.Port)
+	environment[container.WorkspaceIDEnv] = req.WorkspaceID
+	vars := container.NewCommandVars(req.InstanceID, req.ServiceName, req.Port, req.WorkspaceID, environment)
+	if _, err := container.ExpandCommand(req.Command, vars); err != nil {
+		errors = append(errors, "Invalid command: "+err.Error())
+	}
+	if err := container.CheckImageRequirements(metadata, environment); err != nil {
+		errors = append(errors, err.Error())
+	}
+
 	valid := len(errors) == 0
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"valid":          valid,
 		"errors":         errors,
 		"warnings":       warnings,
@@ -380,7 +1526,30 @@ func (h *Handler) validateInstance(c *gin.Context) {
 		"can_pull":       true, // Would need to check this against the backend
 		"estimated_size": "unknown",
 		"timestamp":      time.Now(),
-	})
+	}
+	if metadata != nil {
+		response["image_metadata"] = metadata
+	}
+	if defaults != nil {
+		response["defaults"] = defaults
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// imageMetadata reads the mcp.* labels and configuration of an image if the
+// backend can, or returns nil. Failures are logged; the runtime reports an image it cannot
+// pull when the instance is created.
+func (h *Handler) imageMetadata(c *gin.Context, image string, pull bool) *models.ImageMetadata {
+	inspector, ok := h.backend.(backends.ImageInspector)
+	if !ok || image == "" {
+		return nil
+	}
+	metadata, err := inspector.ImageMetadata(c.Request.Context(), image, pull)
+	if err != nil {
+		h.logger.Warn("Failed to inspect image", slog.String("image", image), slog.String("error", err.Error()))
+		return nil
+	}
+	return metadata
 }
 
 // checkInstanceHealth checks if a specific instance is healthy
@@ -457,6 +1626,8 @@ func (h *Handler) getDetailedInstanceHealth(c *gin.Context) {
 		"service_name":   instance.ServiceName,
 		"status":         instance.Status,
 		"healthy":        healthResult.Healthy,
+		"live":           healthResult.Live,
+		"ready":          healthResult.Ready,
 		"http_reachable": healthResult.HTTPReachable,
 		"response_time":  healthResult.ResponseTime,
 		"timestamp":      healthResult.Timestamp,
@@ -527,6 +1698,9 @@ func (h *Handler) healthCheckInstances(c *gin.Context) {
 // listContainers returns a list of all managed containers
 func (h *Handler) listContainers(c *gin.Context) {
 	containers := h.containerManager.ListContainers()
+	for i := range containers {
+		containers[i] = redactContainer(containers[i])
+	}
 
 	response := models.ListContainersResponse{
 		Containers: containers,
@@ -549,7 +1723,39 @@ func (h *Handler) createContainer(c *gin.Context) {
 	}
 
 	// Create container (Traefik routing is handled automatically via labels)
-	container, err := h.containerManager.CreateContainer(c.Request.Context(), req)
+	created, err := h.containerManager.CreateContainer(c.Request.Context(), req)
+	if errors.Is(err, container.ErrInsufficientCapacity) {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "insufficient_capacity",
+			Code:    http.StatusServiceUnavailable,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, container.ErrInvalidCommand) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_command",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, container.ErrHostPortConflict) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "host_port_conflict",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, container.ErrHostPortsDisabled) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "host_ports_disabled",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "container_creation_failed",
@@ -559,7 +1765,7 @@ func (h *Handler) createContainer(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, container)
+	c.JSON(http.StatusCreated, redactContainer(*created))
 }
 
 // getContainer returns details of a specific container
@@ -576,7 +1782,16 @@ func (h *Handler) getContainer(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, container)
+	c.JSON(http.StatusOK, redactContainer(*container))
+}
+
+// redactContainer hides the auth token of a container in API responses
+func redactContainer(ctr models.Container) models.Container {
+	if _, ok := ctr.Environment[container.AuthTokenEnv]; ok {
+		ctr.Environment = maps.Clone(ctr.Environment)
+		ctr.Environment[container.AuthTokenEnv] = redact.Placeholder
+	}
+	return ctr
 }
 
 // deleteContainer stops and removes a container
@@ -625,17 +1840,15 @@ func (h *Handler) validateContainer(c *gin.Context) {
 		Status:     "validating",
 	}
 
-	// Perform validation with the container manager
-	// Get current running count for validation
-	currentRunningCount := h.containerManager.GetRunningCount()
-	maxContainers := 10 // Default max containers - should be configurable
+	// Perform validation with the container manager against current usage
+	workspaceID, _ := req.JSONSpec["workspace_id"].(string)
+	quota := h.containerManager.QuotaSnapshot(workspaceID)
 
 	result, err := h.containerManager.ValidateContainerSpecWithLimits(
 		c.Request.Context(),
 		instance,
 		true, // allowImagePull
-		currentRunningCount,
-		maxContainers,
+		quota,
 	)
 
 	if err != nil {
@@ -649,13 +1862,16 @@ func (h *Handler) validateContainer(c *gin.Context) {
 
 	// Return validation result
 	c.JSON(http.StatusOK, gin.H{
-		"valid":          result.Valid,
-		"errors":         result.Errors,
-		"warnings":       result.Warnings,
-		"image_exists":   result.ImageExists,
-		"can_pull":       result.CanPull,
-		"estimated_size": result.EstimatedSize,
-		"timestamp":      time.Now(),
+		"valid":           result.Valid,
+		"errors":          result.Errors,
+		"warnings":        result.Warnings,
+		"issues":          result.Issues,
+		"quota":           result.Quota,
+		"idle_candidates": h.withoutReserved(result.IdleCandidates),
+		"image_exists":    result.ImageExists,
+		"can_pull":        result.CanPull,
+		"estimated_size":  result.EstimatedSize,
+		"timestamp":       time.Now(),
 	})
 }
 
@@ -924,3 +2140,66 @@ func (h *Handler) getHealthSummary(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// getProxyStats returns per-route request and upstream connection reuse counters
+func (h *Handler) getProxyStats(c *gin.Context) {
+	stats := h.routeManager.GetRouteStats()
+
+	var requests, connsNew, connsReused, slow, large uint64
+	for _, s := range stats {
+		requests += s.Requests
+		connsNew += s.ConnsNew
+		connsReused += s.ConnsReused
+		slow += s.SlowRequests
+		large += s.LargePayloads
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"routes":             stats,
+		"total_routes":       len(stats),
+		"total_requests":     requests,
+		"total_conns_new":    connsNew,
+		"total_conns_reused": connsReused,
+		"total_slow":         slow,
+		"total_large":        large,
+		"timestamp":          time.Now(),
+	})
+}
+
+// getCapacity returns host capacity, container reservations and creations
+// waiting for room
+func (h *Handler) getCapacity(c *gin.Context) {
+	status, err := h.containerManager.CapacityStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "capacity_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"capacity":  status,
+		"timestamp": time.Now(),
+	})
+}
+
+// getDiskUsage returns the latest storage filesystem and per-instance disk
+// usage samples
+func (h *Handler) getDiskUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"disk":      h.containerManager.DiskStatus(),
+		"timestamp": time.Now(),
+	})
+}
+
+// getEventQueueStats returns the event pipeline counters, Redis connection
+// state and the depth of the instance creation queue
+func (h *Handler) getEventQueueStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"pipeline":     h.eventSubscriber.PipelineStats(),
+		"create_queue": h.eventSubscriber.CreateQueueStats(),
+		"timestamp":    time.Now(),
+	})
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/handoff"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+)
+
+// handoffReason is the read-only reason while a replacement takes over
+const handoffReason = "handing off to a new manager"
+
+// handoffCoordinator tracks a handoff to a replacement manager
+type handoffCoordinator struct {
+	mu            sync.Mutex
+	token         string
+	settleTimeout time.Duration
+	deadline      time.Duration
+	onComplete    func()
+	active        bool
+	wasReadOnly   bool        // Read-only mode before the handoff, restored on abort
+	expiry        *time.Timer // Aborts the handoff at the deadline
+	generation    int         // Counts begins, so a stale expiry is ignored
+}
+
+// SetHandoff enables the handoff endpoints (Docker environments only).
+// onComplete is called once the replacement has taken over and this
+// process should exit. A handoff not completed within deadline is aborted.
+func (h *Handler) SetHandoff(token string, settleTimeout, deadline time.Duration, onComplete func()) {
+	h.handoff = &handoffCoordinator{token: token, settleTimeout: settleTimeout, deadline: deadline, onComplete: onComplete}
+}
+
+// handoffAuth rejects requests without the handoff token
+func (h *Handler) handoffAuth(c *gin.Context) {
+	if !handoff.ValidToken(h.handoff.token, c.GetHeader(handoff.TokenHeader)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "invalid_handoff_token",
+			Code:    http.StatusForbidden,
+			Message: "missing or invalid " + handoff.TokenHeader,
+		})
+	}
+}
+
+// beginHandoff switches to read-only mode, pauses the background loops,
+// waits for in-flight container operations to settle and returns the state
+// for the replacement manager. Repeated calls restart the deadline.
+func (h *Handler) beginHandoff(c *gin.Context) {
+	h.handoff.mu.Lock()
+	defer h.handoff.mu.Unlock()
+
+	if !h.handoff.active {
+		h.readOnly.mu.Lock()
+		h.handoff.wasReadOnly = h.readOnly.enabled
+		if !h.readOnly.enabled {
+			h.readOnly.enabled = true
+			h.readOnly.reason = handoffReason
+			h.readOnly.since = time.Now()
+		}
+		h.readOnly.mu.Unlock()
+		h.containerManager.PauseBackground()
+		h.handoff.active = true
+	}
+	if h.handoff.expiry != nil {
+		h.handoff.expiry.Stop()
+	}
+	h.handoff.generation++
+	if h.handoff.deadline > 0 {
+		generation := h.handoff.generation
+		h.handoff.expiry = time.AfterFunc(h.handoff.deadline, func() { h.expireHandoff(generation) })
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.handoff.settleTimeout)
+	defer cancel()
+	if err := h.containerManager.Settle(ctx); err != nil {
+		h.logger.Warn("Handing off with container operations in flight", slog.String("error", err.Error()))
+	}
+
+	state := handoff.State{
+		Version:      h.version,
+		ExportedAt:   time.Now(),
+		Routes:       []proxy.RouteSnapshot{},
+		HandoffState: h.containerManager.ExportState(),
+	}
+	if h.routeManager != nil {
+		state.Routes, state.SkippedRoutes = h.routeManager.ExportRoutes()
+	}
+
+	h.logger.Warn("Handoff to a new manager started",
+		slog.Int("containers", len(state.Containers)),
+		slog.Int("routes", len(state.Routes)),
+		slog.Int("operations", len(state.Operations)),
+		slog.String("client_ip", c.ClientIP()))
+
+	c.JSON(http.StatusOK, state)
+}
+
+// completeHandoff lets the process exit now that the replacement serves
+func (h *Handler) completeHandoff(c *gin.Context) {
+	h.handoff.mu.Lock()
+	defer h.handoff.mu.Unlock()
+
+	if !h.handoff.active {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "handoff_not_started",
+			Code:    http.StatusConflict,
+			Message: "no handoff is in progress",
+		})
+		return
+	}
+
+	if h.handoff.expiry != nil {
+		h.handoff.expiry.Stop()
+		h.handoff.expiry = nil
+	}
+	h.logger.Warn("Handoff completed, shutting down", slog.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusAccepted, gin.H{"status": "exiting"})
+	if h.handoff.onComplete != nil {
+		h.handoff.onComplete()
+	}
+}
+
+// abortHandoff leaves read-only mode as it was before the handoff began
+func (h *Handler) abortHandoff(c *gin.Context) {
+	h.handoff.mu.Lock()
+	defer h.handoff.mu.Unlock()
+
+	if h.handoff.active {
+		h.endHandoff()
+		h.logger.Warn("Handoff aborted", slog.String("client_ip", c.ClientIP()))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "serving"})
+}
+
+// expireHandoff aborts a handoff whose replacement neither completed nor
+// aborted it before the deadline set by the given begin
+func (h *Handler) expireHandoff(generation int) {
+	h.handoff.mu.Lock()
+	defer h.handoff.mu.Unlock()
+
+	if !h.handoff.active || h.handoff.generation != generation {
+		return
+	}
+	h.endHandoff()
+	h.logger.Error("Handoff not completed before the deadline, serving again",
+		slog.Duration("deadline", h.handoff.deadline))
+}
+
+// endHandoff restores read-only mode and resumes the background loops.
+// The caller holds h.handoff.mu.
+func (h *Handler) endHandoff() {
+	if h.handoff.expiry != nil {
+		h.handoff.expiry.Stop()
+		h.handoff.expiry = nil
+	}
+	h.readOnly.mu.Lock()
+	if !h.handoff.wasReadOnly && h.readOnly.reason == handoffReason {
+		h.readOnly.enabled = false
+		h.readOnly.reason = ""
+		h.readOnly.since = time.Time{}
+	}
+	h.readOnly.mu.Unlock()
+	h.containerManager.ResumeBackground()
+	h.handoff.active = false
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// SetHostAccessPolicy enables host device and socket pass-through, checked
+// against the policy's allow-lists and workspace grants
+func (h *Handler) SetHostAccessPolicy(policy *container.HostAccessPolicy) {
+	h.hostAccess = policy
+}
+
+// getHostAccess returns the allow-lists and the approved workspaces
+func (h *Handler) getHostAccess(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"allow_list": h.hostAccess.AllowList(),
+		"grants":     h.hostAccess.Grants(),
+	})
+}
+
+// setHostAccessGrant approves allow-listed devices and sockets for a
+// workspace, replacing its previous grant
+func (h *Handler) setHostAccessGrant(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	var access container.HostAccess
+	if err := c.ShouldBindJSON(&access); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := h.hostAccess.Grant(workspaceID, access); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_host_access",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Logged at Warn so every grant leaves an audit trail
+	h.logger.Warn("Host access granted",
+		slog.String("workspace_id", workspaceID),
+		slog.Any("devices", access.Devices),
+		slog.Any("host_sockets", access.HostSockets),
+		slog.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"grant":        access,
+	})
+}
+
+// deleteHostAccessGrant revokes the grant of a workspace; running instances
+// keep their devices until they are recreated
+func (h *Handler) deleteHostAccessGrant(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	_ = h.hostAccess.Grant(workspaceID, container.HostAccess{})
+	h.logger.Warn("Host access revoked",
+		slog.String("workspace_id", workspaceID),
+		slog.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"message":      "Host access revoked",
+	})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// maxLegacyCallers bounds the distinct callers tracked by the legacy shim
+const maxLegacyCallers = 1000
+
+// legacyUsage counts calls to the deprecated /containers endpoints
+type legacyUsage struct {
+	mu       sync.Mutex
+	disabled bool
+	since    time.Time
+	routes   map[string]*legacyCount
+	callers  map[legacyCaller]*legacyCount
+}
+
+// legacyCaller identifies who calls a legacy endpoint
+type legacyCaller struct {
+	ClientIP  string
+	UserAgent string
+}
+
+// legacyCount is the number of calls and the time of the last one
+type legacyCount struct {
+	Count    int64
+	LastSeen time.Time
+}
+
+func newLegacyUsage() *legacyUsage {
+	return &legacyUsage{
+		since:   time.Now(),
+		routes:  make(map[string]*legacyCount),
+		callers: make(map[legacyCaller]*legacyCount),
+	}
+}
+
+// SetLegacyAPIDisabled makes the legacy /containers endpoints answer 410 Gone
+// instead of serving requests; calls are still counted
+func (h *Handler) SetLegacyAPIDisabled(disabled bool) {
+	h.legacy.mu.Lock()
+	h.legacy.disabled = disabled
+	h.legacy.mu.Unlock()
+}
+
+// legacyShim counts calls to the legacy endpoints, marks responses as
+// deprecated and rejects the calls once the legacy API is disabled
+func (h *Handler) legacyShim() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		caller := legacyCaller{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		now := time.Now()
+
+		h.legacy.mu.Lock()
+		disabled := h.legacy.disabled
+		if h.legacy.routes[route] == nil {
+			h.legacy.routes[route] = &legacyCount{}
+		}
+		h.legacy.routes[route].Count++
+		h.legacy.routes[route].LastSeen = now
+		if h.legacy.callers[caller] == nil && len(h.legacy.callers) < maxLegacyCallers {
+			h.legacy.callers[caller] = &legacyCount{}
+		}
+		if count := h.legacy.callers[caller]; count != nil {
+			count.Count++
+			count.LastSeen = now
+		}
+		h.legacy.mu.Unlock()
+
+		h.logger.Debug("Legacy endpoint called",
+			slog.String("route", route),
+			slog.String("client_ip", caller.ClientIP),
+			slog.String("user_agent", caller.UserAgent))
+
+		c.Header("Deprecation", "true")
+		c.Header("Link", `</instances>; rel="successor-version"`)
+		c.Header("Warning", `299 - "The /containers API is deprecated, use /instances"`)
+
+		if disabled {
+			c.AbortWithStatusJSON(http.StatusGone, models.ErrorResponse{
+				Error:   "legacy_api_disabled",
+				Code:    http.StatusGone,
+				Message: "the /containers API has been disabled, use /instances",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// getLegacyUsage shows which legacy routes are still called and by whom
+func (h *Handler) getLegacyUsage(c *gin.Context) {
+	h.legacy.mu.Lock()
+	defer h.legacy.mu.Unlock()
+
+	var total int64
+	routes := make([]gin.H, 0, len(h.legacy.routes))
+	for route, count := range h.legacy.routes {
+		total += count.Count
+		routes = append(routes, gin.H{"route": route, "count": count.Count, "last_seen": count.LastSeen})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i]["count"].(int64) > routes[j]["count"].(int64)
+	})
+
+	callers := make([]gin.H, 0, len(h.legacy.callers))
+	for caller, count := range h.legacy.callers {
+		callers = append(callers, gin.H{
+			"client_ip":  caller.ClientIP,
+			"user_agent": caller.UserAgent,
+			"count":      count.Count,
+			"last_seen":  count.LastSeen,
+		})
+	}
+	sort.Slice(callers, func(i, j int) bool {
+		return callers[i]["count"].(int64) > callers[j]["count"].(int64)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"disabled": h.legacy.disabled,
+		"since":    h.legacy.since,
+		"total":    total,
+		"routes":   routes,
+		"callers":  callers,
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/metrics"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// stopInstance stops an instance without removing its route, secrets or spec
+func (h *Handler) stopInstance(c *gin.Context) {
+	h.cycleInstance(c, "stop", "Instance stopped", h.backend.StopInstance)
+}
+
+// startInstance starts a stopped instance
+func (h *Handler) startInstance(c *gin.Context) {
+	h.cycleInstance(c, "start", "Instance started", h.backend.StartInstance)
+}
+
+// restartInstance restarts an instance in place
+func (h *Handler) restartInstance(c *gin.Context) {
+	h.cycleInstance(c, "restart", "Instance restarted", h.backend.RestartInstance)
+}
+
+// cycleInstance runs a lifecycle operation on an instance and responds with
+// its status afterwards. Stops and restarts respect reservations.
+func (h *Handler) cycleInstance(c *gin.Context, operation, message string, run func(ctx context.Context, instanceID string) error) {
+	instanceID := c.Param("id")
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if operation != "start" && !h.checkReservation(c, instance) {
+		return
+	}
+
+	start := time.Now()
+	err = run(c.Request.Context(), instanceID)
+	metrics.ObserveInstanceOperation(operation, metrics.SourceAPI, start, err)
+	switch {
+	case errors.Is(err, backends.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, container.ErrLifecycleBlocked):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "instance_locked",
+			Code:    http.StatusConflict,
+			Message: "instance is quarantined or checkpointed; unquarantine or restore it instead",
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to "+operation+" instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "instance_" + operation + "_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	status := instance.Status
+	if current, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID); err == nil {
+		status = current.Status
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     message,
+		"instance_id": instanceID,
+		"status":      status,
+		"timestamp":   time.Now(),
+	})
+}
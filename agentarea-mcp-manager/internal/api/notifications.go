@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/notify"
+)
+
+// SetNotifier enables configuring failure notifications through the API
+func (h *Handler) SetNotifier(notifier *notify.Notifier) {
+	h.notifier = notifier
+}
+
+// getNotifications returns the notification channels and routing rules, with
+// webhook URLs, headers and SMTP passwords redacted
+func (h *Handler) getNotifications(c *gin.Context) {
+	cfg, err := h.notifier.Config(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to load notification config", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "notifications_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg.Redacted())
+}
+
+// setNotifications replaces the notification channels and routing rules.
+// Redacted values keep the secret stored for the channel of the same name.
+func (h *Handler) setNotifications(c *gin.Context) {
+	var req notify.Config
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_notifications",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	cfg, err := h.notifier.SetConfig(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to save notification config", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "notifications_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Updated notification config",
+		slog.Int("channels", len(cfg.Channels)),
+		slog.Int("rules", len(cfg.Rules)))
+	c.JSON(http.StatusOK, cfg.Redacted())
+}
+
+// testNotification sends a test notification to one channel
+func (h *Handler) testNotification(c *gin.Context) {
+	var req struct {
+		Channel string `json:"channel" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.notifier.Test(c.Request.Context(), req.Channel); err != nil {
+		if errors.Is(err, notify.ErrUnknownChannel) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "channel_not_found",
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error:   "delivery_failed",
+			Code:    http.StatusBadGateway,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channel":   req.Channel,
+		"delivered": true,
+	})
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Limits of the instance observation endpoints
+const (
+	defaultLogTail = 100
+	execTimeout    = 30 * time.Second
+)
+
+// SetExecEnabled allows running commands inside instances with
+// POST /instances/:id/exec; it is off by default
+func (h *Handler) SetExecEnabled(enabled bool) {
+	h.execEnabled = enabled
+}
+
+// notSupported answers 501 for a capability the backend does not implement
+func notSupported(c *gin.Context, capability string) {
+	c.JSON(http.StatusNotImplemented, models.ErrorResponse{
+		Error:   "not_supported",
+		Code:    http.StatusNotImplemented,
+		Message: "the backend does not support " + capability,
+	})
+}
+
+// requireInstance answers 404 unless the instance exists
+func (h *Handler) requireInstance(c *gin.Context, instanceID string) bool {
+	if _, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// flushWriter flushes every write so followed logs reach the client
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.w.Flush()
+	return n, err
+}
+
+// disableWriteDeadline lifts the server write timeout for a long-lived stream
+func disableWriteDeadline(c *gin.Context) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
+
+// getInstanceLogs streams the logs of an instance as plain text, or as
+// server-sent events with format=sse or Accept: text/event-stream.
+// Query: tail (default 100, 0 for all), since (duration or RFC 3339),
+// follow and timestamps.
+func (h *Handler) getInstanceLogs(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	streamer, ok := h.backend.(backends.LogStreamer)
+	if !ok {
+		notSupported(c, backends.CapabilityLogs)
+		return
+	}
+
+	opts := backends.LogOptions{Tail: defaultLogTail}
+	if tail := c.Query("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_tail",
+				Code:    http.StatusBadRequest,
+				Message: "tail must be a non-negative integer",
+			})
+			return
+		}
+		opts.Tail = n
+	}
+	if since := c.Query("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			opts.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			opts.Since = t
+		} else {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_since",
+				Code:    http.StatusBadRequest,
+				Message: "since must be a duration (e.g. 10m) or an RFC 3339 time",
+			})
+			return
+		}
+	}
+	opts.Follow = c.Query("follow") == "true"
+	opts.Timestamps = c.Query("timestamps") == "true"
+
+	if !h.requireInstance(c, instanceID) {
+		return
+	}
+	if wantsEventStream(c) {
+		h.streamLogEvents(c, streamer, instanceID, opts)
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	var w io.Writer = c.Writer
+	if opts.Follow {
+		disableWriteDeadline(c)
+		w = flushWriter{w: c.Writer}
+	}
+	if err := streamer.StreamLogs(c.Request.Context(), instanceID, opts, w); err != nil {
+		h.logger.Error("Failed to stream instance logs", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		if c.Writer.Size() <= 0 {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "logs_failed",
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+}
+
+// streamLogEvents sends each log line as a `log` event. The stream ends with
+// an `end` event, or an `error` event carrying the message, so EventSource
+// clients can close instead of reconnecting.
+func (h *Handler) streamLogEvents(c *gin.Context, streamer backends.LogStreamer, instanceID string, opts backends.LogOptions) {
+	disableWriteDeadline(c)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	w := &sseLogWriter{c: c}
+	err := streamer.StreamLogs(c.Request.Context(), instanceID, opts, w)
+	w.flushPartial()
+	if err != nil {
+		h.logger.Error("Failed to stream instance logs", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.SSEvent("error", err.Error())
+	} else {
+		c.SSEvent("end", "")
+	}
+	c.Writer.Flush()
+}
+
+// wantsEventStream reports whether the client asked for server-sent events
+func wantsEventStream(c *gin.Context) bool {
+	return c.Query("format") == "sse" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// sseLogWriter sends every complete line written to it as a `log` event
+type sseLogWriter struct {
+	c       *gin.Context
+	partial []byte
+}
+
+func (s *sseLogWriter) Write(p []byte) (int, error) {
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		s.c.SSEvent("log", strings.TrimSuffix(string(s.partial[:i]), "\r"))
+		s.partial = s.partial[i+1:]
+	}
+	s.c.Writer.Flush()
+	return len(p), nil
+}
+
+// flushPartial sends a last line that did not end with a newline
+func (s *sseLogWriter) flushPartial() {
+	if len(s.partial) > 0 {
+		s.c.SSEvent("log", string(s.partial))
+		s.partial = nil
+	}
+}
+
+// execInstance runs a command inside an instance and returns its output
+func (h *Handler) execInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	execer, ok := h.backend.(backends.Execer)
+	if !ok {
+		notSupported(c, backends.CapabilityExec)
+		return
+	}
+	if !h.execEnabled {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "exec_disabled",
+			Code:    http.StatusForbidden,
+			Message: "exec is disabled, set ALLOW_INSTANCE_EXEC=true to enable it",
+		})
+		return
+	}
+
+	var req struct {
+		Command []string `json:"command" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !h.requireInstance(c, instanceID) {
+		return
+	}
+
+	// The command is logged without its arguments, which may hold secrets
+	h.logger.Warn("Executing command in instance",
+		slog.String("instance_id", instanceID),
+		slog.String("command", req.Command[0]),
+		slog.String("client_ip", c.ClientIP()))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), execTimeout)
+	defer cancel()
+	result, err := execer.Exec(ctx, instanceID, req.Command)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "exec_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"exit_code":   result.ExitCode,
+		"stdout":      result.Stdout,
+		"stderr":      result.Stderr,
+	})
+}
+
+// getInstanceStats returns a resource usage sample of an instance
+func (h *Handler) getInstanceStats(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	provider, ok := h.backend.(backends.StatsProvider)
+	if !ok {
+		notSupported(c, backends.CapabilityStats)
+		return
+	}
+	if !h.requireInstance(c, instanceID) {
+		return
+	}
+
+	stats, err := provider.InstanceStats(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "stats_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"stats":       stats,
+	})
+}
+
+// getInstanceDrift compares an instance with its desired spec and, with
+// ?fix=true, recreates a drifted instance from the spec
+func (h *Handler) getInstanceDrift(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	detector, ok := h.backend.(backends.DriftDetector)
+	if !ok {
+		notSupported(c, backends.CapabilityDrift)
+		return
+	}
+	instance, ok := h.pathInstance(c, instanceID)
+	if !ok {
+		return
+	}
+	fix := c.Query("fix") == "true"
+	if fix && !h.checkReservation(c, instance) {
+		return
+	}
+
+	report, err := detector.CheckDrift(c.Request.Context(), instanceID, fix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "drift_check_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"drift":       report,
+	})
+}
+
+// watchInstance streams lifecycle events of an instance as server-sent events
+func (h *Handler) watchInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	watcher, ok := h.backend.(backends.Watcher)
+	if !ok {
+		notSupported(c, backends.CapabilityWatch)
+		return
+	}
+	if !h.requireInstance(c, instanceID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, err := watcher.Watch(ctx, instanceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "watch_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	disableWriteDeadline(c)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("instance", event)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
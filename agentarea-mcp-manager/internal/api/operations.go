@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/operations"
+)
+
+// operationIDHeader lets callers choose the ID of a create or update so they
+// can cancel it while the request is still waiting; it is echoed back
+const operationIDHeader = "X-Operation-ID"
+
+// SetOperations tracks creates and updates so they can be listed and
+// cancelled under /operations
+func (h *Handler) SetOperations(tracker *operations.Tracker) {
+	h.operations = tracker
+}
+
+// listOperations returns the in-flight operations
+func (h *Handler) listOperations(c *gin.Context) {
+	ops := h.operations.List()
+	c.JSON(http.StatusOK, gin.H{
+		"operations": ops,
+		"count":      len(ops),
+	})
+}
+
+// getOperation returns an in-flight operation
+func (h *Handler) getOperation(c *gin.Context) {
+	op, ok := h.operations.Get(c.Param("id"))
+	if !ok {
+		operationNotFound(c)
+		return
+	}
+	c.JSON(http.StatusOK, op)
+}
+
+// cancelOperation cancels an in-flight operation. It answers once the
+// cancellation is requested; the operation rolls back its partial resources
+// and publishes a cancelled status on its own.
+func (h *Handler) cancelOperation(c *gin.Context) {
+	op, ok := h.operations.Cancel(c.Param("id"))
+	if !ok {
+		operationNotFound(c)
+		return
+	}
+	h.logger.Info("Cancelling operation",
+		slog.String("operation_id", op.ID),
+		slog.String("kind", op.Kind),
+		slog.String("instance_id", op.InstanceID))
+	c.JSON(http.StatusAccepted, op)
+}
+
+// operationNotFound answers for an operation that finished or never existed
+func operationNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, models.ErrorResponse{
+		Error:   "operation_not_found",
+		Code:    http.StatusNotFound,
+		Message: "no operation in flight with this ID",
+	})
+}
+
+// startOperation tracks a create or update of the request under the ID of
+// the X-Operation-ID header or a generated one. It responds 409 and returns
+// false if the ID is in use.
+func (h *Handler) startOperation(c *gin.Context, kind, instanceID, name string) (context.Context, operations.Operation, func(), bool) {
+	ctx, op, done, err := h.operations.Start(c.Request.Context(), operations.Operation{
+		ID:         c.GetHeader(operationIDHeader),
+		Kind:       kind,
+		Source:     operations.SourceAPI,
+		InstanceID: instanceID,
+		Name:       name,
+	})
+	if errors.Is(err, operations.ErrDuplicateID) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "operation_id_in_use",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return nil, op, nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "operation_tracking_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return nil, op, nil, false
+	}
+	if op.ID != "" {
+		c.Header(operationIDHeader, op.ID)
+	}
+	return ctx, op, done, true
+}
+
+// rollbackOperation undoes a cancelled create or update and responds 409.
+// A cancelled create is deleted. A cancelled update is rolled back to
+// previous only if it left the instance gone, as Docker updates recreate the
+// container; backends that update in place keep whatever was applied.
+func (h *Handler) rollbackOperation(c *gin.Context, op operations.Operation, previous *backends.InstanceSpec) {
+	// The request context is cancelled along with the operation
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	h.logger.Info("Operation cancelled, rolling back",
+		slog.String("operation_id", op.ID),
+		slog.String("kind", op.Kind),
+		slog.String("instance_id", op.InstanceID))
+
+	var err error
+	switch op.Kind {
+	case operations.KindCreate:
+		if err = h.backend.DeleteInstance(ctx, op.InstanceID); errors.Is(err, backends.ErrNotFound) {
+			err = nil
+		}
+	case operations.KindUpdate:
+		if _, statusErr := h.backend.GetInstanceStatus(ctx, op.InstanceID); errors.Is(statusErr, backends.ErrNotFound) && previous != nil {
+			_, err = h.backend.CreateInstance(ctx, previous)
+		}
+	}
+	if err != nil {
+		h.logger.Error("Failed to roll back cancelled operation",
+			slog.String("operation_id", op.ID),
+			slog.String("instance_id", op.InstanceID),
+			slog.String("error", err.Error()))
+	}
+
+	if h.eventPublisher != nil {
+		if err := h.eventPublisher.PublishCancelled(ctx, op.InstanceID, op.Name, "operation "+op.ID+" cancelled"); err != nil {
+			h.logger.Warn("Failed to publish cancelled status",
+				slog.String("instance_id", op.InstanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	response := gin.H{
+		"error":        "operation_cancelled",
+		"code":         http.StatusConflict,
+		"message":      op.Kind + " was cancelled and rolled back",
+		"operation_id": op.ID,
+		"rolled_back":  err == nil,
+		"timestamp":    time.Now(),
+	}
+	if err != nil {
+		response["rollback_error"] = err.Error()
+	}
+	c.JSON(http.StatusConflict, response)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// getHostPorts returns the host port range and the ports published by
+// instances
+func (h *Handler) getHostPorts(c *gin.Context) {
+	portRange, assignments := h.containerManager.HostPorts()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     portRange != "",
+		"range":       portRange,
+		"assignments": assignments,
+		"total":       len(assignments),
+	})
+}
+
+// checkHostPort rejects a host port that cannot be published for the
+// service, so conflicts surface before the creation is queued
+func (h *Handler) checkHostPort(c *gin.Context, serviceName string, port *int) bool {
+	labels, err := container.SetHostPort(nil, port)
+	if err == nil {
+		if h.containerManager == nil {
+			err = container.ErrHostPortsDisabled
+		} else {
+			err = h.containerManager.CheckHostPort(serviceName, labels)
+		}
+	}
+
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, container.ErrHostPortConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "host_port_conflict",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+	case errors.Is(err, container.ErrHostPortsDisabled):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "host_ports_disabled",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_host_port",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// quarantineInstance isolates a possibly compromised instance: it removes the
+// route and blocks egress but keeps the container running for forensics
+func (h *Handler) quarantineInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	quarantine, err := h.containerManager.QuarantineContainer(c.Request.Context(), instance.ServiceName, req.Reason, container.QuarantineManual)
+	switch {
+	case errors.Is(err, container.ErrQuarantined):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "already_quarantined",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to quarantine instance", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "quarantine_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"quarantine":  quarantine,
+		"timestamp":   time.Now(),
+	})
+}
+
+// unquarantineInstance releases a quarantined instance, restoring its egress
+// and route
+func (h *Handler) unquarantineInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err = h.containerManager.ReleaseContainer(c.Request.Context(), instance.ServiceName)
+	switch {
+	case errors.Is(err, container.ErrNotQuarantined):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "not_quarantined",
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to release instance from quarantine", slog.String("instance_id", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "unquarantine_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"message":     "Instance released from quarantine",
+		"timestamp":   time.Now(),
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+)
+
+// getWorkspaceUsage returns the proxied request counts and quotas of a
+// workspace and of the API keys calling into it
+func (h *Handler) getWorkspaceUsage(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	usage := h.routeManager.QuotaUsage(workspaceID)
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"usage":        usage,
+		"total":        len(usage),
+	})
+}
+
+// setWorkspaceQuota overrides the daily and monthly request quota of a
+// workspace, e.g. to apply its plan
+func (h *Handler) setWorkspaceQuota(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	var limits proxy.QuotaLimits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if limits.Daily < 0 || limits.Monthly < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_quota",
+			Code:    http.StatusBadRequest,
+			Message: "quota limits must not be negative",
+		})
+		return
+	}
+
+	h.routeManager.SetWorkspaceQuota(workspaceID, &limits)
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"limits":       limits,
+	})
+}
+
+// resetWorkspaceQuota restores the default request quota of a workspace
+func (h *Handler) resetWorkspaceQuota(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	h.routeManager.SetWorkspaceQuota(workspaceID, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"message":      "Workspace quota reset to default",
+	})
+}
+
+// getUsage returns the proxied request counts of all workspaces and API keys
+func (h *Handler) getUsage(c *gin.Context) {
+	usage := h.routeManager.QuotaUsage("")
+	c.JSON(http.StatusOK, gin.H{
+		"usage": usage,
+		"total": len(usage),
+	})
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// breakGlassHeader carries the token that lets a request through read-only mode
+const breakGlassHeader = "X-Break-Glass-Token"
+
+// readOnlyExempt are mutating routes that stay available in read-only mode:
+// the handoff, and routes that validate or check without changing state. The
+// toggle is not exempt; see readOnlyToggle.
+var readOnlyExempt = map[string]bool{
+	"/admin/handoff":              true,
+	"/admin/handoff/complete":     true,
+	"/admin/handoff/abort":        true,
+	"/instances/validate":         true,
+	"/notifications/test":         true,
+	"/containers/validate":        true,
+	"/instances/:id/health":       true,
+	"/containers/:service/health": true,
+}
+
+// readOnlyToggle is the route that changes read-only mode. While the mode is
+// on it needs the break-glass token, unless none is configured: then it stays
+// open to authenticated callers, so the mode can be lifted without a restart.
+const readOnlyToggle = "/admin/read-only"
+
+// readOnlyMode freezes the API during incidents and migrations
+type readOnlyMode struct {
+	mu              sync.RWMutex
+	enabled         bool
+	reason          string
+	since           time.Time
+	breakGlassToken string
+}
+
+// SetReadOnly sets the initial read-only mode and the break-glass token that
+// lets mutating requests through while it is on (empty disables break-glass)
+func (h *Handler) SetReadOnly(enabled bool, breakGlassToken string) {
+	h.readOnly.mu.Lock()
+	defer h.readOnly.mu.Unlock()
+
+	h.readOnly.enabled = enabled
+	h.readOnly.breakGlassToken = breakGlassToken
+	if enabled {
+		h.readOnly.reason = "enabled at startup"
+		h.readOnly.since = time.Now()
+	}
+}
+
+// readOnlyGuard rejects mutating requests with 503 while read-only mode is on,
+// unless they carry the break-glass token. A drift check that fixes the
+// instance mutates it despite being a GET.
+func (h *Handler) readOnlyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if c.FullPath() != "/instances/:id/drift" || c.Query("fix") != "true" {
+				c.Next()
+				return
+			}
+		}
+		if readOnlyExempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		h.readOnly.mu.RLock()
+		enabled, reason, token := h.readOnly.enabled, h.readOnly.reason, h.readOnly.breakGlassToken
+		h.readOnly.mu.RUnlock()
+		if !enabled || (token == "" && c.FullPath() == readOnlyToggle) {
+			c.Next()
+			return
+		}
+
+		if presented := c.GetHeader(breakGlassHeader); token != "" && presented != "" &&
+			subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			// Logged at Warn so every override leaves an audit trail
+			h.logger.Warn("Break-glass request in read-only mode",
+				slog.String("method", c.Request.Method),
+				slog.String("path", c.Request.URL.Path),
+				slog.String("client_ip", c.ClientIP()))
+			c.Next()
+			return
+		}
+
+		message := "the API is in read-only mode"
+		if reason != "" {
+			message += ": " + reason
+		}
+		c.Header("Retry-After", "60")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "read_only",
+			Code:    http.StatusServiceUnavailable,
+			Message: message,
+		})
+	}
+}
+
+// getReadOnly returns the current read-only mode
+func (h *Handler) getReadOnly(c *gin.Context) {
+	c.JSON(http.StatusOK, h.readOnlyResponse())
+}
+
+// setReadOnly turns read-only mode on or off at runtime. While the mode is
+// on, readOnlyGuard only lets the change through with the break-glass token,
+// if one is configured.
+func (h *Handler) setReadOnly(c *gin.Context) {
+	var req struct {
+		Enabled *bool  `json:"enabled" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.readOnly.mu.Lock()
+	h.readOnly.enabled = *req.Enabled
+	if *req.Enabled {
+		h.readOnly.reason = req.Reason
+		h.readOnly.since = time.Now()
+	} else {
+		h.readOnly.reason = ""
+		h.readOnly.since = time.Time{}
+	}
+	h.readOnly.mu.Unlock()
+
+	h.logger.Warn("Read-only mode changed",
+		slog.Bool("enabled", *req.Enabled),
+		slog.String("reason", req.Reason),
+		slog.String("client_ip", c.ClientIP()))
+
+	c.JSON(http.StatusOK, h.readOnlyResponse())
+}
+
+// readOnlyResponse describes the read-only mode
+func (h *Handler) readOnlyResponse() gin.H {
+	h.readOnly.mu.RLock()
+	defer h.readOnly.mu.RUnlock()
+
+	response := gin.H{
+		"enabled":     h.readOnly.enabled,
+		"break_glass": h.readOnly.breakGlassToken != "",
+	}
+	if h.readOnly.enabled {
+		response["reason"] = h.readOnly.reason
+		response["since"] = h.readOnly.since
+	}
+	return response
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func readOnlyRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(h.readOnlyGuard())
+	router.PUT("/admin/read-only", h.setReadOnly)
+	router.POST("/instances", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return router
+}
+
+func putReadOnly(router *gin.Engine, body, token string) int {
+	req := httptest.NewRequest(http.MethodPut, "/admin/read-only", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set(breakGlassHeader, token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestReadOnlyToggle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Without a break-glass token the toggle stays reachable
+	h := &Handler{logger: logger}
+	h.SetReadOnly(true, "")
+	router := readOnlyRouter(h)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/instances", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("create in read-only mode = %d, want 503", w.Code)
+	}
+	if code := putReadOnly(router, `{"enabled": false}`, ""); code != http.StatusOK || h.readOnly.enabled {
+		t.Errorf("lifting without a configured token = %d, enabled %v", code, h.readOnly.enabled)
+	}
+
+	// With one, lifting the mode needs it
+	h = &Handler{logger: logger}
+	h.SetReadOnly(true, "glass")
+	router = readOnlyRouter(h)
+	if code := putReadOnly(router, `{"enabled": false}`, ""); code != http.StatusServiceUnavailable || !h.readOnly.enabled {
+		t.Errorf("lifting without the token = %d, enabled %v", code, h.readOnly.enabled)
+	}
+	if code := putReadOnly(router, `{"enabled": false}`, "wrong"); code != http.StatusServiceUnavailable {
+		t.Errorf("lifting with a wrong token = %d", code)
+	}
+	if code := putReadOnly(router, `{"enabled": false}`, "glass"); code != http.StatusOK || h.readOnly.enabled {
+		t.Errorf("lifting with the token = %d, enabled %v", code, h.readOnly.enabled)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/registry"
+	"github.com/agentarea/mcp-manager/internal/templates"
+)
+
+const (
+	defaultRegistrySearchLimit = 30
+	maxRegistrySearchLimit     = 100
+)
+
+// SetRegistryClient enables template discovery from a public MCP registry
+func (h *Handler) SetRegistryClient(client *registry.Client) {
+	h.registry = client
+}
+
+// searchRegistry searches the MCP registry for servers
+func (h *Handler) searchRegistry(c *gin.Context) {
+	limit := defaultRegistrySearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxRegistrySearchLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: "limit must be between 1 and " + strconv.Itoa(maxRegistrySearchLimit),
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	servers, err := h.registry.Search(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		h.logger.Error("Registry search failed", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error:   "registry_unavailable",
+			Code:    http.StatusBadGateway,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	results := make([]gin.H, 0, len(servers))
+	for i := range servers {
+		server := &servers[i]
+		result := gin.H{
+			"name":        server.Name,
+			"description": server.Description,
+			"version":     server.Version,
+			"repository":  server.Repository.URL,
+			"importable":  false,
+		}
+		if pkg, ok := server.ImagePackage(); ok {
+			result["importable"] = true
+			result["image"] = pkg.Image()
+			result["transport"] = pkg.Transport.Type
+			result["env_vars"] = pkg.EnvironmentVariables
+			result["template_id"] = registry.TemplateKey(server.Name)
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"registry": h.registry.URL(),
+		"servers":  results,
+		"total":    len(results),
+	})
+}
+
+// importFromRegistry adds a registry server to the local template catalog
+func (h *Handler) importFromRegistry(c *gin.Context) {
+	var req struct {
+		Name       string `json:"name" binding:"required"`
+		Version    string `json:"version,omitempty"`
+		TemplateID string `json:"template_id,omitempty"`
+		Overwrite  bool   `json:"overwrite,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if h.templateLoader == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "templates_unavailable",
+			Code:    http.StatusServiceUnavailable,
+			Message: "template catalog is not configured",
+		})
+		return
+	}
+
+	server, err := h.registry.Get(c.Request.Context(), req.Name, req.Version)
+	if errors.Is(err, registry.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "registry_server_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Registry lookup failed", slog.String("name", req.Name), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error:   "registry_unavailable",
+			Code:    http.StatusBadGateway,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	key := req.TemplateID
+	if key == "" {
+		key = registry.TemplateKey(server.Name)
+	}
+
+	template, err := registry.ToTemplate(server, key, h.registry.URL(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "not_importable",
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err = h.templateLoader.Add(key, template, req.Overwrite)
+	if errors.Is(err, templates.ErrTemplateExists) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "template_exists",
+			Code:    http.StatusConflict,
+			Message: err.Error() + "; pass overwrite=true to replace it",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save imported template", slog.String("template_id", key), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "template_import_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Imported template from registry",
+		slog.String("template_id", key),
+		slog.String("name", server.Name),
+		slog.String("version", server.Version))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"template_id": key,
+		"template":    template,
+	})
+}
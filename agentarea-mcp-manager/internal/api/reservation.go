@@ -0,0 +1,265 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+const (
+	// defaultReservationLease is the lease of a reservation that sets none
+	defaultReservationLease = 15 * time.Minute
+	// maxReservationLease bounds a single lease; agents renew longer work
+	maxReservationLease = 24 * time.Hour
+)
+
+// errReservedByOther is returned when another session holds the reservation
+var errReservedByOther = errors.New("instance is reserved by another session")
+
+// reservation holds an instance for an agent session until it expires
+type reservation struct {
+	InstanceID  string    `json:"instance_id"`
+	ServiceName string    `json:"service_name"`
+	SessionID   string    `json:"session_id"`
+	AgentID     string    `json:"agent_id,omitempty"`
+	ReservedAt  time.Time `json:"reserved_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// reservationStore holds the active reservation per instance, keyed by
+// service name, which survives the container being recreated. Expired
+// reservations are dropped when next looked up.
+type reservationStore struct {
+	mu           sync.Mutex
+	reservations map[string]reservation // service name -> reservation
+}
+
+// newReservationStore creates an empty reservation store
+func newReservationStore() *reservationStore {
+	return &reservationStore{reservations: make(map[string]reservation)}
+}
+
+// reserve reserves an instance for a session or renews the session's lease
+func (s *reservationStore) reserve(instance *backends.InstanceStatus, sessionID, agentID string, lease time.Duration) (reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	current, exists := s.activeUnsafe(instance.ServiceName, now)
+	if exists && current.SessionID != sessionID {
+		return current, errReservedByOther
+	}
+	if !exists {
+		current = reservation{ServiceName: instance.ServiceName, SessionID: sessionID, ReservedAt: now}
+	}
+	current.InstanceID = instance.ID
+	if agentID != "" {
+		current.AgentID = agentID
+	}
+	current.ExpiresAt = now.Add(lease)
+	s.reservations[instance.ServiceName] = current
+	return current, nil
+}
+
+// active returns the unexpired reservation of a service
+func (s *reservationStore) active(serviceName string) (reservation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeUnsafe(serviceName, time.Now())
+}
+
+// activeUnsafe is active with s.mu held
+func (s *reservationStore) activeUnsafe(serviceName string, now time.Time) (reservation, bool) {
+	current, exists := s.reservations[serviceName]
+	if !exists {
+		return reservation{}, false
+	}
+	if !now.Before(current.ExpiresAt) {
+		delete(s.reservations, serviceName)
+		return reservation{}, false
+	}
+	return current, true
+}
+
+// release drops the reservation of a service. A non-empty sessionID must
+// match the holder.
+func (s *reservationStore) release(serviceName, sessionID string) (reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.activeUnsafe(serviceName, time.Now())
+	if !exists {
+		return reservation{}, nil
+	}
+	if sessionID != "" && current.SessionID != sessionID {
+		return current, errReservedByOther
+	}
+	delete(s.reservations, serviceName)
+	return current, nil
+}
+
+// Reserved reports whether a service is reserved for an agent session
+func (h *Handler) Reserved(serviceName string) bool {
+	_, ok := h.reservations.active(serviceName)
+	return ok
+}
+
+// reserveInstance reserves an instance for an agent session for a lease,
+// keeping updates, deletion and idle shutdown away from it until the lease
+// expires or the session releases it. Reserving again renews the lease.
+func (h *Handler) reserveInstance(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	var req struct {
+		SessionID string `json:"session_id" binding:"required"`
+		AgentID   string `json:"agent_id,omitempty"`
+		Lease     string `json:"lease,omitempty"` // Duration, e.g. "30m"
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	lease, ok := parseGroupDuration(c, "lease", req.Lease, defaultReservationLease)
+	if !ok {
+		return
+	}
+	if lease <= 0 || lease > maxReservationLease {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "lease must be positive and at most " + maxReservationLease.String(),
+		})
+		return
+	}
+	instance, ok := h.pathInstance(c, instanceID)
+	if !ok {
+		return
+	}
+
+	held, err := h.reservations.reserve(instance, req.SessionID, req.AgentID, lease)
+	if err != nil {
+		reservedConflict(c, held)
+		return
+	}
+
+	c.JSON(http.StatusOK, held)
+}
+
+// getReservation returns the active reservation of an instance
+func (h *Handler) getReservation(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, ok := h.pathInstance(c, instanceID)
+	if !ok {
+		return
+	}
+	held, ok := h.reservations.active(instance.ServiceName)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "reservation_not_found",
+			Code:    http.StatusNotFound,
+			Message: "instance " + instanceID + " is not reserved",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, held)
+}
+
+// releaseReservation ends the reservation of an instance. With ?session_id=
+// only the holding session's reservation is released; without it the
+// reservation is broken whoever holds it.
+func (h *Handler) releaseReservation(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, ok := h.pathInstance(c, instanceID)
+	if !ok {
+		return
+	}
+	held, err := h.reservations.release(instance.ServiceName, c.Query("session_id"))
+	if err != nil {
+		reservedConflict(c, held)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"released":    held.SessionID != "",
+	})
+}
+
+// pathInstance looks up the instance in the path, responding 404 if it
+// does not exist
+func (h *Handler) pathInstance(c *gin.Context, instanceID string) (*backends.InstanceStatus, bool) {
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	return instance, true
+}
+
+// checkReservation responds 409 if a session other than the one in the
+// X-Reservation-Session header holds the instance
+func (h *Handler) checkReservation(c *gin.Context, instance *backends.InstanceStatus) bool {
+	held, ok := h.reservations.active(instance.ServiceName)
+	if !ok || held.SessionID == c.GetHeader("X-Reservation-Session") {
+		return true
+	}
+	reservedConflict(c, held)
+	return false
+}
+
+// reservedConflict responds that an instance is reserved
+func reservedConflict(c *gin.Context, held reservation) {
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Error:   "instance_reserved",
+		Code:    http.StatusConflict,
+		Message: "instance " + held.InstanceID + " is reserved by session " + held.SessionID + " until " + held.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// unreserved splits instances into those without an active reservation and
+// skipped results for the reserved ones
+func (h *Handler) unreserved(instances []*backends.InstanceStatus) ([]*backends.InstanceStatus, []backends.GroupResult) {
+	free := make([]*backends.InstanceStatus, 0, len(instances))
+	var skipped []backends.GroupResult
+	for _, instance := range instances {
+		if held, ok := h.reservations.active(instance.ServiceName); ok {
+			skipped = append(skipped, backends.GroupResult{
+				InstanceID: instance.ID,
+				Result:     backends.GroupResultSkipped,
+				Error:      "reserved by session " + held.SessionID,
+			})
+			continue
+		}
+		free = append(free, instance)
+	}
+	return free, skipped
+}
+
+// withoutReserved drops reserved instances from shutdown suggestions
+func (h *Handler) withoutReserved(candidates []container.IdleInstance) []container.IdleInstance {
+	kept := make([]container.IdleInstance, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, ok := h.reservations.active(candidate.ServiceName); !ok {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+// getInstanceSpec returns the effective specification of an instance, with
+// secrets redacted, next to the spec it was submitted with when known
+func (h *Handler) getInstanceSpec(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var submitted *backends.SubmittedSpec
+	if provider, ok := h.backend.(backends.SpecProvider); ok {
+		submitted, err = provider.SubmittedSpec(c.Request.Context(), instanceID)
+		if err != nil && !errors.Is(err, backends.ErrNotFound) {
+			h.logger.Warn("Failed to get submitted spec",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	sources := effectiveEnvSources(instance.Environment, submitted)
+	environment := make(map[string]string, len(instance.Environment))
+	for _, entry := range sources {
+		environment[entry.Name] = redactEnvValue(entry, instance.Environment[entry.Name])
+	}
+
+	response := gin.H{
+		"instance_id": instanceID,
+		"effective": gin.H{
+			"image":       instance.Image,
+			"port":        instance.Port,
+			"environment": environment,
+			"labels":      instance.Labels,
+		},
+		"environment": sources,
+		"submitted":   nil,
+	}
+	if submitted != nil {
+		response["submitted"] = submitted.Spec
+		response["submitted_at"] = submitted.SubmittedAt
+	}
+	setETag(c, instance.ResourceVersion)
+	c.JSON(http.StatusOK, response)
+}
+
+// effectiveEnvSources returns the origin of every effective variable, from
+// the submitted spec when recorded; otherwise variables the manager injects
+// are system ones and the rest are assumed to come from the request
+func effectiveEnvSources(environment map[string]string, submitted *backends.SubmittedSpec) []container.EnvAuditEntry {
+	recorded := make(map[string]container.EnvAuditEntry)
+	if submitted != nil {
+		for _, entry := range submitted.Environment {
+			recorded[entry.Name] = entry
+		}
+	}
+
+	sources := make([]container.EnvAuditEntry, 0, len(environment))
+	for name := range environment {
+		entry, ok := recorded[name]
+		if !ok {
+			entry = container.EnvAuditEntry{Name: name, Source: container.EnvSourceRequest}
+			if container.IsSystemEnv(name) {
+				entry.Source = container.EnvSourceSystem
+				entry.Secret = name == container.AuthTokenEnv
+			}
+		}
+		sources = append(sources, entry)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	return sources
+}
+
+// redactEnvValue hides values flagged secret by their template, of
+// secret-looking names or resolved from secret references
+func redactEnvValue(entry container.EnvAuditEntry, value string) string {
+	if value != "" && (entry.Secret || redact.IsSecretKey(entry.Name) || redact.IsSecret(value)) {
+		return redact.Placeholder
+	}
+	return redact.String(value)
+}
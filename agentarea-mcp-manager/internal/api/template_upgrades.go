@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/templates"
+)
+
+// Template upgrade states
+const (
+	upgradeRunning   = "running"
+	upgradePaused    = "paused"
+	upgradeCompleted = "completed"
+	upgradeCancelled = "cancelled"
+)
+
+// templateUpgrade is an automatic rollout of a template version to the
+// instances that track the template
+type templateUpgrade struct {
+	TemplateID string                 `json:"template_id"`
+	Version    string                 `json:"version"`
+	Image      string                 `json:"image"`
+	State      string                 `json:"state"`
+	Results    []backends.GroupResult `json:"results"`
+	StartedAt  time.Time              `json:"started_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+
+	cancel context.CancelFunc
+}
+
+// templateUpgrades holds the latest automatic upgrade per template
+type templateUpgrades struct {
+	mu             sync.Mutex
+	byTemplate     map[string]*templateUpgrade
+	maxUnavailable int
+	healthTimeout  time.Duration
+}
+
+func newTemplateUpgrades() *templateUpgrades {
+	return &templateUpgrades{
+		byTemplate:     make(map[string]*templateUpgrade),
+		maxUnavailable: 1,
+		healthTimeout:  defaultGroupHealthTimeout,
+	}
+}
+
+// SetTemplateUpgradePolicy sets how many tracking instances are upgraded at
+// once and how long each has to become healthy
+func (h *Handler) SetTemplateUpgradePolicy(maxUnavailable int, healthTimeout time.Duration) {
+	h.upgrades.mu.Lock()
+	defer h.upgrades.mu.Unlock()
+
+	if maxUnavailable > 0 {
+		h.upgrades.maxUnavailable = maxUnavailable
+	}
+	if healthTimeout > 0 {
+		h.upgrades.healthTimeout = healthTimeout
+	}
+}
+
+// setTracking adds or removes the label that opts an instance into
+// automatic template upgrades
+func setTracking(labels map[string]string, track bool) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if track {
+		labels[container.TrackTemplateLabel] = "true"
+	} else {
+		delete(labels, container.TrackTemplateLabel)
+	}
+	return labels
+}
+
+// tracksTemplate reports whether an instance opted into automatic upgrades
+func tracksTemplate(labels map[string]string) bool {
+	return labels[container.TrackTemplateLabel] == "true"
+}
+
+// startTemplateUpgrade rolls the current version of a template out to the
+// outdated instances tracking it, replacing any upgrade still in progress.
+// It returns nil if no instance needs upgrading.
+func (h *Handler) startTemplateUpgrade(ctx context.Context, templateID string) (*templateUpgrade, error) {
+	if h.templateLoader == nil {
+		return nil, nil
+	}
+	template, ok := h.templateLoader.Get(templateID)
+	if !ok {
+		return nil, nil
+	}
+	version := templates.VersionOf(template)
+
+	instances, err := h.backend.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]*backends.InstanceStatus, 0)
+	for _, instance := range instances {
+		if instance.Labels[container.TemplateLabel] == templateID &&
+			instance.Labels[container.TemplateVersionLabel] != version &&
+			tracksTemplate(instance.Labels) {
+			targets = append(targets, instance)
+		}
+	}
+	// Reserved instances stay outdated until the next upgrade or resume
+	targets, _ = h.unreserved(targets)
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	upgrade := &templateUpgrade{
+		TemplateID: templateID,
+		Version:    version,
+		Image:      template.DockerImage,
+		State:      upgradeRunning,
+		StartedAt:  now,
+		UpdatedAt:  now,
+		cancel:     cancel,
+	}
+
+	h.upgrades.mu.Lock()
+	if previous, exists := h.upgrades.byTemplate[templateID]; exists && previous.State == upgradeRunning {
+		previous.cancel()
+		previous.State = upgradeCancelled
+		previous.UpdatedAt = now
+	}
+	h.upgrades.byTemplate[templateID] = upgrade
+	maxUnavailable, healthTimeout := h.upgrades.maxUnavailable, h.upgrades.healthTimeout
+	h.upgrades.mu.Unlock()
+
+	h.logger.Info("Starting automatic template upgrade",
+		slog.String("template_id", templateID),
+		slog.String("version", version),
+		slog.Int("instances", len(targets)),
+		slog.Int("max_unavailable", maxUnavailable))
+
+	go h.runTemplateUpgrade(runCtx, upgrade, targets, maxUnavailable, healthTimeout)
+	return upgrade, nil
+}
+
+// runTemplateUpgrade rolls out an upgrade and pauses it at the first
+// instance that fails to update or become healthy
+func (h *Handler) runTemplateUpgrade(ctx context.Context, upgrade *templateUpgrade, targets []*backends.InstanceStatus, maxUnavailable int, healthTimeout time.Duration) {
+	defer upgrade.cancel()
+
+	results := backends.RollOut(ctx, targets, maxUnavailable, func(ctx context.Context, instance *backends.InstanceStatus) error {
+		return h.rollInstance(ctx, instance, func(spec *backends.InstanceSpec) error {
+			if upgrade.Image != "" {
+				spec.Image = upgrade.Image
+			}
+			spec.Labels = setTemplate(spec.Labels, upgrade.TemplateID, upgrade.Version)
+			return nil
+		}, 0, healthTimeout)
+	})
+
+	h.upgrades.mu.Lock()
+	defer h.upgrades.mu.Unlock()
+
+	upgrade.Results = results
+	upgrade.UpdatedAt = time.Now()
+	if upgrade.State == upgradeCancelled {
+		return
+	}
+
+	upgrade.State = upgradeCompleted
+	for _, result := range results {
+		if result.Result != backends.GroupResultFailed {
+			continue
+		}
+		upgrade.State = upgradePaused
+
+		h.logger.Error("Automatic template upgrade paused",
+			slog.String("template_id", upgrade.TemplateID),
+			slog.String("version", upgrade.Version),
+			slog.String("instance_id", result.InstanceID),
+			slog.String("error", result.Error))
+		if h.eventPublisher != nil {
+			warning := "automatic upgrade to template " + upgrade.TemplateID + " " + upgrade.Version + " paused: " + result.Error
+			if err := h.eventPublisher.PublishWarning(context.Background(), result.InstanceID, result.InstanceID, warning); err != nil {
+				h.logger.Warn("Failed to publish upgrade warning", slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	if upgrade.State == upgradeCompleted {
+		h.logger.Info("Automatic template upgrade completed",
+			slog.String("template_id", upgrade.TemplateID),
+			slog.String("version", upgrade.Version),
+			slog.Int("instances", len(results)))
+	}
+}
+
+// listTemplateUpgrades returns the latest automatic upgrade of each template
+func (h *Handler) listTemplateUpgrades(c *gin.Context) {
+	h.upgrades.mu.Lock()
+	upgrades := make([]templateUpgrade, 0, len(h.upgrades.byTemplate))
+	for _, upgrade := range h.upgrades.byTemplate {
+		upgrades = append(upgrades, *upgrade)
+	}
+	h.upgrades.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"upgrades": upgrades,
+		"total":    len(upgrades),
+	})
+}
+
+// resumeTemplateUpgrade restarts a paused upgrade for the tracking instances
+// that are still outdated
+func (h *Handler) resumeTemplateUpgrade(c *gin.Context) {
+	templateID := c.Param("id")
+
+	h.upgrades.mu.Lock()
+	current, exists := h.upgrades.byTemplate[templateID]
+	running := exists && current.State == upgradeRunning
+	h.upgrades.mu.Unlock()
+
+	if running {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "upgrade_in_progress",
+			Code:    http.StatusConflict,
+			Message: "an upgrade of template " + templateID + " is already running",
+		})
+		return
+	}
+
+	upgrade, err := h.startTemplateUpgrade(c.Request.Context(), templateID)
+	if err != nil {
+		h.logger.Error("Failed to resume template upgrade", slog.String("template_id", templateID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_instances_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if upgrade == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"template_id": templateID,
+			"message":     "No tracking instances are outdated",
+		})
+		return
+	}
+
+	h.upgrades.mu.Lock()
+	defer h.upgrades.mu.Unlock()
+	c.JSON(http.StatusAccepted, *upgrade)
+}
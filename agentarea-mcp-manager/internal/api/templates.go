@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/naming"
+	"github.com/agentarea/mcp-manager/internal/templates"
+)
+
+// templateUpdateTimeout bounds the instance lookup and publish for one template update
+const templateUpdateTimeout = 10 * time.Second
+
+// SetEventPublisher enables template update events
+func (h *Handler) SetEventPublisher(p *events.EventPublisher) {
+	h.eventPublisher = p
+}
+
+// NotifyTemplateUpdated publishes a template update event listing the
+// instances still on an older version, then starts rolling the new version
+// out to instances that track the template. Register it with Loader.OnUpdate.
+func (h *Handler) NotifyTemplateUpdated(update templates.Update) {
+	ctx, cancel := context.WithTimeout(context.Background(), templateUpdateTimeout)
+	defer cancel()
+
+	h.publishTemplateUpdated(ctx, update)
+
+	if _, err := h.startTemplateUpgrade(ctx, update.TemplateID); err != nil {
+		h.logger.Warn("Failed to start automatic template upgrade",
+			slog.String("template_id", update.TemplateID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// publishTemplateUpdated announces a template update with its outdated instances
+func (h *Handler) publishTemplateUpdated(ctx context.Context, update templates.Update) {
+	if h.eventPublisher == nil {
+		return
+	}
+
+	outdated := []string{}
+	if instances, err := h.backend.ListInstances(ctx); err == nil {
+		for _, instance := range instances {
+			if instance.Labels[container.TemplateLabel] == update.TemplateID &&
+				instance.Labels[container.TemplateVersionLabel] != update.Version {
+				outdated = append(outdated, instance.ID)
+			}
+		}
+	} else {
+		h.logger.Warn("Failed to list instances for template update",
+			slog.String("template_id", update.TemplateID),
+			slog.String("error", err.Error()))
+	}
+
+	if err := h.eventPublisher.PublishTemplateUpdated(ctx, events.TemplateUpdatedEvent{
+		TemplateID:        update.TemplateID,
+		PreviousVersion:   update.PreviousVersion,
+		Version:           update.Version,
+		OutdatedInstances: outdated,
+	}); err != nil {
+		h.logger.Warn("Failed to publish template update",
+			slog.String("template_id", update.TemplateID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// reloadTemplates re-reads the template catalog and reports changed versions
+func (h *Handler) reloadTemplates(c *gin.Context) {
+	if h.templateLoader == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "templates_unavailable",
+			Code:    http.StatusServiceUnavailable,
+			Message: "template catalog is not configured",
+		})
+		return
+	}
+
+	updates, err := h.templateLoader.Load()
+	if errors.Is(err, templates.ErrInvalidCatalog) {
+		// The previous catalog stays in use; details are in the diagnostics
+		h.logger.Warn("Rejected invalid template catalog", slog.String("error", err.Error()))
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "invalid_template_catalog",
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to reload templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "template_reload_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	updated := make([]gin.H, 0, len(updates))
+	for _, update := range updates {
+		updated = append(updated, gin.H{
+			"template_id":      update.TemplateID,
+			"previous_version": update.PreviousVersion,
+			"version":          update.Version,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": len(h.templateLoader.List()),
+		"updated":   updated,
+	})
+}
+
+// templateDiagnostics reports the outcome of the last catalog load: the files
+// read and every error and warning with its file, line and field
+func (h *Handler) templateDiagnostics(c *gin.Context) {
+	if h.templateLoader == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "templates_unavailable",
+			Code:    http.StatusServiceUnavailable,
+			Message: "template catalog is not configured",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.templateLoader.Diagnostics())
+}
+
+// templateVersion returns the current version of a catalog template
+func (h *Handler) templateVersion(key string) (string, bool) {
+	if h.templateLoader == nil {
+		return "", false
+	}
+	return h.templateLoader.Version(key)
+}
+
+// isOutdated reports whether an instance was created from an older version
+// of a template that is still in the catalog
+func (h *Handler) isOutdated(instance *backends.InstanceStatus) bool {
+	key := instance.Labels[container.TemplateLabel]
+	if key == "" {
+		return false
+	}
+	current, ok := h.templateVersion(key)
+	return ok && instance.Labels[container.TemplateVersionLabel] != current
+}
+
+// setTemplate records the template and version an instance was created from,
+// and the template it is billed to
+func setTemplate(labels map[string]string, template, version string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, container.TemplateLabel)
+	delete(labels, container.TemplateVersionLabel)
+	delete(labels, container.CostTemplateLabel)
+	if template != "" {
+		labels[container.TemplateLabel] = template
+		labels[container.CostTemplateLabel] = naming.Normalize(template, naming.MaxLabelLength)
+		if version != "" {
+			labels[container.TemplateVersionLabel] = version
+		}
+	}
+	return labels
+}
+
+// listWarmPools returns the pre-started containers kept for templates with a warm pool
+func (h *Handler) listWarmPools(c *gin.Context) {
+	pools := []container.WarmPoolStatus{}
+	if h.containerManager != nil {
+		if status := h.containerManager.WarmPoolStatus(); status != nil {
+			pools = status
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pools": pools,
+		"total": len(pools),
+	})
+}
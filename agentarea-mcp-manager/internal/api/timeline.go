@@ -0,0 +1,102 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// defaultTimelineLimit bounds the steps returned without ?limit=
+const defaultTimelineLimit = 200
+
+// SetTimeline enables the lifecycle history of instances
+func (h *Handler) SetTimeline(timeline *events.TimelineRecorder) {
+	h.timeline = timeline
+}
+
+// timelineEntry is a lifecycle step with the time it took until the next one
+type timelineEntry struct {
+	events.TimelineStep
+	DurationMS   *int64 `json:"duration_ms,omitempty"` // Until the next step; unset for the latest
+	SinceStartMS int64  `json:"since_start_ms"`        // Since the first step returned
+}
+
+// getInstanceTimeline returns the lifecycle steps of an instance, oldest
+// first. A deleted instance is looked up by its service name.
+func (h *Handler) getInstanceTimeline(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	limit := defaultTimelineLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: "limit must be a positive integer",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	serviceName := instanceID
+	if instance, err := h.backend.GetInstanceStatus(c.Request.Context(), instanceID); err == nil {
+		serviceName = instance.ServiceName
+	}
+
+	steps, err := h.timeline.Timeline(c.Request.Context(), serviceName, limit)
+	if err != nil {
+		h.logger.Error("Failed to load instance timeline",
+			slog.String("instance_id", instanceID),
+			slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "timeline_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(steps) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "instance_not_found",
+			Code:    http.StatusNotFound,
+			Message: "no lifecycle steps recorded for " + instanceID,
+		})
+		return
+	}
+
+	entries := make([]timelineEntry, len(steps))
+	for i, step := range steps {
+		entries[i] = timelineEntry{TimelineStep: step, SinceStartMS: step.Timestamp.Sub(steps[0].Timestamp).Milliseconds()}
+		if i+1 < len(steps) {
+			duration := steps[i+1].Timestamp.Sub(step.Timestamp).Milliseconds()
+			entries[i].DurationMS = &duration
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id":  instanceID,
+		"service_name": serviceName,
+		"steps":        entries,
+		"total":        len(entries),
+		"timestamp":    time.Now(),
+	})
+}
+
+// changedFields summarizes the fields an update changed, without values
+func changedFields(changes []backends.FieldChange) string {
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/variables"
+)
+
+// SetVariableStore enables workspace variable management and ${workspace.NAME}
+// references in instance environments
+func (h *Handler) SetVariableStore(store *variables.Store) {
+	h.variables = store
+}
+
+// resolveVariables expands workspace variable references in the spec
+// environment. It responds 422 and returns false if any are undefined.
+func (h *Handler) resolveVariables(c *gin.Context, spec *backends.InstanceSpec) bool {
+	if h.variables == nil || !variables.HasReferences(spec.Environment) {
+		return true
+	}
+
+	env, err := h.variables.Resolve(c.Request.Context(), spec.WorkspaceID, spec.Environment)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "unresolved_variables",
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	spec.Environment = env
+	return true
+}
+
+// listVariables returns the variable set of a workspace
+func (h *Handler) listVariables(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	vars, err := h.variables.List(c.Request.Context(), workspaceID)
+	if err != nil {
+		h.logger.Error("Failed to list workspace variables", slog.String("workspace_id", workspaceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "variables_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"variables":    vars,
+		"total":        len(vars),
+	})
+}
+
+// replaceVariables replaces the whole variable set of a workspace
+func (h *Handler) replaceVariables(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	var req struct {
+		Variables map[string]string `json:"variables"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	for name := range req.Variables {
+		if err := variables.ValidateName(name); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_variable_name",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.variables.Replace(c.Request.Context(), workspaceID, req.Variables); err != nil {
+		h.logger.Error("Failed to replace workspace variables", slog.String("workspace_id", workspaceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "variables_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"variables":    req.Variables,
+		"total":        len(req.Variables),
+	})
+}
+
+// setVariable creates or updates one workspace variable
+func (h *Handler) setVariable(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+	name := c.Param("name")
+
+	var req struct {
+		Value *string `json:"value" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := variables.ValidateName(name); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_variable_name",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.variables.Set(c.Request.Context(), workspaceID, name, *req.Value); err != nil {
+		h.logger.Error("Failed to set workspace variable", slog.String("workspace_id", workspaceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "variables_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspace_id": workspaceID,
+		"name":         name,
+		"value":        *req.Value,
+	})
+}
+
+// deleteVariable removes one workspace variable
+func (h *Handler) deleteVariable(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+	name := c.Param("name")
+
+	deleted, err := h.variables.Delete(c.Request.Context(), workspaceID, name)
+	if err != nil {
+		h.logger.Error("Failed to delete workspace variable", slog.String("workspace_id", workspaceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "variables_unavailable",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "variable_not_found",
+			Code:    http.StatusNotFound,
+			Message: "variable " + name + " is not defined in workspace " + workspaceID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Variable deleted successfully",
+		"workspace_id": workspaceID,
+		"name":         name,
+	})
+}
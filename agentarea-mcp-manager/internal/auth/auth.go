@@ -0,0 +1,89 @@
+// Package auth authenticates API requests with static API keys or JWT bearer
+// tokens signed by keys published as a JWKS.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// APIKeyHeader carries an API key; keys are also accepted as bearer tokens
+const APIKeyHeader = "X-API-Key"
+
+// ErrNoCredentials is returned for requests without an API key or bearer token
+var ErrNoCredentials = errors.New("missing API key or bearer token")
+
+// ErrInvalidCredentials is returned for credentials that are not accepted
+var ErrInvalidCredentials = errors.New("invalid API key or bearer token")
+
+// Principal is who an authenticated request came from
+type Principal struct {
+	Method  string // "api_key" or "jwt"
+	Subject string // sub claim of a JWT; key index for API keys
+}
+
+// Authenticator checks the credentials of requests
+type Authenticator struct {
+	apiKeys [][sha256.Size]byte // Hashed so comparisons take constant time regardless of length
+	jwt     *jwtVerifier        // nil without a JWKS URL
+}
+
+// New creates an authenticator for the configured API keys and JWT issuer
+func New(cfg config.AuthConfig, logger *slog.Logger) *Authenticator {
+	a := &Authenticator{}
+	for _, key := range cfg.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			a.apiKeys = append(a.apiKeys, sha256.Sum256([]byte(key)))
+		}
+	}
+	if cfg.JWKSURL != "" {
+		a.jwt = newJWTVerifier(cfg, logger)
+	}
+	return a
+}
+
+// Authenticate returns the principal of a request carrying a valid API key in
+// X-API-Key, or a valid API key or JWT as Authorization bearer token
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.apiKey(key)
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	token = strings.TrimSpace(token)
+
+	// A JWT has three dot-separated parts; anything else can only be a key
+	if strings.Count(token, ".") == 2 && a.jwt != nil {
+		subject, err := a.jwt.verify(r.Context(), token)
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{Method: "jwt", Subject: subject}, nil
+	}
+	return a.apiKey(token)
+}
+
+// apiKey checks a presented API key against every configured one
+func (a *Authenticator) apiKey(presented string) (Principal, error) {
+	sum := sha256.Sum256([]byte(presented))
+	match := -1
+	for i, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare(sum[:], key[:]) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Method: "api_key", Subject: "key-" + strconv.Itoa(match)}, nil
+}
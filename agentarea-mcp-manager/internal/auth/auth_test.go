@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func b64(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + b64(signature)
+}
+
+func request(header, value string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/instances", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestAPIKeys(t *testing.T) {
+	a := New(config.AuthConfig{APIKeys: []string{"first", " second ", ""}}, discard)
+
+	if p, err := a.Authenticate(request(APIKeyHeader, "second")); err != nil || p.Method != "api_key" || p.Subject != "key-1" {
+		t.Errorf("X-API-Key: %+v, %v", p, err)
+	}
+	if _, err := a.Authenticate(request("Authorization", "Bearer first")); err != nil {
+		t.Errorf("bearer API key: %v", err)
+	}
+	if _, err := a.Authenticate(request(APIKeyHeader, "third")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong key = %v", err)
+	}
+	if _, err := a.Authenticate(request(APIKeyHeader, "")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("no credentials = %v", err)
+	}
+	if _, err := a.Authenticate(request("Authorization", "Basic Zmlyc3Q=")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("basic auth = %v", err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetches := 0
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "k1", "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())},
+			{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		}})
+	}))
+	defer jwks.Close()
+
+	a := New(config.AuthConfig{
+		JWKSURL:     jwks.URL,
+		JWTIssuer:   "https://issuer.example",
+		JWTAudience: "mcp-manager",
+		JWKSRefresh: time.Hour,
+		ClockSkew:   time.Minute,
+	}, discard)
+	claims := func(modify func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss": "https://issuer.example",
+			"aud": []string{"other", "mcp-manager"},
+			"sub": "agent-42",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	bearer := func(token string) *http.Request { return request("Authorization", "Bearer "+token) }
+
+	p, err := a.Authenticate(bearer(signRS256(t, key, "k1", claims(nil))))
+	if err != nil || p.Method != "jwt" || p.Subject != "agent-42" {
+		t.Fatalf("valid JWT: %+v, %v", p, err)
+	}
+
+	for name, token := range map[string]string{
+		"expired":      signRS256(t, key, "k1", claims(func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+		"no expiry":    signRS256(t, key, "k1", claims(func(c map[string]any) { delete(c, "exp") })),
+		"not yet":      signRS256(t, key, "k1", claims(func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+		"issuer":       signRS256(t, key, "k1", claims(func(c map[string]any) { c["iss"] = "https://evil.example" })),
+		"audience":     signRS256(t, key, "k1", claims(func(c map[string]any) { c["aud"] = "other" })),
+		"unknown kid":  signRS256(t, key, "k2", claims(nil)),
+		"tampered":     signRS256(t, key, "k1", claims(nil))[:10] + "x" + signRS256(t, key, "k1", claims(nil))[11:],
+		"alg none":     b64([]byte(`{"alg":"none","kid":"k1"}`)) + "." + b64([]byte(`{"sub":"x"}`)) + ".",
+		"hmac confuse": b64([]byte(`{"alg":"HS256","kid":"k1"}`)) + "." + b64([]byte(`{"sub":"x"}`)) + ".c2ln",
+	} {
+		if _, err := a.Authenticate(bearer(token)); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: err = %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+
+	// Unknown kids refetch at most once per jwksRetry
+	if fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", fetches)
+	}
+}
+
+func TestJWTWithECKeyAndUnavailableJWKS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	available := true
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "EC", "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))},
+		}})
+	}))
+	defer jwks.Close()
+
+	header := b64([]byte(`{"alg":"ES256"}`))
+	payload, _ := json.Marshal(map[string]any{"sub": "svc", "exp": time.Now().Add(time.Hour).Unix()})
+	signingInput := header + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signingInput + "." + b64(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...))
+
+	// Without keys, verification is unavailable rather than denied
+	available = false
+	down := New(config.AuthConfig{JWKSURL: jwks.URL, JWKSRefresh: time.Hour}, discard)
+	if _, err := down.Authenticate(request("Authorization", "Bearer "+token)); !errors.Is(err, ErrKeysUnavailable) {
+		t.Errorf("JWKS down: err = %v, want ErrKeysUnavailable", err)
+	}
+
+	available = true
+	a := New(config.AuthConfig{JWKSURL: jwks.URL, JWKSRefresh: time.Hour}, discard)
+	if p, err := a.Authenticate(request("Authorization", "Bearer "+token)); err != nil || p.Subject != "svc" {
+		t.Errorf("ES256 JWT without kid: %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,369 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// ErrKeysUnavailable is returned when a JWT cannot be verified because the
+// JWKS could not be fetched and no keys are cached
+var ErrKeysUnavailable = errors.New("JWT signing keys unavailable")
+
+// jwksRetry is the least time between fetches triggered by unknown key IDs,
+// so tokens with made-up kids cannot hammer the JWKS endpoint
+const jwksRetry = time.Minute
+
+// jwtVerifier verifies JWTs against the keys of a JWKS, which is fetched on
+// first use and again once older than the refresh interval or when a token
+// is signed with an unknown key
+type jwtVerifier struct {
+	jwksURL  string
+	issuer   string
+	audience string
+	refresh  time.Duration
+	skew     time.Duration
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey // By kid
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func newJWTVerifier(cfg config.AuthConfig, logger *slog.Logger) *jwtVerifier {
+	return &jwtVerifier{
+		jwksURL:  cfg.JWKSURL,
+		issuer:   cfg.JWTIssuer,
+		audience: cfg.JWTAudience,
+		refresh:  cfg.JWKSRefresh,
+		skew:     cfg.ClockSkew,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+	}
+}
+
+// jwtHeader is the JOSE header of a token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims are the registered claims checked by the verifier
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"` // A string or an array of strings
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// verify checks the signature and claims of a token and returns its subject
+func (v *jwtVerifier) verify(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed JWT", ErrInvalidCredentials)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("%w: malformed JWT header", ErrInvalidCredentials)
+	}
+	hash, ok := algorithmHash(header.Alg)
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported JWT algorithm %q", ErrInvalidCredentials, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed JWT signature", ErrInvalidCredentials)
+	}
+
+	keys, err := v.keysFor(ctx, header.Kid)
+	if err != nil {
+		return "", err
+	}
+	digest := hash.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	signed := digest.Sum(nil)
+
+	verified := false
+	for _, key := range keys {
+		if verifySignature(header.Alg, hash, key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return "", fmt.Errorf("%w: JWT signature does not verify", ErrInvalidCredentials)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("%w: malformed JWT claims", ErrInvalidCredentials)
+	}
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+// checkClaims checks the issuer, audience and validity period of a token
+func (v *jwtVerifier) checkClaims(claims jwtClaims, now time.Time) error {
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: JWT issuer %q not accepted", ErrInvalidCredentials, claims.Issuer)
+	}
+	if v.audience != "" && !hasAudience(claims.Audience, v.audience) {
+		return fmt.Errorf("%w: JWT not issued for audience %q", ErrInvalidCredentials, v.audience)
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: JWT has no expiry", ErrInvalidCredentials)
+	}
+	if now.Add(-v.skew).After(unixTime(*claims.ExpiresAt)) {
+		return fmt.Errorf("%w: JWT expired", ErrInvalidCredentials)
+	}
+	if claims.NotBefore != nil && now.Add(v.skew).Before(unixTime(*claims.NotBefore)) {
+		return fmt.Errorf("%w: JWT not valid yet", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// keysFor returns the key with the kid, or every key for tokens without one.
+// The JWKS is fetched when stale or, at most once per jwksRetry, when the kid
+// is unknown; a failed fetch keeps the cached keys.
+func (v *jwtVerifier) keysFor(ctx context.Context, kid string) ([]crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, known := v.keys[kid]
+	stale := v.keys == nil || time.Since(v.fetchedAt) >= v.refresh
+	if (stale || (kid != "" && !known)) && time.Since(v.attemptedAt) >= jwksRetry {
+		v.attemptedAt = time.Now()
+		keys, err := v.fetch(ctx)
+		if err != nil {
+			v.logger.Warn("Failed to fetch JWKS",
+				slog.String("url", v.jwksURL),
+				slog.String("error", err.Error()))
+		} else {
+			v.keys, v.fetchedAt = keys, time.Now()
+		}
+	}
+	if v.keys == nil {
+		return nil, ErrKeysUnavailable
+	}
+
+	if kid != "" {
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown JWT key %q", ErrInvalidCredentials, kid)
+		}
+		return []crypto.PublicKey{key}, nil
+	}
+	keys := make([]crypto.PublicKey, 0, len(v.keys))
+	for _, key := range v.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// jwk is a key of a JWKS; only the members of RSA and EC signing keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch downloads the JWKS and parses its signing keys. Keys of other types
+// or uses are skipped.
+func (v *jwtVerifier) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint answered %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			v.logger.Warn("Skipping JWKS key", slog.String("kid", k.Kid), slog.String("error", err.Error()))
+			continue
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey parses an RSA or EC key; other key types return nil
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key of %d bits is too short", n.BitLen())
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := decodeInt(k.X)
+		y, errY := decodeInt(k.Y)
+		if errX != nil || errY != nil || !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+// algorithmHash returns the hash of a supported signing algorithm. HMAC and
+// "none" are not supported: tokens must be signed with a key of the JWKS.
+func algorithmHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, true
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, true
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// verifySignature checks a signature made with alg over the digest
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, digest, signature []byte) bool {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(pub, hash, digest, signature) == nil
+		case "PS":
+			return rsa.VerifyPSS(pub, hash, digest, signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		// ES256 is only valid with P-256 and so on
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(signature) != 2*size || pub.Curve != curveFor(alg) {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+	return false
+}
+
+// curveFor returns the curve an ES algorithm signs with
+func curveFor(alg string) elliptic.Curve {
+	switch alg {
+	case "ES256":
+		return elliptic.P256()
+	case "ES384":
+		return elliptic.P384()
+	case "ES512":
+		return elliptic.P521()
+	}
+	return nil
+}
+
+// hasAudience reports whether an aud claim, a string or an array of
+// strings, includes audience
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		for _, aud := range list {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeInt decodes a base64url big-endian integer of a JWK
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// unixTime converts a NumericDate claim
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
@@ -0,0 +1,133 @@
+package backends
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Optional backend capabilities. Handlers type-assert the backend and answer
+// 501 when it does not implement one, so endpoints built on them work the
+// same way across backends.
+const (
+	CapabilityLogs  = "logs"
+	CapabilityExec  = "exec"
+	CapabilityStats = "stats"
+	CapabilityWatch = "watch"
+	CapabilityGC    = "gc"
+	CapabilityDrift = "drift"
+	CapabilityImage = "image_metadata"
+)
+
+// LogOptions selects the log lines to stream
+type LogOptions struct {
+	Tail       int       // Last lines to return; 0 returns all
+	Since      time.Time // Only lines after this time; zero returns all
+	Follow     bool      // Keep streaming new lines until ctx is done
+	Timestamps bool
+}
+
+// LogStreamer is implemented by backends that can stream instance logs
+type LogStreamer interface {
+	StreamLogs(ctx context.Context, instanceID string, opts LogOptions, w io.Writer) error
+}
+
+// ExecResult is the output of a command run inside an instance
+type ExecResult struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// Execer is implemented by backends that can run commands inside instances
+type Execer interface {
+	Exec(ctx context.Context, instanceID string, command []string) (*ExecResult, error)
+}
+
+// InstanceStats is a resource usage sample of an instance
+type InstanceStats struct {
+	CPUPercent  float64   `json:"cpu_percent"` // 100 per fully used core
+	MemoryBytes uint64    `json:"memory_bytes"`
+	MemoryLimit uint64    `json:"memory_limit_bytes,omitempty"`
+	NetOutput   uint64    `json:"net_output_bytes"`
+	PIDs        uint64    `json:"pids"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// StatsProvider is implemented by backends that can sample instance resource usage
+type StatsProvider interface {
+	InstanceStats(ctx context.Context, instanceID string) (*InstanceStats, error)
+}
+
+// InstanceEvent is a lifecycle event of an instance reported by the backend
+type InstanceEvent struct {
+	InstanceID string    `json:"instance_id"`
+	Type       string    `json:"type"` // Backend-specific, e.g. start, died, pod_modified
+	Message    string    `json:"message,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Watcher is implemented by backends that can stream instance lifecycle
+// events. The channel is closed when ctx is done or the stream ends.
+type Watcher interface {
+	Watch(ctx context.Context, instanceID string) (<-chan InstanceEvent, error)
+}
+
+// SubmittedSpec is the spec an instance was created with and the origin of
+// each variable of its effective environment
+type SubmittedSpec struct {
+	Spec        map[string]any            `json:"spec"` // Redacted
+	Environment []container.EnvAuditEntry `json:"environment"`
+	SubmittedAt time.Time                 `json:"submitted_at"`
+}
+
+// SpecProvider is implemented by backends that remember the spec instances
+// were submitted with. SubmittedSpec returns nil for instances created before
+// the manager started.
+type SpecProvider interface {
+	SubmittedSpec(ctx context.Context, instanceID string) (*SubmittedSpec, error)
+}
+
+// DriftDetector is implemented by backends that can compare a running
+// instance with its desired spec and, with fix set, recreate it from the spec
+type DriftDetector interface {
+	CheckDrift(ctx context.Context, instanceID string, fix bool) (*container.DriftReport, error)
+}
+
+// ImageInspector is implemented by backends that can read the mcp.* labels,
+// exposed ports and entrypoint of an image. ImageMetadata returns nil for
+// images declaring none of them; with pull set, an image missing locally is
+// pulled first.
+type ImageInspector interface {
+	ImageMetadata(ctx context.Context, image string, pull bool) (*models.ImageMetadata, error)
+}
+
+// Capabilities lists the optional capabilities a backend implements
+func Capabilities(b Backend) []string {
+	capabilities := []string{}
+	if _, ok := b.(LogStreamer); ok {
+		capabilities = append(capabilities, CapabilityLogs)
+	}
+	if _, ok := b.(Execer); ok {
+		capabilities = append(capabilities, CapabilityExec)
+	}
+	if _, ok := b.(StatsProvider); ok {
+		capabilities = append(capabilities, CapabilityStats)
+	}
+	if _, ok := b.(Watcher); ok {
+		capabilities = append(capabilities, CapabilityWatch)
+	}
+	if _, ok := b.(GarbageCollector); ok {
+		capabilities = append(capabilities, CapabilityGC)
+	}
+	if _, ok := b.(DriftDetector); ok {
+		capabilities = append(capabilities, CapabilityDrift)
+	}
+	if _, ok := b.(ImageInspector); ok {
+		capabilities = append(capabilities, CapabilityImage)
+	}
+	return capabilities
+}
@@ -0,0 +1,17 @@
+package backends
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	if got := Capabilities(&DockerBackend{}); !reflect.DeepEqual(got, []string{CapabilityLogs, CapabilityExec, CapabilityStats, CapabilityWatch, CapabilityDrift, CapabilityImage}) {
+		t.Errorf("Docker capabilities = %v", got)
+	}
+	// Kubernetes has no exec or stats without extra cluster components, and
+	// collects resources left behind by interrupted creations
+	if got := Capabilities(&KubernetesBackend{}); !reflect.DeepEqual(got, []string{CapabilityLogs, CapabilityWatch, CapabilityGC}) {
+		t.Errorf("Kubernetes capabilities = %v", got)
+	}
+}
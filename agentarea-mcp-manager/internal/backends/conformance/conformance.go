@@ -0,0 +1,244 @@
+// Package conformance is the contract every backends.Backend implementation
+// must pass: the create, status, list, update, health, stop/start and delete lifecycle,
+// idempotency of repeated calls and the shape of errors. New backends run
+// Run from their tests; the in-memory fake backend is checked against it in
+// CI, real runtimes locally.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+)
+
+// Factory returns a fresh, initialized backend for one run
+type Factory func(t *testing.T) backends.Backend
+
+// Options tunes the harness to a runtime
+type Options struct {
+	Image       string        // Image to create; defaults to a small public MCP server
+	UpdateImage string        // Image to update to; defaults to Image
+	Port        int           // Container port; defaults to 8000
+	Timeout     time.Duration // How long state may take to converge; defaults to 2m
+}
+
+// Statuses an instance may report
+var knownStatuses = map[string]bool{
+	"validating": true, "pulling": true, "pending": true, "starting": true, "partial": true,
+	"running": true, "healthy": true, "unhealthy": true, "stopping": true, "stopped": true,
+	"error": true, "unknown": true,
+}
+
+// missingID is an instance ID no backend should know
+const missingID = "conformance-missing-instance"
+
+// Run checks a backend against the contract. Each step is a subtest; a
+// failed step stops the run since later steps depend on it.
+func Run(t *testing.T, newBackend Factory, opts Options) {
+	if opts.Image == "" {
+		opts.Image = "ghcr.io/modelcontextprotocol/servers/everything:latest"
+	}
+	if opts.UpdateImage == "" {
+		opts.UpdateImage = opts.Image
+	}
+	if opts.Port == 0 {
+		opts.Port = 8000
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+
+	ctx := context.Background()
+	backend := newBackend(t)
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano()%1_000_000)
+	spec := &backends.InstanceSpec{
+		Name:        "conformance-" + suffix,
+		ServiceName: "conformance-" + suffix,
+		InstanceID:  "conformance-id-" + suffix,
+		Image:       opts.Image,
+		Port:        opts.Port,
+		Environment: map[string]string{"CONFORMANCE": "true"},
+		Labels:      map[string]string{"team": "conformance"},
+	}
+	t.Cleanup(func() {
+		_ = backend.DeleteInstance(context.Background(), spec.InstanceID)
+	})
+
+	var created *backends.InstanceResult
+	steps := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{"UnknownInstance", func(t *testing.T) {
+			_, err := backend.GetInstanceStatus(ctx, missingID)
+			expectNotFound(t, "GetInstanceStatus", err)
+			_, err = backend.PerformHealthCheck(ctx, missingID)
+			expectNotFound(t, "PerformHealthCheck", err)
+			expectNotFound(t, "UpdateInstance", backend.UpdateInstance(ctx, missingID, spec))
+			expectNotFound(t, "DeleteInstance", backend.DeleteInstance(ctx, missingID))
+			expectNotFound(t, "StopInstance", backend.StopInstance(ctx, missingID))
+			expectNotFound(t, "StartInstance", backend.StartInstance(ctx, missingID))
+			expectNotFound(t, "RestartInstance", backend.RestartInstance(ctx, missingID))
+		}},
+		{"Create", func(t *testing.T) {
+			result, err := backend.CreateInstance(ctx, spec)
+			if err != nil {
+				t.Fatalf("CreateInstance: %v", err)
+			}
+			if result.ID == "" || result.Name == "" {
+				t.Fatalf("CreateInstance returned no ID or name: %+v", result)
+			}
+			created = result
+		}},
+		{"Status", func(t *testing.T) {
+			// Instances are addressed by the spec instance ID and by the
+			// ID returned from CreateInstance
+			for _, id := range []string{spec.InstanceID, created.ID} {
+				status, err := backend.GetInstanceStatus(ctx, id)
+				if err != nil {
+					t.Fatalf("GetInstanceStatus(%s): %v", id, err)
+				}
+				if status.Image != spec.Image || status.Port != spec.Port {
+					t.Errorf("GetInstanceStatus(%s) = image %q port %d, want %q %d", id, status.Image, status.Port, spec.Image, spec.Port)
+				}
+				if !knownStatuses[status.Status] {
+					t.Errorf("GetInstanceStatus(%s) reported unknown status %q", id, status.Status)
+				}
+				if status.ResourceVersion == "" {
+					t.Errorf("GetInstanceStatus(%s) reported no resource version", id)
+				}
+			}
+		}},
+		{"List", func(t *testing.T) {
+			if n := countListed(ctx, t, backend, created.Name); n != 1 {
+				t.Fatalf("ListInstances lists %s %d times, want 1", created.Name, n)
+			}
+		}},
+		{"CreateIsNotDuplicated", func(t *testing.T) {
+			// A repeated create may fail or return the existing instance, but
+			// must not start a second one
+			_, _ = backend.CreateInstance(ctx, spec)
+			if n := countListed(ctx, t, backend, created.Name); n != 1 {
+				t.Fatalf("ListInstances lists %s %d times after a repeated create, want 1", created.Name, n)
+			}
+		}},
+		{"UpdateConflict", func(t *testing.T) {
+			stale := *spec
+			stale.ResourceVersion = "conformance-stale-version"
+			if err := backend.UpdateInstance(ctx, spec.InstanceID, &stale); !errors.Is(err, backends.ErrConflict) {
+				t.Fatalf("UpdateInstance with a stale resource version = %v, want ErrConflict", err)
+			}
+		}},
+		{"Update", func(t *testing.T) {
+			current, err := backend.GetInstanceStatus(ctx, spec.InstanceID)
+			if err != nil {
+				t.Fatalf("GetInstanceStatus: %v", err)
+			}
+			updated := *spec
+			updated.Image = opts.UpdateImage
+			updated.Environment = map[string]string{"CONFORMANCE": "updated"}
+			updated.ResourceVersion = current.ResourceVersion
+			if err := backend.UpdateInstance(ctx, spec.InstanceID, &updated); err != nil {
+				t.Fatalf("UpdateInstance: %v", err)
+			}
+
+			eventually(t, opts.Timeout, "the update to be visible", func() bool {
+				status, err := backend.GetInstanceStatus(ctx, spec.InstanceID)
+				return err == nil && status.Image == opts.UpdateImage && status.ResourceVersion != current.ResourceVersion
+			})
+		}},
+		{"Health", func(t *testing.T) {
+			result, err := backend.PerformHealthCheck(ctx, spec.InstanceID)
+			if err != nil {
+				t.Fatalf("PerformHealthCheck: %v", err)
+			}
+			if result.Timestamp.IsZero() {
+				t.Error("PerformHealthCheck returned no timestamp")
+			}
+		}},
+		{"StopStart", func(t *testing.T) {
+			if err := backend.StopInstance(ctx, spec.InstanceID); err != nil {
+				t.Fatalf("StopInstance: %v", err)
+			}
+			eventually(t, opts.Timeout, "the instance to stop", func() bool {
+				status, err := backend.GetInstanceStatus(ctx, spec.InstanceID)
+				return err == nil && status.Status == "stopped"
+			})
+			// A stopped instance is still listed
+			if n := countListed(ctx, t, backend, created.Name); n != 1 {
+				t.Fatalf("ListInstances lists a stopped %s %d times, want 1", created.Name, n)
+			}
+			if err := backend.StartInstance(ctx, spec.InstanceID); err != nil {
+				t.Fatalf("StartInstance: %v", err)
+			}
+			eventually(t, opts.Timeout, "the instance to start", func() bool {
+				status, err := backend.GetInstanceStatus(ctx, spec.InstanceID)
+				return err == nil && status.Status != "stopped"
+			})
+			if err := backend.RestartInstance(ctx, spec.InstanceID); err != nil {
+				t.Fatalf("RestartInstance: %v", err)
+			}
+		}},
+		{"Delete", func(t *testing.T) {
+			if err := backend.DeleteInstance(ctx, spec.InstanceID); err != nil {
+				t.Fatalf("DeleteInstance: %v", err)
+			}
+			eventually(t, opts.Timeout, "the instance to be gone", func() bool {
+				_, err := backend.GetInstanceStatus(ctx, spec.InstanceID)
+				return errors.Is(err, backends.ErrNotFound)
+			})
+			if n := countListed(ctx, t, backend, created.Name); n != 0 {
+				t.Errorf("ListInstances still lists %s after delete", created.Name)
+			}
+			// Deleting again reports the instance as missing
+			expectNotFound(t, "DeleteInstance of a deleted instance", backend.DeleteInstance(ctx, spec.InstanceID))
+		}},
+	}
+
+	for _, step := range steps {
+		if !t.Run(step.name, step.run) {
+			return
+		}
+	}
+}
+
+// expectNotFound fails unless err wraps backends.ErrNotFound
+func expectNotFound(t *testing.T, operation string, err error) {
+	t.Helper()
+	if !errors.Is(err, backends.ErrNotFound) {
+		t.Errorf("%s of an unknown instance = %v, want ErrNotFound", operation, err)
+	}
+}
+
+// countListed counts the listed instances with a name
+func countListed(ctx context.Context, t *testing.T, backend backends.Backend, name string) int {
+	t.Helper()
+	instances, err := backend.ListInstances(ctx)
+	if err != nil {
+		t.Fatalf("ListInstances: %v", err)
+	}
+	n := 0
+	for _, instance := range instances {
+		if instance.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// eventually polls cond until it holds or timeout passes
+func eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,33 @@
+package conformance
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+func TestFakeBackend(t *testing.T) {
+	Run(t, func(t *testing.T) backends.Backend {
+		return backends.NewFakeBackend(config.FakeConfig{}, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	}, Options{})
+}
+
+// TestDocker runs the contract against the local Podman runtime. It needs a
+// running Podman and is skipped unless CONFORMANCE_DOCKER=1.
+func TestDocker(t *testing.T) {
+	if os.Getenv("CONFORMANCE_DOCKER") != "1" {
+		t.Skip("set CONFORMANCE_DOCKER=1 to run against the local Podman runtime")
+	}
+	Run(t, func(t *testing.T) backends.Backend {
+		backend := backends.NewDockerBackend(config.Load(), slog.New(slog.NewTextHandler(os.Stderr, nil)))
+		if err := backend.Initialize(context.Background()); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		t.Cleanup(func() { _ = backend.Shutdown(context.Background()) })
+		return backend
+	}, Options{Image: os.Getenv("CONFORMANCE_IMAGE")})
+}
@@ -0,0 +1,154 @@
+package backends
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// FieldChange describes a single difference between an instance and an update.
+// Environment values are never included since they may hold secrets.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Change string      `json:"change"` // "added", "removed" or "modified"
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+// UpdateImpact describes how a backend applies an update
+type UpdateImpact struct {
+	Strategy          string        `json:"strategy"` // "none", "recreate" or "rolling"
+	RestartRequired   bool          `json:"restart_required"`
+	Recreate          bool          `json:"recreate"`
+	EstimatedDowntime time.Duration `json:"-"`
+}
+
+// UpdatePlanner is implemented by backends that can describe the impact of an update
+type UpdatePlanner interface {
+	PlanUpdate(current *InstanceStatus, spec *InstanceSpec, changes []FieldChange) UpdateImpact
+}
+
+// DiffInstance compares the current instance with the spec an update would apply
+func DiffInstance(current *InstanceStatus, spec *InstanceSpec) []FieldChange {
+	changes := []FieldChange{}
+
+	if spec.Image != current.Image {
+		changes = append(changes, FieldChange{Field: "image", Change: "modified", From: current.Image, To: spec.Image})
+	}
+	if spec.Port != current.Port {
+		changes = append(changes, FieldChange{Field: "port", Change: "modified", From: current.Port, To: spec.Port})
+	}
+	// An update without a command or resources keeps the current ones
+	if len(spec.Command) > 0 && !slices.Equal(spec.Command, current.Command) {
+		change := FieldChange{Field: "command", Change: "modified", To: strings.Join(spec.Command, " ")}
+		if len(current.Command) > 0 {
+			change.From = strings.Join(current.Command, " ")
+		}
+		changes = append(changes, change)
+	}
+
+	changes = append(changes, diffMap("environment", current.Environment, spec.Environment, false)...)
+	changes = append(changes, diffMap("labels", UserLabels(current.Labels), UserLabels(spec.Labels), true)...)
+
+	if wasProtected, protected := IsProtected(current.Labels), IsProtected(spec.Labels); wasProtected != protected {
+		changes = append(changes, FieldChange{Field: "protected", Change: "modified", From: wasProtected, To: protected})
+	}
+
+	resources := map[string]string{
+		"resources.requests.cpu":    spec.Resources.Requests.CPU,
+		"resources.requests.memory": spec.Resources.Requests.Memory,
+		"resources.limits.cpu":      spec.Resources.Limits.CPU,
+		"resources.limits.memory":   spec.Resources.Limits.Memory,
+	}
+	currentResources := map[string]string{
+		"resources.requests.cpu":    current.Resources.Requests.CPU,
+		"resources.requests.memory": current.Resources.Requests.Memory,
+		"resources.limits.cpu":      current.Resources.Limits.CPU,
+		"resources.limits.memory":   current.Resources.Limits.Memory,
+	}
+	for _, field := range sortedKeys(resources) {
+		from, to := currentResources[field], resources[field]
+		if to == "" || sameQuantity(from, to) {
+			continue
+		}
+		change := FieldChange{Field: field, Change: "modified", To: to}
+		if from != "" {
+			change.From = from
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// diffMap compares two string maps key by key
+func diffMap(prefix string, from, to map[string]string, showValues bool) []FieldChange {
+	keys := make(map[string]struct{}, len(from)+len(to))
+	for k := range from {
+		keys[k] = struct{}{}
+	}
+	for k := range to {
+		keys[k] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	changes := []FieldChange{}
+	for _, key := range sorted {
+		oldValue, hadKey := from[key]
+		newValue, hasKey := to[key]
+		change := FieldChange{Field: fmt.Sprintf("%s.%s", prefix, key)}
+
+		switch {
+		case hadKey && !hasKey:
+			change.Change = "removed"
+		case !hadKey && hasKey:
+			change.Change = "added"
+		case oldValue != newValue:
+			change.Change = "modified"
+		default:
+			continue
+		}
+
+		if showValues {
+			if hadKey {
+				change.From = oldValue
+			}
+			if hasKey {
+				change.To = newValue
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// sameQuantity reports whether two resource quantities are equal, so that
+// "0.5" and "500m" are not reported as a change
+func sameQuantity(a, b string) bool {
+	qa, errA := resource.ParseQuantity(a)
+	qb, errB := resource.ParseQuantity(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return qa.Cmp(qb) == 0
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
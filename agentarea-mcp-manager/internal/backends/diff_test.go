@@ -0,0 +1,90 @@
+package backends
+
+import "testing"
+
+func TestDiffInstance(t *testing.T) {
+	current := &InstanceStatus{
+		Image:       "mcp/echo:1",
+		Port:        8000,
+		Environment: map[string]string{"API_KEY": "old", "MODE": "fast"},
+		Labels:      map[string]string{"team": "ml", "agentarea.io/instance": "x"},
+	}
+	spec := &InstanceSpec{
+		Image:       "mcp/echo:2",
+		Port:        8000,
+		Environment: map[string]string{"API_KEY": "new", "DEBUG": "1"},
+		Labels:      map[string]string{"team": "ml"},
+	}
+
+	changes := DiffInstance(current, spec)
+	got := make(map[string]FieldChange, len(changes))
+	for _, change := range changes {
+		got[change.Field] = change
+	}
+
+	expected := map[string]string{
+		"image":               "modified",
+		"environment.API_KEY": "modified",
+		"environment.DEBUG":   "added",
+		"environment.MODE":    "removed",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for field, kind := range expected {
+		if got[field].Change != kind {
+			t.Errorf("Expected %s to be %s, got %+v", field, kind, got[field])
+		}
+	}
+
+	// Environment values may be secrets and must not leak into the diff
+	if got["environment.API_KEY"].From != nil || got["environment.API_KEY"].To != nil {
+		t.Errorf("Expected environment values to be omitted, got %+v", got["environment.API_KEY"])
+	}
+
+	if len(DiffInstance(current, &InstanceSpec{Image: current.Image, Port: current.Port, Environment: current.Environment, Labels: map[string]string{"team": "ml"}})) != 0 {
+		t.Error("Expected no changes for an identical spec")
+	}
+}
+
+func TestDiffInstanceCommandAndResources(t *testing.T) {
+	current := &InstanceStatus{
+		Image:   "mcp/echo:1",
+		Port:    8000,
+		Command: []string{"serve", "--stdio"},
+		Resources: ResourceRequirements{
+			Requests: ResourceList{CPU: "500m", Memory: "128Mi"},
+			Limits:   ResourceList{CPU: "1", Memory: "256Mi"},
+		},
+	}
+
+	unchanged := &InstanceSpec{
+		Image:   current.Image,
+		Port:    current.Port,
+		Command: []string{"serve", "--stdio"},
+		Resources: ResourceRequirements{
+			Requests: ResourceList{CPU: "0.5", Memory: "128Mi"},
+			Limits:   ResourceList{CPU: "1000m"},
+		},
+	}
+	if changes := DiffInstance(current, unchanged); len(changes) != 0 {
+		t.Fatalf("Expected an unchanged command and resources to produce no changes, got %+v", changes)
+	}
+
+	changed := &InstanceSpec{
+		Image:     current.Image,
+		Port:      current.Port,
+		Command:   []string{"serve"},
+		Resources: ResourceRequirements{Limits: ResourceList{Memory: "512Mi"}},
+	}
+	changes := DiffInstance(current, changed)
+	if len(changes) != 2 {
+		t.Fatalf("Expected the command and memory limit to change, got %+v", changes)
+	}
+	if changes[0].Field != "command" || changes[0].From != "serve --stdio" || changes[0].To != "serve" {
+		t.Errorf("Unexpected command change %+v", changes[0])
+	}
+	if changes[1].Field != "resources.limits.memory" || changes[1].From != "256Mi" || changes[1].To != "512Mi" {
+		t.Errorf("Unexpected resource change %+v", changes[1])
+	}
+}
@@ -3,7 +3,11 @@ package backends
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log/slog"
+	"maps"
+	"sync"
 	"time"
 
 	"github.com/agentarea/mcp-manager/internal/config"
@@ -16,6 +20,10 @@ type DockerBackend struct {
 	manager *container.Manager
 	config  *config.Config
 	logger  *slog.Logger
+
+	// Updates are delete+create, so they are serialized to keep the
+	// resource version check and the recreate atomic
+	updateMu sync.Mutex
 }
 
 // NewDockerBackend creates a new Docker/Podman backend
@@ -46,6 +54,10 @@ func (d *DockerBackend) CreateInstance(ctx context.Context, spec *InstanceSpec)
 		slog.String("name", spec.Name),
 		slog.String("image", spec.Image))
 
+	// Keep the spec as submitted; the conversion injects MCP_* variables
+	submitted := *spec
+	submitted.Environment = maps.Clone(spec.Environment)
+
 	// Convert InstanceSpec to models.CreateContainerRequest
 	req := d.specToCreateRequest(spec)
 
@@ -58,6 +70,8 @@ func (d *DockerBackend) CreateInstance(ctx context.Context, spec *InstanceSpec)
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
 
+	d.manager.RecordSpec(container.ServiceName, submitted, submitted.Environment)
+
 	// Convert to InstanceResult
 	result := &InstanceResult{
 		ID:        container.ID,
@@ -83,7 +97,7 @@ func (d *DockerBackend) DeleteInstance(ctx context.Context, instanceID string) e
 	// Find container by ID or service name
 	serviceName := d.findServiceNameByID(instanceID)
 	if serviceName == "" {
-		return fmt.Errorf("instance not found: %s", instanceID)
+		return fmt.Errorf("%w: %s", ErrNotFound, instanceID)
 	}
 
 	err := d.manager.DeleteContainer(ctx, serviceName)
@@ -106,7 +120,7 @@ func (d *DockerBackend) DeleteInstance(ctx context.Context, instanceID string) e
 func (d *DockerBackend) GetInstanceStatus(ctx context.Context, instanceID string) (*InstanceStatus, error) {
 	serviceName := d.findServiceNameByID(instanceID)
 	if serviceName == "" {
-		return nil, fmt.Errorf("instance not found: %s", instanceID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
 	}
 
 	container, err := d.manager.GetContainer(serviceName)
@@ -128,6 +142,8 @@ func (d *DockerBackend) GetInstanceStatus(ctx context.Context, instanceID string
 	if healthResult, exists := d.manager.GetContainerHealthStatus(serviceName); exists {
 		healthStatus = &HealthCheckResult{
 			Healthy:       healthResult.Healthy,
+			Live:          healthResult.Live,
+			Ready:         healthResult.Ready,
 			Status:        string(healthResult.Status),
 			HTTPReachable: healthResult.HTTPReachable,
 			ResponseTime:  healthResult.ResponseTime,
@@ -140,18 +156,20 @@ func (d *DockerBackend) GetInstanceStatus(ctx context.Context, instanceID string
 	}
 
 	instanceStatus := &InstanceStatus{
-		ID:           container.ID,
-		Name:         container.ServiceName,
-		ServiceName:  container.ServiceName,
-		Status:       string(status),
-		URL:          container.URL,
-		Image:        container.Image,
-		Port:         container.Port,
-		Environment:  container.Environment,
-		Labels:       container.Labels,
-		CreatedAt:    container.CreatedAt,
-		UpdatedAt:    container.UpdatedAt,
-		HealthStatus: healthStatus,
+		ID:              container.ID,
+		Name:            container.ServiceName,
+		ServiceName:     container.ServiceName,
+		Status:          string(status),
+		URL:             container.URL,
+		Image:           container.Image,
+		Port:            container.Port,
+		Environment:     container.Environment,
+		Labels:          container.Labels,
+		Command:         container.Command,
+		ResourceVersion: containerVersion(container),
+		CreatedAt:       container.CreatedAt,
+		UpdatedAt:       container.UpdatedAt,
+		HealthStatus:    healthStatus,
 	}
 
 	return instanceStatus, nil
@@ -168,6 +186,8 @@ func (d *DockerBackend) ListInstances(ctx context.Context) ([]*InstanceStatus, e
 		if healthResult, exists := d.manager.GetContainerHealthStatus(container.ServiceName); exists {
 			healthStatus = &HealthCheckResult{
 				Healthy:       healthResult.Healthy,
+				Live:          healthResult.Live,
+				Ready:         healthResult.Ready,
 				Status:        string(healthResult.Status),
 				HTTPReachable: healthResult.HTTPReachable,
 				ResponseTime:  healthResult.ResponseTime,
@@ -180,18 +200,20 @@ func (d *DockerBackend) ListInstances(ctx context.Context) ([]*InstanceStatus, e
 		}
 
 		instance := &InstanceStatus{
-			ID:           container.ID,
-			Name:         container.ServiceName,
-			ServiceName:  container.ServiceName,
-			Status:       string(container.Status),
-			URL:          container.URL,
-			Image:        container.Image,
-			Port:         container.Port,
-			Environment:  container.Environment,
-			Labels:       container.Labels,
-			CreatedAt:    container.CreatedAt,
-			UpdatedAt:    container.UpdatedAt,
-			HealthStatus: healthStatus,
+			ID:              container.ID,
+			Name:            container.ServiceName,
+			ServiceName:     container.ServiceName,
+			Status:          string(container.Status),
+			URL:             container.URL,
+			Image:           container.Image,
+			Port:            container.Port,
+			Environment:     container.Environment,
+			Labels:          container.Labels,
+			Command:         container.Command,
+			ResourceVersion: containerVersion(&container),
+			CreatedAt:       container.CreatedAt,
+			UpdatedAt:       container.UpdatedAt,
+			HealthStatus:    healthStatus,
 		}
 
 		instances = append(instances, instance)
@@ -205,6 +227,19 @@ func (d *DockerBackend) UpdateInstance(ctx context.Context, instanceID string, s
 	d.logger.Info("Updating instance with Docker backend",
 		slog.String("instance_id", instanceID))
 
+	d.updateMu.Lock()
+	defer d.updateMu.Unlock()
+
+	if spec.ResourceVersion != "" {
+		current, err := d.GetInstanceStatus(ctx, instanceID)
+		if err != nil {
+			return err
+		}
+		if current.ResourceVersion != spec.ResourceVersion {
+			return fmt.Errorf("%w: expected version %s, current is %s", ErrConflict, spec.ResourceVersion, current.ResourceVersion)
+		}
+	}
+
 	// For Docker backend, we need to recreate the container
 	// First delete the existing instance
 	if err := d.DeleteInstance(ctx, instanceID); err != nil {
@@ -220,11 +255,47 @@ func (d *DockerBackend) UpdateInstance(ctx context.Context, instanceID string, s
 	return nil
 }
 
+// StopInstance drains and stops the container of an instance
+func (d *DockerBackend) StopInstance(ctx context.Context, instanceID string) error {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	if _, err := d.manager.StopContainer(ctx, serviceName); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	return nil
+}
+
+// StartInstance starts the stopped container of an instance
+func (d *DockerBackend) StartInstance(ctx context.Context, instanceID string) error {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	if _, err := d.manager.StartContainer(ctx, serviceName); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	return nil
+}
+
+// RestartInstance drains and restarts the container of an instance in place
+func (d *DockerBackend) RestartInstance(ctx context.Context, instanceID string) error {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	if _, err := d.manager.RestartContainer(ctx, serviceName, "requested via API"); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+	return nil
+}
+
 // PerformHealthCheck performs health check on an instance
 func (d *DockerBackend) PerformHealthCheck(ctx context.Context, instanceID string) (*HealthCheckResult, error) {
 	serviceName := d.findServiceNameByID(instanceID)
 	if serviceName == "" {
-		return nil, fmt.Errorf("instance not found: %s", instanceID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
 	}
 
 	healthData, err := d.manager.PerformHealthCheck(ctx, serviceName)
@@ -242,6 +313,14 @@ func (d *DockerBackend) PerformHealthCheck(ctx context.Context, instanceID strin
 		result.Healthy = healthy
 	}
 
+	if live, ok := healthData["live"].(bool); ok {
+		result.Live = live
+	}
+
+	if ready, ok := healthData["ready"].(bool); ok {
+		result.Ready = ready
+	}
+
 	if status, ok := healthData["container_status"].(string); ok {
 		result.Status = status
 	}
@@ -303,10 +382,22 @@ func (d *DockerBackend) specToCreateRequest(spec *InstanceSpec) models.CreateCon
 	req.Environment["MCP_INSTANCE_ID"] = spec.InstanceID
 	req.Environment["MCP_SERVICE_NAME"] = spec.ServiceName
 	req.Environment["MCP_CONTAINER_PORT"] = fmt.Sprintf("%d", spec.Port)
+	if spec.WorkspaceID != "" {
+		req.Environment[container.WorkspaceIDEnv] = spec.WorkspaceID
+	}
 
 	return req
 }
 
+// containerVersion derives a resource version for a container. Docker updates
+// recreate the container, so the ID and creation time identify a revision.
+func containerVersion(c *models.Container) string {
+	h := fnv.New64a()
+	h.Write([]byte(c.ID))
+	h.Write([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano)))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
 // findServiceNameByID finds the service name by container ID or instance ID
 func (d *DockerBackend) findServiceNameByID(instanceID string) string {
 	containers := d.manager.ListContainers()
@@ -332,3 +423,119 @@ func (d *DockerBackend) findServiceNameByID(instanceID string) string {
 
 	return ""
 }
+
+// SubmittedSpec implements SpecProvider from the specs recorded by the manager
+func (d *DockerBackend) SubmittedSpec(ctx context.Context, instanceID string) (*SubmittedSpec, error) {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	record, ok := d.manager.SpecRecord(serviceName)
+	if !ok {
+		return nil, nil
+	}
+	return &SubmittedSpec{Spec: record.Submitted, Environment: record.Environment, SubmittedAt: record.SubmittedAt}, nil
+}
+
+// PlanUpdate implements UpdatePlanner. Docker updates always delete and
+// recreate the container, so the instance is unavailable until it restarts.
+func (d *DockerBackend) PlanUpdate(current *InstanceStatus, spec *InstanceSpec, changes []FieldChange) UpdateImpact {
+	if len(changes) == 0 {
+		return UpdateImpact{Strategy: "none"}
+	}
+
+	// Roughly the time to stop, start and pass the first health check; a new
+	// image may additionally need to be pulled
+	downtime := 5 * time.Second
+	if spec.Image != current.Image {
+		downtime += 30 * time.Second
+	}
+
+	return UpdateImpact{
+		Strategy:          "recreate",
+		RestartRequired:   true,
+		Recreate:          true,
+		EstimatedDowntime: downtime,
+	}
+}
+
+// StreamLogs implements LogStreamer with `podman logs`
+func (d *DockerBackend) StreamLogs(ctx context.Context, instanceID string, opts LogOptions, w io.Writer) error {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	return d.manager.StreamLogs(ctx, serviceName, container.LogOptions(opts), w)
+}
+
+// Exec implements Execer with `podman exec`
+func (d *DockerBackend) Exec(ctx context.Context, instanceID string, command []string) (*ExecResult, error) {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	result, err := d.manager.Exec(ctx, serviceName, command)
+	if err != nil {
+		return nil, err
+	}
+	return &ExecResult{ExitCode: result.ExitCode, Stdout: result.Stdout, Stderr: result.Stderr}, nil
+}
+
+// InstanceStats implements StatsProvider with `podman stats`
+func (d *DockerBackend) InstanceStats(ctx context.Context, instanceID string) (*InstanceStats, error) {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	usage, err := d.manager.ContainerStats(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	return &InstanceStats{
+		CPUPercent:  usage.CPUPercent,
+		MemoryBytes: usage.MemoryBytes,
+		MemoryLimit: usage.MemoryLimit,
+		NetOutput:   usage.NetOutput,
+		PIDs:        usage.PIDs,
+		Timestamp:   time.Now(),
+	}, nil
+}
+
+// CheckDrift implements DriftDetector by inspecting the container
+func (d *DockerBackend) CheckDrift(ctx context.Context, instanceID string, fix bool) (*container.DriftReport, error) {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	return d.manager.CheckDrift(ctx, serviceName, fix)
+}
+
+// ImageMetadata implements ImageInspector with `podman image inspect`
+func (d *DockerBackend) ImageMetadata(ctx context.Context, image string, pull bool) (*models.ImageMetadata, error) {
+	return d.manager.ImageMetadata(ctx, image, pull)
+}
+
+// Watch implements Watcher with `podman events`
+func (d *DockerBackend) Watch(ctx context.Context, instanceID string) (<-chan InstanceEvent, error) {
+	serviceName := d.findServiceNameByID(instanceID)
+	if serviceName == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+	}
+	runtimeEvents, err := d.manager.WatchEvents(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan InstanceEvent)
+	go func() {
+		defer close(events)
+		for event := range runtimeEvents {
+			select {
+			case events <- InstanceEvent{InstanceID: instanceID, Type: event.Action, Timestamp: event.Timestamp}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,407 @@
+package backends
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// FakeFailEnv injects a failure into one fake instance: "start" makes it fail
+// to start, "health" makes its health checks fail
+const FakeFailEnv = "FAKE_FAIL"
+
+// StatusPublisher receives the simulated status changes of fake instances;
+// *events.EventPublisher implements it
+type StatusPublisher interface {
+	PublishStarting(ctx context.Context, instanceID, name string) error
+	PublishRunning(ctx context.Context, instanceID, name, containerID, url string) error
+	PublishFailed(ctx context.Context, instanceID, name, errorMsg string) error
+	PublishStopped(ctx context.Context, instanceID, name string) error
+	PublishDeleted(ctx context.Context, instanceID, name string) error
+}
+
+// FakeBackend simulates the instance lifecycle in memory, with no container
+// runtime: instances start after a delay and may fail to start or fail health
+// checks. It lets the full stack run on laptops and in tests.
+type FakeBackend struct {
+	config    config.FakeConfig
+	logger    *slog.Logger
+	publisher StatusPublisher
+
+	mu        sync.Mutex
+	instances map[string]*fakeInstance // By spec instance ID
+}
+
+// fakeLogLine is a simulated log line that appears at a time
+type fakeLogLine struct {
+	at   time.Time
+	text string
+}
+
+type fakeInstance struct {
+	id        string
+	spec      InstanceSpec
+	version   int64
+	failStart bool
+	stopped   bool
+	readyAt   time.Time
+	timer     *time.Timer // Publishes the end of startup
+	logs      []fakeLogLine
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// NewFakeBackend creates an empty fake backend
+func NewFakeBackend(cfg config.FakeConfig, logger *slog.Logger) *FakeBackend {
+	return &FakeBackend{config: cfg, logger: logger, instances: make(map[string]*fakeInstance)}
+}
+
+// SetStatusPublisher publishes simulated status changes, e.g. to Redis so
+// the Core API sees instances start
+func (f *FakeBackend) SetStatusPublisher(publisher StatusPublisher) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publisher = publisher
+}
+
+// Initialize implements Backend
+func (f *FakeBackend) Initialize(ctx context.Context) error {
+	f.logger.Info("Initializing fake backend",
+		slog.Duration("startup_delay", f.config.StartupDelay),
+		slog.Float64("failure_rate", f.config.FailureRate),
+		slog.Float64("unhealthy_rate", f.config.UnhealthyRate))
+	return nil
+}
+
+// Shutdown implements Backend
+func (f *FakeBackend) Shutdown(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, instance := range f.instances {
+		instance.timer.Stop()
+	}
+	return nil
+}
+
+// CreateInstance implements Backend. Creating an instance ID that already
+// exists fails.
+func (f *FakeBackend) CreateInstance(ctx context.Context, spec *InstanceSpec) (*InstanceResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.instances[spec.InstanceID]; exists {
+		return nil, fmt.Errorf("instance %s already exists", spec.InstanceID)
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
+	now := time.Now()
+	instance := &fakeInstance{id: hex.EncodeToString(b), spec: *spec, version: 1, createdAt: now}
+	f.instances[spec.InstanceID] = instance
+	f.start(instance, now)
+
+	f.logger.Info("Created fake instance",
+		slog.String("instance_id", spec.InstanceID),
+		slog.String("name", spec.ServiceName),
+		slog.Bool("fail_start", instance.failStart))
+
+	return &InstanceResult{
+		ID:        instance.id,
+		Name:      spec.ServiceName,
+		URL:       fakeURL(spec.ServiceName),
+		Status:    instance.status(now),
+		CreatedAt: now,
+	}, nil
+}
+
+// start (re)starts the simulated lifecycle of an instance; f.mu must be held
+func (f *FakeBackend) start(instance *fakeInstance, now time.Time) {
+	instance.stopped = false
+	instance.failStart = instance.spec.Environment[FakeFailEnv] == "start" || roll(f.config.FailureRate)
+	instance.readyAt = now.Add(f.config.StartupDelay)
+	instance.updatedAt = now
+	instance.log(now, "pulling image "+instance.spec.Image)
+	instance.log(now, "starting container")
+	if instance.failStart {
+		instance.log(instance.readyAt, "error: simulated startup failure")
+	} else {
+		instance.log(instance.readyAt, fmt.Sprintf("listening on port %d", instance.spec.Port))
+	}
+
+	if instance.timer != nil {
+		instance.timer.Stop()
+	}
+	instanceID, name, failed := instance.spec.InstanceID, instance.spec.ServiceName, instance.failStart
+	if f.publisher != nil {
+		_ = f.publisher.PublishStarting(context.Background(), instanceID, name)
+	}
+	instance.timer = time.AfterFunc(f.config.StartupDelay, func() {
+		f.mu.Lock()
+		publisher := f.publisher
+		f.mu.Unlock()
+		if publisher == nil {
+			return
+		}
+		if failed {
+			_ = publisher.PublishFailed(context.Background(), instanceID, name, "simulated startup failure")
+		} else {
+			_ = publisher.PublishRunning(context.Background(), instanceID, name, instance.id, fakeURL(name))
+		}
+	})
+}
+
+// lookup finds an instance by spec instance ID, backend ID or service name;
+// f.mu must be held
+func (f *FakeBackend) lookup(instanceID string) (string, *fakeInstance, error) {
+	for key, instance := range f.instances {
+		if key == instanceID || instance.id == instanceID || instance.spec.ServiceName == instanceID {
+			return key, instance, nil
+		}
+	}
+	return "", nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
+}
+
+// DeleteInstance implements Backend
+func (f *FakeBackend) DeleteInstance(ctx context.Context, instanceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return err
+	}
+	instance.timer.Stop()
+	delete(f.instances, key)
+	if f.publisher != nil {
+		_ = f.publisher.PublishDeleted(ctx, instance.spec.InstanceID, instance.spec.ServiceName)
+	}
+	return nil
+}
+
+// GetInstanceStatus implements Backend
+func (f *FakeBackend) GetInstanceStatus(ctx context.Context, instanceID string) (*InstanceStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return instance.instanceStatus(time.Now()), nil
+}
+
+// ListInstances implements Backend
+func (f *FakeBackend) ListInstances(ctx context.Context) ([]*InstanceStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	instances := make([]*InstanceStatus, 0, len(f.instances))
+	for _, instance := range f.instances {
+		instances = append(instances, instance.instanceStatus(now))
+	}
+	return instances, nil
+}
+
+// UpdateInstance implements Backend. Like Docker, an update restarts the
+// instance.
+func (f *FakeBackend) UpdateInstance(ctx context.Context, instanceID string, spec *InstanceSpec) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return err
+	}
+	if current := strconv.FormatInt(instance.version, 10); spec.ResourceVersion != "" && spec.ResourceVersion != current {
+		return fmt.Errorf("%w: expected version %s, current is %s", ErrConflict, spec.ResourceVersion, current)
+	}
+
+	updated := *spec
+	updated.InstanceID = instance.spec.InstanceID
+	instance.spec = updated
+	instance.version++
+	f.start(instance, time.Now())
+	return nil
+}
+
+// StopInstance implements Backend
+func (f *FakeBackend) StopInstance(ctx context.Context, instanceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return err
+	}
+	if instance.stopped {
+		return nil
+	}
+	instance.timer.Stop()
+	instance.stopped = true
+	instance.updatedAt = time.Now()
+	instance.log(instance.updatedAt, "stopping container")
+	if f.publisher != nil {
+		_ = f.publisher.PublishStopped(ctx, instance.spec.InstanceID, instance.spec.ServiceName)
+	}
+	return nil
+}
+
+// StartInstance implements Backend
+func (f *FakeBackend) StartInstance(ctx context.Context, instanceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return err
+	}
+	if instance.stopped {
+		f.start(instance, time.Now())
+	}
+	return nil
+}
+
+// RestartInstance implements Backend
+func (f *FakeBackend) RestartInstance(ctx context.Context, instanceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return err
+	}
+	f.start(instance, time.Now())
+	return nil
+}
+
+// PerformHealthCheck implements Backend. Running instances are healthy
+// unless a health failure is injected.
+func (f *FakeBackend) PerformHealthCheck(ctx context.Context, instanceID string) (*HealthCheckResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := instance.status(now)
+	result := &HealthCheckResult{
+		Status:      status,
+		ContainerID: instance.id,
+		ServiceName: instance.spec.ServiceName,
+		Timestamp:   now,
+	}
+	switch {
+	case status != "running":
+		result.Error = "instance is " + status
+	case instance.spec.Environment[FakeFailEnv] == "health" || roll(f.config.UnhealthyRate):
+		result.Live = true
+		result.HTTPReachable = true
+		result.Error = "simulated health check failure"
+	default:
+		result.Healthy = true
+		result.Live = true
+		result.Ready = true
+		result.HTTPReachable = true
+		result.ResponseTime = time.Duration(5+mathrand.IntN(20)) * time.Millisecond
+	}
+	return result, nil
+}
+
+// StreamLogs implements LogStreamer with the simulated lifecycle log
+func (f *FakeBackend) StreamLogs(ctx context.Context, instanceID string, opts LogOptions, w io.Writer) error {
+	f.mu.Lock()
+	_, instance, err := f.lookup(instanceID)
+	if err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	now := time.Now()
+	var lines []string
+	for _, line := range instance.logs {
+		if line.at.After(now) || (!opts.Since.IsZero() && line.at.Before(opts.Since)) {
+			continue
+		}
+		text := line.text
+		if opts.Timestamps {
+			text = line.at.UTC().Format(time.RFC3339Nano) + " " + text
+		}
+		lines = append(lines, text)
+	}
+	f.mu.Unlock()
+
+	if opts.Tail > 0 && len(lines) > opts.Tail {
+		lines = lines[len(lines)-opts.Tail:]
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	if opts.Follow {
+		<-ctx.Done()
+	}
+	return nil
+}
+
+// status is the simulated status at a time
+func (i *fakeInstance) status(now time.Time) string {
+	switch {
+	case i.stopped:
+		return "stopped"
+	case now.Before(i.readyAt):
+		return "starting"
+	case i.failStart:
+		return "error"
+	default:
+		return "running"
+	}
+}
+
+func (i *fakeInstance) instanceStatus(now time.Time) *InstanceStatus {
+	return &InstanceStatus{
+		ID:              i.id,
+		Name:            i.spec.ServiceName,
+		ServiceName:     i.spec.ServiceName,
+		Status:          i.status(now),
+		URL:             fakeURL(i.spec.ServiceName),
+		Image:           i.spec.Image,
+		Port:            i.spec.Port,
+		Environment:     i.spec.Environment,
+		Labels:          i.spec.Labels,
+		Command:         i.spec.Command,
+		Resources:       i.spec.Resources,
+		ResourceVersion: strconv.FormatInt(i.version, 10),
+		CreatedAt:       i.createdAt,
+		UpdatedAt:       i.updatedAt,
+	}
+}
+
+// log records a simulated log line that appears at a time
+func (i *fakeInstance) log(at time.Time, text string) {
+	i.logs = append(i.logs, fakeLogLine{at: at, text: text})
+}
+
+// fakeURL is the URL reported for a fake instance; the .invalid domain never resolves
+func fakeURL(serviceName string) string {
+	return "http://fake.invalid/mcp/" + serviceName
+}
+
+// roll reports true with probability rate
+func roll(rate float64) bool {
+	return rate > 0 && mathrand.Float64() < rate
+}
@@ -0,0 +1,110 @@
+package backends
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+type recordingPublisher struct {
+	statuses chan string
+}
+
+func (p *recordingPublisher) PublishStarting(ctx context.Context, instanceID, name string) error {
+	p.statuses <- "starting"
+	return nil
+}
+
+func (p *recordingPublisher) PublishRunning(ctx context.Context, instanceID, name, containerID, url string) error {
+	p.statuses <- "running"
+	return nil
+}
+
+func (p *recordingPublisher) PublishFailed(ctx context.Context, instanceID, name, errorMsg string) error {
+	p.statuses <- "failed"
+	return nil
+}
+
+func (p *recordingPublisher) PublishStopped(ctx context.Context, instanceID, name string) error {
+	p.statuses <- "stopped"
+	return nil
+}
+
+func (p *recordingPublisher) PublishDeleted(ctx context.Context, instanceID, name string) error {
+	p.statuses <- "deleted"
+	return nil
+}
+
+func TestFakeBackendLifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeBackend(config.FakeConfig{StartupDelay: 50 * time.Millisecond}, slog.New(slog.DiscardHandler))
+	publisher := &recordingPublisher{statuses: make(chan string, 10)}
+	fake.SetStatusPublisher(publisher)
+
+	if _, err := fake.CreateInstance(ctx, &InstanceSpec{InstanceID: "ok", ServiceName: "ok", Image: "img", Port: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.CreateInstance(ctx, &InstanceSpec{
+		InstanceID: "broken", ServiceName: "broken", Image: "img", Port: 8000,
+		Environment: map[string]string{FakeFailEnv: "start"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.CreateInstance(ctx, &InstanceSpec{
+		InstanceID: "sick", ServiceName: "sick", Image: "img", Port: 8000,
+		Environment: map[string]string{FakeFailEnv: "health"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := fake.GetInstanceStatus(ctx, "ok"); status.Status != "starting" {
+		t.Errorf("status during startup = %s, want starting", status.Status)
+	}
+	if health, _ := fake.PerformHealthCheck(ctx, "ok"); health.Healthy {
+		t.Error("instance is healthy during startup")
+	}
+
+	// Each create publishes starting, then running or failed after the delay
+	counts := map[string]int{}
+	for range 6 {
+		select {
+		case status := <-publisher.statuses:
+			counts[status]++
+		case <-time.After(time.Second):
+			t.Fatalf("published statuses = %v, want 3 starting, 2 running, 1 failed", counts)
+		}
+	}
+	if counts["starting"] != 3 || counts["running"] != 2 || counts["failed"] != 1 {
+		t.Errorf("published statuses = %v, want 3 starting, 2 running, 1 failed", counts)
+	}
+
+	for id, want := range map[string]string{"ok": "running", "broken": "error", "sick": "running"} {
+		if status, _ := fake.GetInstanceStatus(ctx, id); status.Status != want {
+			t.Errorf("status of %s = %s, want %s", id, status.Status, want)
+		}
+	}
+	for id, want := range map[string]bool{"ok": true, "broken": false, "sick": false} {
+		if health, _ := fake.PerformHealthCheck(ctx, id); health.Healthy != want {
+			t.Errorf("health of %s = %v, want %v", id, health.Healthy, want)
+		}
+	}
+
+	var logs strings.Builder
+	if err := fake.StreamLogs(ctx, "broken", LogOptions{}, &logs); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "simulated startup failure") {
+		t.Errorf("logs = %q, want the startup failure", logs.String())
+	}
+
+	if err := fake.DeleteInstance(ctx, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if status := <-publisher.statuses; status != "deleted" {
+		t.Errorf("published %s on delete, want deleted", status)
+	}
+}
@@ -0,0 +1,167 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+)
+
+// RestartedAtLabel records when a group restart was requested; changing it
+// makes both backends recreate the instance with an otherwise unchanged spec
+const RestartedAtLabel = "agentarea.io/restarted-at"
+
+// Group operation results per instance
+const (
+	GroupResultUpdated = "updated"
+	GroupResultFailed  = "failed"
+	GroupResultSkipped = "skipped"
+)
+
+// GroupSummary describes one instance group
+type GroupSummary struct {
+	Name      string `json:"name"`
+	Instances int    `json:"instances"`
+	Running   int    `json:"running"`
+}
+
+// GroupResult is the outcome of a group operation for one instance
+type GroupResult struct {
+	InstanceID string `json:"instance_id"`
+	Result     string `json:"result"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ValidateGroupName checks that a group name can be stored as a label value
+func ValidateGroupName(name string) error {
+	if name == "" {
+		return fmt.Errorf("group name cannot be empty")
+	}
+	if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+		return fmt.Errorf("invalid group name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// GroupOf returns the group an instance belongs to, or "" if none
+func GroupOf(labels map[string]string) string {
+	return labels[container.GroupLabel]
+}
+
+// SetGroup adds or removes (group "") the group label
+func SetGroup(labels map[string]string, group string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if group != "" {
+		labels[container.GroupLabel] = group
+	} else {
+		delete(labels, container.GroupLabel)
+	}
+	return labels
+}
+
+// MarkRestarted sets the restart label so the next update recreates the instance
+func MarkRestarted(labels map[string]string, now time.Time) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[RestartedAtLabel] = strconv.FormatInt(now.Unix(), 10)
+	return labels
+}
+
+// ListGroups summarizes the groups of the given instances, sorted by name
+func ListGroups(instances []*InstanceStatus) []GroupSummary {
+	byName := make(map[string]*GroupSummary)
+	for _, instance := range instances {
+		group := GroupOf(instance.Labels)
+		if group == "" {
+			continue
+		}
+		summary, ok := byName[group]
+		if !ok {
+			summary = &GroupSummary{Name: group}
+			byName[group] = summary
+		}
+		summary.Instances++
+		if instance.Status == "running" {
+			summary.Running++
+		}
+	}
+
+	groups := make([]GroupSummary, 0, len(byName))
+	for _, summary := range byName {
+		groups = append(groups, *summary)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// GroupMembers returns the instances of a group sorted by ID, so rollouts
+// always proceed in the same order
+func GroupMembers(instances []*InstanceStatus, group string) []*InstanceStatus {
+	members := make([]*InstanceStatus, 0)
+	for _, instance := range instances {
+		if GroupOf(instance.Labels) == group {
+			members = append(members, instance)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// RollOut applies an operation to instances in batches of at most
+// maxUnavailable, running each batch concurrently. apply must only return
+// once the instance is serving again. If any instance in a batch fails, the
+// rollout stops and the remaining instances are skipped.
+func RollOut(ctx context.Context, instances []*InstanceStatus, maxUnavailable int, apply func(ctx context.Context, instance *InstanceStatus) error) []GroupResult {
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+
+	results := make([]GroupResult, len(instances))
+	for i, instance := range instances {
+		results[i] = GroupResult{InstanceID: instance.ID, Result: GroupResultSkipped}
+	}
+
+	for start := 0; start < len(instances); start += maxUnavailable {
+		if ctx.Err() != nil {
+			break
+		}
+
+		end := start + maxUnavailable
+		if end > len(instances) {
+			end = len(instances)
+		}
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := apply(ctx, instances[i]); err != nil {
+					results[i].Result = GroupResultFailed
+					results[i].Error = err.Error()
+					return
+				}
+				results[i].Result = GroupResultUpdated
+			}(i)
+		}
+		wg.Wait()
+
+		for i := start; i < end; i++ {
+			if results[i].Result == GroupResultFailed {
+				return results
+			}
+		}
+	}
+
+	return results
+}
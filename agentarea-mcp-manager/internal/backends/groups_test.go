@@ -0,0 +1,70 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGroupMembers(t *testing.T) {
+	instances := []*InstanceStatus{
+		{ID: "c", Labels: SetGroup(nil, "search"), Status: "running"},
+		{ID: "a", Labels: SetGroup(nil, "search"), Status: "error"},
+		{ID: "b", Labels: SetGroup(nil, "git"), Status: "running"},
+		{ID: "d"},
+	}
+
+	members := GroupMembers(instances, "search")
+	if len(members) != 2 || members[0].ID != "a" || members[1].ID != "c" {
+		t.Fatalf("unexpected members: %v", members)
+	}
+
+	groups := ListGroups(instances)
+	if len(groups) != 2 || groups[0].Name != "git" || groups[1] != (GroupSummary{Name: "search", Instances: 2, Running: 1}) {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+}
+
+func TestRollOut(t *testing.T) {
+	instances := []*InstanceStatus{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+
+	var mu sync.Mutex
+	var inFlight, maxInFlight int32
+	results := RollOut(context.Background(), instances, 2, func(ctx context.Context, instance *InstanceStatus) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		mu.Lock()
+		if n > maxInFlight {
+			maxInFlight = n
+		}
+		mu.Unlock()
+		return nil
+	})
+	if maxInFlight > 2 {
+		t.Errorf("expected at most 2 concurrent updates, got %d", maxInFlight)
+	}
+	for _, result := range results {
+		if result.Result != GroupResultUpdated {
+			t.Errorf("expected %s to be updated: %+v", result.InstanceID, result)
+		}
+	}
+
+	// A failure stops the rollout after the current batch
+	results = RollOut(context.Background(), instances, 2, func(ctx context.Context, instance *InstanceStatus) error {
+		if instance.ID == "c" {
+			return errors.New("unhealthy")
+		}
+		return nil
+	})
+	want := []string{GroupResultUpdated, GroupResultUpdated, GroupResultFailed, GroupResultUpdated, GroupResultSkipped}
+	for i, result := range results {
+		if result.Result != want[i] {
+			t.Errorf("instance %s: expected %s, got %s", result.InstanceID, want[i], result.Result)
+		}
+	}
+	if results[2].Error != "unhealthy" {
+		t.Errorf("expected failure reason to be reported, got %q", results[2].Error)
+	}
+}
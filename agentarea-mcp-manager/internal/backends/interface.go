@@ -2,9 +2,22 @@ package backends
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"maps"
 	"time"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/redact"
 )
 
+// ErrConflict is returned by UpdateInstance when the instance changed since
+// the resource version the caller based its update on
+var ErrConflict = errors.New("instance was modified concurrently")
+
+// ErrNotFound is returned for operations on an instance that does not exist
+var ErrNotFound = errors.New("instance not found")
+
 // Backend defines the interface for container management backends (Docker/Kubernetes)
 type Backend interface {
 	// CreateInstance creates a new MCP server instance
@@ -22,6 +35,15 @@ type Backend interface {
 	// UpdateInstance updates an existing instance configuration
 	UpdateInstance(ctx context.Context, instanceID string, spec *InstanceSpec) error
 
+	// StopInstance stops an instance, keeping its route, secrets and spec
+	StopInstance(ctx context.Context, instanceID string) error
+
+	// StartInstance starts a stopped instance; starting a running one does nothing
+	StartInstance(ctx context.Context, instanceID string) error
+
+	// RestartInstance restarts an instance in place, starting it if stopped
+	RestartInstance(ctx context.Context, instanceID string) error
+
 	// PerformHealthCheck performs health check on an instance
 	PerformHealthCheck(ctx context.Context, instanceID string) (*HealthCheckResult, error)
 
@@ -57,6 +79,10 @@ type InstanceSpec struct {
 	InstanceID  string `json:"instance_id"`
 	WorkspaceID string `json:"workspace_id,omitempty"`
 	ServiceName string `json:"service_name"`
+
+	// ResourceVersion is the version the update is based on; UpdateInstance
+	// returns ErrConflict if the instance has changed since (empty skips the check)
+	ResourceVersion string `json:"resource_version,omitempty"`
 }
 
 // ResourceRequirements defines resource constraints for instances
@@ -77,29 +103,49 @@ type InstanceResult struct {
 	URL         string    `json:"url"`
 	InternalURL string    `json:"internal_url,omitempty"`
 	Status      string    `json:"status"`
+	AuthToken   string    `json:"auth_token,omitempty"` // Only returned at creation
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 // InstanceStatus represents the current status of an instance
 type InstanceStatus struct {
-	ID           string             `json:"id"`
-	Name         string             `json:"name"`
-	ServiceName  string             `json:"service_name"`
-	Status       string             `json:"status"`
-	URL          string             `json:"url,omitempty"`
-	InternalURL  string             `json:"internal_url,omitempty"`
-	Image        string             `json:"image"`
-	Port         int                `json:"port"`
-	Environment  map[string]string  `json:"environment,omitempty"`
-	Labels       map[string]string  `json:"labels,omitempty"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
-	HealthStatus *HealthCheckResult `json:"health_status,omitempty"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	ServiceName string            `json:"service_name"`
+	Status      string            `json:"status"`
+	URL         string            `json:"url,omitempty"`
+	InternalURL string            `json:"internal_url,omitempty"`
+	Image       string            `json:"image"`
+	Port        int               `json:"port"`
+	Environment map[string]string `json:"environment,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Command     []string          `json:"command,omitempty"`
+	// Resources are empty when the backend does not apply per-instance resources
+	Resources       ResourceRequirements `json:"resources,omitempty"`
+	ResourceVersion string               `json:"resource_version"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	HealthStatus    *HealthCheckResult   `json:"health_status,omitempty"`
+}
+
+// MarshalJSON redacts the instance auth token; clients receive it only when
+// the instance is created and when the token is rotated
+func (s InstanceStatus) MarshalJSON() ([]byte, error) {
+	type plain InstanceStatus
+	if _, ok := s.Environment[container.AuthTokenEnv]; ok {
+		s.Environment = maps.Clone(s.Environment)
+		s.Environment[container.AuthTokenEnv] = redact.Placeholder
+	}
+	return json.Marshal(plain(s))
 }
 
-// HealthCheckResult represents the result of a health check
+// HealthCheckResult represents the result of a health check. Live means the
+// instance runs and is not due a restart; Ready means it accepts traffic and
+// is routed. Healthy means both.
 type HealthCheckResult struct {
 	Healthy       bool          `json:"healthy"`
+	Live          bool          `json:"live"`
+	Ready         bool          `json:"ready"`
 	Status        string        `json:"status"`
 	HTTPReachable bool          `json:"http_reachable"`
 	ResponseTime  time.Duration `json:"response_time"`
@@ -116,6 +162,7 @@ type BackendType string
 const (
 	BackendTypeDocker     BackendType = "docker"
 	BackendTypeKubernetes BackendType = "kubernetes"
+	BackendTypeFake       BackendType = "fake"
 )
 
 // BackendFactory creates backend instances based on configuration
@@ -0,0 +1,27 @@
+package backends
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+)
+
+func TestInstanceStatusRedactsAuthToken(t *testing.T) {
+	status := &InstanceStatus{
+		ID:          "i-1",
+		Environment: map[string]string{container.AuthTokenEnv: "s3cret-token", "MODE": "fast"},
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret-token") || !strings.Contains(string(data), `"MODE":"fast"`) {
+		t.Fatalf("expected only the auth token to be redacted, got %s", data)
+	}
+	if status.Environment[container.AuthTokenEnv] != "s3cret-token" {
+		t.Error("expected the status itself to keep the token")
+	}
+}
@@ -0,0 +1,36 @@
+package backends
+
+import (
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplyIPFamilies(t *testing.T) {
+	spec := corev1.ServiceSpec{}
+	applyIPFamilies(&spec, &config.KubernetesConfig{})
+	if spec.IPFamilyPolicy != nil || spec.IPFamilies != nil {
+		t.Errorf("expected cluster defaults, got %+v", spec)
+	}
+
+	cfg := &config.KubernetesConfig{IPFamilyPolicy: "PreferDualStack", IPFamilies: []string{"IPv6", "IPv4"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	applyIPFamilies(&spec, cfg)
+	if spec.IPFamilyPolicy == nil || *spec.IPFamilyPolicy != corev1.IPFamilyPolicyPreferDualStack ||
+		len(spec.IPFamilies) != 2 || spec.IPFamilies[0] != corev1.IPv6Protocol {
+		t.Errorf("unexpected dual-stack spec %+v", spec)
+	}
+
+	for _, invalid := range []config.KubernetesConfig{
+		{IPFamilyPolicy: "DualStack"},
+		{IPFamilies: []string{"ipv4"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
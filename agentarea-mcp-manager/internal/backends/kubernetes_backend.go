@@ -3,12 +3,19 @@ package backends
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/egress"
+	"github.com/agentarea/mcp-manager/internal/naming"
+	"github.com/agentarea/mcp-manager/internal/outbound"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -31,6 +38,11 @@ type KubernetesBackend struct {
 	k8sConfig *config.KubernetesConfig
 	logger    *slog.Logger
 	scheme    *runtime.Scheme
+	outbound  *outbound.Settings // Corporate proxy passed to instances; nil for none
+	egress    *egress.Filter     // DNS filtering of allow-listed pods; nil disables
+
+	healthClient *container.HealthClient // Pooled across health checks
+	cache        *instanceCache          // Informer-backed listings; nil until started
 }
 
 // NewKubernetesBackend creates a new Kubernetes backend
@@ -68,13 +80,23 @@ func NewKubernetesBackend(cfg *config.Config, logger *slog.Logger) (*KubernetesB
 		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
 	}
 
+	if err := cfg.Kubernetes.Validate(); err != nil {
+		return nil, err
+	}
+
+	healthClient, err := container.NewHealthClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health check client: %w", err)
+	}
+
 	return &KubernetesBackend{
-		client:    runtimeClient,
-		clientset: clientset,
-		config:    cfg,
-		k8sConfig: &cfg.Kubernetes,
-		logger:    logger,
-		scheme:    scheme,
+		client:       runtimeClient,
+		clientset:    clientset,
+		config:       cfg,
+		k8sConfig:    &cfg.Kubernetes,
+		logger:       logger,
+		scheme:       scheme,
+		healthClient: healthClient,
 	}, nil
 }
 
@@ -89,6 +111,10 @@ func (k *KubernetesBackend) Initialize(ctx context.Context) error {
 		return fmt.Errorf("failed to ensure namespace: %w", err)
 	}
 
+	if k.k8sConfig.ListCache {
+		k.startCache(ctx)
+	}
+
 	k.logger.Info("Kubernetes backend initialized successfully")
 	return nil
 }
@@ -173,6 +199,7 @@ func (k *KubernetesBackend) DeleteInstance(ctx context.Context, instanceID strin
 	if err := k.cleanupResources(ctx, instanceName); err != nil {
 		return fmt.Errorf("failed to cleanup resources: %w", err)
 	}
+	k.cache.forget(instanceName)
 
 	k.logger.Info("Successfully deleted Kubernetes instance",
 		slog.String("instance_id", instanceID),
@@ -195,7 +222,7 @@ func (k *KubernetesBackend) GetInstanceStatus(ctx context.Context, instanceID st
 		Name:      fmt.Sprintf("mcp-%s", instanceName),
 	}, deployment); err != nil {
 		if errors.IsNotFound(err) {
-			return nil, fmt.Errorf("instance not found: %s", instanceID)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, instanceID)
 		}
 		return nil, fmt.Errorf("failed to get deployment: %w", err)
 	}
@@ -211,12 +238,33 @@ func (k *KubernetesBackend) GetInstanceStatus(ctx context.Context, instanceID st
 			slog.String("error", err.Error()))
 	}
 
-	// Determine status from deployment conditions
-	status := k.getDeploymentStatus(deployment)
+	// Get environment variables from secret
+	secret := &corev1.Secret{}
+	if err := k.client.Get(ctx, types.NamespacedName{
+		Namespace: k.k8sConfig.Namespace,
+		Name:      fmt.Sprintf("mcp-%s", instanceName),
+	}, secret); err != nil {
+		secret = nil
+	}
+
+	instanceStatus := k.instanceStatus(instanceName, deployment, configMap, secret)
 
+	// Perform health check if instance is running
+	if instanceStatus.Status == "running" {
+		healthResult := k.deploymentHealth(ctx, instanceName, deployment)
+		k.cache.setHealth(instanceName, healthResult)
+		instanceStatus.HealthStatus = healthResult
+	}
+
+	return instanceStatus, nil
+}
+
+// instanceStatus builds the status of an instance from its resources;
+// configMap and secret may be nil
+func (k *KubernetesBackend) instanceStatus(instanceName string, deployment *appsv1.Deployment, configMap *corev1.ConfigMap, secret *corev1.Secret) *InstanceStatus {
 	// Extract port from configmap
 	port := 8000
-	if configMap.Data != nil {
+	if configMap != nil && configMap.Data != nil {
 		if portStr, exists := configMap.Data["port"]; exists {
 			if p, err := strconv.Atoi(portStr); err == nil {
 				port = p
@@ -224,51 +272,67 @@ func (k *KubernetesBackend) GetInstanceStatus(ctx context.Context, instanceID st
 		}
 	}
 
-	// Extract image from deployment
+	// Extract image, command and resources from deployment
 	image := ""
+	var command []string
+	var resources ResourceRequirements
 	if len(deployment.Spec.Template.Spec.Containers) > 0 {
-		image = deployment.Spec.Template.Spec.Containers[0].Image
+		server := deployment.Spec.Template.Spec.Containers[0]
+		image = server.Image
+		command = server.Command
+		resources = ResourceRequirements{
+			Requests: resourceList(server.Resources.Requests),
+			Limits:   resourceList(server.Resources.Limits),
+		}
 	}
 
-	// Get environment variables from secret
 	environment := make(map[string]string)
-	secret := &corev1.Secret{}
-	if err := k.client.Get(ctx, types.NamespacedName{
-		Namespace: k.k8sConfig.Namespace,
-		Name:      fmt.Sprintf("mcp-%s", instanceName),
-	}, secret); err == nil {
+	if secret != nil {
 		for key := range secret.Data {
 			environment[key] = "[REDACTED]" // Don't expose secret values
 		}
 	}
 
-	instanceStatus := &InstanceStatus{
+	return &InstanceStatus{
 		ID:          string(deployment.UID),
 		Name:        instanceName,
 		ServiceName: instanceName,
-		Status:      status,
+		Status:      k.getDeploymentStatus(deployment),
 		URL:         k.k8sConfig.GetInstanceURL(instanceName),
 		InternalURL: k.k8sConfig.GetInternalServiceURL(instanceName, port),
 		Image:       image,
 		Port:        port,
 		Environment: environment,
-		Labels:      deployment.Labels,
-		CreatedAt:   deployment.CreationTimestamp.Time,
-		UpdatedAt:   time.Now(),
+		Labels:      maps.Clone(deployment.Labels), // Cached deployments are shared
+		Command:     slices.Clone(command),
+		Resources:   resources,
+		// Generation only changes with the spec, not with rollout status
+		ResourceVersion: strconv.FormatInt(deployment.Generation, 10),
+		CreatedAt:       deployment.CreationTimestamp.Time,
+		UpdatedAt:       time.Now(),
 	}
+}
 
-	// Perform health check if instance is running
-	if status == "running" {
-		if healthResult, err := k.PerformHealthCheck(ctx, instanceID); err == nil {
-			instanceStatus.HealthStatus = healthResult
-		}
+// resourceList converts the CPU and memory of a Kubernetes resource list
+func resourceList(list corev1.ResourceList) ResourceList {
+	var result ResourceList
+	if cpu, ok := list[corev1.ResourceCPU]; ok {
+		result.CPU = cpu.String()
 	}
-
-	return instanceStatus, nil
+	if memory, ok := list[corev1.ResourceMemory]; ok {
+		result.Memory = memory.String()
+	}
+	return result
 }
 
-// ListInstances returns all managed Kubernetes instances
+// ListInstances returns all managed Kubernetes instances. With the list
+// cache synced they are read from it without API round-trips, with the
+// latest health result of each instance.
 func (k *KubernetesBackend) ListInstances(ctx context.Context) ([]*InstanceStatus, error) {
+	if k.cache.ready() {
+		return k.cache.list(k)
+	}
+
 	deployments := &appsv1.DeploymentList{}
 	if err := k.client.List(ctx, deployments, client.InNamespace(k.k8sConfig.Namespace), client.MatchingLabels{
 		"app.kubernetes.io/managed-by": "mcp-manager",
@@ -306,18 +370,36 @@ func (k *KubernetesBackend) UpdateInstance(ctx context.Context, instanceID strin
 		slog.String("instance_id", instanceID),
 		slog.String("instance_name", instanceName))
 
-	// Update configmap
-	if err := k.updateConfigMap(ctx, instanceName, spec); err != nil {
+	// Check the version before touching any resources
+	if spec.ResourceVersion != "" {
+		deployment := &appsv1.Deployment{}
+		if err := k.client.Get(ctx, types.NamespacedName{
+			Namespace: k.k8sConfig.Namespace,
+			Name:      fmt.Sprintf("mcp-%s", instanceName),
+		}, deployment); err != nil {
+			return fmt.Errorf("failed to get deployment: %w", err)
+		}
+		if current := strconv.FormatInt(deployment.Generation, 10); current != spec.ResourceVersion {
+			return fmt.Errorf("%w: expected version %s, current is %s", ErrConflict, spec.ResourceVersion, current)
+		}
+	}
+
+	// The deployment can still change before it is updated below, so the
+	// previous configmap and secret data is kept to roll them back
+	previousConfig, err := k.updateConfigMap(ctx, instanceName, spec)
+	if err != nil {
 		return fmt.Errorf("failed to update configmap: %w", err)
 	}
 
-	// Update secret
-	if err := k.updateSecret(ctx, instanceName, spec); err != nil {
+	previousSecret, err := k.updateSecret(ctx, instanceName, spec)
+	if err != nil {
+		k.restoreConfig(ctx, instanceName, previousConfig, nil)
 		return fmt.Errorf("failed to update secret: %w", err)
 	}
 
 	// Update deployment (this will trigger a rolling update)
 	if err := k.updateDeployment(ctx, instanceName, spec); err != nil {
+		k.restoreConfig(ctx, instanceName, previousConfig, previousSecret)
 		return fmt.Errorf("failed to update deployment: %w", err)
 	}
 
@@ -328,6 +410,63 @@ func (k *KubernetesBackend) UpdateInstance(ctx context.Context, instanceID strin
 	return nil
 }
 
+// StopInstance scales the deployment of an instance to zero replicas; its
+// service, ingress and secret stay in place
+func (k *KubernetesBackend) StopInstance(ctx context.Context, instanceID string) error {
+	return k.modifyDeployment(ctx, instanceID, "stop", func(deployment *appsv1.Deployment) {
+		deployment.Spec.Replicas = int32Ptr(0)
+	})
+}
+
+// StartInstance scales the deployment of a stopped instance back to one replica
+func (k *KubernetesBackend) StartInstance(ctx context.Context, instanceID string) error {
+	return k.modifyDeployment(ctx, instanceID, "start", func(deployment *appsv1.Deployment) {
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
+			deployment.Spec.Replicas = int32Ptr(1)
+		}
+	})
+}
+
+// RestartInstance rolls the pods of an instance the way kubectl rollout
+// restart does, by stamping the pod template; a stopped instance is started
+func (k *KubernetesBackend) RestartInstance(ctx context.Context, instanceID string) error {
+	return k.modifyDeployment(ctx, instanceID, "restart", func(deployment *appsv1.Deployment) {
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
+			deployment.Spec.Replicas = int32Ptr(1)
+		}
+		if deployment.Spec.Template.Annotations == nil {
+			deployment.Spec.Template.Annotations = make(map[string]string)
+		}
+		deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
+	})
+}
+
+// modifyDeployment applies a change to the deployment of an instance
+func (k *KubernetesBackend) modifyDeployment(ctx context.Context, instanceID, operation string, modify func(*appsv1.Deployment)) error {
+	instanceName, err := k.findInstanceNameByID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance: %w", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := k.client.Get(ctx, types.NamespacedName{
+		Namespace: k.k8sConfig.Namespace,
+		Name:      fmt.Sprintf("mcp-%s", instanceName),
+	}, deployment); err != nil {
+		return fmt.Errorf("failed to get deployment: %w", err)
+	}
+	modify(deployment)
+	if err := k.client.Update(ctx, deployment); err != nil {
+		return fmt.Errorf("failed to %s deployment: %w", operation, err)
+	}
+
+	k.logger.Info("Updated Kubernetes instance lifecycle",
+		slog.String("instance_id", instanceID),
+		slog.String("instance_name", instanceName),
+		slog.String("operation", operation))
+	return nil
+}
+
 // PerformHealthCheck performs health check on a Kubernetes instance
 func (k *KubernetesBackend) PerformHealthCheck(ctx context.Context, instanceID string) (*HealthCheckResult, error) {
 	instanceName, err := k.findInstanceNameByID(ctx, instanceID)
@@ -350,12 +489,23 @@ func (k *KubernetesBackend) PerformHealthCheck(ctx context.Context, instanceID s
 		}, nil
 	}
 
-	// Check deployment readiness
+	result := k.deploymentHealth(ctx, instanceName, deployment)
+	k.cache.setHealth(instanceName, result)
+	return result, nil
+}
+
+// deploymentHealth checks the readiness of a deployment and, once ready,
+// probes its server over HTTP
+func (k *KubernetesBackend) deploymentHealth(ctx context.Context, instanceName string, deployment *appsv1.Deployment) *HealthCheckResult {
+	// Check deployment readiness. Liveness is the kubelet's: pods failing
+	// their liveness probe are restarted, so running pods are live.
 	ready := deployment.Status.ReadyReplicas > 0 &&
 		deployment.Status.ReadyReplicas == deployment.Status.Replicas
 
 	result := &HealthCheckResult{
 		Healthy:     ready,
+		Live:        deployment.Status.Replicas > 0,
+		Ready:       ready,
 		Status:      k.getDeploymentStatus(deployment),
 		ServiceName: instanceName,
 		Timestamp:   time.Now(),
@@ -367,15 +517,16 @@ func (k *KubernetesBackend) PerformHealthCheck(ctx context.Context, instanceID s
 		result.HTTPReachable = httpHealthy
 		result.ResponseTime = responseTime
 		result.Healthy = ready && httpHealthy
+		result.Ready = result.Healthy
 	}
 
-	return result, nil
+	return result
 }
 
 // Shutdown gracefully shuts down the Kubernetes backend
 func (k *KubernetesBackend) Shutdown(ctx context.Context) error {
 	k.logger.Info("Shutting down Kubernetes backend")
-	// No specific cleanup needed for Kubernetes client
+	k.cache.stop()
 	return nil
 }
 
@@ -383,32 +534,14 @@ func (k *KubernetesBackend) Shutdown(ctx context.Context) error {
 
 // sanitizeInstanceName sanitizes an instance name for Kubernetes
 func (k *KubernetesBackend) sanitizeInstanceName(name string) string {
-	// Kubernetes names must be lowercase and contain only alphanumeric characters and hyphens
-	sanitized := strings.ToLower(name)
-	sanitized = strings.ReplaceAll(sanitized, "_", "-")
-	sanitized = strings.ReplaceAll(sanitized, " ", "-")
-
-	// Remove any non-alphanumeric characters except hyphens
-	var result strings.Builder
-	for _, r := range sanitized {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			result.WriteRune(r)
-		}
-	}
-
-	sanitized = result.String()
-
-	// Trim leading/trailing hyphens
-	sanitized = strings.Trim(sanitized, "-")
+	// Kubernetes names must be lowercase and contain only alphanumeric
+	// characters and hyphens; the Service name mcp-<name> must also be a DNS label
+	sanitized := naming.Normalize(name, naming.MaxLabelLength-len("mcp-"))
 
-	// Ensure it's not empty and doesn't exceed 253 characters
+	// Ensure it's not empty
 	if sanitized == "" {
 		sanitized = "instance"
 	}
-	if len(sanitized) > 253 {
-		sanitized = sanitized[:253]
-		sanitized = strings.TrimSuffix(sanitized, "-")
-	}
 
 	return sanitized
 }
@@ -451,3 +584,111 @@ func (k *KubernetesBackend) getCommonLabels(instanceName string) map[string]stri
 }
 
 // Continue in next message due to length...
+
+// PlanUpdate implements UpdatePlanner. Kubernetes updates roll out a new pod
+// before the old one is removed, so no downtime is expected.
+func (k *KubernetesBackend) PlanUpdate(current *InstanceStatus, spec *InstanceSpec, changes []FieldChange) UpdateImpact {
+	if len(changes) == 0 {
+		return UpdateImpact{Strategy: "none"}
+	}
+
+	return UpdateImpact{
+		Strategy:        "rolling",
+		RestartRequired: true,
+	}
+}
+
+// podSelector selects the pods of an instance deployment
+func podSelector(instanceName string) string {
+	return fmt.Sprintf("app.kubernetes.io/name=mcp-server,app.kubernetes.io/instance=%s", instanceName)
+}
+
+// StreamLogs implements LogStreamer with the pod log API. The newest pod of
+// the instance is used, so logs follow a rollout.
+func (k *KubernetesBackend) StreamLogs(ctx context.Context, instanceID string, opts LogOptions, w io.Writer) error {
+	instanceName, err := k.findInstanceNameByID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find instance: %w", err)
+	}
+
+	pods, err := k.clientset.CoreV1().Pods(k.k8sConfig.Namespace).List(ctx, metav1.ListOptions{LabelSelector: podSelector(instanceName)})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("instance %s has no pods", instanceID)
+	}
+	pod := pods.Items[0]
+	for _, candidate := range pods.Items[1:] {
+		if candidate.CreationTimestamp.After(pod.CreationTimestamp.Time) {
+			pod = candidate
+		}
+	}
+
+	logOptions := &corev1.PodLogOptions{Follow: opts.Follow, Timestamps: opts.Timestamps}
+	if opts.Tail > 0 {
+		tail := int64(opts.Tail)
+		logOptions.TailLines = &tail
+	}
+	if !opts.Since.IsZero() {
+		since := metav1.NewTime(opts.Since)
+		logOptions.SinceTime = &since
+	}
+
+	stream, err := k.clientset.CoreV1().Pods(k.k8sConfig.Namespace).GetLogs(pod.Name, logOptions).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stream pod logs: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := io.Copy(w, stream); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read pod logs: %w", err)
+	}
+	return nil
+}
+
+// Watch implements Watcher by watching the pods of the instance
+func (k *KubernetesBackend) Watch(ctx context.Context, instanceID string) (<-chan InstanceEvent, error) {
+	instanceName, err := k.findInstanceNameByID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instance: %w", err)
+	}
+
+	watcher, err := k.clientset.CoreV1().Pods(k.k8sConfig.Namespace).Watch(ctx, metav1.ListOptions{LabelSelector: podSelector(instanceName)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch pods: %w", err)
+	}
+
+	events := make(chan InstanceEvent)
+	go func() {
+		defer close(events)
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case watchEvent, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				pod, isPod := watchEvent.Object.(*corev1.Pod)
+				if !isPod {
+					continue
+				}
+				event := InstanceEvent{
+					InstanceID: instanceID,
+					Type:       "pod_" + strings.ToLower(string(watchEvent.Type)),
+					Message:    fmt.Sprintf("%s %s", pod.Name, pod.Status.Phase),
+					Timestamp:  time.Now(),
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
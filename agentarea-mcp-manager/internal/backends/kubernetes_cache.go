@@ -0,0 +1,232 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// cacheResync is how often informers replay their whole cache; watches keep
+// it current in between
+const cacheResync = 10 * time.Minute
+
+// maxHealthRefreshes bounds the background health probes started by listings
+const maxHealthRefreshes = 8
+
+// instanceCache serves listings from informers on the managed deployments,
+// configmaps and secrets of the namespace and keeps the latest health result
+// of each instance. A nil cache is never ready, so callers fall back to the
+// API.
+type instanceCache struct {
+	factory     informers.SharedInformerFactory
+	deployments appslisters.DeploymentNamespaceLister
+	configMaps  corelisters.ConfigMapNamespaceLister
+	secrets     corelisters.SecretNamespaceLister
+	synced      []cache.InformerSynced
+	cancel      context.CancelFunc
+	ctx         context.Context
+
+	healthTTL  time.Duration
+	refreshing chan struct{} // Semaphore of background health probes
+
+	mu      sync.Mutex
+	health  map[string]*HealthCheckResult // By instance name
+	probing map[string]bool
+}
+
+// newInstanceCache creates the informers on the resources of the namespace
+// managed by mcp-manager; start runs them
+func newInstanceCache(clientset kubernetes.Interface, namespace string, healthTTL time.Duration) *instanceCache {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, cacheResync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = "app.kubernetes.io/managed-by=mcp-manager"
+		}))
+
+	deployments := factory.Apps().V1().Deployments()
+	configMaps := factory.Core().V1().ConfigMaps()
+	secrets := factory.Core().V1().Secrets()
+
+	return &instanceCache{
+		factory:     factory,
+		deployments: deployments.Lister().Deployments(namespace),
+		configMaps:  configMaps.Lister().ConfigMaps(namespace),
+		secrets:     secrets.Lister().Secrets(namespace),
+		synced: []cache.InformerSynced{
+			deployments.Informer().HasSynced,
+			configMaps.Informer().HasSynced,
+			secrets.Informer().HasSynced,
+		},
+		healthTTL:  healthTTL,
+		refreshing: make(chan struct{}, maxHealthRefreshes),
+		health:     make(map[string]*HealthCheckResult),
+		probing:    make(map[string]bool),
+	}
+}
+
+// startCache starts the list cache and waits for its initial sync for up to
+// the readiness timeout. Listings use the API until it has synced, and for
+// good if it never does, for example without list/watch permissions.
+func (k *KubernetesBackend) startCache(ctx context.Context) {
+	c := newInstanceCache(k.clientset, k.k8sConfig.Namespace, k.k8sConfig.HealthCacheTTL)
+	c.start()
+	k.cache = c
+
+	syncCtx, cancel := context.WithTimeout(ctx, k.k8sConfig.ReadinessTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), c.synced...) {
+		k.logger.Warn("Instance list cache has not synced, listing through the API until it does")
+		return
+	}
+	k.logger.Info("Instance list cache synced")
+}
+
+// start runs the informers until stop
+func (c *instanceCache) start() {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.factory.Start(c.ctx.Done())
+}
+
+// stop stops the informers and background health probes
+func (c *instanceCache) stop() {
+	if c == nil || c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.factory.Shutdown()
+}
+
+// ready reports whether listings can be served from the cache
+func (c *instanceCache) ready() bool {
+	if c == nil {
+		return false
+	}
+	for _, synced := range c.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// list builds the status of every managed instance from the cache. Health
+// comes from the latest result; missing or stale results are refreshed in
+// the background for the next listing.
+func (c *instanceCache) list(k *KubernetesBackend) ([]*InstanceStatus, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		"app.kubernetes.io/managed-by": "mcp-manager",
+		"app.kubernetes.io/component":  "mcp-server",
+	})
+	deployments, err := c.deployments.List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached deployments: %w", err)
+	}
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+
+	instances := make([]*InstanceStatus, 0, len(deployments))
+	for _, deployment := range deployments {
+		instanceName := strings.TrimPrefix(deployment.Name, "mcp-")
+
+		configMap, err := c.configMaps.Get(deployment.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get cached configmap: %w", err)
+		}
+		secret, err := c.secrets.Get(deployment.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get cached secret: %w", err)
+		}
+
+		status := k.instanceStatus(instanceName, deployment, configMap, secret)
+		if status.Status == "running" {
+			status.HealthStatus = c.cachedHealth(k, instanceName, deployment)
+		}
+		instances = append(instances, status)
+	}
+	return instances, nil
+}
+
+// cachedHealth returns the latest health result of an instance, if any, and
+// refreshes it in the background once older than the TTL
+func (c *instanceCache) cachedHealth(k *KubernetesBackend, instanceName string, deployment *appsv1.Deployment) *HealthCheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := c.health[instanceName]
+	if (result == nil || time.Since(result.Timestamp) >= c.healthTTL) && !c.probing[instanceName] {
+		select {
+		case c.refreshing <- struct{}{}:
+			c.probing[instanceName] = true
+			go c.refreshHealth(k, instanceName, deployment)
+		default:
+			// Enough probes running; a later listing picks this one up
+		}
+	}
+	return result
+}
+
+// refreshHealth probes an instance and records the result
+func (c *instanceCache) refreshHealth(k *KubernetesBackend, instanceName string, deployment *appsv1.Deployment) {
+	defer func() {
+		<-c.refreshing
+		c.mu.Lock()
+		delete(c.probing, instanceName)
+		c.mu.Unlock()
+	}()
+
+	result := k.deploymentHealth(c.ctx, instanceName, deployment)
+	if c.ctx.Err() != nil {
+		return
+	}
+	c.setHealth(instanceName, result)
+}
+
+// setHealth records the latest health result of an instance
+func (c *instanceCache) setHealth(instanceName string, result *HealthCheckResult) {
+	if c == nil || result == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.health[instanceName] = result
+}
+
+// instanceName finds the instance of a deployment UID or instance ID in the
+// cache. Misses are not conclusive: the cache may lag behind a creation.
+func (c *instanceCache) instanceName(instanceID string) (string, bool) {
+	if instanceID == "" || !c.ready() {
+		return "", false
+	}
+	deployments, err := c.deployments.List(labels.Everything())
+	if err != nil {
+		return "", false
+	}
+	for _, deployment := range deployments {
+		if string(deployment.UID) == instanceID || deployment.Spec.Template.Annotations["agentarea.io/instance-id"] == instanceID {
+			return strings.TrimPrefix(deployment.Name, "mcp-"), true
+		}
+	}
+	return "", false
+}
+
+// forget drops the health result of a deleted instance
+func (c *instanceCache) forget(instanceName string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.health, instanceName)
+}
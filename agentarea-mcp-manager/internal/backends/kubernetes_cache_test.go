@@ -0,0 +1,93 @@
+package backends
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListInstancesFromCache(t *testing.T) {
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "mcp", Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "mcp-manager",
+			"app.kubernetes.io/component":  "mcp-server",
+		}}
+	}
+	running := &appsv1.Deployment{
+		ObjectMeta: meta("mcp-running"),
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"agentarea.io/instance-id": "i-running"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Image: "mcp/echo:1"}}},
+		}},
+		Status: appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1, AvailableReplicas: 1},
+	}
+	running.UID = types.UID("uid-running")
+	stopped := &appsv1.Deployment{ObjectMeta: meta("mcp-stopped"), Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(0)}}
+	clientset := fake.NewSimpleClientset(running, stopped,
+		&corev1.ConfigMap{ObjectMeta: meta("mcp-running"), Data: map[string]string{"port": "3000"}},
+		&corev1.Secret{ObjectMeta: meta("mcp-running"), Data: map[string][]byte{"API_KEY": []byte("secret")}},
+	)
+
+	cfg := config.KubernetesConfig{Namespace: "mcp", HealthCacheTTL: time.Hour, ReadinessTimeout: 5 * time.Second}
+	// No client: listings must not reach the API
+	backend := &KubernetesBackend{clientset: clientset, k8sConfig: &cfg, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	backend.startCache(context.Background())
+	defer backend.cache.stop()
+	if !backend.cache.ready() {
+		t.Fatal("cache not synced")
+	}
+
+	instances, err := backend.ListInstances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("listed %d instances, want 2", len(instances))
+	}
+	got := instances[0]
+	if got.ID != "uid-running" || got.Name != "running" || got.Port != 3000 || got.Image != "mcp/echo:1" ||
+		got.Status != "running" || got.Environment["API_KEY"] != "[REDACTED]" {
+		t.Errorf("running instance = %+v", got)
+	}
+	if instances[1].Status != "stopped" || instances[1].HealthStatus != nil {
+		t.Errorf("stopped instance = %+v", instances[1])
+	}
+
+	// The first listing starts a probe in the background; later ones serve
+	// its result until it is older than the TTL
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		instances, err = backend.ListInstances(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if health := instances[0].HealthStatus; health != nil {
+			// Without a health client the server never answers
+			if health.Healthy || health.HTTPReachable || health.ServiceName != "running" {
+				t.Errorf("health = %+v", health)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("health never refreshed in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if name, ok := backend.cache.instanceName("i-running"); !ok || name != "running" {
+		t.Errorf("instanceName(i-running) = %q, %v", name, ok)
+	}
+	if _, ok := backend.cache.instanceName(""); ok {
+		t.Error("empty instance ID matched a deployment")
+	}
+}
@@ -0,0 +1,60 @@
+package backends
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/egress"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetEgressFilter sends the DNS queries of pods with an egress allow-list
+// through the filtering resolver
+func (k *KubernetesBackend) SetEgressFilter(filter *egress.Filter) {
+	k.egress = filter
+	filter.SetLookup(k.egressClient)
+}
+
+// applyEgress makes the filtering resolver the only nameserver of a pod with
+// an egress allow-list and records the list as a pod annotation, where the
+// resolver reads it back
+func (k *KubernetesBackend) applyEgress(template *corev1.PodTemplateSpec, labels map[string]string) {
+	allow := labels[container.EgressAllowLabel]
+	if allow == "" || k.egress == nil {
+		delete(template.Annotations, container.EgressAllowLabel)
+		if template.Spec.DNSPolicy == corev1.DNSNone {
+			template.Spec.DNSPolicy = ""
+		}
+		return
+	}
+
+	template.Annotations[container.EgressAllowLabel] = allow
+	template.Spec.DNSPolicy = corev1.DNSNone
+	searches := container.DNSOptionsFromLabels(labels).DNSSearch
+	template.Spec.DNSConfig = &corev1.PodDNSConfig{Nameservers: []string{k.egress.Address()}, Searches: searches}
+}
+
+// egressClient finds the filtered pod with the given IP
+func (k *KubernetesBackend) egressClient(ctx context.Context, ip string) (egress.Client, bool) {
+	pods := &corev1.PodList{}
+	if err := k.client.List(ctx, pods, client.InNamespace(k.k8sConfig.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/managed-by": "mcp-manager",
+	}); err != nil {
+		k.logger.Warn("Failed to list pods for egress filtering", slog.String("error", err.Error()))
+		return egress.Client{}, false
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP != ip {
+			continue
+		}
+		allow := container.EgressAllowFromLabels(pod.Annotations)
+		if len(allow) == 0 {
+			return egress.Client{}, false
+		}
+		return egress.Client{Service: pod.Labels["agentarea.io/instance"], Allow: allow}, true
+	}
+	return egress.Client{}, false
+}
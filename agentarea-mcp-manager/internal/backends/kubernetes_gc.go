@@ -0,0 +1,159 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OrphanedResource is a managed resource whose instance has no Deployment
+type OrphanedResource struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Instance  string    `json:"instance"`
+	CreatedAt time.Time `json:"created_at"`
+	Deleted   bool      `json:"deleted"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// GCReport is the result of a garbage collection pass
+type GCReport struct {
+	DryRun      bool               `json:"dry_run"`
+	GracePeriod string             `json:"grace_period"`
+	Orphans     []OrphanedResource `json:"orphans"`
+	Deleted     int                `json:"deleted"`
+	CheckedAt   time.Time          `json:"checked_at"`
+}
+
+// GarbageCollector is implemented by backends whose instances are made of
+// several resources that a crash can leave behind
+type GarbageCollector interface {
+	CollectGarbage(ctx context.Context, dryRun bool) (*GCReport, error)
+}
+
+// CollectGarbage finds the ConfigMaps, Secrets, Services and Ingresses of
+// instances without a Deployment and, unless dryRun, deletes them. Resources
+// younger than the grace period are kept, since CreateInstance makes the
+// ConfigMap and Secret before the Deployment.
+func (k *KubernetesBackend) CollectGarbage(ctx context.Context, dryRun bool) (*GCReport, error) {
+	inNamespace := client.InNamespace(k.k8sConfig.Namespace)
+	managed := client.MatchingLabels{"app.kubernetes.io/managed-by": "mcp-manager"}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := k.client.List(ctx, deployments, inNamespace, managed); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	live := make(map[string]bool, len(deployments.Items))
+	for _, deployment := range deployments.Items {
+		live[deployment.Name] = true
+	}
+
+	var candidates []client.Object
+	for _, list := range []client.ObjectList{
+		&networkingv1.IngressList{},
+		&corev1.ServiceList{},
+		&corev1.SecretList{},
+		&corev1.ConfigMapList{},
+	} {
+		if err := k.client.List(ctx, list, inNamespace, managed); err != nil {
+			return nil, fmt.Errorf("failed to list %T: %w", list, err)
+		}
+		switch items := list.(type) {
+		case *networkingv1.IngressList:
+			for i := range items.Items {
+				candidates = append(candidates, &items.Items[i])
+			}
+		case *corev1.ServiceList:
+			for i := range items.Items {
+				candidates = append(candidates, &items.Items[i])
+			}
+		case *corev1.SecretList:
+			for i := range items.Items {
+				candidates = append(candidates, &items.Items[i])
+			}
+		case *corev1.ConfigMapList:
+			for i := range items.Items {
+				candidates = append(candidates, &items.Items[i])
+			}
+		}
+	}
+
+	report := &GCReport{
+		DryRun:      dryRun,
+		GracePeriod: k.k8sConfig.GCGracePeriod.String(),
+		Orphans:     []OrphanedResource{},
+		CheckedAt:   time.Now(),
+	}
+	cutoff := report.CheckedAt.Add(-k.k8sConfig.GCGracePeriod)
+	for _, object := range candidates {
+		// Only per-instance resources; shared ones such as the CA bundle
+		// ConfigMap carry no instance label
+		instance := object.GetLabels()["agentarea.io/instance"]
+		if instance == "" || !strings.HasPrefix(object.GetName(), "mcp-") || live[object.GetName()] {
+			continue
+		}
+		if object.GetCreationTimestamp().Time.After(cutoff) || object.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		orphan := OrphanedResource{
+			Kind:      strings.TrimPrefix(fmt.Sprintf("%T", object), "*v1."),
+			Name:      object.GetName(),
+			Instance:  instance,
+			CreatedAt: object.GetCreationTimestamp().Time,
+		}
+		if !dryRun {
+			if err := k.client.Delete(ctx, object); err != nil && !errors.IsNotFound(err) {
+				orphan.Error = err.Error()
+			} else {
+				orphan.Deleted = true
+				report.Deleted++
+			}
+		}
+		report.Orphans = append(report.Orphans, orphan)
+	}
+	slices.SortFunc(report.Orphans, func(a, b OrphanedResource) int {
+		return strings.Compare(a.Name+"/"+a.Kind, b.Name+"/"+b.Kind)
+	})
+	return report, nil
+}
+
+// RunGarbageCollection deletes orphaned resources every GC interval until ctx
+// is done
+func (k *KubernetesBackend) RunGarbageCollection(ctx context.Context) {
+	ticker := time.NewTicker(k.k8sConfig.GCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := k.CollectGarbage(ctx, false)
+			if err != nil {
+				k.logger.Warn("Garbage collection failed", slog.String("error", err.Error()))
+				continue
+			}
+			for _, orphan := range report.Orphans {
+				if orphan.Error != "" {
+					k.logger.Warn("Failed to delete orphaned resource",
+						slog.String("kind", orphan.Kind),
+						slog.String("name", orphan.Name),
+						slog.String("error", orphan.Error))
+				}
+			}
+			if report.Deleted > 0 {
+				k.logger.Info("Deleted orphaned resources", slog.Int("count", report.Deleted))
+			}
+		}
+	}
+}
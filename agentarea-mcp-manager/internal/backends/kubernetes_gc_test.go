@@ -0,0 +1,71 @@
+package backends
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	old := metav1.NewTime(time.Now().Add(-time.Hour))
+	meta := func(name, instance string, created metav1.Time) metav1.ObjectMeta {
+		labels := map[string]string{"app.kubernetes.io/managed-by": "mcp-manager"}
+		if instance != "" {
+			labels["agentarea.io/instance"] = instance
+		}
+		return metav1.ObjectMeta{Name: name, Namespace: "mcp", Labels: labels, CreationTimestamp: created}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: meta("mcp-live", "live", old)},
+		&corev1.ConfigMap{ObjectMeta: meta("mcp-live", "live", old)},
+		&corev1.ConfigMap{ObjectMeta: meta("mcp-crashed", "crashed", old)},
+		&corev1.Secret{ObjectMeta: meta("mcp-crashed", "crashed", old)},
+		&corev1.Secret{ObjectMeta: meta("mcp-creating", "creating", metav1.Now())},
+		&corev1.ConfigMap{ObjectMeta: meta(caBundleConfigMap, "", old)},
+	).Build()
+
+	cfg := config.DefaultKubernetesConfig()
+	cfg.Namespace = "mcp"
+	backend := &KubernetesBackend{client: k8sClient, k8sConfig: &cfg, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	ctx := context.Background()
+	report, err := backend.CollectGarbage(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Orphans) != 2 || report.Deleted != 0 || report.Orphans[0].Kind != "ConfigMap" || report.Orphans[1].Kind != "Secret" {
+		t.Fatalf("unexpected dry-run report: %+v", report)
+	}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "mcp", Name: "mcp-crashed"}, &corev1.Secret{}); err != nil {
+		t.Fatalf("dry run deleted a resource: %v", err)
+	}
+
+	if report, err = backend.CollectGarbage(ctx, false); err != nil || report.Deleted != 2 {
+		t.Fatalf("deleted %+v, %v", report, err)
+	}
+	for name, obj := range map[string]*corev1.ConfigMap{"mcp-live": {}, caBundleConfigMap: {}} {
+		if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "mcp", Name: name}, obj); err != nil {
+			t.Errorf("%s was collected: %v", name, err)
+		}
+	}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "mcp", Name: "mcp-creating"}, &corev1.Secret{}); err != nil {
+		t.Errorf("resource within the grace period was collected: %v", err)
+	}
+}
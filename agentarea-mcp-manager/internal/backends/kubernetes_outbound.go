@@ -0,0 +1,81 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/agentarea/mcp-manager/internal/outbound"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// caBundleConfigMap holds the merged CA bundle mounted into every instance
+const caBundleConfigMap = "mcp-outbound-ca"
+
+// SetOutbound passes the corporate proxy and CA bundle to every instance
+// deployed from now on
+func (k *KubernetesBackend) SetOutbound(settings *outbound.Settings) {
+	k.outbound = settings
+}
+
+// applyOutbound adds the proxy and CA variables to the pod and mounts the CA
+// bundle from a ConfigMap kept in sync with the manager's bundle
+func (k *KubernetesBackend) applyOutbound(ctx context.Context, template *corev1.PodTemplateSpec, env map[string]string) error {
+	mcp := &template.Spec.Containers[0]
+	vars := k.outbound.Environment(env)
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		mcp.Env = append(mcp.Env, corev1.EnvVar{Name: name, Value: vars[name]})
+	}
+
+	bundle := k.outbound.Bundle()
+	if bundle == "" {
+		return nil
+	}
+	if err := k.syncCABundle(ctx, bundle); err != nil {
+		return err
+	}
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: "outbound-ca",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: caBundleConfigMap},
+			},
+		},
+	})
+	mcp.VolumeMounts = append(mcp.VolumeMounts, corev1.VolumeMount{
+		Name:      "outbound-ca",
+		MountPath: outbound.ContainerCABundle,
+		SubPath:   "ca-bundle.pem",
+		ReadOnly:  true,
+	})
+	return nil
+}
+
+// syncCABundle creates or updates the CA bundle ConfigMap
+func (k *KubernetesBackend) syncCABundle(ctx context.Context, bundle string) error {
+	pem, err := os.ReadFile(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      caBundleConfigMap,
+			Namespace: k.k8sConfig.Namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "mcp-manager"},
+		},
+		Data: map[string]string{"ca-bundle.pem": string(pem)},
+	}
+	err = k.client.Create(ctx, configMap)
+	if errors.IsAlreadyExists(err) {
+		err = k.client.Update(ctx, configMap)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sync CA bundle configmap: %w", err)
+	}
+	return nil
+}
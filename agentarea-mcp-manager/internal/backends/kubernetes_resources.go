@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/container"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -79,7 +82,7 @@ func (k *KubernetesBackend) createSecret(ctx context.Context, instanceName strin
 
 // createDeployment creates a Deployment for the MCP server
 func (k *KubernetesBackend) createDeployment(ctx context.Context, instanceName string, spec *InstanceSpec) error {
-	labels := k.getCommonLabels(instanceName)
+	labels := k.podLabels(instanceName, spec)
 
 	// Convert ResourceList to config.ResourceRequirements
 	var configRequests, configLimits *config.ResourceRequirements
@@ -252,6 +255,16 @@ func (k *KubernetesBackend) createDeployment(ctx context.Context, instanceName s
 	}
 	deployment.Spec.Template.ObjectMeta.Annotations["agentarea.io/instance-id"] = spec.InstanceID
 	deployment.Spec.Template.ObjectMeta.Annotations["agentarea.io/workspace-id"] = spec.WorkspaceID
+	setBandwidthAnnotations(deployment.Spec.Template.ObjectMeta.Annotations, spec.Labels)
+	applyRuntimeOptions(&deployment.Spec.Template, spec.Labels, spec.Environment)
+	applyStartupProbe(&deployment.Spec.Template, spec.Labels)
+	applyHostAccess(&deployment.Spec.Template, spec.Labels)
+	applyDNS(&deployment.Spec.Template, spec.Labels)
+	applyScratch(&deployment.Spec.Template, spec.Labels)
+	k.applyEgress(&deployment.Spec.Template, spec.Labels)
+	if err := k.applyOutbound(ctx, &deployment.Spec.Template, spec.Environment); err != nil {
+		return err
+	}
 
 	if err := k.client.Create(ctx, deployment); err != nil {
 		return fmt.Errorf("failed to create deployment: %w", err)
@@ -326,6 +339,7 @@ func (k *KubernetesBackend) createService(ctx context.Context, instanceName stri
 			Protocol:   corev1.ProtocolTCP,
 		})
 	}
+	applyIPFamilies(&service.Spec, k.k8sConfig)
 
 	if err := k.client.Create(ctx, service); err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
@@ -334,6 +348,18 @@ func (k *KubernetesBackend) createService(ctx context.Context, instanceName stri
 	return nil
 }
 
+// applyIPFamilies sets the configured IP family policy and families of a
+// Service; without them the cluster defaults apply
+func applyIPFamilies(spec *corev1.ServiceSpec, cfg *config.KubernetesConfig) {
+	if cfg.IPFamilyPolicy != "" {
+		policy := corev1.IPFamilyPolicy(cfg.IPFamilyPolicy)
+		spec.IPFamilyPolicy = &policy
+	}
+	for _, family := range cfg.IPFamilies {
+		spec.IPFamilies = append(spec.IPFamilies, corev1.IPFamily(family))
+	}
+}
+
 // createIngress creates an Ingress for external access
 func (k *KubernetesBackend) createIngress(ctx context.Context, instanceName string, spec *InstanceSpec) error {
 	pathType := networkingv1.PathTypePrefix
@@ -464,34 +490,43 @@ func (k *KubernetesBackend) cleanupResources(ctx context.Context, instanceName s
 // Update methods
 
 // updateConfigMap updates the ConfigMap for an instance
-func (k *KubernetesBackend) updateConfigMap(ctx context.Context, instanceName string, spec *InstanceSpec) error {
+func (k *KubernetesBackend) updateConfigMap(ctx context.Context, instanceName string, spec *InstanceSpec) (map[string]string, error) {
 	configMap := &corev1.ConfigMap{}
 	if err := k.client.Get(ctx, types.NamespacedName{
 		Namespace: k.k8sConfig.Namespace,
 		Name:      fmt.Sprintf("mcp-%s", instanceName),
 	}, configMap); err != nil {
-		return fmt.Errorf("failed to get configmap: %w", err)
+		return nil, fmt.Errorf("failed to get configmap: %w", err)
 	}
+	previous := make(map[string]string, len(configMap.Data))
+	maps.Copy(previous, configMap.Data)
 
 	// Update data
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
 	configMap.Data["port"] = strconv.Itoa(spec.Port)
 	configMap.Data["workspace-id"] = spec.WorkspaceID
 
 	if err := k.client.Update(ctx, configMap); err != nil {
-		return fmt.Errorf("failed to update configmap: %w", err)
+		return nil, fmt.Errorf("failed to update configmap: %w", err)
 	}
 
-	return nil
+	return previous, nil
 }
 
 // updateSecret updates the Secret for an instance
-func (k *KubernetesBackend) updateSecret(ctx context.Context, instanceName string, spec *InstanceSpec) error {
+func (k *KubernetesBackend) updateSecret(ctx context.Context, instanceName string, spec *InstanceSpec) (map[string][]byte, error) {
 	secret := &corev1.Secret{}
 	if err := k.client.Get(ctx, types.NamespacedName{
 		Namespace: k.k8sConfig.Namespace,
 		Name:      fmt.Sprintf("mcp-%s", instanceName),
 	}, secret); err != nil {
-		return fmt.Errorf("failed to get secret: %w", err)
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+	previous := secret.Data
+	if previous == nil {
+		previous = make(map[string][]byte)
 	}
 
 	// Update data
@@ -506,10 +541,44 @@ func (k *KubernetesBackend) updateSecret(ctx context.Context, instanceName strin
 	secret.Data = secretData
 
 	if err := k.client.Update(ctx, secret); err != nil {
-		return fmt.Errorf("failed to update secret: %w", err)
+		return nil, fmt.Errorf("failed to update secret: %w", err)
 	}
 
-	return nil
+	return previous, nil
+}
+
+// restoreConfig puts back the configmap and secret data of an update whose
+// deployment could not be updated; a nil map leaves that resource alone
+func (k *KubernetesBackend) restoreConfig(ctx context.Context, instanceName string, configData map[string]string, secretData map[string][]byte) {
+	key := types.NamespacedName{Namespace: k.k8sConfig.Namespace, Name: fmt.Sprintf("mcp-%s", instanceName)}
+
+	if configData != nil {
+		configMap := &corev1.ConfigMap{}
+		err := k.client.Get(ctx, key, configMap)
+		if err == nil {
+			configMap.Data = configData
+			err = k.client.Update(ctx, configMap)
+		}
+		if err != nil {
+			k.logger.Error("Failed to roll back configmap after a failed update",
+				slog.String("instance_name", instanceName),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if secretData != nil {
+		secret := &corev1.Secret{}
+		err := k.client.Get(ctx, key, secret)
+		if err == nil {
+			secret.Data = secretData
+			err = k.client.Update(ctx, secret)
+		}
+		if err != nil {
+			k.logger.Error("Failed to roll back secret after a failed update",
+				slog.String("instance_name", instanceName),
+				slog.String("error", err.Error()))
+		}
+	}
 }
 
 // updateDeployment updates the Deployment for an instance
@@ -522,6 +591,19 @@ func (k *KubernetesBackend) updateDeployment(ctx context.Context, instanceName s
 		return fmt.Errorf("failed to get deployment: %w", err)
 	}
 
+	// Reject the update if the spec changed since the caller's version; the
+	// Update below is additionally guarded by the API server's resourceVersion
+	if spec.ResourceVersion != "" && strconv.FormatInt(deployment.Generation, 10) != spec.ResourceVersion {
+		return fmt.Errorf("%w: deployment changed during update", ErrConflict)
+	}
+
+	// Replace user labels, keeping the managed ones
+	if spec.Labels != nil {
+		labels := k.podLabels(instanceName, spec)
+		deployment.Labels = labels
+		deployment.Spec.Template.Labels = labels
+	}
+
 	// Update container image and command if needed
 	if len(deployment.Spec.Template.Spec.Containers) > 0 {
 		container := &deployment.Spec.Template.Spec.Containers[0]
@@ -569,8 +651,20 @@ func (k *KubernetesBackend) updateDeployment(ctx context.Context, instanceName s
 		deployment.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
 	}
 	deployment.Spec.Template.ObjectMeta.Annotations["agentarea.io/updated-at"] = time.Now().Format(time.RFC3339)
+	if spec.Labels != nil {
+		setBandwidthAnnotations(deployment.Spec.Template.ObjectMeta.Annotations, spec.Labels)
+		applyRuntimeOptions(&deployment.Spec.Template, spec.Labels, spec.Environment)
+		applyStartupProbe(&deployment.Spec.Template, spec.Labels)
+		applyHostAccess(&deployment.Spec.Template, spec.Labels)
+		applyDNS(&deployment.Spec.Template, spec.Labels)
+		applyScratch(&deployment.Spec.Template, spec.Labels)
+		k.applyEgress(&deployment.Spec.Template, spec.Labels)
+	}
 
 	if err := k.client.Update(ctx, deployment); err != nil {
+		if errors.IsConflict(err) {
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
 		return fmt.Errorf("failed to update deployment: %w", err)
 	}
 
@@ -579,8 +673,205 @@ func (k *KubernetesBackend) updateDeployment(ctx context.Context, instanceName s
 
 // Helper functions
 
+// setBandwidthAnnotations maps the instance bandwidth labels to the pod
+// annotations read by the CNI bandwidth plugin
+func setBandwidthAnnotations(annotations, labels map[string]string) {
+	for label, annotation := range map[string]string{
+		container.IngressBandwidthLabel: "kubernetes.io/ingress-bandwidth",
+		container.EgressBandwidthLabel:  "kubernetes.io/egress-bandwidth",
+	} {
+		if rate := labels[label]; rate != "" {
+			annotations[annotation] = rate
+		} else {
+			delete(annotations, annotation)
+		}
+	}
+}
+
+// podLabels returns the deployment and pod labels: the user and attribution
+// labels, the cost tool aliases of the attribution, and the managed labels
+func (k *KubernetesBackend) podLabels(instanceName string, spec *InstanceSpec) map[string]string {
+	labels := mergeLabels(withoutSpecLabels(spec.Labels), container.CostLabels(spec.Labels, k.config.Container.CostLabelAliases))
+	return mergeLabels(labels, k.getCommonLabels(instanceName))
+}
+
+// withoutSpecLabels drops the labels carrying runtime options, DNS settings,
+// host paths, egress allow-lists and warm-up requests, whose values (such as Europe/Berlin) are not valid Kubernetes label values
+func withoutSpecLabels(labels map[string]string) map[string]string {
+	result := maps.Clone(labels)
+	for _, label := range container.RuntimeOptionLabels {
+		delete(result, label)
+	}
+	for _, label := range container.DNSLabels {
+		delete(result, label)
+	}
+	delete(result, container.DevicesLabel)
+	delete(result, container.HostSocketsLabel)
+	delete(result, container.EgressAllowLabel)
+	delete(result, container.WarmupLabel)
+	return result
+}
+
+// applyDNS maps the instance DNS options to the pod DNS config and host
+// aliases. The pod keeps the cluster DNS policy, so the custom nameservers
+// and search domains come after the cluster ones.
+func applyDNS(template *corev1.PodTemplateSpec, labels map[string]string) {
+	opts := container.DNSOptionsFromLabels(labels)
+	for _, label := range container.DNSLabels {
+		if value := labels[label]; value != "" {
+			template.Annotations[label] = value
+		} else {
+			delete(template.Annotations, label)
+		}
+	}
+
+	template.Spec.DNSConfig = nil
+	if len(opts.DNSServers) > 0 || len(opts.DNSSearch) > 0 {
+		template.Spec.DNSConfig = &corev1.PodDNSConfig{Nameservers: opts.DNSServers, Searches: opts.DNSSearch}
+	}
+
+	// Host aliases are grouped by address
+	hostsByIP := make(map[string][]string)
+	for _, host := range slices.Sorted(maps.Keys(opts.ExtraHosts)) {
+		ip := opts.ExtraHosts[host]
+		hostsByIP[ip] = append(hostsByIP[ip], host)
+	}
+	template.Spec.HostAliases = nil
+	for _, ip := range slices.Sorted(maps.Keys(hostsByIP)) {
+		template.Spec.HostAliases = append(template.Spec.HostAliases, corev1.HostAlias{IP: ip, Hostnames: hostsByIP[ip]})
+	}
+}
+
+// applyHostAccess mounts the instance's host devices and sockets as hostPath
+// volumes at the same paths and records them as pod annotations. Opening a
+// device also depends on the node's device permissions for the pod.
+func applyHostAccess(template *corev1.PodTemplateSpec, labels map[string]string) {
+	access := container.HostAccessFromLabels(labels)
+	for _, label := range []string{container.DevicesLabel, container.HostSocketsLabel} {
+		if value := labels[label]; value != "" {
+			template.Annotations[label] = value
+		} else {
+			delete(template.Annotations, label)
+		}
+	}
+
+	isHostVolume := func(name string) bool {
+		return strings.HasPrefix(name, "device-") || strings.HasPrefix(name, "host-socket-")
+	}
+	mcp := &template.Spec.Containers[0]
+	template.Spec.Volumes = slices.DeleteFunc(template.Spec.Volumes, func(v corev1.Volume) bool { return isHostVolume(v.Name) })
+	mcp.VolumeMounts = slices.DeleteFunc(mcp.VolumeMounts, func(m corev1.VolumeMount) bool { return isHostVolume(m.Name) })
+
+	mount := func(name, path string, pathType corev1.HostPathType) {
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: path, Type: &pathType},
+			},
+		})
+		mcp.VolumeMounts = append(mcp.VolumeMounts, corev1.VolumeMount{Name: name, MountPath: path})
+	}
+	for i, device := range access.Devices {
+		mount(fmt.Sprintf("device-%d", i), device, corev1.HostPathCharDev)
+	}
+	for i, socket := range access.HostSockets {
+		mount(fmt.Sprintf("host-socket-%d", i), socket, corev1.HostPathSocket)
+	}
+}
+
+// scratchVolume names the volume of an instance's scratch directory
+const scratchVolume = "scratch"
+
+// applyScratch mounts the instance scratch volume as an emptyDir with a
+// sizeLimit; the kubelet evicts the pod when it grows beyond the limit. A
+// scratch path replaces the default mount at the same path, such as /tmp.
+func applyScratch(template *corev1.PodTemplateSpec, labels map[string]string) {
+	if len(template.Spec.Containers) == 0 {
+		return
+	}
+	mcp := &template.Spec.Containers[0]
+	template.Spec.Volumes = slices.DeleteFunc(template.Spec.Volumes, func(v corev1.Volume) bool { return v.Name == scratchVolume })
+	mcp.VolumeMounts = slices.DeleteFunc(mcp.VolumeMounts, func(m corev1.VolumeMount) bool { return m.Name == scratchVolume })
+
+	scratch := container.ScratchFromLabels(labels)
+	if scratch == nil {
+		return
+	}
+	size, err := scratch.Bytes()
+	if err != nil {
+		return
+	}
+	path := scratch.MountPath()
+	mcp.VolumeMounts = slices.DeleteFunc(mcp.VolumeMounts, func(m corev1.VolumeMount) bool { return m.MountPath == path })
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: scratchVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: resource.NewQuantity(size, resource.BinarySI)},
+		},
+	})
+	mcp.VolumeMounts = append(mcp.VolumeMounts, corev1.VolumeMount{Name: scratchVolume, MountPath: path})
+}
+
+// applyStartupProbe maps the instance startup probe to a startupProbe on the
+// liveness endpoint, which holds back the liveness and readiness probes until
+// it passes
+func applyStartupProbe(template *corev1.PodTemplateSpec, labels map[string]string) {
+	if len(template.Spec.Containers) == 0 {
+		return
+	}
+	mcpServer := &template.Spec.Containers[0]
+	probe := container.StartupProbeFromLabels(labels)
+	if probe == nil || mcpServer.LivenessProbe == nil {
+		mcpServer.StartupProbe = nil
+		return
+	}
+	mcpServer.StartupProbe = &corev1.Probe{
+		ProbeHandler:     mcpServer.LivenessProbe.ProbeHandler,
+		PeriodSeconds:    int32(probe.PeriodDuration() / time.Second),
+		TimeoutSeconds:   mcpServer.LivenessProbe.TimeoutSeconds,
+		FailureThreshold: int32(probe.Failures()),
+	}
+}
+
+// applyRuntimeOptions maps the instance runtime options to the pod: timezone
+// and locale variables, sysctls in the pod security context, and every option
+// as a pod annotation. Kubernetes has no per-container ulimits, so nofile and
+// nproc are only recorded; the node's container runtime defaults apply.
+func applyRuntimeOptions(template *corev1.PodTemplateSpec, labels, env map[string]string) {
+	opts := container.RuntimeOptionsFromLabels(labels)
+	for _, label := range container.RuntimeOptionLabels {
+		if value := labels[label]; value != "" {
+			template.Annotations[label] = value
+		} else {
+			delete(template.Annotations, label)
+		}
+	}
+
+	mcp := &template.Spec.Containers[0]
+	vars := opts.Environment(env)
+	mcp.Env = slices.DeleteFunc(mcp.Env, func(v corev1.EnvVar) bool {
+		return v.Name == "TZ" || v.Name == "LANG" || v.Name == "LC_ALL"
+	})
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		mcp.Env = append(mcp.Env, corev1.EnvVar{Name: name, Value: vars[name]})
+	}
+
+	if template.Spec.SecurityContext == nil {
+		template.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	template.Spec.SecurityContext.Sysctls = nil
+	for _, name := range slices.Sorted(maps.Keys(opts.Sysctls)) {
+		template.Spec.SecurityContext.Sysctls = append(template.Spec.SecurityContext.Sysctls,
+			corev1.Sysctl{Name: name, Value: opts.Sysctls[name]})
+	}
+}
+
 // findInstanceNameByID finds instance name by deployment UID or instance ID
 func (k *KubernetesBackend) findInstanceNameByID(ctx context.Context, instanceID string) (string, error) {
+	if instanceName, ok := k.cache.instanceName(instanceID); ok {
+		return instanceName, nil
+	}
+
 	deployments := &appsv1.DeploymentList{}
 	if err := k.client.List(ctx, deployments, client.InNamespace(k.k8sConfig.Namespace), client.MatchingLabels{
 		"app.kubernetes.io/managed-by": "mcp-manager",
@@ -604,11 +895,16 @@ func (k *KubernetesBackend) findInstanceNameByID(ctx context.Context, instanceID
 		}
 	}
 
-	return "", fmt.Errorf("instance not found: %s", instanceID)
+	return "", fmt.Errorf("%w: %s", ErrNotFound, instanceID)
 }
 
 // getDeploymentStatus determines status from deployment conditions
 func (k *KubernetesBackend) getDeploymentStatus(deployment *appsv1.Deployment) string {
+	// Scaled to zero by StopInstance
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0 {
+		return "stopped"
+	}
+
 	if deployment.Status.ReadyReplicas == 0 {
 		return "starting"
 	}
@@ -635,13 +931,19 @@ func (k *KubernetesBackend) getDeploymentStatus(deployment *appsv1.Deployment) s
 
 // performHTTPHealthCheck performs HTTP health check against the service
 func (k *KubernetesBackend) performHTTPHealthCheck(ctx context.Context, instanceName string) (bool, time.Duration) {
+	if k.healthClient == nil {
+		return false, 0
+	}
 	// Use internal service URL for health check
-	url := fmt.Sprintf("http://mcp-%s.%s.svc.cluster.local/health", instanceName, k.k8sConfig.Namespace)
+	host := fmt.Sprintf("mcp-%s.%s.svc.cluster.local", instanceName, k.k8sConfig.Namespace)
+	url := k.healthClient.URL(host, 80, "/health")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, 0
+	}
 
 	start := time.Now()
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	resp, err := client.Get(url)
+	resp, err := k.healthClient.Do(req, nil)
 	responseTime := time.Since(start)
 
 	if err != nil {
@@ -0,0 +1,86 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestUpdateInstanceRollsBackConfigOnConflict(t *testing.T) {
+	meta := metav1.ObjectMeta{Name: "mcp-echo", Namespace: "mcp", UID: "uid-1", Labels: map[string]string{"app.kubernetes.io/managed-by": "mcp-manager"}}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// The deployment changes between the version check and its update
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: meta, Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "mcp-server",
+				Image: "mcp/echo:1",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+				},
+			}}},
+		}}},
+		&corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{"port": "8000", "workspace-id": "ws-1"}},
+		&corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{"API_KEY": []byte("old")}},
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*appsv1.Deployment); ok {
+				return apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, obj.GetName(), errors.New("modified"))
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+
+	cfg := config.DefaultKubernetesConfig()
+	cfg.Namespace = "mcp"
+	backend := &KubernetesBackend{client: k8sClient, k8sConfig: &cfg, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	ctx := context.Background()
+	err := backend.UpdateInstance(ctx, "uid-1", &InstanceSpec{
+		Image:       "mcp/echo:2",
+		Port:        9000,
+		WorkspaceID: "ws-1",
+		Environment: map[string]string{"API_KEY": "new"},
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	key := types.NamespacedName{Namespace: "mcp", Name: "mcp-echo"}
+	configMap := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, key, configMap); err != nil {
+		t.Fatal(err)
+	}
+	if configMap.Data["port"] != "8000" {
+		t.Errorf("Expected the configmap to be rolled back, got %v", configMap.Data)
+	}
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, key, secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["API_KEY"]) != "old" || len(secret.Data) != 1 {
+		t.Errorf("Expected the secret to be rolled back, got %v", secret.Data)
+	}
+}
@@ -0,0 +1,117 @@
+package backends
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// reservedLabelPrefixes are label keys managed by mcp-manager itself
+var reservedLabelPrefixes = []string{"app.kubernetes.io/", "agentarea.io/"}
+
+// ProtectedLabel marks an instance as delete-protected
+const ProtectedLabel = "agentarea.io/protected"
+
+// IsProtected reports whether instance labels mark it as delete-protected
+func IsProtected(labels map[string]string) bool {
+	return labels[ProtectedLabel] == "true"
+}
+
+// SetProtected adds or removes the delete-protection label
+func SetProtected(labels map[string]string, protected bool) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if protected {
+		labels[ProtectedLabel] = "true"
+	} else {
+		delete(labels, ProtectedLabel)
+	}
+	return labels
+}
+
+// ValidateLabels checks user labels against Kubernetes label syntax so the
+// same labels work on both backends, and rejects reserved keys
+func ValidateLabels(userLabels map[string]string) error {
+	for key, value := range userLabels {
+		if isReservedLabel(key) {
+			return fmt.Errorf("label %q uses a reserved prefix", key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid label value %q for key %q: %s", value, key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// UserLabels returns the labels that were set by users, dropping labels
+// managed by mcp-manager
+func UserLabels(all map[string]string) map[string]string {
+	result := make(map[string]string, len(all))
+	for key, value := range all {
+		if !isReservedLabel(key) {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// ParseLabelSelector parses one or more label selector expressions
+// (e.g. "team=ml", "env!=prod,tier in (a,b)") into a single selector
+func ParseLabelSelector(expressions []string) (labels.Selector, error) {
+	nonEmpty := make([]string, 0, len(expressions))
+	for _, expr := range expressions {
+		if expr = strings.TrimSpace(expr); expr != "" {
+			nonEmpty = append(nonEmpty, expr)
+		}
+	}
+
+	selector, err := labels.Parse(strings.Join(nonEmpty, ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+	return selector, nil
+}
+
+// FilterByLabels returns the instances whose labels match the selector
+func FilterByLabels(instances []*InstanceStatus, selector labels.Selector) []*InstanceStatus {
+	if selector.Empty() {
+		return instances
+	}
+
+	filtered := make([]*InstanceStatus, 0, len(instances))
+	for _, instance := range instances {
+		if selector.Matches(labels.Set(instance.Labels)) {
+			filtered = append(filtered, instance)
+		}
+	}
+	return filtered
+}
+
+// isReservedLabel reports whether a label key is managed by mcp-manager
+func isReservedLabel(key string) bool {
+	for _, prefix := range reservedLabelPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeLabels overlays managed labels on top of user labels so users cannot
+// override the labels mcp-manager relies on
+func mergeLabels(userLabels, managed map[string]string) map[string]string {
+	result := make(map[string]string, len(userLabels)+len(managed))
+	for key, value := range userLabels {
+		result[key] = value
+	}
+	for key, value := range managed {
+		result[key] = value
+	}
+	return result
+}
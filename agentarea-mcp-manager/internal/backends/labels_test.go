@@ -0,0 +1,63 @@
+package backends
+
+import "testing"
+
+func TestValidateLabels(t *testing.T) {
+	valid := map[string]string{"team": "ml", "example.com/owner": "alice"}
+	if err := ValidateLabels(valid); err != nil {
+		t.Errorf("Expected labels to be valid, got %v", err)
+	}
+
+	invalid := []map[string]string{
+		{"agentarea.io/instance": "x"},
+		{"bad key": "x"},
+		{"team": "not valid!"},
+	}
+	for _, labels := range invalid {
+		if err := ValidateLabels(labels); err == nil {
+			t.Errorf("Expected %v to be rejected", labels)
+		}
+	}
+}
+
+func TestFilterByLabels(t *testing.T) {
+	instances := []*InstanceStatus{
+		{ID: "a", Labels: map[string]string{"team": "ml", "env": "prod"}},
+		{ID: "b", Labels: map[string]string{"team": "ml", "env": "dev"}},
+		{ID: "c", Labels: map[string]string{"team": "web"}},
+		{ID: "d"},
+	}
+
+	tests := []struct {
+		selectors []string
+		want      []string
+	}{
+		{nil, []string{"a", "b", "c", "d"}},
+		{[]string{"team=ml"}, []string{"a", "b"}},
+		{[]string{"team=ml", "env!=prod"}, []string{"b"}},
+		{[]string{"team in (web,data)"}, []string{"c"}},
+		{[]string{"!team"}, []string{"d"}},
+	}
+
+	for _, tt := range tests {
+		selector, err := ParseLabelSelector(tt.selectors)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%v) failed: %v", tt.selectors, err)
+		}
+
+		got := FilterByLabels(instances, selector)
+		if len(got) != len(tt.want) {
+			t.Errorf("selector %v: expected %v, got %d instances", tt.selectors, tt.want, len(got))
+			continue
+		}
+		for i, instance := range got {
+			if instance.ID != tt.want[i] {
+				t.Errorf("selector %v: expected %v, got %s at %d", tt.selectors, tt.want, instance.ID, i)
+			}
+		}
+	}
+
+	if _, err := ParseLabelSelector([]string{"team in ml"}); err == nil {
+		t.Error("Expected invalid selector to be rejected")
+	}
+}
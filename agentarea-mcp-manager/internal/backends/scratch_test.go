@@ -0,0 +1,38 @@
+package backends
+
+import (
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplyScratch(t *testing.T) {
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{VolumeMounts: []corev1.VolumeMount{{Name: "tmp", MountPath: "/tmp"}}}},
+		Volumes:    []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+	}}
+
+	labels, err := container.SetScratch(nil, &container.ScratchVolume{Size: "256Mi", Path: "/tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Applying twice, as updates do, keeps a single volume
+	applyScratch(template, labels)
+	applyScratch(template, labels)
+
+	mounts := template.Spec.Containers[0].VolumeMounts
+	if len(mounts) != 1 || mounts[0].Name != "scratch" || mounts[0].MountPath != "/tmp" {
+		t.Errorf("mounts = %+v", mounts)
+	}
+	volumes := template.Spec.Volumes
+	if len(volumes) != 2 || volumes[1].EmptyDir == nil || volumes[1].EmptyDir.SizeLimit.Value() != 256<<20 {
+		t.Errorf("volumes = %+v", volumes)
+	}
+
+	applyScratch(template, nil)
+	if len(template.Spec.Volumes) != 1 || len(template.Spec.Containers[0].VolumeMounts) != 0 {
+		t.Errorf("scratch volume kept after removal: %+v", template.Spec)
+	}
+}
@@ -0,0 +1,36 @@
+package backends
+
+import (
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplyStartupProbe(t *testing.T) {
+	liveness := &corev1.Probe{
+		ProbeHandler:   corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/health"}},
+		TimeoutSeconds: 5,
+	}
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{LivenessProbe: liveness}}}}
+
+	labels, err := container.SetStartupProbe(nil, &container.StartupProbe{Period: "15s", FailureThreshold: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyStartupProbe(template, labels)
+	probe := template.Spec.Containers[0].StartupProbe
+	if probe == nil {
+		t.Fatal("expected a startup probe")
+	}
+	if probe.HTTPGet.Path != "/health" || probe.PeriodSeconds != 15 || probe.FailureThreshold != 40 || probe.TimeoutSeconds != 5 {
+		t.Errorf("unexpected startup probe %+v", probe)
+	}
+
+	// Updates without a startup probe remove it
+	applyStartupProbe(template, map[string]string{})
+	if template.Spec.Containers[0].StartupProbe != nil {
+		t.Error("startup probe kept after it was removed from the spec")
+	}
+}
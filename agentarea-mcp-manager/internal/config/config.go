@@ -3,10 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/agentarea/mcp-manager/internal/naming"
 )
 
 // Config holds all configuration for the MCP Manager
@@ -20,6 +21,9 @@ type Config struct {
 	// Traefik configuration
 	Traefik TraefikConfig `json:"traefik"`
 
+	// Internal reverse proxy configuration
+	Proxy ProxyConfig `json:"proxy"`
+
 	// Logging configuration
 	Logging LoggingConfig `json:"logging"`
 
@@ -32,11 +36,174 @@ type Config struct {
 	// Kubernetes configuration
 	Kubernetes KubernetesConfig `json:"kubernetes"`
 
+	// OAuth token broker configuration
+	OAuth OAuthConfig `json:"oauth"`
+
 	// Environment override (for forcing backend selection)
 	Environment string `json:"environment"`
 
-	// Path to MCP providers YAML file
-	MCPProvidersPath string `json:"mcp_providers_path"`
+	// MCP providers YAML files and directories of them
+	MCPProvidersPaths []string `json:"mcp_providers_paths"`
+
+	// Public MCP server registry used for template discovery
+	Registry RegistryConfig `json:"registry"`
+
+	// Automatic rollouts to instances that track their template
+	TemplateUpgrade TemplateUpgradeConfig `json:"template_upgrade"`
+
+	// Health SLO alerting
+	SLO SLOConfig `json:"slo"`
+
+	// Metadata store backing persistence, audit and history
+	Store StoreConfig `json:"store"`
+
+	// Detection of changed secrets referenced by running instances
+	SecretWatch SecretWatchConfig `json:"secret_watch"`
+
+	// Simulated runtime used with BACKEND_ENVIRONMENT=fake
+	Fake FakeConfig `json:"fake"`
+
+	// Signatures on published events
+	EventSigning EventSigningConfig `json:"event_signing"`
+
+	// Corporate proxy and CA bundle for outbound traffic
+	Outbound OutboundConfig `json:"outbound"`
+
+	// DNS filtering of instances with an egress allow-list
+	Egress EgressConfig `json:"egress"`
+
+	// Coordinated restarts that hand state and listeners to a new manager
+	Handoff HandoffConfig `json:"handoff"`
+
+	// Slack, email and webhook notifications of instance failures
+	Notifications NotificationsConfig `json:"notifications"`
+
+	// Aggregated instance view across the managers of other regions or clusters
+	Federation FederationConfig `json:"federation"`
+
+	// HTTP client of instance health checks, route probes and warm-ups
+	HealthCheck HealthCheckConfig `json:"health_check"`
+}
+
+// HealthCheckConfig configures the pooled client that probes instances:
+// its timeouts and connection pool, TLS to instances serving HTTPS, and
+// headers sent with every probe
+type HealthCheckConfig struct {
+	Timeout             time.Duration `json:"timeout"` // Per probe; 0 uses RUNTIME_HTTP_TIMEOUT
+	DialTimeout         time.Duration `json:"dial_timeout"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout"`
+
+	Scheme             string `json:"scheme"`      // http or https
+	CAFile             string `json:"ca_file"`     // PEM roots verifying instance certificates
+	ServerName         string `json:"server_name"` // SNI and verified name; empty uses the instance address
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	Headers       []string `json:"-"`               // "Name: value" pairs
+	SendAuthToken bool     `json:"send_auth_token"` // Send the instance's MCP_AUTH_TOKEN as a bearer token
+}
+
+// FederationConfig lists the peer managers aggregated by /federation/instances.
+// Name labels this manager's own instances.
+type FederationConfig struct {
+	Name    string        `json:"name"`
+	Peers   []string      `json:"peers"` // name=url pairs
+	Token   string        `json:"-"`     // Bearer token sent to peers
+	Timeout time.Duration `json:"timeout"`
+}
+
+// NotificationsConfig tunes the delivery of failure notifications; channels
+// and routing rules are configured through the API
+type NotificationsConfig struct {
+	Timeout  time.Duration `json:"timeout"`  // Per delivery
+	Cooldown time.Duration `json:"cooldown"` // Between notifications of the same event for an instance
+}
+
+// HandoffConfig configures zero-downtime restarts in Docker mode. A manager
+// with a token serves the handoff endpoints and opens its listeners with
+// SO_REUSEPORT; one started with From takes over from the manager there.
+// A handoff that is neither completed nor aborted within Deadline is aborted.
+type HandoffConfig struct {
+	Token         string        `json:"-"`
+	From          string        `json:"from"`
+	SettleTimeout time.Duration `json:"settle_timeout"`
+	Deadline      time.Duration `json:"deadline"`
+}
+
+// OutboundConfig routes the manager's and the instances' outbound HTTP(S)
+// traffic through a corporate proxy and trusts an extra CA bundle
+type OutboundConfig struct {
+	HTTPProxy  string `json:"http_proxy"`
+	HTTPSProxy string `json:"https_proxy"`
+	NoProxy    string `json:"no_proxy"`  // Comma separated hosts, domains and CIDRs
+	CABundle   string `json:"ca_bundle"` // PEM file added to the system roots
+	CADir      string `json:"ca_dir"`    // Keeps CA certificates registered at runtime
+	Inject     bool   `json:"inject"`    // Pass the settings to managed instances
+}
+
+// EgressConfig runs a DNS resolver that answers instances with an egress
+// allow-list only for the allowed domains
+type EgressConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Listen   string `json:"listen"`   // UDP address the resolver listens on
+	Address  string `json:"address"`  // Resolver IP given to instances as their nameserver
+	Upstream string `json:"upstream"` // Resolver answering allowed queries; empty uses /etc/resolv.conf
+	LogSize  int    `json:"log_size"` // Queries kept per instance
+}
+
+// EventSigningConfig signs published events so consumers sharing the Redis
+// can reject spoofed ones
+type EventSigningConfig struct {
+	Algorithm string `json:"algorithm"` // hmac-sha256 or ed25519; empty disables signing
+	KeyID     string `json:"key_id"`
+	Key       string `json:"-"` // Shared secret, or base64 ed25519 seed or private key
+}
+
+// FakeConfig controls the simulated instance lifecycle of the fake backend
+type FakeConfig struct {
+	StartupDelay  time.Duration `json:"startup_delay"`  // Time instances spend starting
+	FailureRate   float64       `json:"failure_rate"`   // Fraction of starts that fail, 0-1
+	UnhealthyRate float64       `json:"unhealthy_rate"` // Fraction of health checks that fail, 0-1
+}
+
+// SecretWatchConfig controls polling for changed instance secrets
+type SecretWatchConfig struct {
+	Interval time.Duration `json:"interval"` // 0 disables the watch
+	Restart  bool          `json:"restart"`  // Recreate instances with the new values by default
+}
+
+// StoreConfig selects the metadata store
+type StoreConfig struct {
+	Driver string `json:"driver"` // memory, sqlite or postgres
+	DSN    string `json:"-"`      // SQLite file path or Postgres URL
+
+	// Comma-separated Fernet keys for sensitive spec values, primary first.
+	// Falls back to SECRET_MANAGER_ENCRYPTION_KEY.
+	EncryptionKeys string `json:"-"`
+}
+
+// SLOConfig controls when instance health SLO alerts fire and where they go
+type SLOConfig struct {
+	// Alert when the error budget is consumed this many times faster than
+	// the SLO window allows, measured over the last hour
+	BurnRateAlert float64 `json:"burn_rate_alert"`
+
+	// Optional webhook that receives alerts in addition to Redis events
+	WebhookURL     string        `json:"webhook_url"`
+	WebhookTimeout time.Duration `json:"webhook_timeout"`
+}
+
+// TemplateUpgradeConfig controls automatic rolling upgrades after template updates
+type TemplateUpgradeConfig struct {
+	MaxUnavailable int           `json:"max_unavailable"`
+	HealthTimeout  time.Duration `json:"health_timeout"`
+}
+
+// RegistryConfig holds the MCP server registry client configuration
+type RegistryConfig struct {
+	Enabled bool          `json:"enabled"`
+	URL     string        `json:"url"`
+	Timeout time.Duration `json:"timeout"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -48,11 +215,46 @@ type ServerConfig struct {
 	// CORS configuration
 	CORSEnabled        bool     `json:"cors_enabled"`
 	CORSAllowedOrigins []string `json:"cors_allowed_origins"`
+
+	// Read-only mode rejects mutating requests unless they carry the
+	// break-glass token; it can also be toggled at runtime
+	ReadOnly        bool   `json:"read_only"`
+	BreakGlassToken string `json:"-"`
+
+	// Answer the deprecated /containers endpoints with 410 Gone
+	LegacyAPIDisabled bool `json:"legacy_api_disabled"`
+
+	// Allow running commands inside instances through the API
+	ExecEnabled bool `json:"exec_enabled"`
+
+	// Serve Prometheus metrics on /metrics
+	MetricsEnabled bool `json:"metrics_enabled"`
+
+	// API authentication; enforced once API keys or a JWKS URL are set
+	Auth AuthConfig `json:"auth"`
+}
+
+// AuthConfig holds the credentials accepted by the API
+type AuthConfig struct {
+	APIKeys     []string      `json:"-"`
+	JWTIssuer   string        `json:"jwt_issuer"`   // Required iss claim; empty accepts any
+	JWTAudience string        `json:"jwt_audience"` // Required aud claim; empty accepts any
+	JWKSURL     string        `json:"jwks_url"`     // Keys that sign JWT bearer tokens
+	JWKSRefresh time.Duration `json:"jwks_refresh"`
+	ExemptPaths []string      `json:"exempt_paths"` // Routes served without credentials
+	ClockSkew   time.Duration `json:"clock_skew"`   // Leeway for exp and nbf
+}
+
+// Enabled reports whether requests must carry credentials
+func (a AuthConfig) Enabled() bool {
+	return len(a.APIKeys) > 0 || a.JWKSURL != ""
 }
 
 // ContainerConfig holds container runtime configuration
 type ContainerConfig struct {
 	Runtime          string `json:"runtime"`
+	RuntimeAPI       string `json:"runtime_api"`    // auto, api or exec
+	RuntimeSocket    string `json:"runtime_socket"` // API socket; empty for the runtime's default
 	StorageDriver    string `json:"storage_driver"`
 	StorageRunroot   string `json:"storage_runroot"`
 	StorageGraphroot string `json:"storage_graphroot"`
@@ -64,30 +266,196 @@ type ContainerConfig struct {
 	StartupTimeout  time.Duration `json:"startup_timeout"`
 	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
 
+	// Deadlines of runtime calls on top of the API request or background loop
+	// that made them, so a hung runtime cannot stall an operation forever
+	// (0 = no deadline)
+	CommandTimeout    time.Duration `json:"command_timeout"`    // inspect, ps, stop, rm, start, exec
+	CreateTimeout     time.Duration `json:"create_timeout"`     // run, which may pull the image
+	PullTimeout       time.Duration `json:"pull_timeout"`       // Image pulls
+	CheckpointTimeout time.Duration `json:"checkpoint_timeout"` // Checkpoint and restore
+	HTTPTimeout       time.Duration `json:"http_timeout"`       // Health probes and Core API requests
+
+	// Quotas
+	MaxPerWorkspace int           `json:"max_per_workspace"` // Running containers per workspace (0 = unlimited)
+	IdleAfter       time.Duration `json:"idle_after"`        // Inactivity before an instance is suggested for shutdown
+
 	// Resource limits
 	DefaultMemoryLimit string `json:"default_memory_limit"`
 	DefaultCPULimit    string `json:"default_cpu_limit"`
+
+	// Resource pressure warnings: usage at or above the threshold (fraction of
+	// the limit) for this many consecutive health checks
+	ResourcePressureThreshold float64 `json:"resource_pressure_threshold"`
+	ResourcePressureSamples   int     `json:"resource_pressure_samples"`
+
+	// Directory for exported checkpoint archives
+	CheckpointDir string `json:"checkpoint_dir"`
+
+	// Host admission: container limits may add up to host memory and CPUs
+	// times Oversubscription (0 disables admission). Creations without room
+	// fail, or wait up to AdmissionWait for capacity to be released.
+	// AdmissionQueueAtLimit queues creations beyond MaxContainers the same way
+	// instead of failing them outright.
+	Oversubscription      float64       `json:"oversubscription"`
+	MinFreeDisk           string        `json:"min_free_disk"`
+	AdmissionWait         time.Duration `json:"admission_wait"`
+	AdmissionQueueAtLimit bool          `json:"admission_queue_at_limit"`
+
+	// Disk usage: per-container writable layer quota (--storage-opt size=,
+	// empty for none), sampling interval (0 disables) and alert thresholds for
+	// the storage graphroot filesystem
+	DefaultDiskQuota      string        `json:"default_disk_quota"`
+	DiskUsageInterval     time.Duration `json:"disk_usage_interval"`
+	DiskWarnThreshold     float64       `json:"disk_warn_threshold"`
+	DiskCriticalThreshold float64       `json:"disk_critical_threshold"`
+
+	// Bandwidth limits for instances without their own, in bits per second
+	// with a k, M or G suffix (empty = unlimited)
+	DefaultIngressBandwidth string `json:"default_ingress_bandwidth"`
+	DefaultEgressBandwidth  string `json:"default_egress_bandwidth"`
+
+	// Host devices and sockets instances may be given (path.Match patterns);
+	// each also needs an admin grant for the instance's workspace
+	AllowedDevices     []string `json:"allowed_devices"`
+	AllowedHostSockets []string `json:"allowed_host_sockets"`
+
+	// Host ports instances may be published on (first-last, empty disables
+	// publishing) and the host address they are bound to
+	HostPortRange   string `json:"host_port_range"`
+	HostPortAddress string `json:"host_port_address"`
+
+	// Extra unprefixed labels carrying the cost attribution, as
+	// attribute=label pairs (e.g. cost-center=department)
+	CostLabelAliases []string `json:"cost_label_aliases"`
+
+	// Anomalies that quarantine an instance automatically when seen on this
+	// many consecutive health checks: egress above a rate (bytes per second,
+	// e.g. 20m; empty disables) or more processes than a limit (0 disables)
+	AnomalyEgressRate string `json:"anomaly_egress_rate"`
+	AnomalyMaxPIDs    int    `json:"anomaly_max_pids"`
+	AnomalySamples    int    `json:"anomaly_samples"`
+
+	// An instance whose health check fails this many times within the window
+	// is reported as crash looping (0 disables)
+	CrashLoopFailures int           `json:"crash_loop_failures"`
+	CrashLoopWindow   time.Duration `json:"crash_loop_window"`
+
+	// An instance failing this many consecutive readiness checks is taken
+	// out of routing until it passes one; a running container whose server
+	// stops answering this many consecutive liveness checks is restarted once
+	// it has run for the initial delay (0 disables either)
+	ReadinessFailures    int           `json:"readiness_failures"`
+	LivenessFailures     int           `json:"liveness_failures"`
+	LivenessInitialDelay time.Duration `json:"liveness_initial_delay"`
+
+	// Adopt containers created through the legacy /containers API into the
+	// instance model at startup, optionally under a workspace
+	AdoptLegacyOnStartup bool   `json:"adopt_legacy_on_startup"`
+	AdoptLegacyWorkspace string `json:"adopt_legacy_workspace,omitempty"`
 }
 
 // TraefikConfig holds Traefik configuration
 type TraefikConfig struct {
 	Network           string `json:"network"`
+	NetworkSubnet     string `json:"network_subnet"`  // Comma separated, IPv4 and/or IPv6; used when the manager creates the network
+	NetworkGateway    string `json:"network_gateway"` // Comma separated, one per subnet
+	IPFamily          string `json:"ip_family"`       // Address of dual-stack containers to route to: ipv4 or ipv6
 	ProxyPort         int    `json:"proxy_port"`
 	DefaultDomain     string `json:"default_domain"`
 	ProxyHost         string `json:"proxy_host"`
 	ManagerServiceURL string `json:"manager_service_url"`
 	ConfigPath        string `json:"config_path"`
+
+	// How often routes of vanished containers are removed and routes of
+	// moved containers repaired; 0 disables
+	RouteSweepInterval time.Duration `json:"route_sweep_interval"`
+
+	// How long the route of a new container waits for the server to answer
+	// before it is published anyway; 0 publishes routes at once
+	RouteReadyTimeout time.Duration `json:"route_ready_timeout"`
+
+	// How long the warm-up requests of a new container may take before its
+	// route is published without them completing
+	WarmupTimeout time.Duration `json:"warmup_timeout"`
+}
+
+// ProxyConfig holds connection pool settings for the internal reverse proxy
+type ProxyConfig struct {
+	MaxIdleConns        int           `json:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int           `json:"max_conns_per_host"`
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout"`
+	DialTimeout         time.Duration `json:"dial_timeout"`
+	KeepAlive           time.Duration `json:"keep_alive"`
+
+	// Passive health ejection of failing route endpoints
+	EjectFailures int           `json:"eject_failures"`
+	EjectDuration time.Duration `json:"eject_duration"`
+
+	// Default proxied request quotas per workspace and per API key (0 = unlimited)
+	WorkspaceDailyQuota   int64 `json:"workspace_daily_quota"`
+	WorkspaceMonthlyQuota int64 `json:"workspace_monthly_quota"`
+	ClientDailyQuota      int64 `json:"client_daily_quota"`
+	ClientMonthlyQuota    int64 `json:"client_monthly_quota"`
+
+	// Encodings for proxied responses the upstream sent uncompressed, in
+	// order of preference (empty disables), and the smallest size compressed
+	Compression        []string `json:"compression,omitempty"`
+	CompressionMinSize int      `json:"compression_min_size"`
+
+	// Proxied requests slower or with larger bodies than these are logged as
+	// warnings and counted per route; 0 disables
+	SlowRequestThreshold time.Duration `json:"slow_request_threshold"`
+	LargePayloadBytes    int64         `json:"large_payload_bytes"`
+
+	// Rebinding after the listener fails: the longest delay between
+	// attempts, and a port used after FallbackAfter failed binds (0 disables)
+	RebindMaxBackoff time.Duration `json:"rebind_max_backoff"`
+	FallbackPort     int           `json:"fallback_port"`
+	FallbackAfter    int           `json:"fallback_after"`
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level  string `json:"level"`
 	Format string `json:"format"`
+
+	// Per-component overrides of Level, e.g. "events=debug,proxy=warn"
+	ComponentLevels string `json:"component_levels"`
+
+	// Sampling of repetitive Info and Debug messages; SampleInitial 0 disables it
+	SampleInitial    int `json:"sample_initial"`
+	SampleThereafter int `json:"sample_thereafter"`
+
+	// EventPayloads logs raw event payloads at Debug
+	EventPayloads bool `json:"event_payloads"`
 }
 
 // RedisConfig holds Redis configuration for event handling
 type RedisConfig struct {
 	URL string `json:"url"`
+
+	// REDIS_URL gives the address, database and credentials (rediss:// enables
+	// TLS); the settings below add to or override it
+	Mode             string   `json:"mode"`            // standalone, sentinel or cluster
+	Addrs            []string `json:"addrs,omitempty"` // Sentinel or cluster seed addresses
+	Username         string   `json:"username,omitempty"`
+	Password         string   `json:"-"`
+	SentinelMaster   string   `json:"sentinel_master,omitempty"`
+	SentinelUsername string   `json:"sentinel_username,omitempty"`
+	SentinelPassword string   `json:"-"`
+
+	// TLS: client certificates, a private CA and the expected server name
+	TLS                   bool   `json:"tls"`
+	TLSCAFile             string `json:"tls_ca_file,omitempty"`
+	TLSCertFile           string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile            string `json:"tls_key_file,omitempty"`
+	TLSServerName         string `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+
+	// Worker pool for event-driven instance creation
+	CreateWorkers   int `json:"create_workers"`
+	CreateQueueSize int `json:"create_queue_size"`
 }
 
 // Load loads configuration from environment variables with sensible defaults
@@ -101,39 +469,237 @@ func Load() *Config {
 			// CORS disabled by default for security
 			CORSEnabled:        getEnvBool("CORS_ENABLED", false),
 			CORSAllowedOrigins: getEnvStringSlice("CORS_ALLOWED_ORIGINS", []string{}),
+
+			ReadOnly:        getEnvBool("READ_ONLY_MODE", false),
+			BreakGlassToken: getEnv("BREAK_GLASS_TOKEN", ""),
+
+			LegacyAPIDisabled: getEnvBool("DISABLE_LEGACY_API", false),
+			ExecEnabled:       getEnvBool("ALLOW_INSTANCE_EXEC", false),
+			MetricsEnabled:    getEnvBool("METRICS_ENABLED", true),
+
+			Auth: AuthConfig{
+				APIKeys:     getEnvStringSlice("API_KEYS", nil),
+				JWTIssuer:   getEnv("JWT_ISSUER", ""),
+				JWTAudience: getEnv("JWT_AUDIENCE", ""),
+				JWKSURL:     getEnv("JWT_JWKS_URL", ""),
+				JWKSRefresh: getEnvDuration("JWT_JWKS_REFRESH", time.Hour),
+				ExemptPaths: getEnvStringSlice("API_AUTH_EXEMPT_PATHS", []string{"/health", "/ready"}),
+				ClockSkew:   getEnvDuration("JWT_CLOCK_SKEW", time.Minute),
+			},
 		},
 		Container: ContainerConfig{
 			Runtime:            getEnv("CONTAINER_RUNTIME", "podman"),
+			RuntimeAPI:         getEnv("CONTAINER_RUNTIME_API", "auto"),
+			RuntimeSocket:      getEnv("CONTAINER_RUNTIME_SOCKET", ""),
 			StorageDriver:      getEnv("CONTAINERS_STORAGE_DRIVER", "overlay"),
 			StorageRunroot:     getEnv("CONTAINERS_STORAGE_RUNROOT", "/tmp/containers"),
 			StorageGraphroot:   getEnv("CONTAINERS_STORAGE_GRAPHROOT", "/var/lib/containers/storage"),
 			NamePrefix:         getEnv("CONTAINER_NAME_PREFIX", "mcp-"),
 			ManagedByLabel:     getEnv("CONTAINER_MANAGED_BY_LABEL", "mcp-manager"),
 			MaxContainers:      getEnvInt("MAX_CONTAINERS", 50),
+			MaxPerWorkspace:    getEnvInt("MAX_CONTAINERS_PER_WORKSPACE", 0),
+			IdleAfter:          getEnvDuration("INSTANCE_IDLE_AFTER", 30*time.Minute),
 			StartupTimeout:     getEnvDuration("STARTUP_TIMEOUT", 120*time.Second),
 			ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+			CommandTimeout:     getEnvDuration("RUNTIME_COMMAND_TIMEOUT", 30*time.Second),
+			CreateTimeout:      getEnvDuration("RUNTIME_CREATE_TIMEOUT", 5*time.Minute),
+			PullTimeout:        getEnvDuration("RUNTIME_PULL_TIMEOUT", 10*time.Minute),
+			CheckpointTimeout:  getEnvDuration("RUNTIME_CHECKPOINT_TIMEOUT", 5*time.Minute),
+			HTTPTimeout:        getEnvDuration("RUNTIME_HTTP_TIMEOUT", 10*time.Second),
 			DefaultMemoryLimit: getEnv("DEFAULT_MEMORY_LIMIT", "512m"),
 			DefaultCPULimit:    getEnv("DEFAULT_CPU_LIMIT", "1.0"),
+
+			ResourcePressureThreshold: getEnvFloat("RESOURCE_PRESSURE_THRESHOLD", 0.9),
+			ResourcePressureSamples:   getEnvInt("RESOURCE_PRESSURE_SAMPLES", 5),
+
+			CheckpointDir: getEnv("CHECKPOINT_DIR", "/var/lib/mcp-manager/checkpoints"),
+
+			Oversubscription:      getEnvFloat("HOST_OVERSUBSCRIPTION", 1.5),
+			MinFreeDisk:           getEnv("HOST_MIN_FREE_DISK", "1g"),
+			AdmissionWait:         getEnvDuration("ADMISSION_WAIT", 0),
+			AdmissionQueueAtLimit: getEnvBool("ADMISSION_QUEUE_AT_LIMIT", false),
+
+			DefaultDiskQuota:      getEnv("DEFAULT_DISK_QUOTA", ""),
+			DiskUsageInterval:     getEnvDuration("DISK_USAGE_INTERVAL", 5*time.Minute),
+			DiskWarnThreshold:     getEnvFloat("DISK_WARN_THRESHOLD", 0.85),
+			DiskCriticalThreshold: getEnvFloat("DISK_CRITICAL_THRESHOLD", 0.95),
+
+			DefaultIngressBandwidth: getEnv("DEFAULT_INGRESS_BANDWIDTH", ""),
+			DefaultEgressBandwidth:  getEnv("DEFAULT_EGRESS_BANDWIDTH", ""),
+
+			AllowedDevices:     getEnvStringSlice("HOST_DEVICE_ALLOWLIST", nil),
+			CostLabelAliases:   getEnvStringSlice("COST_LABEL_ALIASES", nil),
+			AllowedHostSockets: getEnvStringSlice("HOST_SOCKET_ALLOWLIST", nil),
+
+			HostPortRange:   getEnv("HOST_PORT_RANGE", ""),
+			HostPortAddress: getEnv("HOST_PORT_ADDRESS", "127.0.0.1"),
+
+			AnomalyEgressRate: getEnv("ANOMALY_EGRESS_RATE", ""),
+			AnomalyMaxPIDs:    getEnvInt("ANOMALY_MAX_PIDS", 0),
+			AnomalySamples:    getEnvInt("ANOMALY_SAMPLES", 2),
+
+			CrashLoopFailures: getEnvInt("CRASH_LOOP_FAILURES", 3),
+			CrashLoopWindow:   getEnvDuration("CRASH_LOOP_WINDOW", 10*time.Minute),
+
+			ReadinessFailures:    getEnvInt("READINESS_FAILURES", 2),
+			LivenessFailures:     getEnvInt("LIVENESS_FAILURES", 3),
+			LivenessInitialDelay: getEnvDuration("LIVENESS_INITIAL_DELAY", 60*time.Second),
+
+			AdoptLegacyOnStartup: getEnvBool("ADOPT_LEGACY_CONTAINERS", false),
+			AdoptLegacyWorkspace: getEnv("ADOPT_LEGACY_WORKSPACE_ID", ""),
 		},
 		Traefik: TraefikConfig{
 			Network:           getEnv("TRAEFIK_NETWORK", "podman"),
+			NetworkSubnet:     getEnv("TRAEFIK_NETWORK_SUBNET", ""),
+			NetworkGateway:    getEnv("TRAEFIK_NETWORK_GATEWAY", ""),
+			IPFamily:          strings.ToLower(getEnv("NETWORK_IP_FAMILY", "ipv4")),
 			ProxyPort:         getEnvInt("TRAEFIK_PROXY_PORT", 81),
 			DefaultDomain:     getEnv("DEFAULT_DOMAIN", "localhost"),
 			ProxyHost:         getEnv("MCP_PROXY_HOST", "http://localhost:7999"),
 			ManagerServiceURL: getEnv("MANAGER_SERVICE_URL", "http://localhost:8000"),
 			ConfigPath:        getEnv("TRAEFIK_CONFIG_PATH", "/etc/traefik/dynamic.yml"),
+
+			RouteSweepInterval: getEnvDuration("ROUTE_SWEEP_INTERVAL", 5*time.Minute),
+			RouteReadyTimeout:  getEnvDuration("ROUTE_READY_TIMEOUT", 60*time.Second),
+			WarmupTimeout:      getEnvDuration("WARMUP_TIMEOUT", 2*time.Minute),
+		},
+		Proxy: ProxyConfig{
+			MaxIdleConns:        getEnvInt("PROXY_MAX_IDLE_CONNS", 100),
+			MaxIdleConnsPerHost: getEnvInt("PROXY_MAX_IDLE_CONNS_PER_HOST", 32),
+			MaxConnsPerHost:     getEnvInt("PROXY_MAX_CONNS_PER_HOST", 0),
+			IdleConnTimeout:     getEnvDuration("PROXY_IDLE_CONN_TIMEOUT", 90*time.Second),
+			DialTimeout:         getEnvDuration("PROXY_DIAL_TIMEOUT", 10*time.Second),
+			KeepAlive:           getEnvDuration("PROXY_KEEP_ALIVE", 30*time.Second),
+			EjectFailures:       getEnvInt("PROXY_EJECT_FAILURES", 3),
+			EjectDuration:       getEnvDuration("PROXY_EJECT_DURATION", 30*time.Second),
+
+			WorkspaceDailyQuota:   int64(getEnvInt("PROXY_WORKSPACE_DAILY_QUOTA", 0)),
+			WorkspaceMonthlyQuota: int64(getEnvInt("PROXY_WORKSPACE_MONTHLY_QUOTA", 0)),
+			ClientDailyQuota:      int64(getEnvInt("PROXY_CLIENT_DAILY_QUOTA", 0)),
+			ClientMonthlyQuota:    int64(getEnvInt("PROXY_CLIENT_MONTHLY_QUOTA", 0)),
+
+			Compression:        getEnvStringSlice("PROXY_COMPRESSION", nil),
+			CompressionMinSize: getEnvInt("PROXY_COMPRESSION_MIN_SIZE", 1024),
+
+			SlowRequestThreshold: getEnvDuration("PROXY_SLOW_REQUEST_THRESHOLD", 30*time.Second),
+			LargePayloadBytes:    int64(getEnvInt("PROXY_LARGE_PAYLOAD_BYTES", 1<<20)),
+
+			RebindMaxBackoff: getEnvDuration("PROXY_REBIND_MAX_BACKOFF", 30*time.Second),
+			FallbackPort:     getEnvInt("PROXY_FALLBACK_PORT", 0),
+			FallbackAfter:    getEnvInt("PROXY_FALLBACK_AFTER", 3),
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "INFO"),
 			Format: getEnv("LOG_FORMAT", "json"),
+
+			ComponentLevels:  getEnv("LOG_LEVELS", ""),
+			SampleInitial:    getEnvInt("LOG_SAMPLE_INITIAL", 0),
+			SampleThereafter: getEnvInt("LOG_SAMPLE_THEREAFTER", 100),
+			EventPayloads:    getEnvBool("LOG_EVENT_PAYLOADS", false),
 		},
 		Redis: RedisConfig{
-			URL: getEnv("REDIS_URL", "redis://localhost:6379"),
+			URL:      getEnv("REDIS_URL", "redis://localhost:6379"),
+			Mode:     getEnv("REDIS_MODE", "standalone"),
+			Addrs:    getEnvStringSlice("REDIS_ADDRS", nil),
+			Username: getEnv("REDIS_USERNAME", ""),
+			Password: getEnv("REDIS_PASSWORD", ""),
+
+			SentinelMaster:   getEnv("REDIS_SENTINEL_MASTER", ""),
+			SentinelUsername: getEnv("REDIS_SENTINEL_USERNAME", ""),
+			SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+
+			TLS:                   getEnvBool("REDIS_TLS", false),
+			TLSCAFile:             getEnv("REDIS_TLS_CA_FILE", ""),
+			TLSCertFile:           getEnv("REDIS_TLS_CERT_FILE", ""),
+			TLSKeyFile:            getEnv("REDIS_TLS_KEY_FILE", ""),
+			TLSServerName:         getEnv("REDIS_TLS_SERVER_NAME", ""),
+			TLSInsecureSkipVerify: getEnvBool("REDIS_TLS_INSECURE_SKIP_VERIFY", false),
+
+			CreateWorkers:   getEnvInt("EVENT_CREATE_WORKERS", 4),
+			CreateQueueSize: getEnvInt("EVENT_CREATE_QUEUE_SIZE", 100),
+		},
+		CoreAPIURL:        getEnv("CORE_API_URL", "http://localhost:8000"),
+		Kubernetes:        loadKubernetesConfig(),
+		OAuth:             loadOAuthConfig(),
+		Environment:       getEnv("BACKEND_ENVIRONMENT", ""),
+		MCPProvidersPaths: getEnvStringSlice("MCP_PROVIDERS_YAML", []string{"/app/data/mcp_providers.yaml"}),
+		Registry: RegistryConfig{
+			Enabled: getEnvBool("MCP_REGISTRY_ENABLED", true),
+			URL:     getEnv("MCP_REGISTRY_URL", "https://registry.modelcontextprotocol.io"),
+			Timeout: getEnvDuration("MCP_REGISTRY_TIMEOUT", 10*time.Second),
+		},
+		TemplateUpgrade: TemplateUpgradeConfig{
+			MaxUnavailable: getEnvInt("TEMPLATE_UPGRADE_MAX_UNAVAILABLE", 1),
+			HealthTimeout:  getEnvDuration("TEMPLATE_UPGRADE_HEALTH_TIMEOUT", 60*time.Second),
+		},
+		SLO: SLOConfig{
+			BurnRateAlert:  getEnvFloat("SLO_BURN_RATE_ALERT", 6),
+			WebhookURL:     getEnv("SLO_ALERT_WEBHOOK_URL", ""),
+			WebhookTimeout: getEnvDuration("SLO_ALERT_WEBHOOK_TIMEOUT", 5*time.Second),
+		},
+		Store: StoreConfig{
+			Driver: getEnv("STORE_DRIVER", "memory"),
+			DSN:    getEnv("STORE_DSN", ""),
+
+			EncryptionKeys: getEnv("STORE_ENCRYPTION_KEYS", os.Getenv("SECRET_MANAGER_ENCRYPTION_KEY")),
+		},
+		SecretWatch: SecretWatchConfig{
+			Interval: getEnvDuration("SECRET_WATCH_INTERVAL", 0),
+			Restart:  getEnvBool("SECRET_WATCH_RESTART", false),
+		},
+		Outbound: OutboundConfig{
+			HTTPProxy:  getEnv("OUTBOUND_HTTP_PROXY", ""),
+			HTTPSProxy: getEnv("OUTBOUND_HTTPS_PROXY", ""),
+			NoProxy:    getEnv("OUTBOUND_NO_PROXY", ""),
+			CABundle:   getEnv("OUTBOUND_CA_BUNDLE", ""),
+			CADir:      getEnv("OUTBOUND_CA_DIR", ""),
+			Inject:     getEnvBool("OUTBOUND_INJECT", true),
+		},
+		Egress: EgressConfig{
+			Enabled:  getEnvBool("EGRESS_DNS_FILTER", false),
+			Listen:   getEnv("EGRESS_DNS_LISTEN", ":53"),
+			Address:  getEnv("EGRESS_DNS_ADDRESS", ""),
+			Upstream: getEnv("EGRESS_DNS_UPSTREAM", ""),
+			LogSize:  getEnvInt("EGRESS_LOG_SIZE", 200),
+		},
+		Handoff: HandoffConfig{
+			Token:         getEnv("HANDOFF_TOKEN", ""),
+			From:          getEnv("HANDOFF_FROM", ""),
+			SettleTimeout: getEnvDuration("HANDOFF_SETTLE_TIMEOUT", 30*time.Second),
+			Deadline:      getEnvDuration("HANDOFF_DEADLINE", 5*time.Minute),
+		},
+		Federation: FederationConfig{
+			Name:    getEnv("FEDERATION_NAME", "local"),
+			Peers:   getEnvStringSlice("FEDERATION_PEERS", nil),
+			Token:   getEnv("FEDERATION_TOKEN", ""),
+			Timeout: getEnvDuration("FEDERATION_TIMEOUT", 5*time.Second),
+		},
+		HealthCheck: HealthCheckConfig{
+			Timeout:             getEnvDuration("HEALTH_CHECK_TIMEOUT", 0),
+			DialTimeout:         getEnvDuration("HEALTH_CHECK_DIAL_TIMEOUT", 5*time.Second),
+			MaxIdleConnsPerHost: getEnvInt("HEALTH_CHECK_MAX_IDLE_CONNS_PER_HOST", 2),
+			IdleConnTimeout:     getEnvDuration("HEALTH_CHECK_IDLE_CONN_TIMEOUT", 90*time.Second),
+			Scheme:              getEnv("HEALTH_CHECK_SCHEME", "http"),
+			CAFile:              getEnv("HEALTH_CHECK_CA_FILE", ""),
+			ServerName:          getEnv("HEALTH_CHECK_SERVER_NAME", ""),
+			InsecureSkipVerify:  getEnvBool("HEALTH_CHECK_INSECURE_SKIP_VERIFY", false),
+			Headers:             getEnvStringSlice("HEALTH_CHECK_HEADERS", nil),
+			SendAuthToken:       getEnvBool("HEALTH_CHECK_SEND_AUTH_TOKEN", false),
+		},
+		Notifications: NotificationsConfig{
+			Timeout:  getEnvDuration("NOTIFICATION_TIMEOUT", 10*time.Second),
+			Cooldown: getEnvDuration("NOTIFICATION_COOLDOWN", 5*time.Minute),
+		},
+		EventSigning: EventSigningConfig{
+			Algorithm: getEnv("EVENT_SIGNING_ALGORITHM", ""),
+			KeyID:     getEnv("EVENT_SIGNING_KEY_ID", "mcp-manager"),
+			Key:       getEnv("EVENT_SIGNING_KEY", ""),
+		},
+		Fake: FakeConfig{
+			StartupDelay:  getEnvDuration("FAKE_STARTUP_DELAY", 2*time.Second),
+			FailureRate:   getEnvFloat("FAKE_FAILURE_RATE", 0),
+			UnhealthyRate: getEnvFloat("FAKE_UNHEALTHY_RATE", 0),
 		},
-		CoreAPIURL:       getEnv("CORE_API_URL", "http://localhost:8000"),
-		Kubernetes:       loadKubernetesConfig(),
-		Environment:      getEnv("BACKEND_ENVIRONMENT", ""),
-		MCPProvidersPath: getEnv("MCP_PROVIDERS_YAML", "/app/data/mcp_providers.yaml"),
 	}
 }
 
@@ -154,6 +720,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -236,33 +811,34 @@ func loadKubernetesConfig() KubernetesConfig {
 			config.ReadinessTimeout = timeout
 		}
 	}
+	config.GCInterval = getEnvDuration("KUBERNETES_GC_INTERVAL", config.GCInterval)
+	config.GCGracePeriod = getEnvDuration("KUBERNETES_GC_GRACE_PERIOD", config.GCGracePeriod)
+	config.ListCache = getEnvBool("KUBERNETES_LIST_CACHE", config.ListCache)
+	config.HealthCacheTTL = getEnvDuration("KUBERNETES_HEALTH_CACHE_TTL", config.HealthCacheTTL)
+
+	// Dual-stack Services
+	config.IPFamilyPolicy = getEnv("KUBERNETES_IP_FAMILY_POLICY", config.IPFamilyPolicy)
+	config.IPFamilies = getEnvStringSlice("KUBERNETES_IP_FAMILIES", config.IPFamilies)
 
 	return config
 }
 
 // sanitizeServiceName sanitizes a service name to be valid for container names
-func sanitizeServiceName(serviceName string) string {
-	// Convert to lowercase
-	sanitized := strings.ToLower(serviceName)
-
-	// Replace any non-alphanumeric characters with hyphens
-	reg := regexp.MustCompile(`[^a-z0-9]+`)
-	sanitized = reg.ReplaceAllString(sanitized, "-")
-
-	// Remove leading/trailing hyphens
-	sanitized = strings.Trim(sanitized, "-")
+func sanitizeServiceName(serviceName string, maxLength int) string {
+	sanitized := naming.Normalize(serviceName, maxLength)
 
-	// Ensure it's not empty and starts with alphanumeric
-	if sanitized == "" || !regexp.MustCompile(`^[a-z0-9]`).MatchString(sanitized) {
-		sanitized = "container-" + sanitized
+	// Ensure it's not empty
+	if sanitized == "" {
+		sanitized = "container"
 	}
 
 	return sanitized
 }
 
-// GetContainerName generates a container name for a service
+// GetContainerName generates a container name for a service. Container names
+// are hostnames on the MCP network, so long names are shortened to a DNS label.
 func (c *Config) GetContainerName(serviceName string) string {
-	sanitizedName := sanitizeServiceName(serviceName)
+	sanitizedName := sanitizeServiceName(serviceName, naming.MaxLabelLength-len(c.Container.NamePrefix))
 	return fmt.Sprintf("%s%s", c.Container.NamePrefix, sanitizedName)
 }
 
@@ -15,6 +15,12 @@ type KubernetesConfig struct {
 	Domain       string `json:"domain"`
 	IngressClass string `json:"ingress_class"`
 
+	// IP families of instance Services: SingleStack, PreferDualStack or
+	// RequireDualStack, and the families in order (IPv4, IPv6); empty
+	// leaves the cluster defaults
+	IPFamilyPolicy string   `json:"ip_family_policy,omitempty"`
+	IPFamilies     []string `json:"ip_families,omitempty"`
+
 	// Storage
 	StorageClass string `json:"storage_class"`
 
@@ -33,6 +39,15 @@ type KubernetesConfig struct {
 	DeploymentTimeout time.Duration `json:"deployment_timeout"`
 	ReadinessTimeout  time.Duration `json:"readiness_timeout"`
 
+	// Garbage collection of resources left behind without a Deployment
+	GCInterval    time.Duration `json:"gc_interval"`     // 0 disables the periodic pass
+	GCGracePeriod time.Duration `json:"gc_grace_period"` // Younger resources may still be in creation
+
+	// Listings served from informer caches instead of API round-trips per
+	// instance; health is probed in the background once older than the TTL
+	ListCache      bool          `json:"list_cache"`
+	HealthCacheTTL time.Duration `json:"health_cache_ttl"`
+
 	// TLS/Certificate management
 	TLS TLSConfig `json:"tls"`
 }
@@ -178,6 +193,10 @@ func DefaultKubernetesConfig() KubernetesConfig {
 
 		DeploymentTimeout: 300 * time.Second,
 		ReadinessTimeout:  120 * time.Second,
+		GCInterval:        10 * time.Minute,
+		GCGracePeriod:     15 * time.Minute,
+		ListCache:         true,
+		HealthCacheTTL:    30 * time.Second,
 
 		TLS: TLSConfig{
 			Enabled:    true,
@@ -192,6 +211,19 @@ func DefaultKubernetesConfig() KubernetesConfig {
 
 // Validate validates the Kubernetes configuration
 func (k *KubernetesConfig) Validate() error {
+	switch k.IPFamilyPolicy {
+	case "", "SingleStack", "PreferDualStack", "RequireDualStack":
+	default:
+		return fmt.Errorf("invalid KUBERNETES_IP_FAMILY_POLICY %q: use SingleStack, PreferDualStack or RequireDualStack", k.IPFamilyPolicy)
+	}
+	if len(k.IPFamilies) > 2 {
+		return fmt.Errorf("KUBERNETES_IP_FAMILIES lists at most IPv4 and IPv6")
+	}
+	for _, family := range k.IPFamilies {
+		if family != "IPv4" && family != "IPv6" {
+			return fmt.Errorf("invalid KUBERNETES_IP_FAMILIES entry %q: use IPv4 or IPv6", family)
+		}
+	}
 	if k.Enabled {
 		if k.Namespace == "" {
 			return fmt.Errorf("kubernetes namespace is required when kubernetes is enabled")
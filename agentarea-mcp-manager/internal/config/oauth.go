@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthConfig holds configuration for the OAuth token broker
+type OAuthConfig struct {
+	// Providers keyed by name (e.g. "github", "google")
+	Providers map[string]OAuthProviderConfig `json:"providers"`
+
+	// Refresh access tokens this long before they expire
+	RefreshSkew time.Duration `json:"refresh_skew"`
+
+	// Give up on a token endpoint that has not answered within this time
+	RefreshTimeout time.Duration `json:"refresh_timeout"`
+}
+
+// OAuthProviderConfig holds client credentials for a single OAuth provider
+type OAuthProviderConfig struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"-"`
+	TokenURL     string   `json:"token_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// defaultOAuthTokenURLs lists token endpoints for well-known providers
+var defaultOAuthTokenURLs = map[string]string{
+	"github": "https://github.com/login/oauth/access_token",
+	"google": "https://oauth2.googleapis.com/token",
+}
+
+// loadOAuthConfig loads OAuth providers from environment variables.
+// OAUTH_PROVIDERS lists provider names; each provider is configured with
+// OAUTH_<NAME>_CLIENT_ID, OAUTH_<NAME>_CLIENT_SECRET, OAUTH_<NAME>_TOKEN_URL
+// and OAUTH_<NAME>_SCOPES.
+func loadOAuthConfig() OAuthConfig {
+	config := OAuthConfig{
+		Providers:      make(map[string]OAuthProviderConfig),
+		RefreshSkew:    getEnvDuration("OAUTH_REFRESH_SKEW", time.Minute),
+		RefreshTimeout: getEnvDuration("OAUTH_REFRESH_TIMEOUT", 10*time.Second),
+	}
+
+	for _, name := range getEnvStringSlice("OAUTH_PROVIDERS", []string{}) {
+		name = strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		config.Providers[name] = OAuthProviderConfig{
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			TokenURL:     getEnv(prefix+"TOKEN_URL", defaultOAuthTokenURLs[name]),
+			Scopes:       getEnvStringSlice(prefix+"SCOPES", []string{}),
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,87 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Address families of instance addresses (NETWORK_IP_FAMILY)
+const (
+	IPv4 = "ipv4"
+	IPv6 = "ipv6"
+)
+
+// networkAddresses are the addresses of a container on one network
+type networkAddresses struct {
+	IPAddress         string `json:"IPAddress"`
+	GlobalIPv6Address string `json:"GlobalIPv6Address"`
+}
+
+// pick returns the address of the preferred family, or the other one on a
+// single-stack network
+func (a networkAddresses) pick(family string) string {
+	if family == IPv6 && a.GlobalIPv6Address != "" || a.IPAddress == "" {
+		return a.GlobalIPv6Address
+	}
+	return a.IPAddress
+}
+
+// all returns the IPv4 and IPv6 addresses that are set
+func (a networkAddresses) all() []string {
+	var addrs []string
+	for _, addr := range []string{a.IPAddress, a.GlobalIPv6Address} {
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// inspectNetworkSettings are the network settings in inspect output
+type inspectNetworkSettings struct {
+	networkAddresses
+	Networks map[string]networkAddresses `json:"Networks"`
+}
+
+// inspectAddresses reads the addresses of a container on network from its
+// inspect output. With anyNetwork a container not on network falls back to
+// the first of its networks by name, then to its default network settings.
+func inspectAddresses(output []byte, network string, anyNetwork bool) (networkAddresses, error) {
+	var inspected []ContainerInfo
+	if err := json.Unmarshal(output, &inspected); err != nil {
+		return networkAddresses{}, fmt.Errorf("failed to parse inspect output: %w", err)
+	}
+	if len(inspected) == 0 {
+		return networkAddresses{}, fmt.Errorf("no container data found")
+	}
+	return inspected[0].NetworkSettings.addresses(network, anyNetwork)
+}
+
+// addresses returns the addresses on network, like inspectAddresses
+func (settings inspectNetworkSettings) addresses(network string, anyNetwork bool) (networkAddresses, error) {
+	if addrs, ok := settings.Networks[network]; ok {
+		if len(addrs.all()) == 0 {
+			return addrs, fmt.Errorf("container has no address on network %s", network)
+		}
+		return addrs, nil
+	}
+	if !anyNetwork {
+		return networkAddresses{}, fmt.Errorf("network %s not found", network)
+	}
+
+	names := make([]string, 0, len(settings.Networks))
+	for name := range settings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if addrs := settings.Networks[name]; len(addrs.all()) > 0 {
+			return addrs, nil
+		}
+	}
+	if len(settings.networkAddresses.all()) > 0 {
+		return settings.networkAddresses, nil
+	}
+	return networkAddresses{}, fmt.Errorf("container IP address is empty")
+}
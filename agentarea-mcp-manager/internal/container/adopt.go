@@ -0,0 +1,275 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/store"
+)
+
+// adoptedProvider is the provider recorded for adopted legacy containers
+const adoptedProvider = "docker"
+
+// AdoptOptions controls how legacy containers are adopted
+type AdoptOptions struct {
+	WorkspaceID string // Workspace the adopted instances belong to, if any
+	Register    bool   // Register the adopted instances with the Core API
+	DryRun      bool   // Report what would be adopted without changing anything
+}
+
+// Adoption is the outcome of adopting one legacy container
+type Adoption struct {
+	ServiceName string `json:"service_name"`
+	InstanceID  string `json:"instance_id"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	Slug        string `json:"slug,omitempty"`
+	Registered  bool   `json:"registered"`
+	Error       string `json:"error,omitempty"`
+}
+
+// adoptedSpec is the spec persisted for an adopted container
+type adoptedSpec struct {
+	Image   string `json:"image"`
+	Port    int    `json:"port"`
+	Slug    string `json:"slug,omitempty"`
+	Adopted bool   `json:"adopted"`
+}
+
+// SetInstanceStore sets the store adopted legacy containers are recorded in,
+// so their instance IDs survive restarts
+func (m *Manager) SetInstanceStore(instances store.InstanceStore) {
+	m.instanceStore = instances
+}
+
+// LegacyContainers returns the service names of containers created through
+// the legacy /containers API, which have no instance ID
+func (m *Manager) LegacyContainers() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var names []string
+	for name, container := range m.containers {
+		if container.Environment["MCP_INSTANCE_ID"] == "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AdoptLegacyContainers gives every legacy container an instance ID (and the
+// workspace in opts), records it in the instance store and optionally
+// registers it with the Core API. Containers keep running untouched; the IDs
+// are restored from the store on the next startup.
+func (m *Manager) AdoptLegacyContainers(ctx context.Context, opts AdoptOptions) []Adoption {
+	adoptions := []Adoption{}
+	for _, serviceName := range m.LegacyContainers() {
+		m.mutex.RLock()
+		container, exists := m.containers[serviceName]
+		m.mutex.RUnlock()
+		if !exists {
+			continue
+		}
+
+		adoption := Adoption{ServiceName: serviceName, WorkspaceID: opts.WorkspaceID, Slug: container.Slug}
+		instanceID, err := newInstanceID()
+		if err != nil {
+			adoption.Error = err.Error()
+			adoptions = append(adoptions, adoption)
+			continue
+		}
+		adoption.InstanceID = instanceID
+		if opts.DryRun {
+			adoptions = append(adoptions, adoption)
+			continue
+		}
+
+		if err := m.recordAdoption(ctx, container, instanceID, opts.WorkspaceID); err != nil {
+			adoption.Error = err.Error()
+			adoptions = append(adoptions, adoption)
+			continue
+		}
+
+		m.mutex.Lock()
+		if container.Environment == nil {
+			container.Environment = make(map[string]string)
+		}
+		container.Environment["MCP_INSTANCE_ID"] = instanceID
+		container.Environment["MCP_SERVICE_NAME"] = serviceName
+		if opts.WorkspaceID != "" {
+			container.Environment[WorkspaceIDEnv] = opts.WorkspaceID
+		}
+		container.UpdatedAt = time.Now()
+		m.saveState(ctx, container)
+		m.mutex.Unlock()
+		m.flushState(ctx)
+
+		if opts.Register {
+			if err := m.registerWithCoreAPI(ctx, container, instanceID, opts.WorkspaceID); err != nil {
+				adoption.Error = err.Error()
+			} else {
+				adoption.Registered = true
+			}
+		}
+
+		m.logger.Info("Adopted legacy container",
+			slog.String("service", serviceName),
+			slog.String("instance_id", instanceID),
+			slog.String("workspace_id", opts.WorkspaceID),
+			slog.Bool("registered", adoption.Registered))
+		adoptions = append(adoptions, adoption)
+	}
+	return adoptions
+}
+
+// recordAdoption persists an adopted container in the instance store
+func (m *Manager) recordAdoption(ctx context.Context, container *models.Container, instanceID, workspaceID string) error {
+	if m.instanceStore == nil {
+		m.logger.Warn("No instance store, adoption will not survive a restart",
+			slog.String("service", container.ServiceName))
+		return nil
+	}
+	spec, err := json.Marshal(adoptedSpec{Image: container.Image, Port: container.Port, Slug: container.Slug, Adopted: true})
+	if err != nil {
+		return fmt.Errorf("failed to encode adopted spec: %w", err)
+	}
+	now := time.Now()
+	err = m.instanceStore.Put(ctx, store.Instance{
+		ID:          instanceID,
+		Name:        container.ServiceName,
+		Provider:    adoptedProvider,
+		WorkspaceID: workspaceID,
+		Status:      string(container.Status),
+		Spec:        spec,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record adoption: %w", err)
+	}
+	return nil
+}
+
+// registerWithCoreAPI creates the Core API record of an adopted instance
+func (m *Manager) registerWithCoreAPI(ctx context.Context, container *models.Container, instanceID, workspaceID string) error {
+	jsonSpec := map[string]interface{}{
+		"image": container.Image,
+		"port":  container.Port,
+	}
+	if workspaceID != "" {
+		jsonSpec["workspace_id"] = workspaceID
+	}
+	body, err := json.Marshal(models.MCPServerInstance{
+		InstanceID:  instanceID,
+		Name:        container.ServiceName,
+		Description: "Adopted from the legacy containers API",
+		JSONSpec:    jsonSpec,
+		Status:      string(container.Status),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode instance: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/mcp-server-instances/", m.config.CoreAPIURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Core API request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: m.config.Container.HTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to register with Core API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Core API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// restoreAdoptions gives discovered legacy containers back the instance IDs
+// they were adopted under
+func (m *Manager) restoreAdoptions(ctx context.Context) error {
+	if m.instanceStore == nil {
+		return nil
+	}
+	instances, err := m.instanceStore.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list adopted instances: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, instance := range instances {
+		var spec adoptedSpec
+		if instance.Provider != adoptedProvider || json.Unmarshal(instance.Spec, &spec) != nil || !spec.Adopted {
+			continue
+		}
+		container, exists := m.containers[instance.Name]
+		if !exists || container.Environment["MCP_INSTANCE_ID"] != "" {
+			continue
+		}
+		if container.Environment == nil {
+			container.Environment = make(map[string]string)
+		}
+		container.Environment["MCP_INSTANCE_ID"] = instance.ID
+		container.Environment["MCP_SERVICE_NAME"] = instance.Name
+		if instance.WorkspaceID != "" {
+			container.Environment[WorkspaceIDEnv] = instance.WorkspaceID
+		}
+		m.logger.Debug("Restored adopted instance ID",
+			slog.String("service", instance.Name),
+			slog.String("instance_id", instance.ID))
+	}
+	return nil
+}
+
+// forgetAdoption drops the adoption record of a removed container
+func (m *Manager) forgetAdoption(ctx context.Context, container *models.Container) {
+	instanceID := container.Environment["MCP_INSTANCE_ID"]
+	if m.instanceStore == nil || instanceID == "" {
+		return
+	}
+	instance, err := m.instanceStore.Get(ctx, instanceID)
+	if err != nil || instance.Provider != adoptedProvider {
+		return
+	}
+	if err := m.instanceStore.Delete(ctx, instanceID); err != nil {
+		m.logger.Warn("Failed to delete adoption record",
+			slog.String("instance_id", instanceID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// identityEnv picks the instance and workspace IDs a container was created
+// with from its environment, so discovered instances keep their identity
+func identityEnv(env map[string]string) map[string]string {
+	identity := make(map[string]string)
+	for _, key := range []string{"MCP_INSTANCE_ID", WorkspaceIDEnv} {
+		if env[key] != "" {
+			identity[key] = env[key]
+		}
+	}
+	return identity
+}
+
+// newInstanceID generates a random (version 4) UUID
+func newInstanceID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate instance ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
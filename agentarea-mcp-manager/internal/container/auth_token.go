@@ -0,0 +1,59 @@
+package container
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// Environment variables describing an MCP instance to its container
+const (
+	// AuthTokenEnv holds the bearer token clients must present to reach the
+	// instance. The proxy enforces it; servers may also verify it themselves.
+	AuthTokenEnv = "MCP_AUTH_TOKEN"
+	// WorkspaceIDEnv holds the workspace that owns the instance
+	WorkspaceIDEnv = "MCP_WORKSPACE_ID"
+)
+
+// NewAuthToken generates a random per-instance bearer token
+func NewAuthToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate auth token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// EnsureAuthToken adds a generated auth token to env unless one is already set
+func EnsureAuthToken(env map[string]string) error {
+	if env[AuthTokenEnv] != "" {
+		return nil
+	}
+	token, err := NewAuthToken()
+	if err != nil {
+		return err
+	}
+	env[AuthTokenEnv] = token
+	return nil
+}
+
+// authEnabled reports whether json_spec of an event-created instance enables
+// per-instance auth. It is on unless the spec sets `"auth": false`. A token
+// the platform sets in the environment is used whether or not auth is enabled.
+func authEnabled(jsonSpec map[string]interface{}) bool {
+	enabled, ok := jsonSpec["auth"].(bool)
+	return enabled || !ok
+}
+
+// eventAuthToken adds a generated auth token to the environment of an
+// event-created instance unless auth is disabled or the platform set one. It
+// returns the generated token so the running event can deliver it.
+func eventAuthToken(jsonSpec map[string]interface{}, env map[string]string) (string, error) {
+	if !authEnabled(jsonSpec) || env[AuthTokenEnv] != "" {
+		return "", nil
+	}
+	if err := EnsureAuthToken(env); err != nil {
+		return "", err
+	}
+	return env[AuthTokenEnv], nil
+}
@@ -0,0 +1,172 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Bandwidth limits of an instance, in the Kubernetes bandwidth annotation
+// format (e.g. 10M for 10 Mbit/s)
+const (
+	IngressBandwidthLabel = "agentarea.io/ingress-bandwidth"
+	EgressBandwidthLabel  = "agentarea.io/egress-bandwidth"
+)
+
+// bandwidthInterface is the container side of the network
+const bandwidthInterface = "eth0"
+
+// minBandwidthBurst is the smallest token bucket, in bytes
+const minBandwidthBurst = 16 * 1024
+
+// ParseBandwidth parses a rate in bits per second with an optional decimal
+// k, M or G suffix
+func ParseBandwidth(rate string) (int64, error) {
+	original := strings.TrimSpace(rate)
+	if original == "" {
+		return 0, fmt.Errorf("empty bandwidth")
+	}
+	rate = original
+	multiplier := 1.0
+	switch rate[len(rate)-1] {
+	case 'k', 'K':
+		multiplier = 1e3
+	case 'M':
+		multiplier = 1e6
+	case 'G':
+		multiplier = 1e9
+	}
+	if multiplier != 1 {
+		rate = rate[:len(rate)-1]
+	}
+	value, err := strconv.ParseFloat(rate, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q", original)
+	}
+	bits := int64(value * multiplier)
+	if bits < 8000 {
+		return 0, fmt.Errorf("bandwidth %q is below 8k", original)
+	}
+	return bits, nil
+}
+
+// SetBandwidth validates and records bandwidth limits in instance labels;
+// empty rates are left out
+func SetBandwidth(labels map[string]string, ingress, egress string) (map[string]string, error) {
+	for label, rate := range map[string]string{IngressBandwidthLabel: ingress, EgressBandwidthLabel: egress} {
+		if rate == "" {
+			continue
+		}
+		if _, err := ParseBandwidth(rate); err != nil {
+			return labels, err
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[label] = rate
+	}
+	return labels, nil
+}
+
+// bandwidthLimits returns the ingress and egress rates of a container in
+// bits per second, falling back to the configured defaults (0 = unlimited)
+func (m *Manager) bandwidthLimits(container *models.Container) (ingress, egress int64) {
+	rate := func(label, fallback string) int64 {
+		value := container.Labels[label]
+		if value == "" {
+			value = fallback
+		}
+		if value == "" {
+			return 0
+		}
+		bits, err := ParseBandwidth(value)
+		if err != nil {
+			m.logger.Warn("Ignoring invalid bandwidth limit",
+				slog.String("service", container.ServiceName),
+				slog.String("label", label),
+				slog.String("error", err.Error()))
+			return 0
+		}
+		return bits
+	}
+	return rate(IngressBandwidthLabel, m.config.Container.DefaultIngressBandwidth),
+		rate(EgressBandwidthLabel, m.config.Container.DefaultEgressBandwidth)
+}
+
+// applyBandwidth shapes the traffic of a started container with tc in its
+// network namespace: a token bucket on egress and a policer on ingress. The
+// limits are lost when the container restarts, so it is called after every
+// start. Failures are logged and published as warnings; the container keeps
+// running unshaped.
+func (m *Manager) applyBandwidth(ctx context.Context, container *models.Container) {
+	ingress, egress := m.bandwidthLimits(container)
+	if ingress == 0 && egress == 0 {
+		return
+	}
+
+	err := func() error {
+		pid, err := m.containerPID(ctx, container)
+		if err != nil {
+			return err
+		}
+		for _, args := range bandwidthCommands(pid, ingress, egress) {
+			if output, err := commandCombinedOutput(ctx, m.config.Container.CommandTimeout, "nsenter", args...); err != nil {
+				return fmt.Errorf("%s: %w, output: %s", strings.Join(args[3:], " "), err, strings.TrimSpace(string(output)))
+			}
+		}
+		return nil
+	}()
+	if err == nil {
+		m.logger.Debug("Applied bandwidth limits",
+			slog.String("service", container.ServiceName),
+			slog.Int64("ingress_bps", ingress),
+			slog.Int64("egress_bps", egress))
+		return
+	}
+
+	m.logger.Warn("Failed to apply bandwidth limits",
+		slog.String("service", container.ServiceName),
+		slog.String("error", err.Error()))
+	if instanceID := container.Environment["MCP_INSTANCE_ID"]; instanceID != "" {
+		warning := "bandwidth limits not applied: " + err.Error()
+		if err := m.eventPublisher.PublishWarning(ctx, instanceID, container.ServiceName, warning); err != nil {
+			m.logger.Warn("Failed to publish bandwidth warning",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+// bandwidthCommands builds the nsenter arguments that run tc in the network
+// namespace of pid. Rates are in bits per second; 0 leaves a direction unshaped.
+func bandwidthCommands(pid string, ingress, egress int64) [][]string {
+	tc := func(args ...string) []string {
+		return append([]string{"-t", pid, "-n", "tc"}, args...)
+	}
+
+	var commands [][]string
+	if egress > 0 {
+		commands = append(commands, tc("qdisc", "replace", "dev", bandwidthInterface, "root", "tbf",
+			"rate", fmt.Sprintf("%dbit", egress),
+			"burst", strconv.FormatInt(bandwidthBurst(egress), 10),
+			"latency", "50ms"))
+	}
+	if ingress > 0 {
+		commands = append(commands,
+			tc("qdisc", "replace", "dev", bandwidthInterface, "handle", "ffff:", "ingress"),
+			tc("filter", "replace", "dev", bandwidthInterface, "parent", "ffff:", "protocol", "all", "prio", "1",
+				"u32", "match", "u32", "0", "0",
+				"police", "rate", fmt.Sprintf("%dbit", ingress),
+				"burst", strconv.FormatInt(bandwidthBurst(ingress), 10), "drop"))
+	}
+	return commands
+}
+
+// bandwidthBurst sizes the token bucket to 100ms of traffic
+func bandwidthBurst(bitsPerSecond int64) int64 {
+	return max(bitsPerSecond/8/10, minBandwidthBurst)
+}
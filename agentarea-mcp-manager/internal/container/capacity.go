@@ -0,0 +1,421 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrInsufficientCapacity is returned when admitting an instance would
+// oversubscribe the host
+var ErrInsufficientCapacity = errors.New("insufficient host capacity")
+
+// ErrContainerLimit is returned when MaxContainers instances already exist
+var ErrContainerLimit = errors.New("maximum container limit reached")
+
+// admissionPollInterval is how often a queued creation rechecks capacity
+// when no container is released
+const admissionPollInterval = 5 * time.Second
+
+// HostCapacity is what the host offers to containers
+type HostCapacity struct {
+	MemoryBytes     int64   `json:"memory_bytes"`
+	MemoryAvailable int64   `json:"memory_available_bytes"`
+	CPUs            float64 `json:"cpus"`
+	DiskFreeBytes   int64   `json:"disk_free_bytes"`
+}
+
+// Reservation is the memory and CPU held for a container, taken from its limits
+type Reservation struct {
+	MemoryBytes int64   `json:"memory_bytes"`
+	CPUs        float64 `json:"cpus"`
+}
+
+// CapacityStatus reports host capacity against reservations
+type CapacityStatus struct {
+	Host             HostCapacity `json:"host"`
+	Reserved         Reservation  `json:"reserved"`
+	Allocatable      Reservation  `json:"allocatable"` // Host capacity times the oversubscription factor
+	Oversubscription float64      `json:"oversubscription"`
+	MinFreeDiskBytes int64        `json:"min_free_disk_bytes"`
+	Containers       int          `json:"containers"`
+	MaxContainers    int          `json:"max_containers"`
+	Waiting          int          `json:"waiting"` // Creations queued for capacity
+	Queue            []QueuedItem `json:"queue"`   // In admission order
+}
+
+// AdmissionOptions order and bound the wait of a queued creation
+type AdmissionOptions struct {
+	Priority int           // Higher priorities are admitted first
+	TTL      time.Duration // How long to wait for room; 0 uses AdmissionWait
+}
+
+// QueuedItem is a creation waiting for admission
+type QueuedItem struct {
+	ServiceName string    `json:"service_name"`
+	Priority    int       `json:"priority"`
+	Reason      string    `json:"reason"`
+	QueuedAt    time.Time `json:"queued_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// admission tracks reservations per service
+type admission struct {
+	mu       sync.Mutex
+	reserved map[string]Reservation
+	queue    []*QueuedItem // By priority, then arrival
+	released chan struct{} // Closed and replaced whenever capacity is released
+}
+
+func newAdmission() *admission {
+	return &admission{reserved: make(map[string]Reservation), released: make(chan struct{})}
+}
+
+// readHostCapacity reads memory from /proc/meminfo, the CPU count and the
+// free space of the container storage
+func readHostCapacity(storagePath string) (HostCapacity, error) {
+	capacity := HostCapacity{CPUs: float64(runtime.NumCPU())}
+
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return capacity, fmt.Errorf("failed to read host memory: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			capacity.MemoryBytes = kb << 10
+		case "MemAvailable:":
+			capacity.MemoryAvailable = kb << 10
+		}
+	}
+
+	if _, free, ok := statFilesystem(storagePath); ok {
+		capacity.DiskFreeBytes = free
+	}
+
+	return capacity, nil
+}
+
+// statFilesystem returns the size and free space of the filesystem holding
+// path, or of its nearest existing parent
+func statFilesystem(path string) (total, free int64, ok bool) {
+	var stat syscall.Statfs_t
+	for ; path != ""; path = parentDir(path) {
+		if err := syscall.Statfs(path, &stat); err == nil {
+			return int64(stat.Blocks) * int64(stat.Bsize), int64(stat.Bavail) * int64(stat.Bsize), true
+		}
+	}
+	return 0, 0, false
+}
+
+// parentDir returns the parent of an absolute path, or "" at the root
+func parentDir(path string) string {
+	if path == "/" {
+		return ""
+	}
+	i := strings.LastIndex(strings.TrimSuffix(path, "/"), "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// parseSize parses a podman size such as 512m or 1g (binary units)
+func parseSize(limit string) (int64, error) {
+	limit = strings.ToLower(strings.TrimSpace(limit))
+	if limit == "" {
+		return 0, nil
+	}
+	shift := 0
+	switch limit[len(limit)-1] {
+	case 'b':
+		limit = limit[:len(limit)-1]
+	case 'k':
+		shift = 10
+		limit = limit[:len(limit)-1]
+	case 'm':
+		shift = 20
+		limit = limit[:len(limit)-1]
+	case 'g':
+		shift = 30
+		limit = limit[:len(limit)-1]
+	}
+	value, err := strconv.ParseFloat(limit, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", limit)
+	}
+	return int64(value * float64(int64(1)<<shift)), nil
+}
+
+// defaultReservation is the reservation of a container with the default limits
+func (m *Manager) defaultReservation() Reservation {
+	memory, _ := parseSize(m.config.Container.DefaultMemoryLimit)
+	cpus, _ := strconv.ParseFloat(m.config.Container.DefaultCPULimit, 64)
+	return Reservation{MemoryBytes: memory, CPUs: cpus}
+}
+
+// checkCapacity reports why res cannot be admitted next to reserved, or nil.
+// Reservations may add up to the host capacity times the oversubscription
+// factor, the memory actually available must cover the new container's share
+// at that factor, and the container storage must keep its minimum free space.
+func checkCapacity(host HostCapacity, reserved, res Reservation, factor float64, minFreeDisk int64) error {
+	if res.MemoryBytes > 0 && host.MemoryBytes > 0 {
+		allocatable := int64(float64(host.MemoryBytes) * factor)
+		if reserved.MemoryBytes+res.MemoryBytes > allocatable {
+			return fmt.Errorf("%w: memory reservations would reach %d of %d bytes", ErrInsufficientCapacity,
+				reserved.MemoryBytes+res.MemoryBytes, allocatable)
+		}
+		if share := int64(float64(res.MemoryBytes) / factor); host.MemoryAvailable < share {
+			return fmt.Errorf("%w: %d bytes of memory available, %d needed", ErrInsufficientCapacity,
+				host.MemoryAvailable, share)
+		}
+	}
+	if res.CPUs > 0 && host.CPUs > 0 {
+		if allocatable := host.CPUs * factor; reserved.CPUs+res.CPUs > allocatable {
+			return fmt.Errorf("%w: CPU reservations would reach %.2f of %.2f cores", ErrInsufficientCapacity,
+				reserved.CPUs+res.CPUs, allocatable)
+		}
+	}
+	if minFreeDisk > 0 && host.DiskFreeBytes > 0 && host.DiskFreeBytes < minFreeDisk {
+		return fmt.Errorf("%w: %d bytes of disk free, %d required", ErrInsufficientCapacity,
+			host.DiskFreeBytes, minFreeDisk)
+	}
+	return nil
+}
+
+// ParseAdmissionOptions reads json_spec.priority and json_spec.queue_ttl
+func ParseAdmissionOptions(jsonSpec map[string]interface{}) (AdmissionOptions, error) {
+	var opts AdmissionOptions
+	if priority, ok := jsonSpec["priority"].(float64); ok {
+		opts.Priority = int(priority)
+	}
+	if ttl, ok := jsonSpec["queue_ttl"].(string); ok && ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid queue_ttl %q", ttl)
+		}
+		opts.TTL = d
+	}
+	return opts, nil
+}
+
+// wake lets queued creations recheck admission (caller holds mu)
+func (a *admission) wake() {
+	close(a.released)
+	a.released = make(chan struct{})
+}
+
+// enqueue inserts a waiter after those of the same or higher priority
+// (caller holds mu)
+func (a *admission) enqueue(item *QueuedItem) {
+	i := slices.IndexFunc(a.queue, func(queued *QueuedItem) bool { return queued.Priority < item.Priority })
+	if i < 0 {
+		i = len(a.queue)
+	}
+	a.queue = slices.Insert(a.queue, i, item)
+}
+
+// dequeue removes a waiter and wakes the ones behind it (caller holds mu)
+func (a *admission) dequeue(item *QueuedItem) {
+	if i := slices.Index(a.queue, item); i >= 0 {
+		a.queue = slices.Delete(a.queue, i, i+1)
+		a.wake()
+	}
+}
+
+// ahead counts the waiters to be admitted before item, or before a new
+// creation of the priority when item is nil (caller holds mu)
+func (a *admission) ahead(item *QueuedItem, priority int) int {
+	if item != nil {
+		return slices.Index(a.queue, item)
+	}
+	n := 0
+	for _, queued := range a.queue {
+		if queued.Priority >= priority {
+			n++
+		}
+	}
+	return n
+}
+
+// reserveCapacity admits a container and holds its reservation until
+// releaseCapacity. Without room it fails with ErrInsufficientCapacity, or,
+// when a wait is set (opts.TTL, else AdmissionWait), queues for that long
+// behind creations of the same or higher priority until containers are
+// released; onQueued is called once if it has to wait. Creations beyond
+// MaxContainers fail with ErrContainerLimit, and queue alike when
+// AdmissionQueueAtLimit is set. Host checks are disabled when the
+// oversubscription factor is 0.
+func (m *Manager) reserveCapacity(ctx context.Context, serviceName string, res Reservation, opts AdmissionOptions, onQueued func()) error {
+	factor := m.config.Container.Oversubscription
+	maxContainers := m.config.Container.MaxContainers
+	wait := opts.TTL
+	if wait <= 0 {
+		wait = m.config.Container.AdmissionWait
+	}
+	var deadline time.Time
+	if wait > 0 {
+		deadline = time.Now().Add(wait)
+	}
+	minFreeDisk, err := parseSize(m.config.Container.MinFreeDisk)
+	if err != nil {
+		m.logger.Warn("Ignoring invalid HOST_MIN_FREE_DISK", slog.String("error", err.Error()))
+		minFreeDisk = 0
+	}
+
+	var queued *QueuedItem
+	for {
+		m.admission.mu.Lock()
+		if _, exists := m.admission.reserved[serviceName]; exists {
+			m.admission.mu.Unlock()
+			return fmt.Errorf("container %s already exists", serviceName)
+		}
+
+		admitErr := error(nil)
+		if maxContainers > 0 && len(m.admission.reserved) >= maxContainers {
+			admitErr = fmt.Errorf("%w (%d)", ErrContainerLimit, maxContainers)
+		} else if factor > 0 {
+			host, err := readHostCapacity(m.config.Container.StorageGraphroot)
+			if err != nil {
+				// Without host figures only the container limit applies
+				m.logger.Debug("Failed to read host capacity", slog.String("error", err.Error()))
+			} else {
+				admitErr = checkCapacity(host, m.reservedUnsafe(), res, factor, minFreeDisk)
+			}
+		}
+		if n := m.admission.ahead(queued, opts.Priority); admitErr == nil && n > 0 {
+			admitErr = fmt.Errorf("%w: %d creations queued ahead", ErrInsufficientCapacity, n)
+		}
+		if admitErr == nil {
+			m.admission.reserved[serviceName] = res
+			if queued != nil {
+				m.admission.dequeue(queued)
+			}
+			m.admission.mu.Unlock()
+			return nil
+		}
+
+		queueable := !errors.Is(admitErr, ErrContainerLimit) || m.config.Container.AdmissionQueueAtLimit
+		if !queueable || deadline.IsZero() || time.Now().After(deadline) {
+			if queued != nil {
+				m.admission.dequeue(queued)
+			}
+			m.admission.mu.Unlock()
+			return admitErr
+		}
+		if queued == nil {
+			queued = &QueuedItem{
+				ServiceName: serviceName,
+				Priority:    opts.Priority,
+				QueuedAt:    time.Now(),
+				ExpiresAt:   deadline,
+			}
+			m.admission.enqueue(queued)
+			m.logger.Info("Queuing container creation for admission",
+				slog.String("service", serviceName),
+				slog.Int("priority", opts.Priority),
+				slog.String("reason", admitErr.Error()))
+			if onQueued != nil {
+				onQueued()
+			}
+		}
+		queued.Reason = admitErr.Error()
+		released := m.admission.released
+		m.admission.mu.Unlock()
+
+		timer := time.NewTimer(min(admissionPollInterval, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			m.admission.mu.Lock()
+			m.admission.dequeue(queued)
+			m.admission.mu.Unlock()
+			return ctx.Err()
+		case <-released:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// holdCapacity records the reservation of an existing container without
+// admission checks
+func (m *Manager) holdCapacity(serviceName string) {
+	m.admission.mu.Lock()
+	m.admission.reserved[serviceName] = m.defaultReservation()
+	m.admission.mu.Unlock()
+}
+
+// releaseCapacity drops the reservation of a service and wakes queued creations
+func (m *Manager) releaseCapacity(serviceName string) {
+	m.admission.mu.Lock()
+	defer m.admission.mu.Unlock()
+
+	if _, ok := m.admission.reserved[serviceName]; !ok {
+		return
+	}
+	delete(m.admission.reserved, serviceName)
+	m.admission.wake()
+}
+
+// reservedUnsafe sums all reservations (caller holds admission.mu)
+func (m *Manager) reservedUnsafe() Reservation {
+	var total Reservation
+	for _, res := range m.admission.reserved {
+		total.MemoryBytes += res.MemoryBytes
+		total.CPUs += res.CPUs
+	}
+	return total
+}
+
+// CapacityStatus returns host capacity, reservations and queued creations
+func (m *Manager) CapacityStatus() (CapacityStatus, error) {
+	host, err := readHostCapacity(m.config.Container.StorageGraphroot)
+	if err != nil {
+		return CapacityStatus{}, err
+	}
+	minFreeDisk, _ := parseSize(m.config.Container.MinFreeDisk)
+	factor := m.config.Container.Oversubscription
+
+	m.admission.mu.Lock()
+	defer m.admission.mu.Unlock()
+
+	queue := make([]QueuedItem, 0, len(m.admission.queue))
+	for _, item := range m.admission.queue {
+		queue = append(queue, *item)
+	}
+	return CapacityStatus{
+		Host:     host,
+		Reserved: m.reservedUnsafe(),
+		Allocatable: Reservation{
+			MemoryBytes: int64(float64(host.MemoryBytes) * factor),
+			CPUs:        host.CPUs * factor,
+		},
+		Oversubscription: factor,
+		MinFreeDiskBytes: minFreeDisk,
+		Containers:       len(m.admission.reserved),
+		MaxContainers:    m.config.Container.MaxContainers,
+		Waiting:          len(queue),
+		Queue:            queue,
+	}, nil
+}
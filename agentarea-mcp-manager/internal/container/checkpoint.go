@@ -0,0 +1,305 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// ErrNotCheckpointed is returned when restoring a container in place that has
+// no checkpoint
+var ErrNotCheckpointed = errors.New("container has no checkpoint")
+
+// ErrContainerExists is returned when an imported checkpoint belongs to a
+// service that already has a container on this host
+var ErrContainerExists = errors.New("container already exists")
+
+// CheckpointOptions controls a checkpoint
+type CheckpointOptions struct {
+	// Export writes the checkpoint to an archive in CheckpointDir that can
+	// be restored on another host
+	Export bool `json:"export"`
+	// LeaveRunning keeps the container running after the checkpoint
+	LeaveRunning bool `json:"leave_running"`
+	// TCPEstablished checkpoints open TCP connections
+	TCPEstablished bool `json:"tcp_established"`
+}
+
+// Checkpoint describes the last checkpoint of a container
+type Checkpoint struct {
+	ServiceName    string        `json:"service_name"`
+	ContainerID    string        `json:"container_id"`
+	Slug           string        `json:"slug"`
+	Archive        string        `json:"archive,omitempty"`
+	SizeBytes      int64         `json:"size_bytes,omitempty"`
+	LeaveRunning   bool          `json:"leave_running"`
+	TCPEstablished bool          `json:"tcp_established"`
+	Duration       time.Duration `json:"duration_ns"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// checkpoints tracks checkpointed containers per service
+type checkpoints struct {
+	mu       sync.Mutex
+	services map[string]Checkpoint
+}
+
+func newCheckpoints() *checkpoints {
+	return &checkpoints{services: make(map[string]Checkpoint)}
+}
+
+// isCheckpointed reports whether a service is stopped at a checkpoint, in
+// which case it must be restored rather than restarted
+func (m *Manager) isCheckpointed(serviceName string) bool {
+	m.checkpoints.mu.Lock()
+	defer m.checkpoints.mu.Unlock()
+
+	checkpoint, ok := m.checkpoints.services[serviceName]
+	return ok && !checkpoint.LeaveRunning
+}
+
+// CheckpointContainer checkpoints the process state of a running container
+// with CRIU. Unless LeaveRunning is set the container stops and is skipped by
+// health checks and auto restarts until it is restored.
+func (m *Manager) CheckpointContainer(ctx context.Context, serviceName string, opts CheckpointOptions) (*Checkpoint, error) {
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	container, exists := m.containers[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("container %s not found", serviceName)
+	}
+
+	checkpoint := Checkpoint{
+		ServiceName:    serviceName,
+		ContainerID:    container.ID,
+		Slug:           container.Slug,
+		LeaveRunning:   opts.LeaveRunning,
+		TCPEstablished: opts.TCPEstablished,
+		CreatedAt:      time.Now(),
+	}
+	if opts.Export {
+		if err := os.MkdirAll(m.config.Container.CheckpointDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+		}
+		checkpoint.Archive = filepath.Join(m.config.Container.CheckpointDir,
+			fmt.Sprintf("%s-%s.tar.gz", sanitizeNamePart(serviceName), checkpoint.CreatedAt.UTC().Format("20060102T150405Z")))
+	}
+
+	if output, err := m.checkpointCommand(ctx, checkpointArgs(container.ID, checkpoint.Archive, opts)...); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint container: %w, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	checkpoint.Duration = time.Since(checkpoint.CreatedAt)
+
+	if checkpoint.Archive != "" {
+		if info, err := os.Stat(checkpoint.Archive); err == nil {
+			checkpoint.SizeBytes = info.Size()
+		}
+	}
+
+	if !opts.LeaveRunning {
+		container.Status = models.StatusStopped
+		container.UpdatedAt = time.Now()
+	}
+
+	m.checkpoints.mu.Lock()
+	m.checkpoints.services[serviceName] = checkpoint
+	m.checkpoints.mu.Unlock()
+	m.saveState(ctx, container)
+
+	m.logger.Info("Checkpointed container",
+		slog.String("service", serviceName),
+		slog.String("archive", checkpoint.Archive),
+		slog.Bool("leave_running", opts.LeaveRunning),
+		slog.Duration("duration", checkpoint.Duration))
+
+	return &checkpoint, nil
+}
+
+// RestoreContainer restores the checkpoint of a stopped container in place
+func (m *Manager) RestoreContainer(ctx context.Context, serviceName string) (*models.Container, error) {
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	container, exists := m.containers[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("container %s not found", serviceName)
+	}
+	m.checkpoints.mu.Lock()
+	checkpoint, ok := m.checkpoints.services[serviceName]
+	m.checkpoints.mu.Unlock()
+	if !ok || checkpoint.LeaveRunning {
+		return nil, ErrNotCheckpointed
+	}
+
+	if output, err := m.checkpointCommand(ctx, restoreArgs(container.ID, "", checkpoint.TCPEstablished)...); err != nil {
+		return nil, fmt.Errorf("failed to restore container: %w, output: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	m.finishRestore(ctx, container)
+	return container, nil
+}
+
+// ImportCheckpoint restores a container from a checkpoint archive exported on
+// this or another host and starts routing to it. Pass the slug of the
+// checkpoint to keep the instance URL; a new one is generated if empty.
+func (m *Manager) ImportCheckpoint(ctx context.Context, archive, slug string, tcpEstablished bool) (*models.Container, error) {
+	if _, err := os.Stat(archive); err != nil {
+		return nil, fmt.Errorf("checkpoint archive not readable: %w", err)
+	}
+
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	output, err := m.checkpointCommand(ctx, restoreArgs("", archive, tcpEstablished)...)
+	if err != nil {
+		if strings.Contains(string(output), "already in use") {
+			return nil, ErrContainerExists
+		}
+		return nil, fmt.Errorf("failed to import checkpoint: %w, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	containerID := lastLine(string(output))
+
+	container, err := m.inspectRestored(ctx, containerID, slug)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := m.containers[container.ServiceName]; exists {
+		m.runtime.Remove(ctx, containerID, true)
+		return nil, ErrContainerExists
+	}
+	m.containers[container.ServiceName] = container
+	m.holdCapacity(container.ServiceName)
+	m.holdHostPort(container)
+
+	m.finishRestore(ctx, container)
+	return container, nil
+}
+
+// finishRestore re-adds the route of a restored container, whose IP may have
+// changed, and marks it running (caller holds m.mutex)
+func (m *Manager) finishRestore(ctx context.Context, container *models.Container) {
+	m.checkpoints.mu.Lock()
+	delete(m.checkpoints.services, container.ServiceName)
+	m.checkpoints.mu.Unlock()
+
+	m.applyBandwidth(ctx, container)
+
+	if container.Slug != "" {
+		containerIP, err := m.getContainerIP(ctx, container.ID)
+		if err != nil {
+			m.logger.Error("Failed to get container IP after restore",
+				slog.String("service", container.ServiceName),
+				slog.String("error", err.Error()))
+		} else if err := m.addContainerRoute(ctx, container, containerIP); err != nil {
+			m.logger.Error("Failed to update route after restore",
+				slog.String("slug", container.Slug),
+				slog.String("service", container.ServiceName),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	container.Status = models.StatusRunning
+	container.UpdatedAt = time.Now()
+	m.saveState(ctx, container)
+
+	m.logger.Info("Restored container from checkpoint",
+		slog.String("service", container.ServiceName),
+		slog.String("container_id", container.ID))
+
+	if instanceID, ok := container.Environment["MCP_INSTANCE_ID"]; ok {
+		if err := m.eventPublisher.PublishRunning(ctx, instanceID, container.ServiceName, container.ID, container.URL); err != nil {
+			m.logger.Warn("Failed to publish running status after restore",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+// inspectRestored builds the record of an imported container from its
+// configuration
+func (m *Manager) inspectRestored(ctx context.Context, containerID, slug string) (*models.Container, error) {
+	info, err := m.runtime.Inspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect restored container: %w", err)
+	}
+	env := parseEnvList(info.Config.Env)
+
+	serviceName := env["MCP_SERVICE_NAME"]
+	if serviceName == "" {
+		serviceName = strings.TrimPrefix(info.Name, m.config.Container.NamePrefix)
+	}
+	port := 8000
+	fmt.Sscanf(env["MCP_CONTAINER_PORT"], "%d", &port)
+
+	if slug == "" {
+		slug = generateSlug(serviceName)
+	}
+	now := time.Now()
+	return &models.Container{
+		ID:          info.ID,
+		Name:        info.Name,
+		ServiceName: serviceName,
+		Slug:        slug,
+		Image:       info.ImageName,
+		Status:      models.StatusStarting,
+		Port:        port,
+		URL:         fmt.Sprintf("%s/mcp/%s", m.config.Traefik.ProxyHost, slug),
+		Host:        m.config.Traefik.ProxyHost,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Labels:      info.Config.Labels,
+		Environment: env,
+	}, nil
+}
+
+// checkpointArgs builds the podman arguments of a checkpoint
+func checkpointArgs(containerID, archive string, opts CheckpointOptions) []string {
+	args := []string{"container", "checkpoint"}
+	if archive != "" {
+		args = append(args, "--export="+archive)
+	}
+	if opts.LeaveRunning {
+		args = append(args, "--leave-running")
+	}
+	if opts.TCPEstablished {
+		args = append(args, "--tcp-established")
+	}
+	return append(args, containerID)
+}
+
+// restoreArgs builds the podman arguments of a restore in place (archive
+// empty) or from an exported archive
+func restoreArgs(containerID, archive string, tcpEstablished bool) []string {
+	args := []string{"container", "restore"}
+	if tcpEstablished {
+		args = append(args, "--tcp-established")
+	}
+	if archive != "" {
+		return append(args, "--import="+archive)
+	}
+	return append(args, containerID)
+}
+
+// lastLine returns the last non-empty line of command output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// checkpointCommand runs a checkpoint or restore bounded by the checkpoint
+// timeout, as dumping or loading memory takes longer than other calls
+func (m *Manager) checkpointCommand(ctx context.Context, args ...string) ([]byte, error) {
+	return commandCombinedOutput(ctx, m.config.Container.CheckpointTimeout, m.config.Container.Runtime, args...)
+}
@@ -0,0 +1,391 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Command validation issue codes
+const (
+	CodeInvalidCommand    = "invalid_command"
+	CodeUnsafeCommand     = "unsafe_command"
+	CodeCommandNotFound   = "command_not_found"
+	CodeCommandUnverified = "command_unverified"
+)
+
+var (
+	// ErrInvalidCommand is returned when the command of an instance cannot be expanded
+	ErrInvalidCommand = errors.New("invalid command")
+	// ErrUnsafeCommand is returned when a substituted value could inject shell syntax
+	ErrUnsafeCommand = errors.New("unsafe command")
+)
+
+// defaultImagePath is the PATH the runtime uses for images that set none
+const defaultImagePath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// maxSymlinkHops bounds symlink resolution inside an image filesystem
+const maxSymlinkHops = 40
+
+var (
+	// commandReferencePattern matches ${env.NAME} and ${instance.FIELD};
+	// $${ is an escaped literal ${
+	commandReferencePattern = regexp.MustCompile(`\$?\$\{([a-z]+)\.([A-Za-z0-9_]+)\}`)
+	// shellSafeValue is what a value substituted into a shell script may contain
+	shellSafeValue = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]*$`)
+)
+
+// shells run their -c argument as a script
+var shells = map[string]bool{"sh": true, "bash": true, "dash": true, "ash": true, "zsh": true, "ksh": true}
+
+// CommandVars are the values command arguments can reference: ${env.NAME}
+// from the instance environment and ${instance.id}, ${instance.name},
+// ${instance.port} and ${instance.workspace_id}
+type CommandVars struct {
+	Env      map[string]string
+	Instance map[string]string
+}
+
+// NewCommandVars collects the values of an instance for command templating
+func NewCommandVars(instanceID, name string, port int, workspaceID string, env map[string]string) CommandVars {
+	return CommandVars{
+		Env: env,
+		Instance: map[string]string{
+			"id":           instanceID,
+			"name":         name,
+			"port":         strconv.Itoa(port),
+			"workspace_id": workspaceID,
+		},
+	}
+}
+
+// specCommand returns the cmd of a json_spec
+func specCommand(jsonSpec map[string]interface{}) []string {
+	var command []string
+	if items, ok := jsonSpec["cmd"].([]interface{}); ok {
+		for _, item := range items {
+			if arg, ok := item.(string); ok {
+				command = append(command, arg)
+			}
+		}
+	}
+	return command
+}
+
+// specCommandVars returns the values the command of a json_spec can
+// reference before the instance is created: its environment over the
+// template defaults and the variables the manager adds
+func (v *ContainerValidator) specCommandVars(instance *models.MCPServerInstance) CommandVars {
+	environment := make(map[string]string)
+	if env, ok := instance.JSONSpec["environment"].(map[string]interface{}); ok {
+		for name, value := range env {
+			if str, ok := value.(string); ok {
+				environment[name] = str
+			}
+		}
+	}
+	if template, ok := instance.JSONSpec["template"].(string); ok && template != "" && v.manager != nil && v.manager.templateEnv != nil {
+		environment, _, _ = templateEnvironment(v.manager.templateEnv(template), environment)
+	}
+
+	port := 8000
+	if p, ok := instance.JSONSpec["port"].(float64); ok {
+		port = int(p)
+	}
+	workspaceID, _ := instance.JSONSpec["workspace_id"].(string)
+	environment["MCP_INSTANCE_ID"] = instance.InstanceID
+	environment["MCP_SERVICE_NAME"] = instance.Name
+	environment["MCP_CONTAINER_PORT"] = strconv.Itoa(port)
+	if workspaceID != "" {
+		environment[WorkspaceIDEnv] = workspaceID
+	}
+	return NewCommandVars(instance.InstanceID, instance.Name, port, workspaceID, environment)
+}
+
+// ExpandCommand substitutes references in command arguments. Unknown
+// references are errors, and so are values that could inject shell syntax
+// into the script of a `sh -c` style command; other arguments are passed to
+// the runtime as they are and need no quoting.
+func ExpandCommand(command []string, vars CommandVars) ([]string, error) {
+	if len(command) == 0 {
+		return command, nil
+	}
+	script := shellScriptIndex(command)
+	expanded := make([]string, len(command))
+	for i, arg := range command {
+		if strings.ContainsRune(arg, 0) {
+			return nil, fmt.Errorf("command argument %d contains a NUL byte", i)
+		}
+
+		var expandErr error
+		expanded[i] = commandReferencePattern.ReplaceAllStringFunc(arg, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			match := commandReferencePattern.FindStringSubmatch(ref)
+			value, err := vars.lookup(match[1], match[2])
+			if err == nil && i == script && !shellSafeValue.MatchString(value) {
+				err = fmt.Errorf("%w: the value of %s in shell script argument %d may only contain letters, digits and _./:=@%%+,-", ErrUnsafeCommand, ref, i)
+			}
+			if err != nil && expandErr == nil {
+				expandErr = err
+			}
+			return value
+		})
+		if expandErr != nil {
+			return nil, expandErr
+		}
+	}
+	return expanded, nil
+}
+
+// lookup resolves one reference
+func (v CommandVars) lookup(namespace, name string) (string, error) {
+	var values map[string]string
+	switch namespace {
+	case "env":
+		values = v.Env
+	case "instance":
+		values = v.Instance
+	default:
+		return "", fmt.Errorf("unknown reference ${%s.%s}: use ${env.NAME} or ${instance.FIELD}", namespace, name)
+	}
+	value, ok := values[name]
+	if !ok {
+		return "", fmt.Errorf("reference ${%s.%s} is not set", namespace, name)
+	}
+	return value, nil
+}
+
+// shellScriptIndex returns the index of the script argument when the command
+// runs a shell with -c (e.g. ["sh", "-c", "..."] or ["bash", "-ec", "..."]),
+// or -1
+func shellScriptIndex(command []string) int {
+	if len(command) == 0 || !shells[path.Base(command[0])] {
+		return -1
+	}
+	for i := 1; i < len(command); i++ {
+		arg := command[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			return -1
+		}
+		if !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") {
+			if i+1 < len(command) {
+				return i + 1
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// imageConfig is the part of an image configuration that decides what runs
+// and what the image declares about itself
+type imageConfig struct {
+	Entrypoint   []string
+	Cmd          []string
+	WorkingDir   string
+	Path         []string
+	Labels       map[string]string
+	ExposedPorts []int // TCP only, sorted
+}
+
+// inspectImageConfig reads the entrypoint, command, working directory, PATH,
+// labels and exposed ports of a local image
+func (v *ContainerValidator) inspectImageConfig(ctx context.Context, image string) (*imageConfig, error) {
+	output, err := commandOutput(ctx, v.commandTimeout, v.runtime, "image", "inspect", image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	var inspected []struct {
+		Config struct {
+			Entrypoint   []string            `json:"Entrypoint"`
+			Cmd          []string            `json:"Cmd"`
+			WorkingDir   string              `json:"WorkingDir"`
+			Env          []string            `json:"Env"`
+			Labels       map[string]string   `json:"Labels"`
+			ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		} `json:"Config"`
+	}
+	if err := json.Unmarshal(output, &inspected); err != nil || len(inspected) == 0 {
+		return nil, fmt.Errorf("failed to parse inspect output of image %s", image)
+	}
+	config := inspected[0].Config
+
+	searchPath := defaultImagePath
+	if value, ok := parseEnvList(config.Env)["PATH"]; ok {
+		searchPath = value
+	}
+	return &imageConfig{
+		Entrypoint:   config.Entrypoint,
+		Cmd:          config.Cmd,
+		WorkingDir:   config.WorkingDir,
+		Path:         filepath.SplitList(searchPath),
+		Labels:       config.Labels,
+		ExposedPorts: parseExposedPorts(config.ExposedPorts),
+	}, nil
+}
+
+// parseExposedPorts returns the TCP ports of an image's ExposedPorts, whose
+// keys look like "8080/tcp" or "53/udp"
+func parseExposedPorts(exposed map[string]struct{}) []int {
+	var ports []int
+	for key := range exposed {
+		number, protocol, _ := strings.Cut(key, "/")
+		if protocol != "" && protocol != "tcp" {
+			continue
+		}
+		if port, err := strconv.Atoi(number); err == nil && port >= 1 && port <= 65535 {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// checkCommand expands the command of a spec and, if the image is present
+// locally, checks that the program it runs exists in the image. With an
+// image entrypoint the command only supplies its arguments.
+func (v *ContainerValidator) checkCommand(ctx context.Context, result *ValidationResult, image string, command []string, vars CommandVars) {
+	if len(command) == 0 {
+		return
+	}
+	expanded, err := ExpandCommand(command, vars)
+	if err != nil {
+		code := CodeInvalidCommand
+		if errors.Is(err, ErrUnsafeCommand) {
+			code = CodeUnsafeCommand
+		}
+		result.addError(code, "Invalid command: "+err.Error(),
+			"Reference ${env.NAME} or ${instance.id|name|port|workspace_id}; pass values with shell syntax through the environment instead of a shell script")
+		return
+	}
+	if !result.ImageExists {
+		return
+	}
+
+	config, err := v.inspectImageConfig(ctx, image)
+	if err != nil {
+		result.addWarning(CodeCommandUnverified, "Could not check the command against the image: "+err.Error(), "")
+		return
+	}
+	if len(config.Entrypoint) > 0 {
+		return
+	}
+
+	program := expanded[0]
+	found, err := v.programExists(ctx, image, program, config)
+	switch {
+	case err != nil:
+		result.addWarning(CodeCommandUnverified, fmt.Sprintf("Could not check that %s exists in image %s: %v", program, image, err),
+			"The container fails to start if the program is missing")
+	case !found:
+		result.addError(CodeCommandNotFound, fmt.Sprintf("Command %s not found in image %s", program, image),
+			"Use an absolute path or a program on the image PATH ("+strings.Join(config.Path, ":")+")")
+	}
+}
+
+// programExists mounts the image and looks the program up like the runtime
+// does: relative to the working directory if it contains a slash, else on
+// the image PATH
+func (v *ContainerValidator) programExists(ctx context.Context, image, program string, config *imageConfig) (bool, error) {
+	output, err := commandOutput(ctx, v.commandTimeout, v.runtime, "image", "mount", image)
+	if err != nil {
+		return false, fmt.Errorf("image mount unavailable: %w", err)
+	}
+	defer func() {
+		if _, err := commandOutput(context.Background(), v.commandTimeout, v.runtime, "image", "unmount", image); err != nil {
+			v.logger.Warn("Failed to unmount image", slog.String("image", image), slog.String("error", err.Error()))
+		}
+	}()
+
+	_, ok := findProgram(strings.TrimSpace(string(output)), program, config.WorkingDir, config.Path)
+	return ok, nil
+}
+
+// findProgram resolves a program in an image filesystem mounted at root and
+// returns its path in the image
+func findProgram(root, program, workDir string, searchPath []string) (string, bool) {
+	if strings.Contains(program, "/") {
+		candidate := program
+		if !path.IsAbs(candidate) {
+			candidate = path.Join("/", workDir, candidate)
+		}
+		return candidate, isExecutable(root, candidate)
+	}
+	for _, dir := range searchPath {
+		if dir == "" {
+			continue
+		}
+		candidate := path.Join("/", dir, program)
+		if isExecutable(root, candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// isExecutable reports whether name is an executable file in the image
+// mounted at root. Symlinks are resolved inside root, not on the host, as
+// images link absolute paths such as /bin -> /usr/bin.
+func isExecutable(root, name string) bool {
+	resolved, err := resolveInRoot(root, name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(root, resolved))
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// resolveInRoot resolves every symlink in name against the image mounted
+// at root and returns the resulting path in the image
+func resolveInRoot(root, name string) (string, error) {
+	pending := strings.Split(strings.TrimPrefix(path.Clean("/"+name), "/"), "/")
+	resolved := "/"
+	for hops := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if hops++; hops > maxSymlinkHops {
+			return "", errors.New("too many levels of symbolic links")
+		}
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+	return resolved, nil
+}
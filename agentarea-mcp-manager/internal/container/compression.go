@@ -0,0 +1,32 @@
+package container
+
+// CompressionLabel opts an instance out of proxy response compression
+const CompressionLabel = "agentarea.io/proxy-compression"
+
+// SetCompression records the compression switch of an instance in labels,
+// replacing any label of that name supplied with the spec. nil and true keep
+// the proxy default.
+func SetCompression(labels map[string]string, enabled *bool) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, CompressionLabel)
+	if enabled != nil && !*enabled {
+		labels[CompressionLabel] = "false"
+	}
+	return labels
+}
+
+// CompressionFromLabels reports whether the proxy may compress responses of
+// the instance
+func CompressionFromLabels(labels map[string]string) bool {
+	return labels[CompressionLabel] != "false"
+}
+
+// ParseCompression reads json_spec.compression
+func ParseCompression(jsonSpec map[string]interface{}) *bool {
+	if enabled, ok := jsonSpec["compression"].(bool); ok {
+		return &enabled
+	}
+	return nil
+}
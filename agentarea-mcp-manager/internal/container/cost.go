@@ -0,0 +1,116 @@
+package container
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/naming"
+)
+
+// Cost attribution labels of an instance. Values are normalized to valid
+// Kubernetes label values so cost tools and cluster policies can select on
+// them in both backends.
+const (
+	WorkspaceLabel  = "agentarea.io/workspace"
+	OwnerLabel      = "agentarea.io/owner"
+	CostCenterLabel = "agentarea.io/cost-center"
+	// CostTemplateLabel is the normalized template; TemplateLabel keeps the
+	// catalog key, which may not be a valid label value
+	CostTemplateLabel = "agentarea.io/cost-template"
+)
+
+// costAttributes maps the attribute names used in COST_LABEL_ALIASES to
+// their labels
+var costAttributes = map[string]string{
+	"workspace":   WorkspaceLabel,
+	"template":    CostTemplateLabel,
+	"owner":       OwnerLabel,
+	"cost-center": CostCenterLabel,
+}
+
+// aliasKeyPattern matches an unprefixed Kubernetes label key
+var aliasKeyPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,61}[a-z0-9])?$`)
+
+// Attribution is who an instance is billed to, beyond its workspace and
+// template
+type Attribution struct {
+	Owner      string `json:"owner,omitempty"`
+	CostCenter string `json:"cost_center,omitempty"`
+}
+
+// ParseAttribution reads json_spec.owner and json_spec.cost_center
+func ParseAttribution(jsonSpec map[string]interface{}) Attribution {
+	var attribution Attribution
+	attribution.Owner, _ = jsonSpec["owner"].(string)
+	attribution.CostCenter, _ = jsonSpec["cost_center"].(string)
+	return attribution
+}
+
+// SetAttribution records the normalized workspace, template, owner and cost
+// center in instance labels, replacing any labels of those names supplied
+// with the spec. Call it after the template label is set.
+func SetAttribution(labels map[string]string, workspaceID string, attribution Attribution) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for label, value := range map[string]string{
+		WorkspaceLabel:    workspaceID,
+		CostTemplateLabel: labels[TemplateLabel],
+		OwnerLabel:        attribution.Owner,
+		CostCenterLabel:   attribution.CostCenter,
+	} {
+		if value = naming.Normalize(value, naming.MaxLabelLength); value != "" {
+			labels[label] = value
+		} else {
+			delete(labels, label)
+		}
+	}
+	return labels
+}
+
+// AttributionFromLabels reads the workspace and attribution recorded in labels
+func AttributionFromLabels(labels map[string]string) (string, Attribution) {
+	return labels[WorkspaceLabel], Attribution{Owner: labels[OwnerLabel], CostCenter: labels[CostCenterLabel]}
+}
+
+// ParseCostLabelAliases parses COST_LABEL_ALIASES entries such as
+// "cost-center=department" into the unprefixed label each attribute is
+// copied to
+func ParseCostLabelAliases(entries []string) (map[string]string, error) {
+	aliases := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		attribute, key, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cost label alias %q: expected attribute=label", entry)
+		}
+		if _, known := costAttributes[attribute]; !known {
+			return nil, fmt.Errorf("unknown cost attribute %q: use workspace, template, owner or cost-center", attribute)
+		}
+		if !aliasKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid label key %q for cost attribute %s", key, attribute)
+		}
+		aliases[attribute] = key
+	}
+	return aliases, nil
+}
+
+// CostLabels returns the alias labels, such as team or department, that
+// carry the attribution of labels for cost tools configured with their own
+// keys. Invalid aliases are rejected at startup and ignored here.
+func CostLabels(labels map[string]string, entries []string) map[string]string {
+	aliases, err := ParseCostLabelAliases(entries)
+	if err != nil {
+		return nil
+	}
+	result := make(map[string]string)
+	for attribute, key := range aliases {
+		if value := labels[costAttributes[attribute]]; value != "" {
+			result[key] = value
+		}
+	}
+	return result
+}
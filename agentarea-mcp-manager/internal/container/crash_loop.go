@@ -0,0 +1,93 @@
+package container
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// crashLoops counts recent health check failures per service
+type crashLoops struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	reported map[string]time.Time // When the current crash loop was reported
+}
+
+func newCrashLoops() *crashLoops {
+	return &crashLoops{failures: make(map[string][]time.Time), reported: make(map[string]time.Time)}
+}
+
+// record adds a failure at now and reports whether the service has now failed
+// limit times within window without the crash loop having been reported yet
+func (c *crashLoops) record(serviceName string, now time.Time, limit int, window time.Duration) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	recent := []time.Time{now}
+	for _, at := range c.failures[serviceName] {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+	c.failures[serviceName] = recent
+
+	if len(recent) < limit {
+		return len(recent), false
+	}
+	if reported, ok := c.reported[serviceName]; ok && now.Sub(reported) < window {
+		return len(recent), false
+	}
+	c.reported[serviceName] = now
+	return len(recent), true
+}
+
+// forget drops the failures of a deleted service
+func (c *crashLoops) forget(serviceName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.failures, serviceName)
+	delete(c.reported, serviceName)
+}
+
+// recordFailure counts a failed health check of a container and publishes
+// MCPServerInstanceCrashLoop once it fails CrashLoopFailures times within
+// CrashLoopWindow
+func (m *Manager) recordFailure(container *models.Container, lastError string) {
+	limit, window := m.config.Container.CrashLoopFailures, m.config.Container.CrashLoopWindow
+	if limit <= 0 || window <= 0 {
+		return
+	}
+	failures, looping := m.crashLoops.record(container.ServiceName, time.Now(), limit, window)
+	if !looping {
+		return
+	}
+
+	m.logger.Warn("Container is crash looping",
+		slog.String("service", container.ServiceName),
+		slog.Int("failures", failures),
+		slog.Duration("window", window))
+
+	instanceID, ok := container.Environment["MCP_INSTANCE_ID"]
+	if !ok {
+		return
+	}
+	event := events.CrashLoopEvent{
+		InstanceID: instanceID,
+		Name:       container.ServiceName,
+		Failures:   failures,
+		Window:     window.String(),
+		LastError:  lastError,
+		Timestamp:  time.Now(),
+	}
+	go func() {
+		if err := m.eventPublisher.PublishCrashLoop(m.healthCtx, event); err != nil {
+			m.logger.Warn("Failed to publish crash loop event",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}()
+}
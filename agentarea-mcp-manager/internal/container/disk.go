@@ -0,0 +1,300 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// DiskQuotaLabel holds the writable layer quota of an instance
+// (json_spec.disk_quota) so it survives rediscovery
+const DiskQuotaLabel = "agentarea.io/disk-quota"
+
+// Storage filesystem levels
+const (
+	DiskLevelOK       = "ok"
+	DiskLevelWarning  = "warning"
+	DiskLevelCritical = "critical"
+)
+
+// diskQuotaWarning is the fraction of its quota at which an instance is logged
+const diskQuotaWarning = 0.9
+
+// DiskUsage is the disk used by one container
+type DiskUsage struct {
+	WritableBytes int64     `json:"writable_bytes"` // Writable layer
+	VolumeBytes   int64     `json:"volume_bytes"`   // Named volumes mounted by the container
+	QuotaBytes    int64     `json:"quota_bytes,omitempty"`
+	QuotaUsage    float64   `json:"quota_usage,omitempty"` // Fraction of the writable layer quota
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// FilesystemUsage is the usage of the container storage filesystem
+type FilesystemUsage struct {
+	Path       string    `json:"path"`
+	TotalBytes int64     `json:"total_bytes"`
+	FreeBytes  int64     `json:"free_bytes"`
+	Usage      float64   `json:"usage"`
+	Level      string    `json:"level"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DiskStatus reports storage filesystem and per-instance disk usage
+type DiskStatus struct {
+	Filesystem FilesystemUsage      `json:"filesystem"`
+	Instances  map[string]DiskUsage `json:"instances"` // By service name
+}
+
+// diskMonitor holds the latest disk samples
+type diskMonitor struct {
+	mu       sync.Mutex
+	services map[string]DiskUsage
+	fs       FilesystemUsage
+}
+
+func newDiskMonitor() *diskMonitor {
+	return &diskMonitor{services: make(map[string]DiskUsage)}
+}
+
+// podmanSizedContainer is the subset of `podman ps --size --format json` we use
+type podmanSizedContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Size  *struct {
+		RwSize int64 `json:"rwSize"`
+	} `json:"Size"`
+}
+
+// podmanMount is an entry of the Mounts of `podman inspect`
+type podmanMount struct {
+	Type   string `json:"Type"`
+	Name   string `json:"Name"`
+	Source string `json:"Source"`
+}
+
+// diskQuota returns the writable layer quota of a container, if any
+func (m *Manager) diskQuota(container *models.Container) string {
+	if quota := container.Labels[DiskQuotaLabel]; quota != "" {
+		return quota
+	}
+	return m.config.Container.DefaultDiskQuota
+}
+
+// startDiskMonitoring samples disk usage every DiskUsageInterval
+func (m *Manager) startDiskMonitoring() {
+	interval := m.config.Container.DiskUsageInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !m.BackgroundPaused() {
+			m.checkDiskUsage(m.healthCtx)
+		}
+		select {
+		case <-m.healthCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDiskUsage samples the storage filesystem and every managed container,
+// publishing an event when the filesystem changes level
+func (m *Manager) checkDiskUsage(ctx context.Context) {
+	m.checkFilesystem(ctx, time.Now())
+
+	m.mutex.RLock()
+	containers := make([]*models.Container, 0, len(m.containers))
+	for _, container := range m.containers {
+		containers = append(containers, container)
+	}
+	m.mutex.RUnlock()
+	if len(containers) == 0 {
+		return
+	}
+
+	sizes, err := m.collectWritableSizes(ctx)
+	if err != nil {
+		m.logger.Debug("Failed to collect container sizes", slog.String("error", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	for _, container := range containers {
+		usage := DiskUsage{WritableBytes: sizes[container.Name], UpdatedAt: now}
+		if container.ID != "" {
+			usage.VolumeBytes = m.volumeBytes(ctx, container.ID)
+		}
+		if quota, err := parseSize(m.diskQuota(container)); err == nil && quota > 0 {
+			usage.QuotaBytes = quota
+			usage.QuotaUsage = float64(usage.WritableBytes) / float64(quota)
+			if usage.QuotaUsage >= diskQuotaWarning {
+				m.logger.Warn("Instance close to its disk quota",
+					slog.String("service", container.ServiceName),
+					slog.Int64("writable_bytes", usage.WritableBytes),
+					slog.Int64("quota_bytes", quota))
+			}
+		}
+
+		m.disk.mu.Lock()
+		m.disk.services[container.ServiceName] = usage
+		m.disk.mu.Unlock()
+	}
+}
+
+// checkFilesystem records the usage of the storage graphroot filesystem and
+// publishes level changes
+func (m *Manager) checkFilesystem(ctx context.Context, now time.Time) {
+	path := m.config.Container.StorageGraphroot
+	total, free, ok := statFilesystem(path)
+	if !ok || total == 0 {
+		return
+	}
+	fsUsage := FilesystemUsage{
+		Path:       path,
+		TotalBytes: total,
+		FreeBytes:  free,
+		Usage:      1 - float64(free)/float64(total),
+		UpdatedAt:  now,
+	}
+	fsUsage.Level = diskLevel(fsUsage.Usage, m.config.Container.DiskWarnThreshold, m.config.Container.DiskCriticalThreshold)
+
+	m.disk.mu.Lock()
+	previous := m.disk.fs.Level
+	m.disk.fs = fsUsage
+	m.disk.mu.Unlock()
+
+	// The first sample only alerts when the filesystem is already filling up
+	if fsUsage.Level == previous || (previous == "" && fsUsage.Level == DiskLevelOK) {
+		return
+	}
+
+	logLevel := slog.LevelWarn
+	if fsUsage.Level == DiskLevelOK {
+		logLevel = slog.LevelInfo
+	}
+	m.logger.Log(ctx, logLevel, "Container storage usage level changed",
+		slog.String("path", path),
+		slog.String("level", fsUsage.Level),
+		slog.Float64("usage", fsUsage.Usage),
+		slog.Int64("free_bytes", free))
+
+	event := events.DiskPressureEvent{
+		Path:       path,
+		Level:      fsUsage.Level,
+		Usage:      fsUsage.Usage,
+		FreeBytes:  free,
+		TotalBytes: total,
+		Timestamp:  now,
+	}
+	if err := m.eventPublisher.PublishDiskPressure(ctx, event); err != nil {
+		m.logger.Warn("Failed to publish disk pressure event", slog.String("error", err.Error()))
+	}
+}
+
+// diskLevel maps a filesystem usage fraction to a level; a threshold of 0
+// disables it
+func diskLevel(usage, warn, critical float64) string {
+	switch {
+	case critical > 0 && usage >= critical:
+		return DiskLevelCritical
+	case warn > 0 && usage >= warn:
+		return DiskLevelWarning
+	default:
+		return DiskLevelOK
+	}
+}
+
+// collectWritableSizes returns the writable layer size of every container,
+// keyed by name
+func (m *Manager) collectWritableSizes(ctx context.Context) (map[string]int64, error) {
+	output, err := m.runtimeOutput(ctx, "ps", "-a", "--size", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list container sizes: %w", err)
+	}
+	return parseWritableSizes(output)
+}
+
+// parseWritableSizes reads the output of `podman ps --size --format json`
+func parseWritableSizes(output []byte) (map[string]int64, error) {
+	var containers []podmanSizedContainer
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse container sizes: %w", err)
+	}
+	sizes := make(map[string]int64, len(containers))
+	for _, container := range containers {
+		if len(container.Names) == 0 || container.Size == nil {
+			continue
+		}
+		sizes[container.Names[0]] = container.Size.RwSize
+	}
+	return sizes, nil
+}
+
+// volumeBytes sums the files of the named volumes mounted by a container
+func (m *Manager) volumeBytes(ctx context.Context, containerID string) int64 {
+	output, err := m.runtimeOutput(ctx, "inspect", containerID, "--format", "{{json .Mounts}}")
+	if err != nil {
+		return 0
+	}
+	var mounts []podmanMount
+	if err := json.Unmarshal(output, &mounts); err != nil {
+		return 0
+	}
+
+	var total int64
+	for _, mount := range mounts {
+		if mount.Type != "volume" || mount.Source == "" {
+			continue
+		}
+		filepath.WalkDir(mount.Source, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+			return nil
+		})
+	}
+	return total
+}
+
+// DiskUsage returns the latest disk usage sample of a service
+func (m *Manager) DiskUsage(serviceName string) (DiskUsage, bool) {
+	m.disk.mu.Lock()
+	defer m.disk.mu.Unlock()
+
+	usage, ok := m.disk.services[serviceName]
+	return usage, ok
+}
+
+// DiskStatus returns the latest storage filesystem and per-instance samples
+func (m *Manager) DiskStatus() DiskStatus {
+	m.disk.mu.Lock()
+	defer m.disk.mu.Unlock()
+
+	status := DiskStatus{Filesystem: m.disk.fs, Instances: make(map[string]DiskUsage, len(m.disk.services))}
+	for service, usage := range m.disk.services {
+		status.Instances[service] = usage
+	}
+	return status
+}
+
+// forgetDisk drops the samples of a removed service
+func (m *Manager) forgetDisk(serviceName string) {
+	m.disk.mu.Lock()
+	delete(m.disk.services, serviceName)
+	m.disk.mu.Unlock()
+}
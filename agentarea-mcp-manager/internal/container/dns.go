@@ -0,0 +1,158 @@
+package container
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DNS settings of an instance, recorded in its labels as comma separated
+// lists; extra hosts as host=ip pairs
+const (
+	DNSServersLabel = "agentarea.io/dns-servers"
+	DNSSearchLabel  = "agentarea.io/dns-search"
+	ExtraHostsLabel = "agentarea.io/extra-hosts"
+)
+
+// DNSLabels lists the labels holding DNS settings
+var DNSLabels = []string{DNSServersLabel, DNSSearchLabel, ExtraHostsLabel}
+
+// Limits of resolv.conf and the Kubernetes pod DNS config
+const (
+	maxDNSServers = 3
+	maxDNSSearch  = 6
+)
+
+// hostnamePattern matches DNS names such as corp.example.com
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// DNSOptions customize name resolution of an instance
+type DNSOptions struct {
+	DNSServers []string          `json:"dns_servers,omitempty"` // Nameserver IPs
+	DNSSearch  []string          `json:"dns_search,omitempty"`  // Search domains
+	ExtraHosts map[string]string `json:"extra_hosts,omitempty"` // Hostname to IP, added to /etc/hosts
+}
+
+// IsZero reports whether no DNS option is set
+func (o DNSOptions) IsZero() bool {
+	return len(o.DNSServers) == 0 && len(o.DNSSearch) == 0 && len(o.ExtraHosts) == 0
+}
+
+// Validate checks the addresses and names
+func (o DNSOptions) Validate() error {
+	if len(o.DNSServers) > maxDNSServers {
+		return fmt.Errorf("at most %d dns_servers are supported", maxDNSServers)
+	}
+	for _, server := range o.DNSServers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid dns server %q: expected an IP address", server)
+		}
+	}
+	if len(o.DNSSearch) > maxDNSSearch {
+		return fmt.Errorf("at most %d dns_search domains are supported", maxDNSSearch)
+	}
+	for _, domain := range o.DNSSearch {
+		if len(domain) > 253 || !hostnamePattern.MatchString(domain) {
+			return fmt.Errorf("invalid dns search domain %q", domain)
+		}
+	}
+	for host, ip := range o.ExtraHosts {
+		if len(host) > 253 || !hostnamePattern.MatchString(host) {
+			return fmt.Errorf("invalid extra host name %q", host)
+		}
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid address %q for extra host %s", ip, host)
+		}
+	}
+	return nil
+}
+
+// SetDNSOptions validates and records DNS options in instance labels;
+// options that are not set are left out
+func SetDNSOptions(labels map[string]string, opts DNSOptions) (map[string]string, error) {
+	if opts.IsZero() {
+		return labels, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return labels, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if len(opts.DNSServers) > 0 {
+		labels[DNSServersLabel] = strings.Join(opts.DNSServers, ",")
+	}
+	if len(opts.DNSSearch) > 0 {
+		labels[DNSSearchLabel] = strings.Join(opts.DNSSearch, ",")
+	}
+	if len(opts.ExtraHosts) > 0 {
+		pairs := make([]string, 0, len(opts.ExtraHosts))
+		for _, host := range slices.Sorted(maps.Keys(opts.ExtraHosts)) {
+			pairs = append(pairs, host+"="+opts.ExtraHosts[host])
+		}
+		labels[ExtraHostsLabel] = strings.Join(pairs, ",")
+	}
+	return labels, nil
+}
+
+// DNSOptionsFromLabels reads the DNS options recorded in labels
+func DNSOptionsFromLabels(labels map[string]string) DNSOptions {
+	split := func(value string) []string {
+		if value == "" {
+			return nil
+		}
+		return strings.Split(value, ",")
+	}
+	opts := DNSOptions{
+		DNSServers: split(labels[DNSServersLabel]),
+		DNSSearch:  split(labels[DNSSearchLabel]),
+	}
+	for _, pair := range split(labels[ExtraHostsLabel]) {
+		if host, ip, ok := strings.Cut(pair, "="); ok {
+			if opts.ExtraHosts == nil {
+				opts.ExtraHosts = make(map[string]string)
+			}
+			opts.ExtraHosts[host] = ip
+		}
+	}
+	return opts
+}
+
+// ParseDNSOptions reads the DNS options of a json_spec:
+//
+//	"dns_servers": ["10.0.0.53"], "dns_search": ["corp.example.com"],
+//	"extra_hosts": {"git.corp.example.com": "10.0.4.20"}
+func ParseDNSOptions(jsonSpec map[string]interface{}) DNSOptions {
+	list := func(key string) []string {
+		var values []string
+		if raw, ok := jsonSpec[key].([]interface{}); ok {
+			for _, item := range raw {
+				if value, ok := item.(string); ok {
+					values = append(values, value)
+				}
+			}
+		}
+		return values
+	}
+	opts := DNSOptions{DNSServers: list("dns_servers"), DNSSearch: list("dns_search")}
+	if hosts, ok := jsonSpec["extra_hosts"].(map[string]interface{}); ok {
+		opts.ExtraHosts = make(map[string]string, len(hosts))
+		for host, ip := range hosts {
+			opts.ExtraHosts[host] = fmt.Sprint(ip)
+		}
+	}
+	return opts
+}
+
+// applyTo adds the nameservers, search domains and hosts entries to a create spec
+func (o DNSOptions) applyTo(spec *createSpec) {
+	host := &spec.HostConfig
+	host.DNS = append(host.DNS, o.DNSServers...)
+	host.DNSSearch = append(host.DNSSearch, o.DNSSearch...)
+	for _, name := range slices.Sorted(maps.Keys(o.ExtraHosts)) {
+		host.ExtraHosts = append(host.ExtraHosts, name+":"+o.ExtraHosts[name])
+	}
+}
@@ -0,0 +1,77 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DrainResult reports how an instance's proxied connections were drained
+type DrainResult struct {
+	Drained   bool `json:"drained"`   // No connections were left open
+	Remaining int  `json:"remaining"` // Connections open when the timeout expired
+	Closed    int  `json:"closed"`    // Connections force-closed after the timeout
+}
+
+// connectionDrainer is implemented by proxy.RouteManager
+type connectionDrainer interface {
+	ActiveConnections(slug string) int
+	DrainMCPService(ctx context.Context, slug string, timeout time.Duration, force bool) (remaining, closed int)
+	ResumeMCPService(slug string)
+}
+
+// DrainInstance stops new proxied requests to an instance and waits up to
+// timeout for in-flight requests and streams to finish. With force, requests
+// still open afterwards are closed and a warning event is published. Call
+// ResumeInstance if the lifecycle operation is abandoned.
+func (m *Manager) DrainInstance(ctx context.Context, serviceName string, timeout time.Duration, force bool) (DrainResult, error) {
+	container, err := m.GetContainer(serviceName)
+	if err != nil {
+		return DrainResult{}, err
+	}
+
+	drainer, ok := m.routeManager.(connectionDrainer)
+	if !ok || container.Slug == "" {
+		// Without the internal proxy there are no tracked connections
+		return DrainResult{Drained: true}, nil
+	}
+
+	remaining, closed := drainer.DrainMCPService(ctx, container.Slug, timeout, force)
+	result := DrainResult{Drained: remaining == 0, Remaining: remaining, Closed: closed}
+
+	if closed > 0 {
+		warning := fmt.Sprintf("force-closed %d active connection(s) after waiting %s for them to finish", closed, timeout)
+		m.logger.Warn("Force-closed active connections",
+			slog.String("service", serviceName),
+			slog.Int("closed", closed))
+		if instanceID := container.Environment["MCP_INSTANCE_ID"]; instanceID != "" {
+			if err := m.eventPublisher.PublishWarning(ctx, instanceID, serviceName, warning); err != nil {
+				m.logger.Warn("Failed to publish warning event",
+					slog.String("instance_id", instanceID),
+					slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ResumeInstance admits proxied requests to a drained instance again
+func (m *Manager) ResumeInstance(serviceName string) {
+	container, err := m.GetContainer(serviceName)
+	if err != nil {
+		return
+	}
+	if drainer, ok := m.routeManager.(connectionDrainer); ok {
+		drainer.ResumeMCPService(container.Slug)
+	}
+}
+
+// activeConnections returns the number of in-flight proxied requests of a container
+func (m *Manager) activeConnections(slug string) int {
+	if drainer, ok := m.routeManager.(connectionDrainer); ok {
+		return drainer.ActiveConnections(slug)
+	}
+	return 0
+}
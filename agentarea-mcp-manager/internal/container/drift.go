@@ -0,0 +1,307 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Kinds of drift change
+const (
+	DriftModified   = "modified"
+	DriftMissing    = "missing"    // Desired but absent from the container
+	DriftUnexpected = "unexpected" // Set in the container but not desired
+)
+
+// runtimeEnv are variables the runtime sets in every container
+var runtimeEnv = map[string]bool{
+	"HOSTNAME":  true,
+	"HOME":      true,
+	"TERM":      true,
+	"container": true,
+}
+
+// DriftChange is a difference between a running container and its desired
+// spec. Environment values are never reported, only the variable name.
+type DriftChange struct {
+	Field   string `json:"field"` // image, image_digest, command, memory, cpus or env
+	Key     string `json:"key,omitempty"`
+	Change  string `json:"change"`
+	Desired string `json:"desired,omitempty"`
+	Actual  string `json:"actual,omitempty"`
+}
+
+// DriftReport is the result of comparing a container with its desired spec
+type DriftReport struct {
+	ServiceName string        `json:"service_name"`
+	ContainerID string        `json:"container_id"`
+	Drifted     bool          `json:"drifted"`
+	Changes     []DriftChange `json:"changes"`
+	CheckedAt   time.Time     `json:"checked_at"`
+	Fixed       bool          `json:"fixed"`
+	FixError    string        `json:"fix_error,omitempty"`
+}
+
+// driftState is the part of a container configuration checked for drift
+type driftState struct {
+	Image    string
+	ImageID  string
+	Env      map[string]string
+	Command  []string
+	Memory   int64 // Bytes; 0 is unlimited
+	NanoCPUs int64
+}
+
+// CheckDrift compares the running container of a service with the spec the
+// manager created it from. With fix set, a drifted container is recreated
+// from the spec.
+func (m *Manager) CheckDrift(ctx context.Context, serviceName string, fix bool) (*DriftReport, error) {
+	container, err := m.managedContainer(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.RLock()
+	desired := m.desiredState(container)
+	containerID := container.ID
+	m.mutex.RUnlock()
+
+	actual, err := m.inspectDriftState(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	imageEnv := map[string]string{}
+	if id, env, err := m.inspectImage(ctx, desired.Image); err == nil {
+		desired.ImageID, imageEnv = id, env
+	} else {
+		m.logger.Debug("Failed to inspect image for drift check",
+			slog.String("image", desired.Image),
+			slog.String("error", err.Error()))
+	}
+
+	report := &DriftReport{
+		ServiceName: serviceName,
+		ContainerID: containerID,
+		Changes:     diffDrift(desired, actual, imageEnv),
+		CheckedAt:   time.Now(),
+	}
+	report.Drifted = len(report.Changes) > 0
+	if !fix || !report.Drifted {
+		return report, nil
+	}
+
+	if err := m.recreateContainer(ctx, serviceName); err != nil {
+		report.FixError = err.Error()
+		return report, nil
+	}
+	report.Fixed = true
+	m.logger.Info("Recreated drifted container",
+		slog.String("service", serviceName),
+		slog.Int("changes", len(report.Changes)))
+	return report, nil
+}
+
+// desiredState returns the configuration a container was created with
+// (caller holds m.mutex)
+func (m *Manager) desiredState(container *models.Container) driftState {
+	env := maps.Clone(container.Environment)
+	if env == nil {
+		env = map[string]string{}
+	}
+	maps.Copy(env, m.outbound.Environment(container.Environment))
+	maps.Copy(env, RuntimeOptionsFromLabels(container.Labels).Environment(container.Environment))
+
+	memory, _ := parseSize(m.config.Container.DefaultMemoryLimit)
+	cpus, _ := strconv.ParseFloat(m.config.Container.DefaultCPULimit, 64)
+	return driftState{
+		Image:    container.Image,
+		Env:      env,
+		Command:  slices.Clone(container.Command),
+		Memory:   memory,
+		NanoCPUs: int64(cpus * 1e9),
+	}
+}
+
+// inspectDriftState reads the configuration of a running container
+func (m *Manager) inspectDriftState(ctx context.Context, containerID string) (driftState, error) {
+	info, err := m.runtime.Inspect(ctx, containerID)
+	if err != nil {
+		return driftState{}, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	image := info.ImageName
+	if image == "" {
+		image = info.Config.Image
+	}
+	return driftState{
+		Image:    image,
+		ImageID:  info.Image,
+		Env:      parseEnvList(info.Config.Env),
+		Command:  info.Config.Cmd,
+		Memory:   info.HostConfig.Memory,
+		NanoCPUs: info.HostConfig.NanoCpus,
+	}, nil
+}
+
+// inspectImage returns the ID and default environment of a local image
+func (m *Manager) inspectImage(ctx context.Context, image string) (string, map[string]string, error) {
+	output, err := m.runtimeOutput(ctx, "image", "inspect", image)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	var inspected []struct {
+		ID     string `json:"Id"`
+		Config struct {
+			Env []string `json:"Env"`
+		} `json:"Config"`
+	}
+	if err := json.Unmarshal(output, &inspected); err != nil || len(inspected) == 0 {
+		return "", nil, fmt.Errorf("failed to parse inspect output of image %s", image)
+	}
+	return inspected[0].ID, parseEnvList(inspected[0].Config.Env), nil
+}
+
+// parseEnvList turns NAME=value entries into a map
+func parseEnvList(entries []string) map[string]string {
+	env := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// diffDrift lists the differences between the desired and actual state of a
+// container. Variables the image or the runtime set are not unexpected, and
+// limits and commands left to the defaults are not compared.
+func diffDrift(desired, actual driftState, imageEnv map[string]string) []DriftChange {
+	changes := []DriftChange{}
+	if desired.Image != actual.Image {
+		changes = append(changes, DriftChange{Field: "image", Change: DriftModified, Desired: desired.Image, Actual: actual.Image})
+	} else if desired.ImageID != "" && actual.ImageID != "" && imageDigest(desired.ImageID) != imageDigest(actual.ImageID) {
+		// The tag was pulled again and now points at another image
+		changes = append(changes, DriftChange{Field: "image_digest", Change: DriftModified,
+			Desired: imageDigest(desired.ImageID), Actual: imageDigest(actual.ImageID)})
+	}
+
+	if len(desired.Command) > 0 && !slices.Equal(desired.Command, actual.Command) {
+		changes = append(changes, DriftChange{Field: "command", Change: DriftModified,
+			Desired: strings.Join(desired.Command, " "), Actual: strings.Join(actual.Command, " ")})
+	}
+
+	if desired.Memory > 0 && desired.Memory != actual.Memory {
+		changes = append(changes, DriftChange{Field: "memory", Change: DriftModified,
+			Desired: strconv.FormatInt(desired.Memory, 10), Actual: strconv.FormatInt(actual.Memory, 10)})
+	}
+	if desired.NanoCPUs > 0 && desired.NanoCPUs != actual.NanoCPUs {
+		changes = append(changes, DriftChange{Field: "cpus", Change: DriftModified,
+			Desired: formatCPUs(desired.NanoCPUs), Actual: formatCPUs(actual.NanoCPUs)})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(desired.Env)) {
+		value, ok := actual.Env[name]
+		switch {
+		case !ok:
+			changes = append(changes, DriftChange{Field: "env", Key: name, Change: DriftMissing})
+		case value != desired.Env[name]:
+			changes = append(changes, DriftChange{Field: "env", Key: name, Change: DriftModified})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(actual.Env)) {
+		if _, ok := desired.Env[name]; ok || runtimeEnv[name] {
+			continue
+		}
+		if value, ok := imageEnv[name]; ok && value == actual.Env[name] {
+			continue
+		}
+		changes = append(changes, DriftChange{Field: "env", Key: name, Change: DriftUnexpected})
+	}
+	return changes
+}
+
+// imageDigest normalizes an image ID, which Docker prefixes with sha256:
+func imageDigest(id string) string {
+	return "sha256:" + strings.TrimPrefix(id, "sha256:")
+}
+
+// formatCPUs formats a CPU limit in nanocores as a number of cores
+func formatCPUs(nanoCPUs int64) string {
+	return strconv.FormatFloat(float64(nanoCPUs)/1e9, 'f', -1, 64)
+}
+
+// recreateContainer replaces the container of a service with one created
+// from its spec, keeping its name, slug and URL
+func (m *Manager) recreateContainer(ctx context.Context, serviceName string) error {
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	container, exists := m.containers[serviceName]
+	if !exists {
+		return fmt.Errorf("container %s not found", serviceName)
+	}
+	if m.isQuarantined(serviceName) || m.isCheckpointed(serviceName) || m.isStopped(serviceName) {
+		return fmt.Errorf("container %s is quarantined, checkpointed or stopped", serviceName)
+	}
+
+	container.Status = models.StatusStarting
+	container.UpdatedAt = time.Now()
+
+	if err := m.runtime.Remove(ctx, container.ID, true); err != nil {
+		container.Status = models.StatusError
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+
+	spec, err := m.buildCreateSpec(container)
+	var id string
+	if err == nil {
+		id, err = m.runtime.Create(ctx, spec)
+	}
+	if err != nil {
+		container.Status = models.StatusError
+		return fmt.Errorf("failed to create container: %w", err)
+	}
+	container.ID = id
+	m.saveState(ctx, container)
+
+	if err := m.waitForContainer(ctx, container.ID); err != nil {
+		container.Status = models.StatusError
+		return fmt.Errorf("container failed to start: %w", err)
+	}
+
+	m.applyBandwidth(ctx, container)
+
+	if container.Slug != "" {
+		containerIP, err := m.getContainerIP(ctx, container.ID)
+		if err != nil {
+			m.logger.Error("Failed to get container IP after recreate",
+				slog.String("service", serviceName),
+				slog.String("error", err.Error()))
+		} else if err := m.addContainerRoute(ctx, container, containerIP); err != nil {
+			m.logger.Error("Failed to update route after recreate",
+				slog.String("slug", container.Slug),
+				slog.String("service", serviceName),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	container.Status = models.StatusRunning
+	container.UpdatedAt = time.Now()
+
+	if instanceID, ok := container.Environment["MCP_INSTANCE_ID"]; ok {
+		if err := m.eventPublisher.PublishRunning(ctx, instanceID, container.ServiceName, container.ID, container.URL); err != nil {
+			m.logger.Warn("Failed to publish running status after recreate",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/egress"
+)
+
+// EgressAllowLabel holds the comma separated egress allow-list of an
+// instance whose DNS goes through the filtering resolver
+const EgressAllowLabel = "agentarea.io/egress-allow"
+
+// ErrEgressFilterDisabled is returned for an egress allow-list when the
+// filtering resolver is not enabled
+var ErrEgressFilterDisabled = errors.New("egress filtering is not enabled (EGRESS_DNS_FILTER)")
+
+// SetEgressAllow validates and records an egress allow-list in instance
+// labels, replacing any label of that name supplied with the spec. The
+// filtering resolver becomes the instance's only nameserver, so the list
+// cannot be combined with dns_servers.
+func SetEgressAllow(labels map[string]string, allow []string) (map[string]string, error) {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, EgressAllowLabel)
+	if len(allow) == 0 {
+		return labels, nil
+	}
+	normalized := make([]string, len(allow))
+	for i, pattern := range allow {
+		normalized[i] = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	}
+	if err := egress.ValidatePatterns(normalized); err != nil {
+		return labels, err
+	}
+	if labels[DNSServersLabel] != "" {
+		return labels, fmt.Errorf("egress_allow cannot be combined with dns_servers")
+	}
+	labels[EgressAllowLabel] = strings.Join(normalized, ",")
+	return labels, nil
+}
+
+// EgressAllowFromLabels reads the egress allow-list recorded in labels
+func EgressAllowFromLabels(labels map[string]string) []string {
+	if value := labels[EgressAllowLabel]; value != "" {
+		return strings.Split(value, ",")
+	}
+	return nil
+}
+
+// ParseEgressAllow reads json_spec.egress_allow
+func ParseEgressAllow(jsonSpec map[string]interface{}) []string {
+	var allow []string
+	if raw, ok := jsonSpec["egress_allow"].([]interface{}); ok {
+		for _, item := range raw {
+			if value, ok := item.(string); ok {
+				allow = append(allow, value)
+			}
+		}
+	}
+	return allow
+}
+
+// SetEgressFilter sends the DNS queries of instances with an egress
+// allow-list through the filtering resolver
+func (m *Manager) SetEgressFilter(filter *egress.Filter) {
+	m.egress = filter
+	filter.SetLookup(m.egressClient)
+}
+
+// setEgressAllow records an allow-list of a json_spec, which needs the
+// filtering resolver
+func (m *Manager) setEgressAllow(labels map[string]string, allow []string) (map[string]string, error) {
+	if len(allow) > 0 && m.egress == nil {
+		return labels, ErrEgressFilterDisabled
+	}
+	return SetEgressAllow(labels, allow)
+}
+
+// forgetEgress drops the query log of a deleted instance
+func (m *Manager) forgetEgress(serviceName string) {
+	if m.egress != nil {
+		m.egress.Forget(serviceName)
+	}
+}
+
+// applyEgress points a filtered instance's create spec at the resolver
+func (m *Manager) applyEgress(spec *createSpec, labels map[string]string) {
+	if m.egress == nil || labels[EgressAllowLabel] == "" {
+		return
+	}
+	spec.HostConfig.DNS = append(spec.HostConfig.DNS, m.egress.Address())
+}
+
+// egressClient finds the filtered container with the given IP on the MCP
+// network
+func (m *Manager) egressClient(ctx context.Context, ip string) (egress.Client, bool) {
+	m.mutex.RLock()
+	candidates := make(map[string]egress.Client)
+	for serviceName, container := range m.containers {
+		if allow := EgressAllowFromLabels(container.Labels); len(allow) > 0 && container.ID != "" {
+			candidates[container.ID] = egress.Client{Service: serviceName, Allow: allow}
+		}
+	}
+	m.mutex.RUnlock()
+
+	for containerID, client := range candidates {
+		// Dual-stack containers may query over either family
+		containerIPs, err := m.getContainerIPs(ctx, containerID)
+		if err != nil {
+			m.logger.Debug("Failed to get container IP for egress filtering",
+				slog.String("service", client.Service),
+				slog.String("error", err.Error()))
+			continue
+		}
+		if slices.Contains(containerIPs, ip) {
+			return client, true
+		}
+	}
+	return egress.Client{}, false
+}
@@ -0,0 +1,151 @@
+package container
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// settlePollInterval is how often Settle checks for in-flight operations
+const settlePollInterval = 250 * time.Millisecond
+
+// HandoffState is what a manager hands over to its replacement
+type HandoffState struct {
+	Containers []models.Container `json:"containers"`
+	Operations []Operation        `json:"operations"` // Still in flight when exported
+}
+
+// Operation is a container change in progress
+type Operation struct {
+	ServiceName string    `json:"service_name"`
+	Status      string    `json:"status"`
+	Since       time.Time `json:"since"`
+}
+
+// inFlight reports whether a container in the status is being created,
+// started or stopped
+func inFlight(status models.ContainerStatus) bool {
+	switch status {
+	case models.StatusValidating, models.StatusPulling, models.StatusStarting, models.StatusStopping:
+		return true
+	}
+	return false
+}
+
+// Operations returns the container operations in flight, including
+// creations queued for admission, sorted by service name
+func (m *Manager) Operations() []Operation {
+	operations := []Operation{}
+	m.mutex.RLock()
+	for _, container := range m.containers {
+		if inFlight(container.Status) {
+			operations = append(operations, Operation{
+				ServiceName: container.ServiceName,
+				Status:      string(container.Status),
+				Since:       container.UpdatedAt,
+			})
+		}
+	}
+	m.mutex.RUnlock()
+
+	m.admission.mu.Lock()
+	for _, item := range m.admission.queue {
+		operations = append(operations, Operation{ServiceName: item.ServiceName, Status: "queued", Since: item.QueuedAt})
+	}
+	m.admission.mu.Unlock()
+
+	slices.SortFunc(operations, func(a, b Operation) int { return strings.Compare(a.ServiceName, b.ServiceName) })
+	return operations
+}
+
+// PauseBackground stops the background loops (health checks and restarts,
+// scheduled restarts, warm pools, disk checks and route sweeps) from changing
+// containers while a replacement manager takes them over
+func (m *Manager) PauseBackground() {
+	m.paused.Store(true)
+}
+
+// ResumeBackground lets paused background loops run again from their next tick
+func (m *Manager) ResumeBackground() {
+	m.paused.Store(false)
+}
+
+// BackgroundPaused reports whether the background loops are paused
+func (m *Manager) BackgroundPaused() bool {
+	return m.paused.Load()
+}
+
+// Settle waits until no container operation is in flight, or ctx is done
+func (m *Manager) Settle(ctx context.Context) error {
+	ticker := time.NewTicker(settlePollInterval)
+	defer ticker.Stop()
+	for len(m.Operations()) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// ExportState snapshots the managed containers and the operations in flight
+func (m *Manager) ExportState() HandoffState {
+	operations := m.Operations()
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	state := HandoffState{Containers: make([]models.Container, 0, len(m.containers)), Operations: operations}
+	for _, name := range slices.Sorted(maps.Keys(m.containers)) {
+		container := *m.containers[name]
+		container.Labels = maps.Clone(container.Labels)
+		container.Environment = maps.Clone(container.Environment)
+		container.Command = slices.Clone(container.Command)
+		state.Containers = append(state.Containers, container)
+	}
+	return state
+}
+
+// ImportState fills in what discovery cannot tell about the containers a
+// previous manager handed over: when they were created and the environment
+// beyond their identity. It returns the handed over containers that were not
+// discovered, and logs the operations the previous manager left unfinished.
+func (m *Manager) ImportState(state HandoffState) (missing []string) {
+	defer m.flushState(context.Background())
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, handed := range state.Containers {
+		container, exists := m.containers[handed.ServiceName]
+		if !exists || container.ID != handed.ID {
+			missing = append(missing, handed.ServiceName)
+			continue
+		}
+		container.CreatedAt = handed.CreatedAt
+		if container.Environment == nil {
+			container.Environment = make(map[string]string, len(handed.Environment))
+		}
+		for key, value := range handed.Environment {
+			if _, ok := container.Environment[key]; !ok {
+				container.Environment[key] = value
+			}
+		}
+		if len(container.Command) == 0 {
+			container.Command = handed.Command
+		}
+		m.saveState(context.Background(), container)
+	}
+
+	for _, operation := range state.Operations {
+		m.logger.Warn("Container operation interrupted by handoff",
+			slog.String("service", operation.ServiceName),
+			slog.String("status", operation.Status),
+			slog.Time("since", operation.Since))
+	}
+	return missing
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,33 +16,51 @@ import (
 
 // HealthChecker handles health checks for MCP containers
 type HealthChecker struct {
-	logger     *slog.Logger
-	config     *config.Config
-	httpClient *http.Client
+	logger  *slog.Logger
+	config  *config.Config
+	client  *HealthClient
+	runtime Runtime
 }
 
-// NewHealthChecker creates a new health checker
-func NewHealthChecker(cfg *config.Config, logger *slog.Logger) *HealthChecker {
+// NewHealthChecker creates a new health checker. Invalid HEALTH_CHECK_*
+// settings are logged and the defaults used; main rejects them at startup.
+func NewHealthChecker(cfg *config.Config, runtime Runtime, logger *slog.Logger) *HealthChecker {
+	client, err := NewHealthClient(cfg)
+	if err != nil {
+		logger.Error("Invalid health check client settings, using defaults",
+			slog.String("error", err.Error()))
+		defaults := *cfg
+		defaults.HealthCheck = config.HealthCheckConfig{}
+		client, _ = NewHealthClient(&defaults)
+	}
 	return &HealthChecker{
-		logger: logger,
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		logger:  logger,
+		config:  cfg,
+		client:  client,
+		runtime: runtime,
 	}
 }
 
-// HealthCheckResult represents the result of a health check
+// HealthCheckResult represents the result of a health check. Like
+// Kubernetes probes, it separates liveness (the container runs and its
+// server answers; failing it gets the container restarted) from readiness
+// (the server answers its health path successfully; failing it takes the
+// instance out of routing). Healthy means both.
 type HealthCheckResult struct {
 	ContainerID   string                 `json:"container_id"`
 	ServiceName   string                 `json:"service_name"`
 	Healthy       bool                   `json:"healthy"`
+	Live          bool                   `json:"live"`
+	Ready         bool                   `json:"ready"`
 	Status        models.ContainerStatus `json:"status"`
 	HTTPReachable bool                   `json:"http_reachable"`
 	ResponseTime  time.Duration          `json:"response_time"`
 	Error         string                 `json:"error,omitempty"`
 	Timestamp     time.Time              `json:"timestamp"`
 	Details       map[string]interface{} `json:"details,omitempty"`
+
+	probed   bool // The server was probed over HTTP
+	starting bool // Within the window of the container's startup probe
 }
 
 // PerformHealthCheck performs a comprehensive health check on a container
@@ -64,7 +82,7 @@ func (h *HealthChecker) PerformHealthCheck(ctx context.Context, container *model
 
 	// Check container health based on real-time status
 	containerHealthy := h.checkContainerStatusRealTime(realTimeStatus)
-	result.Healthy = containerHealthy
+	result.Live = containerHealthy
 
 	if !containerHealthy {
 		result.Error = "Container is not running"
@@ -79,9 +97,7 @@ func (h *HealthChecker) PerformHealthCheck(ctx context.Context, container *model
 			h.logger.Warn("Failed to get container IP for health check",
 				slog.String("container", container.Name),
 				slog.String("error", err.Error()))
-			// If we can't get IP, skip HTTP health check but consider container healthy since it's running
-			result.Healthy = true
-			result.HTTPReachable = false
+			// If we can't get IP, skip HTTP health check but consider container live since it's running
 			result.Error = "Could not determine container IP for health check"
 		} else {
 			// Get the container's internal exposed port
@@ -90,25 +106,28 @@ func (h *HealthChecker) PerformHealthCheck(ctx context.Context, container *model
 				h.logger.Warn("Failed to get container exposed port for health check",
 					slog.String("container", container.Name),
 					slog.String("error", err.Error()))
-				// Skip HTTP health check but consider container healthy since it's running
-				result.Healthy = true
-				result.HTTPReachable = false
+				// Skip HTTP health check but consider container live since it's running
 				result.Error = "Could not determine container exposed port for health check"
 			} else {
 				// Construct direct URL to container using internal port
-				directURL := fmt.Sprintf("http://%s:%d", containerIP, internalPort)
+				healthPath := HealthPath(container.Labels)
+				directURL := h.client.URL(containerIP, internalPort, healthPath)
 
-				httpHealthy, responseTime, err := h.checkHTTPEndpoint(ctx, directURL)
-				result.HTTPReachable = httpHealthy
+				statusCode, responseTime, err := h.checkHTTPEndpoint(ctx, directURL, container.Environment)
+				result.probed = true
 				result.ResponseTime = responseTime
+				// A server that answers at all is live; ready when it answers
+				// the probe successfully
+				result.Live = err == nil
+				result.Ready = err == nil && probeReady(statusCode, healthPath != "")
+				result.HTTPReachable = result.Ready
 
 				if err != nil {
 					result.Error = err.Error()
-					result.Healthy = false
-				} else if !httpHealthy {
-					result.Error = "HTTP endpoint not reachable"
-					result.Healthy = false
+				} else if !result.Ready {
+					result.Error = fmt.Sprintf("HTTP endpoint not ready: status %d", statusCode)
 				}
+				result.Details["status_code"] = statusCode
 
 				result.Details["direct_http_endpoint"] = directURL
 				result.Details["internal_port"] = internalPort
@@ -119,6 +138,7 @@ func (h *HealthChecker) PerformHealthCheck(ctx context.Context, container *model
 		// Always include the proxy URL for reference
 		result.Details["proxy_url"] = container.URL
 	}
+	result.Healthy = result.Live && result.Ready
 
 	// Add additional container details
 	result.Details["container_port"] = container.Port
@@ -129,7 +149,8 @@ func (h *HealthChecker) PerformHealthCheck(ctx context.Context, container *model
 	h.logger.Info("Health check completed",
 		slog.String("container", container.Name),
 		slog.Bool("healthy", result.Healthy),
-		slog.Bool("http_reachable", result.HTTPReachable),
+		slog.Bool("live", result.Live),
+		slog.Bool("ready", result.Ready),
 		slog.Duration("response_time", result.ResponseTime))
 
 	return result, nil
@@ -141,17 +162,14 @@ func (h *HealthChecker) getRealTimeContainerStatus(ctx context.Context, containe
 		return models.StatusError
 	}
 
-	cmd := exec.CommandContext(ctx, h.config.Container.Runtime, "inspect", container.ID, "--format", "{{.State.Status}}")
-	output, err := cmd.CombinedOutput()
+	info, err := h.runtime.Inspect(ctx, container.ID)
 	if err != nil {
 		h.logger.Error("Failed to get real-time container status",
 			slog.String("container", container.Name),
 			slog.String("error", err.Error()))
 		return models.StatusError
 	}
-
-	podmanStatus := strings.TrimSpace(string(output))
-	return h.mapPodmanStatus(podmanStatus)
+	return h.mapPodmanStatus(info.State.Status)
 }
 
 // mapPodmanStatus maps Podman status to our container status
@@ -183,27 +201,36 @@ func (h *HealthChecker) checkContainerStatusRealTime(status models.ContainerStat
 	}
 }
 
-// checkHTTPEndpoint checks if the HTTP endpoint is reachable
-func (h *HealthChecker) checkHTTPEndpoint(ctx context.Context, url string) (bool, time.Duration, error) {
+// checkHTTPEndpoint requests url of an instance with environment env and
+// returns the status code it answers
+func (h *HealthChecker) checkHTTPEndpoint(ctx context.Context, url string, env map[string]string) (int, time.Duration, error) {
 	start := time.Now()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return false, 0, fmt.Errorf("failed to create HTTP request: %w", err)
+		return 0, 0, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	resp, err := h.httpClient.Do(req)
+	resp, err := h.client.Do(req, env)
 	responseTime := time.Since(start)
 
 	if err != nil {
-		return false, responseTime, fmt.Errorf("HTTP request failed: %w", err)
+		return 0, responseTime, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Consider 2xx and 3xx status codes as healthy
-	healthy := resp.StatusCode >= 200 && resp.StatusCode < 400
+	return resp.StatusCode, responseTime, nil
+}
 
-	return healthy, responseTime, nil
+// probeReady reports whether a readiness probe answered successfully. A
+// declared health path must answer 2xx or 3xx. Without one the server's root
+// is probed, which many MCP servers answer with 404 or 405, so any status
+// below 500 shows the server is accepting requests.
+func probeReady(statusCode int, healthPath bool) bool {
+	if healthPath {
+		return statusCode >= 200 && statusCode < 400
+	}
+	return statusCode < http.StatusInternalServerError
 }
 
 // PerformBulkHealthCheck performs health checks on multiple containers
@@ -317,48 +344,37 @@ func (h *HealthChecker) GetHealthSummary(ctx context.Context, containers []*mode
 	return summary, nil
 }
 
-// getContainerIP retrieves the IP address of a container
+// getContainerIP retrieves the IP address of a container, preferring the
+// mcp-network and, on a dual-stack network, the NETWORK_IP_FAMILY family
 func (h *HealthChecker) getContainerIP(ctx context.Context, containerID string) (string, error) {
-	cmd := exec.CommandContext(ctx, h.config.Container.Runtime, "inspect", containerID, "--format", "{{.NetworkSettings.IPAddress}}")
-	output, err := cmd.CombinedOutput()
+	info, err := h.runtime.Inspect(ctx, containerID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get container IP: %w", err)
 	}
-
-	ip := strings.TrimSpace(string(output))
-	if ip == "" {
-		// Try alternative format for newer podman versions
-		cmd = exec.CommandContext(ctx, h.config.Container.Runtime, "inspect", containerID, "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}")
-		output, err = cmd.CombinedOutput()
-		if err != nil {
-			return "", fmt.Errorf("failed to get container IP (alternative): %w", err)
-		}
-		ip = strings.TrimSpace(string(output))
-	}
-
-	if ip == "" {
-		return "", fmt.Errorf("container IP address is empty")
+	addrs, err := info.NetworkSettings.addresses(h.config.Traefik.Network, true)
+	if err != nil {
+		return "", err
 	}
-
-	return ip, nil
+	return addrs.pick(h.config.Traefik.IPFamily), nil
 }
 
 // getContainerExposedPort retrieves the first exposed HTTP port from a container
 func (h *HealthChecker) getContainerExposedPort(ctx context.Context, containerID string) (int, error) {
-	cmd := exec.CommandContext(ctx, h.config.Container.Runtime, "inspect", containerID, "--format", "{{range $port, $config := .Config.ExposedPorts}}{{$port}} {{end}}")
-	output, err := cmd.CombinedOutput()
+	info, err := h.runtime.Inspect(ctx, containerID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get container exposed ports: %w", err)
 	}
-
-	portsStr := strings.TrimSpace(string(output))
-	if portsStr == "" {
+	if len(info.Config.ExposedPorts) == 0 {
 		// No exposed ports found, try common HTTP ports
 		return h.guessHTTPPort(ctx, containerID)
 	}
 
-	// Parse exposed ports (format: "80/tcp 443/tcp")
-	ports := strings.Fields(portsStr)
+	// Exposed ports are keyed like "80/tcp"; sort them so the pick is stable
+	ports := make([]string, 0, len(info.Config.ExposedPorts))
+	for port := range info.Config.ExposedPorts {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
 	for _, port := range ports {
 		if strings.HasSuffix(port, "/tcp") {
 			portNumStr := strings.TrimSuffix(port, "/tcp")
@@ -376,14 +392,12 @@ func (h *HealthChecker) getContainerExposedPort(ctx context.Context, containerID
 // guessHTTPPort tries to guess the HTTP port based on common patterns
 func (h *HealthChecker) guessHTTPPort(ctx context.Context, containerID string) (int, error) {
 	// Get container image to make educated guesses
-	cmd := exec.CommandContext(ctx, h.config.Container.Runtime, "inspect", containerID, "--format", "{{.Config.Image}}")
-	output, err := cmd.CombinedOutput()
+	info, err := h.runtime.Inspect(ctx, containerID)
 	if err != nil {
 		return 80, nil // Default to port 80
 	}
 
-	image := strings.TrimSpace(string(output))
-	imageLower := strings.ToLower(image)
+	imageLower := strings.ToLower(info.Config.Image)
 
 	// Common HTTP port mappings
 	if strings.Contains(imageLower, "nginx") {
@@ -0,0 +1,128 @@
+package container
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/outbound"
+)
+
+// HealthClient makes the HTTP requests of instance health checks, route
+// probes and warm-ups. Its connections are pooled across check cycles, it
+// speaks TLS to instances serving HTTPS and it adds the configured headers.
+type HealthClient struct {
+	client        *http.Client
+	scheme        string
+	headers       http.Header
+	sendAuthToken bool
+}
+
+// NewHealthClient builds the health check client from HEALTH_CHECK_*
+// settings; requests time out after HEALTH_CHECK_TIMEOUT, or
+// RUNTIME_HTTP_TIMEOUT if unset
+func NewHealthClient(cfg *config.Config) (*HealthClient, error) {
+	settings := cfg.HealthCheck
+	scheme := strings.ToLower(settings.Scheme)
+	switch scheme {
+	case "":
+		scheme = "http"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("invalid HEALTH_CHECK_SCHEME %q: use http or https", settings.Scheme)
+	}
+
+	headers := make(http.Header)
+	for _, header := range settings.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid HEALTH_CHECK_HEADERS entry %q: expected Name: value", header)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+
+	// Instances are probed directly, never through the corporate proxy
+	transport := outbound.Direct()
+	// Every instance is its own host: keep an idle connection to each rather
+	// than capping the total, which would redial most instances every cycle
+	transport.MaxIdleConns = 0
+	if settings.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: settings.DialTimeout, KeepAlive: transport.IdleConnTimeout}).DialContext
+	}
+	if settings.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	}
+	if settings.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = settings.IdleConnTimeout
+	}
+	if scheme == "https" || settings.CAFile != "" || settings.ServerName != "" || settings.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			ServerName:         settings.ServerName,
+			InsecureSkipVerify: settings.InsecureSkipVerify,
+		}
+		if settings.CAFile != "" {
+			pem, err := os.ReadFile(settings.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read HEALTH_CHECK_CA_FILE: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("HEALTH_CHECK_CA_FILE %s holds no PEM certificates", settings.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	timeout := settings.Timeout
+	if timeout <= 0 {
+		timeout = cfg.Container.HTTPTimeout
+	}
+	return &HealthClient{
+		client:        &http.Client{Timeout: timeout, Transport: transport},
+		scheme:        scheme,
+		headers:       headers,
+		sendAuthToken: settings.SendAuthToken,
+	}, nil
+}
+
+// URL returns the address of path on an instance listening at host:port
+func (c *HealthClient) URL(host string, port int, path string) string {
+	return c.scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + path
+}
+
+// Do sends req with the configured headers and, with
+// HEALTH_CHECK_SEND_AUTH_TOKEN, the instance's bearer token from env. Headers
+// already set on req win.
+func (c *HealthClient) Do(req *http.Request, env map[string]string) (*http.Response, error) {
+	c.addHeaders(req.Header, env)
+	return c.client.Do(req)
+}
+
+// addHeaders adds the configured headers and the instance's bearer token to
+// header, keeping those already set
+func (c *HealthClient) addHeaders(header http.Header, env map[string]string) {
+	for name, values := range c.headers {
+		if header.Get(name) == "" {
+			header[name] = append([]string(nil), values...)
+		}
+	}
+	if token := env[AuthTokenEnv]; c.sendAuthToken && token != "" && header.Get("Authorization") == "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// unbounded returns a client sharing the pooled connections whose requests
+// are only bounded by their context, for calls that may take far longer
+// than a health check
+func (c *HealthClient) unbounded() *http.Client {
+	return &http.Client{Transport: c.client.Transport}
+}
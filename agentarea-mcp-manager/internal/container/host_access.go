@@ -0,0 +1,267 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Host devices and sockets passed through to an instance, as comma separated
+// absolute paths
+const (
+	DevicesLabel     = "agentarea.io/devices"
+	HostSocketsLabel = "agentarea.io/host-sockets"
+)
+
+// ErrHostAccessDenied is returned when an instance asks for a host device or
+// socket that is not allow-listed or not approved for its workspace
+var ErrHostAccessDenied = errors.New("host access denied")
+
+// forbiddenSockets give control over the host and are never passed through,
+// whatever the allow-list says
+var forbiddenSockets = []string{"docker.sock", "podman.sock", "containerd.sock", "crio.sock", "dockershim.sock"}
+
+// HostAccess lists the host devices and sockets of an instance
+type HostAccess struct {
+	Devices     []string `json:"devices,omitempty"`
+	HostSockets []string `json:"host_sockets,omitempty"`
+}
+
+// IsZero reports whether no device or socket is listed
+func (a HostAccess) IsZero() bool {
+	return len(a.Devices) == 0 && len(a.HostSockets) == 0
+}
+
+// Validate checks that every path is a clean absolute path of the right kind
+func (a HostAccess) Validate() error {
+	for _, device := range a.Devices {
+		if err := validateHostPath(device); err != nil {
+			return fmt.Errorf("invalid device: %w", err)
+		}
+		if !strings.HasPrefix(device, "/dev/") {
+			return fmt.Errorf("invalid device %q: devices must be under /dev", device)
+		}
+	}
+	for _, socket := range a.HostSockets {
+		if err := validateHostPath(socket); err != nil {
+			return fmt.Errorf("invalid host socket: %w", err)
+		}
+		if slices.Contains(forbiddenSockets, path.Base(socket)) {
+			return fmt.Errorf("host socket %q controls the container runtime and cannot be passed through", socket)
+		}
+	}
+	return nil
+}
+
+// validateHostPath rejects relative, unclean and separator-carrying paths
+func validateHostPath(p string) error {
+	switch {
+	case !path.IsAbs(p):
+		return fmt.Errorf("%q is not an absolute path", p)
+	case path.Clean(p) != p:
+		return fmt.Errorf("%q is not a clean path", p)
+	case strings.ContainsAny(p, ",: \t\n"):
+		return fmt.Errorf("%q contains a separator character", p)
+	}
+	return nil
+}
+
+// ParseHostAccess reads json_spec.devices and json_spec.host_sockets
+func ParseHostAccess(jsonSpec map[string]interface{}) HostAccess {
+	list := func(key string) []string {
+		var values []string
+		if raw, ok := jsonSpec[key].([]interface{}); ok {
+			for _, item := range raw {
+				if value, ok := item.(string); ok {
+					values = append(values, value)
+				}
+			}
+		}
+		return values
+	}
+	return HostAccess{Devices: list("devices"), HostSockets: list("host_sockets")}
+}
+
+// SetHostAccess records host access in instance labels, replacing any labels
+// of that name supplied with the spec
+func SetHostAccess(labels map[string]string, access HostAccess) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, DevicesLabel)
+	delete(labels, HostSocketsLabel)
+	if len(access.Devices) > 0 {
+		labels[DevicesLabel] = strings.Join(access.Devices, ",")
+	}
+	if len(access.HostSockets) > 0 {
+		labels[HostSocketsLabel] = strings.Join(access.HostSockets, ",")
+	}
+	return labels
+}
+
+// HostAccessFromLabels reads the host access recorded in labels
+func HostAccessFromLabels(labels map[string]string) HostAccess {
+	split := func(value string) []string {
+		if value == "" {
+			return nil
+		}
+		return strings.Split(value, ",")
+	}
+	return HostAccess{Devices: split(labels[DevicesLabel]), HostSockets: split(labels[HostSocketsLabel])}
+}
+
+// applyTo passes the devices and sockets through at the same paths
+func (a HostAccess) applyTo(spec *createSpec) {
+	host := &spec.HostConfig
+	for _, device := range a.Devices {
+		host.Devices = append(host.Devices, createDevice{PathOnHost: device, PathInContainer: device, CgroupPermissions: "rwm"})
+	}
+	for _, socket := range a.HostSockets {
+		host.Binds = append(host.Binds, socket+":"+socket)
+	}
+}
+
+// SetHostAccessPolicy sets the policy checked when instances ask for host
+// devices or sockets; without one they are denied
+func (m *Manager) SetHostAccessPolicy(policy *HostAccessPolicy) {
+	m.hostAccess = policy
+}
+
+// HostAccessPolicy decides which instances may use host devices and sockets.
+// A path must be on the operator's allow-list and approved by an admin for
+// the instance's workspace.
+type HostAccessPolicy struct {
+	mu             sync.RWMutex
+	allowedDevices []string
+	allowedSockets []string
+	runtimeSocket  string                // Never allowed, whatever its name
+	grants         map[string]HostAccess // By workspace
+}
+
+// NewHostAccessPolicy creates a policy with the configured allow-lists and no
+// approvals. Allow-list entries may use path.Match patterns.
+func NewHostAccessPolicy(allowedDevices, allowedSockets []string) *HostAccessPolicy {
+	return &HostAccessPolicy{
+		allowedDevices: allowedDevices,
+		allowedSockets: allowedSockets,
+		grants:         make(map[string]HostAccess),
+	}
+}
+
+// SetRuntimeSocket refuses the runtime's API socket even when the allow-list
+// covers it and its name is not a well-known runtime socket. Call it before
+// the policy is used.
+func (p *HostAccessPolicy) SetRuntimeSocket(socket string) {
+	p.runtimeSocket = socket
+}
+
+// CheckSocket returns an error wrapping ErrHostAccessDenied unless the
+// manager itself may connect to socket, for URL instances served over a
+// Unix socket. The socket must be allow-listed; no workspace grant is needed
+// since it is not passed into a container.
+func (p *HostAccessPolicy) CheckSocket(socket string) error {
+	if p == nil {
+		return fmt.Errorf("%w: host sockets are not enabled", ErrHostAccessDenied)
+	}
+	return p.allowed(HostAccess{HostSockets: []string{socket}})
+}
+
+// AllowList returns the configured allow-lists
+func (p *HostAccessPolicy) AllowList() HostAccess {
+	return HostAccess{Devices: p.allowedDevices, HostSockets: p.allowedSockets}
+}
+
+// Grant approves host access for a workspace, replacing its previous grant.
+// Every path must be valid and allow-listed; an empty grant revokes.
+func (p *HostAccessPolicy) Grant(workspaceID string, access HostAccess) error {
+	if workspaceID == "" {
+		return fmt.Errorf("workspace_id is required")
+	}
+	if err := p.allowed(access); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if access.IsZero() {
+		delete(p.grants, workspaceID)
+	} else {
+		p.grants[workspaceID] = access
+	}
+	return nil
+}
+
+// Grants returns the approvals by workspace
+func (p *HostAccessPolicy) Grants() map[string]HostAccess {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	grants := make(map[string]HostAccess, len(p.grants))
+	for workspaceID, access := range p.grants {
+		grants[workspaceID] = access
+	}
+	return grants
+}
+
+// Check returns an error wrapping ErrHostAccessDenied unless every path of
+// access is allow-listed and approved for the workspace
+func (p *HostAccessPolicy) Check(workspaceID string, access HostAccess) error {
+	if access.IsZero() {
+		return nil
+	}
+	if p == nil {
+		return fmt.Errorf("%w: host devices and sockets are not enabled", ErrHostAccessDenied)
+	}
+	if err := p.allowed(access); err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	grant, ok := p.grants[workspaceID]
+	p.mu.RUnlock()
+	for _, device := range access.Devices {
+		if !ok || !slices.Contains(grant.Devices, device) {
+			return fmt.Errorf("%w: device %s is not approved for workspace %q", ErrHostAccessDenied, device, workspaceID)
+		}
+	}
+	for _, socket := range access.HostSockets {
+		if !ok || !slices.Contains(grant.HostSockets, socket) {
+			return fmt.Errorf("%w: host socket %s is not approved for workspace %q", ErrHostAccessDenied, socket, workspaceID)
+		}
+	}
+	return nil
+}
+
+// allowed validates access and checks it against the allow-lists
+func (p *HostAccessPolicy) allowed(access HostAccess) error {
+	if err := access.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrHostAccessDenied, err)
+	}
+	for _, device := range access.Devices {
+		if !matchesAny(p.allowedDevices, device) {
+			return fmt.Errorf("%w: device %s is not on the allow-list", ErrHostAccessDenied, device)
+		}
+	}
+	for _, socket := range access.HostSockets {
+		if p.runtimeSocket != "" && socket == p.runtimeSocket {
+			return fmt.Errorf("%w: host socket %s controls the container runtime", ErrHostAccessDenied, socket)
+		}
+		if !matchesAny(p.allowedSockets, socket) {
+			return fmt.Errorf("%w: host socket %s is not on the allow-list", ErrHostAccessDenied, socket)
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether p matches one of the path.Match patterns
+func matchesAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, p); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
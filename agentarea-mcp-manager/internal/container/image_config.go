@@ -0,0 +1,161 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Image configuration validation issue codes
+const (
+	CodePortNotExposed = "port_not_exposed"
+	CodeAmbiguousPort  = "ambiguous_port"
+	CodeMissingCommand = "missing_command"
+)
+
+// Where the port of an instance without one in its spec comes from
+const (
+	PortSourceLabel   = "label"        // mcp.port
+	PortSourceExposed = "exposed_port" // The only port the image exposes
+	PortSourceDefault = "default"      // 8000
+)
+
+// DefaultContainerPort is the port of instances whose spec and image name none
+const DefaultContainerPort = 8000
+
+// SpecDefaults are the values an instance gets from its image where the
+// spec sets none
+type SpecDefaults struct {
+	Port       int      `json:"port"`
+	PortSource string   `json:"port_source"`
+	Command    []string `json:"command,omitempty"` // Entrypoint and CMD of the image
+}
+
+// imageMetadata combines the mcp.* labels of an image with its exposed ports
+// and entrypoint. It returns nil if the image declares none of them.
+func imageMetadata(config *imageConfig) (*models.ImageMetadata, []string) {
+	metadata, problems := ParseImageMetadata(config.Labels)
+	if metadata == nil {
+		if len(config.ExposedPorts) == 0 && len(config.Entrypoint) == 0 && len(config.Cmd) == 0 {
+			return nil, nil
+		}
+		metadata = &models.ImageMetadata{}
+	}
+	metadata.ExposedPorts = config.ExposedPorts
+	metadata.Entrypoint = config.Entrypoint
+	metadata.Command = config.Cmd
+	return metadata, problems
+}
+
+// DefaultPort returns the port of an instance without one in its spec: the
+// mcp.port label, else the only TCP port the image exposes, else 8000
+func DefaultPort(metadata *models.ImageMetadata) (int, string) {
+	switch {
+	case metadata == nil:
+	case metadata.Port != 0:
+		return metadata.Port, PortSourceLabel
+	case len(metadata.ExposedPorts) == 1:
+		return metadata.ExposedPorts[0], PortSourceExposed
+	}
+	return DefaultContainerPort, PortSourceDefault
+}
+
+// ImageMetadata returns what a local image declares in its mcp.* labels and
+// configuration, or nil if it declares nothing. With pull set, a missing image is pulled
+// first; otherwise a missing image has no metadata.
+func (m *Manager) ImageMetadata(ctx context.Context, image string, pull bool) (*models.ImageMetadata, error) {
+	if exists, _ := m.validator.imageExistsLocally(ctx, image); !exists {
+		if !pull {
+			return nil, nil
+		}
+		if output, err := commandCombinedOutput(ctx, m.config.Container.PullTimeout, m.config.Container.Runtime, "pull", "--quiet", image); err != nil {
+			return nil, fmt.Errorf("failed to pull image %s: %w: %s", image, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	config, err := m.validator.inspectImageConfig(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	metadata, problems := imageMetadata(config)
+	if len(problems) > 0 {
+		m.logger.Warn("Ignoring invalid MCP image labels",
+			slog.String("image", image),
+			slog.Any("problems", problems))
+	}
+	return metadata, nil
+}
+
+// checkImageMetadata surfaces what a local image declares in the result,
+// fills in the port and command the spec leaves to the image and checks the
+// spec against them. specPort is 0 when the spec sets no port.
+func (v *ContainerValidator) checkImageMetadata(ctx context.Context, result *ValidationResult, image string, specPort int, specCommand []string, env map[string]string) {
+	if !result.ImageExists {
+		return
+	}
+	config, err := v.inspectImageConfig(ctx, image)
+	if err != nil {
+		v.logger.Warn("Failed to inspect image", slog.String("image", image), slog.String("error", err.Error()))
+		return
+	}
+	metadata, problems := imageMetadata(config)
+	for _, problem := range problems {
+		result.addWarning(CodeInvalidImageLabel, "Ignoring invalid image label "+problem, "Fix the label in the image build")
+	}
+	result.ImageMetadata = metadata
+
+	defaults := &SpecDefaults{}
+	if specPort == 0 {
+		defaults.Port, defaults.PortSource = DefaultPort(metadata)
+	}
+	if len(specCommand) == 0 {
+		defaults.Command = append(append([]string{}, config.Entrypoint...), config.Cmd...)
+		if len(defaults.Command) == 0 {
+			result.addError(CodeMissingCommand, fmt.Sprintf("Image %s has no entrypoint or command", image),
+				"Set json_spec.cmd to the program that starts the MCP server")
+		}
+	}
+	if defaults.Port != 0 || len(defaults.Command) > 0 {
+		result.Defaults = defaults
+	}
+	if metadata == nil {
+		return
+	}
+
+	if metadata.Transport == TransportStdio {
+		result.addError(CodeUnsupportedTransport, fmt.Sprintf("Image %s serves MCP over stdio", image),
+			"Use an image serving "+TransportStreamableHTTP+" or "+TransportSSE+", or wrap the server in an HTTP bridge")
+	}
+	if missing := MissingEnv(metadata, env); len(missing) > 0 {
+		result.addError(CodeMissingRequiredEnv, "Required environment variables not set: "+strings.Join(missing, ", "),
+			"Set them in the environment, through the template defaults or as secret references")
+	}
+
+	exposed := formatPorts(metadata.ExposedPorts)
+	switch {
+	case specPort != 0 && metadata.Port != 0 && specPort != metadata.Port:
+		result.addWarning(CodePortMismatch, fmt.Sprintf("Port %d differs from port %d declared by image %s", specPort, metadata.Port, image),
+			"Leave the port unset to use the image's")
+	case specPort != 0 && len(metadata.ExposedPorts) > 0 && !slices.Contains(metadata.ExposedPorts, specPort):
+		result.addWarning(CodePortNotExposed, fmt.Sprintf("Port %d is not exposed by image %s, which exposes %s", specPort, image, exposed),
+			"Check the port the server listens on; the instance is unreachable if nothing listens on it")
+	case specPort == 0 && defaults.PortSource == PortSourceDefault && len(metadata.ExposedPorts) > 1 &&
+		!slices.Contains(metadata.ExposedPorts, DefaultContainerPort):
+		result.addWarning(CodeAmbiguousPort, fmt.Sprintf("Image %s exposes %s; port %d will be used", image, exposed, DefaultContainerPort),
+			"Set the port the MCP server listens on")
+	}
+}
+
+// formatPorts joins ports for messages
+func formatPorts(ports []int) string {
+	formatted := make([]string, len(ports))
+	for i, port := range ports {
+		formatted[i] = strconv.Itoa(port)
+	}
+	return strings.Join(formatted, ", ")
+}
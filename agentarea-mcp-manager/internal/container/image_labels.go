@@ -0,0 +1,148 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Labels MCP images set to describe themselves, so they can be deployed from
+// an image reference alone
+const (
+	ImageTransportLabel   = "mcp.transport"
+	ImagePortLabel        = "mcp.port"
+	ImageHealthPathLabel  = "mcp.health-path"
+	ImageRequiredEnvLabel = "mcp.required-env"
+)
+
+// MCP transports an image can declare
+const (
+	TransportStreamableHTTP = "streamable-http"
+	TransportSSE            = "sse"
+	TransportStdio          = "stdio"
+)
+
+// HealthPathLabel records the path health checks request on an instance
+const HealthPathLabel = "agentarea.io/health-path"
+
+// Image label validation issue codes
+const (
+	CodeInvalidImageLabel    = "invalid_image_label"
+	CodeUnsupportedTransport = "unsupported_transport"
+	CodeMissingRequiredEnv   = "missing_required_env"
+	CodePortMismatch         = "port_mismatch"
+)
+
+// ErrImageRequirements is returned when an instance does not meet what its
+// image declares it needs
+var ErrImageRequirements = errors.New("image requirements not met")
+
+// ParseImageMetadata reads the mcp.* labels of an image. It returns nil if
+// the image sets none, and a problem for every label with an invalid value,
+// which is then ignored.
+func ParseImageMetadata(labels map[string]string) (*models.ImageMetadata, []string) {
+	metadata := &models.ImageMetadata{}
+	var problems []string
+	found := false
+
+	if value, ok := labels[ImageTransportLabel]; ok {
+		found = true
+		switch transport := strings.ToLower(strings.TrimSpace(value)); transport {
+		case TransportStreamableHTTP, TransportSSE, TransportStdio:
+			metadata.Transport = transport
+		default:
+			problems = append(problems, fmt.Sprintf("%s=%q: use %s, %s or %s", ImageTransportLabel, value, TransportStreamableHTTP, TransportSSE, TransportStdio))
+		}
+	}
+	if value, ok := labels[ImagePortLabel]; ok {
+		found = true
+		if port, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && port >= 1 && port <= 65535 {
+			metadata.Port = port
+		} else {
+			problems = append(problems, fmt.Sprintf("%s=%q: not a port number", ImagePortLabel, value))
+		}
+	}
+	if value, ok := labels[ImageHealthPathLabel]; ok {
+		found = true
+		if path := strings.TrimSpace(value); strings.HasPrefix(path, "/") && !strings.ContainsAny(path, " \t\r\n?#") {
+			metadata.HealthPath = path
+		} else {
+			problems = append(problems, fmt.Sprintf("%s=%q: must be an absolute URL path", ImageHealthPathLabel, value))
+		}
+	}
+	if value, ok := labels[ImageRequiredEnvLabel]; ok {
+		found = true
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !envKeyPattern.MatchString(name) {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a variable name", ImageRequiredEnvLabel, name))
+				continue
+			}
+			metadata.RequiredEnv = append(metadata.RequiredEnv, name)
+		}
+		sort.Strings(metadata.RequiredEnv)
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return metadata, problems
+}
+
+// MissingEnv returns the variables the image requires that env does not set
+func MissingEnv(metadata *models.ImageMetadata, env map[string]string) []string {
+	if metadata == nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range metadata.RequiredEnv {
+		if value, ok := env[name]; !ok || value == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// CheckImageRequirements fails for images the manager cannot route to and
+// for required variables env does not set
+func CheckImageRequirements(metadata *models.ImageMetadata, env map[string]string) error {
+	if metadata == nil {
+		return nil
+	}
+	if metadata.Transport == TransportStdio {
+		return fmt.Errorf("%w: the image serves MCP over stdio; the manager routes HTTP (%s or %s)", ErrImageRequirements, TransportStreamableHTTP, TransportSSE)
+	}
+	if missing := MissingEnv(metadata, env); len(missing) > 0 {
+		return fmt.Errorf("%w: required environment variables not set: %s", ErrImageRequirements, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// SetHealthPath records the health check path of an instance in labels,
+// replacing any label of that name supplied with the spec. An empty path
+// keeps the default of checking /.
+func SetHealthPath(labels map[string]string, path string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, HealthPathLabel)
+	if path != "" && path != "/" {
+		labels[HealthPathLabel] = path
+	}
+	return labels
+}
+
+// HealthPath returns the path health checks request on an instance
+func HealthPath(labels map[string]string) string {
+	if path := labels[HealthPathLabel]; strings.HasPrefix(path, "/") {
+		return path
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// ErrLifecycleBlocked is returned when stopping, starting or restarting a
+// container that is quarantined or stopped at a checkpoint
+var ErrLifecycleBlocked = errors.New("container is quarantined or checkpointed")
+
+// isStopped reports whether a service was stopped on request and must stay
+// stopped until started again (caller holds m.mutex)
+func (m *Manager) isStopped(serviceName string) bool {
+	return m.stopped[serviceName]
+}
+
+// StopContainer drains and stops a container, keeping its route, secrets and
+// state so StartContainer can bring it back with the same URL. Health checks,
+// auto restarts and drift repair leave it alone until then.
+func (m *Manager) StopContainer(ctx context.Context, serviceName string) (*models.Container, error) {
+	if err := m.checkLifecycle(serviceName); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.DrainInstance(ctx, serviceName, m.config.Container.ShutdownTimeout, true); err != nil {
+		m.logger.Warn("Failed to drain connections before stop",
+			slog.String("service", serviceName),
+			slog.String("error", err.Error()))
+	}
+	defer m.ResumeInstance(serviceName)
+
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	container, exists := m.containers[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("container %s not found", serviceName)
+	}
+
+	container.Status = models.StatusStopping
+	container.UpdatedAt = time.Now()
+	if err := m.stopGracefully(ctx, container); err != nil {
+		container.Status = models.StatusError
+		return nil, err
+	}
+
+	m.stopped[serviceName] = true
+	container.Status = models.StatusStopped
+	container.UpdatedAt = time.Now()
+	m.saveState(ctx, container)
+
+	m.logger.Info("Stopped container",
+		slog.String("service", serviceName),
+		slog.String("container_id", container.ID))
+
+	if instanceID := container.Environment["MCP_INSTANCE_ID"]; instanceID != "" {
+		if err := m.eventPublisher.PublishStopped(ctx, instanceID, serviceName); err != nil {
+			m.logger.Warn("Failed to publish stopped status",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+	return container, nil
+}
+
+// StartContainer starts a stopped container again and re-adds its route.
+// Starting a running container does nothing.
+func (m *Manager) StartContainer(ctx context.Context, serviceName string) (*models.Container, error) {
+	if err := m.checkLifecycle(serviceName); err != nil {
+		return nil, err
+	}
+
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	container, exists := m.containers[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("container %s not found", serviceName)
+	}
+	if !m.stopped[serviceName] && m.getRealTimeContainerStatus(ctx, container) == models.StatusRunning {
+		return container, nil
+	}
+
+	delete(m.stopped, serviceName)
+	if err := m.restartContainer(ctx, container); err != nil {
+		// Stay stopped rather than have auto restarts retry it
+		m.stopped[serviceName] = true
+		return nil, err
+	}
+	m.saveState(ctx, container)
+	return container, nil
+}
+
+// RestartContainer drains, stops and starts a container in place, as a
+// scheduled restart does. A stopped container is started.
+func (m *Manager) RestartContainer(ctx context.Context, serviceName, reason string) (*models.Container, error) {
+	if err := m.checkLifecycle(serviceName); err != nil {
+		return nil, err
+	}
+	container, err := m.GetContainer(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.cycleContainer(ctx, container, reason); err != nil {
+		return nil, err
+	}
+
+	defer m.flushState(ctx)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stopped[serviceName] {
+		delete(m.stopped, serviceName)
+		m.saveState(ctx, container)
+	}
+	return container, nil
+}
+
+// cycleContainer publishes the restart, drains the container and restarts it
+// in place
+func (m *Manager) cycleContainer(ctx context.Context, container *models.Container, reason string) error {
+	if instanceID := container.Environment["MCP_INSTANCE_ID"]; instanceID != "" {
+		if err := m.eventPublisher.PublishRestarting(ctx, instanceID, container.ServiceName, reason); err != nil {
+			m.logger.Warn("Failed to publish restarting status",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	// Let in-flight requests finish; anything still open when the shutdown
+	// timeout expires is closed with a warning event
+	if _, err := m.DrainInstance(ctx, container.ServiceName, m.config.Container.ShutdownTimeout, true); err != nil {
+		m.logger.Warn("Failed to drain connections before restart",
+			slog.String("service", container.ServiceName),
+			slog.String("error", err.Error()))
+	}
+	defer m.ResumeInstance(container.ServiceName)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Record the attempt first so a failing restart is not retried every tick
+	m.restartedAt[container.ServiceName] = time.Now()
+
+	if err := m.stopGracefully(ctx, container); err != nil {
+		return err
+	}
+	return m.restartContainer(ctx, container)
+}
+
+// stopGracefully stops a container, which the runtime kills if it is still
+// running after the shutdown timeout (caller holds m.mutex)
+func (m *Manager) stopGracefully(ctx context.Context, container *models.Container) error {
+	if err := m.runtime.Stop(ctx, container.ID, m.config.Container.ShutdownTimeout); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	return nil
+}
+
+// checkLifecycle rejects lifecycle operations on quarantined and
+// checkpointed containers, which are released with their own endpoints
+func (m *Manager) checkLifecycle(serviceName string) error {
+	if m.isQuarantined(serviceName) || m.isCheckpointed(serviceName) {
+		return fmt.Errorf("%w: %s", ErrLifecycleBlocked, serviceName)
+	}
+	return nil
+}
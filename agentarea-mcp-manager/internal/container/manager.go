@@ -5,19 +5,28 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
-	"os/exec"
-	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/egress"
 	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/metrics"
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/naming"
+	"github.com/agentarea/mcp-manager/internal/outbound"
+	"github.com/agentarea/mcp-manager/internal/redisconn"
+	"github.com/agentarea/mcp-manager/internal/store"
+	"github.com/agentarea/mcp-manager/internal/supervisor"
 )
 
 // Manager manages container lifecycle for MCP servers
@@ -27,11 +36,40 @@ type Manager struct {
 	containerHealth map[string]*HealthCheckResult // Track health status
 	mutex           sync.RWMutex
 	logger          *slog.Logger
-	traefikManager  *TraefikManager
-	routeManager    interface{} // proxy.RouteManager set at runtime
+	runtime         Runtime         // Runtime API client, or the CLI
+	traefikManager  *TraefikManager // nil in proxy-only routing
+	routeManager    interface{}     // proxy.RouteManager set at runtime
+	routing         RoutingStatus   // Set by ConfigureRouting before initialization
 	validator       *ContainerValidator
 	healthChecker   *HealthChecker
 	eventPublisher  *events.EventPublisher
+	restartedAt     map[string]time.Time // Last scheduled restart per service
+	stopped         map[string]bool      // Services stopped on request, guarded by mutex
+	healthHistory   *healthHistory       // Per-minute health check counts for SLOs
+	templateSLO     func(templateID string) *SLO
+	templateEnv     func(templateID string) []models.MCPEnvVar
+	reserved        func(serviceName string) bool
+	specs           map[string]*SpecRecord // Submitted spec per service, guarded by mutex
+	hostAccess      *HostAccessPolicy      // Host devices and sockets; nil denies all
+	outbound        *outbound.Settings     // Corporate proxy passed to instances; nil for none
+	egress          *egress.Filter         // DNS filtering of allow-listed instances; nil disables
+	sloMu           sync.Mutex
+	sloAlerts       map[string]string      // Alert reason per service with a firing SLO alert
+	resources       *resourceTracker       // Memory and CPU pressure per service
+	warmPools       *warmPools             // Pre-started containers per template
+	checkpoints     *checkpoints           // Checkpointed containers per service
+	admission       *admission             // Host capacity reservations per service
+	ports           *PortAllocator         // Published host ports; nil disables publishing
+	disk            *diskMonitor           // Disk usage samples
+	quarantines     *quarantines           // Isolated instances and anomaly counters
+	crashLoops      *crashLoops            // Recent health check failures per service
+	probes          map[string]*probeState // Consecutive probe failures per service, guarded by mutex
+	instanceStore   store.InstanceStore    // Adopted legacy containers; nil keeps them in memory
+	stateStore      store.ContainerStore   // Persisted containers; nil keeps them in memory
+	stateEncrypted  bool                   // stateStore encrypts environment values
+	stateWrites     stateWrites            // Container records waiting to be persisted
+	supervisor      *supervisor.Supervisor // Restarts background loops; nil runs them bare
+	paused          atomic.Bool            // Background loops skip their work during a handoff
 	healthCtx       context.Context
 	healthCancel    context.CancelFunc
 }
@@ -39,8 +77,29 @@ type Manager struct {
 // NewManager creates a new container manager with Traefik integration
 func NewManager(cfg *config.Config, logger *slog.Logger) *Manager {
 	traefikManager := NewTraefikManager(cfg, logger)
-	healthChecker := NewHealthChecker(cfg, logger)
-	eventPublisher := events.NewEventPublisher(cfg.Redis.URL, logger)
+	runtime, err := NewRuntime(cfg, logger)
+	if err != nil {
+		// main rejects invalid settings at startup; tools fall back to the CLI
+		logger.Error("Invalid container runtime API configuration, using the runtime CLI", slog.String("error", err.Error()))
+		runtime = &execRuntime{
+			runtime:        cfg.Container.Runtime,
+			commandTimeout: cfg.Container.CommandTimeout,
+			createTimeout:  cfg.Container.CreateTimeout,
+		}
+	}
+	healthChecker := NewHealthChecker(cfg, runtime, logger)
+	ports, err := NewPortAllocator(cfg.Container.HostPortRange)
+	if err != nil {
+		// main rejects invalid settings at startup; tools run without publishing
+		logger.Error("Invalid host port range, host port publishing disabled", slog.String("error", err.Error()))
+	}
+	redisOptions, err := redisconn.Parse(cfg.Redis)
+	if err != nil {
+		// main rejects invalid settings at startup; tools fall back to REDIS_URL
+		logger.Error("Invalid Redis configuration, connecting with REDIS_URL only", slog.String("error", err.Error()))
+		redisOptions = redisconn.FromURL(cfg.Redis.URL)
+	}
+	eventPublisher := events.NewEventPublisher(redisOptions, logger)
 
 	// Create context for health monitoring
 	healthCtx, healthCancel := context.WithCancel(context.Background())
@@ -50,9 +109,23 @@ func NewManager(cfg *config.Config, logger *slog.Logger) *Manager {
 		containers:      make(map[string]*models.Container),
 		containerHealth: make(map[string]*HealthCheckResult),
 		logger:          logger,
+		runtime:         runtime,
 		traefikManager:  traefikManager,
 		healthChecker:   healthChecker,
 		eventPublisher:  eventPublisher,
+		restartedAt:     make(map[string]time.Time),
+		stopped:         make(map[string]bool),
+		healthHistory:   newHealthHistory(),
+		sloAlerts:       make(map[string]string),
+		resources:       newResourceTracker(),
+		warmPools:       newWarmPools(),
+		checkpoints:     newCheckpoints(),
+		admission:       newAdmission(),
+		ports:           ports,
+		disk:            newDiskMonitor(),
+		quarantines:     newQuarantines(),
+		crashLoops:      newCrashLoops(),
+		probes:          make(map[string]*probeState),
 		healthCtx:       healthCtx,
 		healthCancel:    healthCancel,
 	}
@@ -67,11 +140,29 @@ func NewManager(cfg *config.Config, logger *slog.Logger) *Manager {
 func (m *Manager) Initialize(ctx context.Context) error {
 	m.logger.Info("Initializing container manager")
 
+	// Instances are created on the network, so it must exist before any loop or discovery starts
+	if err := m.EnsureNetwork(ctx); err != nil {
+		m.logger.Error("Failed to set up container network", slog.String("error", err.Error()))
+		return err
+	}
+
 	// Start health monitoring in background
 	m.logger.Info("Starting health monitoring...")
-	go m.startHealthMonitoring()
+	m.runLoop("health-monitor", true, m.startHealthMonitoring)
 	m.logger.Info("Health monitoring started")
 
+	// Run restart policies in background
+	m.runLoop("restart-scheduler", false, m.startRestartScheduler)
+
+	// Keep warm pools of templates that ask for them filled
+	m.runLoop("warm-pools", false, m.startWarmPools)
+
+	// Sample container and storage disk usage
+	m.runLoop("disk-monitor", false, m.startDiskMonitoring)
+
+	// Drop routes of vanished containers and follow changed container IPs
+	m.runLoop("route-sweeper", false, m.startRouteSweeper)
+
 	// Discover existing containers
 	m.logger.Info("Discovering existing containers...")
 	if err := m.discoverContainers(ctx); err != nil {
@@ -80,6 +171,17 @@ func (m *Manager) Initialize(ctx context.Context) error {
 	}
 	m.logger.Info("Container discovery completed")
 
+	if err := m.restoreAdoptions(ctx); err != nil {
+		m.logger.Warn("Failed to restore adopted instances", slog.String("error", err.Error()))
+	}
+	if m.config.Container.AdoptLegacyOnStartup {
+		adoptions := m.AdoptLegacyContainers(ctx, AdoptOptions{
+			WorkspaceID: m.config.Container.AdoptLegacyWorkspace,
+			Register:    true,
+		})
+		m.logger.Info("Adopted legacy containers", slog.Int("count", len(adoptions)))
+	}
+
 	// Synchronize with Core API to handle pending instances
 	m.logger.Info("Starting Core API synchronization...")
 	if err := m.syncWithCoreAPI(ctx); err != nil {
@@ -103,6 +205,26 @@ func (m *Manager) Initialize(ctx context.Context) error {
 
 // CreateContainer creates a new container from a template
 func (m *Manager) CreateContainer(ctx context.Context, req models.CreateContainerRequest) (*models.Container, error) {
+	// Substitute ${env.NAME} and ${instance.FIELD} in the command arguments
+	vars := NewCommandVars(req.Environment["MCP_INSTANCE_ID"], req.ServiceName, req.Port, req.Environment[WorkspaceIDEnv], req.Environment)
+	command, err := ExpandCommand(req.Command, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+
+	// Reserve host capacity before locking; admission may wait for room
+	if err := m.reserveCapacity(ctx, req.ServiceName, m.defaultReservation(), AdmissionOptions{}, nil); err != nil {
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			m.releaseCapacity(req.ServiceName)
+			m.ports.Release(req.ServiceName)
+		}
+	}()
+
+	defer m.flushState(ctx)
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -119,6 +241,11 @@ func (m *Manager) CreateContainer(ctx context.Context, req models.CreateContaine
 		return nil, fmt.Errorf("maximum container limit reached (%d)", m.config.Container.MaxContainers)
 	}
 
+	// Publish the requested host port, if any
+	if err := m.assignHostPort(req.ServiceName, req.Labels); err != nil {
+		return nil, err
+	}
+
 	// Generate slug for consistent URL routing
 	slug := generateSlug(req.ServiceName)
 
@@ -136,31 +263,30 @@ func (m *Manager) CreateContainer(ctx context.Context, req models.CreateContaine
 		UpdatedAt:   time.Now(),
 		Labels:      req.Labels,
 		Environment: req.Environment,
+		Command:     command,
 	}
 
-	// Build runtime run command
-	args := m.buildPodmanRunArgs(container)
-
-	// Execute runtime run
-	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, args...)
-	output, err := cmd.CombinedOutput()
+	// Create and start the container through the runtime
+	spec, err := m.buildCreateSpec(container)
+	var id string
+	if err == nil {
+		id, err = m.runtime.Create(ctx, spec)
+	}
 	if err != nil {
 		container.Status = models.StatusError
 		m.logger.Error("Failed to create container",
 			slog.String("container", containerName),
-			slog.String("error", err.Error()),
-			slog.String("output", string(output)))
+			slog.String("error", err.Error()))
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
-
-	// Get container ID from output
-	container.ID = strings.TrimSpace(string(output))
+	container.ID = id
 
 	// Wait for container to be running
 	if err := m.waitForContainer(ctx, container.ID); err != nil {
 		container.Status = models.StatusError
 		return nil, fmt.Errorf("container failed to start: %w", err)
 	}
+	events.RecordStep(req.Environment["MCP_INSTANCE_ID"], req.ServiceName, events.StepStarted, container.ID)
 
 	// Get container IP for Traefik routing
 	containerIP, err := m.getContainerIP(ctx, container.ID)
@@ -172,17 +298,17 @@ func (m *Manager) CreateContainer(ctx context.Context, req models.CreateContaine
 		containerIP = "127.0.0.1" // fallback
 	}
 
-	// Add route for the container using the slug (proxy or Traefik)
-	if err := m.addRoute(ctx, slug, containerIP, req.Port); err != nil {
-		m.logger.Error("Failed to add route",
-			slog.String("slug", slug),
-			slog.String("service", req.ServiceName),
-			slog.String("error", err.Error()))
-		// Continue - container is created but routing may not work
-	}
+	// Shape traffic before the container receives requests
+	m.applyBandwidth(ctx, container)
 
 	container.Status = models.StatusRunning
 	m.containers[req.ServiceName] = container
+	created = true
+	m.saveState(ctx, container)
+
+	// Add route for the container using the slug (proxy or Traefik) once
+	// its server answers
+	m.publishRoute(ctx, container, containerIP)
 
 	m.logger.Info("Container created successfully with slug",
 		slog.String("container", containerName),
@@ -232,14 +358,12 @@ func (m *Manager) GetContainerStatus(ctx context.Context, serviceName string) (m
 	}
 
 	// Get real-time status from runtime
-	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, "inspect", container.ID, "--format", "{{.State.Status}}")
-	output, err := cmd.CombinedOutput()
+	info, err := m.runtime.Inspect(ctx, container.ID)
 	if err != nil {
 		return models.StatusError, fmt.Errorf("failed to get container status: %w", err)
 	}
 
-	podmanStatus := strings.TrimSpace(string(output))
-	status := m.mapPodmanStatus(podmanStatus)
+	status := m.mapPodmanStatus(info.State.Status)
 
 	// Update cached status
 	m.mutex.RUnlock()
@@ -273,6 +397,8 @@ func (m *Manager) PerformHealthCheck(ctx context.Context, serviceName string) (m
 		"container_id":     healthResult.ContainerID,
 		"container_status": string(healthResult.Status),
 		"healthy":          healthResult.Healthy,
+		"live":             healthResult.Live,
+		"ready":            healthResult.Ready,
 		"http_reachable":   healthResult.HTTPReachable,
 		"response_time_ms": healthResult.ResponseTime.Milliseconds(),
 		"timestamp":        healthResult.Timestamp,
@@ -284,6 +410,27 @@ func (m *Manager) PerformHealthCheck(ctx context.Context, serviceName string) (m
 		result["error"] = healthResult.Error
 	}
 
+	if pressure := m.ResourcePressure(serviceName); pressure != nil {
+		if healthResult.Details == nil {
+			healthResult.Details = make(map[string]interface{})
+		}
+		healthResult.Details["resource_pressure"] = pressure
+	}
+
+	if disk, ok := m.DiskUsage(serviceName); ok {
+		if healthResult.Details == nil {
+			healthResult.Details = make(map[string]interface{})
+		}
+		healthResult.Details["disk"] = disk
+	}
+
+	if quarantine, ok := m.QuarantineStatus(serviceName); ok {
+		if healthResult.Details == nil {
+			healthResult.Details = make(map[string]interface{})
+		}
+		healthResult.Details["quarantine"] = quarantine
+	}
+
 	if healthResult.Details != nil {
 		result["details"] = healthResult.Details
 	}
@@ -293,6 +440,7 @@ func (m *Manager) PerformHealthCheck(ctx context.Context, serviceName string) (m
 
 // DeleteContainer stops and removes a container
 func (m *Manager) DeleteContainer(ctx context.Context, serviceName string) error {
+	defer m.flushState(ctx)
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -304,21 +452,17 @@ func (m *Manager) DeleteContainer(ctx context.Context, serviceName string) error
 	container.Status = models.StatusStopping
 
 	// Stop container
-	stopCmd := exec.CommandContext(ctx, m.config.Container.Runtime, "stop", container.ID)
-	if output, err := stopCmd.CombinedOutput(); err != nil {
+	if err := m.runtime.Stop(ctx, container.ID, 0); err != nil {
 		m.logger.Error("Failed to stop container",
 			slog.String("container", container.Name),
-			slog.String("error", err.Error()),
-			slog.String("output", string(output)))
+			slog.String("error", err.Error()))
 	}
 
 	// Remove container
-	rmCmd := exec.CommandContext(ctx, m.config.Container.Runtime, "rm", container.ID)
-	if output, err := rmCmd.CombinedOutput(); err != nil {
+	if err := m.runtime.Remove(ctx, container.ID, false); err != nil {
 		m.logger.Error("Failed to remove container",
 			slog.String("container", container.Name),
-			slog.String("error", err.Error()),
-			slog.String("output", string(output)))
+			slog.String("error", err.Error()))
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
 
@@ -334,6 +478,22 @@ func (m *Manager) DeleteContainer(ctx context.Context, serviceName string) error
 	}
 
 	delete(m.containers, serviceName)
+	delete(m.specs, serviceName)
+	delete(m.stopped, serviceName)
+	m.forgetState(serviceName)
+	m.healthHistory.forget(serviceName)
+	m.forgetResources(serviceName)
+	m.releaseCapacity(serviceName)
+	m.ports.Release(serviceName)
+	m.forgetDisk(serviceName)
+	m.forgetQuarantine(serviceName)
+	m.crashLoops.forget(serviceName)
+	delete(m.probes, serviceName)
+	m.forgetEgress(serviceName)
+	m.forgetAdoption(ctx, container)
+	m.checkpoints.mu.Lock()
+	delete(m.checkpoints.services, serviceName)
+	m.checkpoints.mu.Unlock()
 
 	m.logger.Info("Container deleted successfully",
 		slog.String("container", container.Name),
@@ -372,105 +532,74 @@ func (m *Manager) getRunningCountUnsafe() int {
 
 // discoverContainers discovers existing containers managed by this service
 func (m *Manager) discoverContainers(ctx context.Context) error {
-	// List all containers with our prefix
-	// Use {{json .}} to get JSON lines output which is compatible across more versions
-	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, "ps", "-a", "--format", "{{json .}}")
-	output, err := cmd.CombinedOutput()
+	// Restored containers are persisted once discovery completes
+	defer m.flushState(ctx)
+
+	// List all containers; ours carry the name prefix
+	listed, err := m.runtime.List(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	if len(output) == 0 {
-		return nil
-	}
-
-	var podmanContainers []map[string]interface{}
-
-	// Parse NDJSON (newline delimited JSON)
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		var container map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &container); err != nil {
-			m.logger.Warn("Failed to parse container line", slog.String("line", line), slog.String("error", err.Error()))
-			continue
+	// Load Traefik configuration to find existing slugs
+	var traefikConfig *TraefikConfig
+	if m.traefikManager != nil {
+		traefikConfig, err = m.traefikManager.LoadConfig()
+		if err != nil {
+			m.logger.Warn("Failed to load Traefik config for slug discovery",
+				slog.String("error", err.Error()))
+			traefikConfig = nil
 		}
-		podmanContainers = append(podmanContainers, container)
 	}
 
-	// Load Traefik configuration to find existing slugs
-	traefikConfig, err := m.traefikManager.LoadConfig()
-	if err != nil {
-		m.logger.Warn("Failed to load Traefik config for slug discovery",
-			slog.String("error", err.Error()))
-		traefikConfig = nil
-	}
+	// Containers with persisted state are restored from it as they were
+	states := m.loadState(ctx)
+	seen := make(map[string]bool)
 
 	prefix := m.config.Container.NamePrefix
-	for _, pc := range podmanContainers {
-		names, ok := pc["Names"].([]interface{})
-		if !ok || len(names) == 0 {
+	for _, listedContainer := range listed {
+		if len(listedContainer.Names) == 0 {
 			continue
 		}
 
-		containerName, ok := names[0].(string)
-		if !ok || !strings.HasPrefix(containerName, prefix) {
+		containerName := listedContainer.Names[0]
+		if !strings.HasPrefix(containerName, prefix) || m.isWarmPoolName(containerName) {
 			continue
 		}
-
-		// Extract service name from container environment (original name)
-		// First try to get original service name from environment variable
-		originalServiceName := ""
-		if inspectCmd := exec.CommandContext(ctx, m.config.Container.Runtime, "inspect", pc["Id"].(string), "--format", "{{.Config.Env}}"); inspectCmd != nil {
-			if inspectOutput, err := inspectCmd.CombinedOutput(); err == nil {
-				envStr := string(inspectOutput)
-				if strings.Contains(envStr, "MCP_SERVICE_NAME=") {
-					// Extract service name from environment variables
-					if idx := strings.Index(envStr, "MCP_SERVICE_NAME="); idx != -1 {
-						serviceNameStr := envStr[idx+len("MCP_SERVICE_NAME="):]
-						if spaceIdx := strings.Index(serviceNameStr, " "); spaceIdx != -1 {
-							serviceNameStr = serviceNameStr[:spaceIdx]
-						}
-						// Remove any quotes that might be present
-						serviceNameStr = strings.Trim(serviceNameStr, "\"'")
-						if serviceNameStr != "" {
-							originalServiceName = serviceNameStr
-						}
-					}
-				}
+		containerID := listedContainer.ID
+
+		if state, ok := states[containerID]; ok {
+			if container, ok := m.containerFromState(ctx, state, m.mapPodmanStatus(listedContainer.State)); ok {
+				m.containers[container.ServiceName] = container
+				m.holdCapacity(container.ServiceName)
+				m.holdHostPort(container)
+				seen[container.ServiceName] = true
+
+				m.logger.Info("Restored container from persisted state",
+					slog.String("name", containerName),
+					slog.String("service", container.ServiceName),
+					slog.String("slug", container.Slug),
+					slog.String("desired_state", state.DesiredState),
+					slog.String("status", string(container.Status)))
+				continue
 			}
 		}
 
+		// The original service name and port are in the container environment
+		var env map[string]string
+		if info, err := m.runtime.Inspect(ctx, containerID); err == nil {
+			env = parseEnvList(info.Config.Env)
+		}
+
 		// Fallback to sanitized name if we can't find the original
-		serviceName := originalServiceName
+		serviceName := env["MCP_SERVICE_NAME"]
 		if serviceName == "" {
 			serviceName = strings.TrimPrefix(containerName, prefix)
 		}
 
-		containerID := pc["Id"].(string)
-
-		// Get container port from inspect
 		port := 8000 // Default port
-		if inspectCmd := exec.CommandContext(ctx, m.config.Container.Runtime, "inspect", containerID, "--format", "{{.Config.Env}}"); inspectCmd != nil {
-			if inspectOutput, err := inspectCmd.CombinedOutput(); err == nil {
-				envStr := string(inspectOutput)
-				if strings.Contains(envStr, "MCP_CONTAINER_PORT=") {
-					// Extract port from environment variables
-					if idx := strings.Index(envStr, "MCP_CONTAINER_PORT="); idx != -1 {
-						portStr := envStr[idx+len("MCP_CONTAINER_PORT="):]
-						if spaceIdx := strings.Index(portStr, " "); spaceIdx != -1 {
-							portStr = portStr[:spaceIdx]
-						}
-						if portStr != "" {
-							if p, err := strconv.Atoi(portStr); err == nil {
-								port = p
-							}
-						}
-					}
-				}
-			}
+		if p, err := strconv.Atoi(env["MCP_CONTAINER_PORT"]); err == nil {
+			port = p
 		}
 
 		// Try to find existing slug from Traefik configuration
@@ -488,18 +617,24 @@ func (m *Manager) discoverContainers(ctx context.Context) error {
 			Name:        containerName,
 			ServiceName: serviceName,
 			Slug:        slug,
-			Image:       pc["Image"].(string),
-			Status:      m.mapPodmanStatus(pc["State"].(string)),
+			Image:       listedContainer.Image,
+			Status:      m.mapPodmanStatus(listedContainer.State),
 			Port:        port,
 			URL:         fmt.Sprintf("%s/mcp/%s", m.config.Traefik.ProxyHost, slug),
 			Host:        m.config.Traefik.ProxyHost,
 			CreatedAt:   time.Now(), // We don't have exact creation time
 			UpdatedAt:   time.Now(),
+			Labels:      listedContainer.Labels,
+			Environment: identityEnv(env),
 		}
 
 		// Store container using the original service name for lookup
 		// This ensures health checks can find containers by their original name
 		m.containers[serviceName] = container
+		m.holdCapacity(serviceName)
+		m.holdHostPort(container)
+		m.saveState(ctx, container)
+		seen[serviceName] = true
 
 		m.logger.Info("Discovered existing container with slug",
 			slog.String("name", containerName),
@@ -509,6 +644,7 @@ func (m *Manager) discoverContainers(ctx context.Context) error {
 			slog.String("status", string(container.Status)))
 	}
 
+	m.pruneState(states, seen)
 	return nil
 }
 
@@ -537,47 +673,106 @@ func (m *Manager) findExistingSlugFromTraefik(serviceName string, config *Traefi
 	return ""
 }
 
-// buildPodmanRunArgs builds the arguments for podman run command
-func (m *Manager) buildPodmanRunArgs(container *models.Container) []string {
-	args := []string{"run", "-d"}
+// SetRuntime replaces the runtime client that creates, inspects and stops
+// containers
+func (m *Manager) SetRuntime(runtime Runtime) {
+	m.runtime = runtime
+	m.healthChecker.runtime = runtime
+}
 
-	// Add name
-	args = append(args, "--name", container.Name)
+// SetOutbound passes the corporate proxy and CA bundle to every instance
+// started from now on
+func (m *Manager) SetOutbound(settings *outbound.Settings) {
+	m.outbound = settings
+}
 
-	// Add network (important for Traefik discovery)
-	args = append(args, "--network", m.config.Traefik.Network)
+// buildCreateSpec builds the runtime create spec of a container
+func (m *Manager) buildCreateSpec(container *models.Container) (*createSpec, error) {
+	spec := &createSpec{
+		Name:  container.Name,
+		Image: container.Image,
+		// A custom command overrides the image's default CMD
+		Cmd: container.Command,
+		// Network is important for Traefik discovery
+		HostConfig: createHostConfig{NetworkMode: m.config.Traefik.Network},
+	}
 
 	// No port mapping needed - Traefik will handle routing via path-based routing
-	// The container will expose its internal port and Traefik will proxy to it
+	// The container will expose its internal port and Traefik will proxy to it.
+	// Only instances that asked for a host port publish one.
+	m.publishHostPort(spec, container.Labels, container.Port)
 
 	// Add environment variables
-	for key, value := range container.Environment {
-		args = append(args, "-e", fmt.Sprintf("%s=%s", key, value))
+	for _, key := range slices.Sorted(maps.Keys(container.Environment)) {
+		spec.Env = append(spec.Env, key+"="+container.Environment[key])
 	}
 
-	// Add labels for automatic service discovery
-	for key, value := range container.Labels {
-		args = append(args, "--label", fmt.Sprintf("%s=%s", key, value))
+	// Add labels for automatic service discovery, and attribution under the
+	// label keys the cost tools are configured with
+	spec.Labels = maps.Clone(container.Labels)
+	if costLabels := CostLabels(container.Labels, m.config.Container.CostLabelAliases); len(costLabels) > 0 {
+		if spec.Labels == nil {
+			spec.Labels = make(map[string]string, len(costLabels))
+		}
+		maps.Copy(spec.Labels, costLabels)
 	}
 
-	// Add default resource limits
-	if m.config.Container.DefaultMemoryLimit != "" {
-		args = append(args, "--memory", m.config.Container.DefaultMemoryLimit)
+	// Timezone, locale, ulimits and sysctls recorded in the labels
+	RuntimeOptionsFromLabels(container.Labels).applyTo(spec, container.Environment)
+
+	// Nameservers, search domains and /etc/hosts entries
+	DNSOptionsFromLabels(container.Labels).applyTo(spec)
+
+	// Size-limited tmpfs for instances that asked for a scratch volume
+	if scratch := ScratchFromLabels(container.Labels); scratch != nil {
+		scratch.applyTo(spec)
 	}
 
-	if m.config.Container.DefaultCPULimit != "" {
-		args = append(args, "--cpus", m.config.Container.DefaultCPULimit)
+	// The filtering resolver for instances with an egress allow-list
+	m.applyEgress(spec, container.Labels)
+
+	// Corporate proxy variables and CA bundle
+	proxyEnv := m.outbound.Environment(container.Environment)
+	for _, name := range slices.Sorted(maps.Keys(proxyEnv)) {
+		spec.Env = append(spec.Env, name+"="+proxyEnv[name])
+	}
+	if bundle := m.outbound.Bundle(); bundle != "" {
+		spec.HostConfig.Binds = append(spec.HostConfig.Binds, bundle+":"+outbound.ContainerCABundle+":ro")
 	}
 
-	// Add image
-	args = append(args, container.Image)
+	// Host devices and sockets, checked against the host access policy at creation
+	HostAccessFromLabels(container.Labels).applyTo(spec)
 
-	// Add custom command if specified (this overrides the container's default CMD)
-	if len(container.Command) > 0 {
-		args = append(args, container.Command...)
+	// Default resource limits and the writable layer quota (requires a
+	// storage driver with quota support)
+	if err := m.applyResourceLimits(spec, m.diskQuota(container)); err != nil {
+		return nil, err
 	}
+	return spec, nil
+}
 
-	return args
+// applyResourceLimits adds the default memory and CPU limits and a disk quota
+// to a create spec
+func (m *Manager) applyResourceLimits(spec *createSpec, diskQuota string) error {
+	host := &spec.HostConfig
+	if limit := m.config.Container.DefaultMemoryLimit; limit != "" {
+		memory, err := parseSize(limit)
+		if err != nil {
+			return fmt.Errorf("invalid DEFAULT_MEMORY_LIMIT: %w", err)
+		}
+		host.Memory = memory
+	}
+	if limit := m.config.Container.DefaultCPULimit; limit != "" {
+		cpus, err := strconv.ParseFloat(limit, 64)
+		if err != nil || cpus < 0 {
+			return fmt.Errorf("invalid DEFAULT_CPU_LIMIT %q", limit)
+		}
+		host.NanoCPUs = int64(cpus * 1e9)
+	}
+	if diskQuota != "" {
+		host.StorageOpt = map[string]string{"size": diskQuota}
+	}
+	return nil
 }
 
 // waitForContainer waits for a container to be running
@@ -593,13 +788,12 @@ func (m *Manager) waitForContainer(ctx context.Context, containerID string) erro
 		case <-timeout:
 			return fmt.Errorf("timeout waiting for container to start")
 		case <-ticker.C:
-			cmd := exec.CommandContext(ctx, m.config.Container.Runtime, "inspect", containerID, "--format", "{{.State.Status}}")
-			output, err := cmd.CombinedOutput()
+			info, err := m.runtime.Inspect(ctx, containerID)
 			if err != nil {
 				continue
 			}
 
-			status := strings.TrimSpace(string(output))
+			status := info.State.Status
 			if status == "running" {
 				return nil
 			}
@@ -626,6 +820,30 @@ func (m *Manager) mapPodmanStatus(podmanStatus string) models.ContainerStatus {
 	}
 }
 
+// GroupLabel names the instance group an instance belongs to
+const GroupLabel = "agentarea.io/group"
+
+// Template labels record which catalog template, and which version of it, an
+// instance was created from
+const (
+	TemplateLabel        = "agentarea.io/template"
+	TemplateVersionLabel = "agentarea.io/template-version"
+	// TrackTemplateLabel opts an instance into automatic rolling upgrades
+	// when its template changes
+	TrackTemplateLabel = "agentarea.io/track-template"
+)
+
+// specLabels extracts user labels from an instance json_spec
+func specLabels(jsonSpec map[string]interface{}) map[string]string {
+	result := make(map[string]string)
+	if raw, ok := jsonSpec["labels"].(map[string]interface{}); ok {
+		for key, value := range raw {
+			result[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return result
+}
+
 // Helper functions
 // nolint:unused // May be used for future features
 func mergeLabels(template, request map[string]string) map[string]string {
@@ -639,7 +857,7 @@ func mergeLabels(template, request map[string]string) map[string]string {
 	return result
 }
 
-// nolint:unused // May be used for future features
+// mergeEnvironment overlays request values on template defaults
 func mergeEnvironment(template, request map[string]string) map[string]string {
 	result := make(map[string]string)
 	for k, v := range template {
@@ -651,47 +869,33 @@ func mergeEnvironment(template, request map[string]string) map[string]string {
 	return result
 }
 
-// getContainerIP retrieves the IP address of a container in the mcp-network
+// getContainerIP retrieves the IP address of a container in the mcp-network,
+// of the NETWORK_IP_FAMILY family on a dual-stack network
 func (m *Manager) getContainerIP(ctx context.Context, containerID string) (string, error) {
-	// Use a simpler approach to get container IP
-	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, "inspect", containerID)
-	output, err := cmd.CombinedOutput()
+	addrs, err := m.containerAddresses(ctx, containerID)
 	if err != nil {
-		return "", fmt.Errorf("failed to inspect container: %w", err)
-	}
-
-	// Parse JSON to extract IP
-	var inspectData []map[string]interface{}
-	if err := json.Unmarshal(output, &inspectData); err != nil {
-		return "", fmt.Errorf("failed to parse inspect output: %w", err)
-	}
-
-	if len(inspectData) == 0 {
-		return "", fmt.Errorf("no container data found")
-	}
-
-	// Navigate to the IP address
-	networkSettings, ok := inspectData[0]["NetworkSettings"].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("NetworkSettings not found")
-	}
-
-	networks, ok := networkSettings["Networks"].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("Networks not found")
+		return "", err
 	}
+	return addrs.pick(m.config.Traefik.IPFamily), nil
+}
 
-	mcpNetwork, ok := networks[m.config.Traefik.Network].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("network %s not found", m.config.Traefik.Network)
+// getContainerIPs retrieves the IPv4 and IPv6 addresses of a container in
+// the mcp-network
+func (m *Manager) getContainerIPs(ctx context.Context, containerID string) ([]string, error) {
+	addrs, err := m.containerAddresses(ctx, containerID)
+	if err != nil {
+		return nil, err
 	}
+	return addrs.all(), nil
+}
 
-	ipAddress, ok := mcpNetwork["IPAddress"].(string)
-	if !ok || ipAddress == "" {
-		return "", fmt.Errorf("IPAddress not found or empty")
+// containerAddresses inspects the addresses of a container in the mcp-network
+func (m *Manager) containerAddresses(ctx context.Context, containerID string) (networkAddresses, error) {
+	info, err := m.runtime.Inspect(ctx, containerID)
+	if err != nil {
+		return networkAddresses{}, fmt.Errorf("failed to inspect container: %w", err)
 	}
-
-	return ipAddress, nil
+	return info.NetworkSettings.addresses(m.config.Traefik.Network, false)
 }
 
 // HandleMCPInstanceCreated handles the creation of an MCP server instance from domain events
@@ -711,12 +915,12 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 		Status:     "validating",
 	}
 
-	// Get current running count before validation (while unlocked)
-	currentRunningCount := m.GetRunningCount()
-	maxContainers := m.config.Container.MaxContainers
+	// Get current usage before validation (while unlocked)
+	workspaceID, _ := jsonSpec["workspace_id"].(string)
+	quota := m.QuotaSnapshot(workspaceID)
 
 	// Perform comprehensive validation with image pulling (OUTSIDE MUTEX)
-	validationResult, err := m.ValidateContainerSpecWithLimits(ctx, instance, true, currentRunningCount, maxContainers)
+	validationResult, err := m.ValidateContainerSpecWithLimits(ctx, instance, true, quota)
 	if err != nil {
 		m.logger.Error("Container validation failed",
 			slog.String("instance_id", instanceID),
@@ -746,6 +950,7 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 			slog.String("instance_id", instanceID),
 			slog.Any("warnings", validationResult.Warnings))
 	}
+	events.RecordStep(instanceID, name, events.StepValidated, strings.Join(validationResult.Warnings, "; "))
 
 	// Extract image (validated above)
 	image, ok := jsonSpec["image"].(string)
@@ -756,8 +961,21 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 	// Get container name for later use
 	containerName := m.config.GetContainerName(name)
 
-	// Extract container port (for internal use)
-	containerPort := 8000 // Default MCP port
+	// Read the mcp.* labels and configuration of the image; an image
+	// validation could not inspect is pulled now
+	metadata := validationResult.ImageMetadata
+	if metadata == nil && !validationResult.ImageExists {
+		if metadata, err = m.ImageMetadata(ctx, image, true); err != nil {
+			m.logger.Warn("Failed to inspect image",
+				slog.String("instance_id", instanceID),
+				slog.String("image", image),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	// Extract container port (for internal use), falling back to the one the
+	// image declares or exposes
+	containerPort, _ := DefaultPort(metadata)
 	if p, ok := jsonSpec["port"].(float64); ok {
 		containerPort = int(p)
 	} else if p, ok := jsonSpec["port"].(int); ok {
@@ -775,23 +993,154 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 	}
 
 	// Extract custom command (optional)
-	var command []string
-	if cmdInterface, ok := jsonSpec["cmd"]; ok {
-		if cmdSlice, ok := cmdInterface.([]interface{}); ok {
-			for _, cmdItem := range cmdSlice {
-				if cmdStr, ok := cmdItem.(string); ok {
-					command = append(command, cmdStr)
-				}
-			}
-		}
-	}
+	command := specCommand(jsonSpec)
+
+	// Fill in the defaults of the instance's template under the request values
+	template, _ := jsonSpec["template"].(string)
+	environment, envAudit := m.applyTemplateEnvironment(instanceID, template, environment)
 
 	// Add MCP-specific environment variables
 	environment["MCP_INSTANCE_ID"] = instanceID
 	environment["MCP_SERVICE_NAME"] = name
 	environment["MCP_CONTAINER_PORT"] = fmt.Sprintf("%d", containerPort)
+	if workspaceID, ok := jsonSpec["workspace_id"].(string); ok && workspaceID != "" {
+		environment[WorkspaceIDEnv] = workspaceID
+	}
+	authToken, err := eventAuthToken(jsonSpec, environment)
+	if err != nil {
+		return err
+	}
+
+	// Substitute ${env.NAME} and ${instance.FIELD} in the command arguments
+	command, err = ExpandCommand(command, NewCommandVars(instanceID, name, containerPort, workspaceID, environment))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+	if err := CheckImageRequirements(metadata, environment); err != nil {
+		if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, err.Error()); publishErr != nil {
+			m.logger.Warn("Failed to publish failed status",
+				slog.String("instance_id", instanceID),
+				slog.String("error", publishErr.Error()))
+		}
+		return err
+	}
+
+	labels := specLabels(jsonSpec)
+	if group, ok := jsonSpec["group"].(string); ok && group != "" {
+		labels[GroupLabel] = group
+	}
+	if template, ok := jsonSpec["template"].(string); ok && template != "" {
+		labels[TemplateLabel] = template
+		if version, ok := jsonSpec["template_version"].(string); ok && version != "" {
+			labels[TemplateVersionLabel] = version
+		}
+		if track, ok := jsonSpec["track_template"].(bool); ok && track {
+			labels[TrackTemplateLabel] = "true"
+		}
+	}
+	labels = SetAttribution(labels, workspaceID, ParseAttribution(jsonSpec))
+	restartPolicy, err := ParseRestartPolicy(jsonSpec)
+	if err != nil {
+		return err
+	}
+	slo, err := ParseSLO(jsonSpec)
+	if err != nil {
+		return err
+	}
+	if slo != nil {
+		for key, value := range slo.Labels() {
+			labels[key] = value
+		}
+	}
+	if restartPolicy != nil {
+		for key, value := range restartPolicy.Labels() {
+			labels[key] = value
+		}
+	}
+	startupProbe, err := ParseStartupProbe(jsonSpec)
+	if err != nil {
+		return err
+	}
+	if labels, err = SetStartupProbe(labels, startupProbe); err != nil {
+		return err
+	}
+	warmup, err := ParseWarmup(jsonSpec)
+	if err != nil {
+		return err
+	}
+	if labels, err = SetWarmup(labels, warmup); err != nil {
+		return err
+	}
+	if bandwidth, ok := jsonSpec["bandwidth"].(map[string]interface{}); ok {
+		ingress, _ := bandwidth["ingress"].(string)
+		egress, _ := bandwidth["egress"].(string)
+		if labels, err = SetBandwidth(labels, ingress, egress); err != nil {
+			return fmt.Errorf("invalid bandwidth: %w", err)
+		}
+	}
+	if quota, ok := jsonSpec["disk_quota"].(string); ok && quota != "" {
+		if _, err := parseSize(quota); err != nil {
+			return fmt.Errorf("invalid disk_quota: %w", err)
+		}
+		labels[DiskQuotaLabel] = quota
+	}
+	if labels, err = SetRuntimeOptions(labels, ParseRuntimeOptions(jsonSpec)); err != nil {
+		return err
+	}
+	if labels, err = SetDNSOptions(labels, ParseDNSOptions(jsonSpec)); err != nil {
+		return err
+	}
+	scratch, err := ParseScratch(jsonSpec)
+	if err != nil {
+		return err
+	}
+	if labels, err = SetScratch(labels, scratch); err != nil {
+		return err
+	}
+	if labels, err = m.setEgressAllow(labels, ParseEgressAllow(jsonSpec)); err != nil {
+		return err
+	}
+	labels = SetCompression(labels, ParseCompression(jsonSpec))
+	if metadata != nil && HealthPath(labels) == "" {
+		labels = SetHealthPath(labels, metadata.HealthPath)
+	}
+	hostAccess := ParseHostAccess(jsonSpec)
+	if err := m.hostAccess.Check(workspaceID, hostAccess); err != nil {
+		return err
+	}
+	labels = SetHostAccess(labels, hostAccess)
+	if labels, err = SetHostPort(labels, ParseHostPort(jsonSpec)); err != nil {
+		return err
+	}
+	if err := m.CheckHostPort(name, labels); err != nil {
+		return err
+	}
+	admissionOpts, err := ParseAdmissionOptions(jsonSpec)
+	if err != nil {
+		return err
+	}
+
+	// Reserve host capacity; creations may be queued until there is room
+	err = m.reserveCapacity(ctx, name, m.defaultReservation(), admissionOpts, func() {
+		if err := m.eventPublisher.PublishQueued(ctx, instanceID, name); err != nil {
+			m.logger.Warn("Failed to publish queued status",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+		}
+	})
+	if errors.Is(err, ErrInsufficientCapacity) || errors.Is(err, ErrContainerLimit) {
+		if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, err.Error()); publishErr != nil {
+			m.logger.Warn("Failed to publish failed status",
+				slog.String("instance_id", instanceID),
+				slog.String("error", publishErr.Error()))
+		}
+	}
+	if err != nil {
+		return err
+	}
 
 	// NOW ACQUIRE MUTEX FOR CONTAINER OPERATIONS
+	defer m.flushState(ctx)
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -802,9 +1151,22 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 
 	// Check container limit
 	if len(m.containers) >= m.config.Container.MaxContainers {
+		m.releaseCapacity(name)
 		return fmt.Errorf("maximum container limit reached (%d)", m.config.Container.MaxContainers)
 	}
 
+	// Publish the requested host port; it was free at validation but may have
+	// been taken while the creation was queued
+	if err := m.assignHostPort(name, labels); err != nil {
+		m.releaseCapacity(name)
+		if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, err.Error()); publishErr != nil {
+			m.logger.Warn("Failed to publish failed status",
+				slog.String("instance_id", instanceID),
+				slog.String("error", publishErr.Error()))
+		}
+		return err
+	}
+
 	// Generate a unique slug for routing
 	slug := generateSlug(name)
 
@@ -820,13 +1182,14 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 		Host:        m.config.Traefik.ProxyHost,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
-		Labels:      make(map[string]string), // No labels needed for Traefik
+		Labels:      labels,
 		Environment: environment,
 		Command:     command,
 	}
 
 	// Store container in tracking map with validating status
 	m.containers[name] = container
+	m.recordSpec(name, jsonSpec, envAudit, environment)
 
 	// Update status to starting
 	container.Status = models.StatusStarting
@@ -844,73 +1207,79 @@ func (m *Manager) HandleMCPInstanceCreated(ctx context.Context, instanceID, name
 		slog.String("instance_id", instanceID),
 		slog.String("image", image))
 
-	// Build podman run command
-	args := m.buildPodmanRunArgs(container)
-
-	// Execute container runtime run command
-	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		container.Status = models.StatusError
-
-		// Publish failed status
-		errorMsg := fmt.Sprintf("Failed to create container: %v", err)
-		if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, errorMsg); publishErr != nil {
-			m.logger.Warn("Failed to publish failed status",
-				slog.String("instance_id", instanceID),
-				slog.String("error", publishErr.Error()))
+	// A pre-started container from the template's warm pool skips image pull,
+	// creation and network setup
+	var containerIP string
+	if warm, claimed := m.claimWarmContainer(ctx, container); claimed {
+		container.ID = warm.ID
+		containerIP = warm.IP
+		events.RecordStep(instanceID, name, events.StepStarted, "claimed from warm pool")
+	} else {
+		// Create and start the container through the runtime
+		spec, err := m.buildCreateSpec(container)
+		var id string
+		if err == nil {
+			id, err = m.runtime.Create(ctx, spec)
 		}
+		if err != nil {
+			container.Status = models.StatusError
+
+			// Publish failed status
+			errorMsg := fmt.Sprintf("Failed to create container: %v", err)
+			if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, errorMsg); publishErr != nil {
+				m.logger.Warn("Failed to publish failed status",
+					slog.String("instance_id", instanceID),
+					slog.String("error", publishErr.Error()))
+			}
 
-		m.logger.Error("Failed to create container",
-			slog.String("container", containerName),
-			slog.String("error", err.Error()),
-			slog.String("output", string(output)))
-		return fmt.Errorf("failed to create container: %w", err)
-	}
-
-	// Get container ID from output
-	container.ID = strings.TrimSpace(string(output))
-
-	// Wait for container to be running
-	if err := m.waitForContainer(ctx, container.ID); err != nil {
-		container.Status = models.StatusError
-
-		// Publish failed status
-		errorMsg := fmt.Sprintf("Container failed to start: %v", err)
-		if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, errorMsg); publishErr != nil {
-			m.logger.Warn("Failed to publish failed status",
-				slog.String("instance_id", instanceID),
-				slog.String("error", publishErr.Error()))
+			m.logger.Error("Failed to create container",
+				slog.String("container", containerName),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("failed to create container: %w", err)
 		}
+		container.ID = id
+
+		// Wait for container to be running
+		if err := m.waitForContainer(ctx, container.ID); err != nil {
+			container.Status = models.StatusError
+
+			// Publish failed status
+			errorMsg := fmt.Sprintf("Container failed to start: %v", err)
+			if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, name, errorMsg); publishErr != nil {
+				m.logger.Warn("Failed to publish failed status",
+					slog.String("instance_id", instanceID),
+					slog.String("error", publishErr.Error()))
+			}
 
-		return fmt.Errorf("container failed to start: %w", err)
-	}
+			return fmt.Errorf("container failed to start: %w", err)
+		}
+		events.RecordStep(instanceID, name, events.StepStarted, container.ID)
 
-	// Get container IP for Traefik routing
-	containerIP, err := m.getContainerIP(ctx, container.ID)
-	if err != nil {
-		m.logger.Error("Failed to get container IP",
-			slog.String("container", containerName),
-			slog.String("error", err.Error()))
-		// Continue without IP - container is still created
-		containerIP = "127.0.0.1" // fallback
+		// Get container IP for Traefik routing
+		containerIP, err = m.getContainerIP(ctx, container.ID)
+		if err != nil {
+			m.logger.Error("Failed to get container IP",
+				slog.String("container", containerName),
+				slog.String("error", err.Error()))
+			// Continue without IP - container is still created
+			containerIP = "127.0.0.1" // fallback
+		}
 	}
 
-	// Add route for the container using the slug (proxy or Traefik)
-	if err := m.addRoute(ctx, slug, containerIP, containerPort); err != nil {
-		m.logger.Error("Failed to add route",
-			slog.String("slug", slug),
-			slog.String("service", name),
-			slog.String("error", err.Error()))
-		// Continue - container is created but routing may not work
-	}
+	// Shape traffic before the container receives requests
+	m.applyBandwidth(ctx, container)
 
 	// Update final status and container info
 	container.Status = models.StatusRunning
 	container.UpdatedAt = time.Now()
 
-	// Publish running status
-	if err := m.eventPublisher.PublishRunning(ctx, instanceID, name, container.ID, container.URL); err != nil {
+	// Add route for the container using the slug (proxy or Traefik) once
+	// its server answers
+	m.publishRoute(ctx, container, containerIP)
+	m.saveState(ctx, container)
+
+	// Publish running status, with the token if one was generated
+	if err := m.eventPublisher.PublishCreated(ctx, instanceID, name, container.ID, container.URL, authToken); err != nil {
 		m.logger.Warn("Failed to publish running status",
 			slog.String("instance_id", instanceID),
 			slog.String("error", err.Error()))
@@ -970,15 +1339,8 @@ func (m *Manager) HandleMCPInstanceDeleted(ctx context.Context, instanceID strin
 
 // generateSlug generates a URL-friendly slug from a name with a random suffix
 func generateSlug(name string) string {
-	// Convert to lowercase and replace spaces/special chars with hyphens
-	slug := strings.ToLower(name)
-
-	// Replace any non-alphanumeric characters with hyphens
-	reg := regexp.MustCompile(`[^a-z0-9]+`)
-	slug = reg.ReplaceAllString(slug, "-")
-
-	// Remove leading/trailing hyphens
-	slug = strings.Trim(slug, "-")
+	// Normalize, leaving room for the suffix within a DNS label
+	slug := naming.Normalize(name, naming.MaxLabelLength-9)
 
 	// Add random suffix to ensure uniqueness
 	randomBytes := make([]byte, 4)
@@ -1036,6 +1398,7 @@ func (m *Manager) ValidateContainerSpec(ctx context.Context, instance *models.MC
 						slog.String("error", err.Error()))
 					return nil, fmt.Errorf("failed to pull image: %w", err)
 				}
+				events.RecordStep(instance.InstanceID, instance.Name, events.StepPulled, image)
 			}
 		}
 	}
@@ -1049,16 +1412,16 @@ func (m *Manager) ValidateContainerSpec(ctx context.Context, instance *models.MC
 	return result, nil
 }
 
-// ValidateContainerSpecWithLimits validates container specification with explicit container limits (deadlock-safe)
-func (m *Manager) ValidateContainerSpecWithLimits(ctx context.Context, instance *models.MCPServerInstance, allowImagePull bool, currentRunningCount int, maxContainers int) (*ValidationResult, error) {
+// ValidateContainerSpecWithLimits validates container specification against a quota snapshot (deadlock-safe)
+func (m *Manager) ValidateContainerSpecWithLimits(ctx context.Context, instance *models.MCPServerInstance, allowImagePull bool, quota QuotaSnapshot) (*ValidationResult, error) {
 	m.logger.Info("Validating container specification with limits",
 		slog.String("instance_id", instance.InstanceID),
 		slog.String("name", instance.Name),
-		slog.Int("current_running", currentRunningCount),
-		slog.Int("max_containers", maxContainers))
+		slog.Int("current_running", quota.Running),
+		slog.Int("max_containers", quota.MaxContainers))
 
 	// Use validator for dry-run validation (but avoid manager callbacks that cause deadlock)
-	result, err := m.validator.DryRunValidationWithLimits(ctx, instance, currentRunningCount, maxContainers)
+	result, err := m.validator.DryRunValidationWithLimits(ctx, instance, quota)
 	if err != nil {
 		m.logger.Error("Dry-run validation failed",
 			slog.String("instance_id", instance.InstanceID),
@@ -1099,6 +1462,7 @@ func (m *Manager) ValidateContainerSpecWithLimits(ctx context.Context, instance
 						slog.String("error", err.Error()))
 					return nil, fmt.Errorf("failed to pull image: %w", err)
 				}
+				events.RecordStep(instance.InstanceID, instance.Name, events.StepPulled, image)
 			}
 		}
 	}
@@ -1129,7 +1493,9 @@ func (m *Manager) startHealthMonitoring() {
 			m.logger.Info("Health monitoring stopped")
 			return
 		case <-ticker.C:
-			m.performHealthCheckAll()
+			if !m.BackgroundPaused() {
+				m.performHealthCheckAll()
+			}
 		}
 	}
 }
@@ -1139,6 +1505,9 @@ func (m *Manager) performHealthCheckAll() {
 	m.mutex.RLock()
 	containers := make([]*models.Container, 0, len(m.containers))
 	for _, container := range m.containers {
+		if m.isCheckpointed(container.ServiceName) || m.isQuarantined(container.ServiceName) || m.isStopped(container.ServiceName) {
+			continue
+		}
 		containers = append(containers, container)
 	}
 	m.mutex.RUnlock()
@@ -1150,7 +1519,13 @@ func (m *Manager) performHealthCheckAll() {
 	m.logger.Debug("Performing health checks on all containers",
 		slog.Int("container_count", len(containers)))
 
+	statsCtx, statsCancel := context.WithTimeout(m.healthCtx, 15*time.Second)
+	usage := m.collectResourceUsage(statsCtx, containers)
+	statsCancel()
+
 	// Perform health checks
+	var anomalous []*models.Container
+	var anomalies []string
 	for _, container := range containers {
 		// Create a timeout context for each health check
 		healthCtx, cancel := context.WithTimeout(m.healthCtx, 15*time.Second)
@@ -1170,12 +1545,38 @@ func (m *Manager) performHealthCheckAll() {
 				Error:       err.Error(),
 				Timestamp:   time.Now(),
 			}
+		} else {
+			m.applyStartup(container, result, time.Now())
+		}
+
+		if sample, ok := usage[container.Name]; ok {
+			m.checkResourcePressure(container, sample, result, time.Now())
+			if reason := m.checkAnomalies(container, sample, time.Now()); reason != "" {
+				anomalous = append(anomalous, container)
+				anomalies = append(anomalies, reason)
+			}
 		}
 
 		// Update health status
 		m.updateContainerHealth(container, result)
+		m.applyProbes(container, result)
+		if !result.starting {
+			healthy := result.Healthy && result.HTTPReachable
+			if !healthy {
+				metrics.HealthCheckFailed()
+			}
+			m.recordSLOSample(container, healthy, time.Now())
+		}
 		cancel()
 	}
+
+	for i, container := range anomalous {
+		if _, err := m.QuarantineContainer(m.healthCtx, container.ServiceName, anomalies[i], QuarantineAnomaly); err != nil {
+			m.logger.Error("Failed to quarantine anomalous container",
+				slog.String("service", container.ServiceName),
+				slog.String("error", err.Error()))
+		}
+	}
 }
 
 // updateContainerHealth updates the health status of a container
@@ -1198,8 +1599,16 @@ func (m *Manager) updateContainerHealth(container *models.Container, result *Hea
 			slog.String("container", container.Name),
 			slog.String("previous_status", string(previousStatus)),
 			slog.String("new_status", string(newStatus)),
-			slog.Bool("healthy", result.Healthy),
-			slog.Bool("http_reachable", result.HTTPReachable))
+			slog.Bool("live", result.Live),
+			slog.Bool("ready", result.Ready))
+
+		if newStatus == models.StatusError || newStatus == models.StatusStopped {
+			m.recordFailure(container, result.Error)
+		}
+
+		if newStatus == models.StatusRunning {
+			events.RecordStep(container.Environment["MCP_INSTANCE_ID"], container.ServiceName, events.StepHealthy, "")
+		}
 
 		// Publish status change event if needed
 		if instanceID, exists := container.Environment["MCP_INSTANCE_ID"]; exists {
@@ -1208,6 +1617,8 @@ func (m *Manager) updateContainerHealth(container *models.Container, result *Hea
 				switch newStatus {
 				case models.StatusRunning:
 					publishErr = m.eventPublisher.PublishRunning(m.healthCtx, instanceID, container.ServiceName, container.ID, container.URL)
+				case models.StatusUnhealthy:
+					publishErr = m.eventPublisher.PublishStatusUpdate(m.healthCtx, instanceID, container.ServiceName, "unhealthy", container.ID, "")
 				case models.StatusError:
 					publishErr = m.eventPublisher.PublishFailed(m.healthCtx, instanceID, container.ServiceName, result.Error)
 				case models.StatusStopped:
@@ -1227,10 +1638,19 @@ func (m *Manager) updateContainerHealth(container *models.Container, result *Hea
 
 // determineContainerStatus determines the container status based on health check result
 func (m *Manager) determineContainerStatus(result *HealthCheckResult) models.ContainerStatus {
-	if result.Healthy && result.HTTPReachable {
+	if result.starting {
+		return models.StatusStarting
+	}
+
+	if result.Live && result.Ready {
 		return models.StatusRunning
 	}
 
+	// The server answers but is not ready: it stays up, out of routing
+	if result.Live && result.probed {
+		return models.StatusUnhealthy
+	}
+
 	if result.Status == models.StatusStopped {
 		return models.StatusStopped
 	}
@@ -1252,6 +1672,24 @@ func (m *Manager) GetContainerHealthStatus(serviceName string) (*HealthCheckResu
 	return healthResult, exists
 }
 
+// SetSupervisor runs the background loops started by Initialize under a
+// supervisor, which restarts them when they panic
+func (m *Manager) SetSupervisor(s *supervisor.Supervisor) {
+	m.supervisor = s
+}
+
+// runLoop starts a background loop that returns when the manager shuts down
+func (m *Manager) runLoop(name string, critical bool, loop func()) {
+	if m.supervisor == nil {
+		go loop()
+		return
+	}
+	m.supervisor.Go(name, supervisor.Policy{Critical: critical}, func(ctx context.Context) error {
+		loop()
+		return nil
+	})
+}
+
 // Shutdown gracefully shuts down the container manager
 // SetRouteManager sets the route manager for proxy-based routing
 func (m *Manager) SetRouteManager(rm interface{}) {
@@ -1277,6 +1715,22 @@ func (m *Manager) addRoute(ctx context.Context, slug, containerIP string, contai
 	return fmt.Errorf("no route manager configured")
 }
 
+// addContainerRoute adds the route for a container. The instance identity and
+// auth token are taken from its environment so the proxy can forward identity
+// headers and require the token, and the compression switch from its labels.
+// The Traefik fallback does none of this.
+func (m *Manager) addContainerRoute(ctx context.Context, container *models.Container, containerIP string) error {
+	if rm, ok := m.routeManager.(interface {
+		AddMCPInstance(context.Context, string, string, int, string, string, string, bool) error
+	}); ok {
+		env := container.Environment
+		return rm.AddMCPInstance(ctx, container.Slug, containerIP, container.Port,
+			env[WorkspaceIDEnv], env["MCP_INSTANCE_ID"], env[AuthTokenEnv], CompressionFromLabels(container.Labels))
+	}
+
+	return m.addRoute(ctx, container.Slug, containerIP, container.Port)
+}
+
 // removeRoute removes a route from either the proxy manager or Traefik
 func (m *Manager) removeRoute(ctx context.Context, slug string) error {
 	// Try routeManager first (Docker with internal proxy)
@@ -1359,11 +1813,11 @@ func (m *Manager) autoRestartContainers(ctx context.Context) error {
 
 // shouldContainerBeRunning determines if a container should be running based on its metadata
 func (m *Manager) shouldContainerBeRunning(container *models.Container) bool {
-	// For now, assume all discovered containers should be running
-	// In a more sophisticated system, this could check database state,
-	// environment variables, or other metadata to determine desired state
-	_ = container // Parameter may be used in future implementations
-	return true
+	// Containers should be running unless checkpointed or stopped on request,
+	// which stay stopped until they are restored or started (across restarts
+	// when the state store persists), or quarantined, which are left as they
+	// are for forensics.
+	return !m.isCheckpointed(container.ServiceName) && !m.isStopped(container.ServiceName) && !m.isQuarantined(container.ServiceName)
 }
 
 // getRealTimeContainerStatus gets the real-time status from Podman
@@ -1372,17 +1826,14 @@ func (m *Manager) getRealTimeContainerStatus(ctx context.Context, container *mod
 		return models.StatusError
 	}
 
-	cmd := exec.CommandContext(ctx, "podman", "inspect", container.ID, "--format", "{{.State.Status}}")
-	output, err := cmd.CombinedOutput()
+	info, err := m.runtime.Inspect(ctx, container.ID)
 	if err != nil {
 		m.logger.Debug("Failed to get real-time container status",
 			slog.String("container", container.Name),
 			slog.String("error", err.Error()))
 		return models.StatusError
 	}
-
-	podmanStatus := strings.TrimSpace(string(output))
-	return m.mapPodmanStatus(podmanStatus)
+	return m.mapPodmanStatus(info.State.Status)
 }
 
 // restartContainer restarts a stopped container
@@ -1396,11 +1847,9 @@ func (m *Manager) restartContainer(ctx context.Context, container *models.Contai
 	container.UpdatedAt = time.Now()
 
 	// Start the container
-	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, "start", container.ID)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
+	if err := m.runtime.Start(ctx, container.ID); err != nil {
 		container.Status = models.StatusError
-		return fmt.Errorf("failed to start container: %w, output: %s", err, string(output))
+		return fmt.Errorf("failed to start container: %w", err)
 	}
 
 	// Wait for container to be running
@@ -1408,6 +1857,7 @@ func (m *Manager) restartContainer(ctx context.Context, container *models.Contai
 		container.Status = models.StatusError
 		return fmt.Errorf("container failed to start properly: %w", err)
 	}
+	events.RecordStep(container.Environment["MCP_INSTANCE_ID"], container.ServiceName, events.StepRestarted, container.ID)
 
 	// Get container IP for Traefik routing (in case it changed)
 	containerIP, err := m.getContainerIP(ctx, container.ID)
@@ -1419,9 +1869,12 @@ func (m *Manager) restartContainer(ctx context.Context, container *models.Contai
 		containerIP = "127.0.0.1" // fallback
 	}
 
+	// Limits are lost when the network namespace is recreated
+	m.applyBandwidth(ctx, container)
+
 	// Update/refresh route for the container (proxy or Traefik)
 	if container.Slug != "" {
-		if err := m.addRoute(ctx, container.Slug, containerIP, container.Port); err != nil {
+		if err := m.addContainerRoute(ctx, container, containerIP); err != nil {
 			m.logger.Error("Failed to update route after restart",
 				slog.String("slug", container.Slug),
 				slog.String("service", container.ServiceName),
@@ -1454,12 +1907,12 @@ func (m *Manager) syncWithCoreAPI(ctx context.Context) error {
 	url := fmt.Sprintf("%s/v1/mcp-server-instances/", m.config.CoreAPIURL)
 	m.logger.Info("Fetching MCP instances from Core API", slog.String("url", url))
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build Core API request: %w", err)
 	}
-
-	resp, err := client.Get(url)
+	client := &http.Client{Timeout: m.config.Container.HTTPTimeout}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to fetch MCP instances: %w", err)
 	}
@@ -1517,6 +1970,13 @@ func (m *Manager) syncWithCoreAPI(ctx context.Context) error {
 
 				// Add MCP instance ID to environment for tracking
 				environment["MCP_INSTANCE_ID"] = instance.InstanceID
+				if workspaceID, ok := instance.JSONSpec["workspace_id"].(string); ok && workspaceID != "" {
+					environment[WorkspaceIDEnv] = workspaceID
+				}
+				authToken, err := eventAuthToken(instance.JSONSpec, environment)
+				if err != nil {
+					return err
+				}
 
 				// Create container request
 				req := models.CreateContainerRequest{
@@ -1527,12 +1987,17 @@ func (m *Manager) syncWithCoreAPI(ctx context.Context) error {
 				}
 
 				// Create container
-				if _, err := m.CreateContainer(ctx, req); err != nil {
+				if container, err := m.CreateContainer(ctx, req); err != nil {
 					m.logger.Error("Failed to create container for pending instance",
 						slog.String("instance_id", instance.InstanceID),
 						slog.String("name", instance.Name),
 						slog.String("error", err.Error()))
 				} else {
+					if err := m.eventPublisher.PublishCreated(ctx, instance.InstanceID, instance.Name, container.ID, container.URL, authToken); err != nil {
+						m.logger.Warn("Failed to publish running status",
+							slog.String("instance_id", instance.InstanceID),
+							slog.String("error", err.Error()))
+					}
 					m.logger.Info("Successfully created container for pending instance",
 						slog.String("instance_id", instance.InstanceID),
 						slog.String("name", instance.Name))
@@ -2,14 +2,31 @@ package container
 
 import (
 	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/egress"
+	"github.com/agentarea/mcp-manager/internal/fernet"
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/store"
 )
 
 func TestNewManager(t *testing.T) {
@@ -151,3 +168,2266 @@ func TestDeadlockPrevention(t *testing.T) {
 		t.Fatal("Deadlock detected - GetRunningCount calls did not complete within timeout")
 	}
 }
+
+func TestQuotaValidationHints(t *testing.T) {
+	cfg := &config.Config{
+		Container: config.ContainerConfig{
+			NamePrefix:      "test-",
+			MaxContainers:   10,
+			MaxPerWorkspace: 2,
+			IdleAfter:       time.Minute,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	manager := NewManager(cfg, logger)
+
+	old := time.Now().Add(-time.Hour)
+	manager.containers["busy"] = &models.Container{
+		ServiceName: "busy",
+		Status:      models.StatusRunning,
+		CreatedAt:   time.Now(),
+		Environment: map[string]string{WorkspaceIDEnv: "ws-1", "MCP_INSTANCE_ID": "busy-id"},
+	}
+	manager.containers["idle"] = &models.Container{
+		ServiceName: "idle",
+		Status:      models.StatusRunning,
+		CreatedAt:   old,
+		Environment: map[string]string{WorkspaceIDEnv: "ws-1", "MCP_INSTANCE_ID": "idle-id"},
+	}
+	manager.containers["other"] = &models.Container{
+		ServiceName: "other",
+		Status:      models.StatusRunning,
+		CreatedAt:   old,
+		Environment: map[string]string{WorkspaceIDEnv: "ws-2"},
+	}
+
+	quota := manager.QuotaSnapshot("ws-1")
+	if quota.Running != 3 || quota.WorkspaceRunning != 2 {
+		t.Fatalf("Expected 3 running and 2 in workspace, got %+v", quota)
+	}
+
+	result := &ValidationResult{Valid: true}
+	checkQuota(result, quota)
+
+	if result.Valid {
+		t.Fatal("Expected workspace limit to invalidate the result")
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Code != CodeWorkspaceLimitReached {
+		t.Fatalf("Expected a workspace_limit_reached issue, got %+v", result.Issues)
+	}
+	if usage := result.Issues[0].Usage; usage == nil || usage.Used != 2 || usage.Limit != 2 {
+		t.Errorf("Expected usage 2/2 on the issue, got %+v", usage)
+	}
+	if len(result.IdleCandidates) != 1 || result.IdleCandidates[0].InstanceID != "idle-id" {
+		t.Errorf("Expected only the idle instance of the workspace as candidate, got %+v", result.IdleCandidates)
+	}
+}
+
+func TestRestartPolicyDue(t *testing.T) {
+	policy, err := ParseRestartPolicy(map[string]interface{}{
+		"restart_policy": map[string]interface{}{"at": "03:00", "max_uptime": "168h"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !policy.SkipIfActive {
+		t.Error("skip_if_active should default to true")
+	}
+
+	// The policy must survive the label round trip used for discovered containers
+	policy = restartPolicyFromLabels(policy.Labels())
+	if policy == nil || policy.At != "03:00" || policy.MaxUptime != 168*time.Hour {
+		t.Fatalf("label round trip lost the policy: %+v", policy)
+	}
+
+	day := time.Date(2025, 7, 29, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		now       time.Time
+		startedAt time.Time
+		want      string
+	}{
+		{"before schedule", day.Add(2 * time.Hour), day.Add(-time.Hour), ""},
+		{"at schedule", day.Add(3*time.Hour + time.Minute), day.Add(-time.Hour), "schedule"},
+		{"already restarted", day.Add(3*time.Hour + 2*time.Minute), day.Add(3*time.Hour + time.Minute), ""},
+		{"missed window", day.Add(5 * time.Hour), day.Add(-time.Hour), ""},
+		{"max uptime", day.Add(5 * time.Hour), day.Add(-8 * 24 * time.Hour), "max_uptime"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := policy.due(tt.now, tt.startedAt)
+			if reason != tt.want {
+				t.Errorf("due() = %q, want %q", reason, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseRestartPolicy(map[string]interface{}{
+		"restart_policy": map[string]interface{}{"at": "25:00"},
+	}); err == nil {
+		t.Error("expected invalid time to be rejected")
+	}
+}
+
+func TestSLOEvaluate(t *testing.T) {
+	slo, err := ParseSLO(map[string]interface{}{
+		"slo": map[string]interface{}{"objective": 0.99, "window": "24h"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slo = sloFromLabels(slo.Labels()); slo == nil || slo.Objective != 0.99 || slo.Window != 24*time.Hour {
+		t.Fatalf("label round trip lost the SLO: %+v", slo)
+	}
+
+	now := time.Date(2025, 7, 29, 12, 0, 0, 0, time.UTC)
+	history := newHealthHistory()
+
+	// 990 healthy checks spread over the day use none of the budget
+	for i := 0; i < 990; i++ {
+		history.record("svc", now.Add(time.Duration(i-990)*time.Minute), true)
+	}
+	status := history.evaluate("svc", slo, now, 6)
+	if status.Alerting || status.BudgetRemaining != 1 {
+		t.Fatalf("unexpected status for healthy history: %+v", status)
+	}
+
+	// A burst of failures in the last hour burns fast but leaves budget
+	for i := 0; i < 5; i++ {
+		history.record("svc", now.Add(time.Duration(i-5)*time.Second), false)
+	}
+	status = history.evaluate("svc", slo, now, 6)
+	if status.AlertReason != SLOReasonBurnRate || status.BudgetRemaining <= 0 {
+		t.Fatalf("expected burn rate alert, got %+v", status)
+	}
+
+	// More failures exhaust the budget
+	for i := 0; i < 10; i++ {
+		history.record("svc", now, false)
+	}
+	status = history.evaluate("svc", slo, now, 6)
+	if status.AlertReason != SLOReasonBudgetExhausted {
+		t.Fatalf("expected budget exhausted alert, got %+v", status)
+	}
+
+	// Checks outside the window no longer count
+	status = history.evaluate("svc", slo, now.Add(25*time.Hour), 6)
+	if status.Checks != 0 || status.Alerting {
+		t.Fatalf("expected empty window, got %+v", status)
+	}
+
+	if _, err := ParseSLO(map[string]interface{}{
+		"slo": map[string]interface{}{"objective": 1.0, "window": "24h"},
+	}); err == nil {
+		t.Error("expected objective of 1 to be rejected")
+	}
+}
+
+func TestResourcePressure(t *testing.T) {
+	cfg := &config.Config{
+		Container: config.ContainerConfig{
+			NamePrefix:                "test-",
+			DefaultMemoryLimit:        "512m",
+			DefaultCPULimit:           "1.0",
+			ResourcePressureThreshold: 0.9,
+			ResourcePressureSamples:   3,
+		},
+	}
+	manager := NewManager(cfg, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	container := &models.Container{Name: "test-github", ServiceName: "github"}
+
+	output := []byte(`{"Name":"test-github","CPU":35.5,"MemUsage":503316480,"MemLimit":536870912}` + "\n")
+	usage := parseResourceUsage(output, 1.0)["test-github"]
+	if usage.MemoryUsage < 0.93 || usage.MemoryUsage > 0.94 || usage.CPUUsage != 0.355 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		result := &HealthCheckResult{}
+		manager.checkResourcePressure(container, usage, result, now.Add(time.Duration(i)*30*time.Second))
+		_, pressured := result.Details["resource_pressure"]
+		if pressured != (i == 2) {
+			t.Fatalf("sample %d: resource_pressure present = %v", i, pressured)
+		}
+	}
+
+	pressure := manager.ResourcePressure("github")
+	if pressure == nil || len(pressure.Resources) != 1 || pressure.Resources[0] != ResourceMemory {
+		t.Fatalf("expected memory pressure, got %+v", pressure)
+	}
+	if pressure.Suggestions[ResourceMemory] != "768m" {
+		t.Errorf("expected 768m suggestion, got %q", pressure.Suggestions[ResourceMemory])
+	}
+
+	// One sample below the threshold clears the warning
+	usage.MemoryUsage = 0.5
+	manager.checkResourcePressure(container, usage, &HealthCheckResult{}, now.Add(2*time.Minute))
+	if manager.ResourcePressure("github") != nil {
+		t.Error("expected pressure to clear")
+	}
+}
+
+func TestWarmPool(t *testing.T) {
+	env, err := renderEnvFile(map[string]string{"TOKEN": "it's a secret", "MCP_INSTANCE_ID": "inst-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(env) != "MCP_INSTANCE_ID='inst-1'\nTOKEN='it'\\''s a secret'\n" {
+		t.Errorf("unexpected env file:\n%s", env)
+	}
+	if _, err := renderEnvFile(map[string]string{"BAD;KEY": "x"}); err == nil {
+		t.Error("expected invalid variable name to be rejected")
+	}
+
+	manager := NewManager(&config.Config{Container: config.ContainerConfig{NamePrefix: "mcp-"}}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	manager.warmPools.ready["github"] = []*warmContainer{
+		{ID: "a", Name: "mcp-pool.github.0001", Image: "ghcr.io/acme/github:1"},
+		{ID: "b", Name: "mcp-pool.github.0002", Image: "ghcr.io/acme/github:2"},
+	}
+	if !manager.isWarmPoolName("mcp-pool.github.0001") || manager.isWarmPoolName("mcp-github") {
+		t.Error("unexpected warm pool name detection")
+	}
+	if warm := manager.takeWarmContainer("github", "ghcr.io/acme/github:3"); warm != nil {
+		t.Errorf("took container with a different image: %+v", warm)
+	}
+	if warm := manager.takeWarmContainer("github", "ghcr.io/acme/github:2"); warm == nil || warm.ID != "b" {
+		t.Fatalf("expected container b, got %+v", warm)
+	}
+	if len(manager.warmPools.ready["github"]) != 1 || manager.warmPools.claimed["github"] != 1 {
+		t.Errorf("unexpected pool after claim: %d ready, %d claimed", len(manager.warmPools.ready["github"]), manager.warmPools.claimed["github"])
+	}
+}
+
+func TestCheckpointArgs(t *testing.T) {
+	args := checkpointArgs("abc", "/ckpt/github.tar.gz", CheckpointOptions{Export: true, LeaveRunning: true})
+	if got := strings.Join(args, " "); got != "container checkpoint --export=/ckpt/github.tar.gz --leave-running abc" {
+		t.Errorf("unexpected checkpoint args: %s", got)
+	}
+	if got := strings.Join(restoreArgs("abc", "", true), " "); got != "container restore --tcp-established abc" {
+		t.Errorf("unexpected restore args: %s", got)
+	}
+	if got := strings.Join(restoreArgs("", "/ckpt/github.tar.gz", false), " "); got != "container restore --import=/ckpt/github.tar.gz" {
+		t.Errorf("unexpected import args: %s", got)
+	}
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	container := &models.Container{ServiceName: "github"}
+	manager.checkpoints.services["github"] = Checkpoint{ServiceName: "github"}
+	if !manager.isCheckpointed("github") || manager.shouldContainerBeRunning(container) {
+		t.Error("expected checkpointed container to stay stopped")
+	}
+	manager.checkpoints.services["github"] = Checkpoint{ServiceName: "github", LeaveRunning: true}
+	if manager.isCheckpointed("github") || !manager.shouldContainerBeRunning(container) {
+		t.Error("expected container left running to be restartable")
+	}
+}
+
+func TestHostAdmission(t *testing.T) {
+	if size, err := parseSize("512m"); err != nil || size != 512<<20 {
+		t.Errorf("expected 512 MiB, got %d (%v)", size, err)
+	}
+	if size, err := parseSize("1.5g"); err != nil || size != 3<<29 {
+		t.Errorf("expected 1.5 GiB, got %d (%v)", size, err)
+	}
+	if _, err := parseSize("lots"); err == nil {
+		t.Error("expected invalid size to be rejected")
+	}
+
+	host := HostCapacity{MemoryBytes: 4 << 30, MemoryAvailable: 2 << 30, CPUs: 2, DiskFreeBytes: 10 << 30}
+	res := Reservation{MemoryBytes: 1 << 30, CPUs: 1}
+	if err := checkCapacity(host, Reservation{MemoryBytes: 4 << 30, CPUs: 1}, res, 1.5, 1<<30); err != nil {
+		t.Errorf("expected admission within the oversubscription factor, got %v", err)
+	}
+	if err := checkCapacity(host, Reservation{MemoryBytes: 11 << 29}, res, 1.5, 0); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("expected memory oversubscription to be rejected, got %v", err)
+	}
+	if err := checkCapacity(host, Reservation{CPUs: 2.5}, res, 1.5, 0); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("expected CPU oversubscription to be rejected, got %v", err)
+	}
+	if err := checkCapacity(host, Reservation{}, res, 1.5, 20<<30); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("expected low disk to be rejected, got %v", err)
+	}
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	if err := manager.reserveCapacity(context.Background(), "github", res, AdmissionOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.reserveCapacity(context.Background(), "github", res, AdmissionOptions{}, nil); err == nil {
+		t.Error("expected a second reservation for the same service to fail")
+	}
+	released := manager.admission.released
+	manager.releaseCapacity("github")
+	select {
+	case <-released:
+	default:
+		t.Error("expected release to wake queued creations")
+	}
+	if len(manager.admission.reserved) != 0 {
+		t.Errorf("expected no reservations, got %v", manager.admission.reserved)
+	}
+}
+
+func TestAdmissionQueue(t *testing.T) {
+	if opts, err := ParseAdmissionOptions(map[string]interface{}{"priority": 5.0, "queue_ttl": "2m"}); err != nil || opts.Priority != 5 || opts.TTL != 2*time.Minute {
+		t.Errorf("unexpected admission options: %+v (%v)", opts, err)
+	}
+	if _, err := ParseAdmissionOptions(map[string]interface{}{"queue_ttl": "soon"}); err == nil {
+		t.Error("expected invalid queue_ttl to be rejected")
+	}
+
+	cfg := &config.Config{}
+	cfg.Container.MaxContainers = 1
+	manager := NewManager(cfg, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	ctx := context.Background()
+	if err := manager.reserveCapacity(ctx, "a", Reservation{}, AdmissionOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.reserveCapacity(ctx, "b", Reservation{}, AdmissionOptions{TTL: time.Minute}, nil); !errors.Is(err, ErrContainerLimit) {
+		t.Fatalf("expected the container limit without queuing, got %v", err)
+	}
+
+	cfg.Container.AdmissionQueueAtLimit = true
+	admitted := make(chan string, 2)
+	queue := func(name string, priority int) {
+		queued := make(chan struct{})
+		go func() {
+			err := manager.reserveCapacity(ctx, name, Reservation{}, AdmissionOptions{Priority: priority, TTL: time.Minute}, func() { close(queued) })
+			if err != nil {
+				t.Errorf("expected %s to be admitted, got %v", name, err)
+			}
+			admitted <- name
+		}()
+		<-queued
+	}
+	queue("low", 0)
+	queue("high", 5)
+
+	status, _ := manager.CapacityStatus()
+	if status.Waiting != 2 || status.Queue[0].ServiceName != "high" || status.Queue[0].Reason == "" {
+		t.Errorf("expected high priority first in the queue, got %+v", status.Queue)
+	}
+
+	manager.releaseCapacity("a")
+	if name := <-admitted; name != "high" {
+		t.Errorf("expected high priority to be admitted first, got %s", name)
+	}
+	manager.releaseCapacity("high")
+	if name := <-admitted; name != "low" {
+		t.Errorf("expected low priority to be admitted next, got %s", name)
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	sizes, err := parseWritableSizes([]byte(`[{"Id":"a","Names":["mcp-github"],"Size":{"rootFsSize":1000,"rwSize":4096}},{"Id":"b","Names":["mcp-slack"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sizes["mcp-github"] != 4096 || len(sizes) != 1 {
+		t.Errorf("unexpected sizes: %v", sizes)
+	}
+
+	for usage, expected := range map[float64]string{0.5: DiskLevelOK, 0.9: DiskLevelWarning, 0.97: DiskLevelCritical} {
+		if level := diskLevel(usage, 0.85, 0.95); level != expected {
+			t.Errorf("usage %.2f: expected %s, got %s", usage, expected, level)
+		}
+	}
+
+	manager := NewManager(&config.Config{Container: config.ContainerConfig{DefaultDiskQuota: "1g"}}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	container := &models.Container{Name: "mcp-github", Image: "ghcr.io/acme/github:1", Labels: map[string]string{}}
+	if args := createArgs(t, manager, container); !strings.Contains(args, "--storage-opt size=1g") {
+		t.Errorf("expected default disk quota in run args: %s", args)
+	}
+	container.Labels[DiskQuotaLabel] = "2g"
+	if args := createArgs(t, manager, container); !strings.Contains(args, "--storage-opt size=2g") {
+		t.Errorf("expected instance disk quota in run args: %s", args)
+	}
+}
+
+func TestBandwidth(t *testing.T) {
+	if bits, err := ParseBandwidth("10M"); err != nil || bits != 10_000_000 {
+		t.Errorf("expected 10 Mbit/s, got %d (%v)", bits, err)
+	}
+	for _, invalid := range []string{"", "fast", "-1M", "1k"} {
+		if _, err := ParseBandwidth(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+
+	labels, err := SetBandwidth(nil, "", "5M")
+	if err != nil || labels[EgressBandwidthLabel] != "5M" || len(labels) != 1 {
+		t.Errorf("unexpected labels %v (%v)", labels, err)
+	}
+
+	commands := bandwidthCommands("42", 8_000_000, 0)
+	if len(commands) != 2 {
+		t.Fatalf("expected ingress qdisc and filter, got %v", commands)
+	}
+	if got := strings.Join(commands[1], " "); got != "-t 42 -n tc filter replace dev eth0 parent ffff: protocol all prio 1 u32 match u32 0 0 police rate 8000000bit burst 100000 drop" {
+		t.Errorf("unexpected ingress filter: %s", got)
+	}
+	commands = bandwidthCommands("42", 0, 80_000)
+	if got := strings.Join(commands[0], " "); got != "-t 42 -n tc qdisc replace dev eth0 root tbf rate 80000bit burst 16384 latency 50ms" {
+		t.Errorf("unexpected egress qdisc: %s", got)
+	}
+}
+
+func TestEventAuthTokenIsDefaultOn(t *testing.T) {
+	for spec, want := range map[string]bool{`{}`: true, `{"auth": false}`: false, `{"auth": true}`: true, `{"auth": "yes"}`: true} {
+		var jsonSpec map[string]interface{}
+		if err := json.Unmarshal([]byte(spec), &jsonSpec); err != nil {
+			t.Fatal(err)
+		}
+		if got := authEnabled(jsonSpec); got != want {
+			t.Errorf("authEnabled(%s) = %v, want %v", spec, got, want)
+		}
+	}
+
+	env := map[string]string{}
+	token, err := eventAuthToken(map[string]interface{}{}, env)
+	if err != nil || token == "" || env[AuthTokenEnv] != token {
+		t.Errorf("generated token %q, env %q, err %v", token, env[AuthTokenEnv], err)
+	}
+	// A platform token is kept and not published again
+	env = map[string]string{AuthTokenEnv: "from-platform"}
+	if token, _ := eventAuthToken(map[string]interface{}{"auth": false}, env); token != "" || env[AuthTokenEnv] != "from-platform" {
+		t.Errorf("platform token replaced: %q, %q", token, env[AuthTokenEnv])
+	}
+	env = map[string]string{}
+	if token, _ := eventAuthToken(map[string]interface{}{"auth": false}, env); token != "" || env[AuthTokenEnv] != "" {
+		t.Error("auth: false still generated a token")
+	}
+}
+
+func TestSecurityPosture(t *testing.T) {
+	redact.AddSecret("resolved-from-store")
+	container := &models.Container{
+		Image: "ghcr.io/acme/github",
+		Environment: map[string]string{
+			"GITHUB_TOKEN":  "ghp_plaintext",
+			"DB_PASSWORD":   "resolved-from-store",
+			AuthTokenEnv:    "generated",
+			"LOG_LEVEL":     "debug",
+			"SLACK_API_KEY": "secret_ref:slack",
+		},
+	}
+	inspect := &ContainerInfo{}
+	inspect.Config.User = "0:0"
+	inspect.HostConfig.Privileged = true
+	inspect.HostConfig.Memory = 512 << 20
+
+	checks := map[string]string{}
+	for _, finding := range assessPosture(container, inspect) {
+		checks[finding.Check] = finding.Message
+	}
+	for _, check := range []string{"privileged", "runs_as_root", "no_cpu_limit", "latest_tag", "unpinned_digest"} {
+		if _, ok := checks[check]; !ok {
+			t.Errorf("expected %s finding, got %v", check, checks)
+		}
+	}
+	if _, ok := checks["no_memory_limit"]; ok {
+		t.Error("expected no memory limit finding")
+	}
+	if got := checks["plaintext_secrets"]; !strings.HasSuffix(got, ": GITHUB_TOKEN") {
+		t.Errorf("expected only GITHUB_TOKEN reported as plaintext, got %q", got)
+	}
+
+	container = &models.Container{Image: "ghcr.io/acme/github:1.2@sha256:abc"}
+	if findings := assessPosture(container, nil); len(findings) != 0 {
+		t.Errorf("expected a pinned image without inspect output to pass, got %v", findings)
+	}
+	if !usesLatestTag("localhost:5000/acme/github") || usesLatestTag("localhost:5000/acme/github:2") {
+		t.Error("unexpected latest tag detection with a registry port")
+	}
+}
+
+func TestAnomalyDetection(t *testing.T) {
+	manager := NewManager(&config.Config{Container: config.ContainerConfig{
+		AnomalyEgressRate: "1m",
+		AnomalyMaxPIDs:    100,
+		AnomalySamples:    2,
+	}}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	container := &models.Container{ServiceName: "github"}
+	start := time.Now()
+
+	// The first sample only records the egress counter
+	if reason := manager.checkAnomalies(container, ResourceUsage{NetOutput: 100 << 20}, start); reason != "" {
+		t.Fatalf("expected no anomaly on the first sample, got %q", reason)
+	}
+	// 60 MiB in 30s is 2 MiB/s: anomalous, but a single sample is not enough
+	if reason := manager.checkAnomalies(container, ResourceUsage{NetOutput: 160 << 20}, start.Add(30*time.Second)); reason != "" {
+		t.Fatalf("expected one anomalous sample to be tolerated, got %q", reason)
+	}
+	if reason := manager.checkAnomalies(container, ResourceUsage{NetOutput: 220 << 20}, start.Add(60*time.Second)); !strings.HasPrefix(reason, "egress of") {
+		t.Errorf("expected an egress anomaly, got %q", reason)
+	}
+	// A quiet sample resets the count
+	manager.checkAnomalies(container, ResourceUsage{NetOutput: 220 << 20}, start.Add(90*time.Second))
+	if reason := manager.checkAnomalies(container, ResourceUsage{NetOutput: 220 << 20, PIDs: 500}, start.Add(120*time.Second)); reason != "" {
+		t.Errorf("expected the count to restart after a quiet sample, got %q", reason)
+	}
+
+	manager.quarantines.services["github"] = Quarantine{ServiceName: "github", Source: QuarantineManual}
+	if manager.shouldContainerBeRunning(container) {
+		t.Error("expected a quarantined container not to be restarted")
+	}
+	manager.forgetQuarantine("github")
+	if manager.isQuarantined("github") {
+		t.Error("expected quarantine to be forgotten")
+	}
+
+	if got := strings.Join(egressBlockCommands("42")[1], " "); got != "-t 42 -n tc filter replace dev eth0 parent 1: protocol all prio 1 u32 match u32 0 0 action drop" {
+		t.Errorf("unexpected egress block filter: %s", got)
+	}
+}
+
+func TestLegacyAdoption(t *testing.T) {
+	ctx := context.Background()
+	instances := store.NewMemory().Instances()
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	manager.SetInstanceStore(instances)
+	manager.containers["legacy"] = &models.Container{ServiceName: "legacy", Image: "mcp/legacy:1.0", Port: 8000}
+	manager.containers["modern"] = &models.Container{
+		ServiceName: "modern",
+		Environment: map[string]string{"MCP_INSTANCE_ID": "inst-1"},
+	}
+
+	if legacy := manager.LegacyContainers(); len(legacy) != 1 || legacy[0] != "legacy" {
+		t.Fatalf("expected only the container without an instance ID to be legacy, got %v", legacy)
+	}
+
+	// A dry run changes nothing
+	if adoptions := manager.AdoptLegacyContainers(ctx, AdoptOptions{DryRun: true}); len(adoptions) != 1 {
+		t.Fatalf("expected one planned adoption, got %+v", adoptions)
+	}
+	if len(manager.LegacyContainers()) != 1 {
+		t.Fatal("expected a dry run not to adopt")
+	}
+
+	adoptions := manager.AdoptLegacyContainers(ctx, AdoptOptions{WorkspaceID: "ws-1"})
+	if len(adoptions) != 1 || adoptions[0].Error != "" || len(adoptions[0].InstanceID) != 36 {
+		t.Fatalf("expected one adoption with a UUID, got %+v", adoptions)
+	}
+	instanceID := adoptions[0].InstanceID
+	if env := manager.containers["legacy"].Environment; env["MCP_INSTANCE_ID"] != instanceID || env[WorkspaceIDEnv] != "ws-1" {
+		t.Errorf("expected the container to carry its new identity, got %v", env)
+	}
+	if instance, err := instances.Get(ctx, instanceID); err != nil || instance.Name != "legacy" || instance.WorkspaceID != "ws-1" {
+		t.Fatalf("expected the adoption to be recorded, got %+v (%v)", instance, err)
+	}
+
+	// After a restart the rediscovered container gets its ID back
+	manager.containers["legacy"] = &models.Container{ServiceName: "legacy"}
+	if err := manager.restoreAdoptions(ctx); err != nil {
+		t.Fatalf("restoreAdoptions failed: %v", err)
+	}
+	if got := manager.containers["legacy"].Environment["MCP_INSTANCE_ID"]; got != instanceID {
+		t.Errorf("expected the adopted ID to be restored, got %q", got)
+	}
+
+	manager.forgetAdoption(ctx, manager.containers["legacy"])
+	if _, err := instances.Get(ctx, instanceID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected the adoption record to be removed, got %v", err)
+	}
+}
+
+func TestRuntimeObservation(t *testing.T) {
+	since := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	args := logsArgs("abc", LogOptions{Tail: 50, Since: since, Follow: true})
+	if got := strings.Join(args, " "); got != "logs --tail 50 --since 2025-01-02T03:04:05Z --follow abc" {
+		t.Errorf("unexpected logs args: %s", got)
+	}
+	if got := strings.Join(logsArgs("abc", LogOptions{}), " "); got != "logs abc" {
+		t.Errorf("expected no options by default, got %s", got)
+	}
+
+	event, ok := parseRuntimeEvent([]byte(`{"ID":"abc","Name":"mcp-github","Status":"died","Type":"container"}`), "github")
+	if !ok || event.Action != "died" || event.ServiceName != "github" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if _, ok := parseRuntimeEvent([]byte(`not json`), "github"); ok {
+		t.Error("expected invalid lines to be skipped")
+	}
+}
+
+func TestRuntimeCommandTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := commandOutput(context.Background(), 50*time.Millisecond, "sleep", "5")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "sleep 5 timed out") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("hung command ran for %s", elapsed)
+	}
+
+	// A cancelled caller is reported as such, not as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := commandOutput(ctx, time.Minute, "sleep", "5"); err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+
+	if output, err := commandCombinedOutput(context.Background(), 0, "echo", "ok"); err != nil || strings.TrimSpace(string(output)) != "ok" {
+		t.Errorf("expected no deadline to run the command, got %q, %v", output, err)
+	}
+}
+
+func TestTemplateEnvironment(t *testing.T) {
+	vars := []models.MCPEnvVar{
+		{Name: "LOG_LEVEL", Default: "info"},
+		{Name: "REGION", Default: "us-east-1"},
+		{Name: "API_TOKEN", Required: true, Secret: true},
+		{Name: "WEBHOOK_SECRET", Required: true, Secret: true},
+	}
+	request := map[string]string{"REGION": "eu-west-1", "API_TOKEN": "secret-value", "EXTRA": "1"}
+
+	env, audit, missing := templateEnvironment(vars, request)
+	if env["LOG_LEVEL"] != "info" || env["REGION"] != "eu-west-1" || env["API_TOKEN"] != "secret-value" || env["EXTRA"] != "1" {
+		t.Errorf("unexpected effective environment: %v", env)
+	}
+	if len(missing) != 1 || missing[0] != "WEBHOOK_SECRET" {
+		t.Errorf("missing = %v, want [WEBHOOK_SECRET]", missing)
+	}
+
+	want := []EnvAuditEntry{
+		{Name: "API_TOKEN", Source: EnvSourceRequest, Secret: true},
+		{Name: "EXTRA", Source: EnvSourceRequest},
+		{Name: "LOG_LEVEL", Source: EnvSourceTemplate},
+		{Name: "REGION", Source: EnvSourceRequest, OverridesDefault: true},
+	}
+	if len(audit) != len(want) {
+		t.Fatalf("audit = %+v", audit)
+	}
+	for i := range want {
+		if audit[i] != want[i] {
+			t.Errorf("audit[%d] = %+v, want %+v", i, audit[i], want[i])
+		}
+	}
+}
+
+func TestSpecRecord(t *testing.T) {
+	m := &Manager{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	m.containers = map[string]*models.Container{"github": {
+		ServiceName: "github",
+		Environment: map[string]string{"GITHUB_TOKEN": "ghp_resolved-value", "MCP_INSTANCE_ID": "i-1", AuthTokenEnv: "tok"},
+	}}
+	submitted := map[string]any{"image": "mcp/github", "environment": map[string]any{"GITHUB_TOKEN": "ghp_resolved-value"}}
+	m.RecordSpec("github", submitted, map[string]string{"GITHUB_TOKEN": "ghp_resolved-value"})
+
+	record, ok := m.SpecRecord("github")
+	if !ok {
+		t.Fatal("spec not recorded")
+	}
+	if env := record.Submitted["environment"].(map[string]any); env["GITHUB_TOKEN"] != redact.Placeholder {
+		t.Errorf("submitted secret not redacted: %v", env)
+	}
+	want := []EnvAuditEntry{
+		{Name: "GITHUB_TOKEN", Source: EnvSourceRequest},
+		{Name: AuthTokenEnv, Source: EnvSourceSystem, Secret: true},
+		{Name: "MCP_INSTANCE_ID", Source: EnvSourceSystem},
+	}
+	if len(record.Environment) != len(want) {
+		t.Fatalf("environment = %+v", record.Environment)
+	}
+	for i := range want {
+		if record.Environment[i] != want[i] {
+			t.Errorf("environment[%d] = %+v, want %+v", i, record.Environment[i], want[i])
+		}
+	}
+
+	if _, ok := m.SpecRecord("unknown"); ok {
+		t.Error("record for an unknown service")
+	}
+}
+
+func TestRuntimeOptions(t *testing.T) {
+	opts := ParseRuntimeOptions(map[string]interface{}{
+		"timezone": "Europe/Berlin",
+		"locale":   "de_DE.UTF-8",
+		"ulimits":  map[string]interface{}{"nofile": float64(65536)},
+		"sysctls":  map[string]interface{}{"net.ipv4.ip_local_port_range": "1024 65000", "net.ipv4.tcp_syncookies": "1"},
+	})
+	labels, err := SetRuntimeOptions(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels[SysctlsLabel] != "net.ipv4.ip_local_port_range=1024 65000,net.ipv4.tcp_syncookies=1" || labels[UlimitNprocLabel] != "" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if restored := RuntimeOptionsFromLabels(labels); restored.Timezone != opts.Timezone || restored.Ulimits != opts.Ulimits || len(restored.Sysctls) != 2 {
+		t.Errorf("labels did not round-trip: %+v", restored)
+	}
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	container := &models.Container{Name: "mcp-fs", Image: "ghcr.io/acme/fs:1", Labels: labels, Environment: map[string]string{"LANG": "en_US.UTF-8"}}
+	args := createArgs(t, manager, container)
+	for _, expected := range []string{"--tz Europe/Berlin", "-e TZ=Europe/Berlin", "-e LC_ALL=de_DE.UTF-8", "--ulimit nofile=65536:65536", "--sysctl net.ipv4.tcp_syncookies=1"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("expected %q in run args: %s", expected, args)
+		}
+	}
+	if strings.Contains(args, "LANG=de_DE") {
+		t.Errorf("request LANG was overridden: %s", args)
+	}
+
+	for name, invalid := range map[string]RuntimeOptions{
+		"timezone":      {Timezone: "Mars/Olympus"},
+		"locale":        {Locale: "en_US; rm -rf"},
+		"nofile":        {Ulimits: Ulimits{Nofile: 1 << 30}},
+		"nproc":         {Ulimits: Ulimits{Nproc: -1}},
+		"unsafe sysctl": {Sysctls: map[string]string{"kernel.panic": "1"}},
+		"sysctl value":  {Sysctls: map[string]string{"net.ipv4.tcp_syncookies": "1,2"}},
+		"absolute zone": {Timezone: "/etc/localtime"},
+	} {
+		if _, err := SetRuntimeOptions(nil, invalid); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestHostAccess(t *testing.T) {
+	policy := NewHostAccessPolicy([]string{"/dev/ttyUSB*"}, []string{"/run/cups/cups.sock", "/run/*.sock"})
+	access := HostAccess{Devices: []string{"/dev/ttyUSB0"}, HostSockets: []string{"/run/cups/cups.sock"}}
+
+	if err := policy.Check("ws-1", access); !errors.Is(err, ErrHostAccessDenied) {
+		t.Errorf("expected access without a grant to be denied, got %v", err)
+	}
+	if err := policy.Grant("ws-1", access); err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Check("ws-1", access); err != nil {
+		t.Errorf("expected granted access to pass: %v", err)
+	}
+	if err := policy.Check("ws-2", access); !errors.Is(err, ErrHostAccessDenied) {
+		t.Errorf("expected another workspace to be denied, got %v", err)
+	}
+	if err := policy.Grant("ws-1", HostAccess{}); err != nil || len(policy.Grants()) != 0 {
+		t.Errorf("expected an empty grant to revoke: %v %v", err, policy.Grants())
+	}
+
+	for name, invalid := range map[string]HostAccess{
+		"not allow-listed": {Devices: []string{"/dev/sda"}},
+		"outside /dev":     {Devices: []string{"/etc/shadow"}},
+		"traversal":        {Devices: []string{"/dev/ttyUSB0/../sda"}},
+		"relative":         {HostSockets: []string{"run/cups/cups.sock"}},
+		"runtime socket":   {HostSockets: []string{"/run/podman.sock"}},
+		"separator":        {Devices: []string{"/dev/ttyUSB0,/dev/sda"}},
+	} {
+		if err := policy.Grant("ws-1", invalid); !errors.Is(err, ErrHostAccessDenied) {
+			t.Errorf("%s: expected the grant to be refused, got %v", name, err)
+		}
+	}
+	var disabled *HostAccessPolicy
+	if err := disabled.Check("ws-1", access); !errors.Is(err, ErrHostAccessDenied) {
+		t.Errorf("expected access to be denied without a policy, got %v", err)
+	}
+
+	// Sockets the manager proxies to need no grant, but never reach the runtime
+	if err := policy.CheckSocket("/run/cups/cups.sock"); err != nil {
+		t.Errorf("expected an allow-listed socket to pass: %v", err)
+	}
+	policy.SetRuntimeSocket("/run/runtime-api.sock")
+	for _, socket := range []string{"/run/runtime-api.sock", "/run/podman.sock", "/run/docker.sock", "/var/run/other.sock", "/run/../var/run/docker.sock"} {
+		if err := policy.CheckSocket(socket); !errors.Is(err, ErrHostAccessDenied) {
+			t.Errorf("%s: expected the socket to be refused, got %v", socket, err)
+		}
+	}
+	if err := disabled.CheckSocket("/run/cups/cups.sock"); !errors.Is(err, ErrHostAccessDenied) {
+		t.Errorf("expected sockets to be refused without a policy, got %v", err)
+	}
+
+	labels := SetHostAccess(map[string]string{DevicesLabel: "/dev/sda"}, access)
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	args := createArgs(t, manager, &models.Container{Name: "mcp-serial", Image: "serial:1", Labels: labels})
+	if !strings.Contains(args, "--device /dev/ttyUSB0") || !strings.Contains(args, "-v /run/cups/cups.sock:/run/cups/cups.sock") || strings.Contains(args, "/dev/sda") {
+		t.Errorf("unexpected run args: %s", args)
+	}
+}
+
+func TestDNSOptions(t *testing.T) {
+	opts := ParseDNSOptions(map[string]interface{}{
+		"dns_servers": []interface{}{"10.0.0.53", "fd00::53"},
+		"dns_search":  []interface{}{"corp.example.com"},
+		"extra_hosts": map[string]interface{}{"git.corp.example.com": "10.0.4.20", "db.corp.example.com": "10.0.4.21"},
+	})
+	labels, err := SetDNSOptions(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored := DNSOptionsFromLabels(labels); len(restored.DNSServers) != 2 || restored.ExtraHosts["db.corp.example.com"] != "10.0.4.21" {
+		t.Errorf("labels did not round-trip: %+v", restored)
+	}
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	args := createArgs(t, manager, &models.Container{Name: "mcp-git", Image: "git:1", Labels: labels})
+	if !strings.Contains(args, "--dns fd00::53 --dns-search corp.example.com --add-host db.corp.example.com:10.0.4.21 --add-host git.corp.example.com:10.0.4.20") {
+		t.Errorf("unexpected run args: %s", args)
+	}
+
+	for name, invalid := range map[string]DNSOptions{
+		"server name":    {DNSServers: []string{"dns.example.com"}},
+		"too many":       {DNSServers: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		"search domain":  {DNSSearch: []string{"corp example"}},
+		"host separator": {ExtraHosts: map[string]string{"a=b": "10.0.0.1"}},
+		"host address":   {ExtraHosts: map[string]string{"git": "10.0.0"}},
+	} {
+		if _, err := SetDNSOptions(nil, invalid); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestScratchVolume(t *testing.T) {
+	scratch, err := ParseScratch(map[string]interface{}{"scratch": map[string]interface{}{"size": "1Gi", "path": "/workspace/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels, err := SetScratch(map[string]string{ScratchPathLabel: "/smuggled"}, scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels[ScratchSizeLabel] != "1073741824" || labels[ScratchPathLabel] != "/workspace" {
+		t.Errorf("labels = %v", labels)
+	}
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	spec, err := manager.buildCreateSpec(&models.Container{Name: "mcp-git", Image: "git:1", Labels: labels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(runArgs(spec), " "); !strings.Contains(args, "--tmpfs /workspace:rw,nosuid,nodev,size=1073741824,mode=1777") {
+		t.Errorf("unexpected run args: %s", args)
+	}
+	if spec.HostConfig.Tmpfs["/workspace"] != "rw,nosuid,nodev,size=1073741824,mode=1777" {
+		t.Errorf("tmpfs = %v", spec.HostConfig.Tmpfs)
+	}
+
+	if scratch, _ := ParseScratch(map[string]interface{}{"scratch": map[string]interface{}{"size": "512m"}}); scratch.MountPath() != DefaultScratchPath {
+		t.Errorf("default path = %q", scratch.MountPath())
+	}
+	for name, invalid := range map[string]ScratchVolume{
+		"no size":       {Path: "/workspace"},
+		"zero size":     {Size: "0Gi"},
+		"bad size":      {Size: "lots"},
+		"relative path": {Size: "1Gi", Path: "workspace"},
+		"system path":   {Size: "1Gi", Path: "/etc/"},
+	} {
+		if _, err := SetScratch(nil, &invalid); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEgressAllow(t *testing.T) {
+	allow := ParseEgressAllow(map[string]interface{}{"egress_allow": []interface{}{"API.GitHub.com.", "*.googleapis.com"}})
+	labels, err := SetEgressAllow(map[string]string{EgressAllowLabel: "*"}, allow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(EgressAllowFromLabels(labels), ","); got != "api.github.com,*.googleapis.com" {
+		t.Errorf("allow-list = %q", got)
+	}
+	if _, err := SetEgressAllow(nil, []string{"*"}); err == nil {
+		t.Error("expected an error for a bare wildcard")
+	}
+	if _, err := SetEgressAllow(map[string]string{DNSServersLabel: "10.0.0.53"}, allow); err == nil {
+		t.Error("expected an error when combined with dns_servers")
+	}
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := manager.setEgressAllow(nil, allow); !errors.Is(err, ErrEgressFilterDisabled) {
+		t.Errorf("expected ErrEgressFilterDisabled, got %v", err)
+	}
+	filter, err := egress.NewFilter(config.EgressConfig{Address: "10.89.0.2", Upstream: "127.0.0.1"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetEgressFilter(filter)
+	args := createArgs(t, manager, &models.Container{Name: "mcp-git", Image: "git:1", Labels: labels})
+	if !strings.Contains(args, "--dns 10.89.0.2") {
+		t.Errorf("resolver missing from run args: %s", args)
+	}
+	if args := createArgs(t, manager, &models.Container{Name: "mcp-open", Image: "git:1"}); strings.Contains(args, "--dns") {
+		t.Errorf("unfiltered instance uses the resolver: %s", args)
+	}
+}
+
+func TestCostAttribution(t *testing.T) {
+	jsonSpec := map[string]interface{}{"owner": "Jane.Doe@Example.com", "cost_center": "CC 4711"}
+	labels := SetAttribution(map[string]string{TemplateLabel: "io.github/weather", OwnerLabel: "smuggled"}, "WS_42", ParseAttribution(jsonSpec))
+	for label, want := range map[string]string{
+		WorkspaceLabel:    "ws-42",
+		CostTemplateLabel: "io-github-weather",
+		OwnerLabel:        "jane-doe-example-com",
+		CostCenterLabel:   "cc-4711",
+	} {
+		if labels[label] != want {
+			t.Errorf("%s = %q, want %q", label, labels[label], want)
+		}
+	}
+	if labels = SetAttribution(labels, "ws-42", Attribution{}); labels[OwnerLabel] != "" {
+		t.Error("owner kept after it was cleared")
+	}
+
+	aliases := []string{"cost-center=department", "workspace=team"}
+	manager := NewManager(&config.Config{Container: config.ContainerConfig{CostLabelAliases: aliases}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	labels[CostCenterLabel] = "cc-4711"
+	args := createArgs(t, manager, &models.Container{Name: "mcp-weather", Image: "weather:1", Labels: labels})
+	if !strings.Contains(args, "--label department=cc-4711 --label team=ws-42") {
+		t.Errorf("cost labels missing from run args: %s", args)
+	}
+
+	for _, invalid := range [][]string{{"budget=finance"}, {"owner"}, {"owner=Team/Owner"}} {
+		if _, err := ParseCostLabelAliases(invalid); err == nil {
+			t.Errorf("alias %q was accepted", invalid)
+		}
+	}
+}
+
+func TestRouteSweep(t *testing.T) {
+	cfg := &config.Config{Traefik: config.TraefikConfig{ConfigPath: t.TempDir() + "/dynamic.yml"}}
+	traefik := NewTraefikManager(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if removed, _, err := traefik.Sweep(nil, false); err != nil || removed != nil {
+		t.Fatalf("sweep without a config file = %v, %v", removed, err)
+	}
+	for slug, ip := range map[string]string{"git-1a2b": "10.88.0.5", "gone-3c4d": "10.88.0.6", "idle-5e6f": "10.88.0.7"} {
+		if err := traefik.AddMCPService(context.Background(), slug, ip, 8000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	targets := map[string]string{"git-1a2b": "http://10.88.0.9:8000", "idle-5e6f": ""}
+
+	removed, repaired, err := traefik.Sweep(targets, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(removed, ",") != "gone-3c4d" || len(repaired) != 1 || repaired[0].From != "http://10.88.0.5:8000" {
+		t.Fatalf("dry run = %v, %+v", removed, repaired)
+	}
+	if config, _ := traefik.LoadConfig(); config.HTTP.Routers["mcp-gone-3c4d"].Service == "" {
+		t.Fatal("dry run changed the config")
+	}
+
+	if _, _, err := traefik.Sweep(targets, false); err != nil {
+		t.Fatal(err)
+	}
+	config, err := traefik.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.HTTP.Routers["mcp-gone-3c4d"]; ok {
+		t.Error("route of a vanished container kept")
+	}
+	if _, ok := config.HTTP.Services["mcp-gone-3c4d-service"]; ok {
+		t.Error("service of a vanished container kept")
+	}
+	if _, ok := config.HTTP.Middlewares["mcp-gone-3c4d-stripprefix"]; ok {
+		t.Error("middleware of a vanished container kept")
+	}
+	if url := config.HTTP.Services["mcp-git-1a2b-service"].LoadBalancer.Servers[0].URL; url != "http://10.88.0.9:8000" {
+		t.Errorf("repaired URL = %s", url)
+	}
+	if url := config.HTTP.Services["mcp-idle-5e6f-service"].LoadBalancer.Servers[0].URL; url != "http://10.88.0.7:8000" {
+		t.Errorf("route of a stopped container changed to %s", url)
+	}
+	if _, ok := config.HTTP.Services["mcp-manager-service"]; !ok {
+		t.Error("manager service removed")
+	}
+}
+
+func TestHandoffState(t *testing.T) {
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	created := time.Now().Add(-time.Hour)
+	manager.containers["github"] = &models.Container{
+		ID: "abc", ServiceName: "github", Status: models.StatusRunning, CreatedAt: created,
+		Environment: map[string]string{"MCP_INSTANCE_ID": "inst-1", "GITHUB_TOKEN": "secret"},
+	}
+	manager.containers["slack"] = &models.Container{ID: "def", ServiceName: "slack", Status: models.StatusStarting}
+
+	state := manager.ExportState()
+	if len(state.Containers) != 2 || state.Containers[0].ServiceName != "github" {
+		t.Fatalf("unexpected exported containers: %+v", state.Containers)
+	}
+	if len(state.Operations) != 1 || state.Operations[0].ServiceName != "slack" || state.Operations[0].Status != "starting" {
+		t.Errorf("expected the starting container as an operation, got %+v", state.Operations)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := manager.Settle(ctx); err == nil {
+		t.Error("expected settling to time out with an operation in flight")
+	}
+
+	// The replacement discovered the running container with its identity only
+	replacement := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	replacement.containers["github"] = &models.Container{
+		ID: "abc", ServiceName: "github", Status: models.StatusRunning, CreatedAt: time.Now(),
+		Environment: map[string]string{"MCP_INSTANCE_ID": "inst-1"},
+	}
+	missing := replacement.ImportState(state)
+	if len(missing) != 1 || missing[0] != "slack" {
+		t.Errorf("expected slack to be missing, got %v", missing)
+	}
+	github := replacement.containers["github"]
+	if !github.CreatedAt.Equal(created) || github.Environment["GITHUB_TOKEN"] != "secret" {
+		t.Errorf("expected the handed over creation time and environment, got %+v", github)
+	}
+	if err := replacement.Settle(context.Background()); err != nil {
+		t.Errorf("expected no operations in flight, got %v", err)
+	}
+}
+
+func TestDiffDrift(t *testing.T) {
+	desired := driftState{
+		Image:    "mcp/github:1.2",
+		ImageID:  "aaa",
+		Env:      map[string]string{"MCP_INSTANCE_ID": "inst-1", "GITHUB_TOKEN": "secret", "LOG_LEVEL": "info"},
+		Memory:   512 << 20,
+		NanoCPUs: 1e9,
+	}
+	actual := driftState{
+		Image:    "mcp/github:1.2",
+		ImageID:  "sha256:aaa",
+		Env:      map[string]string{"MCP_INSTANCE_ID": "inst-1", "GITHUB_TOKEN": "secret", "LOG_LEVEL": "info", "PATH": "/usr/bin", "HOSTNAME": "abc"},
+		Command:  []string{"serve"},
+		Memory:   512 << 20,
+		NanoCPUs: 1e9,
+	}
+	imageEnv := map[string]string{"PATH": "/usr/bin"}
+	if changes := diffDrift(desired, actual, imageEnv); len(changes) != 0 {
+		t.Fatalf("expected no drift, got %+v", changes)
+	}
+
+	actual.ImageID = "sha256:bbb"
+	actual.Env = map[string]string{"MCP_INSTANCE_ID": "inst-1", "GITHUB_TOKEN": "changed", "DEBUG": "1", "PATH": "/usr/bin"}
+	actual.Memory = 0
+	changes := diffDrift(desired, actual, imageEnv)
+	want := []DriftChange{
+		{Field: "image_digest", Change: DriftModified, Desired: "sha256:aaa", Actual: "sha256:bbb"},
+		{Field: "memory", Change: DriftModified, Desired: "536870912", Actual: "0"},
+		{Field: "env", Key: "GITHUB_TOKEN", Change: DriftModified},
+		{Field: "env", Key: "LOG_LEVEL", Change: DriftMissing},
+		{Field: "env", Key: "DEBUG", Change: DriftUnexpected},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestCrashLoops(t *testing.T) {
+	loops := newCrashLoops()
+	start := time.Now()
+	window := 10 * time.Minute
+
+	for i, want := range []bool{false, false, true, false} {
+		if _, looping := loops.record("github", start.Add(time.Duration(i)*time.Minute), 3, window); looping != want {
+			t.Errorf("failure %d: looping = %v, want %v", i+1, looping, want)
+		}
+	}
+	// Old failures age out of the window, and a new loop is reported again
+	later := start.Add(time.Hour)
+	for i, want := range []bool{false, false, true} {
+		if _, looping := loops.record("github", later.Add(time.Duration(i)*time.Minute), 3, window); looping != want {
+			t.Errorf("later failure %d: looping = %v, want %v", i+1, looping, want)
+		}
+	}
+}
+
+func TestScheduledRestartSkipsReserved(t *testing.T) {
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	policy, err := ParseRestartPolicy(map[string]interface{}{
+		"restart_policy": map[string]interface{}{"max_uptime": "1h", "skip_if_active": false},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	manager.containers["github"] = &models.Container{
+		ServiceName: "github",
+		Status:      models.StatusRunning,
+		Labels:      policy.Labels(),
+		CreatedAt:   now.Add(-2 * time.Hour),
+	}
+
+	var checked []string
+	manager.SetReservationCheck(func(serviceName string) bool {
+		checked = append(checked, serviceName)
+		return true
+	})
+	manager.runScheduledRestarts(context.Background(), now)
+
+	if len(checked) != 1 || checked[0] != "github" {
+		t.Errorf("expected the reservation of github to be checked, got %v", checked)
+	}
+	if _, restarted := manager.restartedAt["github"]; restarted {
+		t.Error("expected the restart of a reserved instance to be postponed")
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	vars := NewCommandVars("inst-1", "github", 8080, "ws-1", map[string]string{
+		"MODE":  "read-only",
+		"QUERY": "a; rm -rf /",
+	})
+
+	tests := []struct {
+		name    string
+		command []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "references",
+			command: []string{"server", "--port=${instance.port}", "--mode", "${env.MODE}", "--id=${instance.id}"},
+			want:    []string{"server", "--port=8080", "--mode", "read-only", "--id=inst-1"},
+		},
+		{
+			name:    "escaped reference",
+			command: []string{"echo", "$${env.MODE}"},
+			want:    []string{"echo", "${env.MODE}"},
+		},
+		{
+			name:    "unsafe value outside a shell script",
+			command: []string{"server", "--query", "${env.QUERY}"},
+			want:    []string{"server", "--query", "a; rm -rf /"},
+		},
+		{
+			name:    "safe value in a shell script",
+			command: []string{"/bin/sh", "-ec", "exec server --mode ${env.MODE} --workspace ${instance.workspace_id}"},
+			want:    []string{"/bin/sh", "-ec", "exec server --mode read-only --workspace ws-1"},
+		},
+		{
+			name:    "unsafe value in a shell script",
+			command: []string{"sh", "-c", "server --query ${env.QUERY}"},
+			wantErr: ErrUnsafeCommand,
+		},
+		{name: "unset reference", command: []string{"server", "${env.MISSING}"}},
+		{name: "unknown namespace", command: []string{"server", "${secret.TOKEN}"}},
+		{name: "unknown instance field", command: []string{"server", "${instance.image}"}},
+		{name: "NUL byte", command: []string{"server", "a\x00b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandCommand(tt.command, vars)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShellScriptIndex(t *testing.T) {
+	tests := []struct {
+		command []string
+		want    int
+	}{
+		{[]string{"sh", "-c", "echo"}, 2},
+		{[]string{"/bin/bash", "-e", "-c", "echo"}, 3},
+		{[]string{"bash", "-ec", "echo"}, 2},
+		{[]string{"bash", "--login", "-c", "echo"}, 3},
+		{[]string{"sh", "script.sh", "-c"}, -1},
+		{[]string{"sh", "-c"}, -1},
+		{[]string{"python", "-c", "print(1)"}, -1},
+		{nil, -1},
+	}
+	for _, tt := range tests {
+		if got := shellScriptIndex(tt.command); got != tt.want {
+			t.Errorf("shellScriptIndex(%q) = %d, want %d", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestFindProgram(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"usr/bin", "app"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]os.FileMode{"usr/bin/node": 0o755, "app/server": 0o755, "app/config.json": 0o644}
+	for name, mode := range files {
+		if err := os.WriteFile(filepath.Join(root, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Images link absolute paths, which must resolve inside the image
+	if err := os.Symlink("/usr/bin", filepath.Join(root, "bin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/bin/node", filepath.Join(root, "app/node")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		program string
+		workDir string
+		path    []string
+		want    string
+		found   bool
+	}{
+		{program: "node", path: []string{"/usr/local/bin", "/bin"}, want: "/bin/node", found: true},
+		{program: "python", path: []string{"/usr/bin", "/bin"}},
+		{program: "/app/server", want: "/app/server", found: true},
+		{program: "./server", workDir: "/app", want: "/app/server", found: true},
+		{program: "./node", workDir: "/app", want: "/app/node", found: true},
+		{program: "/app/config.json", want: "/app/config.json"},
+		{program: "/app", want: "/app"},
+	}
+	for _, tt := range tests {
+		got, found := findProgram(root, tt.program, tt.workDir, tt.path)
+		if found != tt.found || (tt.want != "" && got != tt.want) {
+			t.Errorf("findProgram(%q) = %q, %v; want %q, %v", tt.program, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestImageDefaults(t *testing.T) {
+	ports := parseExposedPorts(map[string]struct{}{"8080/tcp": {}, "53/udp": {}, "3000": {}, "bogus/tcp": {}})
+	if len(ports) != 2 || ports[0] != 3000 || ports[1] != 8080 {
+		t.Errorf("parseExposedPorts = %v", ports)
+	}
+
+	if metadata, _ := imageMetadata(&imageConfig{Labels: map[string]string{"maintainer": "someone"}}); metadata != nil {
+		t.Errorf("expected no metadata for an image declaring nothing, got %+v", *metadata)
+	}
+	metadata, _ := imageMetadata(&imageConfig{Entrypoint: []string{"node"}, Cmd: []string{"server.js"}, ExposedPorts: []int{3000}})
+	if metadata == nil || strings.Join(metadata.Entrypoint, " ") != "node" || strings.Join(metadata.Command, " ") != "server.js" {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+
+	tests := []struct {
+		metadata *models.ImageMetadata
+		port     int
+		source   string
+	}{
+		{nil, DefaultContainerPort, PortSourceDefault},
+		{metadata, 3000, PortSourceExposed},
+		{&models.ImageMetadata{Port: 9000, ExposedPorts: []int{3000}}, 9000, PortSourceLabel},
+		{&models.ImageMetadata{ExposedPorts: []int{3000, 9000}}, DefaultContainerPort, PortSourceDefault},
+	}
+	for _, tt := range tests {
+		if port, source := DefaultPort(tt.metadata); port != tt.port || source != tt.source {
+			t.Errorf("DefaultPort(%+v) = %d, %s; want %d, %s", tt.metadata, port, source, tt.port, tt.source)
+		}
+	}
+}
+
+func TestParseImageMetadata(t *testing.T) {
+	if metadata, problems := ParseImageMetadata(map[string]string{"maintainer": "someone"}); metadata != nil || problems != nil {
+		t.Errorf("expected no metadata without mcp.* labels, got %+v, %v", metadata, problems)
+	}
+
+	metadata, problems := ParseImageMetadata(map[string]string{
+		ImageTransportLabel:   "Streamable-HTTP",
+		ImagePortLabel:        "3000",
+		ImageHealthPathLabel:  "/healthz",
+		ImageRequiredEnvLabel: "GITHUB_TOKEN, API_URL,,",
+	})
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	want := models.ImageMetadata{Transport: TransportStreamableHTTP, Port: 3000, HealthPath: "/healthz", RequiredEnv: []string{"API_URL", "GITHUB_TOKEN"}}
+	if metadata.Transport != want.Transport || metadata.Port != want.Port || metadata.HealthPath != want.HealthPath ||
+		strings.Join(metadata.RequiredEnv, ",") != strings.Join(want.RequiredEnv, ",") {
+		t.Errorf("expected %+v, got %+v", want, *metadata)
+	}
+
+	// Invalid values are reported and ignored
+	metadata, problems = ParseImageMetadata(map[string]string{
+		ImageTransportLabel:   "websocket",
+		ImagePortLabel:        "70000",
+		ImageHealthPathLabel:  "healthz",
+		ImageRequiredEnvLabel: "TOKEN,not-a-name",
+	})
+	if len(problems) != 4 {
+		t.Errorf("expected 4 problems, got %v", problems)
+	}
+	if metadata.Transport != "" || metadata.Port != 0 || metadata.HealthPath != "" || strings.Join(metadata.RequiredEnv, ",") != "TOKEN" {
+		t.Errorf("expected only the valid values to be kept, got %+v", *metadata)
+	}
+}
+
+func TestCheckImageRequirements(t *testing.T) {
+	if err := CheckImageRequirements(nil, nil); err != nil {
+		t.Errorf("expected images without labels to pass, got %v", err)
+	}
+
+	metadata := &models.ImageMetadata{Transport: TransportSSE, RequiredEnv: []string{"API_URL", "TOKEN"}}
+	err := CheckImageRequirements(metadata, map[string]string{"TOKEN": "secret", "API_URL": ""})
+	if !errors.Is(err, ErrImageRequirements) || !strings.Contains(err.Error(), "API_URL") || strings.Contains(err.Error(), "TOKEN") {
+		t.Errorf("expected API_URL to be reported missing, got %v", err)
+	}
+	if err := CheckImageRequirements(metadata, map[string]string{"TOKEN": "secret", "API_URL": "https://api"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := CheckImageRequirements(&models.ImageMetadata{Transport: TransportStdio}, nil); !errors.Is(err, ErrImageRequirements) {
+		t.Errorf("expected stdio images to be rejected, got %v", err)
+	}
+}
+
+func TestHealthPathLabel(t *testing.T) {
+	labels := SetHealthPath(map[string]string{HealthPathLabel: "/old", "team": "ml"}, "/healthz")
+	if HealthPath(labels) != "/healthz" || labels["team"] != "ml" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if labels = SetHealthPath(labels, "/"); HealthPath(labels) != "" {
+		t.Errorf("expected / to keep the default, got %q", HealthPath(labels))
+	}
+	if HealthPath(map[string]string{HealthPathLabel: "healthz"}) != "" {
+		t.Error("expected a relative path to be ignored")
+	}
+}
+
+// recordingRouter records the routes added through it
+type recordingRouter struct {
+	added chan string
+}
+
+func (r *recordingRouter) AddMCPService(_ context.Context, slug, ip string, port int) error {
+	r.added <- fmt.Sprintf("%s=%s:%d", slug, ip, port)
+	return nil
+}
+
+func TestPublishRouteWaitsForServer(t *testing.T) {
+	var serving atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || !serving.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound) // Answered by the server itself
+	}))
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+
+	manager := NewManager(&config.Config{Traefik: config.TraefikConfig{RouteReadyTimeout: 5 * time.Second}},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	router := &recordingRouter{added: make(chan string, 2)}
+	manager.SetRouteManager(router)
+	container := &models.Container{
+		ID:          "c1",
+		ServiceName: "github",
+		Slug:        "github-1a2b",
+		Port:        port,
+		Labels:      SetHealthPath(nil, "/healthz"),
+	}
+	manager.containers["github"] = container
+
+	manager.publishRoute(context.Background(), container, "127.0.0.1")
+	select {
+	case route := <-router.added:
+		t.Fatalf("route %s published before the server answered", route)
+	case <-time.After(3 * routeProbeInterval):
+	}
+
+	serving.Store(true)
+	select {
+	case route := <-router.added:
+		if route != fmt.Sprintf("github-1a2b=127.0.0.1:%d", port) {
+			t.Errorf("unexpected route %s", route)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("route not published after the server answered")
+	}
+
+	// A container replaced while waiting does not get the old route
+	manager.awaitRoute(container, "c0", "127.0.0.1", time.Second)
+	select {
+	case route := <-router.added:
+		t.Errorf("route %s published for a replaced container", route)
+	default:
+	}
+}
+
+func TestProbeReady(t *testing.T) {
+	tests := []struct {
+		status     int
+		healthPath bool
+		want       bool
+	}{
+		{http.StatusOK, true, true},
+		{http.StatusFound, true, true},
+		{http.StatusNotFound, true, false},
+		{http.StatusNotFound, false, true},
+		{http.StatusMethodNotAllowed, false, true},
+		{http.StatusServiceUnavailable, false, false},
+		{http.StatusServiceUnavailable, true, false},
+	}
+	for _, tt := range tests {
+		if got := probeReady(tt.status, tt.healthPath); got != tt.want {
+			t.Errorf("probeReady(%d, %v) = %v, want %v", tt.status, tt.healthPath, got, tt.want)
+		}
+	}
+}
+
+func TestDetermineContainerStatusSeparatesReadiness(t *testing.T) {
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name   string
+		result HealthCheckResult
+		want   models.ContainerStatus
+	}{
+		{"live and ready", HealthCheckResult{Live: true, Ready: true, probed: true}, models.StatusRunning},
+		{"live, not ready", HealthCheckResult{Live: true, probed: true, Error: "HTTP endpoint not ready: status 503"}, models.StatusUnhealthy},
+		{"not answering", HealthCheckResult{Status: models.StatusRunning, probed: true, Error: "HTTP request failed"}, models.StatusError},
+		{"not probed", HealthCheckResult{Live: true, Status: models.StatusRunning, Error: "Could not determine container IP for health check"}, models.StatusError},
+		{"stopped", HealthCheckResult{Status: models.StatusStopped, Error: "Container is not running"}, models.StatusStopped},
+	}
+	for _, tt := range tests {
+		if got := manager.determineContainerStatus(&tt.result); got != tt.want {
+			t.Errorf("%s: status %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// removingRouter records the routes removed through it
+type removingRouter struct {
+	removed []string
+}
+
+func (r *removingRouter) RemoveMCPService(_ context.Context, slug string) error {
+	r.removed = append(r.removed, slug)
+	return nil
+}
+
+func TestApplyProbes(t *testing.T) {
+	manager := NewManager(&config.Config{Container: config.ContainerConfig{
+		ReadinessFailures:    2,
+		LivenessFailures:     2,
+		LivenessInitialDelay: time.Minute,
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	router := &removingRouter{}
+	manager.SetRouteManager(router)
+	container := &models.Container{ID: "c1", ServiceName: "github", Slug: "github-1a2b", CreatedAt: time.Now().Add(-time.Hour)}
+	manager.containers["github"] = container
+
+	unready := &HealthCheckResult{ContainerID: "c1", Live: true, probed: true}
+	manager.applyProbes(container, unready)
+	if len(router.removed) != 0 {
+		t.Fatalf("route removed after one failed readiness check: %v", router.removed)
+	}
+	manager.applyProbes(container, unready)
+	manager.applyProbes(container, unready)
+	if len(router.removed) != 1 || router.removed[0] != "github-1a2b" {
+		t.Fatalf("expected the route removed once, got %v", router.removed)
+	}
+	if state := manager.probes["github"]; !state.withdrawn || state.livenessFailures != 0 {
+		t.Errorf("unexpected probe state %+v", *state)
+	}
+
+	// Results of a replaced container are ignored
+	manager.applyProbes(container, &HealthCheckResult{ContainerID: "c0", Live: true, Ready: true, probed: true})
+	if !manager.probes["github"].withdrawn {
+		t.Error("result of another container restored the route")
+	}
+
+	state := &probeState{}
+	dead := &HealthCheckResult{ContainerID: "c1", probed: true}
+	now := time.Now()
+	if manager.livenessFailedUnsafe(container, dead, state, now) {
+		t.Fatal("restart after one failed liveness check")
+	}
+	if !manager.livenessFailedUnsafe(container, dead, state, now) {
+		t.Fatal("expected a restart after two failed liveness checks")
+	}
+	if state.livenessFailures != 0 {
+		t.Errorf("failures not reset after the restart: %d", state.livenessFailures)
+	}
+
+	// Failures right after the container started are not counted
+	manager.restartedAt["github"] = now
+	for range 3 {
+		if manager.livenessFailedUnsafe(container, dead, state, now.Add(time.Second)) {
+			t.Fatal("restart within the initial delay")
+		}
+	}
+}
+
+func TestStartupProbe(t *testing.T) {
+	probe, err := ParseStartupProbe(map[string]interface{}{
+		"startup_probe": map[string]interface{}{"period": "20s", "failure_threshold": float64(15)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.Window() != 5*time.Minute {
+		t.Errorf("window %s, want 5m", probe.Window())
+	}
+	labels, err := SetStartupProbe(map[string]string{StartupPeriodLabel: "1s"}, probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := StartupProbeFromLabels(labels); got == nil || got.Window() != 5*time.Minute {
+		t.Errorf("probe not recorded in labels: %v", labels)
+	}
+	if probe, _ := ParseStartupProbe(map[string]interface{}{"startup_probe": map[string]interface{}{}}); probe.Window() != 5*time.Minute {
+		t.Errorf("default window %s, want 5m", probe.Window())
+	}
+	for _, invalid := range []StartupProbe{{Period: "500ms"}, {Period: "1.5s"}, {Period: "soon"}, {FailureThreshold: -1}} {
+		if _, err := SetStartupProbe(nil, &invalid); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+	if labels, _ := SetStartupProbe(map[string]string{StartupPeriodLabel: "10s"}, nil); StartupProbeFromLabels(labels) != nil {
+		t.Error("supplied startup probe label kept")
+	}
+}
+
+func TestApplyStartup(t *testing.T) {
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	labels, _ := SetStartupProbe(nil, &StartupProbe{Period: "10s", FailureThreshold: 6})
+	container := &models.Container{ID: "c1", ServiceName: "search", Labels: labels, CreatedAt: now.Add(-30 * time.Second)}
+	manager.containers["search"] = container
+
+	warming := func() *HealthCheckResult {
+		return &HealthCheckResult{ContainerID: "c1", Status: models.StatusRunning, probed: true, Error: "HTTP request failed", Details: map[string]interface{}{}}
+	}
+
+	result := warming()
+	manager.applyStartup(container, result, now)
+	if got := manager.determineContainerStatus(result); got != models.StatusStarting {
+		t.Errorf("status %s within the startup window, want starting", got)
+	}
+	if result.probed {
+		t.Error("result within the startup window drives readiness and liveness")
+	}
+
+	// After the window the check counts again
+	result = warming()
+	manager.applyStartup(container, result, now.Add(time.Minute))
+	if got := manager.determineContainerStatus(result); got != models.StatusError {
+		t.Errorf("status %s after the startup window, want error", got)
+	}
+
+	// Once ready, the startup has passed for this run of the container
+	manager.applyStartup(container, &HealthCheckResult{Live: true, Ready: true, Status: models.StatusRunning, probed: true}, now)
+	result = warming()
+	manager.applyStartup(container, result, now.Add(time.Second))
+	if result.starting {
+		t.Error("startup probe applied after it passed")
+	}
+}
+
+func TestSetWarmup(t *testing.T) {
+	requests := []WarmupRequest{
+		{Path: "/index/load"},
+		{Tool: "search", Arguments: map[string]interface{}{"query": "warm-up"}},
+	}
+	labels, err := SetWarmup(map[string]string{WarmupLabel: "[]"}, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := WarmupFromLabels(labels)
+	if len(got) != 2 || got[0].String() != "GET /index/load" || got[1].String() != "tool search at /mcp" {
+		t.Errorf("unexpected warm-up requests %v", got)
+	}
+
+	for _, invalid := range []WarmupRequest{
+		{Method: "DELETE"},
+		{Path: "index"},
+		{Tool: "search", Method: "GET"},
+		{Arguments: map[string]interface{}{"query": "x"}},
+		{Body: json.RawMessage(`{"unterminated"`)},
+		{Headers: map[string]string{"Bad Header": "x"}},
+	} {
+		if _, err := SetWarmup(nil, []WarmupRequest{invalid}); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	var calls []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message struct {
+			Method string `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		mu.Lock()
+		calls = append(calls, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+message.Method+" "+r.Header.Get("Mcp-Session-Id")+" "+r.Header.Get("Authorization")))
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/index/load":
+			w.WriteHeader(http.StatusNoContent)
+		case message.Method == "initialize":
+			w.Header().Set("Mcp-Session-Id", "s1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26"}}`)
+		case message.Method == "notifications/initialized", r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusAccepted)
+		case message.Method == "tools/call":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":2,\"result\":{\"content\":[],\"isError\":false}}\n\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+
+	manager := NewManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	labels, err := SetWarmup(nil, []WarmupRequest{
+		{Path: "/index/load", Headers: map[string]string{"Authorization": "Bearer ${env.TOKEN}"}},
+		{Tool: "search"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := &models.Container{ServiceName: "search", Port: port, Labels: labels, Environment: map[string]string{"TOKEN": "t0k"}}
+
+	if err := manager.warmUp(context.Background(), container, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GET /index/load   Bearer t0k",
+		"POST /mcp initialize",
+		"POST /mcp notifications/initialized s1",
+		"POST /mcp tools/call s1",
+		"DELETE /mcp  s1",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected calls:\n%s", strings.Join(calls, "\n"))
+	}
+
+	// A tool error fails the warm-up
+	if err := rpcResponseError("application/json", []byte(`{"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"unknown tool"}}`)); err == nil {
+		t.Error("expected a JSON-RPC error")
+	}
+	if err := rpcResponseError("text/event-stream", []byte("data: {\"result\":{\"isError\":true}}\n\n")); err == nil {
+		t.Error("expected a tool error")
+	}
+}
+
+func TestHealthClient(t *testing.T) {
+	var got http.Header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	host, portStr, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	port, _ := strconv.Atoi(portStr)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{HealthCheck: config.HealthCheckConfig{
+		Scheme:        "https",
+		CAFile:        caFile,
+		ServerName:    "example.com",
+		Headers:       []string{"X-Probe: health", "Authorization: Bearer static"},
+		SendAuthToken: true,
+	}}
+	client, err := NewHealthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := client.URL(host, port, "/health")
+	if url != server.URL+"/health" {
+		t.Fatalf("unexpected URL %s", url)
+	}
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	resp, err := client.Do(req, map[string]string{AuthTokenEnv: "t0k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// Configured headers win over the instance's token
+	if got.Get("X-Probe") != "health" || got.Get("Authorization") != "Bearer static" {
+		t.Errorf("unexpected headers %v", got)
+	}
+
+	cfg.HealthCheck.Headers = nil
+	client, _ = NewHealthClient(cfg)
+	req, _ = http.NewRequest(http.MethodGet, url, nil)
+	if resp, err := client.Do(req, map[string]string{AuthTokenEnv: "t0k"}); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+	if got.Get("Authorization") != "Bearer t0k" {
+		t.Errorf("expected the instance's token, got %q", got.Get("Authorization"))
+	}
+
+	// Without the CA the instance's certificate is not trusted
+	cfg.HealthCheck.CAFile = ""
+	client, _ = NewHealthClient(cfg)
+	req, _ = http.NewRequest(http.MethodGet, url, nil)
+	if _, err := client.Do(req, nil); err == nil {
+		t.Error("expected an unknown authority error")
+	}
+
+	for _, settings := range []config.HealthCheckConfig{
+		{Scheme: "ftp"},
+		{Headers: []string{"no colon"}},
+		{Scheme: "https", CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		if _, err := NewHealthClient(&config.Config{HealthCheck: settings}); err == nil {
+			t.Errorf("expected %+v to be rejected", settings)
+		}
+	}
+}
+
+func TestConfigureRouting(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	mounted := &config.Config{Traefik: config.TraefikConfig{ConfigPath: filepath.Join(dir, "dynamic.yml")}}
+	missing := &config.Config{Traefik: config.TraefikConfig{ConfigPath: filepath.Join(dir, "unmounted", "dynamic.yml")}}
+
+	status, err := NewManager(mounted, logger).ConfigureRouting(true)
+	if err != nil || status.Mode != RoutingProxy || !status.TraefikAvailable {
+		t.Errorf("expected proxy routing, got %+v, %v", status, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("probe left files behind: %v", entries)
+	}
+	if status, err := NewManager(mounted, logger).ConfigureRouting(false); err != nil || status.Mode != RoutingTraefik {
+		t.Errorf("expected Traefik routing, got %+v, %v", status, err)
+	}
+
+	manager := NewManager(missing, logger)
+	status, err = manager.ConfigureRouting(true)
+	if err != nil || status.Mode != RoutingProxyOnly || status.TraefikError == "" {
+		t.Errorf("expected proxy-only routing, got %+v, %v", status, err)
+	}
+	if manager.traefikManager != nil || manager.Routing() != status {
+		t.Error("expected the Traefik manager to be dropped")
+	}
+	// Creating the directory would write routes Traefik never reads
+	if _, err := os.Stat(filepath.Join(dir, "unmounted")); !os.IsNotExist(err) {
+		t.Error("expected the config directory not to be created")
+	}
+
+	if _, err := NewManager(missing, logger).ConfigureRouting(false); err == nil {
+		t.Error("expected an error without any routing path")
+	}
+}
+
+func TestEnsureNetwork(t *testing.T) {
+	dir := t.TempDir()
+	// A runtime whose network only exists once created
+	script := `#!/bin/sh
+case "$1 $2" in
+"network inspect")
+	[ -f "` + dir + `/created" ] || { echo "network not found" >&2; exit 125; }
+	echo '[{"name":"mcp","driver":"bridge","network_interface":"mcp0","subnets":[{"subnet":"10.89.0.0/24","gateway":"10.89.0.1"}]}]' ;;
+"network create")
+	echo "$@" > "` + dir + `/created" ;;
+"ps -a")
+	echo c1 ;;
+"inspect c1")
+	echo '[{"Id":"c1","Name":"/traefik","State":{"Status":"running"},"NetworkSettings":{"Networks":{"mcp":{"IPAddress":"10.89.0.5"}}}}]' ;;
+esac
+`
+	runtime := filepath.Join(dir, "runtime")
+	if err := os.WriteFile(runtime, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Container: config.ContainerConfig{Runtime: runtime},
+		Traefik:   config.TraefikConfig{Network: "mcp", NetworkSubnet: "10.89.0.0/24", NetworkGateway: "10.89.0.1"},
+	}
+	manager := NewManager(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := manager.EnsureNetwork(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	created, _ := os.ReadFile(filepath.Join(dir, "created"))
+	if got := strings.TrimSpace(string(created)); got != "network create --subnet 10.89.0.0/24 --gateway 10.89.0.1 mcp" {
+		t.Errorf("unexpected create command %q", got)
+	}
+
+	status, err := manager.NetworkStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Interface != "mcp0" || len(status.Subnets) != 1 || status.Subnets[0].Gateway != "10.89.0.1" {
+		t.Errorf("unexpected network %+v", status)
+	}
+	if len(status.Containers) != 1 || status.Containers[0].Name != "traefik" || status.Containers[0].IPAddress != "10.89.0.5" {
+		t.Errorf("unexpected containers %+v", status.Containers)
+	}
+
+	// Docker reports subnets in its IPAM config
+	var docker []runtimeNetwork
+	_ = json.Unmarshal([]byte(`[{"Name":"mcp","Driver":"bridge","IPAM":{"Config":[{"Subnet":"172.20.0.0/16","Gateway":"172.20.0.1"}]}}]`), &docker)
+	if subnets := manager.networkStatus(&docker[0]).Subnets; len(subnets) != 1 || subnets[0].Subnet != "172.20.0.0/16" {
+		t.Errorf("unexpected Docker subnets %+v", subnets)
+	}
+	if warnings := manager.networkStatus(&docker[0]).Warnings; len(warnings) == 0 || !strings.Contains(warnings[0], "TRAEFIK_NETWORK_SUBNET") {
+		t.Errorf("expected a subnet mismatch warning, got %v", warnings)
+	}
+
+	addrs := []net.Addr{&net.IPNet{IP: net.ParseIP("10.89.0.1"), Mask: net.CIDRMask(24, 32)}}
+	if !addressIn(addrs, status.Subnets) || addressIn(addrs, docker[0].IPAM.Config) {
+		t.Error("unexpected subnet membership")
+	}
+}
+
+func TestInspectAddresses(t *testing.T) {
+	output := []byte(`[{"NetworkSettings":{"IPAddress":"","Networks":{
+		"mcp":{"IPAddress":"10.89.0.5","GlobalIPv6Address":"fd00:89::5"},
+		"v6only":{"IPAddress":"","GlobalIPv6Address":"fd00:90::5"},
+		"other":{"IPAddress":"10.90.0.5"}}}}]`)
+
+	addrs, err := inspectAddresses(output, "mcp", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs.pick(IPv4) != "10.89.0.5" || addrs.pick(IPv6) != "fd00:89::5" || len(addrs.all()) != 2 {
+		t.Errorf("unexpected dual-stack addresses %+v", addrs)
+	}
+	if addrs, _ := inspectAddresses(output, "v6only", false); addrs.pick(IPv4) != "fd00:90::5" {
+		t.Errorf("expected the IPv6 address of a single-stack network, got %q", addrs.pick(IPv4))
+	}
+	if _, err := inspectAddresses(output, "missing", false); err == nil {
+		t.Error("expected an error for a missing network")
+	}
+	// The health checker falls back to the first network by name
+	if addrs, err := inspectAddresses(output, "missing", true); err != nil || addrs.pick(IPv4) != "10.89.0.5" {
+		t.Errorf("unexpected fallback %+v, %v", addrs, err)
+	}
+
+	// IPv6 addresses are bracketed in URLs and Traefik services
+	client, _ := NewHealthClient(&config.Config{})
+	if url := client.URL("fd00:89::5", 8080, "/health"); url != "http://[fd00:89::5]:8080/health" {
+		t.Errorf("unexpected probe URL %s", url)
+	}
+	tm := NewTraefikManager(&config.Config{Traefik: config.TraefikConfig{ConfigPath: filepath.Join(t.TempDir(), "dynamic.yml")}},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := tm.AddMCPService(context.Background(), "v6", "fd00:89::5", 8080); err != nil {
+		t.Fatal(err)
+	}
+	traefikConfig, _ := tm.LoadConfig()
+	if url := traefikConfig.HTTP.Services["mcp-v6-service"].LoadBalancer.Servers[0].URL; url != "http://[fd00:89::5]:8080" {
+		t.Errorf("unexpected Traefik service URL %s", url)
+	}
+}
+
+// createArgs returns the CLI arguments creating container
+func createArgs(t *testing.T, m *Manager, container *models.Container) string {
+	t.Helper()
+	spec, err := m.buildCreateSpec(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(runArgs(spec), " ")
+}
+
+func TestCreateSpec(t *testing.T) {
+	m := NewManager(&config.Config{
+		Traefik:   config.TraefikConfig{Network: "mcp"},
+		Container: config.ContainerConfig{DefaultMemoryLimit: "512m", DefaultCPULimit: "0.5", DefaultDiskQuota: "1g"},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	spec, err := m.buildCreateSpec(&models.Container{Name: "mcp-echo", Image: "echo:latest",
+		Command: []string{"serve", "--port", "8000"}, Environment: map[string]string{"A": "1"}, Labels: map[string]string{"app": "echo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "mcp-echo" || spec.Image != "echo:latest" || strings.Join(spec.Cmd, " ") != "serve --port 8000" || spec.Env[0] != "A=1" {
+		t.Errorf("spec = %+v", spec)
+	}
+	host := spec.HostConfig
+	if host.NetworkMode != "mcp" || host.Memory != 512<<20 || host.NanoCPUs != 5e8 || spec.Labels["app"] != "echo" || host.StorageOpt["size"] != "1g" {
+		t.Errorf("host config = %+v", host)
+	}
+	want := "run -d --name mcp-echo --network mcp -e A=1 --label app=echo --memory 536870912 --cpus 0.5 --storage-opt size=1g echo:latest serve --port 8000"
+	if args := strings.Join(runArgs(spec), " "); args != want {
+		t.Errorf("run args = %s", args)
+	}
+
+	spec.Entrypoint = []string{"/bin/sh"}
+	spec.HostConfig.Devices = []createDevice{{PathOnHost: "/dev/fuse", PathInContainer: "/dev/fuse", CgroupPermissions: "rw"}}
+	if args := strings.Join(runArgs(spec), " "); !strings.Contains(args, "--device /dev/fuse:/dev/fuse:rw --memory") || !strings.Contains(args, "--entrypoint /bin/sh echo:latest") {
+		t.Errorf("run args = %s", args)
+	}
+
+	m.config.Container.DefaultCPULimit = "half"
+	if _, err := m.buildCreateSpec(&models.Container{Name: "mcp-echo", Image: "echo:latest"}); err == nil {
+		t.Error("invalid CPU limit should be rejected")
+	}
+}
+
+func TestAPIRuntime(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var calls []string
+	var created createSpec
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1.41/containers/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			created.Name = r.URL.Query().Get("name")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id":"c1"}`)
+		case "POST /v4.0.0/libpod/containers/c1/start":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /v4.0.0/libpod/containers/c1/stop":
+			if r.URL.Query().Get("t") != "10" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNotModified)
+		case "GET /v4.0.0/libpod/containers/c1/json":
+			io.WriteString(w, `{"Id":"c1","State":{"Status":"running"},"NetworkSettings":{"Networks":{"mcp":{"IPAddress":"10.89.0.5"}}}}`)
+		case "DELETE /v4.0.0/libpod/containers/c1":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /v4.0.0/libpod/containers/json":
+			if r.URL.Query().Get("all") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `[{"Id":"c1","Names":["mcp-echo"],"Image":"echo:latest","State":"exited","Labels":null}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"no such container"}`)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	cfg := &config.Config{Container: config.ContainerConfig{Runtime: "podman", RuntimeAPI: RuntimeAPI, RuntimeSocket: "unix://" + socket}}
+	runtime, err := NewRuntime(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.Name() != "podman-api" {
+		t.Fatalf("runtime = %s, want podman-api", runtime.Name())
+	}
+
+	ctx := context.Background()
+	id, err := runtime.Create(ctx, &createSpec{Name: "mcp-echo", Image: "echo:latest", HostConfig: createHostConfig{NetworkMode: "mcp"}})
+	if err != nil || id != "c1" {
+		t.Fatalf("Create = %q, %v", id, err)
+	}
+	if created.Name != "mcp-echo" || created.Image != "echo:latest" || created.HostConfig.NetworkMode != "mcp" {
+		t.Errorf("created = %+v", created)
+	}
+	info, err := runtime.Inspect(ctx, "c1")
+	if err != nil || info.State.Status != "running" {
+		t.Fatalf("Inspect = %+v, %v", info, err)
+	}
+	if addrs, err := info.NetworkSettings.addresses("mcp", false); err != nil || addrs.IPAddress != "10.89.0.5" {
+		t.Errorf("addresses = %+v, %v", addrs, err)
+	}
+	// Stopping a stopped container is not an error
+	if err := runtime.Stop(ctx, "c1", 10*time.Second); err != nil {
+		t.Error(err)
+	}
+	if err := runtime.Remove(ctx, "c1", true); err != nil {
+		t.Error(err)
+	}
+	listed, err := runtime.List(ctx)
+	if err != nil || len(listed) != 1 || listed[0].ID != "c1" || listed[0].Names[0] != "mcp-echo" || listed[0].State != "exited" || listed[0].Labels == nil {
+		t.Errorf("List = %+v, %v", listed, err)
+	}
+	var apiErr *runtimeAPIError
+	if _, err := runtime.Inspect(ctx, "c2"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "no such container" {
+		t.Errorf("Inspect missing = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 7 {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestParsePSLines(t *testing.T) {
+	output := []byte(`{"Id":"c1","Names":["mcp-echo"],"Image":"echo:latest","State":"running","Labels":{"agentarea.io/group":"search"}}
+{"ID":"d2","Names":"mcp-fetch,alias","Image":"fetch:1","State":"exited","Labels":"agentarea.io/group=web,team=core"}
+`)
+	containers, err := parsePSLines(output)
+	if err != nil || len(containers) != 2 {
+		t.Fatalf("parsePSLines = %+v, %v", containers, err)
+	}
+	podman, docker := containers[0], containers[1]
+	if podman.ID != "c1" || podman.Names[0] != "mcp-echo" || podman.Labels[GroupLabel] != "search" {
+		t.Errorf("podman entry = %+v", podman)
+	}
+	if docker.ID != "d2" || docker.Names[0] != "mcp-fetch" || docker.State != "exited" || docker.Labels[GroupLabel] != "web" || docker.Labels["team"] != "core" {
+		t.Errorf("docker entry = %+v", docker)
+	}
+}
+
+func TestNewRuntime(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+
+	// auto shells out when the socket is missing
+	cfg := &config.Config{Container: config.ContainerConfig{Runtime: "podman", RuntimeAPI: RuntimeAPIAuto, RuntimeSocket: missing}}
+	if runtime, err := NewRuntime(cfg, logger); err != nil || runtime.Name() != RuntimeExec {
+		t.Errorf("auto = %v, %v; want exec", runtime, err)
+	}
+	cfg.Container.RuntimeAPI = RuntimeAPI
+	if _, err := NewRuntime(cfg, logger); err == nil {
+		t.Error("api mode without a socket should fail")
+	}
+	cfg.Container.RuntimeAPI = "rest"
+	if _, err := NewRuntime(cfg, logger); err == nil {
+		t.Error("invalid mode should fail")
+	}
+}
+
+func TestPortAllocator(t *testing.T) {
+	if ports, err := NewPortAllocator(""); ports != nil || err != nil {
+		t.Fatalf("empty range = %v, %v; want disabled", ports, err)
+	}
+	for _, invalid := range []string{"30000", "3-1", "0-10", "a-b", "1-70000"} {
+		if _, err := NewPortAllocator(invalid); err == nil {
+			t.Errorf("NewPortAllocator(%q) should fail", invalid)
+		}
+	}
+
+	ports, err := NewPortAllocator("30000-30002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy := map[int]bool{30000: true} // Held by another process on the host
+	ports.bindable = func(port int) bool { return !busy[port] }
+
+	if err := ports.Check("a", 30000); !errors.Is(err, ErrHostPortConflict) {
+		t.Errorf("busy host port: err = %v", err)
+	}
+	if err := ports.Check("a", 31000); err == nil || errors.Is(err, ErrHostPortConflict) {
+		t.Errorf("out of range: err = %v", err)
+	}
+	if port, err := ports.Allocate("a", 0); port != 30001 || err != nil {
+		t.Errorf("auto = %d, %v; want 30001", port, err)
+	}
+	if port, err := ports.Allocate("a", 0); port != 30001 || err != nil {
+		t.Errorf("a keeps its port: %d, %v", port, err)
+	}
+	if _, err := ports.Allocate("b", 30001); !errors.Is(err, ErrHostPortConflict) {
+		t.Errorf("taken port: err = %v", err)
+	}
+	if port, err := ports.Allocate("b", 0); port != 30002 || err != nil {
+		t.Errorf("b = %d, %v; want 30002", port, err)
+	}
+	if err := ports.Check("c", 0); !errors.Is(err, ErrHostPortConflict) {
+		t.Errorf("exhausted range: err = %v", err)
+	}
+	ports.Release("a")
+	if got := ports.Assignments(); len(got) != 1 || got[0] != (PortAssignment{Port: 30002, ServiceName: "b"}) {
+		t.Errorf("assignments = %+v", got)
+	}
+
+	var disabled *PortAllocator
+	if err := disabled.Check("a", 0); !errors.Is(err, ErrHostPortsDisabled) {
+		t.Errorf("disabled: err = %v", err)
+	}
+}
+
+func TestHostPortRunArgs(t *testing.T) {
+	m := &Manager{config: &config.Config{Container: config.ContainerConfig{HostPortAddress: "127.0.0.1"}}}
+	labels, err := SetHostPort(nil, new(int))
+	if err != nil || labels[HostPortLabel] != "auto" {
+		t.Fatalf("labels = %v, %v", labels, err)
+	}
+	spec := &createSpec{Name: "mcp-echo", Image: "echo"}
+	if m.publishHostPort(spec, labels, 8000); spec.HostConfig.PortBindings != nil {
+		t.Errorf("unallocated port published: %+v", spec.HostConfig.PortBindings)
+	}
+	ports, _ := NewPortAllocator("30000-30009")
+	ports.bindable = func(int) bool { return true }
+	m.ports = ports
+	if err := m.assignHostPort("echo", labels); err != nil || labels[HostPortLabel] != "30000" {
+		t.Fatalf("assign = %v, labels %v", err, labels)
+	}
+	m.publishHostPort(spec, labels, 8000)
+	binding := spec.HostConfig.PortBindings["8000/tcp"]
+	if _, exposed := spec.ExposedPorts["8000/tcp"]; !exposed || len(binding) != 1 || binding[0] != (createPortBinding{HostIP: "127.0.0.1", HostPort: "30000"}) {
+		t.Errorf("spec = %+v", spec)
+	}
+	if args := strings.Join(runArgs(spec), " "); args != "run -d --name mcp-echo -p 127.0.0.1:30000:8000 echo" {
+		t.Errorf("args = %s", args)
+	}
+	spec.HostConfig.PortBindings["8000/tcp"][0].HostIP = "fd00::1"
+	if args := strings.Join(runArgs(spec), " "); !strings.Contains(args, "-p [fd00::1]:30000:8000") {
+		t.Errorf("args = %s", args)
+	}
+	if _, err := SetHostPort(nil, func() *int { port := 70000; return &port }()); err == nil {
+		t.Error("invalid port should be rejected")
+	}
+}
+
+func TestContainerStateRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	key, _ := fernet.GenerateKey()
+	keys, _ := fernet.NewKeyring(key)
+	states := store.NewEncrypted(store.NewMemory(), keys, slog.New(slog.NewTextHandler(io.Discard, nil))).Containers()
+	m := &Manager{
+		config:      &config.Config{Traefik: config.TraefikConfig{ProxyHost: "http://localhost:7999"}},
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		checkpoints: newCheckpoints(),
+	}
+	m.SetStateStore(states)
+	container := &models.Container{
+		ID:          "c1",
+		Name:        "mcp-echo",
+		ServiceName: "echo",
+		Slug:        "echo-abc123",
+		Image:       "ghcr.io/acme/echo",
+		Port:        8080,
+		CreatedAt:   time.Date(2025, 7, 29, 10, 0, 0, 0, time.UTC),
+		Labels:      map[string]string{"team": "a"},
+		Environment: map[string]string{"MCP_INSTANCE_ID": "i1", "API_KEY": "secret"},
+		Command:     []string{"serve"},
+	}
+	m.saveState(ctx, container)
+
+	// A checkpointed container is persisted as stopped
+	m.checkpoints.services["echo"] = Checkpoint{ServiceName: "echo", ContainerID: "c1", Slug: container.Slug}
+	m.saveState(ctx, container)
+	if _, err := states.Get(ctx, "echo"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("state written before flushing: err = %v", err)
+	}
+	m.flushState(ctx)
+	state, err := states.Get(ctx, "echo")
+	if err != nil || state.DesiredState != store.ContainerStopped || state.InstanceID != "i1" || state.ContainerID != "c1" {
+		t.Fatalf("state = %+v, %v", state, err)
+	}
+
+	// A restarted manager rebuilds the container and its checkpoint
+	m.checkpoints = newCheckpoints()
+	restored, ok := m.containerFromState(ctx, m.loadState(ctx)["c1"], models.StatusStopped)
+	if !ok {
+		t.Fatal("state not restored")
+	}
+	if restored.Slug != container.Slug || restored.URL != "http://localhost:7999/mcp/echo-abc123" || restored.Port != 8080 ||
+		restored.Environment["API_KEY"] != "secret" || restored.Command[0] != "serve" || !restored.CreatedAt.Equal(container.CreatedAt) {
+		t.Errorf("restored = %+v", restored)
+	}
+	if !m.isCheckpointed("echo") {
+		t.Error("checkpoint not restored")
+	}
+
+	// State of containers the runtime no longer has is dropped
+	m.pruneState(m.loadState(ctx), map[string]bool{})
+	m.flushState(ctx)
+	if _, err := states.Get(ctx, "echo"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("pruned state: err = %v", err)
+	}
+}
+
+// envRuntime inspects every container as having env
+type envRuntime struct {
+	Runtime
+	env []string
+}
+
+func (r envRuntime) Inspect(_ context.Context, id string) (*ContainerInfo, error) {
+	info := &ContainerInfo{ID: id}
+	info.Config.Env = r.env
+	return info, nil
+}
+
+func TestStateWithholdsCredentials(t *testing.T) {
+	ctx := context.Background()
+	states := store.NewMemory().Containers()
+	m := &Manager{
+		config:      &config.Config{},
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		checkpoints: newCheckpoints(),
+		runtime:     envRuntime{env: []string{"API_KEY=secret", AuthTokenEnv + "=tok", "MCP_INSTANCE_ID=i1"}},
+	}
+	m.SetStateStore(states)
+	container := &models.Container{ID: "c1", ServiceName: "echo", Slug: "echo-abc123",
+		Environment: map[string]string{"MCP_INSTANCE_ID": "i1", "API_KEY": "secret", AuthTokenEnv: "tok"}}
+	m.saveState(ctx, container)
+	m.flushState(ctx)
+
+	state, err := states.Get(ctx, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(state.Spec), "secret") || strings.Contains(string(state.Spec), "tok") {
+		t.Errorf("credentials stored in plaintext: %s", state.Spec)
+	}
+
+	// They are read back from the container on restore
+	restored, ok := m.containerFromState(ctx, state, models.StatusRunning)
+	if !ok || restored.Environment["API_KEY"] != "secret" || restored.Environment[AuthTokenEnv] != "tok" || restored.Environment["MCP_INSTANCE_ID"] != "i1" {
+		t.Errorf("restored = %+v", restored)
+	}
+}
+
+func TestStoppedContainerStaysStopped(t *testing.T) {
+	ctx := context.Background()
+	states := store.NewMemory().Containers()
+	m := &Manager{
+		config:      &config.Config{},
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		checkpoints: newCheckpoints(),
+		quarantines: newQuarantines(),
+		stopped:     map[string]bool{"echo": true},
+		stateStore:  states,
+	}
+	container := &models.Container{ID: "c1", ServiceName: "echo", Slug: "echo-abc123"}
+
+	if m.shouldContainerBeRunning(container) {
+		t.Error("stopped container should not be auto restarted")
+	}
+	m.saveState(ctx, container)
+	m.flushState(ctx)
+	state, err := states.Get(ctx, "echo")
+	if err != nil || state.DesiredState != store.ContainerStopped {
+		t.Fatalf("state = %+v, %v", state, err)
+	}
+
+	// A restarted manager keeps it stopped
+	m.stopped = make(map[string]bool)
+	if _, ok := m.containerFromState(ctx, state, models.StatusStopped); !ok {
+		t.Fatal("state not restored")
+	}
+	if !m.isStopped("echo") || m.isCheckpointed("echo") {
+		t.Errorf("stopped = %v, checkpointed = %v", m.isStopped("echo"), m.isCheckpointed("echo"))
+	}
+
+	// Quarantined containers are released with their own endpoint
+	m.quarantines.services["echo"] = Quarantine{ServiceName: "echo"}
+	if _, err := m.StartContainer(ctx, "echo"); !errors.Is(err, ErrLifecycleBlocked) {
+		t.Errorf("StartContainer of a quarantined container = %v, want ErrLifecycleBlocked", err)
+	}
+}
@@ -0,0 +1,292 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// NetworkStatus describes the runtime network instances are attached to
+type NetworkStatus struct {
+	Name       string             `json:"name"`
+	Driver     string             `json:"driver,omitempty"`
+	Interface  string             `json:"interface,omitempty"` // Bridge on the manager's host
+	Internal   bool               `json:"internal"`
+	Subnets    []NetworkSubnet    `json:"subnets"`
+	Reachable  bool               `json:"reachable"` // The manager has an address in a subnet, so its proxy reaches containers
+	Containers []NetworkContainer `json:"containers"`
+	Warnings   []string           `json:"warnings,omitempty"`
+}
+
+// NetworkSubnet is a subnet of the network
+type NetworkSubnet struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// NetworkContainer is a container attached to the network
+type NetworkContainer struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ServiceName string `json:"service_name,omitempty"` // Set for managed instances
+	IPAddress   string `json:"ip_address,omitempty"`
+	IPv6Address string `json:"ipv6_address,omitempty"`
+	State       string `json:"state,omitempty"`
+}
+
+// runtimeNetwork is the output of network inspect. Podman lists subnets,
+// Docker its IPAM config; field names match either case.
+type runtimeNetwork struct {
+	Name      string          `json:"name"`
+	Driver    string          `json:"driver"`
+	Interface string          `json:"network_interface"`
+	Internal  bool            `json:"internal"`
+	Subnets   []NetworkSubnet `json:"subnets"`
+	IPAM      struct {
+		Config []NetworkSubnet `json:"config"`
+	} `json:"ipam"`
+}
+
+// EnsureNetwork creates TRAEFIK_NETWORK, with TRAEFIK_NETWORK_SUBNET and
+// TRAEFIK_NETWORK_GATEWAY, unless it exists, and warns when the manager's
+// proxy or Traefik cannot reach containers on it. An IPv6 subnet makes the
+// network IPv6 or, with an IPv4 one, dual-stack.
+func (m *Manager) EnsureNetwork(ctx context.Context) error {
+	name := m.config.Traefik.Network
+	if _, inspectErr := m.inspectNetwork(ctx); inspectErr != nil {
+		args := []string{"network", "create"}
+		subnets := splitList(m.config.Traefik.NetworkSubnet)
+		for _, subnet := range subnets {
+			args = append(args, "--subnet", subnet)
+		}
+		for _, gateway := range splitList(m.config.Traefik.NetworkGateway) {
+			args = append(args, "--gateway", gateway)
+		}
+		if slices.ContainsFunc(subnets, isIPv6CIDR) {
+			args = append(args, "--ipv6")
+		}
+		if output, err := m.runtimeCommand(ctx, append(args, name)...); err != nil {
+			return fmt.Errorf("network %s not found (%v) and could not be created: %w: %s",
+				name, inspectErr, err, strings.TrimSpace(string(output)))
+		}
+		m.logger.Info("Created container network",
+			slog.String("network", name),
+			slog.String("subnet", m.config.Traefik.NetworkSubnet))
+	}
+
+	status, err := m.NetworkStatus(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to check container network",
+			slog.String("network", name),
+			slog.String("error", err.Error()))
+		return nil
+	}
+	for _, warning := range status.Warnings {
+		m.logger.Warn("Container network check failed",
+			slog.String("network", name),
+			slog.String("warning", warning))
+	}
+	return nil
+}
+
+// NetworkStatus inspects the network and lists the containers attached to
+// it with their addresses
+func (m *Manager) NetworkStatus(ctx context.Context) (*NetworkStatus, error) {
+	network, err := m.inspectNetwork(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := m.networkContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := m.networkStatus(network)
+	status.Containers = containers
+	if m.routing.Mode == RoutingTraefik && !traefikAttached(containers) {
+		status.Warnings = append(status.Warnings,
+			"no Traefik container is attached to the network; Traefik cannot reach instances")
+	}
+	return status, nil
+}
+
+// inspectNetwork reads the configured network
+func (m *Manager) inspectNetwork(ctx context.Context) (*runtimeNetwork, error) {
+	output, err := m.runtimeOutput(ctx, "network", "inspect", m.config.Traefik.Network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect network %s: %w", m.config.Traefik.Network, err)
+	}
+	var networks []runtimeNetwork
+	if err := json.Unmarshal(output, &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse network inspect output: %w", err)
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("network %s not found", m.config.Traefik.Network)
+	}
+	return &networks[0], nil
+}
+
+// networkStatus checks the configuration of the network and whether the
+// manager has an address on it
+func (m *Manager) networkStatus(network *runtimeNetwork) *NetworkStatus {
+	status := &NetworkStatus{
+		Name:       network.Name,
+		Driver:     network.Driver,
+		Interface:  network.Interface,
+		Internal:   network.Internal,
+		Subnets:    network.Subnets,
+		Containers: []NetworkContainer{},
+	}
+	if len(status.Subnets) == 0 {
+		status.Subnets = network.IPAM.Config
+	}
+
+	for _, subnet := range splitList(m.config.Traefik.NetworkSubnet) {
+		if !hasSubnet(status.Subnets, subnet) {
+			status.Warnings = append(status.Warnings, fmt.Sprintf(
+				"network has subnets %v, not TRAEFIK_NETWORK_SUBNET %s; remove it to have it recreated", subnetList(status.Subnets), subnet))
+		}
+	}
+	if m.config.Traefik.IPFamily == IPv6 && !slices.ContainsFunc(subnetList(status.Subnets), isIPv6CIDR) {
+		status.Warnings = append(status.Warnings,
+			"NETWORK_IP_FAMILY is ipv6 but the network has no IPv6 subnet; instances are reached over IPv4")
+	}
+	status.Reachable = localAddressIn(status.Subnets)
+	if !status.Reachable && m.routing.Mode != RoutingTraefik {
+		status.Warnings = append(status.Warnings, fmt.Sprintf(
+			"the manager has no address in %v; its proxy and health checks cannot reach instances (rootless runtime?)", subnetList(status.Subnets)))
+	}
+	return status
+}
+
+// networkContainers lists the containers attached to the network
+func (m *Manager) networkContainers(ctx context.Context) ([]NetworkContainer, error) {
+	name := m.config.Traefik.Network
+	output, err := m.runtimeOutput(ctx, "ps", "-a", "--filter", "network="+name, "--format", "{{.ID}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers on network %s: %w", name, err)
+	}
+	ids := strings.Fields(string(output))
+	containers := []NetworkContainer{}
+	if len(ids) == 0 {
+		return containers, nil
+	}
+
+	output, err = m.runtimeOutput(ctx, append([]string{"inspect"}, ids...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect containers on network %s: %w", name, err)
+	}
+	var inspected []struct {
+		ID    string `json:"Id"`
+		Name  string `json:"Name"`
+		State struct {
+			Status string `json:"Status"`
+		} `json:"State"`
+		NetworkSettings struct {
+			Networks map[string]networkAddresses `json:"Networks"`
+		} `json:"NetworkSettings"`
+	}
+	if err := json.Unmarshal(output, &inspected); err != nil {
+		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
+	}
+
+	m.mutex.RLock()
+	services := make(map[string]string, len(m.containers))
+	for serviceName, container := range m.containers {
+		services[container.ID] = serviceName
+	}
+	m.mutex.RUnlock()
+
+	for _, c := range inspected {
+		containers = append(containers, NetworkContainer{
+			ID:          c.ID,
+			Name:        strings.TrimPrefix(c.Name, "/"),
+			ServiceName: services[c.ID],
+			IPAddress:   c.NetworkSettings.Networks[name].IPAddress,
+			IPv6Address: c.NetworkSettings.Networks[name].GlobalIPv6Address,
+			State:       c.State.Status,
+		})
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+	return containers, nil
+}
+
+// traefikAttached reports whether a Traefik container is on the network
+func traefikAttached(containers []NetworkContainer) bool {
+	for _, c := range containers {
+		if strings.Contains(strings.ToLower(c.Name), "traefik") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSubnet reports whether subnets include cidr
+func hasSubnet(subnets []NetworkSubnet, cidr string) bool {
+	_, want, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	for _, s := range subnets {
+		if _, have, err := net.ParseCIDR(s.Subnet); err == nil && have.String() == want.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// localAddressIn reports whether an interface of this host has an address in
+// one of subnets
+func localAddressIn(subnets []NetworkSubnet) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	return addressIn(addrs, subnets)
+}
+
+// addressIn reports whether one of addrs lies in one of subnets
+func addressIn(addrs []net.Addr, subnets []NetworkSubnet) bool {
+	for _, s := range subnets {
+		_, subnet, err := net.ParseCIDR(s.Subnet)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && subnet.Contains(ipNet.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIPv6CIDR reports whether cidr is an IPv6 subnet
+func isIPv6CIDR(cidr string) bool {
+	ip, _, err := net.ParseCIDR(cidr)
+	return err == nil && ip.To4() == nil
+}
+
+// splitList splits a comma separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// subnetList returns the CIDRs of subnets
+func subnetList(subnets []NetworkSubnet) []string {
+	cidrs := make([]string, 0, len(subnets))
+	for _, s := range subnets {
+		cidrs = append(cidrs, s.Subnet)
+	}
+	return cidrs
+}
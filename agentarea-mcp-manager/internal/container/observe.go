@@ -0,0 +1,183 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// LogOptions selects the log lines to stream
+type LogOptions struct {
+	Tail       int       // Last lines to return; 0 returns all
+	Since      time.Time // Only lines after this time; zero returns all
+	Follow     bool      // Keep streaming new lines until ctx is done
+	Timestamps bool
+}
+
+// ExecResult is the output of a command run inside a container
+type ExecResult struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// RuntimeEvent is a lifecycle event reported by the container runtime
+type RuntimeEvent struct {
+	ServiceName string    `json:"service_name"`
+	Action      string    `json:"action"` // e.g. start, died, health_status, oom
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// podmanEvent is the subset of `podman events --format json` we use
+type podmanEvent struct {
+	Status string `json:"Status"`
+}
+
+// managedContainer looks up a container that has been created
+func (m *Manager) managedContainer(serviceName string) (*models.Container, error) {
+	container, err := m.GetContainer(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if container.ID == "" {
+		return nil, fmt.Errorf("container %s has not been created", serviceName)
+	}
+	return container, nil
+}
+
+// StreamLogs writes the logs of a container to w
+func (m *Manager) StreamLogs(ctx context.Context, serviceName string, opts LogOptions, w io.Writer) error {
+	container, err := m.managedContainer(serviceName)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, logsArgs(container.ID, opts)...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+	return nil
+}
+
+// logsArgs builds the `podman logs` arguments for a container
+func logsArgs(containerID string, opts LogOptions) []string {
+	args := []string{"logs"}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	return append(args, containerID)
+}
+
+// Exec runs a command inside a running container. A non-zero exit code is
+// reported in the result, not as an error.
+func (m *Manager) Exec(ctx context.Context, serviceName string, command []string) (*ExecResult, error) {
+	container, err := m.managedContainer(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(command) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, append([]string{"exec", container.ID}, command...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+
+	result := &ExecResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("failed to exec in container: %w", err)
+	}
+	return result, nil
+}
+
+// ContainerStats samples the resource usage of a running container
+func (m *Manager) ContainerStats(ctx context.Context, serviceName string) (ResourceUsage, error) {
+	container, err := m.managedContainer(serviceName)
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	if container.Status != models.StatusRunning {
+		return ResourceUsage{}, fmt.Errorf("container %s is not running", serviceName)
+	}
+
+	usage := m.collectResourceUsage(ctx, []*models.Container{container})
+	sample, ok := usage[container.Name]
+	if !ok {
+		return ResourceUsage{}, fmt.Errorf("no stats for container %s", serviceName)
+	}
+	return sample, nil
+}
+
+// WatchEvents streams the runtime events of a container until ctx is done.
+// The channel is closed when the stream ends.
+func (m *Manager) WatchEvents(ctx context.Context, serviceName string) (<-chan RuntimeEvent, error) {
+	container, err := m.managedContainer(serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, m.config.Container.Runtime, "events",
+		"--filter", "container="+container.ID, "--filter", "type=container", "--format", "json")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch container events: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to watch container events: %w", err)
+	}
+
+	events := make(chan RuntimeEvent)
+	go func() {
+		defer close(events)
+		defer cmd.Wait()
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			event, ok := parseRuntimeEvent(scanner.Bytes(), serviceName)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// parseRuntimeEvent reads one `podman events` JSON line
+func parseRuntimeEvent(line []byte, serviceName string) (RuntimeEvent, bool) {
+	var event podmanEvent
+	if err := json.Unmarshal(line, &event); err != nil || event.Status == "" {
+		return RuntimeEvent{}, false
+	}
+	return RuntimeEvent{ServiceName: serviceName, Action: event.Status, Timestamp: time.Now()}, true
+}
@@ -0,0 +1,284 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// HostPortLabel asks for the instance's port to be published on the host:
+// "auto" for a port from HOST_PORT_RANGE, or a port in it. Once allocated it
+// holds the port.
+const HostPortLabel = "agentarea.io/host-port"
+
+// hostPortAuto asks for any free port of the range
+const hostPortAuto = "auto"
+
+// Host port allocation errors
+var (
+	// ErrHostPortsDisabled is returned for a host port without HOST_PORT_RANGE
+	ErrHostPortsDisabled = errors.New("host port publishing is disabled; set HOST_PORT_RANGE")
+	// ErrHostPortConflict is returned when the port is taken, by another
+	// instance or a process on the host, or the range is exhausted
+	ErrHostPortConflict = errors.New("host port conflict")
+)
+
+// ParseHostPort reads json_spec.host_port: 0 for any free port, or the port
+func ParseHostPort(jsonSpec map[string]interface{}) *int {
+	value, ok := jsonSpec["host_port"].(float64)
+	if !ok {
+		return nil
+	}
+	port := int(value)
+	return &port
+}
+
+// SetHostPort records a host port request in instance labels; nil leaves the
+// port unpublished and 0 asks for any free port
+func SetHostPort(labels map[string]string, port *int) (map[string]string, error) {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, HostPortLabel)
+	switch {
+	case port == nil:
+	case *port == 0:
+		labels[HostPortLabel] = hostPortAuto
+	case *port < 1 || *port > 65535:
+		return labels, fmt.Errorf("invalid host_port %d", *port)
+	default:
+		labels[HostPortLabel] = strconv.Itoa(*port)
+	}
+	return labels, nil
+}
+
+// HostPortFromLabels returns the host port in labels, 0 when any free port
+// was asked for, and whether one was asked for at all
+func HostPortFromLabels(labels map[string]string) (int, bool) {
+	value, ok := labels[HostPortLabel]
+	if !ok || value == hostPortAuto {
+		return 0, ok
+	}
+	port, err := strconv.Atoi(value)
+	return port, err == nil
+}
+
+// PortAssignment is a host port published for an instance
+type PortAssignment struct {
+	Port        int    `json:"port"`
+	ServiceName string `json:"service_name"`
+}
+
+// PortAllocator hands out host ports from HOST_PORT_RANGE and tracks which
+// instance holds each one. A nil allocator has publishing disabled.
+type PortAllocator struct {
+	mu       sync.Mutex
+	min, max int
+	assigned map[int]string // Port -> service name
+	bindable func(port int) bool
+}
+
+// NewPortAllocator parses a range such as 30000-30999; an empty range
+// disables host port publishing and returns nil
+func NewPortAllocator(portRange string) (*PortAllocator, error) {
+	if portRange == "" {
+		return nil, nil
+	}
+	lowValue, highValue, ok := strings.Cut(portRange, "-")
+	low, lowErr := strconv.Atoi(strings.TrimSpace(lowValue))
+	high, highErr := strconv.Atoi(strings.TrimSpace(highValue))
+	if !ok || lowErr != nil || highErr != nil || low < 1 || high > 65535 || low > high {
+		return nil, fmt.Errorf("invalid HOST_PORT_RANGE %q: expected first-last, e.g. 30000-30999", portRange)
+	}
+	return &PortAllocator{min: low, max: high, assigned: make(map[int]string), bindable: hostPortFree}, nil
+}
+
+// Range returns the first and last port of the range
+func (a *PortAllocator) Range() (int, int) {
+	return a.min, a.max
+}
+
+// Check reports whether serviceName could get port (0 for any), without
+// allocating it
+func (a *PortAllocator) Check(serviceName string, port int) error {
+	if a == nil {
+		return ErrHostPortsDisabled
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if port == 0 {
+		if a.free(serviceName) == 0 {
+			return fmt.Errorf("%w: no free port in %d-%d", ErrHostPortConflict, a.min, a.max)
+		}
+		return nil
+	}
+	return a.available(serviceName, port)
+}
+
+// Allocate assigns port, or any free port for 0, to serviceName and returns
+// it. A service keeps the port it already holds.
+func (a *PortAllocator) Allocate(serviceName string, port int) (int, error) {
+	if a == nil {
+		return 0, ErrHostPortsDisabled
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if port == 0 {
+		if port = a.free(serviceName); port == 0 {
+			return 0, fmt.Errorf("%w: no free port in %d-%d", ErrHostPortConflict, a.min, a.max)
+		}
+	} else if err := a.available(serviceName, port); err != nil {
+		return 0, err
+	}
+	a.release(serviceName)
+	a.assigned[port] = serviceName
+	return port, nil
+}
+
+// Reserve records a port an existing container already publishes
+func (a *PortAllocator) Reserve(serviceName string, port int) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.assigned[port] = serviceName
+}
+
+// Release frees the port held by serviceName
+func (a *PortAllocator) Release(serviceName string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.release(serviceName)
+}
+
+// Assignments lists the allocated ports in order
+func (a *PortAllocator) Assignments() []PortAssignment {
+	assignments := []PortAssignment{}
+	if a == nil {
+		return assignments
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for port, serviceName := range a.assigned {
+		assignments = append(assignments, PortAssignment{Port: port, ServiceName: serviceName})
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].Port < assignments[j].Port })
+	return assignments
+}
+
+// available checks a specific port for serviceName
+func (a *PortAllocator) available(serviceName string, port int) error {
+	if port < a.min || port > a.max {
+		return fmt.Errorf("host port %d is outside HOST_PORT_RANGE %d-%d", port, a.min, a.max)
+	}
+	if holder, ok := a.assigned[port]; ok {
+		if holder == serviceName {
+			return nil
+		}
+		return fmt.Errorf("%w: port %d is published by %s", ErrHostPortConflict, port, holder)
+	}
+	if !a.bindable(port) {
+		return fmt.Errorf("%w: port %d is in use on the host", ErrHostPortConflict, port)
+	}
+	return nil
+}
+
+// free returns the port serviceName holds, or the first unassigned port
+// that can be bound, or 0
+func (a *PortAllocator) free(serviceName string) int {
+	for port, holder := range a.assigned {
+		if holder == serviceName {
+			return port
+		}
+	}
+	for port := a.min; port <= a.max; port++ {
+		if _, taken := a.assigned[port]; !taken && a.bindable(port) {
+			return port
+		}
+	}
+	return 0
+}
+
+func (a *PortAllocator) release(serviceName string) {
+	for port, holder := range a.assigned {
+		if holder == serviceName {
+			delete(a.assigned, port)
+		}
+	}
+}
+
+// hostPortFree reports whether nothing listens on port, so the runtime can
+// publish it
+func hostPortFree(port int) bool {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// CheckHostPort reports whether the host port requested in labels could be
+// published for serviceName
+func (m *Manager) CheckHostPort(serviceName string, labels map[string]string) error {
+	port, ok := HostPortFromLabels(labels)
+	if !ok {
+		return nil
+	}
+	return m.ports.Check(serviceName, port)
+}
+
+// HostPorts returns the host port range, empty when publishing is disabled,
+// and the allocated ports
+func (m *Manager) HostPorts() (string, []PortAssignment) {
+	if m.ports == nil {
+		return "", m.ports.Assignments()
+	}
+	low, high := m.ports.Range()
+	return fmt.Sprintf("%d-%d", low, high), m.ports.Assignments()
+}
+
+// assignHostPort allocates the host port requested in labels to serviceName
+// and records the port in the label. Call with m.mutex held.
+func (m *Manager) assignHostPort(serviceName string, labels map[string]string) error {
+	port, ok := HostPortFromLabels(labels)
+	if !ok {
+		return nil
+	}
+	port, err := m.ports.Allocate(serviceName, port)
+	if err != nil {
+		return err
+	}
+	labels[HostPortLabel] = strconv.Itoa(port)
+	return nil
+}
+
+// holdHostPort records the host port an existing container publishes
+func (m *Manager) holdHostPort(container *models.Container) {
+	if port, ok := HostPortFromLabels(container.Labels); ok && port != 0 {
+		m.ports.Reserve(container.ServiceName, port)
+	}
+}
+
+// publishHostPort publishes the instance's port in its create spec
+func (m *Manager) publishHostPort(spec *createSpec, labels map[string]string, containerPort int) {
+	port, ok := HostPortFromLabels(labels)
+	if !ok || port == 0 {
+		return
+	}
+	exposed := strconv.Itoa(containerPort) + "/tcp"
+	spec.ExposedPorts = map[string]struct{}{exposed: {}}
+	spec.HostConfig.PortBindings = map[string][]createPortBinding{
+		exposed: {{HostIP: m.config.Container.HostPortAddress, HostPort: strconv.Itoa(port)}},
+	}
+}
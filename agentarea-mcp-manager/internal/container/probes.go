@@ -0,0 +1,114 @@
+package container
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// probeState counts the consecutive failed probes of a service
+type probeState struct {
+	readinessFailures int
+	livenessFailures  int
+	withdrawn         bool      // The route was removed for failing readiness
+	startupPassed     time.Time // When the startup probe last passed
+}
+
+// applyProbes acts on the readiness and liveness of a health check: the
+// route of an instance follows its readiness, and a container failing
+// liveness is restarted. Checks that did not reach the server over HTTP
+// change neither.
+func (m *Manager) applyProbes(container *models.Container, result *HealthCheckResult) {
+	m.mutex.Lock()
+	current, exists := m.containers[container.ServiceName]
+	if !exists || current != container || current.ID != result.ContainerID || !result.probed {
+		m.mutex.Unlock()
+		return
+	}
+	state, ok := m.probes[container.ServiceName]
+	if !ok {
+		state = &probeState{}
+		m.probes[container.ServiceName] = state
+	}
+	m.applyReadinessUnsafe(container, result, state)
+	restart := m.livenessFailedUnsafe(container, result, state, time.Now())
+	m.mutex.Unlock()
+
+	if restart {
+		reason := fmt.Sprintf("server did not answer %d consecutive liveness checks", m.config.Container.LivenessFailures)
+		m.logger.Warn("Restarting container failing liveness",
+			slog.String("service", container.ServiceName),
+			slog.String("error", result.Error))
+		go m.scheduledRestart(m.healthCtx, container, reason)
+	}
+}
+
+// applyReadinessUnsafe removes the route of an instance once it fails
+// ReadinessFailures consecutive readiness checks and adds it back when it
+// passes one (caller holds the mutex)
+func (m *Manager) applyReadinessUnsafe(container *models.Container, result *HealthCheckResult, state *probeState) {
+	if result.Ready {
+		state.readinessFailures = 0
+		if !state.withdrawn {
+			return
+		}
+		containerIP, err := m.getContainerIP(m.healthCtx, container.ID)
+		if err != nil {
+			m.logger.Warn("Failed to get container IP to restore route",
+				slog.String("service", container.ServiceName),
+				slog.String("error", err.Error()))
+			return
+		}
+		if m.addPublishedRoute(m.healthCtx, container, containerIP) {
+			state.withdrawn = false
+			m.logger.Info("Instance ready again, route restored",
+				slog.String("service", container.ServiceName),
+				slog.String("slug", container.Slug))
+		}
+		return
+	}
+
+	state.readinessFailures++
+	limit := m.config.Container.ReadinessFailures
+	if limit <= 0 || state.withdrawn || container.Slug == "" || state.readinessFailures < limit {
+		return
+	}
+	if err := m.removeRoute(m.healthCtx, container.Slug); err != nil {
+		m.logger.Error("Failed to remove route of unready instance",
+			slog.String("slug", container.Slug),
+			slog.String("service", container.ServiceName),
+			slog.String("error", err.Error()))
+		return
+	}
+	state.withdrawn = true
+	m.logger.Warn("Instance not ready, route removed until it is",
+		slog.String("service", container.ServiceName),
+		slog.String("slug", container.Slug),
+		slog.Int("failures", state.readinessFailures),
+		slog.String("error", result.Error))
+}
+
+// livenessFailedUnsafe counts a failed liveness check of a running container
+// and reports whether it has now failed LivenessFailures consecutive checks.
+// Failures within LivenessInitialDelay of the container starting are not
+// counted (caller holds the mutex).
+func (m *Manager) livenessFailedUnsafe(container *models.Container, result *HealthCheckResult, state *probeState, now time.Time) bool {
+	if result.Live {
+		state.livenessFailures = 0
+		return false
+	}
+	if now.Sub(m.startedAtUnsafe(container)) < m.config.Container.LivenessInitialDelay {
+		return false
+	}
+
+	state.livenessFailures++
+	limit := m.config.Container.LivenessFailures
+	if limit <= 0 || state.livenessFailures < limit {
+		return false
+	}
+	// Restarting adds the route again; readiness takes it out if needed
+	*state = probeState{}
+	return true
+}
@@ -0,0 +1,302 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// ErrQuarantined is returned when quarantining an instance twice
+var ErrQuarantined = errors.New("instance is already quarantined")
+
+// ErrNotQuarantined is returned when releasing an instance that is not quarantined
+var ErrNotQuarantined = errors.New("instance is not quarantined")
+
+// Quarantine sources
+const (
+	QuarantineManual  = "manual"
+	QuarantineAnomaly = "anomaly"
+)
+
+// Quarantine describes an isolated instance
+type Quarantine struct {
+	ServiceName   string    `json:"service_name"`
+	Reason        string    `json:"reason"`
+	Source        string    `json:"source"`
+	EgressBlocked bool      `json:"egress_blocked"`
+	Since         time.Time `json:"since"`
+}
+
+// quarantines tracks quarantined services and anomaly counters
+type quarantines struct {
+	mu        sync.Mutex
+	services  map[string]Quarantine
+	anomalies map[string]*anomalyState
+}
+
+// anomalyState holds the previous stats sample and consecutive anomalous samples
+type anomalyState struct {
+	netOutput uint64
+	sampledAt time.Time
+	count     int
+}
+
+func newQuarantines() *quarantines {
+	return &quarantines{services: make(map[string]Quarantine), anomalies: make(map[string]*anomalyState)}
+}
+
+// isQuarantined reports whether a service is quarantined
+func (m *Manager) isQuarantined(serviceName string) bool {
+	m.quarantines.mu.Lock()
+	defer m.quarantines.mu.Unlock()
+
+	_, ok := m.quarantines.services[serviceName]
+	return ok
+}
+
+// QuarantineStatus returns the quarantine of a service, if any
+func (m *Manager) QuarantineStatus(serviceName string) (Quarantine, bool) {
+	m.quarantines.mu.Lock()
+	defer m.quarantines.mu.Unlock()
+
+	quarantine, ok := m.quarantines.services[serviceName]
+	return quarantine, ok
+}
+
+// QuarantineContainer isolates a possibly compromised instance: its route is
+// removed and its egress dropped, while the container keeps running for
+// forensics. Quarantined containers are not health checked or restarted.
+func (m *Manager) QuarantineContainer(ctx context.Context, serviceName, reason, source string) (Quarantine, error) {
+	m.mutex.RLock()
+	container, exists := m.containers[serviceName]
+	m.mutex.RUnlock()
+	if !exists {
+		return Quarantine{}, fmt.Errorf("container %s not found", serviceName)
+	}
+
+	m.quarantines.mu.Lock()
+	if _, ok := m.quarantines.services[serviceName]; ok {
+		m.quarantines.mu.Unlock()
+		return Quarantine{}, ErrQuarantined
+	}
+	quarantine := Quarantine{ServiceName: serviceName, Reason: reason, Source: source, Since: time.Now()}
+	m.quarantines.services[serviceName] = quarantine
+	m.quarantines.mu.Unlock()
+
+	if container.Slug != "" {
+		if err := m.removeRoute(ctx, container.Slug); err != nil {
+			m.logger.Error("Failed to remove route of quarantined container",
+				slog.String("slug", container.Slug),
+				slog.String("service", serviceName),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if err := m.blockEgress(ctx, container); err != nil {
+		m.logger.Error("Failed to block egress of quarantined container",
+			slog.String("service", serviceName),
+			slog.String("error", err.Error()))
+	} else {
+		quarantine.EgressBlocked = true
+		m.quarantines.mu.Lock()
+		m.quarantines.services[serviceName] = quarantine
+		m.quarantines.mu.Unlock()
+	}
+
+	m.logger.Warn("Quarantined container",
+		slog.String("service", serviceName),
+		slog.String("source", source),
+		slog.String("reason", reason),
+		slog.Bool("egress_blocked", quarantine.EgressBlocked))
+	m.publishQuarantine(ctx, container, "quarantined", reason, source)
+
+	return quarantine, nil
+}
+
+// ReleaseContainer lifts the quarantine of an instance, restoring its egress
+// (with any bandwidth limits) and its route
+func (m *Manager) ReleaseContainer(ctx context.Context, serviceName string) error {
+	m.mutex.RLock()
+	container, exists := m.containers[serviceName]
+	m.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("container %s not found", serviceName)
+	}
+
+	m.quarantines.mu.Lock()
+	quarantine, ok := m.quarantines.services[serviceName]
+	if !ok {
+		m.quarantines.mu.Unlock()
+		return ErrNotQuarantined
+	}
+	delete(m.quarantines.services, serviceName)
+	delete(m.quarantines.anomalies, serviceName)
+	m.quarantines.mu.Unlock()
+
+	if quarantine.EgressBlocked {
+		if err := m.unblockEgress(ctx, container); err != nil {
+			m.logger.Error("Failed to unblock egress of released container",
+				slog.String("service", serviceName),
+				slog.String("error", err.Error()))
+		}
+	}
+	m.applyBandwidth(ctx, container)
+
+	if container.Slug != "" {
+		containerIP, err := m.getContainerIP(ctx, container.ID)
+		if err != nil {
+			m.logger.Error("Failed to get container IP after release",
+				slog.String("service", serviceName),
+				slog.String("error", err.Error()))
+		} else if err := m.addContainerRoute(ctx, container, containerIP); err != nil {
+			m.logger.Error("Failed to restore route after release",
+				slog.String("slug", container.Slug),
+				slog.String("service", serviceName),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	m.logger.Info("Released container from quarantine",
+		slog.String("service", serviceName),
+		slog.Duration("quarantined_for", time.Since(quarantine.Since)))
+	m.publishQuarantine(ctx, container, "released", "", quarantine.Source)
+
+	return nil
+}
+
+// publishQuarantine publishes a quarantine security event for an instance
+func (m *Manager) publishQuarantine(ctx context.Context, container *models.Container, action, reason, source string) {
+	instanceID, ok := container.Environment["MCP_INSTANCE_ID"]
+	if !ok {
+		return
+	}
+	event := events.QuarantineEvent{
+		InstanceID: instanceID,
+		Name:       container.ServiceName,
+		Action:     action,
+		Reason:     reason,
+		Source:     source,
+		Timestamp:  time.Now(),
+	}
+	if err := m.eventPublisher.PublishQuarantine(ctx, event); err != nil {
+		m.logger.Warn("Failed to publish quarantine event",
+			slog.String("instance_id", instanceID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// blockEgress replaces the root qdisc of the container network with one that
+// drops every outgoing packet. Ingress is left open.
+func (m *Manager) blockEgress(ctx context.Context, container *models.Container) error {
+	pid, err := m.containerPID(ctx, container)
+	if err != nil {
+		return err
+	}
+	for _, args := range egressBlockCommands(pid) {
+		if output, err := commandCombinedOutput(ctx, m.config.Container.CommandTimeout, "nsenter", args...); err != nil {
+			return fmt.Errorf("%s: %w, output: %s", strings.Join(args[3:], " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// unblockEgress removes the dropping root qdisc
+func (m *Manager) unblockEgress(ctx context.Context, container *models.Container) error {
+	pid, err := m.containerPID(ctx, container)
+	if err != nil {
+		return err
+	}
+	args := []string{"-t", pid, "-n", "tc", "qdisc", "del", "dev", bandwidthInterface, "root"}
+	if output, err := commandCombinedOutput(ctx, m.config.Container.CommandTimeout, "nsenter", args...); err != nil {
+		return fmt.Errorf("%s: %w, output: %s", strings.Join(args[3:], " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// containerPID returns the pid of a running container as a string
+func (m *Manager) containerPID(ctx context.Context, container *models.Container) (string, error) {
+	info, err := m.runtime.Inspect(ctx, container.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get container pid: %w", err)
+	}
+	if info.State.Pid == 0 {
+		return "", fmt.Errorf("container is not running")
+	}
+	return strconv.Itoa(info.State.Pid), nil
+}
+
+// egressBlockCommands builds the nsenter arguments that drop all egress in
+// the network namespace of pid
+func egressBlockCommands(pid string) [][]string {
+	tc := func(args ...string) []string {
+		return append([]string{"-t", pid, "-n", "tc"}, args...)
+	}
+	return [][]string{
+		tc("qdisc", "replace", "dev", bandwidthInterface, "root", "handle", "1:", "prio"),
+		tc("filter", "replace", "dev", bandwidthInterface, "parent", "1:", "protocol", "all", "prio", "1",
+			"u32", "match", "u32", "0", "0", "action", "drop"),
+	}
+}
+
+// checkAnomalies records a stats sample and returns why the container looks
+// compromised once an anomaly has been seen on AnomalySamples consecutive
+// samples, or "" otherwise
+func (m *Manager) checkAnomalies(container *models.Container, usage ResourceUsage, now time.Time) string {
+	egressRate, err := parseSize(m.config.Container.AnomalyEgressRate)
+	if err != nil {
+		egressRate = 0
+	}
+	maxPIDs := m.config.Container.AnomalyMaxPIDs
+	if egressRate <= 0 && maxPIDs <= 0 {
+		return ""
+	}
+
+	m.quarantines.mu.Lock()
+	defer m.quarantines.mu.Unlock()
+
+	state, ok := m.quarantines.anomalies[container.ServiceName]
+	if !ok {
+		state = &anomalyState{}
+		m.quarantines.anomalies[container.ServiceName] = state
+	}
+
+	var reason string
+	if egressRate > 0 && !state.sampledAt.IsZero() && usage.NetOutput >= state.netOutput {
+		if elapsed := now.Sub(state.sampledAt).Seconds(); elapsed > 0 {
+			if rate := float64(usage.NetOutput-state.netOutput) / elapsed; rate > float64(egressRate) {
+				reason = fmt.Sprintf("egress of %.0f bytes/s above %d", rate, egressRate)
+			}
+		}
+	}
+	if maxPIDs > 0 && usage.PIDs > uint64(maxPIDs) {
+		reason = fmt.Sprintf("%d processes above %d", usage.PIDs, maxPIDs)
+	}
+	state.netOutput = usage.NetOutput
+	state.sampledAt = now
+
+	if reason == "" {
+		state.count = 0
+		return ""
+	}
+	state.count++
+	if state.count < max(m.config.Container.AnomalySamples, 1) {
+		return ""
+	}
+	return reason
+}
+
+// forgetQuarantine drops the quarantine and anomaly state of a removed service
+func (m *Manager) forgetQuarantine(serviceName string) {
+	m.quarantines.mu.Lock()
+	delete(m.quarantines.services, serviceName)
+	delete(m.quarantines.anomalies, serviceName)
+	m.quarantines.mu.Unlock()
+}
@@ -0,0 +1,124 @@
+package container
+
+import (
+	"sort"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// maxIdleCandidates bounds the number of shutdown suggestions in a validation result
+const maxIdleCandidates = 5
+
+// QuotaUsage reports usage against a single limit
+type QuotaUsage struct {
+	Scope       string `json:"scope"` // "global" or "workspace"
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	Used        int    `json:"used"`
+	Limit       int    `json:"limit"`
+}
+
+// IdleInstance is an instance that could be stopped or deleted to free capacity
+type IdleInstance struct {
+	InstanceID    string     `json:"instance_id,omitempty"`
+	ServiceName   string     `json:"service_name"`
+	WorkspaceID   string     `json:"workspace_id,omitempty"`
+	Status        string     `json:"status"`
+	Reason        string     `json:"reason"` // "idle" or "not_running"
+	IdleSeconds   int64      `json:"idle_seconds"`
+	LastRequestAt *time.Time `json:"last_request_at,omitempty"`
+}
+
+// QuotaSnapshot captures container usage for validating a new instance
+type QuotaSnapshot struct {
+	WorkspaceID      string
+	Running          int
+	MaxContainers    int
+	WorkspaceRunning int
+	MaxPerWorkspace  int
+	IdleCandidates   []IdleInstance
+}
+
+// QuotaSnapshot returns current usage for the global and workspace limits,
+// along with instances that could be shut down to make room
+func (m *Manager) QuotaSnapshot(workspaceID string) QuotaSnapshot {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	snapshot := QuotaSnapshot{
+		WorkspaceID:     workspaceID,
+		MaxContainers:   m.config.Container.MaxContainers,
+		MaxPerWorkspace: m.config.Container.MaxPerWorkspace,
+	}
+
+	now := time.Now()
+	for _, container := range m.containers {
+		inWorkspace := workspaceID != "" && container.Environment[WorkspaceIDEnv] == workspaceID
+		running := container.Status == models.StatusRunning
+		if running {
+			snapshot.Running++
+			if inWorkspace {
+				snapshot.WorkspaceRunning++
+			}
+		}
+
+		// Only suggest the caller's own instances when a workspace is known
+		if workspaceID != "" && !inWorkspace {
+			continue
+		}
+		if candidate, ok := m.idleCandidate(container, now); ok {
+			snapshot.IdleCandidates = append(snapshot.IdleCandidates, candidate)
+		}
+	}
+
+	// Instances that are not running come first, then the longest idle
+	sort.Slice(snapshot.IdleCandidates, func(i, j int) bool {
+		a, b := snapshot.IdleCandidates[i], snapshot.IdleCandidates[j]
+		if a.Reason != b.Reason {
+			return a.Reason == "not_running"
+		}
+		return a.IdleSeconds > b.IdleSeconds
+	})
+	if len(snapshot.IdleCandidates) > maxIdleCandidates {
+		snapshot.IdleCandidates = snapshot.IdleCandidates[:maxIdleCandidates]
+	}
+
+	return snapshot
+}
+
+// idleCandidate reports whether a container is a candidate for shutdown.
+// The caller must hold the mutex.
+func (m *Manager) idleCandidate(container *models.Container, now time.Time) (IdleInstance, bool) {
+	candidate := IdleInstance{
+		InstanceID:  container.Environment["MCP_INSTANCE_ID"],
+		ServiceName: container.ServiceName,
+		WorkspaceID: container.Environment[WorkspaceIDEnv],
+		Status:      string(container.Status),
+	}
+
+	if container.Status != models.StatusRunning {
+		candidate.Reason = "not_running"
+		candidate.IdleSeconds = int64(now.Sub(container.UpdatedAt).Seconds())
+		return candidate, true
+	}
+
+	// Without proxy traffic data, fall back to when the container started
+	lastActive := container.CreatedAt
+	if rm, ok := m.routeManager.(interface {
+		LastRequestTime(string) (time.Time, bool)
+	}); ok {
+		if t, ok := rm.LastRequestTime(container.Slug); ok {
+			lastActive = t
+			candidate.LastRequestAt = &t
+		}
+	}
+
+	idle := now.Sub(lastActive)
+	if idle < m.config.Container.IdleAfter {
+		return IdleInstance{}, false
+	}
+
+	candidate.Reason = "idle"
+	candidate.IdleSeconds = int64(idle.Seconds())
+	return candidate, true
+}
@@ -0,0 +1,267 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Resources under pressure
+const (
+	ResourceMemory = "memory"
+	ResourceCPU    = "cpu"
+)
+
+// suggestedLimitFactor scales the current limit in pressure suggestions
+const suggestedLimitFactor = 1.5
+
+// ResourceUsage is one stats sample of a container
+type ResourceUsage struct {
+	MemoryBytes uint64  `json:"memory_bytes"`
+	MemoryLimit uint64  `json:"memory_limit_bytes"`
+	MemoryUsage float64 `json:"memory_usage"`     // Fraction of the memory limit
+	CPUPercent  float64 `json:"cpu_percent"`      // 100 per fully used core
+	CPUUsage    float64 `json:"cpu_usage"`        // Fraction of the CPU limit, 0 without a limit
+	NetOutput   uint64  `json:"net_output_bytes"` // Bytes sent since the container started
+	PIDs        uint64  `json:"pids"`
+}
+
+// ResourcePressure describes resources an instance has been using above the
+// threshold for several consecutive health checks
+type ResourcePressure struct {
+	Resources   []string          `json:"resources"`
+	Usage       ResourceUsage     `json:"usage"`
+	Suggestions map[string]string `json:"suggested_limits"`
+	Since       time.Time         `json:"since"`
+}
+
+// podmanStats is the subset of `podman stats --format '{{json .}}'` we use
+type podmanStats struct {
+	Name      string  `json:"Name"`
+	CPU       float64 `json:"CPU"`
+	MemUsage  uint64  `json:"MemUsage"`
+	MemLimit  uint64  `json:"MemLimit"`
+	NetOutput uint64  `json:"NetOutput"`
+	PIDs      uint64  `json:"PIDs"`
+}
+
+// resourceTracker counts consecutive high-usage samples per service
+type resourceTracker struct {
+	mu       sync.Mutex
+	services map[string]*resourceState
+}
+
+type resourceState struct {
+	high     map[string]int       // Consecutive samples above the threshold per resource
+	alerting map[string]time.Time // Resources under pressure and since when
+	last     ResourceUsage
+}
+
+func newResourceTracker() *resourceTracker {
+	return &resourceTracker{services: make(map[string]*resourceState)}
+}
+
+// collectResourceUsage samples all running containers with a single stats
+// call, keyed by container name
+func (m *Manager) collectResourceUsage(ctx context.Context, containers []*models.Container) map[string]ResourceUsage {
+	args := []string{"stats", "--no-stream", "--format", "{{json .}}"}
+	for _, container := range containers {
+		if container.Status == models.StatusRunning && container.ID != "" {
+			args = append(args, container.ID)
+		}
+	}
+	if len(args) == 4 {
+		return nil
+	}
+
+	output, err := m.runtimeOutput(ctx, args...)
+	if err != nil {
+		m.logger.Debug("Failed to collect container stats", slog.String("error", err.Error()))
+		return nil
+	}
+
+	cpuLimit, _ := strconv.ParseFloat(m.config.Container.DefaultCPULimit, 64)
+	return parseResourceUsage(output, cpuLimit)
+}
+
+// parseResourceUsage reads one JSON stats object per line. cpuLimit is in
+// cores; 0 means unlimited.
+func parseResourceUsage(output []byte, cpuLimit float64) map[string]ResourceUsage {
+	usage := make(map[string]ResourceUsage)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var stats podmanStats
+		if err := json.Unmarshal(scanner.Bytes(), &stats); err != nil || stats.Name == "" {
+			continue
+		}
+		sample := ResourceUsage{
+			MemoryBytes: stats.MemUsage,
+			MemoryLimit: stats.MemLimit,
+			CPUPercent:  stats.CPU,
+			NetOutput:   stats.NetOutput,
+			PIDs:        stats.PIDs,
+		}
+		if stats.MemLimit > 0 {
+			sample.MemoryUsage = float64(stats.MemUsage) / float64(stats.MemLimit)
+		}
+		if cpuLimit > 0 {
+			sample.CPUUsage = stats.CPU / (cpuLimit * 100)
+		}
+		usage[stats.Name] = sample
+	}
+	return usage
+}
+
+// checkResourcePressure records a stats sample and adds resource usage and
+// any resource_pressure warning to the health result details. A resource is
+// under pressure after ResourcePressureSamples consecutive samples at or above
+// ResourcePressureThreshold of its limit; an event is published when that
+// starts.
+func (m *Manager) checkResourcePressure(container *models.Container, usage ResourceUsage, result *HealthCheckResult, now time.Time) {
+	threshold := m.config.Container.ResourcePressureThreshold
+	samples := m.config.Container.ResourcePressureSamples
+	if threshold <= 0 || samples <= 0 {
+		return
+	}
+
+	usageByResource := map[string]float64{ResourceCPU: usage.CPUUsage}
+	// Without a configured memory limit the stats limit is the host memory
+	if m.config.Container.DefaultMemoryLimit != "" {
+		usageByResource[ResourceMemory] = usage.MemoryUsage
+	}
+
+	m.resources.mu.Lock()
+	state, ok := m.resources.services[container.ServiceName]
+	if !ok {
+		state = &resourceState{high: make(map[string]int), alerting: make(map[string]time.Time)}
+		m.resources.services[container.ServiceName] = state
+	}
+	state.last = usage
+
+	var started []string
+	for resource, fraction := range usageByResource {
+		if fraction < threshold {
+			state.high[resource] = 0
+			delete(state.alerting, resource)
+			continue
+		}
+		state.high[resource]++
+		if _, alerting := state.alerting[resource]; !alerting && state.high[resource] >= samples {
+			state.alerting[resource] = now
+			started = append(started, resource)
+		}
+	}
+	pressure := m.pressureUnsafe(state)
+	m.resources.mu.Unlock()
+
+	if result.Details == nil {
+		result.Details = make(map[string]interface{})
+	}
+	result.Details["resources"] = usage
+	if pressure != nil {
+		result.Details["resource_pressure"] = pressure
+	}
+
+	instanceID := container.Environment["MCP_INSTANCE_ID"]
+	for _, resource := range started {
+		event := events.ResourcePressureEvent{
+			InstanceID:     instanceID,
+			Name:           container.ServiceName,
+			Resource:       resource,
+			Usage:          usageByResource[resource],
+			Limit:          m.resourceLimit(resource),
+			SuggestedLimit: pressure.Suggestions[resource],
+			Timestamp:      now,
+		}
+
+		m.logger.Warn("Instance under resource pressure",
+			slog.String("service", container.ServiceName),
+			slog.String("resource", resource),
+			slog.Float64("usage", event.Usage),
+			slog.String("suggested_limit", event.SuggestedLimit))
+
+		if instanceID == "" {
+			continue
+		}
+		go func() {
+			if err := m.eventPublisher.PublishResourcePressure(m.healthCtx, event); err != nil {
+				m.logger.Warn("Failed to publish resource pressure event",
+					slog.String("service", event.Name),
+					slog.String("error", err.Error()))
+			}
+		}()
+	}
+}
+
+// ResourcePressure returns the current resource pressure of a service, or
+// nil if none of its resources is under pressure
+func (m *Manager) ResourcePressure(serviceName string) *ResourcePressure {
+	m.resources.mu.Lock()
+	defer m.resources.mu.Unlock()
+
+	state, ok := m.resources.services[serviceName]
+	if !ok {
+		return nil
+	}
+	return m.pressureUnsafe(state)
+}
+
+// forgetResources drops the samples of a removed service
+func (m *Manager) forgetResources(serviceName string) {
+	m.resources.mu.Lock()
+	delete(m.resources.services, serviceName)
+	m.resources.mu.Unlock()
+}
+
+// pressureUnsafe builds the pressure report of a service (caller holds resources.mu)
+func (m *Manager) pressureUnsafe(state *resourceState) *ResourcePressure {
+	if len(state.alerting) == 0 {
+		return nil
+	}
+	pressure := &ResourcePressure{Usage: state.last, Suggestions: make(map[string]string)}
+	for _, resource := range []string{ResourceMemory, ResourceCPU} {
+		since, ok := state.alerting[resource]
+		if !ok {
+			continue
+		}
+		pressure.Resources = append(pressure.Resources, resource)
+		if pressure.Since.IsZero() || since.Before(pressure.Since) {
+			pressure.Since = since
+		}
+		pressure.Suggestions[resource] = suggestLimit(resource, state.last, m.config.Container.DefaultCPULimit)
+	}
+	return pressure
+}
+
+// resourceLimit returns the configured limit of a resource
+func (m *Manager) resourceLimit(resource string) string {
+	if resource == ResourceMemory {
+		return m.config.Container.DefaultMemoryLimit
+	}
+	return m.config.Container.DefaultCPULimit
+}
+
+// suggestLimit proposes a limit 50% above the current one, rounded up to
+// 64 MiB for memory and a tenth of a core for CPU
+func suggestLimit(resource string, usage ResourceUsage, cpuLimit string) string {
+	if resource == ResourceMemory {
+		const step = 64 << 20
+		suggested := math.Ceil(float64(usage.MemoryLimit)*suggestedLimitFactor/step) * step
+		return fmt.Sprintf("%dm", uint64(suggested)>>20)
+	}
+	cores, err := strconv.ParseFloat(cpuLimit, 64)
+	if err != nil || cores <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(math.Ceil(cores*suggestedLimitFactor*10)/10, 'f', -1, 64)
+}
@@ -0,0 +1,252 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Restart policy labels persist json_spec.restart_policy on the container so
+// it survives manager restarts
+const (
+	RestartAtLabel         = "agentarea.io/restart-at"
+	RestartTimezoneLabel   = "agentarea.io/restart-timezone"
+	RestartMaxUptimeLabel  = "agentarea.io/restart-max-uptime"
+	RestartSkipActiveLabel = "agentarea.io/restart-skip-active"
+)
+
+const (
+	// restartCheckInterval is how often the scheduler evaluates restart policies
+	restartCheckInterval = time.Minute
+	// restartCatchUp bounds how late a daily restart may still run, e.g. after
+	// the manager itself was down at the scheduled time
+	restartCatchUp = time.Hour
+	// restartActiveWindow treats routes with traffic this recent as having active sessions
+	restartActiveWindow = 5 * time.Minute
+)
+
+// RestartPolicy restarts long-running instances to work around leaky MCP servers
+type RestartPolicy struct {
+	At           string         // Daily restart time, "HH:MM"
+	Location     *time.Location // Time zone for At, UTC by default
+	MaxUptime    time.Duration  // Restart once the instance has run this long
+	SkipIfActive bool           // Postpone while the instance is serving traffic
+}
+
+// ParseRestartPolicy reads json_spec.restart_policy:
+//
+//	"restart_policy": {"at": "03:00", "timezone": "Europe/Berlin",
+//	                   "max_uptime": "168h", "skip_if_active": true}
+//
+// It returns nil if the spec has no policy. skip_if_active defaults to true.
+func ParseRestartPolicy(jsonSpec map[string]interface{}) (*RestartPolicy, error) {
+	section, ok := jsonSpec["restart_policy"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	policy := &RestartPolicy{Location: time.UTC, SkipIfActive: true}
+
+	if at, ok := section["at"].(string); ok && at != "" {
+		if _, err := time.Parse("15:04", at); err != nil {
+			return nil, fmt.Errorf("invalid restart_policy.at %q: expected HH:MM", at)
+		}
+		policy.At = at
+	}
+	if tz, ok := section["timezone"].(string); ok && tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid restart_policy.timezone %q: %w", tz, err)
+		}
+		policy.Location = loc
+	}
+	if maxUptime, ok := section["max_uptime"].(string); ok && maxUptime != "" {
+		d, err := time.ParseDuration(maxUptime)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid restart_policy.max_uptime %q", maxUptime)
+		}
+		policy.MaxUptime = d
+	}
+	if skip, ok := section["skip_if_active"].(bool); ok {
+		policy.SkipIfActive = skip
+	}
+
+	if policy.At == "" && policy.MaxUptime == 0 {
+		return nil, fmt.Errorf("restart_policy needs at or max_uptime")
+	}
+	return policy, nil
+}
+
+// Labels encodes the policy as container labels
+func (p *RestartPolicy) Labels() map[string]string {
+	labels := map[string]string{
+		RestartSkipActiveLabel: strconv.FormatBool(p.SkipIfActive),
+	}
+	if p.At != "" {
+		labels[RestartAtLabel] = p.At
+		labels[RestartTimezoneLabel] = p.Location.String()
+	}
+	if p.MaxUptime > 0 {
+		labels[RestartMaxUptimeLabel] = p.MaxUptime.String()
+	}
+	return labels
+}
+
+// restartPolicyFromLabels decodes a policy written by Labels
+func restartPolicyFromLabels(labels map[string]string) *RestartPolicy {
+	section := make(map[string]interface{})
+	if at := labels[RestartAtLabel]; at != "" {
+		section["at"] = at
+		section["timezone"] = labels[RestartTimezoneLabel]
+	}
+	if maxUptime := labels[RestartMaxUptimeLabel]; maxUptime != "" {
+		section["max_uptime"] = maxUptime
+	}
+	if len(section) == 0 {
+		return nil
+	}
+	section["skip_if_active"] = labels[RestartSkipActiveLabel] != "false"
+
+	policy, err := ParseRestartPolicy(map[string]interface{}{"restart_policy": section})
+	if err != nil {
+		return nil
+	}
+	return policy
+}
+
+// due reports whether an instance running since startedAt should restart now,
+// and why
+func (p *RestartPolicy) due(now, startedAt time.Time) (string, bool) {
+	if p.At != "" {
+		at, _ := time.Parse("15:04", p.At)
+		local := now.In(p.Location)
+		scheduled := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, p.Location)
+		if scheduled.After(now) {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+		if now.Sub(scheduled) <= restartCatchUp && startedAt.Before(scheduled) {
+			return "schedule", true
+		}
+	}
+	if p.MaxUptime > 0 && now.Sub(startedAt) >= p.MaxUptime {
+		return "max_uptime", true
+	}
+	return "", false
+}
+
+// SetReservationCheck sets how instances reserved for an agent session are
+// recognized; their scheduled restarts are postponed until the reservation ends
+func (m *Manager) SetReservationCheck(reserved func(serviceName string) bool) {
+	m.reserved = reserved
+}
+
+// startRestartScheduler runs restart policies until the manager shuts down
+func (m *Manager) startRestartScheduler() {
+	ticker := time.NewTicker(restartCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.healthCtx.Done():
+			return
+		case now := <-ticker.C:
+			if !m.BackgroundPaused() {
+				m.runScheduledRestarts(m.healthCtx, now)
+			}
+		}
+	}
+}
+
+// runScheduledRestarts restarts running containers whose policy is due
+func (m *Manager) runScheduledRestarts(ctx context.Context, now time.Time) {
+	m.mutex.RLock()
+	var due []*models.Container
+	var reasons []string
+	for _, container := range m.containers {
+		if container.Status != models.StatusRunning || m.isQuarantined(container.ServiceName) {
+			continue
+		}
+		policy := restartPolicyFromLabels(container.Labels)
+		if policy == nil {
+			continue
+		}
+		reason, ok := policy.due(now, m.startedAtUnsafe(container))
+		if !ok {
+			continue
+		}
+		if m.reserved != nil && m.reserved(container.ServiceName) {
+			m.logger.Debug("Postponing scheduled restart of reserved instance",
+				slog.String("service", container.ServiceName),
+				slog.String("reason", reason))
+			continue
+		}
+		if policy.SkipIfActive && m.hasActiveSessions(container, now) {
+			m.logger.Debug("Postponing scheduled restart of active instance",
+				slog.String("service", container.ServiceName),
+				slog.String("reason", reason))
+			continue
+		}
+		due = append(due, container)
+		reasons = append(reasons, reason)
+	}
+	m.mutex.RUnlock()
+
+	for i, container := range due {
+		m.scheduledRestart(ctx, container, reasons[i])
+	}
+}
+
+// startedAtUnsafe returns when the container last started (caller holds the mutex)
+func (m *Manager) startedAtUnsafe(container *models.Container) time.Time {
+	if restarted, ok := m.restartedAt[container.ServiceName]; ok && restarted.After(container.CreatedAt) {
+		return restarted
+	}
+	return container.CreatedAt
+}
+
+// hasActiveSessions reports whether the instance has open connections or
+// served traffic recently
+func (m *Manager) hasActiveSessions(container *models.Container, now time.Time) bool {
+	if m.activeConnections(container.Slug) > 0 {
+		return true
+	}
+	rm, ok := m.routeManager.(interface {
+		LastRequestTime(string) (time.Time, bool)
+	})
+	if !ok {
+		return false
+	}
+	last, ok := rm.LastRequestTime(container.Slug)
+	return ok && now.Sub(last) < restartActiveWindow
+}
+
+// scheduledRestart stops and starts a container, publishing restarting before
+// and running (or failed) after
+func (m *Manager) scheduledRestart(ctx context.Context, container *models.Container, reason string) {
+	instanceID := container.Environment["MCP_INSTANCE_ID"]
+
+	m.logger.Info("Running scheduled restart",
+		slog.String("service", container.ServiceName),
+		slog.String("reason", reason))
+
+	if err := m.cycleContainer(ctx, container, reason); err != nil {
+		m.logger.Error("Scheduled restart failed",
+			slog.String("service", container.ServiceName),
+			slog.String("error", err.Error()))
+		if instanceID != "" {
+			if publishErr := m.eventPublisher.PublishFailed(ctx, instanceID, container.ServiceName, err.Error()); publishErr != nil {
+				m.logger.Warn("Failed to publish failed status",
+					slog.String("instance_id", instanceID),
+					slog.String("error", publishErr.Error()))
+			}
+		}
+		return
+	}
+
+	m.logger.Info("Scheduled restart completed",
+		slog.String("service", container.ServiceName))
+}
@@ -0,0 +1,190 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// routeProbeInterval is how often a new container is probed until its server
+// answers
+const routeProbeInterval = 500 * time.Millisecond
+
+// publishRoute adds the route of a newly created container. With a
+// ROUTE_READY_TIMEOUT grace period the route waits in the background until
+// the server answers a probe on its health path, so clients of the URL get
+// no 502s while it starts; after the grace period, or the startup probe's
+// window if longer, it is published anyway. Warm-up requests run between
+// the server answering and the route being published.
+// route_published is emitted once the URL routes to the container. Callers
+// hold m.mutex and have added the container to m.containers.
+func (m *Manager) publishRoute(ctx context.Context, container *models.Container, containerIP string) {
+	grace := m.config.Traefik.RouteReadyTimeout
+	if grace <= 0 {
+		if len(WarmupFromLabels(container.Labels)) > 0 {
+			m.logger.Warn("Skipping warm-up: routes are published at once without ROUTE_READY_TIMEOUT",
+				slog.String("service", container.ServiceName))
+		}
+		if m.addPublishedRoute(ctx, container, containerIP) {
+			event := m.routePublishedEvent(container, false, 0)
+			go m.emitRoutePublished(event)
+		}
+		return
+	}
+	if probe := StartupProbeFromLabels(container.Labels); probe != nil && probe.Window() > grace {
+		grace = probe.Window()
+	}
+
+	m.logger.Info("Waiting for the server to answer before publishing its route",
+		slog.String("service", container.ServiceName),
+		slog.String("slug", container.Slug),
+		slog.Duration("grace_period", grace))
+	go m.awaitRoute(container, container.ID, containerIP, grace)
+}
+
+// awaitRoute publishes the route of a container once its server answers or
+// the grace period ends, unless the container was deleted or replaced
+func (m *Manager) awaitRoute(container *models.Container, containerID, containerIP string, grace time.Duration) {
+	start := time.Now()
+	probeURL := m.healthChecker.client.URL(containerIP, container.Port, HealthPath(container.Labels))
+
+	ctx, cancel := context.WithTimeout(m.healthCtx, grace)
+	ready := m.waitServing(ctx, probeURL, container.Environment)
+	cancel()
+	if m.healthCtx.Err() != nil {
+		return
+	}
+
+	warmedUp := false
+	if ready && len(WarmupFromLabels(container.Labels)) > 0 {
+		ctx, cancel := context.WithTimeout(m.healthCtx, m.config.Traefik.WarmupTimeout)
+		err := m.warmUp(ctx, container, containerIP)
+		cancel()
+		if m.healthCtx.Err() != nil {
+			return
+		}
+		warmedUp = err == nil
+		if err != nil {
+			m.logger.Warn("Warm-up failed, publishing route anyway",
+				slog.String("service", container.ServiceName),
+				slog.String("error", err.Error()))
+			m.warnInstance(container, "Route published after warm-up failed: "+err.Error())
+		}
+	}
+	waited := time.Since(start)
+
+	m.mutex.RLock()
+	current, exists := m.containers[container.ServiceName]
+	if !exists || current != container || current.ID != containerID {
+		m.mutex.RUnlock()
+		m.logger.Debug("Container gone before its route was published",
+			slog.String("service", container.ServiceName))
+		return
+	}
+	// Deletions take the write lock before removing routes, so the route
+	// cannot be added after the container's is removed
+	published := m.addPublishedRoute(m.healthCtx, container, containerIP)
+	event := m.routePublishedEvent(container, ready, waited)
+	event.WarmedUp = warmedUp
+	m.mutex.RUnlock()
+	if !published {
+		return
+	}
+
+	if !ready {
+		m.logger.Warn("Server did not answer within the grace period, route published anyway",
+			slog.String("service", container.ServiceName),
+			slog.String("probe_url", probeURL),
+			slog.Duration("grace_period", grace))
+		m.warnInstance(container, fmt.Sprintf("Route published before the server answered %s within %s", probeURL, grace))
+	}
+	m.emitRoutePublished(event)
+}
+
+// warnInstance publishes a warning about the route of an instance created by ID
+func (m *Manager) warnInstance(container *models.Container, warning string) {
+	instanceID := container.Environment["MCP_INSTANCE_ID"]
+	if instanceID == "" {
+		return
+	}
+	if err := m.eventPublisher.PublishWarning(m.healthCtx, instanceID, container.ServiceName, warning); err != nil {
+		m.logger.Warn("Failed to publish route warning",
+			slog.String("instance_id", instanceID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// waitServing probes url of an instance with environment env until the
+// server answers or ctx is done
+func (m *Manager) waitServing(ctx context.Context, url string, env map[string]string) bool {
+	ticker := time.NewTicker(routeProbeInterval)
+	defer ticker.Stop()
+	for {
+		if m.serving(ctx, url, env) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// serving reports whether the server answers url. Any status below 500
+// counts: the server itself handled the request, where the proxy would have
+// answered 502.
+func (m *Manager) serving(ctx context.Context, url string, env map[string]string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := m.healthChecker.client.Do(req, env)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// addPublishedRoute adds the route of a container, logging failures
+func (m *Manager) addPublishedRoute(ctx context.Context, container *models.Container, containerIP string) bool {
+	if err := m.addContainerRoute(ctx, container, containerIP); err != nil {
+		m.logger.Error("Failed to add route",
+			slog.String("slug", container.Slug),
+			slog.String("service", container.ServiceName),
+			slog.String("error", err.Error()))
+		// Continue - container is created but routing may not work
+		return false
+	}
+	return true
+}
+
+// routePublishedEvent describes the route of a container just published
+func (m *Manager) routePublishedEvent(container *models.Container, ready bool, waited time.Duration) events.RoutePublishedEvent {
+	return events.RoutePublishedEvent{
+		InstanceID: container.Environment["MCP_INSTANCE_ID"],
+		Name:       container.ServiceName,
+		URL:        container.URL,
+		Ready:      ready,
+		WaitedMS:   waited.Milliseconds(),
+		Timestamp:  time.Now(),
+	}
+}
+
+// emitRoutePublished publishes route_published for instances created by ID
+func (m *Manager) emitRoutePublished(event events.RoutePublishedEvent) {
+	if event.InstanceID == "" {
+		return
+	}
+	if err := m.eventPublisher.PublishRoutePublished(m.healthCtx, event); err != nil {
+		m.logger.Warn("Failed to publish route published event",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,255 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// RouteRepair is a route whose container address changed
+type RouteRepair struct {
+	Slug   string `json:"slug"`
+	Router string `json:"router"` // "traefik" or "proxy"
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RouteSweep is the result of a route sweep
+type RouteSweep struct {
+	DryRun    bool          `json:"dry_run"`
+	Removed   []string      `json:"removed"` // Slugs of Traefik routes without a container
+	Repaired  []RouteRepair `json:"repaired"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// routeTarget is where the route of a managed container should point; addr
+// is empty when the container is not running, which keeps its route as is
+type routeTarget struct {
+	container *models.Container
+	addr      string
+}
+
+// SweepRoutes removes the Traefik routes of containers that no longer exist
+// and points the Traefik and proxy routes of running containers at their
+// current address, which changes when a container restarts outside the
+// manager. Proxy routes without a container are left alone: external and
+// load balanced instances have none.
+func (m *Manager) SweepRoutes(ctx context.Context, dryRun bool) (*RouteSweep, error) {
+	addrs := make(map[string]string)
+	m.mutex.RLock()
+	running := make(map[string]*models.Container)
+	for _, container := range m.containers {
+		if container.Slug != "" && container.ID != "" && routable(container.Status) {
+			running[container.Slug] = container
+		}
+	}
+	m.mutex.RUnlock()
+	for slug, container := range running {
+		ip, err := m.getContainerIP(ctx, container.ID)
+		if err != nil {
+			m.logger.Debug("Failed to get container IP for route sweep",
+				slog.String("slug", slug),
+				slog.String("error", err.Error()))
+			continue
+		}
+		addrs[container.ID] = net.JoinHostPort(ip, strconv.Itoa(container.Port))
+	}
+
+	// Creations and deletions hold the write lock while they change routes,
+	// so the containers seen here match the routes
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	targets := make(map[string]routeTarget, len(m.containers))
+	for _, container := range m.containers {
+		if container.Slug != "" {
+			targets[container.Slug] = routeTarget{container: container, addr: addrs[container.ID]}
+		}
+	}
+
+	sweep := &RouteSweep{DryRun: dryRun, Removed: []string{}, Repaired: []RouteRepair{}, CheckedAt: time.Now()}
+	if m.traefikManager != nil {
+		urls := make(map[string]string, len(targets))
+		for slug, target := range targets {
+			urls[slug] = ""
+			if target.addr != "" {
+				urls[slug] = "http://" + target.addr
+			}
+		}
+		removed, repaired, err := m.traefikManager.Sweep(urls, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		sweep.Removed = append(sweep.Removed, removed...)
+		sweep.Repaired = append(sweep.Repaired, repaired...)
+	}
+
+	if rm, ok := m.routeManager.(interface {
+		RouteAddr(string) (string, bool)
+	}); ok {
+		for _, slug := range slices.Sorted(maps.Keys(targets)) {
+			target := targets[slug]
+			current, exists := rm.RouteAddr(slug)
+			if !exists || target.addr == "" || current == target.addr {
+				continue
+			}
+			repair := RouteRepair{Slug: slug, Router: "proxy", From: current, To: target.addr}
+			if !dryRun {
+				ip, _, _ := net.SplitHostPort(target.addr)
+				if err := m.addContainerRoute(ctx, target.container, ip); err != nil {
+					repair.Error = err.Error()
+				}
+			}
+			sweep.Repaired = append(sweep.Repaired, repair)
+		}
+	}
+	return sweep, nil
+}
+
+// routable reports whether a container in the status serves requests
+func routable(status models.ContainerStatus) bool {
+	switch status {
+	case models.StatusRunning, models.StatusHealthy, models.StatusUnhealthy:
+		return true
+	}
+	return false
+}
+
+// startRouteSweeper sweeps routes every RouteSweepInterval
+func (m *Manager) startRouteSweeper() {
+	interval := m.config.Traefik.RouteSweepInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.healthCtx.Done():
+			return
+		case <-ticker.C:
+		}
+		if m.BackgroundPaused() {
+			continue
+		}
+		sweep, err := m.SweepRoutes(m.healthCtx, false)
+		if err != nil {
+			m.logger.Warn("Route sweep failed", slog.String("error", err.Error()))
+			continue
+		}
+		for _, repair := range sweep.Repaired {
+			if repair.Error != "" {
+				m.logger.Warn("Failed to repair route",
+					slog.String("slug", repair.Slug),
+					slog.String("router", repair.Router),
+					slog.String("error", repair.Error))
+			}
+		}
+		if len(sweep.Removed) > 0 || len(sweep.Repaired) > 0 {
+			m.logger.Info("Swept routes",
+				slog.Int("removed", len(sweep.Removed)),
+				slog.Int("repaired", len(sweep.Repaired)))
+		}
+	}
+}
+
+// Sweep removes the MCP routes of slugs missing from targets, with their
+// services and middlewares, and points the routes of slugs with a non-empty
+// target URL at it. The manager's own routes are kept. Without a config
+// file, as with the internal proxy, there is nothing to sweep.
+func (tm *TraefikManager) Sweep(targets map[string]string, dryRun bool) (removed []string, repaired []RouteRepair, err error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if _, err := os.Stat(tm.configPath); errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	config, err := tm.loadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for routerName, router := range config.HTTP.Routers {
+		slug, ok := strings.CutPrefix(routerName, "mcp-")
+		if !ok || router.Service != fmt.Sprintf("mcp-%s-service", slug) {
+			continue
+		}
+		target, live := targets[slug]
+		if !live {
+			removed = append(removed, slug)
+			delete(config.HTTP.Routers, routerName)
+			continue
+		}
+		service, ok := config.HTTP.Services[router.Service]
+		if !ok || target == "" {
+			continue
+		}
+		var current string
+		if len(service.LoadBalancer.Servers) > 0 {
+			current = service.LoadBalancer.Servers[0].URL
+		}
+		if current != target {
+			repaired = append(repaired, RouteRepair{Slug: slug, Router: "traefik", From: current, To: target})
+			service.LoadBalancer.Servers = []TraefikServer{{URL: target}}
+			config.HTTP.Services[router.Service] = service
+		}
+	}
+
+	// Drop the services and middlewares of removed routes, and any left
+	// behind earlier, unless a remaining router still uses them
+	used := make(map[string]bool)
+	for _, router := range config.HTTP.Routers {
+		used[router.Service] = true
+		for _, middleware := range router.Middlewares {
+			used[middleware] = true
+		}
+	}
+	dangling := 0
+	for name := range config.HTTP.Services {
+		if slug, ok := mcpSlug(name, "-service"); ok && !used[name] && !hasTarget(targets, slug) {
+			delete(config.HTTP.Services, name)
+			dangling++
+		}
+	}
+	for name := range config.HTTP.Middlewares {
+		if slug, ok := mcpSlug(name, "-stripprefix"); ok && !used[name] && !hasTarget(targets, slug) {
+			delete(config.HTTP.Middlewares, name)
+			dangling++
+		}
+	}
+
+	slices.Sort(removed)
+	slices.SortFunc(repaired, func(a, b RouteRepair) int { return strings.Compare(a.Slug, b.Slug) })
+	if dryRun || len(removed)+len(repaired)+dangling == 0 {
+		return removed, repaired, nil
+	}
+	if err := tm.saveConfig(config); err != nil {
+		return nil, nil, fmt.Errorf("failed to save config: %w", err)
+	}
+	return removed, repaired, nil
+}
+
+// mcpSlug returns the slug of a per-service Traefik name such as
+// mcp-{slug}-service
+func mcpSlug(name, suffix string) (string, bool) {
+	if !strings.HasPrefix(name, "mcp-") || !strings.HasSuffix(name, suffix) || len(name) <= len("mcp-")+len(suffix) {
+		return "", false
+	}
+	return name[len("mcp-") : len(name)-len(suffix)], true
+}
+
+func hasTarget(targets map[string]string, slug string) bool {
+	_, ok := targets[slug]
+	return ok
+}
@@ -0,0 +1,106 @@
+package container
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Routing modes of Docker instances
+const (
+	// RoutingProxy serves routes from the internal proxy and keeps the
+	// Traefik dynamic configuration alongside
+	RoutingProxy = "proxy"
+	// RoutingProxyOnly serves routes from the internal proxy without a
+	// Traefik configuration path; Traefik route sweeps are skipped
+	RoutingProxyOnly = "proxy-only"
+	// RoutingTraefik writes routes to the Traefik dynamic configuration only
+	RoutingTraefik = "traefik"
+)
+
+// RoutingStatus describes how instance routes are served
+type RoutingStatus struct {
+	Mode              string `json:"mode"`
+	TraefikConfigPath string `json:"traefik_config_path"`
+	TraefikAvailable  bool   `json:"traefik_available"`
+	TraefikError      string `json:"traefik_error,omitempty"` // Why the config path is unusable
+}
+
+// CheckConfigPath reports whether the dynamic configuration can be written
+// where Traefik reads it. The directory must already exist: one created by
+// the manager is not mounted into Traefik, so routes written there would
+// silently go nowhere.
+func (tm *TraefikManager) CheckConfigPath() error {
+	dir := filepath.Dir(tm.configPath)
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("directory %s does not exist; is the Traefik config volume mounted?", dir)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if _, err := os.Stat(tm.configPath); err == nil {
+		file, err := os.OpenFile(tm.configPath, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("config file is not writable: %w", err)
+		}
+		return file.Close()
+	}
+	probe, err := os.CreateTemp(dir, ".mcp-manager-probe-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// ConfigureRouting picks how instance routes are served, given whether the
+// internal proxy runs, and must be called before the backend is initialized.
+// Without a usable Traefik config path the manager degrades to proxy-only
+// routing; with neither it fails, since created instances would be
+// unreachable.
+func (m *Manager) ConfigureRouting(proxyEnabled bool) (RoutingStatus, error) {
+	status := RoutingStatus{TraefikConfigPath: m.traefikManager.configPath}
+	traefikErr := m.traefikManager.CheckConfigPath()
+	status.TraefikAvailable = traefikErr == nil
+	if traefikErr != nil {
+		status.TraefikError = traefikErr.Error()
+	}
+
+	switch {
+	case proxyEnabled && status.TraefikAvailable:
+		status.Mode = RoutingProxy
+	case proxyEnabled:
+		status.Mode = RoutingProxyOnly
+		m.traefikManager = nil
+		m.logger.Warn("Traefik config path unavailable, routing through the internal proxy only",
+			slog.String("path", status.TraefikConfigPath),
+			slog.String("error", status.TraefikError))
+	case status.TraefikAvailable:
+		status.Mode = RoutingTraefik
+	default:
+		return status, fmt.Errorf("no routing path: the internal proxy is disabled and Traefik config path %s is unusable: %w",
+			status.TraefikConfigPath, traefikErr)
+	}
+
+	m.routing = status
+	m.logger.Info("Instance routing configured", slog.String("mode", status.Mode))
+	return status, nil
+}
+
+// RuntimeClient returns how the manager reaches the container runtime:
+// docker-api, podman-api or exec
+func (m *Manager) RuntimeClient() string {
+	return m.runtime.Name()
+}
+
+// Routing returns how instance routes are served; the mode is empty until
+// ConfigureRouting is called
+func (m *Manager) Routing() RoutingStatus {
+	return m.routing
+}
@@ -0,0 +1,323 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// boundedContext bounds an operation by timeout on top of ctx, usually the
+// API request or the background loop context. A zero timeout leaves only ctx.
+func boundedContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// commandOutput runs a command bounded by timeout and returns its stdout
+func commandOutput(ctx context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	return runBounded(ctx, timeout, false, name, args...)
+}
+
+// commandCombinedOutput runs a command bounded by timeout and returns its
+// stdout and stderr
+func commandCombinedOutput(ctx context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	return runBounded(ctx, timeout, true, name, args...)
+}
+
+func runBounded(ctx context.Context, timeout time.Duration, combined bool, name string, args ...string) ([]byte, error) {
+	cmdCtx, cancel := boundedContext(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, name, args...)
+	var output []byte
+	var err error
+	if combined {
+		output, err = cmd.CombinedOutput()
+	} else {
+		output, err = cmd.Output()
+	}
+	// A killed command only reports "signal: killed"; name the deadline instead
+	if err != nil && ctx.Err() == nil && errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%s %s timed out after %s: %w", name, subcommand(args), timeout, context.DeadlineExceeded)
+	}
+	return output, err
+}
+
+func subcommand(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// runtimeOutput runs a short runtime command (inspect, ps, ...) bounded by
+// the command timeout and returns its stdout
+func (m *Manager) runtimeOutput(ctx context.Context, args ...string) ([]byte, error) {
+	return commandOutput(ctx, m.config.Container.CommandTimeout, m.config.Container.Runtime, args...)
+}
+
+// runtimeCommand runs a short runtime command (inspect, stop, rm, ...)
+// bounded by the command timeout and returns its stdout and stderr
+func (m *Manager) runtimeCommand(ctx context.Context, args ...string) ([]byte, error) {
+	return commandCombinedOutput(ctx, m.config.Container.CommandTimeout, m.config.Container.Runtime, args...)
+}
+
+// Runtime creates, inspects, starts, stops and removes instance containers.
+// The API runtimes call the Docker Engine API or Podman's libpod API on the
+// runtime socket; the exec runtime shells out to CONTAINER_RUNTIME and is the
+// fallback for both.
+type Runtime interface {
+	// Name is docker-api, podman-api or exec
+	Name() string
+	// Create creates and starts a container and returns its ID
+	Create(ctx context.Context, spec *createSpec) (string, error)
+	Inspect(ctx context.Context, id string) (*ContainerInfo, error)
+	Start(ctx context.Context, id string) error
+	// Stop stops a container, killing it if it is still running after
+	// timeout. A zero timeout uses the runtime's default.
+	Stop(ctx context.Context, id string, timeout time.Duration) error
+	Remove(ctx context.Context, id string, force bool) error
+	// List returns every container, running or not
+	List(ctx context.Context) ([]ContainerSummary, error)
+}
+
+// Runtime API modes (CONTAINER_RUNTIME_API)
+const (
+	// RuntimeAPIAuto uses the runtime socket when it exists, exec otherwise
+	RuntimeAPIAuto = "auto"
+	// RuntimeAPI requires the runtime socket
+	RuntimeAPI = "api"
+	// RuntimeExec always shells out to CONTAINER_RUNTIME
+	RuntimeExec = "exec"
+)
+
+// ContainerInfo is the part of a container's inspect output the manager
+// reads. The CLI, the Engine API and the libpod API share its field names.
+type ContainerInfo struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+	// Image is the image ID; Podman also records the name in ImageName
+	Image     string `json:"Image"`
+	ImageName string `json:"ImageName"`
+	State     struct {
+		Status   string `json:"Status"`
+		ExitCode int    `json:"ExitCode"`
+		Pid      int    `json:"Pid"`
+	} `json:"State"`
+	Config struct {
+		Image        string              `json:"Image"`
+		User         string              `json:"User"`
+		Env          []string            `json:"Env"`
+		Cmd          []string            `json:"Cmd"`
+		Labels       map[string]string   `json:"Labels"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	} `json:"Config"`
+	HostConfig struct {
+		Privileged bool     `json:"Privileged"`
+		CapAdd     []string `json:"CapAdd"`
+		Memory     int64    `json:"Memory"`
+		NanoCpus   int64    `json:"NanoCpus"`
+		CpuQuota   int64    `json:"CpuQuota"`
+	} `json:"HostConfig"`
+	NetworkSettings inspectNetworkSettings `json:"NetworkSettings"`
+}
+
+// ContainerSummary is a container as listed by ps or the containers endpoint
+type ContainerSummary struct {
+	ID     string
+	Names  []string
+	Image  string
+	State  string
+	Labels map[string]string
+}
+
+// NewRuntime picks the runtime client from CONTAINER_RUNTIME_API and
+// CONTAINER_RUNTIME_SOCKET. In auto mode a missing socket selects exec; in
+// api mode it is an error.
+func NewRuntime(cfg *config.Config, logger *slog.Logger) (Runtime, error) {
+	settings := cfg.Container
+	fallback := &execRuntime{
+		runtime:        settings.Runtime,
+		commandTimeout: settings.CommandTimeout,
+		createTimeout:  settings.CreateTimeout,
+	}
+	mode := strings.ToLower(settings.RuntimeAPI)
+	switch mode {
+	case "", RuntimeAPIAuto, RuntimeAPI:
+	case RuntimeExec:
+		return fallback, nil
+	default:
+		return nil, fmt.Errorf("invalid CONTAINER_RUNTIME_API %q: use auto, api or exec", settings.RuntimeAPI)
+	}
+
+	socket := RuntimeSocket(settings)
+	if socket == "" {
+		if mode == RuntimeAPI {
+			return nil, fmt.Errorf("CONTAINER_RUNTIME_API is api but no socket is known for runtime %s; set CONTAINER_RUNTIME_SOCKET", settings.Runtime)
+		}
+		return fallback, nil
+	}
+	if info, err := os.Stat(socket); err != nil || info.Mode()&os.ModeSocket == 0 {
+		if mode == RuntimeAPI {
+			return nil, fmt.Errorf("runtime socket %s is not available: is the API service running?", socket)
+		}
+		return fallback, nil
+	}
+	return newAPIRuntime(socket, isPodman(settings.Runtime, socket), fallback, logger), nil
+}
+
+// RuntimeSocket returns the path of the runtime's API socket:
+// CONTAINER_RUNTIME_SOCKET, or where the runtime serves it by default
+func RuntimeSocket(settings config.ContainerConfig) string {
+	if socket := strings.TrimPrefix(settings.RuntimeSocket, "unix://"); socket != "" {
+		return socket
+	}
+	return defaultRuntimeSocket(settings.Runtime)
+}
+
+// defaultRuntimeSocket returns where the runtime serves its API by default
+func defaultRuntimeSocket(runtime string) string {
+	switch filepath.Base(runtime) {
+	case "podman":
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+			return filepath.Join(dir, "podman", "podman.sock")
+		}
+		return "/run/podman/podman.sock"
+	case "docker":
+		return "/var/run/docker.sock"
+	}
+	return ""
+}
+
+// isPodman reports whether the socket is served by Podman
+func isPodman(runtime, socket string) bool {
+	return filepath.Base(runtime) == "podman" || strings.Contains(socket, "podman")
+}
+
+// execRuntime shells out to the runtime CLI
+type execRuntime struct {
+	runtime        string
+	commandTimeout time.Duration
+	createTimeout  time.Duration
+}
+
+func (r *execRuntime) Name() string { return RuntimeExec }
+
+func (r *execRuntime) Create(ctx context.Context, spec *createSpec) (string, error) {
+	output, err := commandCombinedOutput(ctx, r.createTimeout, r.runtime, runArgs(spec)...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (r *execRuntime) Inspect(ctx context.Context, id string) (*ContainerInfo, error) {
+	output, err := commandOutput(ctx, r.commandTimeout, r.runtime, "inspect", id)
+	if err != nil {
+		return nil, err
+	}
+	var inspected []ContainerInfo
+	if err := json.Unmarshal(output, &inspected); err != nil {
+		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
+	}
+	if len(inspected) == 0 {
+		return nil, fmt.Errorf("no container data found")
+	}
+	return &inspected[0], nil
+}
+
+func (r *execRuntime) Start(ctx context.Context, id string) error {
+	return r.run(ctx, "start", id)
+}
+
+func (r *execRuntime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return r.run(ctx, "stop", id)
+	}
+	// The command runs for up to the stop timeout before the kill
+	output, err := commandCombinedOutput(ctx, timeout+r.commandTimeout, r.runtime, "stop", "-t", stopSeconds(timeout), id)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// stopSeconds renders a stop timeout in whole seconds, as both the CLI and
+// the API take it
+func stopSeconds(timeout time.Duration) string {
+	return strconv.Itoa(int(timeout.Seconds()))
+}
+
+func (r *execRuntime) Remove(ctx context.Context, id string, force bool) error {
+	if force {
+		return r.run(ctx, "rm", "-f", id)
+	}
+	return r.run(ctx, "rm", id)
+}
+
+func (r *execRuntime) List(ctx context.Context) ([]ContainerSummary, error) {
+	output, err := commandOutput(ctx, r.commandTimeout, r.runtime, "ps", "-a", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	return parsePSLines(output)
+}
+
+// parsePSLines reads `ps --format '{{json .}}'` output, one container per
+// line. Podman prints names and labels as JSON; Docker joins them with
+// commas.
+func parsePSLines(output []byte) ([]ContainerSummary, error) {
+	var containers []ContainerSummary
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry struct {
+			ID     string          `json:"Id"`
+			Names  json.RawMessage `json:"Names"`
+			Image  string          `json:"Image"`
+			State  string          `json:"State"`
+			Labels json.RawMessage `json:"Labels"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse ps output: %w", err)
+		}
+		container := ContainerSummary{ID: entry.ID, Image: entry.Image, State: entry.State, Labels: make(map[string]string)}
+		var names string
+		if err := json.Unmarshal(entry.Names, &container.Names); err != nil && json.Unmarshal(entry.Names, &names) == nil {
+			container.Names = strings.Split(names, ",")
+		}
+		var labels string
+		if err := json.Unmarshal(entry.Labels, &container.Labels); err != nil && json.Unmarshal(entry.Labels, &labels) == nil {
+			for _, label := range strings.Split(labels, ",") {
+				if key, value, ok := strings.Cut(label, "="); ok {
+					container.Labels[key] = value
+				}
+			}
+		}
+		if container.Labels == nil {
+			container.Labels = make(map[string]string)
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+func (r *execRuntime) run(ctx context.Context, args ...string) error {
+	if output, err := commandCombinedOutput(ctx, r.commandTimeout, r.runtime, args...); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
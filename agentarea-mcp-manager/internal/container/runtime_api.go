@@ -0,0 +1,416 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Path prefixes of the runtime APIs. Podman serves the Docker-compatible API
+// next to libpod, which is used for everything but create.
+const (
+	engineAPIPrefix = "/v1.41"
+	libpodAPIPrefix = "/v4.0.0/libpod"
+)
+
+// apiRuntime calls the runtime API on its unix socket, falling back to the
+// runtime CLI when the socket cannot be reached
+type apiRuntime struct {
+	client   *http.Client
+	podman   bool
+	fallback *execRuntime
+	logger   *slog.Logger
+}
+
+func newAPIRuntime(socket string, podman bool, fallback *execRuntime, logger *slog.Logger) *apiRuntime {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &apiRuntime{
+		client: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		}},
+		podman:   podman,
+		fallback: fallback,
+		logger:   logger,
+	}
+}
+
+func (r *apiRuntime) Name() string {
+	if r.podman {
+		return "podman-api"
+	}
+	return "docker-api"
+}
+
+// containersPath returns the path of the containers endpoints, on libpod for
+// Podman
+func (r *apiRuntime) containersPath() string {
+	if r.podman {
+		return libpodAPIPrefix + "/containers"
+	}
+	return engineAPIPrefix + "/containers"
+}
+
+// containerPath returns the path of a container endpoint
+func (r *apiRuntime) containerPath(id, action string) string {
+	path := r.containersPath() + "/" + url.PathEscape(id)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+func (r *apiRuntime) Create(ctx context.Context, spec *createSpec) (string, error) {
+	if spec.Timezone != "" {
+		// The create body has no equivalent of Podman's --tz
+		r.logger.Debug("Creating container with the runtime CLI", slog.String("reason", "timezone"))
+		return r.fallback.Create(ctx, spec)
+	}
+
+	ctx, cancel := boundedContext(ctx, r.fallback.createTimeout)
+	defer cancel()
+	id, err := r.create(ctx, spec)
+	var apiErr *runtimeAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		// run pulls missing images; the API leaves that to the caller
+		if err := r.pull(ctx, spec.Image); err != nil {
+			return "", err
+		}
+		id, err = r.create(ctx, spec)
+	}
+	if unreachable(err) {
+		r.logger.Warn("Runtime API unreachable, creating container with the runtime CLI", slog.String("error", err.Error()))
+		return r.fallback.Create(ctx, spec)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.do(ctx, http.MethodPost, r.containerPath(id, "start"), nil, nil); err != nil {
+		return id, fmt.Errorf("container %s created but failed to start: %w", spec.Name, err)
+	}
+	return id, nil
+}
+
+// create creates the container without starting it
+func (r *apiRuntime) create(ctx context.Context, spec *createSpec) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	path := engineAPIPrefix + "/containers/create?name=" + url.QueryEscape(spec.Name)
+	if err := r.do(ctx, http.MethodPost, path, spec, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// pull pulls image; the endpoint streams progress and reports failures in it
+func (r *apiRuntime) pull(ctx context.Context, image string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"http://runtime"+engineAPIPrefix+"/images/create?fromImage="+url.QueryEscape(image), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &progress) == nil && progress.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", image, progress.Error)
+		}
+	}
+	return scanner.Err()
+}
+
+func (r *apiRuntime) Inspect(ctx context.Context, id string) (*ContainerInfo, error) {
+	ctx, cancel := boundedContext(ctx, r.fallback.commandTimeout)
+	defer cancel()
+	var info ContainerInfo
+	err := r.do(ctx, http.MethodGet, r.containerPath(id, "json"), nil, &info)
+	if unreachable(err) {
+		return r.fallback.Inspect(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (r *apiRuntime) Start(ctx context.Context, id string) error {
+	return r.command(ctx, r.fallback.commandTimeout, http.MethodPost, r.containerPath(id, "start"), func() error {
+		return r.fallback.Start(ctx, id)
+	})
+}
+
+func (r *apiRuntime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	path := r.containerPath(id, "stop")
+	if timeout > 0 {
+		path += "?t=" + stopSeconds(timeout)
+	}
+	// The call returns after up to the stop timeout, once the container is gone
+	return r.command(ctx, timeout+r.fallback.commandTimeout, http.MethodPost, path, func() error {
+		return r.fallback.Stop(ctx, id, timeout)
+	})
+}
+
+func (r *apiRuntime) Remove(ctx context.Context, id string, force bool) error {
+	return r.command(ctx, r.fallback.commandTimeout, http.MethodDelete, r.containerPath(id, "")+"?force="+strconv.FormatBool(force), func() error {
+		return r.fallback.Remove(ctx, id, force)
+	})
+}
+
+func (r *apiRuntime) List(ctx context.Context) ([]ContainerSummary, error) {
+	ctx, cancel := boundedContext(ctx, r.fallback.commandTimeout)
+	defer cancel()
+	var containers []ContainerSummary
+	err := r.do(ctx, http.MethodGet, r.containersPath()+"/json?all=true", nil, &containers)
+	if unreachable(err) {
+		return r.fallback.List(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The Engine API names containers with a leading slash
+	for i := range containers {
+		for j, name := range containers[i].Names {
+			containers[i].Names[j] = strings.TrimPrefix(name, "/")
+		}
+		if containers[i].Labels == nil {
+			containers[i].Labels = make(map[string]string)
+		}
+	}
+	return containers, nil
+}
+
+// command makes a call without a response body, bounded by timeout, and runs
+// fallback when the socket cannot be reached
+func (r *apiRuntime) command(ctx context.Context, timeout time.Duration, method, path string, fallback func() error) error {
+	cmdCtx, cancel := boundedContext(ctx, timeout)
+	defer cancel()
+	err := r.do(cmdCtx, method, path, nil, nil)
+	if unreachable(err) {
+		r.logger.Warn("Runtime API unreachable, using the runtime CLI", slog.String("error", err.Error()))
+		return fallback()
+	}
+	return err
+}
+
+// do sends a JSON request and decodes the response into out. 304 Not
+// Modified, for starting a running or stopping a stopped container, is
+// success.
+func (r *apiRuntime) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://runtime"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse runtime API response: %w", err)
+	}
+	return nil
+}
+
+// runtimeAPIError is an error response of the runtime API
+type runtimeAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *runtimeAPIError) Error() string {
+	return fmt.Sprintf("runtime API returned %d: %s", e.StatusCode, e.Message)
+}
+
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		message = body.Message
+	}
+	return &runtimeAPIError{StatusCode: resp.StatusCode, Message: message}
+}
+
+// unreachable reports whether err is a failure to connect to the socket
+func unreachable(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// createSpec is the body of a container create call. Timezone is Podman's
+// --tz, which the API cannot express; specs setting it are created with the
+// runtime CLI.
+type createSpec struct {
+	Name         string              `json:"-"`
+	Timezone     string              `json:"-"`
+	Image        string              `json:"Image"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   createHostConfig    `json:"HostConfig"`
+}
+
+type createHostConfig struct {
+	NetworkMode  string                         `json:"NetworkMode,omitempty"`
+	Binds        []string                       `json:"Binds,omitempty"`
+	PortBindings map[string][]createPortBinding `json:"PortBindings,omitempty"`
+	Memory       int64                          `json:"Memory,omitempty"`
+	NanoCPUs     int64                          `json:"NanoCpus,omitempty"`
+	DNS          []string                       `json:"Dns,omitempty"`
+	DNSSearch    []string                       `json:"DnsSearch,omitempty"`
+	ExtraHosts   []string                       `json:"ExtraHosts,omitempty"`
+	Sysctls      map[string]string              `json:"Sysctls,omitempty"`
+	Ulimits      []createUlimit                 `json:"Ulimits,omitempty"`
+	Devices      []createDevice                 `json:"Devices,omitempty"`
+	StorageOpt   map[string]string              `json:"StorageOpt,omitempty"`
+	Tmpfs        map[string]string              `json:"Tmpfs,omitempty"`
+}
+
+type createPortBinding struct {
+	HostIP   string `json:"HostIp,omitempty"`
+	HostPort string `json:"HostPort"`
+}
+
+type createUlimit struct {
+	Name string `json:"Name"`
+	Soft int64  `json:"Soft"`
+	Hard int64  `json:"Hard"`
+}
+
+type createDevice struct {
+	PathOnHost        string `json:"PathOnHost"`
+	PathInContainer   string `json:"PathInContainer"`
+	CgroupPermissions string `json:"CgroupPermissions"`
+}
+
+// runArgs renders a create spec as `run -d` arguments for the runtime CLI
+func runArgs(spec *createSpec) []string {
+	host := spec.HostConfig
+	args := []string{"run", "-d", "--name", spec.Name}
+	if host.NetworkMode != "" {
+		args = append(args, "--network", host.NetworkMode)
+	}
+	for _, port := range slices.Sorted(maps.Keys(host.PortBindings)) {
+		for _, binding := range host.PortBindings[port] {
+			published := binding.HostPort + ":" + strings.TrimSuffix(port, "/tcp")
+			if address := binding.HostIP; address != "" {
+				if strings.Contains(address, ":") {
+					address = "[" + address + "]"
+				}
+				published = address + ":" + published
+			}
+			args = append(args, "-p", published)
+		}
+	}
+	for _, env := range spec.Env {
+		args = append(args, "-e", env)
+	}
+	for _, key := range slices.Sorted(maps.Keys(spec.Labels)) {
+		args = append(args, "--label", key+"="+spec.Labels[key])
+	}
+	if spec.Timezone != "" {
+		args = append(args, "--tz", spec.Timezone)
+	}
+	for _, ulimit := range host.Ulimits {
+		args = append(args, "--ulimit", fmt.Sprintf("%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+	for _, name := range slices.Sorted(maps.Keys(host.Sysctls)) {
+		args = append(args, "--sysctl", name+"="+host.Sysctls[name])
+	}
+	for _, server := range host.DNS {
+		args = append(args, "--dns", server)
+	}
+	for _, domain := range host.DNSSearch {
+		args = append(args, "--dns-search", domain)
+	}
+	for _, entry := range host.ExtraHosts {
+		args = append(args, "--add-host", entry)
+	}
+	for _, path := range slices.Sorted(maps.Keys(host.Tmpfs)) {
+		args = append(args, "--tmpfs", path+":"+host.Tmpfs[path])
+	}
+	for _, bind := range host.Binds {
+		args = append(args, "-v", bind)
+	}
+	for _, device := range host.Devices {
+		args = append(args, "--device", deviceArg(device))
+	}
+	if host.Memory > 0 {
+		args = append(args, "--memory", strconv.FormatInt(host.Memory, 10))
+	}
+	if host.NanoCPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(host.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	for _, key := range slices.Sorted(maps.Keys(host.StorageOpt)) {
+		args = append(args, "--storage-opt", key+"="+host.StorageOpt[key])
+	}
+	if len(spec.Entrypoint) > 0 {
+		args = append(args, "--entrypoint", strings.Join(spec.Entrypoint, " "))
+	}
+	args = append(args, spec.Image)
+	return append(args, spec.Cmd...)
+}
+
+// deviceArg renders a device as host[:container[:permissions]], leaving out
+// the defaults
+func deviceArg(device createDevice) string {
+	switch {
+	case device.CgroupPermissions != "" && device.CgroupPermissions != "rwm":
+		return device.PathOnHost + ":" + device.PathInContainer + ":" + device.CgroupPermissions
+	case device.PathInContainer != "" && device.PathInContainer != device.PathOnHost:
+		return device.PathOnHost + ":" + device.PathInContainer
+	}
+	return device.PathOnHost
+}
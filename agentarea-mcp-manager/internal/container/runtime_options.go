@@ -0,0 +1,214 @@
+package container
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Runtime options of an instance, recorded in its labels
+const (
+	TimezoneLabel     = "agentarea.io/timezone"
+	LocaleLabel       = "agentarea.io/locale"
+	UlimitNofileLabel = "agentarea.io/ulimit-nofile"
+	UlimitNprocLabel  = "agentarea.io/ulimit-nproc"
+	SysctlsLabel      = "agentarea.io/sysctls"
+)
+
+// RuntimeOptionLabels lists the labels holding runtime options; backends
+// whose label values are restricted map them to their own fields instead
+var RuntimeOptionLabels = []string{TimezoneLabel, LocaleLabel, UlimitNofileLabel, UlimitNprocLabel, SysctlsLabel}
+
+// Upper bounds of the ulimits, the kernel defaults of fs.nr_open and pid_max
+const (
+	maxNofile = 1 << 20
+	maxNproc  = 1 << 22
+)
+
+// SafeSysctls are the namespaced sysctls an instance may set. They only
+// affect the instance's own network and IPC namespaces and are allowed by
+// the default kubelet configuration.
+var SafeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+	"net.ipv4.tcp_fin_timeout":            true,
+}
+
+// localePattern matches POSIX locale names such as C, C.UTF-8 or de_DE.UTF-8
+var localePattern = regexp.MustCompile(`^(C|POSIX|[a-z]{2,3}(_[A-Z]{2})?)(\.[A-Za-z0-9-]+)?(@[a-z]+)?$`)
+
+// sysctlValuePattern matches sysctl values: numbers, optionally space separated
+var sysctlValuePattern = regexp.MustCompile(`^[0-9]+( [0-9]+)*$`)
+
+// RuntimeOptions configure the process environment of an instance
+type RuntimeOptions struct {
+	Timezone string            `json:"timezone,omitempty"` // IANA zone, e.g. Europe/Berlin
+	Locale   string            `json:"locale,omitempty"`   // e.g. en_US.UTF-8
+	Ulimits  Ulimits           `json:"ulimits,omitempty"`
+	Sysctls  map[string]string `json:"sysctls,omitempty"`
+}
+
+// Ulimits are resource limits of the instance processes; 0 keeps the
+// runtime default
+type Ulimits struct {
+	Nofile int64 `json:"nofile,omitempty"` // Open file descriptors
+	Nproc  int64 `json:"nproc,omitempty"`  // Processes
+}
+
+// IsZero reports whether no option is set
+func (o RuntimeOptions) IsZero() bool {
+	return o.Timezone == "" && o.Locale == "" && o.Ulimits == Ulimits{} && len(o.Sysctls) == 0
+}
+
+// Validate checks every option that is set
+func (o RuntimeOptions) Validate() error {
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil || strings.HasPrefix(o.Timezone, "/") {
+			return fmt.Errorf("invalid timezone %q: expected an IANA zone such as Europe/Berlin", o.Timezone)
+		}
+	}
+	if o.Locale != "" && !localePattern.MatchString(o.Locale) {
+		return fmt.Errorf("invalid locale %q: expected a name such as en_US.UTF-8", o.Locale)
+	}
+	if o.Ulimits.Nofile < 0 || o.Ulimits.Nofile > maxNofile {
+		return fmt.Errorf("invalid ulimits.nofile %d: must be between 1 and %d", o.Ulimits.Nofile, maxNofile)
+	}
+	if o.Ulimits.Nproc < 0 || o.Ulimits.Nproc > maxNproc {
+		return fmt.Errorf("invalid ulimits.nproc %d: must be between 1 and %d", o.Ulimits.Nproc, maxNproc)
+	}
+	for name, value := range o.Sysctls {
+		if !SafeSysctls[name] {
+			return fmt.Errorf("sysctl %q is not allowed", name)
+		}
+		if !sysctlValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value %q for sysctl %s", value, name)
+		}
+	}
+	return nil
+}
+
+// SetRuntimeOptions validates and records runtime options in instance labels;
+// options that are not set are left out
+func SetRuntimeOptions(labels map[string]string, opts RuntimeOptions) (map[string]string, error) {
+	if opts.IsZero() {
+		return labels, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return labels, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	set := func(label, value string) {
+		if value != "" {
+			labels[label] = value
+		}
+	}
+	set(TimezoneLabel, opts.Timezone)
+	set(LocaleLabel, opts.Locale)
+	if opts.Ulimits.Nofile > 0 {
+		set(UlimitNofileLabel, strconv.FormatInt(opts.Ulimits.Nofile, 10))
+	}
+	if opts.Ulimits.Nproc > 0 {
+		set(UlimitNprocLabel, strconv.FormatInt(opts.Ulimits.Nproc, 10))
+	}
+	if len(opts.Sysctls) > 0 {
+		pairs := make([]string, 0, len(opts.Sysctls))
+		for _, name := range slices.Sorted(maps.Keys(opts.Sysctls)) {
+			pairs = append(pairs, name+"="+opts.Sysctls[name])
+		}
+		set(SysctlsLabel, strings.Join(pairs, ","))
+	}
+	return labels, nil
+}
+
+// RuntimeOptionsFromLabels reads the runtime options recorded in labels
+func RuntimeOptionsFromLabels(labels map[string]string) RuntimeOptions {
+	opts := RuntimeOptions{
+		Timezone: labels[TimezoneLabel],
+		Locale:   labels[LocaleLabel],
+	}
+	opts.Ulimits.Nofile, _ = strconv.ParseInt(labels[UlimitNofileLabel], 10, 64)
+	opts.Ulimits.Nproc, _ = strconv.ParseInt(labels[UlimitNprocLabel], 10, 64)
+	if value := labels[SysctlsLabel]; value != "" {
+		opts.Sysctls = make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			if name, v, ok := strings.Cut(pair, "="); ok {
+				opts.Sysctls[name] = v
+			}
+		}
+	}
+	return opts
+}
+
+// ParseRuntimeOptions reads the timezone, locale, ulimits and sysctls of a
+// json_spec:
+//
+//	"timezone": "Europe/Berlin", "locale": "de_DE.UTF-8",
+//	"ulimits": {"nofile": 65536, "nproc": 4096},
+//	"sysctls": {"net.ipv4.ip_unprivileged_port_start": "0"}
+func ParseRuntimeOptions(jsonSpec map[string]interface{}) RuntimeOptions {
+	var opts RuntimeOptions
+	opts.Timezone, _ = jsonSpec["timezone"].(string)
+	opts.Locale, _ = jsonSpec["locale"].(string)
+	if ulimits, ok := jsonSpec["ulimits"].(map[string]interface{}); ok {
+		if nofile, ok := ulimits["nofile"].(float64); ok {
+			opts.Ulimits.Nofile = int64(nofile)
+		}
+		if nproc, ok := ulimits["nproc"].(float64); ok {
+			opts.Ulimits.Nproc = int64(nproc)
+		}
+	}
+	if sysctls, ok := jsonSpec["sysctls"].(map[string]interface{}); ok {
+		opts.Sysctls = make(map[string]string, len(sysctls))
+		for name, value := range sysctls {
+			opts.Sysctls[name] = fmt.Sprint(value)
+		}
+	}
+	return opts
+}
+
+// Environment returns the variables selecting the timezone and locale,
+// without those already set in env
+func (o RuntimeOptions) Environment(env map[string]string) map[string]string {
+	vars := make(map[string]string)
+	add := func(name, value string) {
+		if _, set := env[name]; !set && value != "" {
+			vars[name] = value
+		}
+	}
+	add("TZ", o.Timezone)
+	add("LANG", o.Locale)
+	add("LC_ALL", o.Locale)
+	return vars
+}
+
+// applyTo adds the options to a create spec whose environment is env
+func (o RuntimeOptions) applyTo(spec *createSpec, env map[string]string) {
+	spec.Timezone = o.Timezone
+	vars := o.Environment(env)
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		spec.Env = append(spec.Env, name+"="+vars[name])
+	}
+	host := &spec.HostConfig
+	if o.Ulimits.Nofile > 0 {
+		host.Ulimits = append(host.Ulimits, createUlimit{Name: "nofile", Soft: o.Ulimits.Nofile, Hard: o.Ulimits.Nofile})
+	}
+	if o.Ulimits.Nproc > 0 {
+		host.Ulimits = append(host.Ulimits, createUlimit{Name: "nproc", Soft: o.Ulimits.Nproc, Hard: o.Ulimits.Nproc})
+	}
+	if len(o.Sysctls) > 0 && host.Sysctls == nil {
+		host.Sysctls = make(map[string]string, len(o.Sysctls))
+	}
+	maps.Copy(host.Sysctls, o.Sysctls)
+}
@@ -0,0 +1,127 @@
+package container
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Scratch volume labels record the scratch volume of an instance
+const (
+	ScratchSizeLabel = "agentarea.io/scratch-size" // Bytes
+	ScratchPathLabel = "agentarea.io/scratch-path"
+)
+
+// DefaultScratchPath is where the scratch volume is mounted unless a path is given
+const DefaultScratchPath = "/workspace"
+
+// reservedScratchPaths cannot be covered by a scratch volume
+var reservedScratchPaths = []string{"/", "/bin", "/dev", "/etc", "/lib", "/proc", "/sbin", "/sys", "/usr"}
+
+// ScratchVolume is an empty, writable directory that lives as long as the
+// instance's container: a tmpfs with a size limit in Docker mode, an
+// emptyDir with a sizeLimit in Kubernetes
+type ScratchVolume struct {
+	Size string `json:"size"`           // e.g. "1Gi", "512Mi" or "1g"
+	Path string `json:"path,omitempty"` // Mount path, default /workspace
+}
+
+// Bytes returns the size limit; Kubernetes (Gi, Mi, Ki) and Podman (g, m,
+// k) suffixes are both binary multiples
+func (s ScratchVolume) Bytes() (int64, error) {
+	size := strings.TrimSuffix(strings.TrimSpace(s.Size), "i")
+	bytes, err := parseSize(size)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("invalid scratch.size %q: expected a size such as 1Gi or 512Mi", s.Size)
+	}
+	return bytes, nil
+}
+
+// MountPath returns the cleaned mount path
+func (s ScratchVolume) MountPath() string {
+	if s.Path == "" {
+		return DefaultScratchPath
+	}
+	return path.Clean(s.Path)
+}
+
+// Validate checks the size and that the path is absolute and does not hide
+// a system directory
+func (s ScratchVolume) Validate() error {
+	if _, err := s.Bytes(); err != nil {
+		return err
+	}
+	if s.Path != "" && !path.IsAbs(s.Path) {
+		return fmt.Errorf("invalid scratch.path %q: must be absolute", s.Path)
+	}
+	for _, reserved := range reservedScratchPaths {
+		if s.MountPath() == reserved {
+			return fmt.Errorf("invalid scratch.path %q: cannot mount over %s", s.Path, reserved)
+		}
+	}
+	return nil
+}
+
+// SetScratch records the scratch volume of an instance in labels, replacing
+// any labels of those names supplied with the spec. nil records none.
+func SetScratch(labels map[string]string, scratch *ScratchVolume) (map[string]string, error) {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, ScratchSizeLabel)
+	delete(labels, ScratchPathLabel)
+	if scratch == nil {
+		return labels, nil
+	}
+	if err := scratch.Validate(); err != nil {
+		return labels, err
+	}
+	bytes, _ := scratch.Bytes()
+	labels[ScratchSizeLabel] = strconv.FormatInt(bytes, 10)
+	labels[ScratchPathLabel] = scratch.MountPath()
+	return labels, nil
+}
+
+// ScratchFromLabels reads the scratch volume recorded in labels, nil if the
+// instance has none
+func ScratchFromLabels(labels map[string]string) *ScratchVolume {
+	size, ok := labels[ScratchSizeLabel]
+	if !ok {
+		return nil
+	}
+	return &ScratchVolume{Size: size, Path: labels[ScratchPathLabel]}
+}
+
+// ParseScratch reads json_spec.scratch:
+//
+//	"scratch": {"size": "1Gi", "path": "/workspace"}
+//
+// It returns nil if the spec has no scratch volume.
+func ParseScratch(jsonSpec map[string]interface{}) (*ScratchVolume, error) {
+	section, ok := jsonSpec["scratch"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	scratch := &ScratchVolume{}
+	scratch.Size, _ = section["size"].(string)
+	scratch.Path, _ = section["path"].(string)
+	if err := scratch.Validate(); err != nil {
+		return nil, err
+	}
+	return scratch, nil
+}
+
+// applyTo mounts the volume as a tmpfs in a create spec. World-writable so
+// servers running as any user can use it; tmpfs pages count against the
+// container's memory limit.
+func (s ScratchVolume) applyTo(spec *createSpec) {
+	bytes, err := s.Bytes()
+	if err != nil {
+		return
+	}
+	if spec.HostConfig.Tmpfs == nil {
+		spec.HostConfig.Tmpfs = make(map[string]string)
+	}
+	spec.HostConfig.Tmpfs[s.MountPath()] = fmt.Sprintf("rw,nosuid,nodev,size=%d,mode=1777", bytes)
+}
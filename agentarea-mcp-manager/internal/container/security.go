@@ -0,0 +1,183 @@
+package container
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+// Security finding severities
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// SecurityFinding is one posture problem of an instance and how to fix it
+type SecurityFinding struct {
+	Check       string `json:"check"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// InstancePosture lists the findings of one managed container
+type InstancePosture struct {
+	InstanceID  string            `json:"instance_id,omitempty"`
+	ServiceName string            `json:"service_name"`
+	Image       string            `json:"image"`
+	Findings    []SecurityFinding `json:"findings"`
+}
+
+// SecurityReport summarizes the security posture of all managed containers
+type SecurityReport struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Instances   []InstancePosture `json:"instances"`
+	Summary     map[string]int    `json:"summary"` // Instances failing each check
+	BySeverity  map[string]int    `json:"by_severity"`
+}
+
+// managedEnv are variables set by the manager itself, never reported as
+// plaintext secrets
+var managedEnv = map[string]bool{
+	AuthTokenEnv:         true,
+	WorkspaceIDEnv:       true,
+	"MCP_INSTANCE_ID":    true,
+	"MCP_SERVICE_NAME":   true,
+	"MCP_CONTAINER_PORT": true,
+}
+
+// SecurityReport inspects every managed container and reports its posture
+func (m *Manager) SecurityReport(ctx context.Context) SecurityReport {
+	m.mutex.RLock()
+	containers := make([]*models.Container, 0, len(m.containers))
+	for _, container := range m.containers {
+		containers = append(containers, container)
+	}
+	m.mutex.RUnlock()
+
+	report := SecurityReport{
+		GeneratedAt: time.Now(),
+		Instances:   make([]InstancePosture, 0, len(containers)),
+		Summary:     make(map[string]int),
+		BySeverity:  make(map[string]int),
+	}
+	for _, container := range containers {
+		var inspect *ContainerInfo
+		if container.ID != "" {
+			var err error
+			if inspect, err = m.runtime.Inspect(ctx, container.ID); err != nil {
+				m.logger.Debug("Failed to inspect container for security report",
+					slog.String("service", container.ServiceName),
+					slog.String("error", err.Error()))
+			}
+		}
+
+		posture := InstancePosture{
+			InstanceID:  container.Environment["MCP_INSTANCE_ID"],
+			ServiceName: container.ServiceName,
+			Image:       container.Image,
+			Findings:    assessPosture(container, inspect),
+		}
+		for _, finding := range posture.Findings {
+			report.Summary[finding.Check]++
+			report.BySeverity[finding.Severity]++
+		}
+		report.Instances = append(report.Instances, posture)
+	}
+
+	sort.Slice(report.Instances, func(i, j int) bool {
+		return report.Instances[i].ServiceName < report.Instances[j].ServiceName
+	})
+	return report
+}
+
+// assessPosture checks a container against the posture rules. Without inspect
+// output only the checks that need none are run.
+func assessPosture(container *models.Container, inspect *ContainerInfo) []SecurityFinding {
+	findings := []SecurityFinding{}
+	add := func(check, severity, message, remediation string) {
+		findings = append(findings, SecurityFinding{Check: check, Severity: severity, Message: message, Remediation: remediation})
+	}
+
+	if inspect != nil {
+		if inspect.HostConfig.Privileged {
+			add("privileged", SeverityCritical, "container runs privileged",
+				"Recreate the instance without --privileged")
+		}
+		if user := inspect.Config.User; isRootUser(user) {
+			add("runs_as_root", SeverityHigh, "container runs as root",
+				"Set a non-root USER in the image")
+		}
+		if len(inspect.HostConfig.CapAdd) > 0 {
+			add("added_capabilities", SeverityMedium,
+				"container adds capabilities: "+strings.Join(inspect.HostConfig.CapAdd, ", "),
+				"Drop capabilities the server does not need")
+		}
+		if inspect.HostConfig.Memory <= 0 {
+			add("no_memory_limit", SeverityMedium, "container has no memory limit",
+				"Set DEFAULT_MEMORY_LIMIT or resources.memory_limit")
+		}
+		if inspect.HostConfig.NanoCpus <= 0 && inspect.HostConfig.CpuQuota <= 0 {
+			add("no_cpu_limit", SeverityLow, "container has no CPU limit",
+				"Set DEFAULT_CPU_LIMIT or resources.cpu_limit")
+		}
+	}
+
+	if usesLatestTag(container.Image) {
+		add("latest_tag", SeverityMedium, "image uses the :latest tag",
+			"Pin the image to a version tag")
+	}
+	if !strings.Contains(container.Image, "@sha256:") {
+		add("unpinned_digest", SeverityLow, "image is not pinned to a digest",
+			"Reference the image as name@sha256:<digest>")
+	}
+
+	if keys := plaintextSecrets(container.Environment); len(keys) > 0 {
+		add("plaintext_secrets", SeverityHigh,
+			"secret-looking environment variables hold plaintext values: "+strings.Join(keys, ", "),
+			"Store the values in the secret store and reference them as secret_ref:<name>")
+	}
+
+	return findings
+}
+
+// isRootUser reports whether a container user is root; an empty user runs as
+// the image default, which is root unless the image sets USER
+func isRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "0" || name == "root"
+}
+
+// usesLatestTag reports whether an image reference has no tag or :latest
+func usesLatestTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(name, ":")
+	return !found || tag == "latest"
+}
+
+// plaintextSecrets returns the secret-looking variables whose values were
+// not resolved from a secret reference, sorted by name
+func plaintextSecrets(env map[string]string) []string {
+	var keys []string
+	for key, value := range env {
+		if value == "" || managedEnv[key] || !redact.IsSecretKey(key) {
+			continue
+		}
+		if strings.HasPrefix(value, "secret_ref:") || redact.IsSecret(value) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
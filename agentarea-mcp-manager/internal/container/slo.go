@@ -0,0 +1,342 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// SLO labels persist json_spec.slo on the container
+const (
+	SLOObjectiveLabel = "agentarea.io/slo-objective"
+	SLOWindowLabel    = "agentarea.io/slo-window"
+)
+
+const (
+	// sloMaxWindow bounds SLO windows and how long health history is kept
+	sloMaxWindow = 7 * 24 * time.Hour
+	// sloBucket is the resolution of the health history
+	sloBucket = time.Minute
+	// sloBurnWindow is the recent period the burn rate is measured over
+	sloBurnWindow = time.Hour
+	// sloMinBurnChecks avoids burn rate alerts on a handful of checks
+	sloMinBurnChecks = 10
+)
+
+// SLO alert reasons
+const (
+	SLOReasonBurnRate        = "burn_rate"
+	SLOReasonBudgetExhausted = "budget_exhausted"
+)
+
+// ErrNoSLO is returned when neither the instance nor its template defines an SLO
+var ErrNoSLO = errors.New("no SLO defined for instance")
+
+// SLO is a health check objective over a rolling window
+type SLO struct {
+	Objective float64       // Fraction of health checks that must pass, e.g. 0.99
+	Window    time.Duration // Rolling window, e.g. 24h
+	Source    string        // "instance" or "template"
+}
+
+// SLOStatus is the compliance of an instance with its SLO
+type SLOStatus struct {
+	Source          string  `json:"source"`
+	Objective       float64 `json:"objective"`
+	Window          string  `json:"window"`
+	Checks          int     `json:"checks"`
+	Passed          int     `json:"passed"`
+	Compliance      float64 `json:"compliance"`
+	BudgetRemaining float64 `json:"budget_remaining"` // Fraction of the error budget left; negative once overspent
+	BurnRate        float64 `json:"burn_rate"`        // Budget consumption over the last hour relative to the sustainable rate
+	Alerting        bool    `json:"alerting"`
+	AlertReason     string  `json:"alert_reason,omitempty"`
+}
+
+// ParseSLO reads json_spec.slo:
+//
+//	"slo": {"objective": 0.99, "window": "24h"}
+//
+// It returns nil if the spec has no SLO.
+func ParseSLO(jsonSpec map[string]interface{}) (*SLO, error) {
+	section, ok := jsonSpec["slo"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	objective, _ := section["objective"].(float64)
+	window, _ := section["window"].(string)
+	return newSLO(objective, window, "instance")
+}
+
+// SLOFromTemplate converts a template SLO, returning nil if there is none
+func SLOFromTemplate(slo *models.HealthSLO) (*SLO, error) {
+	if slo == nil {
+		return nil, nil
+	}
+	return newSLO(slo.Objective, slo.Window, "template")
+}
+
+// newSLO validates an objective and window
+func newSLO(objective float64, window, source string) (*SLO, error) {
+	if objective <= 0 || objective >= 1 {
+		return nil, fmt.Errorf("invalid slo.objective %v: must be between 0 and 1", objective)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d < sloBurnWindow || d > sloMaxWindow {
+		return nil, fmt.Errorf("invalid slo.window %q: must be between %s and %s", window, sloBurnWindow, sloMaxWindow)
+	}
+	return &SLO{Objective: objective, Window: d, Source: source}, nil
+}
+
+// Labels encodes the SLO as container labels
+func (s *SLO) Labels() map[string]string {
+	return map[string]string{
+		SLOObjectiveLabel: strconv.FormatFloat(s.Objective, 'f', -1, 64),
+		SLOWindowLabel:    s.Window.String(),
+	}
+}
+
+// sloFromLabels decodes an SLO written by Labels
+func sloFromLabels(labels map[string]string) *SLO {
+	objective, err := strconv.ParseFloat(labels[SLOObjectiveLabel], 64)
+	if err != nil {
+		return nil
+	}
+	slo, err := newSLO(objective, labels[SLOWindowLabel], "instance")
+	if err != nil {
+		return nil
+	}
+	return slo
+}
+
+// healthBucket counts health checks in one minute
+type healthBucket struct {
+	start  time.Time
+	passed int
+	total  int
+}
+
+// healthHistory keeps per-minute health check counts per service
+type healthHistory struct {
+	mu      sync.Mutex
+	buckets map[string][]healthBucket
+}
+
+func newHealthHistory() *healthHistory {
+	return &healthHistory{buckets: make(map[string][]healthBucket)}
+}
+
+// record adds a health check result and drops buckets older than sloMaxWindow
+func (h *healthHistory) record(service string, now time.Time, healthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	start := now.Truncate(sloBucket)
+	buckets := h.buckets[service]
+	if n := len(buckets); n == 0 || !buckets[n-1].start.Equal(start) {
+		buckets = append(buckets, healthBucket{start: start})
+	}
+	last := &buckets[len(buckets)-1]
+	last.total++
+	if healthy {
+		last.passed++
+	}
+
+	cutoff := now.Add(-sloMaxWindow)
+	drop := 0
+	for drop < len(buckets) && buckets[drop].start.Before(cutoff) {
+		drop++
+	}
+	h.buckets[service] = buckets[drop:]
+}
+
+// since returns the passed and total checks recorded after the given time
+func (h *healthHistory) since(service string, from time.Time) (passed, total int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := h.buckets[service]
+	for i := len(buckets) - 1; i >= 0 && !buckets[i].start.Before(from.Truncate(sloBucket)); i-- {
+		passed += buckets[i].passed
+		total += buckets[i].total
+	}
+	return passed, total
+}
+
+// forget drops the history of a removed service
+func (h *healthHistory) forget(service string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.buckets, service)
+}
+
+// evaluate computes SLO compliance from the health history
+func (h *healthHistory) evaluate(service string, slo *SLO, now time.Time, burnRateAlert float64) *SLOStatus {
+	passed, total := h.since(service, now.Add(-slo.Window))
+	status := &SLOStatus{
+		Source:          slo.Source,
+		Objective:       slo.Objective,
+		Window:          slo.Window.String(),
+		Checks:          total,
+		Passed:          passed,
+		Compliance:      1,
+		BudgetRemaining: 1,
+	}
+	if total == 0 {
+		return status
+	}
+
+	budget := 1 - slo.Objective
+	status.Compliance = float64(passed) / float64(total)
+	status.BudgetRemaining = 1 - (1-status.Compliance)/budget
+
+	burnPassed, burnTotal := h.since(service, now.Add(-sloBurnWindow))
+	if burnTotal > 0 {
+		status.BurnRate = (1 - float64(burnPassed)/float64(burnTotal)) / budget
+	}
+
+	switch {
+	case status.BudgetRemaining <= 0:
+		status.AlertReason = SLOReasonBudgetExhausted
+	case burnRateAlert > 0 && burnTotal >= sloMinBurnChecks && status.BurnRate >= burnRateAlert:
+		status.AlertReason = SLOReasonBurnRate
+	}
+	status.Alerting = status.AlertReason != ""
+	return status
+}
+
+// SetTemplateSLOLookup sets how SLOs are found for instances created from a
+// template that do not define their own
+func (m *Manager) SetTemplateSLOLookup(lookup func(templateID string) *SLO) {
+	m.templateSLO = lookup
+}
+
+// sloFor returns the SLO of a container: its own, else its template's
+func (m *Manager) sloFor(container *models.Container) *SLO {
+	if slo := sloFromLabels(container.Labels); slo != nil {
+		return slo
+	}
+	if template := container.Labels[TemplateLabel]; template != "" && m.templateSLO != nil {
+		return m.templateSLO(template)
+	}
+	return nil
+}
+
+// SLOStatus returns the SLO compliance of a container
+func (m *Manager) SLOStatus(serviceName string) (*SLOStatus, error) {
+	container, err := m.GetContainer(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	slo := m.sloFor(container)
+	if slo == nil {
+		return nil, ErrNoSLO
+	}
+	return m.healthHistory.evaluate(container.ServiceName, slo, time.Now(), m.config.SLO.BurnRateAlert), nil
+}
+
+// recordSLOSample stores a health check result and fires or resolves the
+// SLO alert of the container when its state changes
+func (m *Manager) recordSLOSample(container *models.Container, healthy bool, now time.Time) {
+	m.healthHistory.record(container.ServiceName, now, healthy)
+
+	slo := m.sloFor(container)
+	if slo == nil {
+		return
+	}
+	status := m.healthHistory.evaluate(container.ServiceName, slo, now, m.config.SLO.BurnRateAlert)
+
+	m.sloMu.Lock()
+	previous := m.sloAlerts[container.ServiceName]
+	if status.AlertReason == previous {
+		m.sloMu.Unlock()
+		return
+	}
+	if status.Alerting {
+		m.sloAlerts[container.ServiceName] = status.AlertReason
+	} else {
+		delete(m.sloAlerts, container.ServiceName)
+	}
+	m.sloMu.Unlock()
+
+	event := events.SLOAlertEvent{
+		InstanceID:      container.Environment["MCP_INSTANCE_ID"],
+		Name:            container.ServiceName,
+		State:           "firing",
+		Reason:          status.AlertReason,
+		Objective:       status.Objective,
+		Window:          status.Window,
+		Compliance:      status.Compliance,
+		BudgetRemaining: status.BudgetRemaining,
+		BurnRate:        status.BurnRate,
+		Timestamp:       now,
+	}
+	if !status.Alerting {
+		event.State = "resolved"
+		event.Reason = previous
+	}
+
+	m.logger.Warn("Instance SLO alert",
+		slog.String("service", container.ServiceName),
+		slog.String("state", event.State),
+		slog.String("reason", event.Reason),
+		slog.Float64("compliance", status.Compliance),
+		slog.Float64("budget_remaining", status.BudgetRemaining),
+		slog.Float64("burn_rate", status.BurnRate))
+
+	go m.sendSLOAlert(event)
+}
+
+// sendSLOAlert publishes an SLO alert event and posts it to the webhook if configured
+func (m *Manager) sendSLOAlert(event events.SLOAlertEvent) {
+	if event.InstanceID != "" {
+		if err := m.eventPublisher.PublishSLOAlert(m.healthCtx, event); err != nil {
+			m.logger.Warn("Failed to publish SLO alert",
+				slog.String("service", event.Name),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if m.config.SLO.WebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(m.healthCtx, m.config.SLO.WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.SLO.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		m.logger.Warn("Invalid SLO alert webhook", slog.String("error", err.Error()))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		m.logger.Warn("Failed to deliver SLO alert webhook",
+			slog.String("service", event.Name),
+			slog.String("error", err.Error()))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		m.logger.Warn("SLO alert webhook rejected alert",
+			slog.String("service", event.Name),
+			slog.Int("status", resp.StatusCode))
+	}
+}
@@ -0,0 +1,164 @@
+package container
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Startup probe labels record the startup probe of an instance
+const (
+	StartupPeriodLabel           = "agentarea.io/startup-period"
+	StartupFailureThresholdLabel = "agentarea.io/startup-failure-threshold"
+)
+
+// Startup probe defaults, those of a Kubernetes probe given a five minute window
+const (
+	defaultStartupPeriod           = 10 * time.Second
+	defaultStartupFailureThreshold = 30
+)
+
+// StartupProbe gives a slow-starting server a window of Period times
+// FailureThreshold to become ready. Until it does, failed health checks
+// report the instance as starting instead of failing it, and liveness and
+// readiness are not evaluated.
+type StartupProbe struct {
+	Period           string `json:"period,omitempty"`            // Time between probes, e.g. "10s"
+	FailureThreshold int    `json:"failure_threshold,omitempty"` // Failed probes allowed before the instance fails
+}
+
+// Validate checks the probe, which needs whole seconds for Kubernetes
+func (p StartupProbe) Validate() error {
+	if p.Period != "" {
+		period, err := time.ParseDuration(p.Period)
+		if err != nil || period < time.Second || period%time.Second != 0 {
+			return fmt.Errorf("invalid startup_probe.period %q: expected whole seconds such as 10s", p.Period)
+		}
+	}
+	if p.FailureThreshold < 0 {
+		return fmt.Errorf("invalid startup_probe.failure_threshold %d: must be positive", p.FailureThreshold)
+	}
+	return nil
+}
+
+// PeriodDuration returns the time between probes
+func (p StartupProbe) PeriodDuration() time.Duration {
+	if period, err := time.ParseDuration(p.Period); err == nil && period >= time.Second {
+		return period
+	}
+	return defaultStartupPeriod
+}
+
+// Failures returns the number of failed probes allowed
+func (p StartupProbe) Failures() int {
+	if p.FailureThreshold > 0 {
+		return p.FailureThreshold
+	}
+	return defaultStartupFailureThreshold
+}
+
+// Window returns how long the server has to become ready
+func (p StartupProbe) Window() time.Duration {
+	return p.PeriodDuration() * time.Duration(p.Failures())
+}
+
+// SetStartupProbe records the startup probe of an instance in labels,
+// replacing any labels of those names supplied with the spec. nil records
+// none.
+func SetStartupProbe(labels map[string]string, probe *StartupProbe) (map[string]string, error) {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, StartupPeriodLabel)
+	delete(labels, StartupFailureThresholdLabel)
+	if probe == nil {
+		return labels, nil
+	}
+	if err := probe.Validate(); err != nil {
+		return labels, err
+	}
+	labels[StartupPeriodLabel] = probe.PeriodDuration().String()
+	labels[StartupFailureThresholdLabel] = strconv.Itoa(probe.Failures())
+	return labels, nil
+}
+
+// StartupProbeFromLabels reads the startup probe recorded in labels, nil if
+// the instance has none
+func StartupProbeFromLabels(labels map[string]string) *StartupProbe {
+	period, ok := labels[StartupPeriodLabel]
+	if !ok {
+		return nil
+	}
+	threshold, _ := strconv.Atoi(labels[StartupFailureThresholdLabel])
+	return &StartupProbe{Period: period, FailureThreshold: threshold}
+}
+
+// ParseStartupProbe reads json_spec.startup_probe:
+//
+//	"startup_probe": {"period": "10s", "failure_threshold": 60}
+//
+// It returns nil if the spec has no startup probe.
+func ParseStartupProbe(jsonSpec map[string]interface{}) (*StartupProbe, error) {
+	section, ok := jsonSpec["startup_probe"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	probe := &StartupProbe{}
+	probe.Period, _ = section["period"].(string)
+	if threshold, ok := section["failure_threshold"].(float64); ok {
+		probe.FailureThreshold = int(threshold)
+	}
+	if err := probe.Validate(); err != nil {
+		return nil, err
+	}
+	return probe, nil
+}
+
+// applyStartup holds back the health check of a container still within the
+// window of its startup probe: once the server is ready the startup has
+// passed; until then a running container is reported as starting and its
+// result does not drive readiness, liveness or crash loops
+func (m *Manager) applyStartup(container *models.Container, result *HealthCheckResult, now time.Time) {
+	probe := StartupProbeFromLabels(container.Labels)
+	if probe == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	startedAt := m.startedAtUnsafe(container)
+	state, ok := m.probes[container.ServiceName]
+	if ok && state.startupPassed.After(startedAt) {
+		return
+	}
+	if now.Sub(startedAt) >= probe.Window() {
+		return
+	}
+	if result.Ready {
+		if !ok {
+			state = &probeState{}
+			m.probes[container.ServiceName] = state
+		}
+		state.startupPassed = now
+		m.logger.Info("Container passed its startup probe",
+			slog.String("service", container.ServiceName),
+			slog.Duration("after", now.Sub(startedAt)))
+		return
+	}
+	if result.Status != models.StatusRunning {
+		// An exited container fails whatever its startup window
+		return
+	}
+
+	result.starting = true
+	result.probed = false
+	result.Status = models.StatusStarting
+	if result.Details == nil {
+		result.Details = make(map[string]interface{})
+	}
+	result.Details["startup_window_remaining_ms"] = (probe.Window() - now.Sub(startedAt)).Milliseconds()
+}
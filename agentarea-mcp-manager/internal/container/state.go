@@ -0,0 +1,253 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/store"
+)
+
+// containerSpec is the spec persisted with a container: what the manager
+// needs to run, route and recreate it after a restart
+type containerSpec struct {
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	Port        int               `json:"port"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Environment map[string]string `json:"environment,omitempty"`
+	Command     []string          `json:"command,omitempty"`
+	Checkpoint  *Checkpoint       `json:"checkpoint,omitempty"` // Set while stopped at a checkpoint
+	// Credentials left out of Environment by an unencrypted store; they are
+	// read back from the container on restore
+	Withheld []string `json:"withheld_environment,omitempty"`
+}
+
+// stateWrites queues container records snapshotted under m.mutex, and
+// deletions, until the caller has released it, so slow store writes do not
+// hold up the manager
+type stateWrites struct {
+	mu      sync.Mutex // Guards pending
+	pending []stateWrite
+	writeMu sync.Mutex // Keeps writes in the order they were queued
+}
+
+// stateWrite puts record, or deletes the state of its service when forget
+// is set
+type stateWrite struct {
+	record store.Container
+	forget bool
+}
+
+// SetStateStore sets where containers are persisted. On startup discovery
+// takes service names, slugs, specs and desired state from it instead of
+// deriving them from the runtime and the Traefik config. Unless the store
+// encrypts them, credentials in the environment are not persisted.
+func (m *Manager) SetStateStore(containers store.ContainerStore) {
+	m.stateStore = containers
+	m.stateEncrypted = store.IsEncrypted(containers)
+}
+
+// saveState snapshots a container for persisting. Call with m.mutex held or
+// before the container is shared, and call flushState once the mutex is
+// released.
+func (m *Manager) saveState(ctx context.Context, container *models.Container) {
+	if m.stateStore == nil {
+		return
+	}
+
+	spec := containerSpec{
+		Name:        container.Name,
+		Image:       container.Image,
+		Port:        container.Port,
+		Labels:      maps.Clone(container.Labels),
+		Environment: maps.Clone(container.Environment),
+		Command:     slices.Clone(container.Command),
+	}
+	if !m.stateEncrypted {
+		for key, value := range spec.Environment {
+			if key == AuthTokenEnv || redact.IsSecretKey(key) || redact.IsSecret(value) {
+				delete(spec.Environment, key)
+				spec.Withheld = append(spec.Withheld, key)
+			}
+		}
+		slices.Sort(spec.Withheld)
+	}
+	desired := store.ContainerRunning
+	if m.stopped[container.ServiceName] {
+		desired = store.ContainerStopped
+	}
+	m.checkpoints.mu.Lock()
+	if checkpoint, ok := m.checkpoints.services[container.ServiceName]; ok && !checkpoint.LeaveRunning {
+		spec.Checkpoint = &checkpoint
+		desired = store.ContainerStopped
+	}
+	m.checkpoints.mu.Unlock()
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		m.logger.Warn("Failed to persist container state",
+			slog.String("service", container.ServiceName),
+			slog.String("error", err.Error()))
+		return
+	}
+	m.queueState(stateWrite{record: store.Container{
+		ServiceName:  container.ServiceName,
+		ContainerID:  container.ID,
+		InstanceID:   container.Environment["MCP_INSTANCE_ID"],
+		Slug:         container.Slug,
+		DesiredState: desired,
+		Spec:         raw,
+		CreatedAt:    container.CreatedAt,
+	}})
+}
+
+func (m *Manager) queueState(write stateWrite) {
+	m.stateWrites.mu.Lock()
+	m.stateWrites.pending = append(m.stateWrites.pending, write)
+	m.stateWrites.mu.Unlock()
+}
+
+// flushState writes the records queued by saveState and forgetState. Call
+// without m.mutex held, usually deferred before locking it.
+func (m *Manager) flushState(ctx context.Context) {
+	if m.stateStore == nil {
+		return
+	}
+	m.stateWrites.writeMu.Lock()
+	defer m.stateWrites.writeMu.Unlock()
+
+	m.stateWrites.mu.Lock()
+	pending := m.stateWrites.pending
+	m.stateWrites.pending = nil
+	m.stateWrites.mu.Unlock()
+
+	for _, write := range pending {
+		if write.forget {
+			if err := m.stateStore.Delete(ctx, write.record.ServiceName); err != nil {
+				m.logger.Warn("Failed to delete container state",
+					slog.String("service", write.record.ServiceName),
+					slog.String("error", err.Error()))
+			}
+			continue
+		}
+		if err := m.stateStore.Put(ctx, write.record); err != nil {
+			m.logger.Warn("Failed to persist container state",
+				slog.String("service", write.record.ServiceName),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+// forgetState queues dropping the persisted state of a removed container,
+// written by flushState like saveState
+func (m *Manager) forgetState(serviceName string) {
+	if m.stateStore == nil {
+		return
+	}
+	m.queueState(stateWrite{record: store.Container{ServiceName: serviceName}, forget: true})
+}
+
+// loadState returns the persisted containers by container ID
+func (m *Manager) loadState(ctx context.Context) map[string]store.Container {
+	states := make(map[string]store.Container)
+	if m.stateStore == nil {
+		return states
+	}
+	containers, err := m.stateStore.List(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to load container state, discovering containers from the runtime",
+			slog.String("error", err.Error()))
+		return states
+	}
+	for _, state := range containers {
+		states[state.ContainerID] = state
+	}
+	return states
+}
+
+// containerFromState rebuilds a discovered container from its persisted
+// state; ok is false when the spec cannot be read
+func (m *Manager) containerFromState(ctx context.Context, state store.Container, status models.ContainerStatus) (*models.Container, bool) {
+	var spec containerSpec
+	if err := json.Unmarshal(state.Spec, &spec); err != nil {
+		m.logger.Warn("Failed to decode container state",
+			slog.String("service", state.ServiceName),
+			slog.String("error", err.Error()))
+		return nil, false
+	}
+
+	if len(spec.Withheld) > 0 {
+		m.restoreWithheld(ctx, state, &spec)
+	}
+
+	if state.DesiredState == store.ContainerStopped {
+		if spec.Checkpoint != nil {
+			m.checkpoints.mu.Lock()
+			m.checkpoints.services[state.ServiceName] = *spec.Checkpoint
+			m.checkpoints.mu.Unlock()
+		} else {
+			m.stopped[state.ServiceName] = true
+		}
+	}
+
+	return &models.Container{
+		ID:          state.ContainerID,
+		Name:        spec.Name,
+		ServiceName: state.ServiceName,
+		Slug:        state.Slug,
+		Image:       spec.Image,
+		Status:      status,
+		Port:        spec.Port,
+		URL:         fmt.Sprintf("%s/mcp/%s", m.config.Traefik.ProxyHost, state.Slug),
+		Host:        m.config.Traefik.ProxyHost,
+		CreatedAt:   state.CreatedAt,
+		UpdatedAt:   time.Now(),
+		Labels:      spec.Labels,
+		Environment: spec.Environment,
+		Command:     spec.Command,
+	}, true
+}
+
+// restoreWithheld reads the credentials left out of a persisted spec back
+// from the container's environment
+func (m *Manager) restoreWithheld(ctx context.Context, state store.Container, spec *containerSpec) {
+	info, err := m.runtime.Inspect(ctx, state.ContainerID)
+	if err != nil {
+		m.logger.Warn("Failed to read withheld environment from the container",
+			slog.String("service", state.ServiceName),
+			slog.String("error", err.Error()))
+		return
+	}
+	env := parseEnvList(info.Config.Env)
+	if spec.Environment == nil {
+		spec.Environment = make(map[string]string, len(spec.Withheld))
+	}
+	for _, key := range spec.Withheld {
+		if value, ok := env[key]; ok {
+			spec.Environment[key] = value
+		}
+	}
+}
+
+// pruneState drops the state of containers that no longer exist in the
+// runtime; seen holds the service names discovery kept
+func (m *Manager) pruneState(states map[string]store.Container, seen map[string]bool) {
+	for _, state := range states {
+		if seen[state.ServiceName] {
+			continue
+		}
+		m.logger.Warn("Container of persisted state is gone, forgetting it",
+			slog.String("service", state.ServiceName),
+			slog.String("container_id", state.ContainerID),
+			slog.String("instance_id", state.InstanceID))
+		m.forgetState(state.ServiceName)
+	}
+}
@@ -0,0 +1,161 @@
+package container
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+// Sources of a variable in the effective environment of an instance
+const (
+	EnvSourceTemplate = "template" // Template default
+	EnvSourceRequest  = "request"  // Instance json_spec.environment
+	EnvSourceSystem   = "system"   // Injected by the manager (MCP_*)
+)
+
+// SpecRecord is the spec a container was submitted with and the origin of
+// every variable of its environment
+type SpecRecord struct {
+	Submitted   map[string]any  `json:"submitted"` // Redacted
+	Environment []EnvAuditEntry `json:"environment"`
+	SubmittedAt time.Time       `json:"submitted_at"`
+}
+
+// EnvAuditEntry describes where a variable of an instance's effective
+// environment came from. Values are never recorded.
+type EnvAuditEntry struct {
+	Name             string `json:"name"`
+	Source           string `json:"source"`
+	Secret           bool   `json:"secret,omitempty"`            // Flagged secret by the template
+	OverridesDefault bool   `json:"overrides_default,omitempty"` // Request value replaced a template default
+}
+
+// IsSystemEnv reports whether a variable is injected into every instance by
+// the manager
+func IsSystemEnv(name string) bool {
+	switch name {
+	case "MCP_INSTANCE_ID", "MCP_SERVICE_NAME", "MCP_CONTAINER_PORT", WorkspaceIDEnv, AuthTokenEnv:
+		return true
+	}
+	return false
+}
+
+// SetTemplateEnvLookup sets how the environment variables of a template are
+// found, so instances created from it inherit the template defaults
+func (m *Manager) SetTemplateEnvLookup(lookup func(templateID string) []models.MCPEnvVar) {
+	m.templateEnv = lookup
+}
+
+// applyTemplateEnvironment merges the defaults of the template, if any, under
+// the request environment and returns it with its audit, which is logged for
+// instances created from a template
+func (m *Manager) applyTemplateEnvironment(instanceID, templateID string, request map[string]string) (map[string]string, []EnvAuditEntry) {
+	var vars []models.MCPEnvVar
+	if templateID != "" && m.templateEnv != nil {
+		vars = m.templateEnv(templateID)
+	}
+	environment, audit, missing := templateEnvironment(vars, request)
+	if len(vars) == 0 {
+		return environment, audit
+	}
+
+	m.logger.Info("Effective instance environment",
+		slog.String("instance_id", instanceID),
+		slog.String("template", templateID),
+		slog.Any("environment", audit))
+	if len(missing) > 0 {
+		m.logger.Warn("Required template environment variables not set",
+			slog.String("instance_id", instanceID),
+			slog.String("template", templateID),
+			slog.Any("missing", missing))
+	}
+	return environment, audit
+}
+
+// templateEnvironment returns the request environment over the template
+// defaults, the audit of every variable, sorted by name, and the required
+// template variables left unset
+func templateEnvironment(vars []models.MCPEnvVar, request map[string]string) (map[string]string, []EnvAuditEntry, []string) {
+	defaults := make(map[string]string)
+	secret := make(map[string]bool)
+	var missing []string
+	for _, v := range vars {
+		secret[v.Name] = v.Secret
+		if v.Default != "" {
+			defaults[v.Name] = v.Default
+		} else if _, set := request[v.Name]; v.Required && !set {
+			missing = append(missing, v.Name)
+		}
+	}
+
+	environment := mergeEnvironment(defaults, request)
+	audit := make([]EnvAuditEntry, 0, len(environment))
+	for name := range environment {
+		_, fromRequest := request[name]
+		_, hasDefault := defaults[name]
+		entry := EnvAuditEntry{Name: name, Source: EnvSourceTemplate, Secret: secret[name]}
+		if fromRequest {
+			entry.Source = EnvSourceRequest
+			entry.OverridesDefault = hasDefault
+		}
+		audit = append(audit, entry)
+	}
+	sort.Slice(audit, func(i, j int) bool { return audit[i].Name < audit[j].Name })
+	return environment, audit, missing
+}
+
+// recordSpec keeps the redacted submitted spec of a container with the audit
+// of its environment, adding the variables injected since as system ones.
+// The caller must hold m.mutex.
+func (m *Manager) recordSpec(serviceName string, submitted any, audit []EnvAuditEntry, environment map[string]string) {
+	scrubbed, err := redact.Value(submitted)
+	if err != nil {
+		m.logger.Warn("Not recording submitted spec",
+			slog.String("service", serviceName),
+			slog.String("error", err.Error()))
+		return
+	}
+	spec, _ := scrubbed.(map[string]any)
+
+	known := make(map[string]bool, len(audit))
+	entries := append([]EnvAuditEntry(nil), audit...)
+	for _, entry := range audit {
+		known[entry.Name] = true
+	}
+	for name := range environment {
+		if !known[name] {
+			entries = append(entries, EnvAuditEntry{Name: name, Source: EnvSourceSystem, Secret: name == AuthTokenEnv})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	if m.specs == nil {
+		m.specs = make(map[string]*SpecRecord)
+	}
+	m.specs[serviceName] = &SpecRecord{Submitted: spec, Environment: entries, SubmittedAt: time.Now()}
+}
+
+// RecordSpec keeps the spec a container was submitted with through the API;
+// request is the environment of the request
+func (m *Manager) RecordSpec(serviceName string, submitted any, request map[string]string) {
+	_, audit, _ := templateEnvironment(nil, request)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	container, ok := m.containers[serviceName]
+	if !ok {
+		return
+	}
+	m.recordSpec(serviceName, submitted, audit, container.Environment)
+}
+
+// SpecRecord returns the spec a container was submitted with, if the manager
+// created it since it started
+func (m *Manager) SpecRecord(serviceName string) (*SpecRecord, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	record, ok := m.specs[serviceName]
+	return record, ok
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 
 	yaml "gopkg.in/yaml.v3"
 
@@ -53,6 +56,7 @@ type TraefikStripPrefix struct {
 
 // TraefikManager manages Traefik configuration
 type TraefikManager struct {
+	mu         sync.Mutex // Serializes read-modify-write of the config file
 	configPath string
 	logger     *slog.Logger
 	config     *config.Config
@@ -73,6 +77,9 @@ func NewTraefikManager(cfg *config.Config, logger *slog.Logger) *TraefikManager
 
 // AddMCPService adds a new MCP service route to Traefik
 func (tm *TraefikManager) AddMCPService(ctx context.Context, slug, containerIP string, containerPort int) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	config, err := tm.loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -92,7 +99,7 @@ func (tm *TraefikManager) AddMCPService(ctx context.Context, slug, containerIP s
 	config.HTTP.Services[serviceNameFull] = TraefikService{
 		LoadBalancer: TraefikLoadBalancer{
 			Servers: []TraefikServer{
-				{URL: fmt.Sprintf("http://%s:%d", containerIP, containerPort)},
+				{URL: "http://" + net.JoinHostPort(containerIP, strconv.Itoa(containerPort))},
 			},
 		},
 	}
@@ -121,6 +128,9 @@ func (tm *TraefikManager) AddMCPService(ctx context.Context, slug, containerIP s
 
 // RemoveMCPService removes an MCP service route from Traefik
 func (tm *TraefikManager) RemoveMCPService(ctx context.Context, slug string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	config, err := tm.loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
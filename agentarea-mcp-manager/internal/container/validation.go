@@ -3,29 +3,90 @@ package container
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/naming"
+	"github.com/agentarea/mcp-manager/internal/schemas"
+)
+
+// Validation issue codes
+const (
+	CodeInvalidSpec           = "invalid_spec"
+	CodeMissingImage          = "missing_image"
+	CodeImageUnavailable      = "image_unavailable"
+	CodeImagePull             = "image_pull_required"
+	CodeContainerLimitReached = "container_limit_reached"
+	CodeWorkspaceLimitReached = "workspace_limit_reached"
+	CodeNearContainerLimit    = "near_container_limit"
+	CodeNearWorkspaceLimit    = "near_workspace_limit"
+	CodeNameConflict          = "name_conflict"
+	CodeInvalidName           = "invalid_name"
+	CodeReservedName          = "reserved_name"
+	CodeNameShortened         = "name_shortened"
 )
 
 // ValidationResult represents the result of container validation
 type ValidationResult struct {
-	Valid         bool     `json:"valid"`
-	Errors        []string `json:"errors,omitempty"`
-	Warnings      []string `json:"warnings,omitempty"`
-	ImageExists   bool     `json:"image_exists"`
-	CanPull       bool     `json:"can_pull"`
-	EstimatedSize string   `json:"estimated_size,omitempty"`
+	Valid          bool              `json:"valid"`
+	Errors         []string          `json:"errors,omitempty"`
+	Warnings       []string          `json:"warnings,omitempty"`
+	Issues         []ValidationIssue `json:"issues,omitempty"`
+	Quota          []QuotaUsage      `json:"quota,omitempty"`
+	IdleCandidates []IdleInstance    `json:"idle_candidates,omitempty"`
+	ImageExists    bool              `json:"image_exists"`
+	CanPull        bool              `json:"can_pull"`
+	EstimatedSize  string            `json:"estimated_size,omitempty"`
+
+	ImageMetadata *models.ImageMetadata `json:"image_metadata,omitempty"` // From the labels and configuration of a local image
+	Defaults      *SpecDefaults         `json:"defaults,omitempty"`       // Taken from the image where the spec sets none
+}
+
+// ValidationIssue is a machine-readable validation error or warning with a
+// hint on how to resolve it
+type ValidationIssue struct {
+	Code     string      `json:"code"`
+	Severity string      `json:"severity"` // "error" or "warning"
+	Message  string      `json:"message"`
+	Hint     string      `json:"hint,omitempty"`
+	Usage    *QuotaUsage `json:"usage,omitempty"`
+}
+
+// addError records a validation error and marks the result invalid
+func (r *ValidationResult) addError(code, message, hint string) {
+	r.Valid = false
+	r.Errors = append(r.Errors, message)
+	r.Issues = append(r.Issues, ValidationIssue{Code: code, Severity: "error", Message: message, Hint: hint})
+}
+
+// addWarning records a validation warning
+func (r *ValidationResult) addWarning(code, message, hint string) {
+	r.Warnings = append(r.Warnings, message)
+	r.Issues = append(r.Issues, ValidationIssue{Code: code, Severity: "warning", Message: message, Hint: hint})
+}
+
+// merge copies the issues of another result into r
+func (r *ValidationResult) merge(other *ValidationResult) {
+	r.Errors = append(r.Errors, other.Errors...)
+	r.Warnings = append(r.Warnings, other.Warnings...)
+	r.Issues = append(r.Issues, other.Issues...)
+	if !other.Valid {
+		r.Valid = false
+	}
 }
 
 // ContainerValidator handles container validation and dry-run checks
 type ContainerValidator struct {
-	logger  *slog.Logger
-	manager *Manager
-	runtime string
+	logger         *slog.Logger
+	manager        *Manager
+	runtime        string
+	commandTimeout time.Duration
+	pullTimeout    time.Duration
 }
 
 // NewContainerValidator creates a new container validator
@@ -33,11 +94,16 @@ func NewContainerValidator(logger *slog.Logger, manager *Manager, runtime string
 	if runtime == "" {
 		runtime = "podman"
 	}
-	return &ContainerValidator{
+	validator := &ContainerValidator{
 		logger:  logger,
 		manager: manager,
 		runtime: runtime,
 	}
+	if manager != nil {
+		validator.commandTimeout = manager.config.Container.CommandTimeout
+		validator.pullTimeout = manager.config.Container.PullTimeout
+	}
+	return validator
 }
 
 // ValidateContainerImage validates that a container image exists and can be used
@@ -55,8 +121,7 @@ func (v *ContainerValidator) ValidateContainerImage(ctx context.Context, imageNa
 	// Check if image exists locally
 	exists, err := v.imageExistsLocally(ctx, imageName)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Failed to check image existence: %v", err))
-		result.Valid = false
+		result.addError(CodeImageUnavailable, fmt.Sprintf("Failed to check image existence: %v", err), "Check that the container runtime is reachable and retry")
 		return result, nil
 	}
 
@@ -69,21 +134,21 @@ func (v *ContainerValidator) ValidateContainerImage(ctx context.Context, imageNa
 		if allowPull {
 			canPull, err := v.canPullImage(ctx, imageName)
 			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Failed to check if image can be pulled: %v", err))
-				result.Valid = false
+				result.addError(CodeImageUnavailable, fmt.Sprintf("Failed to check if image can be pulled: %v", err), "Check registry connectivity and retry")
 				return result, nil
 			}
 			result.CanPull = canPull
 
 			if !canPull {
-				result.Errors = append(result.Errors, fmt.Sprintf("Image %s does not exist locally and cannot be pulled", imageName))
-				result.Valid = false
+				result.addError(CodeImageUnavailable, fmt.Sprintf("Image %s does not exist locally and cannot be pulled", imageName),
+					"Check the image name and tag, and that the registry is public or credentials are configured")
 			} else {
-				result.Warnings = append(result.Warnings, fmt.Sprintf("Image %s will be pulled during container creation", imageName))
+				result.addWarning(CodeImagePull, fmt.Sprintf("Image %s will be pulled during container creation", imageName),
+					"The first start may take longer while the image is downloaded")
 			}
 		} else {
-			result.Errors = append(result.Errors, fmt.Sprintf("Image %s does not exist locally and pulling is disabled", imageName))
-			result.Valid = false
+			result.addError(CodeImageUnavailable, fmt.Sprintf("Image %s does not exist locally and pulling is disabled", imageName),
+				"Pull the image on the host first or enable image pulling")
 		}
 	}
 
@@ -102,16 +167,14 @@ func (v *ContainerValidator) ValidateContainerImage(ctx context.Context, imageNa
 
 // imageExistsLocally checks if an image exists in the local registry
 func (v *ContainerValidator) imageExistsLocally(ctx context.Context, imageName string) (bool, error) {
-	cmd := exec.CommandContext(ctx, v.runtime, "image", "exists", imageName)
-	err := cmd.Run()
+	_, err := commandOutput(ctx, v.commandTimeout, v.runtime, "image", "exists", imageName)
 	return err == nil, nil
 }
 
 // canPullImage checks if an image can be pulled from a registry
 func (v *ContainerValidator) canPullImage(ctx context.Context, imageName string) (bool, error) {
 	// Use runtime search to check if image is available in registries
-	cmd := exec.CommandContext(ctx, v.runtime, "search", "--limit", "1", imageName)
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, v.commandTimeout, v.runtime, "search", "--limit", "1", imageName)
 	if err != nil {
 		return false, nil // If search fails, assume image cannot be pulled
 	}
@@ -134,8 +197,7 @@ func (v *ContainerValidator) canPullImage(ctx context.Context, imageName string)
 
 // getImageSize gets the size of a local image
 func (v *ContainerValidator) getImageSize(ctx context.Context, imageName string) (string, error) {
-	cmd := exec.CommandContext(ctx, v.runtime, "image", "inspect", imageName, "--format", "{{.Size}}")
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, v.commandTimeout, v.runtime, "image", "inspect", imageName, "--format", "{{.Size}}")
 	if err != nil {
 		return "", err
 	}
@@ -150,6 +212,23 @@ func (v *ContainerValidator) formatSize(sizeStr string) string {
 	return sizeStr + " bytes"
 }
 
+// checkName applies the naming policy to an instance name
+func checkName(result *ValidationResult, name string) {
+	if err := naming.Validate(name); err != nil {
+		code := CodeInvalidName
+		if errors.Is(err, naming.ErrReservedName) {
+			code = CodeReservedName
+		}
+		result.addError(code, err.Error(), "Use a name with letters or digits that is not one of the manager's own endpoints")
+		return
+	}
+	if naming.Shortened(name) {
+		result.addWarning(CodeNameShortened,
+			fmt.Sprintf("Name %q is longer than %d characters and will be shortened with a hash suffix", name, naming.MaxLabelLength),
+			"Use a shorter name to keep container names and routes readable")
+	}
+}
+
 // DryRunValidation performs comprehensive dry-run validation
 func (v *ContainerValidator) DryRunValidation(ctx context.Context, instance *models.MCPServerInstance) (*ValidationResult, error) {
 	v.logger.Info("Performing dry-run validation",
@@ -162,25 +241,25 @@ func (v *ContainerValidator) DryRunValidation(ctx context.Context, instance *mod
 		Warnings: []string{},
 	}
 
+	checkName(result, instance.Name)
+
 	// Validate json_spec structure
-	if err := v.validateJSONSpec(instance.JSONSpec); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Invalid JSON spec: %v", err))
-		result.Valid = false
+	for _, err := range schemas.Validate(schemas.JSONSpec, instance.JSONSpec) {
+		result.addError(CodeInvalidSpec, fmt.Sprintf("Invalid JSON spec: %v", err),
+			"See GET /schemas/json-spec/"+schemas.CurrentVersion+" for the accepted format")
 	}
 
 	// Extract image from json_spec
 	image, ok := instance.JSONSpec["image"].(string)
 	if !ok || image == "" {
-		result.Errors = append(result.Errors, "Missing or invalid image in json_spec")
-		result.Valid = false
+		result.addError(CodeMissingImage, "Missing or invalid image in json_spec", "Set json_spec.image to a container image reference")
 		return result, nil
 	}
 
 	// Validate container image
 	imageValidation, err := v.ValidateContainerImage(ctx, image, true)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Image validation failed: %v", err))
-		result.Valid = false
+		result.addError(CodeImageUnavailable, fmt.Sprintf("Image validation failed: %v", err), "")
 		return result, nil
 	}
 
@@ -188,37 +267,29 @@ func (v *ContainerValidator) DryRunValidation(ctx context.Context, instance *mod
 	result.ImageExists = imageValidation.ImageExists
 	result.CanPull = imageValidation.CanPull
 	result.EstimatedSize = imageValidation.EstimatedSize
-	result.Errors = append(result.Errors, imageValidation.Errors...)
-	result.Warnings = append(result.Warnings, imageValidation.Warnings...)
+	result.merge(imageValidation)
 
-	if !imageValidation.Valid {
-		result.Valid = false
-	}
+	// Expand the command and check the program it runs exists in the image
+	vars := v.specCommandVars(instance)
+	command := specCommand(instance.JSONSpec)
+	v.checkCommand(ctx, result, image, command, vars)
+
+	// Check the spec against what the image declares and fill in what it leaves out
+	specPort, _ := instance.JSONSpec["port"].(float64)
+	v.checkImageMetadata(ctx, result, image, int(specPort), command, vars.Env)
 
 	// Check container limits
 	if v.manager != nil {
-		runningCount := v.manager.GetRunningCount()
-		maxContainers := v.manager.config.Container.MaxContainers
-
-		if runningCount >= maxContainers {
-			result.Errors = append(result.Errors, fmt.Sprintf("Container limit reached: %d/%d", runningCount, maxContainers))
-			result.Valid = false
-		} else if runningCount >= maxContainers-1 {
-			result.Warnings = append(result.Warnings, fmt.Sprintf("Close to container limit: %d/%d", runningCount, maxContainers))
-		}
-	}
-
-	// Validate resource requirements
-	if err := v.validateResourceRequirements(instance.JSONSpec); err != nil {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("Resource validation: %v", err))
+		workspaceID, _ := instance.JSONSpec["workspace_id"].(string)
+		checkQuota(result, v.manager.QuotaSnapshot(workspaceID))
 	}
 
 	// Check for naming conflicts
 	if v.manager != nil {
 		containerName := v.manager.config.GetContainerName(instance.Name)
 		if _, exists := v.manager.containers[containerName]; exists {
-			result.Errors = append(result.Errors, fmt.Sprintf("Container with name %s already exists", containerName))
-			result.Valid = false
+			result.addError(CodeNameConflict, fmt.Sprintf("Container with name %s already exists", containerName),
+				"Use a different instance name or delete the existing instance")
 		}
 	}
 
@@ -231,13 +302,14 @@ func (v *ContainerValidator) DryRunValidation(ctx context.Context, instance *mod
 	return result, nil
 }
 
-// DryRunValidationWithLimits performs comprehensive dry-run validation with explicit limits (deadlock-safe)
-func (v *ContainerValidator) DryRunValidationWithLimits(ctx context.Context, instance *models.MCPServerInstance, currentRunningCount int, maxContainers int) (*ValidationResult, error) {
+// DryRunValidationWithLimits performs comprehensive dry-run validation against
+// a quota snapshot taken by the caller (deadlock-safe)
+func (v *ContainerValidator) DryRunValidationWithLimits(ctx context.Context, instance *models.MCPServerInstance, quota QuotaSnapshot) (*ValidationResult, error) {
 	v.logger.Info("Performing dry-run validation with limits",
 		slog.String("instance_id", instance.InstanceID),
 		slog.String("name", instance.Name),
-		slog.Int("current_running", currentRunningCount),
-		slog.Int("max_containers", maxContainers))
+		slog.Int("current_running", quota.Running),
+		slog.Int("max_containers", quota.MaxContainers))
 
 	result := &ValidationResult{
 		Valid:    true,
@@ -245,25 +317,25 @@ func (v *ContainerValidator) DryRunValidationWithLimits(ctx context.Context, ins
 		Warnings: []string{},
 	}
 
+	checkName(result, instance.Name)
+
 	// Validate json_spec structure
-	if err := v.validateJSONSpec(instance.JSONSpec); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Invalid JSON spec: %v", err))
-		result.Valid = false
+	for _, err := range schemas.Validate(schemas.JSONSpec, instance.JSONSpec) {
+		result.addError(CodeInvalidSpec, fmt.Sprintf("Invalid JSON spec: %v", err),
+			"See GET /schemas/json-spec/"+schemas.CurrentVersion+" for the accepted format")
 	}
 
 	// Extract image from json_spec
 	image, ok := instance.JSONSpec["image"].(string)
 	if !ok || image == "" {
-		result.Errors = append(result.Errors, "Missing or invalid image in json_spec")
-		result.Valid = false
+		result.addError(CodeMissingImage, "Missing or invalid image in json_spec", "Set json_spec.image to a container image reference")
 		return result, nil
 	}
 
 	// Validate container image
 	imageValidation, err := v.ValidateContainerImage(ctx, image, true)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Image validation failed: %v", err))
-		result.Valid = false
+		result.addError(CodeImageUnavailable, fmt.Sprintf("Image validation failed: %v", err), "")
 		return result, nil
 	}
 
@@ -271,25 +343,19 @@ func (v *ContainerValidator) DryRunValidationWithLimits(ctx context.Context, ins
 	result.ImageExists = imageValidation.ImageExists
 	result.CanPull = imageValidation.CanPull
 	result.EstimatedSize = imageValidation.EstimatedSize
-	result.Errors = append(result.Errors, imageValidation.Errors...)
-	result.Warnings = append(result.Warnings, imageValidation.Warnings...)
+	result.merge(imageValidation)
 
-	if !imageValidation.Valid {
-		result.Valid = false
-	}
+	// Expand the command and check the program it runs exists in the image
+	vars := v.specCommandVars(instance)
+	command := specCommand(instance.JSONSpec)
+	v.checkCommand(ctx, result, image, command, vars)
 
-	// Check container limits using provided values (no manager callbacks)
-	if currentRunningCount >= maxContainers {
-		result.Errors = append(result.Errors, fmt.Sprintf("Container limit reached: %d/%d", currentRunningCount, maxContainers))
-		result.Valid = false
-	} else if currentRunningCount >= maxContainers-1 {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("Close to container limit: %d/%d", currentRunningCount, maxContainers))
-	}
+	// Check the spec against what the image declares and fill in what it leaves out
+	specPort, _ := instance.JSONSpec["port"].(float64)
+	v.checkImageMetadata(ctx, result, image, int(specPort), command, vars.Env)
 
-	// Validate resource requirements
-	if err := v.validateResourceRequirements(instance.JSONSpec); err != nil {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("Resource validation: %v", err))
-	}
+	// Check container limits using provided values (no manager callbacks)
+	checkQuota(result, quota)
 
 	// Check for naming conflicts (simplified - we'll check this in the manager after acquiring the lock)
 	if v.manager != nil {
@@ -309,80 +375,44 @@ func (v *ContainerValidator) DryRunValidationWithLimits(ctx context.Context, ins
 	return result, nil
 }
 
-// validateJSONSpec validates the structure of json_spec
-func (v *ContainerValidator) validateJSONSpec(jsonSpec map[string]interface{}) error {
-	required := []string{"image", "port"}
-	for _, field := range required {
-		if _, exists := jsonSpec[field]; !exists {
-			return fmt.Errorf("required field %s is missing", field)
-		}
-	}
-
-	// Validate image field
-	if image, ok := jsonSpec["image"].(string); !ok || image == "" {
-		return fmt.Errorf("image field must be a non-empty string")
-	}
-
-	// Validate port field
-	switch port := jsonSpec["port"].(type) {
-	case int:
-		if port < 1 || port > 65535 {
-			return fmt.Errorf("port must be between 1 and 65535")
+// checkQuota adds quota usage, limit errors and shutdown hints to the result
+func checkQuota(result *ValidationResult, quota QuotaSnapshot) {
+	global := QuotaUsage{Scope: "global", Used: quota.Running, Limit: quota.MaxContainers}
+	result.Quota = append(result.Quota, global)
+	limited := checkLimit(result, global, CodeContainerLimitReached, CodeNearContainerLimit, "Container limit")
+
+	if quota.WorkspaceID != "" && quota.MaxPerWorkspace > 0 {
+		workspace := QuotaUsage{Scope: "workspace", WorkspaceID: quota.WorkspaceID, Used: quota.WorkspaceRunning, Limit: quota.MaxPerWorkspace}
+		result.Quota = append(result.Quota, workspace)
+		if checkLimit(result, workspace, CodeWorkspaceLimitReached, CodeNearWorkspaceLimit, "Workspace container limit") {
+			limited = true
 		}
-	case float64:
-		if port < 1 || port > 65535 {
-			return fmt.Errorf("port must be between 1 and 65535")
-		}
-	default:
-		return fmt.Errorf("port field must be a number")
 	}
 
-	// Validate environment variables if present
-	if env, exists := jsonSpec["environment"]; exists {
-		if _, ok := env.(map[string]interface{}); !ok {
-			return fmt.Errorf("environment field must be an object")
-		}
+	if limited {
+		result.IdleCandidates = quota.IdleCandidates
 	}
-
-	// Validate command if present
-	if cmd, exists := jsonSpec["cmd"]; exists {
-		if _, ok := cmd.([]interface{}); !ok {
-			return fmt.Errorf("cmd field must be an array")
-		}
-	}
-
-	return nil
 }
 
-// validateResourceRequirements validates resource requirements
-func (v *ContainerValidator) validateResourceRequirements(jsonSpec map[string]interface{}) error {
-	resources, exists := jsonSpec["resources"]
-	if !exists {
-		return nil // Resources are optional
-	}
-
-	resourceMap, ok := resources.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("resources must be an object")
-	}
-
-	// Validate memory limit
-	if memLimit, exists := resourceMap["memory_limit"]; exists {
-		if _, ok := memLimit.(string); !ok {
-			return fmt.Errorf("memory_limit must be a string")
-		}
-		// Could add more validation for memory format (e.g., "256m", "1g")
-	}
-
-	// Validate CPU limit
-	if cpuLimit, exists := resourceMap["cpu_limit"]; exists {
-		if _, ok := cpuLimit.(string); !ok {
-			return fmt.Errorf("cpu_limit must be a string")
-		}
-		// Could add more validation for CPU format (e.g., "0.5", "1.0")
+// checkLimit records an error at the limit or a warning one below it, and
+// reports whether the limit was reached
+func checkLimit(result *ValidationResult, usage QuotaUsage, limitCode, nearCode, label string) bool {
+	hint := "Stop or delete an unused instance (see idle_candidates) or ask an administrator to raise the limit"
+	var issue *ValidationIssue
+
+	switch {
+	case usage.Used >= usage.Limit:
+		result.addError(limitCode, fmt.Sprintf("%s reached: %d/%d", label, usage.Used, usage.Limit), hint)
+		issue = &result.Issues[len(result.Issues)-1]
+	case usage.Used >= usage.Limit-1:
+		result.addWarning(nearCode, fmt.Sprintf("Close to %s: %d/%d", strings.ToLower(label[:1])+label[1:], usage.Used, usage.Limit), hint)
+		issue = &result.Issues[len(result.Issues)-1]
+	default:
+		return false
 	}
 
-	return nil
+	issue.Usage = &usage
+	return issue.Severity == "error"
 }
 
 // PullImageWithProgress pulls an image with progress tracking
@@ -390,6 +420,8 @@ func (v *ContainerValidator) PullImageWithProgress(ctx context.Context, imageNam
 	v.logger.Info("Pulling image with progress tracking",
 		slog.String("image", imageName))
 
+	ctx, cancel := boundedContext(ctx, v.pullTimeout)
+	defer cancel()
 	cmd := exec.CommandContext(ctx, v.runtime, "pull", imageName)
 
 	// Create a pipe to capture output
@@ -428,8 +460,7 @@ func (v *ContainerValidator) PullImageWithProgress(ctx context.Context, imageNam
 
 // GetContainerStatus gets detailed container status
 func (v *ContainerValidator) GetContainerStatus(ctx context.Context, containerID string) (*models.DetailedContainerStatus, error) {
-	cmd := exec.CommandContext(ctx, v.runtime, "inspect", containerID)
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, v.commandTimeout, v.runtime, "inspect", containerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
@@ -0,0 +1,432 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// WarmPoolLabel marks pre-started containers with the template they were started for
+const WarmPoolLabel = "agentarea.io/warm-pool"
+
+const (
+	// warmPoolInterval is how often pools are refilled and checked for dead containers
+	warmPoolInterval = 15 * time.Second
+	// warmPoolNameInfix separates pool containers from instance containers;
+	// sanitized service names never contain a dot
+	warmPoolNameInfix = "pool."
+	// warmEnvDir holds the environment written when a container is claimed
+	warmEnvDir = "/tmp/agentarea"
+)
+
+// warmWaitScript blocks until the environment file appears, loads it and
+// runs the original entrypoint and command passed as arguments
+const warmWaitScript = `while [ ! -f ` + warmEnvDir + `/env ]; do sleep 0.1; done; set -a; . ` + warmEnvDir + `/env; set +a; exec "$@"`
+
+// envKeyPattern matches environment variable names that are safe in the env file
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WarmPoolSpec asks for Size started but unrouted containers of a template's image
+type WarmPoolSpec struct {
+	Template string
+	Image    string
+	Size     int
+}
+
+// WarmPoolStatus reports the state of one pool
+type WarmPoolStatus struct {
+	Template  string `json:"template"`
+	Image     string `json:"image"`
+	Size      int    `json:"size"`
+	Ready     int    `json:"ready"`
+	Claimed   int64  `json:"claimed"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// warmContainer is a pre-started container waiting for its environment
+type warmContainer struct {
+	ID       string
+	Name     string
+	IP       string
+	Template string
+	Image    string
+}
+
+// warmPools holds the ready containers of every pool
+type warmPools struct {
+	mu      sync.Mutex
+	source  func() []WarmPoolSpec
+	ready   map[string][]*warmContainer // By template
+	claimed map[string]int64
+	errors  map[string]string
+	cleaned bool // Leftovers from a previous run were removed
+}
+
+func newWarmPools() *warmPools {
+	return &warmPools{
+		ready:   make(map[string][]*warmContainer),
+		claimed: make(map[string]int64),
+		errors:  make(map[string]string),
+	}
+}
+
+// SetWarmPoolSource sets how the desired pools are found. It is called on
+// every refill so pool sizes follow template reloads.
+func (m *Manager) SetWarmPoolSource(source func() []WarmPoolSpec) {
+	m.warmPools.mu.Lock()
+	m.warmPools.source = source
+	m.warmPools.mu.Unlock()
+}
+
+// WarmPoolStatus returns the state of every configured pool
+func (m *Manager) WarmPoolStatus() []WarmPoolStatus {
+	m.warmPools.mu.Lock()
+	defer m.warmPools.mu.Unlock()
+
+	if m.warmPools.source == nil {
+		return nil
+	}
+	specs := m.warmPools.source()
+	statuses := make([]WarmPoolStatus, 0, len(specs))
+	for _, spec := range specs {
+		statuses = append(statuses, WarmPoolStatus{
+			Template:  spec.Template,
+			Image:     spec.Image,
+			Size:      spec.Size,
+			Ready:     len(m.warmPools.ready[spec.Template]),
+			Claimed:   m.warmPools.claimed[spec.Template],
+			LastError: m.warmPools.errors[spec.Template],
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Template < statuses[j].Template })
+	return statuses
+}
+
+// startWarmPools keeps pools filled until the manager shuts down
+func (m *Manager) startWarmPools() {
+	ticker := time.NewTicker(warmPoolInterval)
+	defer ticker.Stop()
+
+	for {
+		if !m.BackgroundPaused() {
+			m.refillWarmPools(m.healthCtx)
+		}
+		select {
+		case <-m.healthCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refillWarmPools drops dead and unwanted pool containers and starts new
+// ones until every pool has its size
+func (m *Manager) refillWarmPools(ctx context.Context) {
+	m.warmPools.mu.Lock()
+	source := m.warmPools.source
+	cleaned := m.warmPools.cleaned
+	m.warmPools.mu.Unlock()
+	if source == nil {
+		return
+	}
+
+	if !cleaned {
+		// Containers from a previous run are not tracked; replace them
+		for _, name := range m.listWarmContainers(ctx, false) {
+			m.removeWarmContainer(name)
+		}
+		m.warmPools.mu.Lock()
+		m.warmPools.cleaned = true
+		m.warmPools.mu.Unlock()
+	}
+
+	wanted := make(map[string]WarmPoolSpec)
+	for _, spec := range source() {
+		if spec.Size > 0 && spec.Image != "" {
+			wanted[spec.Template] = spec
+		}
+	}
+	running := make(map[string]bool)
+	for _, name := range m.listWarmContainers(ctx, true) {
+		running[name] = true
+	}
+
+	// Drop containers that died or belong to a removed or changed pool
+	var stale []*warmContainer
+	m.warmPools.mu.Lock()
+	for template, pool := range m.warmPools.ready {
+		spec, ok := wanted[template]
+		keep := pool[:0]
+		for _, warm := range pool {
+			if ok && warm.Image == spec.Image && running[warm.Name] {
+				keep = append(keep, warm)
+			} else {
+				stale = append(stale, warm)
+			}
+		}
+		if len(keep) == 0 {
+			delete(m.warmPools.ready, template)
+		} else {
+			m.warmPools.ready[template] = keep
+		}
+	}
+	missing := make(map[string]int)
+	for template, spec := range wanted {
+		if n := spec.Size - len(m.warmPools.ready[template]); n > 0 {
+			missing[template] = n
+		}
+	}
+	m.warmPools.mu.Unlock()
+
+	for _, warm := range stale {
+		m.removeWarmContainer(warm.Name)
+	}
+
+	for template, n := range missing {
+		spec := wanted[template]
+		for i := 0; i < n && ctx.Err() == nil; i++ {
+			warm, err := m.startWarmContainer(ctx, spec)
+			m.warmPools.mu.Lock()
+			if err != nil {
+				m.warmPools.errors[template] = err.Error()
+				m.warmPools.mu.Unlock()
+				m.logger.Warn("Failed to start warm pool container",
+					slog.String("template", template),
+					slog.String("error", err.Error()))
+				break
+			}
+			delete(m.warmPools.errors, template)
+			m.warmPools.ready[template] = append(m.warmPools.ready[template], warm)
+			m.warmPools.mu.Unlock()
+		}
+	}
+}
+
+// startWarmContainer pulls the image and starts a container that waits for
+// its environment before running the image's entrypoint
+func (m *Manager) startWarmContainer(ctx context.Context, spec WarmPoolSpec) (*warmContainer, error) {
+	command, err := m.imageCommand(ctx, spec.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s%s%s.%s", m.config.Container.NamePrefix, warmPoolNameInfix, sanitizeNamePart(spec.Template), hex.EncodeToString(suffix))
+
+	create := &createSpec{
+		Name:       name,
+		Image:      spec.Image,
+		Entrypoint: []string{"/bin/sh"},
+		Cmd:        append([]string{"-c", warmWaitScript, "warm"}, command...),
+		Labels:     map[string]string{WarmPoolLabel: spec.Template},
+		HostConfig: createHostConfig{NetworkMode: m.config.Traefik.Network},
+	}
+	if err := m.applyResourceLimits(create, m.config.Container.DefaultDiskQuota); err != nil {
+		return nil, err
+	}
+
+	id, err := m.runtime.Create(ctx, create)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start warm container: %w", err)
+	}
+	warm := &warmContainer{
+		ID:       id,
+		Name:     name,
+		Template: spec.Template,
+		Image:    spec.Image,
+	}
+
+	if err := m.waitForContainer(ctx, warm.ID); err != nil {
+		m.removeWarmContainer(name)
+		return nil, fmt.Errorf("warm container failed to start: %w", err)
+	}
+	if warm.IP, err = m.getContainerIP(ctx, warm.ID); err != nil {
+		m.removeWarmContainer(name)
+		return nil, fmt.Errorf("failed to get warm container IP: %w", err)
+	}
+
+	m.logger.Info("Started warm pool container",
+		slog.String("template", spec.Template),
+		slog.String("container", name))
+
+	return warm, nil
+}
+
+// imageCommand returns the entrypoint followed by the command of an image,
+// pulling the image if needed
+func (m *Manager) imageCommand(ctx context.Context, image string) ([]string, error) {
+	inspect := func() ([]byte, error) {
+		return m.runtimeOutput(ctx, "image", "inspect", "--format", "{{json .Config}}", image)
+	}
+	output, err := inspect()
+	if err != nil {
+		if pullOutput, pullErr := commandCombinedOutput(ctx, m.config.Container.PullTimeout, m.config.Container.Runtime, "pull", "--quiet", image); pullErr != nil {
+			return nil, fmt.Errorf("failed to pull %s: %w, output: %s", image, pullErr, strings.TrimSpace(string(pullOutput)))
+		}
+		if output, err = inspect(); err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", image, err)
+		}
+	}
+
+	var config struct {
+		Entrypoint []string
+		Cmd        []string
+	}
+	if err := json.Unmarshal(output, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse image config of %s: %w", image, err)
+	}
+	command := append(config.Entrypoint, config.Cmd...)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("image %s has no entrypoint or command", image)
+	}
+	return command, nil
+}
+
+// listWarmContainers returns the names of pool containers; only running ones
+// if running is true. Claimed containers are renamed and not listed.
+func (m *Manager) listWarmContainers(ctx context.Context, running bool) []string {
+	args := []string{"ps", "--filter", "label=" + WarmPoolLabel, "--format", "{{.Names}}"}
+	if !running {
+		args = append(args, "-a")
+	}
+	output, err := m.runtimeOutput(ctx, args...)
+	if err != nil {
+		return nil
+	}
+
+	prefix := m.config.Container.NamePrefix + warmPoolNameInfix
+	var names []string
+	for _, name := range strings.Fields(string(output)) {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// removeWarmContainer force-removes a pool container
+func (m *Manager) removeWarmContainer(name string) {
+	// Cleanup also runs when the caller's context is done
+	if err := m.runtime.Remove(context.Background(), name, true); err != nil {
+		m.logger.Warn("Failed to remove warm pool container",
+			slog.String("container", name),
+			slog.String("error", err.Error()))
+	}
+}
+
+// isWarmPoolName reports whether a container name belongs to an unclaimed pool container
+func (m *Manager) isWarmPoolName(name string) bool {
+	return strings.HasPrefix(name, m.config.Container.NamePrefix+warmPoolNameInfix)
+}
+
+// takeWarmContainer removes a ready container of the template from its pool
+func (m *Manager) takeWarmContainer(template, image string) *warmContainer {
+	m.warmPools.mu.Lock()
+	defer m.warmPools.mu.Unlock()
+
+	pool := m.warmPools.ready[template]
+	for i, warm := range pool {
+		if warm.Image == image {
+			m.warmPools.ready[template] = append(pool[:i:i], pool[i+1:]...)
+			m.warmPools.claimed[template]++
+			return warm
+		}
+	}
+	return nil
+}
+
+// claimWarmContainer hands a pool container to a new instance: the
+// environment is written into the container, which starts the MCP server,
+// and the container is renamed to the instance container name. It returns
+// false if no pool container fits, in which case the caller creates one.
+func (m *Manager) claimWarmContainer(ctx context.Context, container *models.Container) (*warmContainer, bool) {
+	template := container.Labels[TemplateLabel]
+	// Warm containers run with the default disk quota, not a per-instance
+	// one, and publish no host port or scratch volume
+	_, published := HostPortFromLabels(container.Labels)
+	if template == "" || len(container.Command) > 0 || container.Labels[DiskQuotaLabel] != "" || published ||
+		ScratchFromLabels(container.Labels) != nil {
+		return nil, false
+	}
+	warm := m.takeWarmContainer(template, container.Image)
+	if warm == nil {
+		return nil, false
+	}
+
+	fail := func(err error) (*warmContainer, bool) {
+		m.logger.Warn("Failed to claim warm pool container, creating a new one",
+			slog.String("template", template),
+			slog.String("container", warm.Name),
+			slog.String("error", err.Error()))
+		m.removeWarmContainer(warm.Name)
+		return nil, false
+	}
+
+	envFile, err := renderEnvFile(container.Environment)
+	if err != nil {
+		return fail(err)
+	}
+	// Written to a temporary file and renamed so the wait loop never reads a partial file
+	writeCtx, cancel := boundedContext(ctx, m.config.Container.CommandTimeout)
+	defer cancel()
+	write := exec.CommandContext(writeCtx, m.config.Container.Runtime, "exec", "-i", warm.ID, "/bin/sh", "-c",
+		"mkdir -p "+warmEnvDir+" && cat > "+warmEnvDir+"/env.tmp && mv "+warmEnvDir+"/env.tmp "+warmEnvDir+"/env")
+	write.Stdin = bytes.NewReader(envFile)
+	if output, err := write.CombinedOutput(); err != nil {
+		return fail(fmt.Errorf("failed to write environment: %w, output: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	if output, err := m.runtimeCommand(ctx, "rename", warm.ID, container.Name); err != nil {
+		return fail(fmt.Errorf("failed to rename: %w, output: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	m.logger.Info("Claimed warm pool container",
+		slog.String("template", template),
+		slog.String("container", container.Name),
+		slog.String("id", warm.ID))
+
+	return warm, true
+}
+
+// renderEnvFile renders the environment as shell assignments
+func renderEnvFile(env map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		if !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("environment variable name %q cannot be injected", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		buf.WriteString(key)
+		buf.WriteString("='")
+		buf.WriteString(strings.ReplaceAll(env[key], "'", `'\''`))
+		buf.WriteString("'\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// sanitizeNamePart makes a template ID usable in a container name
+func sanitizeNamePart(value string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
@@ -0,0 +1,363 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// WarmupLabel records the warm-up requests of an instance as JSON
+const WarmupLabel = "agentarea.io/warmup"
+
+const (
+	// maxWarmupRequests bounds the warm-up requests of an instance
+	maxWarmupRequests = 10
+	// maxWarmupResponse bounds how much of a warm-up response is read
+	maxWarmupResponse = 1 << 20
+	// defaultMCPPath is the MCP endpoint tool warm-ups call by default
+	defaultMCPPath = "/mcp"
+	// warmupProtocolVersion is the MCP protocol version warm-up sessions request
+	warmupProtocolVersion = "2025-03-26"
+	// warmupCloseTimeout bounds closing a warm-up session
+	warmupCloseTimeout = 5 * time.Second
+)
+
+// WarmupRequest is a call made to a new instance once it is ready and before
+// its route is published. With Tool set it calls that MCP tool over
+// streamable HTTP at Path, initializing a session first; otherwise it is a
+// plain HTTP request. Header values and the body may reference ${env.NAME}
+// and ${instance.FIELD}.
+type WarmupRequest struct {
+	Method    string                 `json:"method,omitempty"` // GET, or POST with a body
+	Path      string                 `json:"path,omitempty"`   // "/", or /mcp for a tool
+	Headers   map[string]string      `json:"headers,omitempty"`
+	Body      json.RawMessage        `json:"body,omitempty"`
+	Tool      string                 `json:"tool,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// withDefaults fills in the method and path
+func (r WarmupRequest) withDefaults() WarmupRequest {
+	if r.Tool != "" {
+		r.Method = http.MethodPost
+		if r.Path == "" {
+			r.Path = defaultMCPPath
+		}
+		return r
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+		if len(r.Body) > 0 {
+			r.Method = http.MethodPost
+		}
+	}
+	r.Method = strings.ToUpper(r.Method)
+	if r.Path == "" {
+		r.Path = "/"
+	}
+	return r
+}
+
+// String names the request in logs and warnings
+func (r WarmupRequest) String() string {
+	r = r.withDefaults()
+	if r.Tool != "" {
+		return fmt.Sprintf("tool %s at %s", r.Tool, r.Path)
+	}
+	return r.Method + " " + r.Path
+}
+
+// validate checks one warm-up request
+func (r WarmupRequest) validate() error {
+	if r.Tool != "" && (len(r.Body) > 0 || (r.Method != "" && !strings.EqualFold(r.Method, http.MethodPost))) {
+		return fmt.Errorf("a tool call sets arguments, not a method or body")
+	}
+	if r.Tool == "" && len(r.Arguments) > 0 {
+		return fmt.Errorf("arguments need a tool")
+	}
+	r = r.withDefaults()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut:
+	default:
+		return fmt.Errorf("unsupported method %q: use GET, HEAD, POST or PUT", r.Method)
+	}
+	if !strings.HasPrefix(r.Path, "/") || strings.ContainsAny(r.Path, " \t\r\n#") {
+		return fmt.Errorf("path %q must be an absolute URL path", r.Path)
+	}
+	for name := range r.Headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if len(r.Body) > 0 && !json.Valid(r.Body) {
+		return fmt.Errorf("body is not valid JSON")
+	}
+	return nil
+}
+
+// SetWarmup records the warm-up requests of an instance in labels, replacing
+// any label of that name supplied with the spec
+func SetWarmup(labels map[string]string, requests []WarmupRequest) (map[string]string, error) {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, WarmupLabel)
+	if len(requests) == 0 {
+		return labels, nil
+	}
+	if len(requests) > maxWarmupRequests {
+		return labels, fmt.Errorf("invalid warmup: at most %d requests", maxWarmupRequests)
+	}
+	for i, request := range requests {
+		if err := request.validate(); err != nil {
+			return labels, fmt.Errorf("invalid warmup request %d: %w", i, err)
+		}
+	}
+	encoded, err := json.Marshal(requests)
+	if err != nil {
+		return labels, fmt.Errorf("invalid warmup: %w", err)
+	}
+	labels[WarmupLabel] = string(encoded)
+	return labels, nil
+}
+
+// WarmupFromLabels reads the warm-up requests recorded in labels
+func WarmupFromLabels(labels map[string]string) []WarmupRequest {
+	var requests []WarmupRequest
+	if value := labels[WarmupLabel]; value != "" {
+		if err := json.Unmarshal([]byte(value), &requests); err != nil {
+			return nil
+		}
+	}
+	return requests
+}
+
+// ParseWarmup reads json_spec.warmup:
+//
+//	"warmup": [{"path": "/index/load"},
+//	           {"tool": "search", "arguments": {"query": "warm-up"}}]
+func ParseWarmup(jsonSpec map[string]interface{}) ([]WarmupRequest, error) {
+	section, ok := jsonSpec["warmup"]
+	if !ok {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(section)
+	if err != nil {
+		return nil, fmt.Errorf("invalid warmup: %w", err)
+	}
+	var requests []WarmupRequest
+	if err := json.Unmarshal(encoded, &requests); err != nil {
+		return nil, fmt.Errorf("invalid warmup: expected a list of requests: %w", err)
+	}
+	return requests, nil
+}
+
+// warmUp makes the warm-up requests of a container in order, directly to
+// its address, stopping at the first that fails
+func (m *Manager) warmUp(ctx context.Context, container *models.Container, containerIP string) error {
+	requests := WarmupFromLabels(container.Labels)
+	if len(requests) == 0 {
+		return nil
+	}
+	vars := NewCommandVars(container.Environment["MCP_INSTANCE_ID"], container.ServiceName, container.Port,
+		container.Environment[WorkspaceIDEnv], container.Environment)
+	// Warm-up calls may take far longer than a health check; ctx bounds them
+	client := m.healthChecker.client.unbounded()
+	base := m.healthChecker.client.URL(containerIP, container.Port, "")
+
+	for i, request := range requests {
+		request = request.withDefaults()
+		headers, body, err := expandWarmup(request, vars)
+		if err == nil {
+			m.healthChecker.client.addHeaders(headers, container.Environment)
+			if request.Tool != "" {
+				err = callWarmupTool(ctx, client, base+request.Path, headers, request.Tool, request.Arguments)
+			} else {
+				err = doWarmupRequest(ctx, client, request.Method, base+request.Path, headers, body)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("warm-up request %d (%s): %w", i, request, err)
+		}
+		m.logger.Debug("Warm-up request completed",
+			slog.String("service", container.ServiceName),
+			slog.String("request", request.String()))
+	}
+	return nil
+}
+
+// expandWarmup substitutes references in the header values and body of a
+// warm-up request
+func expandWarmup(request WarmupRequest, vars CommandVars) (http.Header, []byte, error) {
+	headers := make(http.Header, len(request.Headers))
+	for name, value := range request.Headers {
+		expanded, err := ExpandCommand([]string{value}, vars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		headers.Set(name, expanded[0])
+	}
+	var body []byte
+	if len(request.Body) > 0 {
+		expanded, err := ExpandCommand([]string{string(request.Body)}, vars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("body: %w", err)
+		}
+		body = []byte(expanded[0])
+		if headers.Get("Content-Type") == "" {
+			headers.Set("Content-Type", "application/json")
+		}
+	}
+	return headers, body, nil
+}
+
+// doWarmupRequest makes a plain warm-up request, which must not answer an
+// error status
+func doWarmupRequest(ctx context.Context, client *http.Client, method, url string, headers http.Header, body []byte) error {
+	resp, _, err := sendWarmup(ctx, client, method, url, headers, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// callWarmupTool initializes an MCP session at url, calls a tool and closes
+// the session
+func callWarmupTool(ctx context.Context, client *http.Client, url string, headers http.Header, tool string, arguments map[string]interface{}) error {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	headers = headers.Clone()
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Accept", "application/json, text/event-stream")
+
+	initialize := map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]interface{}{
+			"protocolVersion": warmupProtocolVersion,
+			"capabilities":    map[string]interface{}{},
+			"clientInfo":      map[string]interface{}{"name": "mcp-manager-warmup", "version": "1.0"},
+		},
+	}
+	resp, err := rpcWarmup(ctx, client, url, headers, initialize)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if session := resp.Header.Get("Mcp-Session-Id"); session != "" {
+		headers.Set("Mcp-Session-Id", session)
+		defer func() {
+			// Close the session, also after the warm-up timed out
+			closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), warmupCloseTimeout)
+			defer cancel()
+			_, _, _ = sendWarmup(closeCtx, client, http.MethodDelete, url, headers, nil)
+		}()
+	}
+
+	initialized := map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/initialized"}
+	if _, err := rpcWarmup(ctx, client, url, headers, initialized); err != nil {
+		return fmt.Errorf("initialized notification: %w", err)
+	}
+
+	call := map[string]interface{}{
+		"jsonrpc": "2.0", "id": 2, "method": "tools/call",
+		"params": map[string]interface{}{"name": tool, "arguments": arguments},
+	}
+	if _, err := rpcWarmup(ctx, client, url, headers, call); err != nil {
+		return fmt.Errorf("tools/call: %w", err)
+	}
+	return nil
+}
+
+// rpcWarmup posts a JSON-RPC message and fails on an error status, a
+// JSON-RPC error or a tool result flagged as an error
+func rpcWarmup(ctx context.Context, client *http.Client, url string, headers http.Header, message map[string]interface{}) (*http.Response, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	resp, data, err := sendWarmup(ctx, client, http.MethodPost, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("answered %d", resp.StatusCode)
+	}
+	if err := rpcResponseError(resp.Header.Get("Content-Type"), data); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sendWarmup makes a request and reads its response body
+func sendWarmup(ctx context.Context, client *http.Client, method, url string, headers http.Header, body []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = headers.Clone()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWarmupResponse))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// rpcResponseError returns the error of a JSON-RPC response, sent as JSON or
+// as server-sent events
+func rpcResponseError(contentType string, data []byte) error {
+	var messages [][]byte
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), maxWarmupResponse)
+		for scanner.Scan() {
+			if payload, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+				messages = append(messages, []byte(strings.TrimSpace(payload)))
+			}
+		}
+	} else if len(bytes.TrimSpace(data)) > 0 {
+		messages = append(messages, data)
+	}
+
+	for _, message := range messages {
+		var response struct {
+			Error *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+			Result *struct {
+				IsError bool `json:"isError"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(message, &response); err != nil {
+			continue
+		}
+		if response.Error != nil {
+			return fmt.Errorf("JSON-RPC error %d: %s", response.Error.Code, response.Error.Message)
+		}
+		if response.Result != nil && response.Result.IsError {
+			return fmt.Errorf("the tool returned an error")
+		}
+	}
+	return nil
+}
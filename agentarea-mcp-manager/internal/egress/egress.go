@@ -0,0 +1,363 @@
+// Package egress filters the DNS queries of instances with an egress
+// allow-list. Those instances get the manager's resolver as their only
+// nameserver; it forwards queries for allowed domains upstream, answers
+// NXDOMAIN for everything else and keeps the recent queries of every
+// instance. Filtering is by name only: it does not stop an instance from
+// connecting to an IP address directly, which needs a network policy.
+package egress
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// maxPatterns bounds the allow-list of one instance
+const maxPatterns = 100
+
+// clientTTL is how long the instance behind a source address is cached
+const clientTTL = 30 * time.Second
+
+// upstreamTimeout bounds a forwarded query
+const upstreamTimeout = 5 * time.Second
+
+// patternPattern matches a domain name, optionally prefixed with "*."
+var patternPattern = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// ValidatePatterns checks an allow-list. "example.com" allows that name
+// only, "*.example.com" allows its subdomains.
+func ValidatePatterns(patterns []string) error {
+	if len(patterns) > maxPatterns {
+		return fmt.Errorf("at most %d egress_allow entries are supported", maxPatterns)
+	}
+	for _, pattern := range patterns {
+		if len(pattern) > 253 || !patternPattern.MatchString(pattern) {
+			return fmt.Errorf("invalid egress_allow entry %q: expected a domain such as api.github.com or *.googleapis.com", pattern)
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether the queried name matches one of the patterns
+func Allowed(patterns []string, name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, pattern := range patterns {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(name, "."+suffix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Client is a filtered instance
+type Client struct {
+	Service string   // Service name of the instance
+	Allow   []string // Allow-list patterns
+}
+
+// LookupFunc finds the filtered instance with the given IP; ok is false
+// when no filtered instance has it
+type LookupFunc func(ctx context.Context, ip string) (client Client, ok bool)
+
+// Entry is a logged query
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Allowed bool      `json:"allowed"`
+	RCode   string    `json:"rcode"` // Response code returned to the instance
+}
+
+type cachedClient struct {
+	client  Client
+	found   bool
+	expires time.Time
+}
+
+// Filter is the filtering resolver
+type Filter struct {
+	address  string
+	listen   string
+	upstream string
+	logSize  int
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	lookup  LookupFunc
+	clients map[string]cachedClient // By source IP
+	logs    map[string][]Entry      // By service, oldest first
+}
+
+// NewFilter creates a resolver from the configuration. Without an upstream
+// it forwards to the first nameserver of /etc/resolv.conf.
+func NewFilter(cfg config.EgressConfig, logger *slog.Logger) (*Filter, error) {
+	if net.ParseIP(cfg.Address) == nil {
+		return nil, fmt.Errorf("EGRESS_DNS_ADDRESS must be the resolver IP reachable by instances, got %q", cfg.Address)
+	}
+	upstream := cfg.Upstream
+	if upstream == "" {
+		var err error
+		if upstream, err = systemNameserver("/etc/resolv.conf"); err != nil {
+			return nil, err
+		}
+	}
+	if _, _, err := net.SplitHostPort(upstream); err != nil {
+		upstream = net.JoinHostPort(upstream, "53")
+	}
+	logSize := cfg.LogSize
+	if logSize <= 0 {
+		logSize = 200
+	}
+	return &Filter{
+		address:  cfg.Address,
+		listen:   cfg.Listen,
+		upstream: upstream,
+		logSize:  logSize,
+		logger:   logger,
+		clients:  make(map[string]cachedClient),
+		logs:     make(map[string][]Entry),
+	}, nil
+}
+
+// systemNameserver returns the first nameserver of a resolv.conf
+func systemNameserver(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("no EGRESS_DNS_UPSTREAM and %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no EGRESS_DNS_UPSTREAM and no nameserver in %s", path)
+}
+
+// Address returns the resolver IP given to instances
+func (f *Filter) Address() string {
+	return f.address
+}
+
+// SetLookup sets how source addresses are mapped to instances
+func (f *Filter) SetLookup(lookup LookupFunc) {
+	f.mu.Lock()
+	f.lookup = lookup
+	f.mu.Unlock()
+}
+
+// Log returns the recent queries of an instance, oldest first
+func (f *Filter) Log(service string) []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Entry{}, f.logs[service]...)
+}
+
+// Forget drops the log and cached addresses of a deleted instance
+func (f *Filter) Forget(service string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.logs, service)
+	for ip, cached := range f.clients {
+		if cached.client.Service == service {
+			delete(f.clients, ip)
+		}
+	}
+}
+
+// ListenAndServe answers queries on the configured UDP address until ctx is
+// done
+func (f *Filter) ListenAndServe(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", f.listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for DNS queries: %w", err)
+	}
+	f.logger.Info("Egress DNS filter listening",
+		slog.String("listen", conn.LocalAddr().String()),
+		slog.String("upstream", f.upstream))
+	return f.Serve(ctx, conn)
+}
+
+// Serve answers the queries received on conn until ctx is done
+func (f *Filter) Serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
+		}
+		query := append([]byte{}, buf[:n]...)
+		go func() {
+			ip := addr.String()
+			if udp, ok := addr.(*net.UDPAddr); ok {
+				ip = udp.IP.String()
+			}
+			if response := f.resolve(ctx, ip, query); response != nil {
+				_, _ = conn.WriteTo(response, addr)
+			}
+		}()
+	}
+}
+
+// resolve answers one query from ip; nil drops it
+func (f *Filter) resolve(ctx context.Context, ip string, query []byte) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil || header.Response {
+		return nil
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return reply(header, nil, dnsmessage.RCodeFormatError)
+	}
+
+	client, ok := f.client(ctx, ip)
+	if !ok {
+		return reply(header, &question, dnsmessage.RCodeRefused)
+	}
+
+	entry := Entry{
+		Time:    time.Now(),
+		Name:    strings.TrimSuffix(question.Name.String(), "."),
+		Type:    strings.TrimPrefix(question.Type.String(), "Type"),
+		Allowed: Allowed(client.Allow, question.Name.String()),
+	}
+	response := reply(header, &question, dnsmessage.RCodeNameError)
+	if entry.Allowed {
+		if response, err = f.forward(ctx, query); err != nil {
+			f.logger.Warn("Egress DNS upstream query failed",
+				slog.String("upstream", f.upstream),
+				slog.String("error", err.Error()))
+			response = reply(header, &question, dnsmessage.RCodeServerFailure)
+		}
+	} else {
+		f.logger.Debug("Egress DNS query denied",
+			slog.String("service", client.Service),
+			slog.String("name", entry.Name))
+	}
+	entry.RCode = rcode(response)
+	f.record(client.Service, entry)
+	return response
+}
+
+// client returns the instance behind ip, asking the lookup on a cache miss
+func (f *Filter) client(ctx context.Context, ip string) (Client, bool) {
+	f.mu.Lock()
+	cached, ok := f.clients[ip]
+	lookup := f.lookup
+	f.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.client, cached.found
+	}
+	if lookup == nil {
+		return Client{}, false
+	}
+
+	client, found := lookup(ctx, ip)
+	f.mu.Lock()
+	f.clients[ip] = cachedClient{client: client, found: found, expires: time.Now().Add(clientTTL)}
+	f.mu.Unlock()
+	return client, found
+}
+
+// forward sends the query upstream and returns the response
+func (f *Filter) forward(ctx context.Context, query []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, upstreamTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", f.upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// record appends to the log of a service, keeping the newest entries
+func (f *Filter) record(service string, entry Entry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	log := append(f.logs[service], entry)
+	if len(log) > f.logSize {
+		log = log[len(log)-f.logSize:]
+	}
+	f.logs[service] = log
+}
+
+// reply builds a response without answers
+func reply(query dnsmessage.Header, question *dnsmessage.Question, code dnsmessage.RCode) []byte {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 query.ID,
+		Response:           true,
+		OpCode:             query.OpCode,
+		RecursionDesired:   query.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              code,
+	})
+	builder.EnableCompression()
+	if question != nil {
+		if err := builder.StartQuestions(); err != nil {
+			return nil
+		}
+		if err := builder.Question(*question); err != nil {
+			return nil
+		}
+	}
+	response, err := builder.Finish()
+	if err != nil {
+		return nil
+	}
+	return response
+}
+
+// rcode returns the response code of a response, e.g. Success or NameError
+func rcode(response []byte) string {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return "Invalid"
+	}
+	return strings.TrimPrefix(header.RCode.String(), "RCode")
+}
@@ -0,0 +1,133 @@
+package egress
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+func TestAllowed(t *testing.T) {
+	patterns := []string{"api.github.com", "*.googleapis.com"}
+	for name, want := range map[string]bool{
+		"api.github.com.":          true,
+		"API.GitHub.com":           true,
+		"github.com":               false,
+		"evil-api.github.com":      false,
+		"storage.googleapis.com.":  true,
+		"googleapis.com":           false,
+		"googleapis.com.evil.test": false,
+	} {
+		if got := Allowed(patterns, name); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", name, got, want)
+		}
+	}
+	for _, invalid := range [][]string{{"*"}, {"*.*.example.com"}, {"exa mple.com"}, {"https://example.com"}} {
+		if ValidatePatterns(invalid) == nil {
+			t.Errorf("pattern %q was accepted", invalid)
+		}
+	}
+}
+
+func query(t *testing.T, name string) []byte {
+	t.Helper()
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	if err := builder.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// fakeUpstream answers every query with an empty success response
+func fakeUpstream(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			question, _ := parser.Question()
+			_, _ = conn.WriteTo(reply(header, &question, dnsmessage.RCodeSuccess), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestResolve(t *testing.T) {
+	filter, err := NewFilter(config.EgressConfig{Address: "10.89.0.2", Upstream: fakeUpstream(t), LogSize: 2},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookups := 0
+	filter.SetLookup(func(_ context.Context, ip string) (Client, bool) {
+		lookups++
+		return Client{Service: "mcp-git", Allow: []string{"api.github.com"}}, ip == "10.89.0.5"
+	})
+
+	ctx := context.Background()
+	for name, want := range map[string]string{"api.github.com.": "Success", "example.com.": "NameError"} {
+		if got := rcode(filter.resolve(ctx, "10.89.0.5", query(t, name))); got != want {
+			t.Errorf("%s: rcode %s, want %s", name, got, want)
+		}
+	}
+	if got := rcode(filter.resolve(ctx, "10.89.0.9", query(t, "api.github.com."))); got != "Refused" {
+		t.Errorf("unknown client: rcode %s", got)
+	}
+	filter.resolve(ctx, "10.89.0.5", query(t, "example.org."))
+	if lookups != 2 {
+		t.Errorf("expected cached lookups, got %d", lookups)
+	}
+
+	entries := filter.Log("mcp-git")
+	if len(entries) != 2 || entries[1].Name != "example.org" || entries[1].Allowed || entries[1].Type != "A" {
+		t.Errorf("unexpected log: %+v", entries)
+	}
+	filter.Forget("mcp-git")
+	if len(filter.Log("mcp-git")) != 0 {
+		t.Error("log kept after Forget")
+	}
+}
+
+func TestSystemNameserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(path, []byte("search corp\nnameserver 10.96.0.10\nnameserver 8.8.8.8\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if server, err := systemNameserver(path); err != nil || server != "10.96.0.10" {
+		t.Errorf("nameserver = %q, %v", server, err)
+	}
+	if _, err := NewFilter(config.EgressConfig{Address: "resolver"}, nil); err == nil {
+		t.Error("expected an error for a non-IP address")
+	}
+}
@@ -14,6 +14,7 @@ type Environment string
 const (
 	EnvironmentDocker     Environment = "docker"
 	EnvironmentKubernetes Environment = "kubernetes"
+	EnvironmentFake       Environment = "fake"
 )
 
 // Detector handles environment detection logic
@@ -144,6 +145,9 @@ func (d *Detector) ForceEnvironment(env string) Environment {
 	case "docker", "podman":
 		d.logger.Info("Forced Docker environment via configuration")
 		return EnvironmentDocker
+	case "fake":
+		d.logger.Info("Forced fake environment via configuration, no container runtime is used")
+		return EnvironmentFake
 	default:
 		d.logger.Warn("Invalid forced environment, falling back to auto-detection",
 			slog.String("forced_env", env))
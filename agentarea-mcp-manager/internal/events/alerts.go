@@ -0,0 +1,43 @@
+package events
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Alert kinds raised by publishers for instance failures
+const (
+	AlertFailed      = "failed"
+	AlertCrashLoop   = "crash_loop"
+	AlertQuarantined = "quarantined"
+)
+
+// Alert is an instance failure announced by a publisher, handed to the alert
+// handler alongside the Redis event
+type Alert struct {
+	Kind       string    `json:"kind"`
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	Message    string    `json:"message,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// alertHandler receives the alerts of every publisher; nil drops them
+var alertHandler atomic.Pointer[func(Alert)]
+
+// SetAlertHandler passes every alert raised from now on, by all publishers,
+// to handle, which must not block. nil stops passing them.
+func SetAlertHandler(handle func(Alert)) {
+	if handle == nil {
+		alertHandler.Store(nil)
+		return
+	}
+	alertHandler.Store(&handle)
+}
+
+// raiseAlert passes an alert to the handler, if one is set
+func raiseAlert(kind, instanceID, name, message string) {
+	if handle := alertHandler.Load(); handle != nil {
+		(*handle)(Alert{Kind: kind, InstanceID: instanceID, Name: name, Message: message, Timestamp: time.Now()})
+	}
+}
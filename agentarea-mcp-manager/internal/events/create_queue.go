@@ -0,0 +1,217 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Default creation worker pool sizing
+const (
+	DefaultCreateWorkers   = 4
+	DefaultCreateQueueSize = 100
+)
+
+// createJob is an instance creation waiting for a worker
+type createJob struct {
+	workspaceID  string
+	providerType string
+	instance     *models.MCPServerInstance
+	enqueued     time.Time
+}
+
+// CreateQueueStats reports the state of the creation worker pool
+type CreateQueueStats struct {
+	Depth       int            `json:"depth"`
+	Capacity    int            `json:"capacity"`
+	Workers     int            `json:"workers"`
+	Active      int64          `json:"active"`
+	ByWorkspace map[string]int `json:"by_workspace"`
+	Enqueued    uint64         `json:"enqueued"`
+	Processed   uint64         `json:"processed"`
+	Cancelled   uint64         `json:"cancelled"`
+	Throttled   uint64         `json:"throttled"` // Enqueues that waited for a free slot
+}
+
+// createQueue is a bounded queue of instance creations drained by a fixed
+// pool of workers. Workspaces are served round-robin so a burst from one
+// workspace does not starve the others. Enqueue blocks while the queue is
+// full, pushing back on the subscriber.
+type createQueue struct {
+	workers int
+	handle  func(context.Context, *createJob)
+
+	slots chan struct{} // One token per queued job, bounds the queue
+	ready chan struct{} // One token per job available to workers
+
+	mu      sync.Mutex
+	pending map[string][]*createJob
+	order   []string // Workspaces with pending jobs, in round-robin order
+	next    int
+
+	active    atomic.Int64
+	enqueued  atomic.Uint64
+	processed atomic.Uint64
+	cancelled atomic.Uint64
+	throttled atomic.Uint64
+}
+
+// newCreateQueue creates a queue; call run to start the workers
+func newCreateQueue(workers, capacity int, handle func(context.Context, *createJob)) *createQueue {
+	if workers <= 0 {
+		workers = DefaultCreateWorkers
+	}
+	if capacity <= 0 {
+		capacity = DefaultCreateQueueSize
+	}
+	return &createQueue{
+		workers: workers,
+		handle:  handle,
+		slots:   make(chan struct{}, capacity),
+		ready:   make(chan struct{}, capacity),
+		pending: make(map[string][]*createJob),
+	}
+}
+
+// run starts the workers; they stop when ctx is cancelled
+func (q *createQueue) run(ctx context.Context) {
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// work processes jobs until ctx is cancelled
+func (q *createQueue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.ready:
+		}
+
+		job := q.pop()
+		if job == nil {
+			// The job was cancelled after being signalled
+			continue
+		}
+
+		q.active.Add(1)
+		q.handle(ctx, job)
+		q.active.Add(-1)
+		q.processed.Add(1)
+	}
+}
+
+// enqueue adds a job, waiting for a free slot while the queue is full
+func (q *createQueue) enqueue(ctx context.Context, job *createJob) error {
+	select {
+	case q.slots <- struct{}{}:
+	default:
+		q.throttled.Add(1)
+		select {
+		case q.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	job.enqueued = time.Now()
+
+	q.mu.Lock()
+	if _, ok := q.pending[job.workspaceID]; !ok {
+		q.order = append(q.order, job.workspaceID)
+	}
+	q.pending[job.workspaceID] = append(q.pending[job.workspaceID], job)
+	q.mu.Unlock()
+
+	q.enqueued.Add(1)
+	q.ready <- struct{}{}
+	return nil
+}
+
+// pop removes the next job, taking workspaces in turn
+func (q *createQueue) pop() *createJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return nil
+	}
+	if q.next >= len(q.order) {
+		q.next = 0
+	}
+
+	workspaceID := q.order[q.next]
+	jobs := q.pending[workspaceID]
+	job := jobs[0]
+	if len(jobs) == 1 {
+		delete(q.pending, workspaceID)
+		q.order = append(q.order[:q.next], q.order[q.next+1:]...)
+	} else {
+		q.pending[workspaceID] = jobs[1:]
+		q.next++
+	}
+
+	<-q.slots
+	return job
+}
+
+// cancel drops a queued creation for the instance and reports whether one was found
+func (q *createQueue) cancel(instanceID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, workspaceID := range q.order {
+		jobs := q.pending[workspaceID]
+		for j, job := range jobs {
+			if job.instance.InstanceID != instanceID {
+				continue
+			}
+			if len(jobs) == 1 {
+				delete(q.pending, workspaceID)
+				q.order = append(q.order[:i], q.order[i+1:]...)
+				if q.next > i {
+					q.next--
+				}
+			} else {
+				q.pending[workspaceID] = append(jobs[:j:j], jobs[j+1:]...)
+			}
+			<-q.slots
+			// Drop the job's ready signal unless a worker already holds it
+			select {
+			case <-q.ready:
+			default:
+			}
+			q.cancelled.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// stats returns a snapshot of queue depth and counters
+func (q *createQueue) stats() CreateQueueStats {
+	q.mu.Lock()
+	byWorkspace := make(map[string]int, len(q.pending))
+	depth := 0
+	for workspaceID, jobs := range q.pending {
+		byWorkspace[workspaceID] = len(jobs)
+		depth += len(jobs)
+	}
+	q.mu.Unlock()
+
+	return CreateQueueStats{
+		Depth:       depth,
+		Capacity:    cap(q.slots),
+		Workers:     q.workers,
+		Active:      q.active.Load(),
+		ByWorkspace: byWorkspace,
+		Enqueued:    q.enqueued.Load(),
+		Processed:   q.processed.Load(),
+		Cancelled:   q.cancelled.Load(),
+		Throttled:   q.throttled.Load(),
+	}
+}
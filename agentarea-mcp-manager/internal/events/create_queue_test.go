@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+func newTestJob(workspaceID, instanceID string) *createJob {
+	return &createJob{
+		workspaceID: workspaceID,
+		instance:    &models.MCPServerInstance{InstanceID: instanceID},
+	}
+}
+
+func TestCreateQueueRoundRobinsWorkspaces(t *testing.T) {
+	q := newCreateQueue(1, 10, nil)
+	ctx := context.Background()
+
+	// A burst from ws-a must not delay ws-b and ws-c behind it
+	for _, job := range []*createJob{
+		newTestJob("ws-a", "a1"), newTestJob("ws-a", "a2"), newTestJob("ws-a", "a3"),
+		newTestJob("ws-b", "b1"), newTestJob("ws-c", "c1"), newTestJob("ws-b", "b2"),
+	} {
+		if err := q.enqueue(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stats := q.stats(); stats.Depth != 6 || stats.ByWorkspace["ws-a"] != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	var got []string
+	for job := q.pop(); job != nil; job = q.pop() {
+		got = append(got, job.instance.InstanceID)
+	}
+
+	want := []string{"a1", "b1", "c1", "a2", "b2", "a3"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCreateQueueBackpressureAndCancel(t *testing.T) {
+	q := newCreateQueue(1, 2, nil)
+
+	if err := q.enqueue(context.Background(), newTestJob("ws-a", "a1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.enqueue(context.Background(), newTestJob("ws-b", "b1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue is full: enqueue waits until the context gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.enqueue(ctx, newTestJob("ws-c", "c1")); err == nil {
+		t.Fatal("expected enqueue to block on a full queue")
+	}
+	if q.stats().Throttled != 1 {
+		t.Fatalf("expected one throttled enqueue, got %+v", q.stats())
+	}
+
+	// Cancelling frees a slot
+	if !q.cancel("a1") {
+		t.Fatal("expected queued job to be cancelled")
+	}
+	if q.cancel("a1") {
+		t.Fatal("job cancelled twice")
+	}
+	if err := q.enqueue(context.Background(), newTestJob("ws-c", "c1")); err != nil {
+		t.Fatal(err)
+	}
+	if stats := q.stats(); stats.Depth != 2 || stats.Cancelled != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCreateQueueWorkersProcessJobs(t *testing.T) {
+	done := make(chan string, 3)
+	q := newCreateQueue(2, 10, func(_ context.Context, job *createJob) {
+		done <- job.instance.InstanceID
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.run(ctx)
+
+	for _, id := range []string{"a1", "a2", "b1"} {
+		if err := q.enqueue(ctx, newTestJob("ws-"+id[:1], id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for workers")
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// redisPingInterval is how often the subscriber checks its Redis connection
+const redisPingInterval = 15 * time.Second
+
+// Redis connection states
+const (
+	RedisConnecting   = "connecting"
+	RedisConnected    = "connected"
+	RedisDisconnected = "disconnected"
+)
+
+// ChannelStats counts the events received on one channel
+type ChannelStats struct {
+	Received       uint64       `json:"received"`
+	Parsed         uint64       `json:"parsed"`
+	ParseFailed    uint64       `json:"parse_failed"`
+	Handled        uint64       `json:"handled"`
+	HandleFailed   uint64       `json:"handle_failed"`
+	LastReceivedAt *time.Time   `json:"last_received_at,omitempty"`
+	Latency        LatencyStats `json:"latency"`
+}
+
+// LatencyStats summarizes handler latency in milliseconds
+type LatencyStats struct {
+	Count  uint64  `json:"count"`
+	AvgMs  float64 `json:"avg_ms"`
+	MaxMs  float64 `json:"max_ms"`
+	LastMs float64 `json:"last_ms"`
+}
+
+// RedisStats reports the state of the subscriber's Redis connection
+type RedisStats struct {
+	State          string     `json:"state"`
+	Subscribed     bool       `json:"subscribed"`
+	LastPingAt     *time.Time `json:"last_ping_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	Disconnects    uint64     `json:"disconnects"`
+	ConnectedSince *time.Time `json:"connected_since,omitempty"`
+}
+
+// PipelineStats reports the event pipeline: Redis connection and per-channel counters
+type PipelineStats struct {
+	Redis       RedisStats              `json:"redis"`
+	Channels    map[string]ChannelStats `json:"channels"`
+	LastEventAt *time.Time              `json:"last_event_at,omitempty"`
+}
+
+// pipelineMetrics collects event pipeline counters
+type pipelineMetrics struct {
+	mu          sync.Mutex
+	redis       RedisStats
+	channels    map[string]*channelMetrics
+	lastEventAt time.Time
+}
+
+// channelMetrics are the counters of one channel
+type channelMetrics struct {
+	stats        ChannelStats
+	totalLatency time.Duration
+}
+
+func newPipelineMetrics() *pipelineMetrics {
+	return &pipelineMetrics{
+		redis:    RedisStats{State: RedisDisconnected},
+		channels: make(map[string]*channelMetrics),
+	}
+}
+
+// channel returns the counters of a channel; callers hold mu
+func (m *pipelineMetrics) channel(name string) *channelMetrics {
+	ch, ok := m.channels[name]
+	if !ok {
+		ch = &channelMetrics{}
+		m.channels[name] = ch
+	}
+	return ch
+}
+
+// received counts an incoming event
+func (m *pipelineMetrics) received(channel string, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := m.channel(channel)
+	ch.stats.Received++
+	ch.stats.LastReceivedAt = &now
+	m.lastEventAt = now
+}
+
+// handled records the outcome and latency of an event handler. Payload
+// errors count as parse failures, other errors as handler failures.
+func (m *pipelineMetrics) handled(channel string, latency time.Duration, parseFailed bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := m.channel(channel)
+	switch {
+	case parseFailed:
+		ch.stats.ParseFailed++
+	case err != nil:
+		ch.stats.Parsed++
+		ch.stats.HandleFailed++
+	default:
+		ch.stats.Parsed++
+		ch.stats.Handled++
+	}
+
+	ms := float64(latency) / float64(time.Millisecond)
+	ch.totalLatency += latency
+	ch.stats.Latency.Count++
+	ch.stats.Latency.LastMs = ms
+	if ms > ch.stats.Latency.MaxMs {
+		ch.stats.Latency.MaxMs = ms
+	}
+	ch.stats.Latency.AvgMs = float64(ch.totalLatency) / float64(time.Millisecond) / float64(ch.stats.Latency.Count)
+}
+
+// connection records the result of a Redis ping
+func (m *pipelineMetrics) connection(now time.Time, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.redis.LastPingAt = &now
+	if err != nil {
+		if m.redis.State == RedisConnected {
+			m.redis.Disconnects++
+		}
+		m.redis.State = RedisDisconnected
+		m.redis.LastError = err.Error()
+		m.redis.LastErrorAt = &now
+		m.redis.ConnectedSince = nil
+		return
+	}
+	if m.redis.State != RedisConnected {
+		m.redis.State = RedisConnected
+		m.redis.ConnectedSince = &now
+	}
+}
+
+// setState sets the connection state and whether the subscription is active
+func (m *pipelineMetrics) setState(state string, subscribed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.redis.State == RedisConnected && state != RedisConnected {
+		m.redis.ConnectedSince = nil
+	}
+	m.redis.State = state
+	m.redis.Subscribed = subscribed
+}
+
+// snapshot returns a copy of the counters
+func (m *pipelineMetrics) snapshot() PipelineStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := PipelineStats{
+		Redis:    m.redis,
+		Channels: make(map[string]ChannelStats, len(m.channels)),
+	}
+	for name, ch := range m.channels {
+		stats.Channels[name] = ch.stats
+	}
+	if !m.lastEventAt.IsZero() {
+		last := m.lastEventAt
+		stats.LastEventAt = &last
+	}
+	return stats
+}
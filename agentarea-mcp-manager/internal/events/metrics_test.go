@@ -0,0 +1,37 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	m := newPipelineMetrics()
+	now := time.Now()
+
+	m.received(ChannelInstanceCreated, now)
+	m.handled(ChannelInstanceCreated, 10*time.Millisecond, false, nil)
+	m.received(ChannelInstanceCreated, now)
+	m.handled(ChannelInstanceCreated, 30*time.Millisecond, true, errors.New("bad payload"))
+
+	stats := m.snapshot().Channels[ChannelInstanceCreated]
+	if stats.Received != 2 || stats.Parsed != 1 || stats.ParseFailed != 1 || stats.Handled != 1 {
+		t.Fatalf("unexpected counters: %+v", stats)
+	}
+	if stats.Latency.Count != 2 || stats.Latency.AvgMs != 20 || stats.Latency.MaxMs != 30 {
+		t.Errorf("unexpected latency: %+v", stats.Latency)
+	}
+
+	// Losing the connection counts a disconnect; reconnecting resets connected_since
+	m.connection(now, nil)
+	m.connection(now.Add(time.Second), errors.New("connection refused"))
+	m.connection(now.Add(2*time.Second), nil)
+	redis := m.snapshot().Redis
+	if redis.State != RedisConnected || redis.Disconnects != 1 || redis.LastError == "" {
+		t.Fatalf("unexpected redis state: %+v", redis)
+	}
+	if !redis.ConnectedSince.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("connected_since = %v, want reconnect time", redis.ConnectedSince)
+	}
+}
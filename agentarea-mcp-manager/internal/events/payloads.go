@@ -0,0 +1,221 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/schemas"
+)
+
+// SchemaVersion is the event payload version produced by the manager and the
+// newest version it accepts. Payloads without a version are treated as version 1.
+const SchemaVersion = 1
+
+// Event channels
+const (
+	ChannelInstanceCreated  = "MCPServerInstanceCreated"
+	ChannelInstanceDeleted  = "MCPServerInstanceDeleted"
+	ChannelStatusChanged    = "MCPServerInstanceStatusChanged"
+	ChannelInstanceError    = "MCPServerInstanceError"
+	ChannelInstanceWarning  = "MCPServerInstanceWarning"
+	ChannelEventRejected    = "MCPServerInstanceEventRejected"
+	ChannelTemplateUpdated  = "MCPServerTemplateUpdated"
+	ChannelSLOAlert         = "MCPServerInstanceSLOAlert"
+	ChannelSecretsChanged   = "MCPServerInstanceSecretsChanged"
+	ChannelResourcePressure = "MCPServerInstanceResourcePressure"
+	ChannelDiskPressure     = "MCPManagerDiskPressure"
+	ChannelQuarantined      = "MCPServerInstanceQuarantined"
+	ChannelComponentError   = "MCPManagerComponentError"
+	ChannelCrashLoop        = "MCPServerInstanceCrashLoop"
+	ChannelRoutePublished   = "MCPServerInstanceRoutePublished"
+	ChannelProxyRebound     = "MCPManagerProxyRebound"
+)
+
+// Rejected payloads are kept in a capped Redis list for inspection and replay
+const (
+	quarantineKey = "mcp-manager:events:quarantine"
+	quarantineMax = 1000
+)
+
+// MCPServerInstanceCreated represents the event when an MCP instance is created
+type MCPServerInstanceCreated struct {
+	InstanceID   string         `json:"instance_id"`
+	Name         string         `json:"name"`
+	ServerSpecID string         `json:"server_spec_id,omitempty"`
+	JSONSpec     map[string]any `json:"json_spec"`
+}
+
+// Validate checks required fields and the json_spec schema
+func (e *MCPServerInstanceCreated) Validate() error {
+	if e.InstanceID == "" {
+		return fmt.Errorf("instance_id is required")
+	}
+	if e.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if e.JSONSpec == nil {
+		return fmt.Errorf("json_spec is required")
+	}
+	if errs := schemas.Validate(schemas.JSONSpec, e.JSONSpec); len(errs) > 0 {
+		return fmt.Errorf("json_spec does not match schema %s/%s: %v", schemas.JSONSpec, schemas.CurrentVersion, errs)
+	}
+	return nil
+}
+
+// MCPServerInstanceDeleted represents the event when an MCP instance is deleted
+type MCPServerInstanceDeleted struct {
+	InstanceID string `json:"instance_id"`
+	Name       string `json:"name"`
+}
+
+// Validate checks required fields
+func (e *MCPServerInstanceDeleted) Validate() error {
+	if e.InstanceID == "" {
+		return fmt.Errorf("instance_id is required")
+	}
+	return nil
+}
+
+// EventRejected is published when an incoming event cannot be processed
+type EventRejected struct {
+	Channel       string    `json:"channel"`
+	EventID       string    `json:"event_id,omitempty"`
+	InstanceID    string    `json:"instance_id,omitempty"`
+	SchemaVersion int       `json:"schema_version,omitempty"`
+	Reason        string    `json:"reason"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// EventMessage represents the wrapper structure from FastStream Redis
+type EventMessage struct {
+	Data    json.RawMessage `json:"data"`
+	Headers map[string]any  `json:"headers"`
+}
+
+// Envelope represents the inner event structure
+type Envelope struct {
+	EventID       string          `json:"event_id"`
+	Timestamp     string          `json:"timestamp"`
+	EventType     string          `json:"event_type"`
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// PayloadError describes an incoming event that is malformed or invalid
+type PayloadError struct {
+	Envelope   *Envelope // nil if the envelope itself could not be decoded
+	InstanceID string
+	Reason     string
+}
+
+// Error implements error
+func (e *PayloadError) Error() string {
+	return e.Reason
+}
+
+// decodeEnvelope unwraps the FastStream message. Its data field is a JSON
+// encoded string when sent by FastStream and an object when sent by the manager.
+func decodeEnvelope(payload string) (*Envelope, error) {
+	var message EventMessage
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		return nil, &PayloadError{Reason: fmt.Sprintf("invalid event message: %v", err)}
+	}
+	if len(message.Data) == 0 {
+		return nil, &PayloadError{Reason: "event message has no data"}
+	}
+
+	inner := []byte(message.Data)
+	if inner[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(inner, &encoded); err != nil {
+			return nil, &PayloadError{Reason: fmt.Sprintf("invalid event data: %v", err)}
+		}
+		inner = []byte(encoded)
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(inner, &envelope); err != nil {
+		return nil, &PayloadError{Reason: fmt.Sprintf("invalid event envelope: %v", err)}
+	}
+
+	if envelope.SchemaVersion == 0 {
+		envelope.SchemaVersion = 1
+	}
+	if envelope.SchemaVersion > SchemaVersion {
+		return nil, &PayloadError{
+			Envelope: &envelope,
+			Reason:   fmt.Sprintf("unsupported schema_version %d (supported up to %d)", envelope.SchemaVersion, SchemaVersion),
+		}
+	}
+	if len(envelope.Data) == 0 {
+		return nil, &PayloadError{Envelope: &envelope, Reason: "event has no data"}
+	}
+
+	return &envelope, nil
+}
+
+// decodeEvent decodes and validates the payload of an event. Unknown fields
+// are ignored so producers can add fields without breaking the manager.
+func decodeEvent[T interface{ Validate() error }](payload string, event T) (*Envelope, error) {
+	envelope, err := decodeEnvelope(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(envelope.Data, event); err != nil {
+		return envelope, &PayloadError{Envelope: envelope, Reason: fmt.Sprintf("invalid %s payload: %v", envelope.EventType, err)}
+	}
+
+	if err := event.Validate(); err != nil {
+		return envelope, &PayloadError{
+			Envelope:   envelope,
+			InstanceID: instanceIDOf(envelope.Data),
+			Reason:     fmt.Sprintf("invalid %s payload: %v", envelope.EventType, err),
+		}
+	}
+
+	return envelope, nil
+}
+
+// instanceIDOf extracts the instance id from raw event data, if present
+func instanceIDOf(data json.RawMessage) string {
+	var probe struct {
+		InstanceID string `json:"instance_id"`
+	}
+	_ = json.Unmarshal(data, &probe)
+	return probe.InstanceID
+}
+
+// encodeEvent wraps event data in the FastStream message format, signed when
+// a signer is set
+func encodeEvent(eventType string, timestamp time.Time, data any) ([]byte, error) {
+	// Error strings and URLs can embed credentials; scrub before they leave the manager
+	data, err := redact.Value(data)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := map[string]any{
+		"event_id":       generateEventID(),
+		"timestamp":      timestamp.Format(time.RFC3339),
+		"event_type":     eventType,
+		"schema_version": SchemaVersion,
+		"data":           data,
+	}
+	headers := map[string]any{}
+	if s := signer.Load(); s != nil {
+		canonical, err := canonicalJSON(envelope)
+		if err != nil {
+			return nil, err
+		}
+		headers = s.sign(canonical)
+	}
+
+	message := map[string]any{
+		"data":    envelope,
+		"headers": headers,
+	}
+	return json.Marshal(message)
+}
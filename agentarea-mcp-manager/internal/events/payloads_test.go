@@ -0,0 +1,113 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fastStreamMessage wraps inner event data the way FastStream publishes it
+func fastStreamMessage(t *testing.T, inner map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := json.Marshal(map[string]any{"data": string(data), "headers": map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(message)
+}
+
+func TestDecodeInstanceCreated(t *testing.T) {
+	validSpec := map[string]any{"type": "docker", "image": "mcp/echo:latest", "port": 8000}
+
+	tests := []struct {
+		name    string
+		inner   map[string]any
+		wantErr string
+	}{
+		{
+			name: "valid without schema_version and with unknown fields",
+			inner: map[string]any{
+				"event_id": "evt_1",
+				"data":     map[string]any{"instance_id": "i-1", "name": "echo", "json_spec": validSpec, "extra": true},
+				"future":   "ignored",
+			},
+		},
+		{
+			name: "valid without port, which comes from the image",
+			inner: map[string]any{
+				"data": map[string]any{"instance_id": "i-1", "name": "echo", "json_spec": map[string]any{"image": "mcp/echo:latest"}},
+			},
+		},
+		{
+			name:    "unsupported schema_version",
+			inner:   map[string]any{"schema_version": SchemaVersion + 1, "data": map[string]any{"instance_id": "i-1"}},
+			wantErr: "unsupported schema_version",
+		},
+		{
+			name:    "missing instance_id",
+			inner:   map[string]any{"data": map[string]any{"name": "echo", "json_spec": validSpec}},
+			wantErr: "instance_id is required",
+		},
+		{
+			name:    "wrong json_spec type",
+			inner:   map[string]any{"data": map[string]any{"instance_id": "i-1", "name": "echo", "json_spec": "docker"}},
+			wantErr: "json_spec",
+		},
+		{
+			name:    "json_spec fails schema",
+			inner:   map[string]any{"data": map[string]any{"instance_id": "i-1", "name": "echo", "json_spec": map[string]any{"type": "docker"}}},
+			wantErr: "does not match schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event MCPServerInstanceCreated
+			envelope, err := decodeEvent(fastStreamMessage(t, tt.inner), &event)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if envelope.SchemaVersion != 1 || event.InstanceID != "i-1" || event.JSONSpec["image"] != "mcp/echo:latest" {
+					t.Fatalf("unexpected decode result: %+v %+v", envelope, event)
+				}
+				return
+			}
+
+			var payloadErr *PayloadError
+			if !errors.As(err, &payloadErr) {
+				t.Fatalf("expected PayloadError, got %v", err)
+			}
+			if !strings.Contains(payloadErr.Reason, tt.wantErr) {
+				t.Fatalf("reason %q does not contain %q", payloadErr.Reason, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeEnvelopeAcceptsObjectData(t *testing.T) {
+	message, err := encodeEvent(ChannelInstanceDeleted, time.Time{}, map[string]any{"instance_id": "i-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event MCPServerInstanceDeleted
+	envelope, err := decodeEvent(string(message), &event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if envelope.EventType != ChannelInstanceDeleted || event.InstanceID != "i-2" {
+		t.Fatalf("unexpected decode result: %+v %+v", envelope, event)
+	}
+
+	if _, err := decodeEnvelope("not json"); err == nil {
+		t.Fatal("expected error for malformed message")
+	}
+}
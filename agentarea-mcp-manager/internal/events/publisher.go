@@ -2,23 +2,30 @@ package events
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log/slog"
-	"strings"
 	"time"
 
 	redis "github.com/go-redis/redis/v8"
+
+	"github.com/agentarea/mcp-manager/internal/metrics"
+	"github.com/agentarea/mcp-manager/internal/operations"
+	"github.com/agentarea/mcp-manager/internal/redisconn"
 )
 
 // StatusUpdateEvent represents a container status update event
 type StatusUpdateEvent struct {
-	InstanceID  string    `json:"instance_id"`
-	Name        string    `json:"name"`
-	Status      string    `json:"status"`
-	ContainerID string    `json:"container_id,omitempty"`
-	URL         string    `json:"url,omitempty"`
-	Error       string    `json:"error,omitempty"`
-	Timestamp   time.Time `json:"timestamp"`
+	InstanceID  string `json:"instance_id"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	ContainerID string `json:"container_id,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	// AuthToken is the bearer token generated for a new instance. Only the
+	// first running event after creation carries it.
+	AuthToken string    `json:"auth_token,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // ErrorEvent represents a container error event
@@ -29,35 +36,159 @@ type ErrorEvent struct {
 	Timestamp  time.Time `json:"timestamp"`
 }
 
+// WarningEvent represents a non-fatal problem with an instance, such as
+// connections that were force-closed during a restart
+type WarningEvent struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	Warning    string    `json:"warning"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// TemplateUpdatedEvent announces a new template version and the instances
+// still running an older one
+type TemplateUpdatedEvent struct {
+	TemplateID        string    `json:"template_id"`
+	PreviousVersion   string    `json:"previous_version"`
+	Version           string    `json:"version"`
+	OutdatedInstances []string  `json:"outdated_instances"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// SLOAlertEvent reports an instance health SLO alert starting ("firing") or
+// ending ("resolved")
+type SLOAlertEvent struct {
+	InstanceID      string    `json:"instance_id"`
+	Name            string    `json:"name"`
+	State           string    `json:"state"`
+	Reason          string    `json:"reason"`
+	Objective       float64   `json:"objective"`
+	Window          string    `json:"window"`
+	Compliance      float64   `json:"compliance"`
+	BudgetRemaining float64   `json:"budget_remaining"`
+	BurnRate        float64   `json:"burn_rate"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// SecretsChangedEvent reports that secrets referenced by a running instance
+// changed. Keys names the affected environment variables, never their values;
+// Action is "restart" when the instance is recreated with the new values and
+// "notify" otherwise.
+type SecretsChangedEvent struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	Keys       []string  `json:"keys"`
+	Action     string    `json:"action"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ResourcePressureEvent reports an instance running close to its memory or
+// CPU limit, with a suggested higher limit
+type ResourcePressureEvent struct {
+	InstanceID     string    `json:"instance_id"`
+	Name           string    `json:"name"`
+	Resource       string    `json:"resource"` // memory or cpu
+	Usage          float64   `json:"usage"`    // Fraction of the limit in use
+	Limit          string    `json:"limit"`
+	SuggestedLimit string    `json:"suggested_limit"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// DiskPressureEvent reports the container storage filesystem crossing a usage
+// threshold, or dropping back below it (level ok)
+type DiskPressureEvent struct {
+	Path       string    `json:"path"`
+	Level      string    `json:"level"` // ok, warning or critical
+	Usage      float64   `json:"usage"` // Fraction of the filesystem in use
+	FreeBytes  int64     `json:"free_bytes"`
+	TotalBytes int64     `json:"total_bytes"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ProxyReboundEvent reports the internal proxy listening again after its
+// listener failed, possibly on the fallback port
+type ProxyReboundEvent struct {
+	Address   string    `json:"address"`
+	Port      int       `json:"port"`
+	Fallback  bool      `json:"fallback"`
+	Rebinds   int       `json:"rebinds"`
+	LastError string    `json:"last_error,omitempty"` // Why the proxy stopped listening
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// QuarantineEvent reports an instance being isolated as possibly compromised,
+// or released again (action quarantined or released)
+type QuarantineEvent struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	Action     string    `json:"action"`
+	Reason     string    `json:"reason,omitempty"`
+	Source     string    `json:"source"` // manual or anomaly
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// CrashLoopEvent reports an instance that failed repeatedly within a window
+type CrashLoopEvent struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	Failures   int       `json:"failures"`
+	Window     string    `json:"window"`
+	LastError  string    `json:"last_error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// RoutePublishedEvent reports that the URL of a new instance routes to it
+// (route_published). Ready is false when the server did not answer a probe
+// within the grace period and the route was published anyway. WarmedUp is
+// true when the instance's warm-up requests completed first.
+type RoutePublishedEvent struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Ready      bool      `json:"ready"`
+	WarmedUp   bool      `json:"warmed_up,omitempty"`
+	WaitedMS   int64     `json:"waited_ms"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ComponentErrorEvent reports a background component of the manager failing
+// or panicking (manager_component_error). Restarting is false when the
+// component gave up.
+type ComponentErrorEvent struct {
+	Component  string    `json:"component"`
+	Error      string    `json:"error"`
+	Panic      bool      `json:"panic"`
+	Stack      string    `json:"stack,omitempty"` // Truncated to maxStackBytes
+	Restarting bool      `json:"restarting"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// maxStackBytes caps the stack trace carried by a component error event
+const maxStackBytes = 8 << 10
+
 // EventPublisher handles publishing events to Redis
 type EventPublisher struct {
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
 	logger      *slog.Logger
 }
 
-// NewEventPublisher creates a new event publisher
-func NewEventPublisher(redisURL string, logger *slog.Logger) *EventPublisher {
-	var opts *redis.Options
-	if parsed, err := redis.ParseURL(redisURL); err == nil {
-		opts = parsed
-	} else {
-		var addr string
-		if cutAddr, found := strings.CutPrefix(redisURL, "redis://"); found {
-			addr = cutAddr
-		} else {
-			addr = redisURL
-		}
-		opts = &redis.Options{Addr: addr}
-	}
-
-	rdb := redis.NewClient(opts)
-
+// NewEventPublisher creates a new event publisher with its own Redis client
+func NewEventPublisher(redisOptions *redisconn.Options, logger *slog.Logger) *EventPublisher {
 	return &EventPublisher{
-		redisClient: rdb,
+		redisClient: redisOptions.NewClient(),
 		logger:      logger,
 	}
 }
 
+// publish sends an encoded event, counting failures in the metrics
+func (p *EventPublisher) publish(ctx context.Context, channel string, eventBytes []byte) error {
+	err := p.redisClient.Publish(ctx, channel, string(eventBytes)).Err()
+	if err != nil {
+		metrics.EventPublishFailed(channel)
+	}
+	return err
+}
+
 // PublishStatusUpdate publishes a container status update event
 func (p *EventPublisher) PublishStatusUpdate(ctx context.Context, instanceID, name, status string, containerID, url string) error {
 	event := StatusUpdateEvent{
@@ -68,42 +199,40 @@ func (p *EventPublisher) PublishStatusUpdate(ctx context.Context, instanceID, na
 		URL:         url,
 		Timestamp:   time.Now(),
 	}
+	return p.publishStatus(ctx, event)
+}
 
+// publishStatus publishes a prepared status update event
+func (p *EventPublisher) publishStatus(ctx context.Context, event StatusUpdateEvent) error {
 	// Wrap in FastStream message format to match the API's expected structure
-	eventData := map[string]any{
-		"event_id":   generateEventID(),
-		"timestamp":  event.Timestamp.Format(time.RFC3339),
-		"event_type": "MCPServerInstanceStatusChanged",
-		"data":       event,
-	}
-
-	message := map[string]any{
-		"data":    eventData,
-		"headers": map[string]any{},
+	message := event.Reason
+	if event.Error != "" {
+		message = event.Error
 	}
+	recordStep(TimelineStep{InstanceID: event.InstanceID, Name: event.Name, Step: event.Status, Message: message, Timestamp: event.Timestamp})
 
-	eventBytes, err := json.Marshal(message)
+	eventBytes, err := encodeEvent(ChannelStatusChanged, event.Timestamp, event)
 	if err != nil {
 		p.logger.Error("Failed to marshal status update event",
-			slog.String("instance_id", instanceID),
+			slog.String("instance_id", event.InstanceID),
 			slog.String("error", err.Error()))
 		return err
 	}
 
-	err = p.redisClient.Publish(ctx, "MCPServerInstanceStatusChanged", string(eventBytes)).Err()
+	err = p.publish(ctx, ChannelStatusChanged, eventBytes)
 	if err != nil {
 		p.logger.Error("Failed to publish status update event",
-			slog.String("instance_id", instanceID),
-			slog.String("status", status),
+			slog.String("instance_id", event.InstanceID),
+			slog.String("status", event.Status),
 			slog.String("error", err.Error()))
 		return err
 	}
 
 	p.logger.Info("Published status update event",
-		slog.String("instance_id", instanceID),
-		slog.String("name", name),
-		slog.String("status", status),
-		slog.String("container_id", containerID))
+		slog.String("instance_id", event.InstanceID),
+		slog.String("name", event.Name),
+		slog.String("status", event.Status),
+		slog.String("container_id", event.ContainerID))
 
 	return nil
 }
@@ -117,20 +246,8 @@ func (p *EventPublisher) PublishError(ctx context.Context, instanceID, name, err
 		Timestamp:  time.Now(),
 	}
 
-	// Wrap in FastStream message format
-	eventData := map[string]any{
-		"event_id":   generateEventID(),
-		"timestamp":  event.Timestamp.Format(time.RFC3339),
-		"event_type": "MCPServerInstanceError",
-		"data":       event,
-	}
-
-	message := map[string]any{
-		"data":    eventData,
-		"headers": map[string]any{},
-	}
-
-	eventBytes, err := json.Marshal(message)
+	// Wrap in FastStream message format to match the API's expected structure
+	eventBytes, err := encodeEvent(ChannelInstanceError, event.Timestamp, event)
 	if err != nil {
 		p.logger.Error("Failed to marshal error event",
 			slog.String("instance_id", instanceID),
@@ -138,7 +255,7 @@ func (p *EventPublisher) PublishError(ctx context.Context, instanceID, name, err
 		return err
 	}
 
-	err = p.redisClient.Publish(ctx, "MCPServerInstanceError", string(eventBytes)).Err()
+	err = p.publish(ctx, ChannelInstanceError, eventBytes)
 	if err != nil {
 		p.logger.Error("Failed to publish error event",
 			slog.String("instance_id", instanceID),
@@ -154,11 +271,331 @@ func (p *EventPublisher) PublishError(ctx context.Context, instanceID, name, err
 	return nil
 }
 
+// PublishWarning publishes an instance warning event
+func (p *EventPublisher) PublishWarning(ctx context.Context, instanceID, name, warning string) error {
+	event := WarningEvent{
+		InstanceID: instanceID,
+		Name:       name,
+		Warning:    warning,
+		Timestamp:  time.Now(),
+	}
+
+	eventBytes, err := encodeEvent(ChannelInstanceWarning, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelInstanceWarning, eventBytes); err != nil {
+		p.logger.Error("Failed to publish warning event",
+			slog.String("instance_id", instanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published warning event",
+		slog.String("instance_id", instanceID),
+		slog.String("name", name),
+		slog.String("warning", warning))
+
+	return nil
+}
+
+// PublishTemplateUpdated publishes a template version change
+func (p *EventPublisher) PublishTemplateUpdated(ctx context.Context, event TemplateUpdatedEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	eventBytes, err := encodeEvent(ChannelTemplateUpdated, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelTemplateUpdated, eventBytes); err != nil {
+		p.logger.Error("Failed to publish template updated event",
+			slog.String("template_id", event.TemplateID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published template updated event",
+		slog.String("template_id", event.TemplateID),
+		slog.String("version", event.Version),
+		slog.Int("outdated_instances", len(event.OutdatedInstances)))
+
+	return nil
+}
+
+// PublishSLOAlert publishes an instance health SLO alert
+func (p *EventPublisher) PublishSLOAlert(ctx context.Context, event SLOAlertEvent) error {
+	eventBytes, err := encodeEvent(ChannelSLOAlert, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelSLOAlert, eventBytes); err != nil {
+		p.logger.Error("Failed to publish SLO alert",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published SLO alert",
+		slog.String("instance_id", event.InstanceID),
+		slog.String("state", event.State),
+		slog.String("reason", event.Reason))
+
+	return nil
+}
+
+// PublishSecretsChanged publishes that an instance's secrets changed
+func (p *EventPublisher) PublishSecretsChanged(ctx context.Context, instanceID, name string, keys []string, action string) error {
+	event := SecretsChangedEvent{
+		InstanceID: instanceID,
+		Name:       name,
+		Keys:       keys,
+		Action:     action,
+		Timestamp:  time.Now(),
+	}
+
+	eventBytes, err := encodeEvent(ChannelSecretsChanged, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelSecretsChanged, eventBytes); err != nil {
+		p.logger.Error("Failed to publish secrets changed event",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published secrets changed event",
+		slog.String("instance_id", event.InstanceID),
+		slog.Int("keys", len(event.Keys)),
+		slog.String("action", event.Action))
+
+	return nil
+}
+
+// PublishResourcePressure publishes a resource pressure warning
+func (p *EventPublisher) PublishResourcePressure(ctx context.Context, event ResourcePressureEvent) error {
+	eventBytes, err := encodeEvent(ChannelResourcePressure, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelResourcePressure, eventBytes); err != nil {
+		p.logger.Error("Failed to publish resource pressure event",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published resource pressure event",
+		slog.String("instance_id", event.InstanceID),
+		slog.String("resource", event.Resource),
+		slog.String("suggested_limit", event.SuggestedLimit))
+
+	return nil
+}
+
+// PublishProxyRebound publishes where the internal proxy listens after a
+// rebind
+func (p *EventPublisher) PublishProxyRebound(ctx context.Context, event ProxyReboundEvent) error {
+	eventBytes, err := encodeEvent(ChannelProxyRebound, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelProxyRebound, eventBytes); err != nil {
+		p.logger.Error("Failed to publish proxy rebound event",
+			slog.String("address", event.Address),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published proxy rebound event",
+		slog.String("address", event.Address),
+		slog.Bool("fallback", event.Fallback))
+
+	return nil
+}
+
+// PublishDiskPressure publishes a container storage usage level change
+func (p *EventPublisher) PublishDiskPressure(ctx context.Context, event DiskPressureEvent) error {
+	eventBytes, err := encodeEvent(ChannelDiskPressure, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelDiskPressure, eventBytes); err != nil {
+		p.logger.Error("Failed to publish disk pressure event",
+			slog.String("path", event.Path),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published disk pressure event",
+		slog.String("path", event.Path),
+		slog.String("level", event.Level),
+		slog.Float64("usage", event.Usage))
+
+	return nil
+}
+
+// PublishQuarantine publishes that an instance was quarantined or released
+func (p *EventPublisher) PublishQuarantine(ctx context.Context, event QuarantineEvent) error {
+	if event.Action == "quarantined" {
+		raiseAlert(AlertQuarantined, event.InstanceID, event.Name, event.Reason)
+	}
+
+	eventBytes, err := encodeEvent(ChannelQuarantined, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelQuarantined, eventBytes); err != nil {
+		p.logger.Error("Failed to publish quarantine event",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published quarantine event",
+		slog.String("instance_id", event.InstanceID),
+		slog.String("action", event.Action),
+		slog.String("source", event.Source))
+
+	return nil
+}
+
+// PublishCrashLoop publishes that an instance keeps failing
+func (p *EventPublisher) PublishCrashLoop(ctx context.Context, event CrashLoopEvent) error {
+	raiseAlert(AlertCrashLoop, event.InstanceID, event.Name,
+		fmt.Sprintf("%d failures within %s, last: %s", event.Failures, event.Window, event.LastError))
+
+	eventBytes, err := encodeEvent(ChannelCrashLoop, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelCrashLoop, eventBytes); err != nil {
+		p.logger.Error("Failed to publish crash loop event",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published crash loop event",
+		slog.String("instance_id", event.InstanceID),
+		slog.Int("failures", event.Failures),
+		slog.String("window", event.Window))
+
+	return nil
+}
+
+// PublishRoutePublished publishes that the URL of an instance became valid
+func (p *EventPublisher) PublishRoutePublished(ctx context.Context, event RoutePublishedEvent) error {
+	recordStep(TimelineStep{
+		InstanceID: event.InstanceID,
+		Name:       event.Name,
+		Step:       stepRoutePublished,
+		Message:    fmt.Sprintf("%s ready=%t warmed_up=%t waited_ms=%d", event.URL, event.Ready, event.WarmedUp, event.WaitedMS),
+		Timestamp:  event.Timestamp,
+	})
+
+	eventBytes, err := encodeEvent(ChannelRoutePublished, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelRoutePublished, eventBytes); err != nil {
+		p.logger.Error("Failed to publish route published event",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published route published event",
+		slog.String("instance_id", event.InstanceID),
+		slog.String("url", event.URL),
+		slog.Bool("ready", event.Ready),
+		slog.Int64("waited_ms", event.WaitedMS))
+
+	return nil
+}
+
+// PublishComponentError publishes that a background component failed; stack
+// is set for panics
+func (p *EventPublisher) PublishComponentError(ctx context.Context, component, errorMsg, stack string, restarting bool) error {
+	if len(stack) > maxStackBytes {
+		stack = stack[:maxStackBytes]
+	}
+	event := ComponentErrorEvent{
+		Component:  component,
+		Error:      errorMsg,
+		Panic:      stack != "",
+		Stack:      stack,
+		Restarting: restarting,
+		Timestamp:  time.Now(),
+	}
+
+	eventBytes, err := encodeEvent(ChannelComponentError, event.Timestamp, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.publish(ctx, ChannelComponentError, eventBytes); err != nil {
+		p.logger.Error("Failed to publish component error event",
+			slog.String("component", component),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	p.logger.Info("Published component error event",
+		slog.String("component", component),
+		slog.Bool("panic", event.Panic),
+		slog.Bool("restarting", restarting))
+
+	return nil
+}
+
 // PublishRunning publishes that a container is running
 func (p *EventPublisher) PublishRunning(ctx context.Context, instanceID, name, containerID, url string) error {
 	return p.PublishStatusUpdate(ctx, instanceID, name, "running", containerID, url)
 }
 
+// PublishCreated publishes that a newly created container is running, with
+// the auth token generated for it, if any
+func (p *EventPublisher) PublishCreated(ctx context.Context, instanceID, name, containerID, url, authToken string) error {
+	return p.publishStatus(ctx, StatusUpdateEvent{
+		InstanceID:  instanceID,
+		Name:        name,
+		Status:      "running",
+		ContainerID: containerID,
+		URL:         url,
+		AuthToken:   authToken,
+		Timestamp:   time.Now(),
+	})
+}
+
+// PublishQueued publishes that a container is waiting for a creation worker
+func (p *EventPublisher) PublishQueued(ctx context.Context, instanceID, name string) error {
+	return p.PublishStatusUpdate(ctx, instanceID, name, "queued", "", "")
+}
+
+// PublishRestarting publishes that a running container is being restarted, e.g. by its restart policy
+func (p *EventPublisher) PublishRestarting(ctx context.Context, instanceID, name, reason string) error {
+	return p.publishStatus(ctx, StatusUpdateEvent{
+		InstanceID: instanceID,
+		Name:       name,
+		Status:     "restarting",
+		Reason:     reason,
+		Timestamp:  time.Now(),
+	})
+}
+
 // PublishStarting publishes that a container is starting
 func (p *EventPublisher) PublishStarting(ctx context.Context, instanceID, name string) error {
 	return p.PublishStatusUpdate(ctx, instanceID, name, "starting", "", "")
@@ -171,8 +608,48 @@ func (p *EventPublisher) PublishValidating(ctx context.Context, instanceID, name
 
 // PublishFailed publishes that a container failed to start
 func (p *EventPublisher) PublishFailed(ctx context.Context, instanceID, name, errorMsg string) error {
+	// Whoever tracks a cancelled operation rolls it back and publishes cancelled
+	if operations.Cancelled(ctx) {
+		return nil
+	}
+	raiseAlert(AlertFailed, instanceID, name, errorMsg)
+	p.PublishError(ctx, instanceID, name, errorMsg)
+	// The error goes with the status too, so the timeline keeps the reason
+	return p.publishStatus(ctx, StatusUpdateEvent{
+		InstanceID: instanceID,
+		Name:       name,
+		Status:     "failed",
+		Error:      errorMsg,
+		Timestamp:  time.Now(),
+	})
+}
+
+// PublishStopped publishes that an instance was stopped on request
+func (p *EventPublisher) PublishStopped(ctx context.Context, instanceID, name string) error {
+	return p.PublishStatusUpdate(ctx, instanceID, name, "stopped", "", "")
+}
+
+// PublishCancelled publishes that an in-flight create or update was
+// cancelled and rolled back
+func (p *EventPublisher) PublishCancelled(ctx context.Context, instanceID, name, reason string) error {
+	return p.publishStatus(ctx, StatusUpdateEvent{
+		InstanceID: instanceID,
+		Name:       name,
+		Status:     "cancelled",
+		Reason:     reason,
+		Timestamp:  time.Now(),
+	})
+}
+
+// PublishDeleted publishes that an instance was removed
+func (p *EventPublisher) PublishDeleted(ctx context.Context, instanceID, name string) error {
+	return p.PublishStatusUpdate(ctx, instanceID, name, "deleted", "", "")
+}
+
+// PublishDeleteFailed publishes that an instance could not be removed
+func (p *EventPublisher) PublishDeleteFailed(ctx context.Context, instanceID, name, errorMsg string) error {
 	p.PublishError(ctx, instanceID, name, errorMsg)
-	return p.PublishStatusUpdate(ctx, instanceID, name, "failed", "", "")
+	return p.PublishStatusUpdate(ctx, instanceID, name, "delete_failed", "", "")
 }
 
 // Close closes the Redis connection
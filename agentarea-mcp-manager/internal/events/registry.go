@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+// instanceProvidersKey is the Redis hash mapping instance IDs to provider types
+const instanceProvidersKey = "mcp-manager:instance-providers"
+
+// InstanceRegistry persists which provider serves each instance so deletion
+// events can be routed without the original json_spec
+type InstanceRegistry struct {
+	redisClient redis.UniversalClient
+}
+
+// NewInstanceRegistry creates a registry backed by the given Redis client
+func NewInstanceRegistry(redisClient redis.UniversalClient) *InstanceRegistry {
+	return &InstanceRegistry{redisClient: redisClient}
+}
+
+// Record stores the provider type for an instance
+func (r *InstanceRegistry) Record(ctx context.Context, instanceID, providerType string) error {
+	if err := r.redisClient.HSet(ctx, instanceProvidersKey, instanceID, providerType).Err(); err != nil {
+		return fmt.Errorf("failed to record provider for instance %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// Lookup returns the provider type for an instance and whether it is known
+func (r *InstanceRegistry) Lookup(ctx context.Context, instanceID string) (string, bool, error) {
+	providerType, err := r.redisClient.HGet(ctx, instanceProvidersKey, instanceID).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up provider for instance %s: %w", instanceID, err)
+	}
+	return providerType, true, nil
+}
+
+// Forget removes an instance from the registry
+func (r *InstanceRegistry) Forget(ctx context.Context, instanceID string) error {
+	if err := r.redisClient.HDel(ctx, instanceProvidersKey, instanceID).Err(); err != nil {
+		return fmt.Errorf("failed to forget provider for instance %s: %w", instanceID, err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+// Signature algorithms
+const (
+	SigningHMACSHA256 = "hmac-sha256" // Shared secret
+	SigningEd25519    = "ed25519"     // Consumers only hold the public key
+)
+
+// Message headers carrying the signature of a published event
+const (
+	HeaderSignature          = "x-signature"
+	HeaderSignatureAlgorithm = "x-signature-alg"
+	HeaderSignatureKeyID     = "x-signature-key-id"
+)
+
+// minHMACKeyLength is the shortest accepted shared secret, in bytes
+const minHMACKeyLength = 32
+
+// ErrUnsigned is returned when verifying an event without a signature
+var ErrUnsigned = errors.New("event is not signed")
+
+// ErrBadSignature is returned when an event signature does not verify
+var ErrBadSignature = errors.New("invalid event signature")
+
+// Signer signs published events so consumers sharing the Redis can tell them
+// from spoofed ones. The signature covers the canonical JSON (sorted keys, no
+// whitespace, no HTML escaping) of the message data: event id, timestamp,
+// type, schema version and payload.
+type Signer struct {
+	algorithm  string
+	keyID      string
+	hmacKey    []byte
+	privateKey ed25519.PrivateKey
+}
+
+// NewSigner creates a signer. key is the shared secret for hmac-sha256, or
+// the base64 encoded seed or private key for ed25519.
+func NewSigner(algorithm, keyID, key string) (*Signer, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("signing key id is required")
+	}
+	signer := &Signer{algorithm: algorithm, keyID: keyID}
+	switch algorithm {
+	case SigningHMACSHA256:
+		if len(key) < minHMACKeyLength {
+			return nil, fmt.Errorf("hmac-sha256 signing key must be at least %d bytes", minHMACKeyLength)
+		}
+		signer.hmacKey = []byte(key)
+	case SigningEd25519:
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("ed25519 signing key is not base64: %w", err)
+		}
+		switch len(raw) {
+		case ed25519.SeedSize:
+			signer.privateKey = ed25519.NewKeyFromSeed(raw)
+		case ed25519.PrivateKeySize:
+			signer.privateKey = ed25519.PrivateKey(raw)
+		default:
+			return nil, fmt.Errorf("ed25519 signing key must be a %d byte seed or %d byte private key", ed25519.SeedSize, ed25519.PrivateKeySize)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q, use %s or %s", algorithm, SigningHMACSHA256, SigningEd25519)
+	}
+	// The key must never reach a published event or log line
+	redact.AddSecret(key)
+	return signer, nil
+}
+
+// Algorithm returns the signature algorithm
+func (s *Signer) Algorithm() string {
+	return s.algorithm
+}
+
+// KeyID returns the key id sent with every signature
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the base64 encoded ed25519 public key that consumers
+// verify with, or "" for hmac-sha256
+func (s *Signer) PublicKey() string {
+	if s.privateKey == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(s.privateKey.Public().(ed25519.PublicKey))
+}
+
+// sign returns the signature headers for canonical message data
+func (s *Signer) sign(canonical []byte) map[string]any {
+	var signature []byte
+	if s.privateKey != nil {
+		signature = ed25519.Sign(s.privateKey, canonical)
+	} else {
+		mac := hmac.New(sha256.New, s.hmacKey)
+		mac.Write(canonical)
+		signature = mac.Sum(nil)
+	}
+	return map[string]any{
+		HeaderSignature:          base64.StdEncoding.EncodeToString(signature),
+		HeaderSignatureAlgorithm: s.algorithm,
+		HeaderSignatureKeyID:     s.keyID,
+	}
+}
+
+// signer signs every event encoded by the package; nil leaves them unsigned
+var signer atomic.Pointer[Signer]
+
+// SetSigner signs every event published from now on, by all publishers and
+// subscribers. nil disables signing.
+func SetSigner(s *Signer) {
+	signer.Store(s)
+}
+
+// canonicalJSON encodes a value with sorted keys, no whitespace and no HTML
+// escaping, so consumers can reproduce the signed bytes from the decoded data
+func canonicalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Verifier checks the signatures of events published by managers. Consumers
+// written in Go can use it; others reproduce the canonical JSON of the
+// message data and check the signature with the key named in the headers.
+type Verifier struct {
+	hmacKeys   map[string][]byte
+	publicKeys map[string]ed25519.PublicKey
+}
+
+// NewVerifier creates a verifier without keys
+func NewVerifier() *Verifier {
+	return &Verifier{
+		hmacKeys:   make(map[string][]byte),
+		publicKeys: make(map[string]ed25519.PublicKey),
+	}
+}
+
+// AddHMACKey trusts a shared secret under a key id
+func (v *Verifier) AddHMACKey(keyID, key string) {
+	v.hmacKeys[keyID] = []byte(key)
+}
+
+// AddPublicKey trusts a base64 encoded ed25519 public key under a key id
+func (v *Verifier) AddPublicKey(keyID, publicKey string) error {
+	raw, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid ed25519 public key for %s", keyID)
+	}
+	v.publicKeys[keyID] = ed25519.PublicKey(raw)
+	return nil
+}
+
+// Verify checks the signature of a published event and returns its envelope
+func (v *Verifier) Verify(payload string) (*Envelope, error) {
+	var message EventMessage
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		return nil, fmt.Errorf("invalid event message: %w", err)
+	}
+	header := func(name string) string {
+		value, _ := message.Headers[name].(string)
+		return value
+	}
+	encoded, algorithm, keyID := header(HeaderSignature), header(HeaderSignatureAlgorithm), header(HeaderSignatureKeyID)
+	if encoded == "" {
+		return nil, ErrUnsigned
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrBadSignature
+	}
+
+	// Numbers keep their literal text so the data encodes back to the signed bytes
+	decoder := json.NewDecoder(bytes.NewReader(message.Data))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid event data: %w", err)
+	}
+	canonical, err := canonicalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var valid bool
+	switch algorithm {
+	case SigningHMACSHA256:
+		key, ok := v.hmacKeys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrBadSignature, keyID)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(canonical)
+		valid = hmac.Equal(signature, mac.Sum(nil))
+	case SigningEd25519:
+		key, ok := v.publicKeys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrBadSignature, keyID)
+		}
+		valid = ed25519.Verify(key, canonical, signature)
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrBadSignature, algorithm)
+	}
+	if !valid {
+		return nil, ErrBadSignature
+	}
+	return decodeEnvelope(payload)
+}
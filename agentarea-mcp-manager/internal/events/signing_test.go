@@ -0,0 +1,100 @@
+package events
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signedEvent(t *testing.T, s *Signer, data any) string {
+	t.Helper()
+	SetSigner(s)
+	t.Cleanup(func() { SetSigner(nil) })
+
+	message, err := encodeEvent(ChannelStatusChanged, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(message)
+}
+
+func TestHMACSignedEvents(t *testing.T) {
+	key := strings.Repeat("k", 32)
+	s, err := NewSigner(SigningHMACSHA256, "manager-1", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HTML characters and large numbers must survive re-encoding by consumers
+	message := signedEvent(t, s, map[string]any{"instance_id": "i-1", "status": "running", "url": "http://a/?x=1&y=<2>", "count": 1000000, "ratio": 0.25})
+
+	verifier := NewVerifier()
+	verifier.AddHMACKey("manager-1", key)
+	envelope, err := verifier.Verify(message)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if envelope.EventType != ChannelStatusChanged {
+		t.Errorf("event type = %s", envelope.EventType)
+	}
+
+	tampered := strings.Replace(message, `"running"`, `"failed"`, 1)
+	if _, err := verifier.Verify(tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered event: err = %v, want ErrBadSignature", err)
+	}
+
+	other := NewVerifier()
+	other.AddHMACKey("manager-1", strings.Repeat("x", 32))
+	if _, err := other.Verify(message); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("wrong key: err = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestEd25519SignedEvents(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSigner(SigningEd25519, "manager-2", base64.StdEncoding.EncodeToString(private.Seed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := signedEvent(t, s, map[string]any{"instance_id": "i-2"})
+
+	verifier := NewVerifier()
+	if err := verifier.AddPublicKey("manager-2", s.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(message); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if _, err := NewVerifier().Verify(message); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("unknown key: err = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestUnsignedEvents(t *testing.T) {
+	message, err := encodeEvent(ChannelStatusChanged, time.Now(), map[string]any{"instance_id": "i-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVerifier().Verify(string(message)); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("err = %v, want ErrUnsigned", err)
+	}
+}
+
+func TestNewSignerRejectsWeakKeys(t *testing.T) {
+	for _, tt := range []struct{ algorithm, keyID, key string }{
+		{SigningHMACSHA256, "m", "short"},
+		{SigningEd25519, "m", "not base64!"},
+		{SigningEd25519, "m", base64.StdEncoding.EncodeToString([]byte("16 bytes of seed"))},
+		{"rsa", "m", strings.Repeat("k", 32)},
+		{SigningHMACSHA256, "", strings.Repeat("k", 32)},
+	} {
+		if _, err := NewSigner(tt.algorithm, tt.keyID, tt.key); err == nil {
+			t.Errorf("NewSigner(%s, %q, %q) accepted", tt.algorithm, tt.keyID, tt.key)
+		}
+	}
+}
@@ -3,75 +3,146 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
-	"strings"
+	"runtime/debug"
+	"time"
 
+	"github.com/agentarea/mcp-manager/internal/metrics"
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/operations"
 	"github.com/agentarea/mcp-manager/internal/providers"
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/redisconn"
 	redis "github.com/go-redis/redis/v8"
 )
 
-// MCPServerInstanceCreated represents the event when an MCP instance is created
-type MCPServerInstanceCreated struct {
-	InstanceID   string         `json:"instance_id"`
-	Name         string         `json:"name"`
-	ServerSpecID string         `json:"server_spec_id,omitempty"`
-	JSONSpec     map[string]any `json:"json_spec"`
-}
-
-// MCPServerInstanceDeleted represents the event when an MCP instance is deleted
-type MCPServerInstanceDeleted struct {
-	InstanceID string `json:"instance_id"`
-	Name       string `json:"name"`
-}
-
 // EventSubscriber handles Redis event subscriptions for MCP events
 type EventSubscriber struct {
-	redisClient     *redis.Client
+	redisClient     redis.UniversalClient
+	redisOptions    *redisconn.Options
 	providerManager *providers.ProviderManager
+	registry        *InstanceRegistry
+	publisher       *EventPublisher
+	createQueue     *createQueue
+	metrics         *pipelineMetrics
+	logPayloads     bool
+	panics          PanicReporter
+	operations      *operations.Tracker
 	logger          *slog.Logger
 }
 
-// NewEventSubscriber creates a new event subscriber
-func NewEventSubscriber(redisURL string, providerManager *providers.ProviderManager, logger *slog.Logger) *EventSubscriber {
-	var opts *redis.Options
-	if parsed, err := redis.ParseURL(redisURL); err == nil {
-		opts = parsed
-	} else {
-		var addr string
-		if cutAddr, found := strings.CutPrefix(redisURL, "redis://"); found {
-			addr = cutAddr
-		} else {
-			addr = redisURL
-		}
-		opts = &redis.Options{Addr: addr}
-	}
+// PanicReporter receives panics recovered in event handlers;
+// *supervisor.Supervisor implements it
+type PanicReporter interface {
+	ReportPanic(component string, value any, stack []byte)
+}
 
-	rdb := redis.NewClient(opts)
+// NewEventSubscriber creates a new event subscriber
+func NewEventSubscriber(redisOptions *redisconn.Options, providerManager *providers.ProviderManager, logger *slog.Logger) *EventSubscriber {
+	rdb := redisOptions.NewClient()
 
-	return &EventSubscriber{
+	s := &EventSubscriber{
 		redisClient:     rdb,
+		redisOptions:    redisOptions,
 		providerManager: providerManager,
+		registry:        NewInstanceRegistry(rdb),
+		publisher:       &EventPublisher{redisClient: rdb, logger: logger},
+		metrics:         newPipelineMetrics(),
 		logger:          logger,
 	}
+	s.createQueue = newCreateQueue(DefaultCreateWorkers, DefaultCreateQueueSize, s.createInstance)
+	return s
+}
+
+// SetCreateConcurrency sizes the creation worker pool; call before Start
+func (s *EventSubscriber) SetCreateConcurrency(workers, queueSize int) {
+	s.createQueue = newCreateQueue(workers, queueSize, s.createInstance)
+}
+
+// CreateQueueStats returns the depth and counters of the creation queue
+func (s *EventSubscriber) CreateQueueStats() CreateQueueStats {
+	return s.createQueue.stats()
+}
+
+// SetLogPayloads logs raw event payloads at Debug; payloads may carry secrets
+// and are large, so this is off by default
+func (s *EventSubscriber) SetLogPayloads(enabled bool) {
+	s.logPayloads = enabled
+}
+
+// SetPanicReporter reports panics recovered in event handlers, which
+// otherwise are only logged
+func (s *EventSubscriber) SetPanicReporter(reporter PanicReporter) {
+	s.panics = reporter
+}
+
+// SetOperations tracks event-driven creations so DELETE /operations/:id can
+// cancel them
+func (s *EventSubscriber) SetOperations(tracker *operations.Tracker) {
+	s.operations = tracker
+}
+
+// recoverPanic is deferred by event handlers so a panic while handling one
+// event does not stop the subscriber or a creation worker
+func (s *EventSubscriber) recoverPanic(component string, onPanic func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	if s.panics != nil {
+		s.panics.ReportPanic(component, r, stack)
+	} else {
+		s.logger.Error("Event handler panicked",
+			slog.String("component", component),
+			slog.Any("panic", r),
+			slog.String("stack", string(stack)))
+	}
+	if onPanic != nil {
+		onPanic()
+	}
+}
+
+// PipelineStats returns the event pipeline counters and Redis connection state
+func (s *EventSubscriber) PipelineStats() PipelineStats {
+	return s.metrics.snapshot()
+}
+
+// CheckRedis pings Redis through the subscriber's client and reports the
+// connection
+func (s *EventSubscriber) CheckRedis(ctx context.Context) redisconn.Health {
+	return s.redisOptions.Check(ctx, s.redisClient)
 }
 
 // Start begins listening for events
 func (s *EventSubscriber) Start(ctx context.Context) error {
 	s.logger.Info("Starting event subscriber")
+	s.metrics.setState(RedisConnecting, false)
+	defer s.metrics.setState(RedisDisconnected, false)
 
 	// Subscribe to MCP events
-	pubsub := s.redisClient.Subscribe(ctx, "MCPServerInstanceCreated", "MCPServerInstanceDeleted")
+	pubsub := s.redisClient.Subscribe(ctx, ChannelInstanceCreated, ChannelInstanceDeleted)
 	defer pubsub.Close()
 
 	// Test Redis connection
 	_, err := s.redisClient.Ping(ctx).Result()
+	s.metrics.connection(time.Now(), err)
 	if err != nil {
 		s.logger.Error("Failed to connect to Redis", slog.String("error", err.Error()))
 		return err
 	}
 
 	s.logger.Info("Connected to Redis, listening for events")
+	s.metrics.setState(RedisConnected, true)
+
+	s.createQueue.run(ctx)
+
+	// The subscription reconnects on its own; pings keep the reported
+	// connection state current while no events arrive
+	ping := time.NewTicker(redisPingInterval)
+	defer ping.Stop()
 
 	// Listen for messages
 	ch := pubsub.Channel()
@@ -80,6 +151,12 @@ func (s *EventSubscriber) Start(ctx context.Context) error {
 		case <-ctx.Done():
 			s.logger.Info("Event subscriber shutting down")
 			return ctx.Err()
+		case <-ping.C:
+			err := s.redisClient.Ping(ctx).Err()
+			if err != nil && ctx.Err() == nil {
+				s.logger.Warn("Redis ping failed", slog.String("error", err.Error()))
+			}
+			s.metrics.connection(time.Now(), err)
 		case msg := <-ch:
 			if msg == nil {
 				continue
@@ -91,188 +168,288 @@ func (s *EventSubscriber) Start(ctx context.Context) error {
 
 // handleMessage processes incoming Redis messages
 func (s *EventSubscriber) handleMessage(ctx context.Context, msg *redis.Message) {
-	s.logger.Info("Received event",
-		slog.String("channel", msg.Channel),
-		slog.String("payload", msg.Payload))
+	if s.logPayloads {
+		s.logger.Debug("Received event",
+			slog.String("channel", msg.Channel),
+			slog.String("payload", msg.Payload))
+	} else {
+		s.logger.Debug("Received event", slog.String("channel", msg.Channel))
+	}
+	start := time.Now()
+	s.metrics.received(msg.Channel, start)
+	defer s.recoverPanic("event-handler", func() {
+		s.metrics.handled(msg.Channel, time.Since(start), false, errors.New("handler panicked"))
+	})
 
+	var err error
 	switch msg.Channel {
-	case "MCPServerInstanceCreated":
-		s.handleInstanceCreated(ctx, msg.Payload)
-	case "MCPServerInstanceDeleted":
-		s.handleInstanceDeleted(ctx, msg.Payload)
+	case ChannelInstanceCreated:
+		err = s.handleInstanceCreated(ctx, msg.Payload)
+	case ChannelInstanceDeleted:
+		err = s.handleInstanceDeleted(ctx, msg.Payload)
 	default:
 		s.logger.Warn("Unknown event channel", slog.String("channel", msg.Channel))
+		return
 	}
-}
 
-// EventMessage represents the wrapper structure from FastStream Redis
-type EventMessage struct {
-	Data    string         `json:"data"`
-	Headers map[string]any `json:"headers"`
+	var payloadErr *PayloadError
+	parseFailed := errors.As(err, &payloadErr)
+	s.metrics.handled(msg.Channel, time.Since(start), parseFailed, err)
+	if parseFailed {
+		s.reject(ctx, msg.Channel, msg.Payload, payloadErr)
+	}
 }
 
-// EventData represents the inner event data structure
-type EventData struct {
-	EventID   string         `json:"event_id"`
-	Timestamp string         `json:"timestamp"`
-	EventType string         `json:"event_type"`
-	Data      map[string]any `json:"data"`
+// reject quarantines a malformed payload and publishes an EventRejected event
+func (s *EventSubscriber) reject(ctx context.Context, channel, payload string, payloadErr *PayloadError) {
+	rejected := EventRejected{
+		Channel:    channel,
+		InstanceID: payloadErr.InstanceID,
+		Reason:     payloadErr.Reason,
+		Timestamp:  time.Now(),
+	}
+	if payloadErr.Envelope != nil {
+		rejected.EventID = payloadErr.Envelope.EventID
+		rejected.SchemaVersion = payloadErr.Envelope.SchemaVersion
+	}
+
+	s.logger.Error("Rejected malformed event",
+		slog.String("channel", channel),
+		slog.String("event_id", rejected.EventID),
+		slog.String("instance_id", rejected.InstanceID),
+		slog.String("reason", rejected.Reason))
+
+	record, err := json.Marshal(map[string]any{"rejection": rejected, "payload": redact.String(payload)})
+	if err == nil {
+		pipe := s.redisClient.TxPipeline()
+		pipe.LPush(ctx, quarantineKey, record)
+		pipe.LTrim(ctx, quarantineKey, 0, quarantineMax-1)
+		if _, err = pipe.Exec(ctx); err != nil {
+			s.logger.Error("Failed to quarantine event", slog.String("error", err.Error()))
+		}
+	}
+
+	message, err := encodeEvent(ChannelEventRejected, rejected.Timestamp, rejected)
+	if err != nil {
+		return
+	}
+	if err := s.redisClient.Publish(ctx, ChannelEventRejected, string(message)).Err(); err != nil {
+		metrics.EventPublishFailed(ChannelEventRejected)
+		s.logger.Error("Failed to publish rejected event", slog.String("error", err.Error()))
+	}
 }
 
 // handleInstanceCreated processes MCP instance creation events
-func (s *EventSubscriber) handleInstanceCreated(ctx context.Context, payload string) {
-	s.logger.Info("Raw payload received", slog.String("payload", payload))
-
-	// First unmarshal the outer FastStream message structure
-	var message EventMessage
-	if err := json.Unmarshal([]byte(payload), &message); err != nil {
-		s.logger.Error("Failed to unmarshal event message",
-			slog.String("error", err.Error()),
-			slog.String("payload", payload))
-		return
+func (s *EventSubscriber) handleInstanceCreated(ctx context.Context, payload string) error {
+	var event MCPServerInstanceCreated
+	envelope, err := decodeEvent(payload, &event)
+	if err != nil {
+		return err
 	}
 
-	s.logger.Info("Outer message parsed",
-		slog.String("data", message.Data),
-		slog.Any("headers", message.Headers))
+	s.logger.Info("Queueing MCP instance creation",
+		slog.String("event_id", envelope.EventID),
+		slog.Int("schema_version", envelope.SchemaVersion),
+		slog.String("instance_id", event.InstanceID),
+		slog.String("name", event.Name))
 
-	// Then unmarshal the inner event data (message.Data is a JSON string)
-	var eventData EventData
-	if err := json.Unmarshal([]byte(message.Data), &eventData); err != nil {
-		s.logger.Error("Failed to unmarshal event data",
-			slog.String("error", err.Error()),
-			slog.String("data", message.Data))
-		return
+	// Create MCP server instance model
+	instance := &models.MCPServerInstance{
+		InstanceID:   event.InstanceID,
+		Name:         event.Name,
+		ServerSpecID: event.ServerSpecID,
+		JSONSpec:     event.JSONSpec,
+		Status:       "pending",
 	}
 
-	s.logger.Info("Parsed event data structure",
-		slog.String("event_id", eventData.EventID),
-		slog.String("event_type", eventData.EventType),
-		slog.Any("data_keys", getMapKeys(eventData.Data)),
-		slog.Any("full_data", eventData.Data))
+	providerType := providers.TypeOf(instance)
+	if _, err := s.providerManager.ProviderByType(providerType); err != nil {
+		s.logger.Error("Failed to get provider",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+		return nil
+	}
 
-	// Extract the actual event fields from the data
-	instanceID, instanceOK := eventData.Data["instance_id"].(string)
-	name, nameOK := eventData.Data["name"].(string)
-	serverSpecID, serverSpecOK := eventData.Data["server_spec_id"].(string)
-	jsonSpecInterface, jsonSpecOK := eventData.Data["json_spec"]
+	// Record the provider before creating so a later deletion reaches it even
+	// if creation is still queued or only partially succeeds
+	if err := s.registry.Record(ctx, event.InstanceID, providerType); err != nil {
+		s.logger.Warn("Failed to record instance provider",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+	}
 
-	var jsonSpec map[string]any
-	if jsonSpecInterface != nil {
-		jsonSpec, _ = jsonSpecInterface.(map[string]any)
+	if err := s.publisher.PublishQueued(ctx, event.InstanceID, event.Name); err != nil {
+		s.logger.Error("Failed to publish queued status", slog.String("error", err.Error()))
 	}
 
-	s.logger.Info("Extracted event data",
-		slog.String("instance_id", instanceID),
-		slog.Bool("instance_id_ok", instanceOK),
-		slog.String("name", name),
-		slog.Bool("name_ok", nameOK),
-		slog.String("server_spec_id", serverSpecID),
-		slog.Bool("server_spec_id_ok", serverSpecOK),
-		slog.Any("json_spec_raw", jsonSpecInterface),
-		slog.Bool("json_spec_ok", jsonSpecOK),
-		slog.Any("json_spec_parsed", jsonSpec))
+	workspaceID, _ := event.JSONSpec["workspace_id"].(string)
+	job := &createJob{workspaceID: workspaceID, providerType: providerType, instance: instance}
+	if err := s.createQueue.enqueue(ctx, job); err != nil {
+		s.logger.Error("Failed to queue MCP instance creation",
+			slog.String("instance_id", event.InstanceID),
+			slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// createInstance runs a queued instance creation on a worker
+func (s *EventSubscriber) createInstance(ctx context.Context, job *createJob) {
+	instance := job.instance
+	defer s.recoverPanic("create-worker", func() {
+		// The instance would otherwise stay pending in the Core API
+		_ = s.publisher.PublishFailed(ctx, instance.InstanceID, instance.Name, "internal error while creating the instance")
+	})
 
 	s.logger.Info("Processing MCP instance creation",
-		slog.String("instance_id", instanceID),
-		slog.String("name", name),
-		slog.Any("json_spec", jsonSpec))
+		slog.String("instance_id", instance.InstanceID),
+		slog.String("workspace_id", job.workspaceID),
+		slog.Duration("queued_for", time.Since(job.enqueued)))
 
-	// Create MCP server instance model
-	instance := &models.MCPServerInstance{
-		InstanceID:   instanceID,
-		Name:         name,
-		ServerSpecID: serverSpecID,
-		JSONSpec:     jsonSpec,
-		Status:       "pending",
+	provider, err := s.providerManager.ProviderByType(job.providerType)
+	if err != nil {
+		s.logger.Error("Failed to get provider",
+			slog.String("instance_id", instance.InstanceID),
+			slog.String("error", err.Error()))
+		return
 	}
 
-	// Get the appropriate provider and create the instance
-	provider, err := s.providerManager.GetProvider(instance)
+	ctx, op, done, err := s.operations.Start(ctx, operations.Operation{
+		Kind:       operations.KindCreate,
+		Source:     operations.SourceEvent,
+		InstanceID: instance.InstanceID,
+		Name:       instance.Name,
+	})
 	if err != nil {
-		s.logger.Error("Failed to get provider",
-			slog.String("instance_id", instanceID),
+		s.logger.Error("Failed to track MCP instance creation",
+			slog.String("instance_id", instance.InstanceID),
 			slog.String("error", err.Error()))
 		return
 	}
+	defer done()
 
-	if err := provider.CreateInstance(ctx, instance); err != nil {
+	start := time.Now()
+	err = provider.CreateInstance(ctx, instance)
+	metrics.ObserveInstanceOperation("create", metrics.SourceEvent, start, err)
+	if err != nil && operations.Cancelled(ctx) {
+		s.rollbackCreate(context.WithoutCancel(ctx), provider, op)
+		return
+	}
+	if err != nil {
 		s.logger.Error("Failed to create MCP instance",
-			slog.String("instance_id", instanceID),
+			slog.String("instance_id", instance.InstanceID),
 			slog.String("error", err.Error()))
 	} else {
 		s.logger.Info("Successfully created MCP instance",
-			slog.String("instance_id", instanceID))
+			slog.String("instance_id", instance.InstanceID))
 	}
 }
 
-// handleInstanceDeleted processes MCP instance deletion events
-func (s *EventSubscriber) handleInstanceDeleted(ctx context.Context, payload string) {
-	// First unmarshal the outer FastStream message structure
-	var message EventMessage
-	if err := json.Unmarshal([]byte(payload), &message); err != nil {
-		s.logger.Error("Failed to unmarshal event message",
-			slog.String("error", err.Error()),
-			slog.String("payload", payload))
-		return
+// rollbackCreate removes what a cancelled creation left behind and publishes
+// that it was cancelled
+func (s *EventSubscriber) rollbackCreate(ctx context.Context, provider providers.Provider, op operations.Operation) {
+	s.logger.Info("MCP instance creation cancelled, rolling back",
+		slog.String("instance_id", op.InstanceID),
+		slog.String("operation_id", op.ID))
+
+	if err := provider.DeleteInstance(ctx, op.InstanceID, op.Name); err != nil {
+		s.logger.Warn("Failed to remove partially created MCP instance",
+			slog.String("instance_id", op.InstanceID),
+			slog.String("error", err.Error()))
+	}
+	if err := s.publisher.PublishCancelled(ctx, op.InstanceID, op.Name, "operation "+op.ID+" cancelled"); err != nil {
+		s.logger.Error("Failed to publish cancelled status", slog.String("error", err.Error()))
 	}
+}
 
-	// Then unmarshal the inner event data
-	var eventData EventData
-	if err := json.Unmarshal([]byte(message.Data), &eventData); err != nil {
-		s.logger.Error("Failed to unmarshal event data",
-			slog.String("error", err.Error()),
-			slog.String("data", message.Data))
-		return
+// handleInstanceDeleted processes MCP instance deletion events
+func (s *EventSubscriber) handleInstanceDeleted(ctx context.Context, payload string) error {
+	var event MCPServerInstanceDeleted
+	envelope, err := decodeEvent(payload, &event)
+	if err != nil {
+		return err
 	}
 
-	// Extract the actual event fields from the data
-	instanceID, _ := eventData.Data["instance_id"].(string)
+	instanceID, name := event.InstanceID, event.Name
 
 	s.logger.Info("Processing MCP instance deletion",
+		slog.String("event_id", envelope.EventID),
 		slog.String("instance_id", instanceID))
 
-	// Extract name from event data for deletion
-	name, _ := eventData.Data["name"].(string)
-
-	// For deletion, we need to determine which provider to use
-	// Since we don't have the full instance data, we'll try both providers
-	// In a production system, you might want to store provider type in a registry
+	// A creation that has not started yet only needs to be dropped from the queue
+	if s.createQueue.cancel(instanceID) {
+		s.logger.Info("Cancelled queued MCP instance creation",
+			slog.String("instance_id", instanceID))
+	}
 
-	// Try Docker provider first
-	dockerProvider, _ := s.providerManager.GetProvider(&models.MCPServerInstance{
-		JSONSpec: map[string]any{"type": "docker"},
-	})
-	if err := dockerProvider.DeleteInstance(ctx, instanceID, name); err != nil {
-		s.logger.Debug("Docker provider deletion failed (may not be docker type)",
+	start := time.Now()
+	var deleteErr error
+	providerType, known, err := s.registry.Lookup(ctx, instanceID)
+	if err != nil {
+		s.logger.Warn("Failed to look up instance provider",
 			slog.String("instance_id", instanceID),
 			slog.String("error", err.Error()))
 	}
+	if known {
+		provider, err := s.providerManager.ProviderByType(providerType)
+		if err != nil {
+			deleteErr = err
+		} else {
+			deleteErr = provider.DeleteInstance(ctx, instanceID, name)
+		}
+	} else {
+		deleteErr = s.deleteUnregistered(ctx, instanceID, name)
+	}
+	metrics.ObserveInstanceOperation("delete", metrics.SourceEvent, start, deleteErr)
 
-	// Try URL provider
-	urlProvider, _ := s.providerManager.GetProvider(&models.MCPServerInstance{
-		JSONSpec: map[string]any{"type": "url"},
-	})
-	if err := urlProvider.DeleteInstance(ctx, instanceID, name); err != nil {
-		s.logger.Debug("URL provider deletion failed (may not be URL type)",
+	if deleteErr != nil {
+		s.logger.Error("Failed to delete MCP instance",
+			slog.String("instance_id", instanceID),
+			slog.String("provider", providerType),
+			slog.String("error", deleteErr.Error()))
+		if err := s.publisher.PublishDeleteFailed(ctx, instanceID, name, deleteErr.Error()); err != nil {
+			s.logger.Error("Failed to publish delete failed status", slog.String("error", err.Error()))
+		}
+		return nil
+	}
+
+	if err := s.registry.Forget(ctx, instanceID); err != nil {
+		s.logger.Warn("Failed to forget instance provider",
 			slog.String("instance_id", instanceID),
 			slog.String("error", err.Error()))
 	}
 
 	s.logger.Info("Processed MCP instance deletion",
+		slog.String("instance_id", instanceID),
+		slog.String("provider", providerType))
+
+	if err := s.publisher.PublishDeleted(ctx, instanceID, name); err != nil {
+		s.logger.Error("Failed to publish deleted status", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// deleteUnregistered handles instances created before the provider registry
+// existed by trying every available provider. It succeeds if any provider does.
+func (s *EventSubscriber) deleteUnregistered(ctx context.Context, instanceID, name string) error {
+	s.logger.Warn("Instance provider unknown, trying all providers",
 		slog.String("instance_id", instanceID))
+
+	var errs []error
+	for _, providerType := range []string{providers.TypeDocker, providers.TypeURL} {
+		provider, err := s.providerManager.ProviderByType(providerType)
+		if err != nil {
+			continue
+		}
+		if err := provider.DeleteInstance(ctx, instanceID, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", providerType, err))
+			continue
+		}
+		return nil
+	}
+	return errors.Join(errs...)
 }
 
 // Close closes the Redis connection
 func (s *EventSubscriber) Close() error {
 	return s.redisClient.Close()
 }
-
-// Helper function to get map keys for debugging
-func getMapKeys(m map[string]any) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
-}
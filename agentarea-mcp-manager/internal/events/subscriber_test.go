@@ -0,0 +1,29 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/providers"
+	"github.com/agentarea/mcp-manager/internal/redisconn"
+)
+
+func TestHandleInstanceCreatedWithoutPort(t *testing.T) {
+	// Nothing listens on the Redis address; recording the provider and
+	// publishing the queued status fail without stopping the creation
+	manager := providers.NewProviderManager(&providers.DockerProvider{}, nil)
+	s := NewEventSubscriber(redisconn.FromURL("127.0.0.1:1"), manager, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer s.Close()
+
+	payload := fastStreamMessage(t, map[string]any{
+		"data": map[string]any{"instance_id": "i-1", "name": "echo", "json_spec": map[string]any{"image": "mcp/echo:latest"}},
+	})
+	if err := s.handleInstanceCreated(context.Background(), payload); err != nil {
+		t.Fatalf("expected a spec without a port to be accepted, got %v", err)
+	}
+	if stats := s.CreateQueueStats(); stats.Depth != 1 {
+		t.Fatalf("expected the creation to be queued, got %+v", stats)
+	}
+}
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/store"
+)
+
+// Lifecycle steps recorded by the manager itself; published statuses
+// (queued, validating, starting, running, unhealthy, restarting, failed,
+// deleted, ...) and route_published are recorded under their own names
+const (
+	StepValidated = "validated"
+	StepPulled    = "pulled"
+	StepStarted   = "started"
+	StepHealthy   = "healthy"
+	StepRestarted = "restarted"
+	StepUpdated   = "updated"
+	StepDeleted   = "deleted"
+
+	stepRoutePublished = "route_published"
+)
+
+// timelineAction prefixes the audit action of timeline entries
+const timelineAction = "lifecycle."
+
+// timelineQueueSize bounds the steps waiting to be persisted
+const timelineQueueSize = 1024
+
+// TimelineStep is one lifecycle step of an instance
+type TimelineStep struct {
+	InstanceID string    `json:"instance_id,omitempty"`
+	Name       string    `json:"name"` // Service name
+	Step       string    `json:"step"`
+	Message    string    `json:"message,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// timelineHandler receives the steps of every publisher; nil drops them
+var timelineHandler atomic.Pointer[func(TimelineStep)]
+
+// SetTimelineHandler passes every lifecycle step recorded from now on, by
+// all publishers, to handle, which must not block. nil stops passing them.
+func SetTimelineHandler(handle func(TimelineStep)) {
+	if handle == nil {
+		timelineHandler.Store(nil)
+		return
+	}
+	timelineHandler.Store(&handle)
+}
+
+// RecordStep records a lifecycle step that has no published event
+func RecordStep(instanceID, name, step, message string) {
+	recordStep(TimelineStep{InstanceID: instanceID, Name: name, Step: step, Message: message, Timestamp: time.Now()})
+}
+
+// recordStep passes step to the timeline handler, if any. Steps are recorded
+// whether or not their event reached Redis.
+func recordStep(step TimelineStep) {
+	if handle := timelineHandler.Load(); handle != nil && step.Name != "" {
+		(*handle)(step)
+	}
+}
+
+// TimelineRecorder persists lifecycle steps in the audit log, keyed by
+// service name so the timeline survives recreations and deletion
+type TimelineRecorder struct {
+	audit  store.AuditStore
+	logger *slog.Logger
+	queue  chan TimelineStep
+}
+
+// NewTimelineRecorder creates a recorder writing to audit
+func NewTimelineRecorder(audit store.AuditStore, logger *slog.Logger) *TimelineRecorder {
+	return &TimelineRecorder{
+		audit:  audit,
+		logger: logger,
+		queue:  make(chan TimelineStep, timelineQueueSize),
+	}
+}
+
+// HandleStep queues a step for Run without blocking
+func (r *TimelineRecorder) HandleStep(step TimelineStep) {
+	select {
+	case r.queue <- step:
+	default:
+		r.logger.Warn("Timeline queue full, dropping lifecycle step",
+			slog.String("name", step.Name),
+			slog.String("step", step.Step))
+	}
+}
+
+// Run persists queued steps until ctx is done
+func (r *TimelineRecorder) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case step := <-r.queue:
+			r.persist(ctx, step)
+		}
+	}
+}
+
+type timelineDetails struct {
+	InstanceID string `json:"instance_id,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+func (r *TimelineRecorder) persist(ctx context.Context, step TimelineStep) {
+	details, err := json.Marshal(timelineDetails{InstanceID: step.InstanceID, Message: redact.String(step.Message)})
+	if err != nil {
+		return
+	}
+	_, err = r.audit.Append(ctx, store.AuditEntry{
+		Time:    step.Timestamp,
+		Actor:   "manager",
+		Action:  timelineAction + step.Step,
+		Target:  step.Name,
+		Details: details,
+	})
+	if err != nil {
+		r.logger.Warn("Failed to persist lifecycle step",
+			slog.String("name", step.Name),
+			slog.String("step", step.Step),
+			slog.String("error", err.Error()))
+	}
+}
+
+// Timeline returns up to limit of the latest steps of a service, oldest
+// first; 0 returns all
+func (r *TimelineRecorder) Timeline(ctx context.Context, name string, limit int) ([]TimelineStep, error) {
+	entries, err := r.audit.List(ctx, store.AuditQuery{Target: name})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []TimelineStep{}
+	for _, entry := range entries {
+		step, ok := stepFromEntry(entry)
+		if !ok {
+			continue
+		}
+		steps = append(steps, step)
+		if limit > 0 && len(steps) == limit {
+			break
+		}
+	}
+	// Entries are listed newest first
+	slices.Reverse(steps)
+	return steps, nil
+}
+
+// Recent returns the steps of all services recorded since the given time,
+// newest first
+func (r *TimelineRecorder) Recent(ctx context.Context, since time.Time) ([]TimelineStep, error) {
+	entries, err := r.audit.List(ctx, store.AuditQuery{Since: since})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []TimelineStep{}
+	for _, entry := range entries {
+		if step, ok := stepFromEntry(entry); ok {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// stepFromEntry reads a lifecycle step from its audit entry; ok is false for
+// other entries
+func stepFromEntry(entry store.AuditEntry) (TimelineStep, bool) {
+	step, ok := strings.CutPrefix(entry.Action, timelineAction)
+	if !ok {
+		return TimelineStep{}, false
+	}
+	var details timelineDetails
+	_ = json.Unmarshal(entry.Details, &details)
+	return TimelineStep{
+		InstanceID: details.InstanceID,
+		Name:       entry.Target,
+		Step:       step,
+		Message:    details.Message,
+		Timestamp:  entry.Time,
+	}, true
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/store"
+)
+
+func TestTimelineOrdersStepsOfOneService(t *testing.T) {
+	ctx := context.Background()
+	audit := store.NewMemory().Audit()
+	recorder := NewTimelineRecorder(audit, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	start := time.Now()
+	for i, step := range []TimelineStep{
+		{InstanceID: "i-1", Name: "svc", Step: "validating"},
+		{InstanceID: "i-1", Name: "svc", Step: StepValidated},
+		{InstanceID: "i-2", Name: "other", Step: StepValidated},
+		{InstanceID: "i-1", Name: "svc", Step: StepStarted},
+		{InstanceID: "i-1", Name: "svc", Step: "running"},
+	} {
+		step.Timestamp = start.Add(time.Duration(i) * time.Second)
+		recorder.persist(ctx, step)
+	}
+	// Other audit entries of the service are not lifecycle steps
+	if _, err := audit.Append(ctx, store.AuditEntry{Time: start, Actor: "admin", Action: "instance.update", Target: "svc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	steps, err := recorder.Timeline(ctx, "svc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, step := range steps {
+		got = append(got, step.Step)
+	}
+	if want := []string{"validating", StepValidated, StepStarted, "running"}; !slices.Equal(got, want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+	if steps[0].InstanceID != "i-1" || !steps[1].Timestamp.After(steps[0].Timestamp) {
+		t.Fatalf("unexpected first steps: %+v", steps[:2])
+	}
+
+	// The limit keeps the latest steps
+	steps, err = recorder.Timeline(ctx, "svc", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Step != StepStarted || steps[1].Step != "running" {
+		t.Fatalf("limited steps = %+v", steps)
+	}
+}
+
+func TestRecordStepPassesStepsToHandler(t *testing.T) {
+	var got []TimelineStep
+	SetTimelineHandler(func(step TimelineStep) { got = append(got, step) })
+	defer SetTimelineHandler(nil)
+
+	RecordStep("i-1", "svc", StepRestarted, "container-id")
+	RecordStep("i-1", "", StepRestarted, "") // Steps need a service name
+
+	if len(got) != 1 || got[0].Name != "svc" || got[0].Step != StepRestarted || got[0].Timestamp.IsZero() {
+		t.Fatalf("recorded steps = %+v", got)
+	}
+}
+
+func TestTimelineRecentSpansServices(t *testing.T) {
+	ctx := context.Background()
+	audit := store.NewMemory().Audit()
+	recorder := NewTimelineRecorder(audit, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	start := time.Now().Add(-time.Hour)
+	for i, step := range []TimelineStep{
+		{InstanceID: "i-1", Name: "svc", Step: StepStarted},
+		{InstanceID: "i-1", Name: "svc", Step: StepRestarted},
+		{InstanceID: "i-2", Name: "other", Step: "failed", Message: "image not found"},
+	} {
+		step.Timestamp = start.Add(time.Duration(i) * 20 * time.Minute)
+		recorder.persist(ctx, step)
+	}
+
+	steps, err := recorder.Recent(ctx, start.Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("recent steps = %+v", steps)
+	}
+	if steps[0].Name != "other" || steps[0].Message != "image not found" || steps[1].Step != StepRestarted {
+		t.Fatalf("recent steps are not newest first: %+v", steps)
+	}
+}
@@ -0,0 +1,226 @@
+// Package federation aggregates the instances of several managers, for
+// organizations running one manager per region or cluster. A manager
+// configured with peers queries their APIs and labels every instance with
+// the manager it came from.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+)
+
+// OriginLabel is added to every federated instance with the name of the
+// manager serving it
+const OriginLabel = "agentarea.io/origin"
+
+// maxResponseSize bounds a peer's instance list
+const maxResponseSize = 32 << 20
+
+// Peer statuses
+const (
+	PeerOK          = "ok"
+	PeerUnreachable = "unreachable"
+)
+
+// Peer is another manager whose instances are aggregated
+type Peer struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// ParsePeers parses name=url entries, e.g. eu=https://mcp-eu.example.com
+func ParsePeers(entries []string) ([]Peer, error) {
+	peers := make([]Peer, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name, rawURL, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name, rawURL = strings.TrimSpace(name), strings.TrimSpace(rawURL)
+		if !ok || name == "" || rawURL == "" {
+			return nil, fmt.Errorf("invalid federation peer %q: use name=url", entry)
+		}
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid federation peer %q: url must be http(s)://host", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate federation peer %q", name)
+		}
+		seen[name] = true
+		peers = append(peers, Peer{Name: name, URL: strings.TrimRight(rawURL, "/")})
+	}
+	return peers, nil
+}
+
+// Instance is an instance with the manager it came from
+type Instance struct {
+	Origin string `json:"origin"`
+	*backends.InstanceStatus
+}
+
+// PeerStatus is the outcome of querying one manager
+type PeerStatus struct {
+	Name      string `json:"name"`
+	URL       string `json:"url,omitempty"` // Empty for the local manager
+	Status    string `json:"status"`
+	Health    string `json:"health,omitempty"` // Overall status reported by the manager's /health
+	Version   string `json:"version,omitempty"`
+	Instances int    `json:"instances"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// View is the aggregated instance list of all managers
+type View struct {
+	Instances []Instance   `json:"instances"`
+	Total     int          `json:"total"`
+	Peers     []PeerStatus `json:"peers"`
+	Partial   bool         `json:"partial"` // Some peer could not be queried
+}
+
+// Client queries the peers of a federation
+type Client struct {
+	peers      []Peer
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for peers. A non-empty token is sent to them
+// as a bearer token, for peers behind an authenticating gateway.
+func NewClient(peers []Peer, token string, timeout time.Duration) *Client {
+	return &Client{peers: peers, token: token, httpClient: &http.Client{Timeout: timeout}}
+}
+
+// Peers returns the configured peers
+func (c *Client) Peers() []Peer {
+	return c.peers
+}
+
+// Aggregate lists the instances of every peer next to the local ones, each
+// labelled with its origin. query is passed on to the peers' /instances
+// (e.g. label selectors). Peers are queried concurrently; one that fails is
+// reported in the peer list and the view is marked partial.
+func (c *Client) Aggregate(ctx context.Context, local PeerStatus, localInstances []*backends.InstanceStatus, query url.Values) View {
+	view := View{Instances: []Instance{}, Peers: []PeerStatus{local}}
+	view.Instances = append(view.Instances, labelled(local.Name, localInstances)...)
+
+	results := make([]peerResult, len(c.peers))
+	var wg sync.WaitGroup
+	for i, peer := range c.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.query(ctx, peer, query)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		view.Peers = append(view.Peers, result.status)
+		if result.status.Status != PeerOK {
+			view.Partial = true
+			continue
+		}
+		view.Instances = append(view.Instances, labelled(result.status.Name, result.instances)...)
+	}
+
+	sort.SliceStable(view.Instances, func(i, j int) bool {
+		if view.Instances[i].Origin != view.Instances[j].Origin {
+			return view.Instances[i].Origin < view.Instances[j].Origin
+		}
+		return view.Instances[i].ID < view.Instances[j].ID
+	})
+	view.Total = len(view.Instances)
+	return view
+}
+
+// peerResult is the answer of one peer
+type peerResult struct {
+	status    PeerStatus
+	instances []*backends.InstanceStatus
+}
+
+// query fetches the health and instances of a peer
+func (c *Client) query(ctx context.Context, peer Peer, query url.Values) peerResult {
+	start := time.Now()
+	result := peerResult{status: PeerStatus{Name: peer.Name, URL: peer.URL, Status: PeerOK}}
+
+	var health struct {
+		Status  string `json:"status"`
+		Version string `json:"version"`
+	}
+	if err := c.get(ctx, peer.URL+"/health", true, &health); err == nil {
+		result.status.Health, result.status.Version = health.Status, health.Version
+	}
+
+	path := peer.URL + "/instances"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var list struct {
+		Instances []*backends.InstanceStatus `json:"instances"`
+	}
+	if err := c.get(ctx, path, false, &list); err != nil {
+		result.status.Status = PeerUnreachable
+		result.status.Error = err.Error()
+	} else {
+		result.instances = list.Instances
+		result.status.Instances = len(list.Instances)
+	}
+	result.status.LatencyMS = time.Since(start).Milliseconds()
+	return result
+}
+
+// get decodes the JSON answer of a peer endpoint. With degraded set, a 503
+// answer, as /health gives for an unhealthy manager, is decoded as well.
+func (c *Client) get(ctx context.Context, endpoint string, degraded bool, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && (!degraded || resp.StatusCode != http.StatusServiceUnavailable) {
+		return fmt.Errorf("%s answered %d", endpoint, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(out); err != nil {
+		return fmt.Errorf("invalid answer from %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// labelled wraps instances with their origin and adds the origin label
+func labelled(origin string, instances []*backends.InstanceStatus) []Instance {
+	wrapped := make([]Instance, 0, len(instances))
+	for _, instance := range instances {
+		if instance == nil {
+			continue
+		}
+		copied := *instance
+		labels := make(map[string]string, len(copied.Labels)+1)
+		for key, value := range copied.Labels {
+			labels[key] = value
+		}
+		labels[OriginLabel] = origin
+		copied.Labels = labels
+		wrapped = append(wrapped, Instance{Origin: origin, InstanceStatus: &copied})
+	}
+	return wrapped
+}
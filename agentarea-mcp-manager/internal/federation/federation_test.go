@@ -0,0 +1,89 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+)
+
+func TestParsePeers(t *testing.T) {
+	peers, err := ParsePeers([]string{"eu=https://mcp-eu.example.com/", " us = http://10.0.0.2:8000 "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Peer{{Name: "eu", URL: "https://mcp-eu.example.com"}, {Name: "us", URL: "http://10.0.0.2:8000"}}
+	if len(peers) != len(want) || peers[0] != want[0] || peers[1] != want[1] {
+		t.Fatalf("peers = %+v, want %+v", peers, want)
+	}
+
+	for _, entries := range [][]string{
+		{"https://mcp-eu.example.com"},
+		{"eu="},
+		{"eu=ftp://mcp-eu.example.com"},
+		{"eu=https://a.example.com", "eu=https://b.example.com"},
+	} {
+		if _, err := ParsePeers(entries); err == nil {
+			t.Errorf("ParsePeers(%q) succeeded, want error", entries)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	var gotQuery, gotAuth string
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "version": "1.2.0"})
+		case "/instances":
+			gotQuery, gotAuth = r.URL.RawQuery, r.Header.Get("Authorization")
+			json.NewEncoder(w).Encode(map[string]any{"instances": []*backends.InstanceStatus{
+				{ID: "b", Name: "remote", Status: "running", Labels: map[string]string{"team": "ml"}},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer peer.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	client := NewClient([]Peer{{Name: "eu", URL: peer.URL}, {Name: "us", URL: down.URL}}, "secret", time.Second)
+	localInstance := &backends.InstanceStatus{ID: "a", Name: "local", Status: "running"}
+	view := client.Aggregate(context.Background(),
+		PeerStatus{Name: "home", Status: PeerOK, Instances: 1},
+		[]*backends.InstanceStatus{localInstance},
+		url.Values{"label": {"team=ml"}})
+
+	if gotQuery != "label=team%3Dml" || gotAuth != "Bearer secret" {
+		t.Errorf("peer got query %q and auth %q", gotQuery, gotAuth)
+	}
+	if !view.Partial || view.Total != 2 || len(view.Peers) != 3 {
+		t.Fatalf("view = %+v, want partial with 2 instances and 3 peers", view)
+	}
+	if view.Instances[0].Origin != "eu" || view.Instances[0].Labels[OriginLabel] != "eu" || view.Instances[0].Labels["team"] != "ml" {
+		t.Errorf("remote instance = %+v", view.Instances[0])
+	}
+	if view.Instances[1].Origin != "home" || view.Instances[1].Labels[OriginLabel] != "home" {
+		t.Errorf("local instance = %+v", view.Instances[1])
+	}
+	if localInstance.Labels != nil {
+		t.Error("local instance labels were modified")
+	}
+
+	eu, us := view.Peers[1], view.Peers[2]
+	if eu.Status != PeerOK || eu.Health != "unhealthy" || eu.Version != "1.2.0" || eu.Instances != 1 {
+		t.Errorf("eu status = %+v", eu)
+	}
+	if us.Status != PeerUnreachable || us.Error == "" {
+		t.Errorf("us status = %+v", us)
+	}
+}
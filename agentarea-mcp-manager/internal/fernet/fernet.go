@@ -0,0 +1,202 @@
+// Package fernet implements Fernet tokens (AES-128-CBC with HMAC-SHA256), the
+// format the core platform uses for encrypted secrets, and key rings that
+// allow keys to be rotated.
+package fernet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	version    = 0x80
+	headerSize = 1 + 8 + aes.BlockSize // Version | Timestamp | IV
+	macSize    = sha256.Size
+)
+
+// ErrInvalidToken is returned when no key can verify a token
+var ErrInvalidToken = errors.New("invalid fernet token")
+
+// Key is a Fernet key: a 16-byte signing key followed by a 16-byte encryption key
+type Key [32]byte
+
+// ParseKey decodes a base64url-encoded 32-byte Fernet key
+func ParseKey(encoded string) (*Key, error) {
+	raw, err := base64.URLEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fernet key: %w", err)
+	}
+	if len(raw) != len(Key{}) {
+		return nil, fmt.Errorf("invalid fernet key length: got %d bytes, expected 32", len(raw))
+	}
+	var key Key
+	copy(key[:], raw)
+	return &key, nil
+}
+
+// GenerateKey returns a new random key
+func GenerateKey() (*Key, error) {
+	var key Key
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Encode returns the base64url form of the key
+func (k *Key) Encode() string {
+	return base64.URLEncoding.EncodeToString(k[:])
+}
+
+func (k *Key) signingKey() []byte    { return k[:16] }
+func (k *Key) encryptionKey() []byte { return k[16:] }
+
+// Encrypt returns a Fernet token for plaintext
+func (k *Key) Encrypt(plaintext []byte) (string, error) {
+	return k.encryptAt(plaintext, time.Now())
+}
+
+func (k *Key) encryptAt(plaintext []byte, now time.Time) (string, error) {
+	block, err := aes.NewCipher(k.encryptionKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := make([]byte, len(plaintext)+padding)
+	copy(padded, plaintext)
+	for i := len(plaintext); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+
+	token := make([]byte, headerSize+len(padded), headerSize+len(padded)+macSize)
+	token[0] = version
+	binary.BigEndian.PutUint64(token[1:9], uint64(now.Unix()))
+	iv := token[9:headerSize]
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(token[headerSize:], padded)
+
+	h := hmac.New(sha256.New, k.signingKey())
+	h.Write(token)
+	token = h.Sum(token)
+
+	return base64.URLEncoding.EncodeToString(token), nil
+}
+
+// Decrypt verifies a Fernet token and returns its plaintext
+func (k *Key) Decrypt(token string) ([]byte, error) {
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("base64 decode failed: %w", err)
+	}
+	if len(data) < headerSize+aes.BlockSize+macSize {
+		return nil, errors.New("encrypted data too short")
+	}
+	if data[0] != version {
+		return nil, fmt.Errorf("unsupported Fernet version: %x", data[0])
+	}
+
+	body, mac := data[:len(data)-macSize], data[len(data)-macSize:]
+	h := hmac.New(sha256.New, k.signingKey())
+	h.Write(body)
+	if !hmac.Equal(mac, h.Sum(nil)) {
+		return nil, ErrInvalidToken
+	}
+
+	ciphertext := body[headerSize:]
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("ciphertext is not a multiple of the block size")
+	}
+	block, err := aes.NewCipher(k.encryptionKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, body[9:headerSize]).CryptBlocks(plaintext, ciphertext)
+
+	return removePadding(plaintext)
+}
+
+// removePadding strips PKCS7 padding
+func removePadding(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty data")
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid padding bytes")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
+
+// Keyring encrypts with its primary (first) key and decrypts with any key,
+// so a new key can be introduced before data encrypted with the old one is
+// rewritten
+type Keyring struct {
+	keys []*Key
+}
+
+// NewKeyring creates a key ring; keys[0] is the primary key
+func NewKeyring(keys ...*Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("key ring needs at least one key")
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// ParseKeyring parses comma-separated base64url keys, primary first
+func ParseKeyring(encoded string) (*Keyring, error) {
+	var keys []*Key
+	for i, part := range strings.Split(encoded, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, err := ParseKey(part)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyring(keys...)
+}
+
+// Encrypt encrypts with the primary key
+func (r *Keyring) Encrypt(plaintext []byte) (string, error) {
+	return r.keys[0].Encrypt(plaintext)
+}
+
+// Decrypt tries each key in turn. primary reports whether the token was
+// encrypted with the primary key; tokens that were not should be re-encrypted.
+func (r *Keyring) Decrypt(token string) (plaintext []byte, primary bool, err error) {
+	for i, key := range r.keys {
+		plaintext, err = key.Decrypt(token)
+		if err == nil {
+			return plaintext, i == 0, nil
+		}
+		if !errors.Is(err, ErrInvalidToken) {
+			return nil, false, err
+		}
+	}
+	return nil, false, ErrInvalidToken
+}
+
+// Len returns the number of keys
+func (r *Keyring) Len() int {
+	return len(r.keys)
+}
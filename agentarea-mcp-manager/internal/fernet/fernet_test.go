@@ -0,0 +1,57 @@
+package fernet
+
+import (
+	"errors"
+	"testing"
+)
+
+// Test vector from the Fernet specification
+const (
+	specKey   = "cw_0x689RpI-jtRR7oE8h_eQsKImvJapLeSbXpwF4e4="
+	specToken = "gAAAAAAdwJ6wAAECAwQFBgcICQoLDA0ODy021cpGVWKZ_eEwCGM4BLLF_5CV9dOPmrhuVUPgJobwOz7JcbmrR64jVmpU4IwqDA=="
+)
+
+func TestDecryptSpecToken(t *testing.T) {
+	key, err := ParseKey(specKey)
+	if err != nil {
+		t.Fatalf("ParseKey: %v", err)
+	}
+	plaintext, err := key.Decrypt(specToken)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(plaintext) != "hello" {
+		t.Errorf("expected hello, got %q", plaintext)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey, _ := GenerateKey()
+	newKey, _ := GenerateKey()
+
+	oldRing, _ := NewKeyring(oldKey)
+	token, err := oldRing.Encrypt([]byte("s3cr3t-value"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated, err := ParseKeyring(newKey.Encode() + "," + oldKey.Encode())
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	plaintext, primary, err := rotated.Decrypt(token)
+	if err != nil {
+		t.Fatalf("Decrypt with rotated ring: %v", err)
+	}
+	if string(plaintext) != "s3cr3t-value" || primary {
+		t.Errorf("expected old-key token to decrypt as non-primary, got %q primary=%v", plaintext, primary)
+	}
+
+	token, _ = rotated.Encrypt([]byte("s3cr3t-value"))
+	if _, primary, _ := rotated.Decrypt(token); !primary {
+		t.Error("expected new token to use the primary key")
+	}
+	if _, _, err := oldRing.Decrypt(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken without the new key, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package handoff coordinates zero-downtime restarts of the manager in
+// Docker mode: a new process takes over the state and listeners of the
+// running one before that one exits.
+package handoff
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/agentarea/mcp-manager/internal/container"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+)
+
+// TokenHeader carries the shared handoff token
+const TokenHeader = "X-Handoff-Token"
+
+// maxStateSize bounds a handed over state
+const maxStateSize = 64 << 20
+
+// State is what the running manager hands over to its replacement
+type State struct {
+	Version       string                `json:"version"`
+	ExportedAt    time.Time             `json:"exported_at"`
+	Routes        []proxy.RouteSnapshot `json:"routes"`
+	SkippedRoutes []string              `json:"skipped_routes,omitempty"` // Routes that could not be serialized
+	container.HandoffState
+}
+
+// ValidToken reports whether a presented token matches the configured one;
+// an empty configured token disables handoff
+func ValidToken(configured, presented string) bool {
+	return configured != "" && subtle.ConstantTimeCompare([]byte(configured), []byte(presented)) == 1
+}
+
+// Client drives the handoff endpoints of the running manager
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the manager API at baseURL. timeout must
+// cover the time the running manager takes to settle in-flight operations.
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Begin freezes the running manager and returns its state
+func (c *Client) Begin(ctx context.Context) (*State, error) {
+	var state State
+	if err := c.post(ctx, "/admin/handoff", &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Complete tells the running manager to release its listeners and exit
+func (c *Client) Complete(ctx context.Context) error {
+	return c.post(ctx, "/admin/handoff/complete", nil)
+}
+
+// Abort tells the running manager to resume as if no handoff had begun
+func (c *Client) Abort(ctx context.Context) error {
+	return c.post(ctx, "/admin/handoff/abort", nil)
+}
+
+// post calls a handoff endpoint and decodes the JSON response into out
+func (c *Client) post(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(nil))
+	if err != nil {
+		return fmt.Errorf("failed to build handoff request: %w", err)
+	}
+	req.Header.Set(TokenHeader, c.token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("handoff request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("handoff returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxStateSize)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode handoff state: %w", err)
+	}
+	return nil
+}
+
+// Listen opens a TCP listener. With reusePort set the socket gets
+// SO_REUSEPORT, so a replacement manager can bind the same address while
+// this one still serves; both processes must set it.
+func Listen(ctx context.Context, addr string, reusePort bool) (net.Listener, error) {
+	var lc net.ListenConfig
+	if reusePort {
+		lc.Control = func(network, address string, conn syscall.RawConn) error {
+			var sockErr error
+			if err := conn.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		}
+	}
+	return lc.Listen(ctx, "tcp", addr)
+}
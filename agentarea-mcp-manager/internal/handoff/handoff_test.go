@@ -0,0 +1,71 @@
+package handoff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListenReusePort(t *testing.T) {
+	ctx := context.Background()
+	first, err := Listen(ctx, "127.0.0.1:0", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	second, err := Listen(ctx, first.Addr().String(), true)
+	if err != nil {
+		t.Fatalf("expected a second listener on the same address, got %v", err)
+	}
+	second.Close()
+
+	if ln, err := Listen(ctx, first.Addr().String(), false); err == nil {
+		ln.Close()
+		t.Error("expected a listener without SO_REUSEPORT to fail")
+	}
+}
+
+func TestClient(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ValidToken("secret", r.Header.Get(TokenHeader)) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		calls = append(calls, r.URL.Path)
+		if r.URL.Path == "/admin/handoff" {
+			json.NewEncoder(w).Encode(State{Version: "0.1.0"})
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	if _, err := NewClient(server.URL, "wrong", time.Second).Begin(ctx); err == nil {
+		t.Error("expected a wrong token to be rejected")
+	}
+
+	client := NewClient(server.URL+"/", "secret", time.Second)
+	state, err := client.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != "0.1.0" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if err := client.Complete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[1] != "/admin/handoff/complete" {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+
+	if ValidToken("", "") {
+		t.Error("expected an empty token to disable handoff")
+	}
+}
@@ -0,0 +1,513 @@
+// Package loadtest creates synthetic instances on a backend, drives proxy
+// traffic to them and reports latencies and memory growth, so the
+// scalability of the manager can be tracked across releases.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+)
+
+// LabelRun labels every synthetic instance with the ID of its run
+const LabelRun = "loadtest-run"
+
+// pingRequest is the MCP request sent through the proxy
+const pingRequest = `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+// Options configures a run
+type Options struct {
+	Instances    int           // Synthetic instances to create
+	Concurrency  int           // Parallel creates and proxy clients
+	Image        string        // Image of the instances
+	Command      []string      // Command of the instances, e.g. a busybox httpd
+	Port         int           // Container port of the instances
+	ReadyTimeout time.Duration // How long an instance may take to run
+	Requests     int           // Proxy requests per instance
+	HealthRounds int           // Health check passes over all instances
+	Keep         bool          // Leave the instances running after the run
+}
+
+// withDefaults fills in unset options
+func (o Options) withDefaults() Options {
+	if o.Instances <= 0 {
+		o.Instances = 50
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 10
+	}
+	if o.Image == "" {
+		o.Image = "busybox:latest"
+	}
+	if o.Port == 0 {
+		o.Port = 8000
+	}
+	if o.ReadyTimeout == 0 {
+		o.ReadyTimeout = 2 * time.Minute
+	}
+	if o.Requests < 0 {
+		o.Requests = 0
+	}
+	if o.HealthRounds < 0 {
+		o.HealthRounds = 0
+	}
+	return o
+}
+
+// Percentiles summarizes a latency distribution in milliseconds
+type Percentiles struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// Memory is heap and goroutine growth over a run, measured after a GC
+type Memory struct {
+	HeapBefore        uint64 `json:"heap_before_bytes"`
+	HeapAfter         uint64 `json:"heap_after_bytes"`
+	HeapGrowth        int64  `json:"heap_growth_bytes"`
+	HeapPerInstance   int64  `json:"heap_per_instance_bytes"`
+	GoroutinesBefore  int    `json:"goroutines_before"`
+	GoroutinesAfter   int    `json:"goroutines_after"`
+	GoroutineGrowth   int    `json:"goroutine_growth"`
+	SysAfter          uint64 `json:"sys_after_bytes"`
+	TotalAllocGrowth  uint64 `json:"total_alloc_growth_bytes"`
+	GarbageCollection uint32 `json:"gc_cycles"`
+}
+
+// Report is the result of a run
+type Report struct {
+	RunID     string  `json:"run_id"`
+	Instances int     `json:"instances"`
+	Created   int     `json:"created"`
+	Ready     int     `json:"ready"`
+	Failed    int     `json:"failed"`
+	Duration  float64 `json:"duration_seconds"`
+
+	// Time for CreateInstance to return, and until the instance runs (polled
+	// every 100ms)
+	CreateLatency Percentiles `json:"create_latency"`
+	ReadyLatency  Percentiles `json:"ready_latency"`
+
+	// Requests through the proxy to the ready instances
+	ProxyLatency    Percentiles `json:"proxy_latency"`
+	ProxyErrors     int         `json:"proxy_errors"`
+	ProxyThroughput float64     `json:"proxy_requests_per_second"`
+
+	// Time for one health check pass over all ready instances, and per check
+	HealthRound    Percentiles `json:"health_round"`
+	HealthPerCheck float64     `json:"health_per_check_ms"`
+
+	Memory Memory   `json:"memory"`
+	Errors []string `json:"errors,omitempty"` // First few failures, for diagnosis
+}
+
+// maxReportedErrors caps the errors kept in a report
+const maxReportedErrors = 10
+
+// Runner runs load tests against a backend
+type Runner struct {
+	backend backends.Backend
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewRunner creates a runner for an initialized backend
+func NewRunner(backend backends.Backend, logger *slog.Logger) *Runner {
+	return &Runner{backend: backend, logger: logger}
+}
+
+// synthetic is one created instance
+type synthetic struct {
+	spec  *backends.InstanceSpec
+	ready bool
+}
+
+// Run creates the instances, drives proxy traffic and health checks, and
+// deletes the instances again unless opts.Keep is set
+func (r *Runner) Run(ctx context.Context, opts Options) (*Report, error) {
+	opts = opts.withDefaults()
+
+	runID, err := newRunID()
+	if err != nil {
+		return nil, err
+	}
+	r.report = &Report{RunID: runID, Instances: opts.Instances}
+	start := time.Now()
+
+	before := sampleMemory()
+
+	instances := r.create(ctx, runID, opts)
+	if !opts.Keep {
+		defer r.cleanup(instances)
+	}
+
+	var ready []*synthetic
+	for _, instance := range instances {
+		if instance.ready {
+			ready = append(ready, instance)
+		}
+	}
+	if len(ready) > 0 {
+		if err := r.driveProxy(ctx, ready, opts); err != nil {
+			return nil, err
+		}
+		r.checkHealth(ctx, ready, opts)
+	}
+
+	after := sampleMemory()
+	r.report.Memory = memoryGrowth(before, after, r.report.Created)
+	r.report.Duration = time.Since(start).Seconds()
+
+	r.logger.Info("Load test finished",
+		slog.String("run_id", runID),
+		slog.Int("created", r.report.Created),
+		slog.Int("ready", r.report.Ready),
+		slog.Int("failed", r.report.Failed))
+	return r.report, nil
+}
+
+// fail records a failure in the report
+func (r *Runner) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.report.Errors) < maxReportedErrors {
+		r.report.Errors = append(r.report.Errors, err.Error())
+	}
+}
+
+// create creates the instances in parallel and waits for each to run
+func (r *Runner) create(ctx context.Context, runID string, opts Options) []*synthetic {
+	var (
+		mu          sync.Mutex
+		instances   []*synthetic
+		createTimes []time.Duration
+		readyTimes  []time.Duration
+		failed      int
+	)
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				name := fmt.Sprintf("loadtest-%s-%d", runID, i)
+				spec := &backends.InstanceSpec{
+					Name:        name,
+					ServiceName: name,
+					InstanceID:  name,
+					Image:       opts.Image,
+					Command:     opts.Command,
+					Port:        opts.Port,
+					Labels:      map[string]string{LabelRun: runID},
+				}
+
+				began := time.Now()
+				_, err := r.backend.CreateInstance(ctx, spec)
+				created := time.Since(began)
+				if err != nil {
+					r.fail(fmt.Errorf("create %s: %w", name, err))
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					continue
+				}
+				instance := &synthetic{spec: spec}
+				mu.Lock()
+				instances = append(instances, instance)
+				createTimes = append(createTimes, created)
+				mu.Unlock()
+
+				if err := r.waitReady(ctx, name, opts.ReadyTimeout); err != nil {
+					r.fail(fmt.Errorf("start %s: %w", name, err))
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					continue
+				}
+				instance.ready = true
+				mu.Lock()
+				readyTimes = append(readyTimes, time.Since(began))
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range opts.Instances {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	r.report.Created = len(instances)
+	r.report.Ready = len(readyTimes)
+	r.report.Failed = failed
+	r.report.CreateLatency = summarize(createTimes)
+	r.report.ReadyLatency = summarize(readyTimes)
+	return instances
+}
+
+// waitReady polls an instance until it runs, fails or times out
+func (r *Runner) waitReady(ctx context.Context, instanceID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		status, err := r.backend.GetInstanceStatus(ctx, instanceID)
+		if err == nil {
+			switch status.Status {
+			case "running", "healthy":
+				return nil
+			case "error", "stopped":
+				return fmt.Errorf("instance is %s", status.Status)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not running after %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// driveProxy sends opts.Requests requests to each ready instance through an
+// in-process proxy. Routes point at a local echo upstream, so the numbers
+// measure the proxy and not the MCP servers.
+func (r *Runner) driveProxy(ctx context.Context, ready []*synthetic, opts Options) error {
+	if opts.Requests == 0 {
+		return nil
+	}
+
+	upstream, err := serve(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.Copy(w, req.Body)
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to start echo upstream: %w", err)
+	}
+	defer upstream.Close()
+
+	proxyServer := proxy.NewProxyServer(proxy.ProxyConfig{}, r.logger)
+	defer proxyServer.Shutdown(context.Background())
+	front, err := serve(proxyServer.Handler())
+	if err != nil {
+		return fmt.Errorf("failed to start proxy: %w", err)
+	}
+	defer front.Close()
+
+	upstreamPort := upstream.Addr().(*net.TCPAddr).Port
+	for _, instance := range ready {
+		if err := proxyServer.AddRoute(instance.spec.ServiceName, "127.0.0.1", upstreamPort); err != nil {
+			return fmt.Errorf("failed to add route %s: %w", instance.spec.ServiceName, err)
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: opts.Concurrency},
+	}
+	defer client.CloseIdleConnections()
+
+	work := make(chan string)
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		failures  atomic.Int64
+		wg        sync.WaitGroup
+	)
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range work {
+				began := time.Now()
+				if err := ping(ctx, client, url); err != nil {
+					if failures.Add(1) == 1 {
+						r.fail(fmt.Errorf("proxy request: %w", err))
+					}
+					continue
+				}
+				mu.Lock()
+				latencies = append(latencies, time.Since(began))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	began := time.Now()
+	base := "http://" + front.Addr().String() + "/mcp/"
+	for range opts.Requests {
+		for _, instance := range ready {
+			if ctx.Err() != nil {
+				break
+			}
+			work <- base + instance.spec.ServiceName + "/"
+		}
+	}
+	close(work)
+	wg.Wait()
+	elapsed := time.Since(began)
+
+	r.report.ProxyLatency = summarize(latencies)
+	r.report.ProxyErrors = int(failures.Load())
+	if elapsed > 0 {
+		r.report.ProxyThroughput = float64(len(latencies)) / elapsed.Seconds()
+	}
+	return nil
+}
+
+// ping sends one MCP request and drains the response
+func ping(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(pingRequest))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkHealth runs health check passes over the ready instances, the way the
+// background health loop does
+func (r *Runner) checkHealth(ctx context.Context, ready []*synthetic, opts Options) {
+	var rounds []time.Duration
+	for range opts.HealthRounds {
+		began := time.Now()
+		for _, instance := range ready {
+			if _, err := r.backend.PerformHealthCheck(ctx, instance.spec.InstanceID); err != nil {
+				r.fail(fmt.Errorf("health check %s: %w", instance.spec.InstanceID, err))
+			}
+		}
+		rounds = append(rounds, time.Since(began))
+	}
+
+	r.report.HealthRound = summarize(rounds)
+	if len(rounds) > 0 {
+		var total time.Duration
+		for _, round := range rounds {
+			total += round
+		}
+		r.report.HealthPerCheck = milliseconds(total) / float64(len(rounds)*len(ready))
+	}
+}
+
+// cleanup deletes the created instances
+func (r *Runner) cleanup(instances []*synthetic) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	for _, instance := range instances {
+		if err := r.backend.DeleteInstance(ctx, instance.spec.InstanceID); err != nil {
+			r.logger.Warn("Failed to delete load test instance",
+				slog.String("instance_id", instance.spec.InstanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+// serve serves a handler on a random local port
+func serve(handler http.Handler) (net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go func() { _ = http.Serve(listener, handler) }()
+	return listener, nil
+}
+
+// summarize computes the percentiles of a set of durations
+func summarize(durations []time.Duration) Percentiles {
+	if len(durations) == 0 {
+		return Percentiles{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) float64 {
+		return milliseconds(sorted[int(p*float64(len(sorted)-1))])
+	}
+	return Percentiles{
+		Count: len(sorted),
+		P50:   at(0.50),
+		P90:   at(0.90),
+		P99:   at(0.99),
+		Max:   milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// memorySample is the memory state at a point in the run
+type memorySample struct {
+	stats      runtime.MemStats
+	goroutines int
+}
+
+// sampleMemory collects garbage and reads the memory state
+func sampleMemory() memorySample {
+	runtime.GC()
+	var sample memorySample
+	runtime.ReadMemStats(&sample.stats)
+	sample.goroutines = runtime.NumGoroutine()
+	return sample
+}
+
+// memoryGrowth compares two samples
+func memoryGrowth(before, after memorySample, instances int) Memory {
+	m := Memory{
+		HeapBefore:        before.stats.HeapAlloc,
+		HeapAfter:         after.stats.HeapAlloc,
+		HeapGrowth:        int64(after.stats.HeapAlloc) - int64(before.stats.HeapAlloc),
+		GoroutinesBefore:  before.goroutines,
+		GoroutinesAfter:   after.goroutines,
+		GoroutineGrowth:   after.goroutines - before.goroutines,
+		SysAfter:          after.stats.Sys,
+		TotalAllocGrowth:  after.stats.TotalAlloc - before.stats.TotalAlloc,
+		GarbageCollection: after.stats.NumGC - before.stats.NumGC,
+	}
+	if instances > 0 {
+		m.HeapPerInstance = m.HeapGrowth / int64(instances)
+	}
+	return m
+}
+
+// newRunID returns a short random run ID
+func newRunID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate run ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,59 @@
+package loadtest
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/backends"
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+func TestRunOnFakeBackend(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	backend := backends.NewFakeBackend(config.FakeConfig{StartupDelay: 10 * time.Millisecond}, logger)
+
+	report, err := NewRunner(backend, logger).Run(context.Background(), Options{
+		Instances:    8,
+		Concurrency:  4,
+		Requests:     5,
+		HealthRounds: 2,
+		ReadyTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Created != 8 || report.Ready != 8 || report.Failed != 0 {
+		t.Errorf("created=%d ready=%d failed=%d, want 8 8 0 (errors: %v)", report.Created, report.Ready, report.Failed, report.Errors)
+	}
+	if report.CreateLatency.Count != 8 || report.ReadyLatency.Count != 8 {
+		t.Errorf("latency samples create=%d ready=%d, want 8", report.CreateLatency.Count, report.ReadyLatency.Count)
+	}
+	if report.ProxyLatency.Count != 40 || report.ProxyErrors != 0 {
+		t.Errorf("proxy requests=%d errors=%d, want 40 0 (errors: %v)", report.ProxyLatency.Count, report.ProxyErrors, report.Errors)
+	}
+	if report.HealthRound.Count != 2 {
+		t.Errorf("health rounds = %d, want 2", report.HealthRound.Count)
+	}
+
+	// Instances are deleted after the run
+	if instances, _ := backend.ListInstances(context.Background()); len(instances) != 0 {
+		t.Errorf("%d instances left after the run", len(instances))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	p := summarize(durations)
+	if p.Count != 100 || p.P50 != 50 || p.P90 != 90 || p.P99 != 99 || p.Max != 100 {
+		t.Errorf("summarize = %+v", p)
+	}
+	if (summarize(nil) != Percentiles{}) {
+		t.Error("summarize of no samples is not empty")
+	}
+}
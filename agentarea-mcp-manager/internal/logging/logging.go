@@ -0,0 +1,231 @@
+// Package logging provides runtime-adjustable log levels per component and
+// sampling of repetitive log messages on top of log/slog.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ComponentKey is the attribute that names the component of a logger, set
+// with logger.With(ComponentKey, "events")
+const ComponentKey = "component"
+
+// sampleTick is the period sampling counts are reset over
+const sampleTick = time.Second
+
+// ParseLevel parses a level name such as "debug" or "WARN"
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+// ParseComponentLevels parses "events=debug,proxy=warn"
+func ParseComponentLevels(s string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		component, name, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(component) == "" {
+			return nil, fmt.Errorf("invalid component level %q: expected component=level", entry)
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(component)] = level
+	}
+	return levels, nil
+}
+
+// SamplingStats reports the sampling configuration and dropped messages
+type SamplingStats struct {
+	Enabled    bool   `json:"enabled"`
+	Initial    int    `json:"initial"`
+	Thereafter int    `json:"thereafter"`
+	Dropped    uint64 `json:"dropped"`
+}
+
+// Control holds the log levels and sampling shared by all handlers created
+// from it. Levels can be changed while the program runs.
+type Control struct {
+	mu         sync.RWMutex
+	base       slog.Level
+	components map[string]slog.Level
+
+	sampler *sampler
+}
+
+// NewControl creates a control with a base level for all components
+func NewControl(base slog.Level) *Control {
+	return &Control{base: base, components: make(map[string]slog.Level)}
+}
+
+// SetSampling logs the first initial messages with the same component, level
+// and text each second and then every thereafter-th. Warnings and errors are
+// never sampled. An initial of 0 disables sampling.
+func (c *Control) SetSampling(initial, thereafter int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if initial <= 0 {
+		c.sampler = nil
+		return
+	}
+	c.sampler = &sampler{initial: initial, thereafter: thereafter, counts: make(map[string]*sampleCount)}
+}
+
+// SetLevel sets the level of a component, or the base level if component is empty
+func (c *Control) SetLevel(component string, level slog.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if component == "" {
+		c.base = level
+		return
+	}
+	c.components[component] = level
+}
+
+// ResetLevel makes a component use the base level again
+func (c *Control) ResetLevel(component string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.components, component)
+}
+
+// Levels returns the base level and the component overrides
+func (c *Control) Levels() (slog.Level, map[string]slog.Level) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	components := make(map[string]slog.Level, len(c.components))
+	for name, level := range c.components {
+		components[name] = level
+	}
+	return c.base, components
+}
+
+// Sampling returns the sampling configuration and how many messages it dropped
+func (c *Control) Sampling() SamplingStats {
+	c.mu.RLock()
+	s := c.sampler
+	c.mu.RUnlock()
+
+	if s == nil {
+		return SamplingStats{}
+	}
+	return SamplingStats{Enabled: true, Initial: s.initial, Thereafter: s.thereafter, Dropped: s.dropped.Load()}
+}
+
+// level returns the effective level of a component
+func (c *Control) level(component string) slog.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if level, ok := c.components[component]; ok {
+		return level
+	}
+	return c.base
+}
+
+// allow applies sampling to a record
+func (c *Control) allow(component string, r slog.Record) bool {
+	if r.Level >= slog.LevelWarn {
+		return true
+	}
+	c.mu.RLock()
+	s := c.sampler
+	c.mu.RUnlock()
+
+	if s == nil {
+		return true
+	}
+	return s.allow(component+"\x00"+r.Level.String()+"\x00"+r.Message, r.Time)
+}
+
+// sampler counts messages per key within a tick
+type sampler struct {
+	initial    int
+	thereafter int
+	dropped    atomic.Uint64
+
+	mu     sync.Mutex
+	counts map[string]*sampleCount
+}
+
+type sampleCount struct {
+	start time.Time
+	n     int
+}
+
+func (s *sampler) allow(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count, ok := s.counts[key]
+	if !ok || now.Sub(count.start) >= sampleTick {
+		count = &sampleCount{start: now}
+		s.counts[key] = count
+	}
+	count.n++
+
+	if count.n <= s.initial || (s.thereafter > 0 && (count.n-s.initial)%s.thereafter == 0) {
+		return true
+	}
+	s.dropped.Add(1)
+	return false
+}
+
+// Handler filters records by the level of their component and applies sampling
+// before passing them to the wrapped handler
+type Handler struct {
+	inner     slog.Handler
+	control   *Control
+	component string
+}
+
+// NewHandler wraps a handler; the wrapped handler should accept all levels
+func NewHandler(inner slog.Handler, control *Control) *Handler {
+	return &Handler{inner: inner, control: control}
+}
+
+// Enabled reports whether the component of the handler logs at the level
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.control.level(h.component)
+}
+
+// Handle passes records that survive sampling to the wrapped handler
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.control.allow(h.component, r) {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs picks up the component attribute
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey {
+			component = attr.Value.String()
+		}
+	}
+	return &Handler{inner: h.inner.WithAttrs(attrs), control: h.control, component: component}
+}
+
+// WithGroup keeps the component of the handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name), control: h.control, component: h.component}
+}
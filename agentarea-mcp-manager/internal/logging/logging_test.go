@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+	control := NewControl(slog.LevelInfo)
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), control))
+	events := logger.With(ComponentKey, "events")
+
+	events.Debug("hidden")
+	control.SetLevel("events", slog.LevelDebug)
+	events.Debug("shown")
+	logger.Debug("base hidden")
+	control.ResetLevel("events")
+	events.Debug("hidden again")
+
+	out := buf.String()
+	if !strings.Contains(out, "msg=shown") || strings.Contains(out, "hidden") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	levels, err := ParseComponentLevels("events=debug, proxy=WARN")
+	if err != nil || levels["events"] != slog.LevelDebug || levels["proxy"] != slog.LevelWarn {
+		t.Errorf("ParseComponentLevels = %v, %v", levels, err)
+	}
+	if _, err := ParseComponentLevels("events"); err == nil {
+		t.Error("expected missing level to be rejected")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	control := NewControl(slog.LevelInfo)
+	control.SetSampling(2, 5)
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil), control))
+
+	for i := 0; i < 12; i++ {
+		logger.Info("repeated")
+		logger.Warn("warning")
+	}
+
+	// 2 initial plus the 5th and 10th after them; warnings are never sampled
+	if got := strings.Count(buf.String(), "msg=repeated"); got != 4 {
+		t.Errorf("logged %d sampled messages, want 4", got)
+	}
+	if got := strings.Count(buf.String(), "msg=warning"); got != 12 {
+		t.Errorf("logged %d warnings, want 12", got)
+	}
+	if dropped := control.Sampling().Dropped; dropped != 8 {
+		t.Errorf("dropped = %d, want 8", dropped)
+	}
+}
@@ -0,0 +1,156 @@
+// Package metrics exposes the manager's Prometheus metrics: instance
+// lifecycle latency, health check failures, proxied request durations,
+// event publish errors and instance counts.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mcp_manager"
+
+// Sources of instance operations
+const (
+	SourceAPI   = "api"
+	SourceEvent = "event"
+)
+
+// listTimeout bounds the instance listing done for one scrape
+const listTimeout = 5 * time.Second
+
+var (
+	registry = prometheus.NewRegistry()
+
+	instanceOperations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "instance_operation_duration_seconds",
+		Help:      "Duration of instance creations and deletions.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"operation", "source", "result"})
+
+	healthCheckFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "health_check_failures_total",
+		Help:      "Instance health checks that found the instance unhealthy or unreachable.",
+	})
+
+	proxyRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "proxy_request_duration_seconds",
+		Help:      "Duration of requests proxied to instances, until the response was written.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	eventPublishErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_publish_errors_total",
+		Help:      "Events that could not be published to Redis.",
+	}, []string{"channel"})
+
+	instanceCount = &instanceCollector{
+		desc: prometheus.NewDesc(namespace+"_instances", "Instances known to the backend by status.", []string{"status"}, nil),
+	}
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		instanceOperations,
+		healthCheckFailures,
+		proxyRequests,
+		eventPublishErrors,
+		instanceCount,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format. A backend
+// that cannot list its instances only drops the instance gauge.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// ObserveInstanceOperation records how long an instance create or delete
+// took and whether it failed
+func ObserveInstanceOperation(operation, source string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	instanceOperations.WithLabelValues(operation, source, result).Observe(time.Since(start).Seconds())
+}
+
+// HealthCheckFailed counts a failed instance health check
+func HealthCheckFailed() {
+	healthCheckFailures.Inc()
+}
+
+// ObserveProxyRequest records a request proxied to an instance. Status codes
+// are grouped by class to bound the number of series.
+func ObserveProxyRequest(method string, status int, duration time.Duration) {
+	proxyRequests.WithLabelValues(method, statusClass(status)).Observe(duration.Seconds())
+}
+
+// EventPublishFailed counts an event that could not be published
+func EventPublishFailed(channel string) {
+	eventPublishErrors.WithLabelValues(channel).Inc()
+}
+
+// SetInstanceLister sets where the instance gauge is read from on each
+// scrape; the gauge is empty until it is set
+func SetInstanceLister(list func(ctx context.Context) ([]string, error)) {
+	instanceCount.mu.Lock()
+	defer instanceCount.mu.Unlock()
+	instanceCount.list = list
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return strconv.Itoa(status)
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// instanceCollector reports the instance count per status when scraped, so
+// the gauge cannot drift from the backend
+type instanceCollector struct {
+	desc *prometheus.Desc
+	mu   sync.Mutex
+	list func(ctx context.Context) ([]string, error) // Status of each instance
+}
+
+func (c *instanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *instanceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	list := c.list
+	c.mu.Unlock()
+	if list == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+	statuses, err := list(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	counts := make(map[string]int)
+	for _, status := range statuses {
+		counts[status]++
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), status)
+	}
+}
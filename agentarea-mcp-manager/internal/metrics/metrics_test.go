@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	ObserveInstanceOperation("create", SourceAPI, time.Now().Add(-time.Second), nil)
+	ObserveInstanceOperation("delete", SourceEvent, time.Now(), errors.New("gone"))
+	HealthCheckFailed()
+	ObserveProxyRequest(http.MethodPost, http.StatusBadGateway, 20*time.Millisecond)
+	EventPublishFailed("mcp.instance.status")
+	SetInstanceLister(func(ctx context.Context) ([]string, error) {
+		return []string{"running", "running", "error"}, nil
+	})
+	defer SetInstanceLister(nil)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`mcp_manager_instance_operation_duration_seconds_count{operation="create",result="success",source="api"} 1`,
+		`mcp_manager_instance_operation_duration_seconds_count{operation="delete",result="error",source="event"} 1`,
+		`mcp_manager_health_check_failures_total 1`,
+		`mcp_manager_proxy_request_duration_seconds_count{code="5xx",method="POST"} 1`,
+		`mcp_manager_event_publish_errors_total{channel="mcp.instance.status"} 1`,
+		`mcp_manager_instances{status="running"} 2`,
+		`mcp_manager_instances{status="error"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
+
+func TestInstanceListerError(t *testing.T) {
+	SetInstanceLister(func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("backend unavailable")
+	})
+	defer SetInstanceLister(nil)
+
+	body := scrape(t)
+	if strings.Contains(body, "mcp_manager_instances{") || !strings.Contains(body, "go_goroutines") {
+		t.Errorf("expected only the instance gauge to be dropped:\n%s", body)
+	}
+}
@@ -74,11 +74,70 @@ type CreateContainerRequest struct {
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status            string    `json:"status"`
-	Version           string    `json:"version"`
-	ContainersRunning int       `json:"containers_running"`
-	Timestamp         time.Time `json:"timestamp"`
-	Uptime            string    `json:"uptime,omitempty"`
+	Status            string            `json:"status"`
+	Version           string            `json:"version"`
+	ContainersRunning int               `json:"containers_running"`
+	Timestamp         time.Time         `json:"timestamp"`
+	Uptime            string            `json:"uptime,omitempty"`
+	Capabilities      []string          `json:"capabilities,omitempty"` // Optional backend capabilities
+	Components        []ComponentStatus `json:"components,omitempty"`   // Supervised background components
+	Proxy             *ProxyStatus      `json:"proxy,omitempty"`        // Internal proxy listener (Docker only)
+}
+
+// ProxyState is the listener state of the internal proxy
+type ProxyState string
+
+const (
+	ProxyServing   ProxyState = "serving"
+	ProxyRebinding ProxyState = "rebinding" // Not listening, waiting to bind again
+	ProxyStopped   ProxyState = "stopped"   // Shut down
+)
+
+// ProxyStatus reports where the internal proxy listens
+type ProxyStatus struct {
+	State       ProxyState `json:"state"`
+	Address     string     `json:"address,omitempty"` // Bound address, empty unless serving
+	Fallback    bool       `json:"fallback"`          // Serving on the fallback port
+	Rebinds     int        `json:"rebinds"`
+	Since       time.Time  `json:"since"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// ComponentState is the state of a supervised background component
+type ComponentState string
+
+const (
+	ComponentRunning    ComponentState = "running"
+	ComponentRestarting ComponentState = "restarting" // Failed, waiting to be restarted
+	ComponentStopped    ComponentState = "stopped"    // Returned without error
+	ComponentFailed     ComponentState = "failed"     // Failed and will not be restarted
+)
+
+// ComponentStatus reports a supervised background component
+type ComponentStatus struct {
+	Name        string         `json:"name"`
+	State       ComponentState `json:"state"`
+	Critical    bool           `json:"critical"`
+	Restarts    int            `json:"restarts"`
+	StartedAt   time.Time      `json:"started_at"`
+	LastError   string         `json:"last_error,omitempty"`
+	LastErrorAt *time.Time     `json:"last_error_at,omitempty"`
+}
+
+// Doctor check results
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+)
+
+// DoctorCheck is one diagnostic check of GET /admin/doctor
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Details any    `json:"details,omitempty"`
 }
 
 // MCPEnvVar represents an environment variable for an MCP provider
@@ -92,18 +151,53 @@ type MCPEnvVar struct {
 
 // MCPProviderTemplate represents an MCP provider template
 type MCPProviderTemplate struct {
-	ID           string      `json:"id" yaml:"id"`
-	Name         string      `json:"name" yaml:"name"`
-	Description  string      `json:"description" yaml:"description"`
-	Icon         string      `json:"icon" yaml:"icon"`
-	DockerImage  string      `json:"docker_image" yaml:"docker_image"`
-	EnvVars      []MCPEnvVar `json:"env_vars" yaml:"env_vars"`
-	Capabilities []string    `json:"capabilities" yaml:"capabilities"`
+	ID           string          `json:"id" yaml:"id"`
+	Name         string          `json:"name" yaml:"name"`
+	Description  string          `json:"description" yaml:"description"`
+	Icon         string          `json:"icon" yaml:"icon"`
+	DockerImage  string          `json:"docker_image" yaml:"docker_image"`
+	EnvVars      []MCPEnvVar     `json:"env_vars" yaml:"env_vars"`
+	Capabilities []string        `json:"capabilities" yaml:"capabilities"`
+	Version      string          `json:"version,omitempty" yaml:"version,omitempty"`
+	SLO          *HealthSLO      `json:"slo,omitempty" yaml:"slo,omitempty"`
+	WarmPool     int             `json:"warm_pool,omitempty" yaml:"warm_pool,omitempty"` // Pre-started containers kept for fast creation (Docker only)
+	Source       *TemplateSource `json:"source,omitempty" yaml:"source,omitempty"`
+
+	ImageMetadata *ImageMetadata `json:"image_metadata,omitempty" yaml:"-"` // Discovered from the image, not stored
+}
+
+// ImageMetadata is what an MCP image declares about itself in its mcp.*
+// labels and its configuration
+type ImageMetadata struct {
+	Transport   string   `json:"transport,omitempty"` // streamable-http, sse or stdio
+	Port        int      `json:"port,omitempty"`
+	HealthPath  string   `json:"health_path,omitempty"`
+	RequiredEnv []string `json:"required_env,omitempty"`
+
+	ExposedPorts []int    `json:"exposed_ports,omitempty"` // TCP ports of EXPOSE
+	Entrypoint   []string `json:"entrypoint,omitempty"`
+	Command      []string `json:"command,omitempty"` // CMD, the entrypoint's arguments if it has one
+}
+
+// HealthSLO is a health check objective, e.g. 99% of checks pass over 24h
+type HealthSLO struct {
+	Objective float64 `json:"objective" yaml:"objective"`
+	Window    string  `json:"window" yaml:"window"`
+}
+
+// TemplateSource records where an imported template came from
+type TemplateSource struct {
+	Registry   string    `json:"registry" yaml:"registry"`
+	Name       string    `json:"name" yaml:"name"`
+	Version    string    `json:"version" yaml:"version"`
+	Repository string    `json:"repository,omitempty" yaml:"repository,omitempty"`
+	ImportedAt time.Time `json:"imported_at" yaml:"imported_at"`
 }
 
 // MCPProviderList represents the list of MCP providers from YAML
 type MCPProviderList struct {
-	Providers map[string]MCPProviderTemplate `json:"providers" yaml:"providers"`
+	APIVersion string                         `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Providers  map[string]MCPProviderTemplate `json:"providers" yaml:"providers"`
 }
 
 // ListContainersResponse represents the response for listing containers
@@ -0,0 +1,80 @@
+// Package naming is the policy for instance names: which names the manager
+// reserves, and how names are normalized into container names, hostnames and
+// route slugs. Names too long for a DNS label are shortened with a hash of
+// the full name so that distinct names stay distinct.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxLabelLength is the longest DNS label; container names are used as
+// hostnames on the MCP network and Kubernetes service names are labels too
+const MaxLabelLength = 63
+
+// hashLength is the number of hex digits of the suffix of shortened names
+const hashLength = 8
+
+// ErrInvalidName is returned for names without letters or digits
+var ErrInvalidName = errors.New("invalid instance name")
+
+// ErrReservedName is returned for names used by the manager itself
+var ErrReservedName = errors.New("reserved instance name")
+
+// reserved are the names of manager endpoints, proxy paths and infrastructure
+// services that an instance must not shadow
+var reserved = map[string]bool{
+	"admin": true, "api": true, "containers": true, "dashboard": true,
+	"debug": true, "health": true, "healthz": true, "instances": true,
+	"internal": true, "live": true, "livez": true, "localhost": true,
+	"mcp": true, "metrics": true, "proxy": true, "ready": true,
+	"readyz": true, "schemas": true, "static": true, "templates": true,
+	"traefik": true,
+}
+
+var invalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Normalize lowercases a name, replaces every run of other characters than
+// letters and digits with a hyphen and trims hyphens. Results longer than
+// maxLength are cut and end with a hash of the whole normalized name.
+func Normalize(name string, maxLength int) string {
+	normalized := normalize(name)
+	if len(normalized) <= maxLength || maxLength <= hashLength+1 {
+		return normalized
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	head := strings.TrimRight(normalized[:maxLength-hashLength-1], "-")
+	return head + "-" + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// normalize replaces runs of characters other than letters and digits
+func normalize(name string) string {
+	return strings.Trim(invalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// IsReserved reports whether a name normalizes to a name reserved by the manager
+func IsReserved(name string) bool {
+	return reserved[Normalize(name, MaxLabelLength)]
+}
+
+// Shortened reports whether Normalize would shorten a name to fit a DNS label
+func Shortened(name string) bool {
+	return len(normalize(name)) > MaxLabelLength
+}
+
+// Validate checks that a requested instance name can be used
+func Validate(name string) error {
+	normalized := Normalize(name, MaxLabelLength)
+	switch {
+	case normalized == "":
+		return fmt.Errorf("%w %q: it must contain letters or digits", ErrInvalidName, name)
+	case reserved[normalized]:
+		return fmt.Errorf("%w %q: %q is used by the manager, choose another name", ErrReservedName, name, normalized)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package naming
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for name, want := range map[string]string{
+		"GitHub MCP":     "github-mcp",
+		"--my_server--":  "my-server",
+		"slack.v2":       "slack-v2",
+		"日本":             "",
+		"already-normal": "already-normal",
+	} {
+		if got := Normalize(name, MaxLabelLength); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNormalizeShortensWithHash(t *testing.T) {
+	base := strings.Repeat("a", 70)
+	first, second := Normalize(base+"-one", MaxLabelLength), Normalize(base+"-two", MaxLabelLength)
+	if len(first) != MaxLabelLength || len(second) != MaxLabelLength {
+		t.Errorf("lengths %d and %d, want %d", len(first), len(second), MaxLabelLength)
+	}
+	if first == second {
+		t.Error("names differing after the cut collide")
+	}
+	if first != Normalize(base+"-one", MaxLabelLength) {
+		t.Error("shortening is not deterministic")
+	}
+	if !Shortened(base) || Shortened("short") {
+		t.Error("Shortened misreports")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("github-mcp"); err != nil {
+		t.Errorf("valid name rejected: %v", err)
+	}
+	for _, name := range []string{"health", "API", " metrics ", "Admin!"} {
+		if err := Validate(name); !errors.Is(err, ErrReservedName) {
+			t.Errorf("Validate(%q) = %v, want ErrReservedName", name, err)
+		}
+	}
+	for _, name := range []string{"", "---", "日本"} {
+		if err := Validate(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Validate(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+	if IsReserved("healthy") {
+		t.Error("names only starting with a reserved word are allowed")
+	}
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// send delivers a notification to a channel
+func (n *Notifier) send(ctx context.Context, channel Channel, notification Notification) error {
+	switch channel.Type {
+	case ChannelSlack:
+		return n.postJSON(ctx, channel.URL, nil, map[string]string{"text": formatText(notification)})
+	case ChannelWebhook:
+		return n.postJSON(ctx, channel.URL, channel.Headers, notification)
+	case ChannelEmail:
+		return n.sendEmail(channel.SMTP, notification)
+	}
+	return fmt.Errorf("unsupported channel type %q", channel.Type)
+}
+
+// postJSON posts a JSON body and fails on a non-2xx answer
+func (n *Notifier) postJSON(ctx context.Context, url string, headers map[string]string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendEmail sends a plain text email, authenticating when a username is set
+func (n *Notifier) sendEmail(settings *SMTPSettings, notification Notification) error {
+	port := settings.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", settings.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(settings.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", formatSubject(notification))
+	fmt.Fprintf(&msg, "Date: %s\r\n", notification.Timestamp.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(formatText(notification), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+	return n.sendMail(addr, auth, settings.From, settings.To, []byte(msg.String()))
+}
+
+// formatSubject is the one-line summary of a notification
+func formatSubject(n Notification) string {
+	return fmt.Sprintf("[%s] MCP instance %s: %s", n.Severity, n.Name, strings.ReplaceAll(n.Event, "_", " "))
+}
+
+// formatText is the human-readable body of a notification
+func formatText(n Notification) string {
+	var text strings.Builder
+	text.WriteString(formatSubject(n))
+	if n.Message != "" {
+		text.WriteString("\n" + n.Message)
+	}
+	if n.InstanceID != "" {
+		text.WriteString("\nInstance: " + n.InstanceID)
+	}
+	if n.WorkspaceID != "" {
+		text.WriteString("\nWorkspace: " + n.WorkspaceID)
+	}
+	text.WriteString("\nTime: " + n.Timestamp.UTC().Format(time.RFC3339))
+	return text.String()
+}
@@ -0,0 +1,453 @@
+// Package notify delivers notifications of instance failures (failed,
+// crash_loop and quarantined alerts) to Slack, email and webhook channels,
+// routed by workspace and severity. Channels and routing rules are set
+// through the API and kept in Redis, so every manager replica uses them.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/redisconn"
+)
+
+// configKey is the Redis key holding the notification configuration
+const configKey = "mcp-manager:notifications"
+
+// queueSize bounds the alerts waiting for delivery
+const queueSize = 256
+
+// ErrUnknownChannel is returned when testing a channel that is not configured
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Channel types
+const (
+	ChannelSlack   = "slack"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Severities, in increasing order
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{SeverityInfo: 0, SeverityWarning: 1, SeverityCritical: 2}
+
+// eventSeverity is the severity of each alert kind
+var eventSeverity = map[string]string{
+	events.AlertFailed:      SeverityWarning,
+	events.AlertCrashLoop:   SeverityCritical,
+	events.AlertQuarantined: SeverityCritical,
+}
+
+// Channel is a destination for notifications
+type Channel struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`     // Slack incoming webhook or webhook endpoint
+	Headers map[string]string `json:"headers,omitempty"` // Added to webhook requests
+	SMTP    *SMTPSettings     `json:"smtp,omitempty"`
+}
+
+// SMTPSettings are the server and recipients of an email channel
+type SMTPSettings struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Rule sends the notifications it matches to its channels. Empty Events and
+// Workspaces match all; MinSeverity defaults to info.
+type Rule struct {
+	Name        string   `json:"name"`
+	Events      []string `json:"events,omitempty"`
+	Workspaces  []string `json:"workspaces,omitempty"`
+	MinSeverity string   `json:"min_severity,omitempty"`
+	Channels    []string `json:"channels"`
+}
+
+// Config is the set of channels and routing rules
+type Config struct {
+	Channels  []Channel `json:"channels"`
+	Rules     []Rule    `json:"rules"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Notification is an alert about an instance, ready for delivery
+type Notification struct {
+	Event       string    `json:"event"`
+	Severity    string    `json:"severity"`
+	InstanceID  string    `json:"instance_id"`
+	Name        string    `json:"name"`
+	WorkspaceID string    `json:"workspace_id,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Delivery is the outcome of sending a notification to a channel
+type Delivery struct {
+	Channel string `json:"channel"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Validate checks the channels and that rules only name known events,
+// severities and channels
+func (c Config) Validate() error {
+	names := make(map[string]bool, len(c.Channels))
+	for _, channel := range c.Channels {
+		if channel.Name == "" {
+			return errors.New("channel name is required")
+		}
+		if names[channel.Name] {
+			return fmt.Errorf("duplicate channel %q", channel.Name)
+		}
+		names[channel.Name] = true
+
+		switch channel.Type {
+		case ChannelSlack, ChannelWebhook:
+			if channel.URL == "" {
+				return fmt.Errorf("channel %q: url is required", channel.Name)
+			}
+		case ChannelEmail:
+			settings := channel.SMTP
+			if settings == nil || settings.Host == "" || settings.From == "" || len(settings.To) == 0 {
+				return fmt.Errorf("channel %q: smtp host, from and to are required", channel.Name)
+			}
+		default:
+			return fmt.Errorf("channel %q: unsupported type %q: use slack, email or webhook", channel.Name, channel.Type)
+		}
+	}
+
+	for _, rule := range c.Rules {
+		if len(rule.Channels) == 0 {
+			return fmt.Errorf("rule %q: at least one channel is required", rule.Name)
+		}
+		for _, name := range rule.Channels {
+			if !names[name] {
+				return fmt.Errorf("rule %q: unknown channel %q", rule.Name, name)
+			}
+		}
+		for _, event := range rule.Events {
+			if _, ok := eventSeverity[event]; !ok {
+				return fmt.Errorf("rule %q: unknown event %q: use failed, crash_loop or quarantined", rule.Name, event)
+			}
+		}
+		if _, ok := severityRank[rule.MinSeverity]; rule.MinSeverity != "" && !ok {
+			return fmt.Errorf("rule %q: unknown severity %q: use info, warning or critical", rule.Name, rule.MinSeverity)
+		}
+	}
+	return nil
+}
+
+// matches reports whether a rule applies to a notification
+func (r Rule) matches(n Notification) bool {
+	if len(r.Events) > 0 && !slices.Contains(r.Events, n.Event) {
+		return false
+	}
+	if len(r.Workspaces) > 0 && !slices.Contains(r.Workspaces, n.WorkspaceID) {
+		return false
+	}
+	return severityRank[n.Severity] >= severityRank[r.MinSeverity]
+}
+
+// route returns the channels of every rule matching a notification, once
+// each and in configuration order
+func (c Config) route(n Notification) []Channel {
+	selected := make(map[string]bool)
+	for _, rule := range c.Rules {
+		if rule.matches(n) {
+			for _, name := range rule.Channels {
+				selected[name] = true
+			}
+		}
+	}
+	var channels []Channel
+	for _, channel := range c.Channels {
+		if selected[channel.Name] {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// Redacted returns the configuration with webhook URLs, header values and
+// SMTP passwords replaced by the redaction placeholder
+func (c Config) Redacted() Config {
+	redacted := c
+	redacted.Channels = make([]Channel, len(c.Channels))
+	for i, channel := range c.Channels {
+		if channel.URL != "" {
+			channel.URL = redact.Placeholder
+		}
+		if len(channel.Headers) > 0 {
+			headers := make(map[string]string, len(channel.Headers))
+			for name := range channel.Headers {
+				headers[name] = redact.Placeholder
+			}
+			channel.Headers = headers
+		}
+		if channel.SMTP != nil {
+			settings := *channel.SMTP
+			if settings.Password != "" {
+				settings.Password = redact.Placeholder
+			}
+			channel.SMTP = &settings
+		}
+		redacted.Channels[i] = channel
+	}
+	return redacted
+}
+
+// keepSecrets replaces redaction placeholders with the values of the channel
+// of the same name in previous, so a configuration read from the API can be
+// edited and written back
+func (c Config) keepSecrets(previous Config) Config {
+	old := make(map[string]Channel, len(previous.Channels))
+	for _, channel := range previous.Channels {
+		old[channel.Name] = channel
+	}
+	merged := c
+	merged.Channels = make([]Channel, len(c.Channels))
+	for i, channel := range c.Channels {
+		prev := old[channel.Name]
+		if channel.URL == redact.Placeholder {
+			channel.URL = prev.URL
+		}
+		if len(channel.Headers) > 0 {
+			headers := make(map[string]string, len(channel.Headers))
+			for name, value := range channel.Headers {
+				if value == redact.Placeholder {
+					value = prev.Headers[name]
+				}
+				headers[name] = value
+			}
+			channel.Headers = headers
+		}
+		if channel.SMTP != nil && channel.SMTP.Password == redact.Placeholder {
+			settings := *channel.SMTP
+			settings.Password = ""
+			if prev.SMTP != nil {
+				settings.Password = prev.SMTP.Password
+			}
+			channel.SMTP = &settings
+		}
+		merged.Channels[i] = channel
+	}
+	return merged
+}
+
+// Notifier turns alerts into notifications and delivers them
+type Notifier struct {
+	redisClient redis.UniversalClient
+	logger      *slog.Logger
+	cooldown    time.Duration
+	httpClient  *http.Client
+	sendMail    func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+	workspaceOf func(ctx context.Context, instanceID string) string
+	queue       chan events.Alert
+
+	mu       sync.Mutex
+	cached   Config               // Last configuration read, used while Redis is unavailable
+	lastSent map[string]time.Time // Per instance and event, for the cooldown
+}
+
+// NewNotifier creates a notifier with its own Redis client
+func NewNotifier(redisOptions *redisconn.Options, cfg config.NotificationsConfig, logger *slog.Logger) *Notifier {
+	return &Notifier{
+		redisClient: redisOptions.NewClient(),
+		logger:      logger,
+		cooldown:    cfg.Cooldown,
+		httpClient:  &http.Client{Timeout: cfg.Timeout},
+		sendMail:    smtp.SendMail,
+		queue:       make(chan events.Alert, queueSize),
+		lastSent:    make(map[string]time.Time),
+	}
+}
+
+// SetWorkspaceLookup sets how the workspace of an instance is found for
+// routing; without it only rules for all workspaces match
+func (n *Notifier) SetWorkspaceLookup(lookup func(ctx context.Context, instanceID string) string) {
+	n.workspaceOf = lookup
+}
+
+// Config returns the current configuration
+func (n *Notifier) Config(ctx context.Context) (Config, error) {
+	raw, err := n.redisClient.Get(ctx, configKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return Config{Channels: []Channel{}, Rules: []Rule{}}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to load notification config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse notification config: %w", err)
+	}
+
+	n.mu.Lock()
+	n.cached = cfg
+	n.mu.Unlock()
+	return cfg, nil
+}
+
+// SetConfig validates and stores a configuration. Redacted secrets keep
+// their current value.
+func (n *Notifier) SetConfig(ctx context.Context, cfg Config) (Config, error) {
+	current, err := n.Config(ctx)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg = cfg.keepSecrets(current)
+	if cfg.Channels == nil {
+		cfg.Channels = []Channel{}
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = []Rule{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	cfg.UpdatedAt = time.Now()
+
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := n.redisClient.Set(ctx, configKey, raw, 0).Err(); err != nil {
+		return Config{}, fmt.Errorf("failed to save notification config: %w", err)
+	}
+
+	n.mu.Lock()
+	n.cached = cfg
+	n.mu.Unlock()
+	return cfg, nil
+}
+
+// HandleAlert queues an alert for delivery; it never blocks and drops the
+// alert when the queue is full
+func (n *Notifier) HandleAlert(alert events.Alert) {
+	select {
+	case n.queue <- alert:
+	default:
+		n.logger.Warn("Notification queue full, dropping alert",
+			slog.String("kind", alert.Kind),
+			slog.String("instance_id", alert.InstanceID))
+	}
+}
+
+// Run delivers queued alerts until ctx is done
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert := <-n.queue:
+			n.deliverAlert(ctx, alert)
+		}
+	}
+}
+
+// deliverAlert routes an alert and sends it, unless the same alert was sent
+// for the instance within the cooldown
+func (n *Notifier) deliverAlert(ctx context.Context, alert events.Alert) {
+	notification := Notification{
+		Event:      alert.Kind,
+		Severity:   eventSeverity[alert.Kind],
+		InstanceID: alert.InstanceID,
+		Name:       alert.Name,
+		Message:    redact.String(alert.Message),
+		Timestamp:  alert.Timestamp,
+	}
+	if n.workspaceOf != nil && alert.InstanceID != "" {
+		notification.WorkspaceID = n.workspaceOf(ctx, alert.InstanceID)
+	}
+
+	key := alert.Kind + "/" + alert.InstanceID
+	n.mu.Lock()
+	if last, ok := n.lastSent[key]; ok && n.cooldown > 0 && alert.Timestamp.Sub(last) < n.cooldown {
+		n.mu.Unlock()
+		return
+	}
+	n.lastSent[key] = alert.Timestamp
+	for k, last := range n.lastSent {
+		if alert.Timestamp.Sub(last) >= n.cooldown {
+			delete(n.lastSent, k)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, delivery := range n.Dispatch(ctx, notification) {
+		if delivery.Error != "" {
+			n.logger.Warn("Failed to deliver notification",
+				slog.String("channel", delivery.Channel),
+				slog.String("event", notification.Event),
+				slog.String("instance_id", notification.InstanceID),
+				slog.String("error", delivery.Error))
+		}
+	}
+}
+
+// Dispatch sends a notification to the channels of the rules it matches
+func (n *Notifier) Dispatch(ctx context.Context, notification Notification) []Delivery {
+	cfg, err := n.Config(ctx)
+	if err != nil {
+		n.logger.Warn("Using cached notification config", slog.String("error", err.Error()))
+		n.mu.Lock()
+		cfg = n.cached
+		n.mu.Unlock()
+	}
+
+	deliveries := []Delivery{}
+	for _, channel := range cfg.route(notification) {
+		delivery := Delivery{Channel: channel.Name}
+		if err := n.send(ctx, channel, notification); err != nil {
+			delivery.Error = err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// Test sends a test notification to one channel
+func (n *Notifier) Test(ctx context.Context, channelName string) error {
+	cfg, err := n.Config(ctx)
+	if err != nil {
+		return err
+	}
+	for _, channel := range cfg.Channels {
+		if channel.Name == channelName {
+			return n.send(ctx, channel, Notification{
+				Event:     "test",
+				Severity:  SeverityInfo,
+				Name:      "mcp-manager",
+				Message:   "Test notification from mcp-manager",
+				Timestamp: time.Now(),
+			})
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownChannel, channelName)
+}
+
+// Close closes the Redis connection
+func (n *Notifier) Close() error {
+	return n.redisClient.Close()
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/events"
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+func testConfig(slackURL string) Config {
+	return Config{
+		Channels: []Channel{
+			{Name: "ops-slack", Type: ChannelSlack, URL: slackURL},
+			{Name: "oncall", Type: ChannelEmail, SMTP: &SMTPSettings{
+				Host: "smtp.example.com", Username: "bot", Password: "hunter2",
+				From: "mcp@example.com", To: []string{"oncall@example.com"},
+			}},
+		},
+		Rules: []Rule{
+			{Name: "everything", Channels: []string{"ops-slack"}},
+			{Name: "acme-critical", Workspaces: []string{"acme"}, MinSeverity: SeverityCritical, Channels: []string{"oncall", "ops-slack"}},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testConfig("https://hooks.slack.com/x").Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]Config{
+		"unknown type":    {Channels: []Channel{{Name: "a", Type: "pager"}}},
+		"missing url":     {Channels: []Channel{{Name: "a", Type: ChannelWebhook}}},
+		"missing smtp":    {Channels: []Channel{{Name: "a", Type: ChannelEmail}}},
+		"unknown channel": {Rules: []Rule{{Name: "r", Channels: []string{"nope"}}}},
+		"unknown event": {
+			Channels: []Channel{{Name: "a", Type: ChannelSlack, URL: "https://x"}},
+			Rules:    []Rule{{Name: "r", Events: []string{"exploded"}, Channels: []string{"a"}}},
+		},
+	}
+	for name, cfg := range cases {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	cfg := testConfig("https://hooks.slack.com/x")
+
+	warning := Notification{Event: events.AlertFailed, Severity: SeverityWarning, WorkspaceID: "acme"}
+	if channels := cfg.route(warning); len(channels) != 1 || channels[0].Name != "ops-slack" {
+		t.Errorf("warning routed to %+v", channels)
+	}
+	critical := Notification{Event: events.AlertCrashLoop, Severity: SeverityCritical, WorkspaceID: "acme"}
+	if channels := cfg.route(critical); len(channels) != 2 {
+		t.Errorf("expected both channels once each, got %+v", channels)
+	}
+	other := Notification{Event: events.AlertQuarantined, Severity: SeverityCritical, WorkspaceID: "globex"}
+	if channels := cfg.route(other); len(channels) != 1 {
+		t.Errorf("expected only the catch-all rule for another workspace, got %+v", channels)
+	}
+}
+
+func TestRedactedKeepsSecrets(t *testing.T) {
+	cfg := testConfig("https://hooks.slack.com/services/secret")
+	redacted := cfg.Redacted()
+	if redacted.Channels[0].URL != redact.Placeholder || redacted.Channels[1].SMTP.Password != redact.Placeholder {
+		t.Fatalf("secrets not redacted: %+v", redacted.Channels)
+	}
+	if cfg.Channels[1].SMTP.Password != "hunter2" {
+		t.Fatal("redaction modified the original config")
+	}
+
+	restored := redacted.keepSecrets(cfg)
+	if restored.Channels[0].URL != cfg.Channels[0].URL || restored.Channels[1].SMTP.Password != "hunter2" {
+		t.Errorf("placeholders not replaced by the stored secrets: %+v", restored.Channels)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	var mail string
+	n := &Notifier{
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		httpClient: server.Client(),
+		sendMail: func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			mail = addr + "\n" + string(msg)
+			return nil
+		},
+	}
+	notification := Notification{
+		Event: events.AlertCrashLoop, Severity: SeverityCritical, InstanceID: "inst-1",
+		Name: "github", Message: "3 failures within 10m0s", Timestamp: time.Now(),
+	}
+
+	cfg := testConfig(server.URL)
+	if err := n.send(context.Background(), cfg.Channels[0], notification); err != nil {
+		t.Fatalf("slack: %v", err)
+	}
+	if !strings.Contains(received["text"], "MCP instance github: crash loop") {
+		t.Errorf("unexpected slack text %q", received["text"])
+	}
+
+	if err := n.send(context.Background(), cfg.Channels[1], notification); err != nil {
+		t.Fatalf("email: %v", err)
+	}
+	if !strings.HasPrefix(mail, "smtp.example.com:587\n") || !strings.Contains(mail, "Subject: [critical] MCP instance github: crash loop\r\n") {
+		t.Errorf("unexpected email %q", mail)
+	}
+}
@@ -0,0 +1,285 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/redact"
+	"github.com/agentarea/mcp-manager/internal/secrets"
+)
+
+// Grant identifies a user's authorization with a provider for one MCP instance
+type Grant struct {
+	WorkspaceID  string
+	InstanceID   string
+	Provider     string
+	RefreshToken string // Plain value or secret reference (secret_ref:...)
+}
+
+// grantKey scopes cached tokens so they are never shared across workspaces
+type grantKey struct {
+	workspace string
+	provider  string
+	instance  string
+}
+
+// Broker mints and refreshes OAuth access tokens for MCP servers that act on
+// behalf of a user (GitHub, Google, ...). Refresh tokens are resolved through
+// the secret resolver, and rotated ones written back when it is a
+// secrets.SecretWriter; access tokens are cached in memory per workspace.
+type Broker struct {
+	providers map[string]*oauth2.Config
+	resolver  secrets.SecretResolver
+	skew      time.Duration
+	timeout   time.Duration
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	sources map[grantKey]oauth2.TokenSource
+}
+
+// NewBroker creates a token broker for the configured providers
+func NewBroker(cfg config.OAuthConfig, resolver secrets.SecretResolver, logger *slog.Logger) *Broker {
+	providers := make(map[string]*oauth2.Config, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		if p.ClientID == "" || p.TokenURL == "" {
+			logger.Warn("Skipping OAuth provider with incomplete configuration",
+				slog.String("provider", name))
+			continue
+		}
+		providers[name] = &oauth2.Config{
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: p.TokenURL},
+			Scopes:       p.Scopes,
+		}
+	}
+
+	return &Broker{
+		providers: providers,
+		resolver:  resolver,
+		skew:      cfg.RefreshSkew,
+		timeout:   cfg.RefreshTimeout,
+		logger:    logger,
+		sources:   make(map[grantKey]oauth2.TokenSource),
+	}
+}
+
+// HasProvider reports whether the provider is configured
+func (b *Broker) HasProvider(name string) bool {
+	_, exists := b.providers[strings.ToLower(name)]
+	return exists
+}
+
+// AccessToken returns a valid access token for the grant, refreshing it if
+// it is missing or about to expire
+func (b *Broker) AccessToken(ctx context.Context, grant Grant) (string, error) {
+	source, err := b.sourceFor(grant)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := source.Token()
+	if err != nil {
+		b.logger.Error("Failed to refresh OAuth access token",
+			slog.String("provider", grant.Provider),
+			slog.String("workspace_id", grant.WorkspaceID),
+			slog.String("instance_id", grant.InstanceID),
+			slog.String("error", err.Error()))
+		return "", fmt.Errorf("failed to refresh %s access token: %w", grant.Provider, err)
+	}
+
+	return token.AccessToken, nil
+}
+
+// Source returns a credential source bound to the grant, suitable for
+// injecting tokens into proxied requests
+func (b *Broker) Source(grant Grant) *GrantSource {
+	return &GrantSource{broker: b, grant: grant}
+}
+
+// ForgetInstance drops cached tokens for an instance
+func (b *Broker) ForgetInstance(instanceID string) {
+	b.forget(func(k grantKey) bool { return k.instance == instanceID })
+}
+
+// ForgetWorkspace drops all cached tokens for a workspace
+func (b *Broker) ForgetWorkspace(workspaceID string) {
+	b.forget(func(k grantKey) bool { return k.workspace == workspaceID })
+}
+
+// forget removes cached token sources matching fn
+func (b *Broker) forget(fn func(grantKey) bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key := range b.sources {
+		if fn(key) {
+			delete(b.sources, key)
+		}
+	}
+}
+
+// sourceFor returns the cached token source for a grant, creating it on first use
+func (b *Broker) sourceFor(grant Grant) (oauth2.TokenSource, error) {
+	if grant.WorkspaceID == "" {
+		return nil, fmt.Errorf("OAuth grant requires a workspace ID")
+	}
+	provider := strings.ToLower(grant.Provider)
+	cfg, exists := b.providers[provider]
+	if !exists {
+		return nil, fmt.Errorf("OAuth provider not configured: %s", grant.Provider)
+	}
+
+	key := grantKey{workspace: grant.WorkspaceID, provider: provider, instance: grant.InstanceID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if source, exists := b.sources[key]; exists {
+		return source, nil
+	}
+
+	if err := b.checkWorkspace(grant); err != nil {
+		return nil, err
+	}
+	refreshToken, err := b.resolveRefreshToken(grant, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	refresher := &refreshSource{
+		config:       cfg,
+		refreshToken: refreshToken,
+		timeout:      b.timeout,
+		persist:      b.refreshTokenWriter(grant, provider),
+	}
+	source := oauth2.ReuseTokenSourceWithExpiry(nil, refresher, b.skew)
+	b.sources[key] = source
+
+	return source, nil
+}
+
+// checkWorkspace denies grants whose workspace is not the one owning the
+// instance in the platform database
+func (b *Broker) checkWorkspace(grant Grant) error {
+	if b.resolver == nil {
+		return nil
+	}
+	owner, err := b.resolver.WorkspaceOf(grant.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to look up the workspace of instance %s: %w", grant.InstanceID, err)
+	}
+	if owner != grant.WorkspaceID {
+		return fmt.Errorf("%w: instance %s does not belong to workspace %s", secrets.ErrAccessDenied, grant.InstanceID, grant.WorkspaceID)
+	}
+	return nil
+}
+
+// refreshSecretKey is the environment key a provider's refresh token is
+// resolved under
+func refreshSecretKey(provider string) string {
+	return "OAUTH_" + strings.ToUpper(strings.ReplaceAll(provider, "-", "_")) + "_REFRESH_TOKEN"
+}
+
+// resolveRefreshToken resolves a secret reference to the stored refresh token
+func (b *Broker) resolveRefreshToken(grant Grant, provider string) (string, error) {
+	if grant.RefreshToken == "" {
+		return "", fmt.Errorf("OAuth grant for %s has no refresh token", provider)
+	}
+	if b.resolver == nil {
+		return grant.RefreshToken, nil
+	}
+
+	secretKey := refreshSecretKey(provider)
+	resolved, err := b.resolver.ResolveSecrets(grant.WorkspaceID, grant.InstanceID, map[string]string{
+		secretKey: grant.RefreshToken,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve refresh token: %w", err)
+	}
+
+	return resolved[secretKey], nil
+}
+
+// refreshTokenWriter returns how a rotated refresh token of the grant is
+// stored, or nil when it is not a secret reference the resolver can update.
+// A token that cannot be stored is still used until the broker restarts.
+func (b *Broker) refreshTokenWriter(grant Grant, provider string) func(string) {
+	writer, ok := b.resolver.(secrets.SecretWriter)
+	if !ok || !strings.HasPrefix(grant.RefreshToken, "secret_ref:") {
+		return nil
+	}
+	return func(refreshToken string) {
+		err := writer.UpdateSecret(grant.WorkspaceID, grant.InstanceID, refreshSecretKey(provider), grant.RefreshToken, refreshToken)
+		if err != nil {
+			b.logger.Error("Failed to store rotated OAuth refresh token",
+				slog.String("provider", provider),
+				slog.String("workspace_id", grant.WorkspaceID),
+				slog.String("instance_id", grant.InstanceID),
+				slog.String("error", err.Error()))
+			return
+		}
+		b.logger.Info("Stored rotated OAuth refresh token",
+			slog.String("provider", provider),
+			slog.String("instance_id", grant.InstanceID))
+	}
+}
+
+// refreshSource exchanges the refresh token for a new access token on every
+// call; caching is left to the wrapping ReuseTokenSource
+type refreshSource struct {
+	config  *oauth2.Config
+	timeout time.Duration
+	persist func(refreshToken string) // Stores rotated refresh tokens, may be nil
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+// Token implements oauth2.TokenSource
+func (s *refreshSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	token, err := s.config.TokenSource(ctx, &oauth2.Token{RefreshToken: s.refreshToken}).Token()
+	if err != nil {
+		return nil, err
+	}
+
+	// Providers that rotate refresh tokens return a new one with each refresh
+	if token.RefreshToken != "" && token.RefreshToken != s.refreshToken {
+		s.refreshToken = token.RefreshToken
+		redact.AddSecret(token.RefreshToken)
+		if s.persist != nil {
+			s.persist(token.RefreshToken)
+		}
+	}
+	redact.AddSecret(token.AccessToken)
+
+	return token, nil
+}
+
+// GrantSource supplies access tokens for a single grant
+type GrantSource struct {
+	broker *Broker
+	grant  Grant
+}
+
+// Token returns a valid access token for the grant
+func (s *GrantSource) Token(ctx context.Context) (string, error) {
+	return s.broker.AccessToken(ctx, s.grant)
+}
@@ -0,0 +1,210 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+	"github.com/agentarea/mcp-manager/internal/secrets"
+)
+
+func newTestBroker(t *testing.T, tokenURL string) *Broker {
+	t.Helper()
+
+	cfg := config.OAuthConfig{
+		Providers: map[string]config.OAuthProviderConfig{
+			"github": {ClientID: "client", ClientSecret: "secret", TokenURL: tokenURL},
+		},
+		RefreshSkew: time.Minute,
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewBroker(cfg, nil, logger)
+}
+
+func TestBrokerRefreshesAndCachesPerWorkspace(t *testing.T) {
+	var refreshes atomic.Int32
+	var lastRefreshToken atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		lastRefreshToken.Store(r.PostForm.Get("refresh_token"))
+		n := refreshes.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"refresh_token": fmt.Sprintf("rotated-%d", n),
+			"token_type":    "bearer",
+			"expires_in":    3600,
+		})
+	}))
+	defer server.Close()
+
+	broker := newTestBroker(t, server.URL)
+	ctx := context.Background()
+	grantA := Grant{WorkspaceID: "ws-a", InstanceID: "inst-1", Provider: "github", RefreshToken: "refresh-a"}
+	grantB := Grant{WorkspaceID: "ws-b", InstanceID: "inst-1", Provider: "github", RefreshToken: "refresh-b"}
+
+	tokenA, err := broker.AccessToken(ctx, grantA)
+	if err != nil {
+		t.Fatalf("AccessToken failed: %v", err)
+	}
+	if again, _ := broker.AccessToken(ctx, grantA); again != tokenA {
+		t.Errorf("Expected cached token %s, got %s", tokenA, again)
+	}
+	if refreshes.Load() != 1 {
+		t.Errorf("Expected a single refresh, got %d", refreshes.Load())
+	}
+
+	tokenB, err := broker.AccessToken(ctx, grantB)
+	if err != nil {
+		t.Fatalf("AccessToken failed: %v", err)
+	}
+	if tokenB == tokenA {
+		t.Error("Expected workspaces to get separate tokens")
+	}
+	if got := lastRefreshToken.Load(); got != "refresh-b" {
+		t.Errorf("Expected workspace B's refresh token to be used, got %v", got)
+	}
+
+	// After forgetting the workspace the next call refreshes again
+	broker.ForgetWorkspace("ws-a")
+	if _, err := broker.AccessToken(ctx, grantA); err != nil {
+		t.Fatalf("AccessToken failed: %v", err)
+	}
+	if refreshes.Load() != 3 {
+		t.Errorf("Expected 3 refreshes, got %d", refreshes.Load())
+	}
+}
+
+func TestBrokerRejectsIncompleteGrants(t *testing.T) {
+	broker := newTestBroker(t, "http://127.0.0.1:0")
+	ctx := context.Background()
+
+	if _, err := broker.AccessToken(ctx, Grant{Provider: "github", RefreshToken: "x"}); err == nil {
+		t.Error("Expected error for grant without workspace")
+	}
+	if _, err := broker.AccessToken(ctx, Grant{WorkspaceID: "ws", Provider: "gitlab", RefreshToken: "x"}); err == nil {
+		t.Error("Expected error for unconfigured provider")
+	}
+	if _, err := broker.AccessToken(ctx, Grant{WorkspaceID: "ws", Provider: "github"}); err == nil {
+		t.Error("Expected error for grant without refresh token")
+	}
+}
+
+// storeResolver keeps secrets of instances owned by ws-a in memory
+type storeResolver struct {
+	mu      sync.Mutex
+	secrets map[string]string // secret_ref -> value
+}
+
+func (r *storeResolver) ResolveSecrets(_, _ string, env map[string]string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resolved := make(map[string]string, len(env))
+	for key, ref := range env {
+		resolved[key] = r.secrets[ref]
+	}
+	return resolved, nil
+}
+
+func (r *storeResolver) UpdateSecret(_, _, _, ref, value string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets[ref] = value
+	return nil
+}
+
+func (r *storeResolver) WorkspaceOf(string) (string, error) { return "ws-a", nil }
+
+func (r *storeResolver) Close() error { return nil }
+
+func TestBrokerStoresRotatedRefreshTokens(t *testing.T) {
+	var lastRefreshToken atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		lastRefreshToken.Store(r.PostForm.Get("refresh_token"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access",
+			"refresh_token": "rotated",
+			"token_type":    "bearer",
+			"expires_in":    3600,
+		})
+	}))
+	defer server.Close()
+
+	resolver := &storeResolver{secrets: map[string]string{"secret_ref:github": "original"}}
+	cfg := config.OAuthConfig{
+		Providers: map[string]config.OAuthProviderConfig{
+			"github": {ClientID: "client", TokenURL: server.URL},
+		},
+		RefreshTimeout: time.Second,
+	}
+	broker := NewBroker(cfg, resolver, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	grant := Grant{WorkspaceID: "ws-a", InstanceID: "inst-1", Provider: "github", RefreshToken: "secret_ref:github"}
+	if _, err := broker.AccessToken(ctx, grant); err != nil {
+		t.Fatalf("AccessToken failed: %v", err)
+	}
+	if got := lastRefreshToken.Load(); got != "original" {
+		t.Errorf("Expected the stored refresh token to be used, got %v", got)
+	}
+	if got := resolver.secrets["secret_ref:github"]; got != "rotated" {
+		t.Errorf("Expected the rotated refresh token to be stored, got %q", got)
+	}
+
+	// A restarted broker continues with the rotated token
+	broker.ForgetInstance("inst-1")
+	if _, err := broker.AccessToken(ctx, grant); err != nil {
+		t.Fatalf("AccessToken failed: %v", err)
+	}
+	if got := lastRefreshToken.Load(); got != "rotated" {
+		t.Errorf("Expected the rotated refresh token to be used, got %v", got)
+	}
+
+	// Grants naming another workspace than the instance's are denied
+	grant.WorkspaceID = "ws-b"
+	if _, err := broker.AccessToken(ctx, grant); !errors.Is(err, secrets.ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied for a foreign workspace, got %v", err)
+	}
+}
+
+func TestBrokerRefreshTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := config.OAuthConfig{
+		Providers: map[string]config.OAuthProviderConfig{
+			"github": {ClientID: "client", TokenURL: server.URL},
+		},
+		RefreshTimeout: 50 * time.Millisecond,
+	}
+	broker := NewBroker(cfg, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	start := time.Now()
+	_, err := broker.AccessToken(context.Background(), Grant{WorkspaceID: "ws", InstanceID: "inst-1", Provider: "github", RefreshToken: "x"})
+	if err == nil {
+		t.Fatal("Expected an error from a token endpoint that does not answer")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Refresh took %s despite the timeout", elapsed)
+	}
+}
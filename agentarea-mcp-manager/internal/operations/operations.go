@@ -0,0 +1,150 @@
+// Package operations tracks long-running instance operations, such as
+// creates that pull images and wait for containers or deployments, so they
+// can be listed and cancelled while in flight.
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operation kinds
+const (
+	KindCreate = "create"
+	KindUpdate = "update"
+)
+
+// Operation sources
+const (
+	SourceAPI   = "api"
+	SourceEvent = "event"
+)
+
+// ErrCancelled is the cause of the context of a cancelled operation
+var ErrCancelled = errors.New("operation cancelled")
+
+// ErrDuplicateID is returned when starting an operation with the ID of one
+// still in flight
+var ErrDuplicateID = errors.New("operation ID already in use")
+
+// Operation is an in-flight operation
+type Operation struct {
+	ID          string     `json:"id"`
+	Kind        string     `json:"kind"`
+	Source      string     `json:"source"`
+	InstanceID  string     `json:"instance_id"`
+	Name        string     `json:"name,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"` // Set once cancellation was requested
+}
+
+type entry struct {
+	op     Operation
+	cancel context.CancelCauseFunc
+}
+
+// Tracker holds the in-flight operations. A nil Tracker tracks nothing, so
+// callers need not check whether tracking is enabled.
+type Tracker struct {
+	mu  sync.Mutex
+	ops map[string]*entry
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{ops: make(map[string]*entry)}
+}
+
+// Start tracks an operation until done is called. The returned context is
+// cancelled with ErrCancelled by Cancel; an empty op.ID is generated.
+func (t *Tracker) Start(ctx context.Context, op Operation) (context.Context, Operation, func(), error) {
+	if t == nil {
+		return ctx, op, func() {}, nil
+	}
+	if op.ID == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return ctx, op, nil, err
+		}
+		op.ID = "op-" + hex.EncodeToString(b)
+	}
+	op.StartedAt = time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.ops[op.ID]; exists {
+		return ctx, op, nil, ErrDuplicateID
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	t.ops[op.ID] = &entry{op: op, cancel: cancel}
+
+	done := func() {
+		t.mu.Lock()
+		delete(t.ops, op.ID)
+		t.mu.Unlock()
+		cancel(nil)
+	}
+	return ctx, op, done, nil
+}
+
+// Cancel cancels an in-flight operation; ok is false if there is none with
+// the ID. The operation stays listed until it has rolled back.
+func (t *Tracker) Cancel(id string) (Operation, bool) {
+	if t == nil {
+		return Operation{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.ops[id]
+	if !ok {
+		return Operation{}, false
+	}
+	if e.op.CancelledAt == nil {
+		now := time.Now()
+		e.op.CancelledAt = &now
+		e.cancel(ErrCancelled)
+	}
+	return e.op, true
+}
+
+// Get returns an in-flight operation
+func (t *Tracker) Get(id string) (Operation, bool) {
+	if t == nil {
+		return Operation{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.ops[id]
+	if !ok {
+		return Operation{}, false
+	}
+	return e.op, true
+}
+
+// List returns the in-flight operations, oldest first
+func (t *Tracker) List() []Operation {
+	ops := []Operation{}
+	if t == nil {
+		return ops
+	}
+	t.mu.Lock()
+	for _, e := range t.ops {
+		ops = append(ops, e.op)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool { return ops[i].StartedAt.Before(ops[j].StartedAt) })
+	return ops
+}
+
+// Cancelled reports whether ctx belongs to an operation cancelled with Cancel
+func Cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCancelled)
+}
@@ -0,0 +1,60 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTrackerCancelsOperation(t *testing.T) {
+	tracker := NewTracker()
+	ctx, op, done, err := tracker.Start(context.Background(), Operation{Kind: KindCreate, Source: SourceAPI, InstanceID: "i-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.ID == "" || op.StartedAt.IsZero() {
+		t.Fatalf("operation = %+v", op)
+	}
+	if _, _, _, err := tracker.Start(context.Background(), Operation{ID: op.ID}); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("duplicate start = %v, want ErrDuplicateID", err)
+	}
+
+	cancelled, ok := tracker.Cancel(op.ID)
+	if !ok || cancelled.CancelledAt == nil {
+		t.Fatalf("Cancel = %+v, %v", cancelled, ok)
+	}
+	if ctx.Err() == nil || !Cancelled(ctx) {
+		t.Error("context not cancelled with ErrCancelled")
+	}
+	// Still listed until done
+	if ops := tracker.List(); len(ops) != 1 || ops[0].CancelledAt == nil {
+		t.Errorf("List = %+v", ops)
+	}
+
+	done()
+	if _, ok := tracker.Get(op.ID); ok {
+		t.Error("operation still tracked after done")
+	}
+	if _, ok := tracker.Cancel(op.ID); ok {
+		t.Error("cancelled a finished operation")
+	}
+}
+
+func TestFinishedOperationIsNotCancelled(t *testing.T) {
+	ctx, _, done, err := NewTracker().Start(context.Background(), Operation{Kind: KindUpdate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done()
+	if ctx.Err() == nil || Cancelled(ctx) {
+		t.Error("done should end the context without marking it cancelled")
+	}
+
+	// A nil tracker passes the context through
+	var tracker *Tracker
+	ctx, _, done, err = tracker.Start(context.Background(), Operation{})
+	if err != nil || ctx.Err() != nil || len(tracker.List()) != 0 {
+		t.Fatalf("nil tracker: ctx err %v, err %v", ctx.Err(), err)
+	}
+	done()
+}
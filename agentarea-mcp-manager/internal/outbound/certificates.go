@@ -0,0 +1,158 @@
+package outbound
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCertificateNotFound is returned when removing an unknown certificate
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// certificateNamePattern keeps names usable as file names
+var certificateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+// Certificate describes a registered CA certificate
+type Certificate struct {
+	Name        string    `json:"name"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"fingerprint"` // SHA-256 of the first certificate
+	Count       int       `json:"count"`       // Certificates in the PEM
+}
+
+// parseCertificates checks that data holds only valid, unexpired
+// certificates and describes them
+func parseCertificates(name string, data []byte) (Certificate, error) {
+	info := Certificate{Name: name}
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			if strings.TrimSpace(string(rest)) != "" {
+				return info, fmt.Errorf("trailing data after the certificates")
+			}
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return info, fmt.Errorf("unexpected PEM block %q, only certificates are accepted", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return info, fmt.Errorf("invalid certificate: %w", err)
+		}
+		if time.Now().After(cert.NotAfter) {
+			return info, fmt.Errorf("certificate %q expired on %s", cert.Subject.CommonName, cert.NotAfter.Format(time.DateOnly))
+		}
+		if info.Count == 0 {
+			sum := sha256.Sum256(cert.Raw)
+			info.Subject = cert.Subject.String()
+			info.Issuer = cert.Issuer.String()
+			info.NotAfter = cert.NotAfter
+			info.Fingerprint = hex.EncodeToString(sum[:])
+		}
+		info.Count++
+	}
+	if info.Count == 0 {
+		return info, fmt.Errorf("no PEM certificates found")
+	}
+	return info, nil
+}
+
+// loadRegistered reads the certificates kept in the CA directory
+func (s *Settings) loadRegistered() error {
+	if s.cfg.CADir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(s.cfg.CADir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CA directory: %w", err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".pem")
+		if entry.IsDir() || !ok || !certificateNamePattern.MatchString(name) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.cfg.CADir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate %s: %w", name, err)
+		}
+		if _, err := parseCertificates(name, data); err != nil {
+			return fmt.Errorf("CA certificate %s: %w", name, err)
+		}
+		s.registered[name] = data
+	}
+	return nil
+}
+
+// AddCertificate registers or replaces a PEM encoded CA certificate (or
+// chain) under name. It is kept in the CA directory, if any, and reaches
+// instances started from now on.
+func (s *Settings) AddCertificate(name string, data []byte) (Certificate, error) {
+	if !certificateNamePattern.MatchString(name) {
+		return Certificate{}, fmt.Errorf("invalid certificate name %q: use lowercase letters, digits, '.', '_' and '-'", name)
+	}
+	info, err := parseCertificates(name, data)
+	if err != nil {
+		return info, err
+	}
+	if s.cfg.CADir != "" {
+		if err := os.MkdirAll(s.cfg.CADir, 0o755); err != nil {
+			return info, fmt.Errorf("failed to create CA directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(s.cfg.CADir, name+".pem"), data, 0o644); err != nil {
+			return info, fmt.Errorf("failed to store CA certificate: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.registered[name] = data
+	s.mu.Unlock()
+	return info, s.rebuild()
+}
+
+// RemoveCertificate unregisters a certificate; running instances keep it
+// until they are recreated
+func (s *Settings) RemoveCertificate(name string) error {
+	s.mu.Lock()
+	_, ok := s.registered[name]
+	delete(s.registered, name)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCertificateNotFound, name)
+	}
+
+	if s.cfg.CADir != "" {
+		if err := os.Remove(filepath.Join(s.cfg.CADir, name+".pem")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove CA certificate: %w", err)
+		}
+	}
+	return s.rebuild()
+}
+
+// Certificates describes the registered certificates, sorted by name
+func (s *Settings) Certificates() []Certificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	certificates := make([]Certificate, 0, len(s.registered))
+	for _, name := range slices.Sorted(maps.Keys(s.registered)) {
+		info, _ := parseCertificates(name, s.registered[name])
+		certificates = append(certificates, info)
+	}
+	return certificates
+}
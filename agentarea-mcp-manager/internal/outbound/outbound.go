@@ -0,0 +1,210 @@
+// Package outbound sends outbound HTTP(S) traffic through a corporate proxy
+// and trusts a corporate CA bundle, both for the manager itself (image pulls,
+// Core API, registry, secret backends) and for the instances it manages.
+package outbound
+
+import (
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// ContainerCABundle is where instances find the merged CA bundle
+const ContainerCABundle = "/etc/ssl/agentarea/ca-bundle.pem"
+
+// mergedBundleName is the merged bundle written for the manager and mounted
+// into Docker instances
+const mergedBundleName = "agentarea-ca-bundle.pem"
+
+// localNoProxy are never proxied from the manager or an instance
+var localNoProxy = []string{"localhost", "127.0.0.1", "::1"}
+
+// clusterNoProxy are the in-cluster names instances reach directly
+var clusterNoProxy = []string{".svc", ".cluster.local"}
+
+// systemBundles are the usual locations of the system roots, merged with the
+// corporate bundle so variables replacing the roots keep public CAs trusted
+var systemBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt", // Debian, Ubuntu, Alpine
+	"/etc/pki/tls/certs/ca-bundle.crt",   // Fedora, RHEL
+	"/etc/ssl/ca-bundle.pem",             // openSUSE
+	"/etc/ssl/cert.pem",                  // macOS, Alpine
+}
+
+// caEnvVars point common HTTP stacks (OpenSSL, Go, Python requests, curl,
+// Node.js) at the CA bundle of an instance
+var caEnvVars = []string{"SSL_CERT_FILE", "REQUESTS_CA_BUNDLE", "CURL_CA_BUNDLE", "NODE_EXTRA_CA_CERTS"}
+
+// Settings are the applied outbound settings. A nil *Settings means none.
+type Settings struct {
+	cfg        config.OutboundConfig
+	mu         sync.RWMutex
+	rebuildMu  sync.Mutex        // Serializes writes of the merged bundle
+	bundle     string            // Merged roots; empty without extra certificates
+	registered map[string][]byte // Registered certificates (PEM) by name
+}
+
+// Configure applies the settings to the manager process through the standard
+// proxy and SSL_CERT_FILE variables, which every HTTP client, the secret
+// backends and the container runtime commands read. Go reads them once, so
+// it must run before the first outbound connection. Certificates registered
+// later only reach instances.
+func Configure(cfg config.OutboundConfig) (*Settings, error) {
+	s := &Settings{cfg: cfg, registered: make(map[string][]byte)}
+	if err := s.loadRegistered(); err != nil {
+		return nil, err
+	}
+	if err := s.rebuild(); err != nil {
+		return nil, err
+	}
+
+	if bundle := s.bundlePath(); bundle != "" {
+		if err := os.Setenv("SSL_CERT_FILE", bundle); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range s.proxyVars(localNoProxy) {
+		if err := os.Setenv(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Active reports whether a proxy or extra CA certificates are configured
+func (s *Settings) Active() bool {
+	return s != nil && (s.cfg.HTTPProxy != "" || s.cfg.HTTPSProxy != "" || s.bundlePath() != "")
+}
+
+// rebuild writes the system roots followed by the configured bundle and the
+// registered certificates to the merged bundle, or clears it when there are
+// no extra certificates. The file is replaced, not rewritten, so running
+// instances keep the bundle they were started with.
+func (s *Settings) rebuild() error {
+	s.rebuildMu.Lock()
+	defer s.rebuildMu.Unlock()
+
+	var extra []byte
+	if s.cfg.CABundle != "" {
+		corporate, err := os.ReadFile(s.cfg.CABundle)
+		if err != nil {
+			return fmt.Errorf("failed to read outbound CA bundle: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(corporate) {
+			return fmt.Errorf("no certificates in outbound CA bundle %s", s.cfg.CABundle)
+		}
+		extra = append(extra, corporate...)
+	}
+	s.mu.RLock()
+	for _, name := range slices.Sorted(maps.Keys(s.registered)) {
+		extra = append(extra, '\n')
+		extra = append(extra, s.registered[name]...)
+	}
+	s.mu.RUnlock()
+
+	if len(extra) == 0 {
+		s.setBundle("")
+		return nil
+	}
+
+	var merged []byte
+	for _, path := range systemBundles {
+		if system, err := os.ReadFile(path); err == nil {
+			merged = append(system, '\n')
+			break
+		}
+	}
+	merged = append(merged, extra...)
+
+	path := filepath.Join(os.TempDir(), mergedBundleName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, merged, 0o644); err != nil {
+		return fmt.Errorf("failed to write merged CA bundle: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write merged CA bundle: %w", err)
+	}
+	s.setBundle(path)
+	return nil
+}
+
+func (s *Settings) setBundle(path string) {
+	s.mu.Lock()
+	s.bundle = path
+	s.mu.Unlock()
+}
+
+func (s *Settings) bundlePath() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bundle
+}
+
+// proxyVars returns the proxy variables in both spellings, with noProxy
+// ahead of the configured exclusions
+func (s *Settings) proxyVars(noProxy []string) map[string]string {
+	vars := make(map[string]string)
+	if s.cfg.HTTPProxy == "" && s.cfg.HTTPSProxy == "" {
+		return vars
+	}
+	excluded := append([]string{}, noProxy...)
+	for _, host := range strings.Split(s.cfg.NoProxy, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			excluded = append(excluded, host)
+		}
+	}
+	for name, value := range map[string]string{
+		"HTTP_PROXY":  s.cfg.HTTPProxy,
+		"HTTPS_PROXY": s.cfg.HTTPSProxy,
+		"NO_PROXY":    strings.Join(excluded, ","),
+	} {
+		if value != "" {
+			vars[name] = value
+			vars[strings.ToLower(name)] = value
+		}
+	}
+	return vars
+}
+
+// Bundle returns the host path of the merged CA bundle, or "" without one
+func (s *Settings) Bundle() string {
+	if s == nil || !s.cfg.Inject {
+		return ""
+	}
+	return s.bundlePath()
+}
+
+// Environment returns the proxy and CA variables for an instance, without
+// those already set in env. Instances reach in-cluster services directly.
+func (s *Settings) Environment(env map[string]string) map[string]string {
+	if s == nil || !s.cfg.Inject {
+		return nil
+	}
+	vars := s.proxyVars(append(append([]string{}, localNoProxy...), clusterNoProxy...))
+	if s.bundlePath() != "" {
+		for _, name := range caEnvVars {
+			vars[name] = ContainerCABundle
+		}
+	}
+	maps.DeleteFunc(vars, func(name, _ string) bool {
+		_, set := env[name]
+		return set
+	})
+	return vars
+}
+
+// Direct returns a transport that never uses the proxy, for traffic to the
+// managed instances themselves
+func Direct() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return transport
+}
@@ -6,13 +6,18 @@ import (
 	"log/slog"
 
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/oauth"
 	"github.com/agentarea/mcp-manager/internal/secrets"
+	"github.com/agentarea/mcp-manager/internal/variables"
 )
 
 // DockerProvider handles Docker-based MCP server instances
 type DockerProvider struct {
 	secretResolver   secrets.SecretResolver
 	containerManager ContainerManagerInterface
+	tokenBroker      *oauth.Broker
+	variables        *variables.Store
+	secrets          *secretWatch
 	logger           *slog.Logger
 }
 
@@ -31,6 +36,16 @@ func NewDockerProvider(secretResolver secrets.SecretResolver, containerManager C
 	}
 }
 
+// SetTokenBroker enables injection of brokered OAuth tokens into instance env
+func (p *DockerProvider) SetTokenBroker(broker *oauth.Broker) {
+	p.tokenBroker = broker
+}
+
+// SetVariableStore enables ${workspace.NAME} references in instance env
+func (p *DockerProvider) SetVariableStore(store *variables.Store) {
+	p.variables = store
+}
+
 // CreateInstance creates a new Docker container for the MCP server using the container manager
 func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MCPServerInstance) error {
 	p.logger.Info("Creating Docker container via container manager",
@@ -43,6 +58,10 @@ func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MC
 		resolvedSpec[key] = value
 	}
 
+	// Environment before and after secret resolution, kept for the secret watch
+	var secretEnv, resolvedSecretEnv map[string]string
+	var secretWorkspace string
+
 	// Resolve environment variables (including secrets)
 	if envInterface, exists := resolvedSpec["environment"]; exists {
 		if envMap, ok := envInterface.(map[string]interface{}); ok {
@@ -52,7 +71,27 @@ func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MC
 				stringEnvMap[key] = fmt.Sprintf("%v", value)
 			}
 
-			resolvedEnv, err := p.secretResolver.ResolveSecrets(instance.InstanceID, stringEnvMap)
+			workspaceID, err := instanceWorkspace(p.secretResolver, instance)
+			if err != nil {
+				p.logger.Error("Failed to verify instance workspace",
+					slog.String("instance_id", instance.InstanceID),
+					slog.String("error", err.Error()))
+				return err
+			}
+
+			// Expand workspace variables before secrets so variables may hold secret references
+			if p.variables != nil {
+				expanded, err := p.variables.Resolve(ctx, workspaceID, stringEnvMap)
+				if err != nil {
+					p.logger.Error("Failed to resolve workspace variables",
+						slog.String("instance_id", instance.InstanceID),
+						slog.String("error", err.Error()))
+					return fmt.Errorf("failed to resolve workspace variables: %w", err)
+				}
+				stringEnvMap = expanded
+			}
+
+			resolvedEnv, err := p.secretResolver.ResolveSecrets(workspaceID, instance.InstanceID, stringEnvMap)
 			if err != nil {
 				p.logger.Error("Failed to resolve secrets",
 					slog.String("instance_id", instance.InstanceID),
@@ -60,6 +99,8 @@ func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MC
 				return fmt.Errorf("failed to resolve secrets: %w", err)
 			}
 
+			secretEnv, resolvedSecretEnv, secretWorkspace = stringEnvMap, resolvedEnv, workspaceID
+
 			// Convert back to map[string]interface{} for json_spec
 			resolvedEnvInterface := make(map[string]interface{})
 			for key, value := range resolvedEnv {
@@ -69,6 +110,14 @@ func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MC
 		}
 	}
 
+	// Inject a brokered OAuth access token into the environment at start
+	if err := p.injectOAuthToken(ctx, instance, resolvedSpec); err != nil {
+		p.logger.Error("Failed to obtain OAuth access token",
+			slog.String("instance_id", instance.InstanceID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("failed to obtain OAuth access token: %w", err)
+	}
+
 	// Use the container manager to create the container
 	// This ensures the container is properly tracked in the manager's internal map
 	err := p.containerManager.HandleMCPInstanceCreated(ctx, instance.InstanceID, instance.Name, resolvedSpec)
@@ -79,6 +128,8 @@ func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MC
 		return fmt.Errorf("failed to create container: %w", err)
 	}
 
+	p.watchSecrets(instance, secretWorkspace, secretEnv, resolvedSecretEnv)
+
 	p.logger.Info("Successfully created Docker container via container manager",
 		slog.String("instance_id", instance.InstanceID),
 		slog.String("name", instance.Name))
@@ -86,6 +137,38 @@ func (p *DockerProvider) CreateInstance(ctx context.Context, instance *models.MC
 	return nil
 }
 
+// injectOAuthToken adds the access token for json_spec.oauth to the
+// environment. The token is minted once at start; long-running servers that
+// need fresh tokens should be reached through the proxy instead.
+func (p *DockerProvider) injectOAuthToken(ctx context.Context, instance *models.MCPServerInstance, spec map[string]interface{}) error {
+	oauthCfg, ok := oauthSpecFrom(instance)
+	if !ok {
+		return nil
+	}
+	if p.tokenBroker == nil {
+		return fmt.Errorf("instance requests OAuth provider %s but no token broker is configured", oauthCfg.grant.Provider)
+	}
+	workspaceID, err := instanceWorkspace(p.secretResolver, instance)
+	if err != nil {
+		return err
+	}
+	oauthCfg.grant.WorkspaceID = workspaceID
+
+	token, err := p.tokenBroker.AccessToken(ctx, oauthCfg.grant)
+	if err != nil {
+		return err
+	}
+
+	env, ok := spec["environment"].(map[string]interface{})
+	if !ok {
+		env = make(map[string]interface{})
+	}
+	env[oauthCfg.env] = token
+	spec["environment"] = env
+
+	return nil
+}
+
 // DeleteInstance removes the Docker container using the container manager
 func (p *DockerProvider) DeleteInstance(ctx context.Context, instanceID, name string) error {
 	p.logger.Info("Deleting Docker container via container manager",
@@ -102,6 +185,11 @@ func (p *DockerProvider) DeleteInstance(ctx context.Context, instanceID, name st
 		return fmt.Errorf("failed to delete container: %w", err)
 	}
 
+	if p.tokenBroker != nil {
+		p.tokenBroker.ForgetInstance(instanceID)
+	}
+	p.forgetSecrets(instanceID)
+
 	p.logger.Info("Successfully deleted Docker container via container manager",
 		slog.String("instance_id", instanceID),
 		slog.String("name", name))
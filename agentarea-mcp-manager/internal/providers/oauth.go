@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/oauth"
+)
+
+// oauthSpec is the parsed json_spec.oauth section of an instance
+type oauthSpec struct {
+	grant oauth.Grant
+	env   string // Environment variable receiving the access token at start
+}
+
+// oauthSpecFrom parses json_spec.oauth:
+//
+//	"oauth": {"provider": "github", "refresh_token": "secret_ref:...",
+//	          "env": "GITHUB_TOKEN"}
+//
+// env defaults to <PROVIDER>_TOKEN. The grant's workspace is left for the
+// caller to set from the platform database.
+func oauthSpecFrom(instance *models.MCPServerInstance) (oauthSpec, bool) {
+	section, ok := instance.JSONSpec["oauth"].(map[string]interface{})
+	if !ok {
+		return oauthSpec{}, false
+	}
+
+	provider, _ := section["provider"].(string)
+	if provider == "" {
+		return oauthSpec{}, false
+	}
+
+	refreshToken, _ := section["refresh_token"].(string)
+
+	env, _ := section["env"].(string)
+	if env == "" {
+		env = strings.ToUpper(strings.ReplaceAll(provider, "-", "_")) + "_TOKEN"
+	}
+
+	return oauthSpec{
+		grant: oauth.Grant{
+			InstanceID:   instance.InstanceID,
+			Provider:     provider,
+			RefreshToken: refreshToken,
+		},
+		env: env,
+	}, true
+}
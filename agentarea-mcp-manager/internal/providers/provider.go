@@ -2,8 +2,10 @@ package providers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/secrets"
 )
 
 // Provider defines the interface for MCP server providers
@@ -26,23 +28,55 @@ func NewProviderManager(dockerProvider *DockerProvider, urlProvider *URLProvider
 	}
 }
 
+// Provider types
+const (
+	TypeDocker = "docker"
+	TypeURL    = "url"
+)
+
+// TypeOf returns the provider type serving the instance. Unrecognized or
+// missing types default to docker.
+func TypeOf(instance *models.MCPServerInstance) string {
+	if typeStr, ok := instance.JSONSpec["type"].(string); ok && typeStr == TypeURL {
+		return TypeURL
+	}
+	return TypeDocker
+}
+
+// instanceWorkspace returns the workspace owning an instance according to the
+// platform database. json_spec.workspace_id is only checked against it, and
+// used as is when no secret resolver is configured.
+func instanceWorkspace(resolver secrets.SecretResolver, instance *models.MCPServerInstance) (string, error) {
+	claimed, _ := instance.JSONSpec["workspace_id"].(string)
+	if resolver == nil {
+		return claimed, nil
+	}
+	owner, err := resolver.WorkspaceOf(instance.InstanceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the workspace of instance %s: %w", instance.InstanceID, err)
+	}
+	if claimed != "" && claimed != owner {
+		return "", fmt.Errorf("%w: instance %s does not belong to workspace %s", secrets.ErrAccessDenied, instance.InstanceID, claimed)
+	}
+	return owner, nil
+}
+
 // GetProvider returns the appropriate provider based on the instance type
 func (pm *ProviderManager) GetProvider(instance *models.MCPServerInstance) (Provider, error) {
-	// Check the type in json_spec
-	if typeInterface, exists := instance.JSONSpec["type"]; exists {
-		if typeStr, ok := typeInterface.(string); ok {
-			switch typeStr {
-			case "docker":
-				return pm.dockerProvider, nil
-			case "url":
-				return pm.urlProvider, nil
-			default:
-				// Default to docker if type is not recognized
-				return pm.dockerProvider, nil
-			}
+	return pm.ProviderByType(TypeOf(instance))
+}
+
+// ProviderByType returns the provider registered for the given type
+func (pm *ProviderManager) ProviderByType(providerType string) (Provider, error) {
+	switch providerType {
+	case TypeDocker:
+		if pm.dockerProvider == nil {
+			return nil, fmt.Errorf("docker provider is not available in this environment")
 		}
+		return pm.dockerProvider, nil
+	case TypeURL:
+		return pm.urlProvider, nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", providerType)
 	}
-
-	// Default to docker provider if no type specified
-	return pm.dockerProvider, nil
 }
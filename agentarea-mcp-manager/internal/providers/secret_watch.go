@@ -0,0 +1,241 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// secretRefPrefix marks environment values resolved by the secret resolver
+const secretRefPrefix = "secret_ref:"
+
+// Secret change actions reported in secrets changed events
+const (
+	SecretActionRestart = "restart"
+	SecretActionNotify  = "notify"
+)
+
+// SecretChangePublisher announces secret changes and the restarts they cause
+type SecretChangePublisher interface {
+	PublishSecretsChanged(ctx context.Context, instanceID, name string, keys []string, action string) error
+	PublishRestarting(ctx context.Context, instanceID, name, reason string) error
+}
+
+// secretWatch tracks the secret references of running instances so changed
+// values can be detected by polling the resolver
+type secretWatch struct {
+	mu        sync.Mutex
+	instances map[string]*watchedInstance
+	publisher SecretChangePublisher
+	restart   bool // Default for instances without json_spec.restart_on_secret_change
+}
+
+// watchedInstance is an instance with secret references and the digests of
+// the values it was started with
+type watchedInstance struct {
+	instance    *models.MCPServerInstance
+	workspaceID string            // Workspace owning the instance, verified at creation
+	refs        map[string]string // Env key -> secret_ref:... value
+	digests     map[string]string // Env key -> sha256 of the resolved value
+}
+
+// SetSecretWatch enables detection of changed secrets for instances created
+// after this call. When restart is true, instances are recreated with the new
+// values unless their json_spec sets restart_on_secret_change to false.
+func (p *DockerProvider) SetSecretWatch(publisher SecretChangePublisher, restart bool) {
+	p.secrets = &secretWatch{
+		instances: make(map[string]*watchedInstance),
+		publisher: publisher,
+		restart:   restart,
+	}
+}
+
+// WatchSecrets re-resolves watched secrets every interval until ctx is done
+func (p *DockerProvider) WatchSecrets(ctx context.Context, interval time.Duration) {
+	if p.secrets == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.checkSecrets(ctx)
+		}
+	}
+}
+
+// watchSecrets records the secret references of an instance being created.
+// env holds the references after workspace variable expansion and resolved
+// the values the instance starts with.
+func (p *DockerProvider) watchSecrets(instance *models.MCPServerInstance, workspaceID string, env, resolved map[string]string) {
+	if p.secrets == nil {
+		return
+	}
+
+	refs := make(map[string]string)
+	for key, value := range env {
+		if strings.HasPrefix(value, secretRefPrefix) {
+			refs[key] = value
+		}
+	}
+
+	p.secrets.mu.Lock()
+	defer p.secrets.mu.Unlock()
+
+	if len(refs) == 0 {
+		delete(p.secrets.instances, instance.InstanceID)
+		return
+	}
+	p.secrets.instances[instance.InstanceID] = &watchedInstance{
+		instance:    instance,
+		workspaceID: workspaceID,
+		refs:        refs,
+		digests:     secretDigests(refs, resolved),
+	}
+}
+
+// forgetSecrets stops watching an instance
+func (p *DockerProvider) forgetSecrets(instanceID string) {
+	if p.secrets == nil {
+		return
+	}
+	p.secrets.mu.Lock()
+	delete(p.secrets.instances, instanceID)
+	p.secrets.mu.Unlock()
+}
+
+// checkSecrets resolves the references of every watched instance and handles
+// those whose values changed
+func (p *DockerProvider) checkSecrets(ctx context.Context) {
+	p.secrets.mu.Lock()
+	watched := make([]*watchedInstance, 0, len(p.secrets.instances))
+	for _, w := range p.secrets.instances {
+		watched = append(watched, w)
+	}
+	p.secrets.mu.Unlock()
+
+	for _, w := range watched {
+		if ctx.Err() != nil {
+			return
+		}
+		instanceID := w.instance.InstanceID
+		resolved, err := p.secretResolver.ResolveSecrets(w.workspaceID, instanceID, w.refs)
+		if err != nil {
+			p.logger.Warn("Failed to re-resolve instance secrets",
+				slog.String("instance_id", instanceID),
+				slog.String("error", err.Error()))
+			continue
+		}
+
+		digests := secretDigests(w.refs, resolved)
+		changed := changedKeys(w.digests, digests)
+		if len(changed) == 0 {
+			continue
+		}
+
+		p.secrets.mu.Lock()
+		current, ok := p.secrets.instances[instanceID]
+		if ok && current == w {
+			w.digests = digests
+		}
+		p.secrets.mu.Unlock()
+		if !ok || current != w {
+			// Deleted or recreated while resolving
+			continue
+		}
+
+		p.handleSecretChange(ctx, w, changed)
+	}
+}
+
+// handleSecretChange publishes the change and recreates the instance if its
+// policy asks for it
+func (p *DockerProvider) handleSecretChange(ctx context.Context, w *watchedInstance, changed []string) {
+	instance := w.instance
+	action := SecretActionNotify
+	if p.restartOnSecretChange(instance) {
+		action = SecretActionRestart
+	}
+
+	p.logger.Info("Detected changed instance secrets",
+		slog.String("instance_id", instance.InstanceID),
+		slog.Any("keys", changed),
+		slog.String("action", action))
+
+	if p.secrets.publisher != nil {
+		if err := p.secrets.publisher.PublishSecretsChanged(ctx, instance.InstanceID, instance.Name, changed, action); err != nil {
+			p.logger.Warn("Failed to publish secrets changed event",
+				slog.String("instance_id", instance.InstanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if action != SecretActionRestart {
+		return
+	}
+
+	if p.secrets.publisher != nil {
+		if err := p.secrets.publisher.PublishRestarting(ctx, instance.InstanceID, instance.Name, "secret_changed"); err != nil {
+			p.logger.Warn("Failed to publish restarting status",
+				slog.String("instance_id", instance.InstanceID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	// Environment is fixed at container creation, so new values need a new container
+	if err := p.DeleteInstance(ctx, instance.InstanceID, instance.Name); err != nil {
+		p.logger.Error("Failed to remove instance for secret rotation",
+			slog.String("instance_id", instance.InstanceID),
+			slog.String("error", err.Error()))
+		return
+	}
+	if err := p.CreateInstance(ctx, instance); err != nil {
+		p.logger.Error("Failed to recreate instance after secret rotation",
+			slog.String("instance_id", instance.InstanceID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// restartOnSecretChange reads json_spec.restart_on_secret_change, falling
+// back to the configured default
+func (p *DockerProvider) restartOnSecretChange(instance *models.MCPServerInstance) bool {
+	if restart, ok := instance.JSONSpec["restart_on_secret_change"].(bool); ok {
+		return restart
+	}
+	return p.secrets.restart
+}
+
+// secretDigests hashes the resolved value of each reference so values are
+// never kept in memory longer than needed
+func secretDigests(refs, resolved map[string]string) map[string]string {
+	digests := make(map[string]string, len(refs))
+	for key := range refs {
+		sum := sha256.Sum256([]byte(resolved[key]))
+		digests[key] = hex.EncodeToString(sum[:])
+	}
+	return digests
+}
+
+// changedKeys returns the sorted keys whose digests differ
+func changedKeys(before, after map[string]string) []string {
+	var changed []string
+	for key, digest := range after {
+		if before[key] != digest {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
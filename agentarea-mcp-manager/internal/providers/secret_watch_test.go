@@ -0,0 +1,127 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/secrets"
+)
+
+type fakeResolver struct{ values map[string]string }
+
+func (r *fakeResolver) ResolveSecrets(_, _ string, env map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(env))
+	for key, value := range env {
+		if name, ok := strings.CutPrefix(value, secretRefPrefix); ok {
+			value = r.values[name]
+		}
+		resolved[key] = value
+	}
+	return resolved, nil
+}
+
+func (r *fakeResolver) WorkspaceOf(string) (string, error) { return "ws-1", nil }
+
+func (r *fakeResolver) Close() error { return nil }
+
+type fakeContainers struct{ created, deleted int }
+
+func (c *fakeContainers) HandleMCPInstanceCreated(context.Context, string, string, map[string]interface{}) error {
+	c.created++
+	return nil
+}
+
+func (c *fakeContainers) HandleMCPInstanceDeleted(context.Context, string) error {
+	c.deleted++
+	return nil
+}
+
+type fakeSecretPublisher struct {
+	keys    []string
+	actions []string
+}
+
+func (p *fakeSecretPublisher) PublishSecretsChanged(_ context.Context, _, _ string, keys []string, action string) error {
+	p.keys = keys
+	p.actions = append(p.actions, action)
+	return nil
+}
+
+func (p *fakeSecretPublisher) PublishRestarting(context.Context, string, string, string) error {
+	return nil
+}
+
+func TestSecretWatch(t *testing.T) {
+	resolver := &fakeResolver{values: map[string]string{"github": "token-one"}}
+	containers := &fakeContainers{}
+	publisher := &fakeSecretPublisher{}
+
+	provider := NewDockerProvider(resolver, containers, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	provider.SetSecretWatch(publisher, true)
+
+	instance := &models.MCPServerInstance{
+		InstanceID: "inst-1",
+		Name:       "github",
+		JSONSpec: map[string]interface{}{
+			"environment": map[string]interface{}{
+				"GITHUB_TOKEN": "secret_ref:github",
+				"LOG_LEVEL":    "debug",
+			},
+		},
+	}
+	if err := provider.CreateInstance(context.Background(), instance); err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+
+	provider.checkSecrets(context.Background())
+	if len(publisher.actions) != 0 {
+		t.Fatalf("unchanged secrets reported: %v", publisher.actions)
+	}
+
+	resolver.values["github"] = "token-two"
+	provider.checkSecrets(context.Background())
+	if len(publisher.actions) != 1 || publisher.actions[0] != SecretActionRestart {
+		t.Fatalf("expected one restart action, got %v", publisher.actions)
+	}
+	if len(publisher.keys) != 1 || publisher.keys[0] != "GITHUB_TOKEN" {
+		t.Errorf("expected changed key GITHUB_TOKEN, got %v", publisher.keys)
+	}
+	if containers.deleted != 1 || containers.created != 2 {
+		t.Errorf("expected instance to be recreated, got %d deletes and %d creates", containers.deleted, containers.created)
+	}
+
+	// The recreated instance is watched with the new value
+	provider.checkSecrets(context.Background())
+	if len(publisher.actions) != 1 {
+		t.Errorf("rotated secret reported again: %v", publisher.actions)
+	}
+
+	// Instances can opt out of restarts
+	instance.JSONSpec["restart_on_secret_change"] = false
+	resolver.values["github"] = "token-three"
+	provider.checkSecrets(context.Background())
+	if len(publisher.actions) != 2 || publisher.actions[1] != SecretActionNotify {
+		t.Errorf("expected notify action, got %v", publisher.actions)
+	}
+	if containers.created != 2 {
+		t.Errorf("opted-out instance was recreated")
+	}
+}
+
+func TestInstanceWorkspaceComesFromResolver(t *testing.T) {
+	resolver := &fakeResolver{}
+	instance := &models.MCPServerInstance{InstanceID: "inst-1", JSONSpec: map[string]interface{}{}}
+	if workspaceID, err := instanceWorkspace(resolver, instance); err != nil || workspaceID != "ws-1" {
+		t.Errorf("instanceWorkspace() = %q, %v, want ws-1", workspaceID, err)
+	}
+
+	instance.JSONSpec["workspace_id"] = "ws-2"
+	if _, err := instanceWorkspace(resolver, instance); !errors.Is(err, secrets.ErrAccessDenied) {
+		t.Errorf("claimed foreign workspace: err = %v, want ErrAccessDenied", err)
+	}
+}
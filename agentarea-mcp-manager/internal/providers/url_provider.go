@@ -4,18 +4,47 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/oauth"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+	"github.com/agentarea/mcp-manager/internal/secrets"
 )
 
+// ExternalRouteRegistrar registers external URLs with the internal proxy
+type ExternalRouteRegistrar interface {
+	AddExternalService(ctx context.Context, slug, rawURL string, opts proxy.RouteOptions) error
+	AddMCPServiceEndpoints(ctx context.Context, slug, strategy string, endpoints []proxy.EndpointConfig, opts proxy.RouteOptions) error
+	RemoveMCPService(ctx context.Context, slug string) error
+}
+
+// SocketPolicy decides which host Unix sockets URL instances may use
+type SocketPolicy interface {
+	CheckSocket(socket string) error
+}
+
 // URLProvider handles URL-based MCP server instances
 type URLProvider struct {
-	logger *slog.Logger
-	client *http.Client
+	logger  *slog.Logger
+	client  *http.Client
+	sockets SocketPolicy // Allowed bridge sockets; nil refuses socket endpoints
+
+	// Optional proxy registration for URL instances (Docker environments only)
+	routes         ExternalRouteRegistrar
+	secretResolver secrets.SecretResolver
+	tokenBroker    *oauth.Broker
+	slugsMu        sync.Mutex
+	slugs          map[string]string // instance ID -> proxy slug
 }
 
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
 // NewURLProvider creates a new URL provider
 func NewURLProvider(logger *slog.Logger) *URLProvider {
 	return &URLProvider{
@@ -26,6 +55,25 @@ func NewURLProvider(logger *slog.Logger) *URLProvider {
 	}
 }
 
+// SetRouteRegistrar enables proxying of URL instances that opt in via
+// json_spec.proxy. Upstream credentials are resolved with secretResolver.
+func (p *URLProvider) SetRouteRegistrar(routes ExternalRouteRegistrar, secretResolver secrets.SecretResolver) {
+	p.routes = routes
+	p.secretResolver = secretResolver
+	p.slugs = make(map[string]string)
+}
+
+// SetSocketPolicy allows endpoints on the host Unix sockets the policy
+// accepts; without one unix:// and http+unix:// endpoints are refused
+func (p *URLProvider) SetSocketPolicy(policy SocketPolicy) {
+	p.sockets = policy
+}
+
+// SetTokenBroker enables brokered OAuth tokens for proxied URL instances
+func (p *URLProvider) SetTokenBroker(broker *oauth.Broker) {
+	p.tokenBroker = broker
+}
+
 // CreateInstance registers a URL-based MCP server (no container creation needed)
 func (p *URLProvider) CreateInstance(ctx context.Context, instance *models.MCPServerInstance) error {
 	spec := instance.JSONSpec
@@ -41,6 +89,12 @@ func (p *URLProvider) CreateInstance(ctx context.Context, instance *models.MCPSe
 		slog.String("name", instance.Name),
 		slog.String("endpoint", endpoint))
 
+	// The manager connects to socket endpoints itself, so they must be
+	// allowed before it dials them
+	if err := p.checkSocket(endpoint); err != nil {
+		return err
+	}
+
 	// Validate the endpoint is reachable
 	if err := p.validateEndpoint(ctx, endpoint, spec); err != nil {
 		p.logger.Error("Failed to validate URL endpoint",
@@ -50,6 +104,14 @@ func (p *URLProvider) CreateInstance(ctx context.Context, instance *models.MCPSe
 		return fmt.Errorf("endpoint validation failed: %w", err)
 	}
 
+	if err := p.registerProxyRoute(ctx, instance, endpoint); err != nil {
+		p.logger.Error("Failed to register proxy route for URL endpoint",
+			slog.String("instance_id", instance.InstanceID),
+			slog.String("endpoint", endpoint),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("proxy registration failed: %w", err)
+	}
+
 	p.logger.Info("Successfully registered URL-based MCP server",
 		slog.String("instance_id", instance.InstanceID),
 		slog.String("name", instance.Name),
@@ -58,14 +120,235 @@ func (p *URLProvider) CreateInstance(ctx context.Context, instance *models.MCPSe
 	return nil
 }
 
+// registerProxyRoute exposes the endpoint under /mcp/{slug} if the instance
+// enables it with json_spec.proxy
+func (p *URLProvider) registerProxyRoute(ctx context.Context, instance *models.MCPServerInstance, endpoint string) error {
+	proxySpec, enabled := proxySpecFrom(instance.JSONSpec)
+	if !enabled {
+		return nil
+	}
+	if p.routes == nil {
+		p.logger.Warn("Proxying requested for URL instance but the internal proxy is not available",
+			slog.String("instance_id", instance.InstanceID))
+		return nil
+	}
+
+	opts, err := p.routeOptions(instance, proxySpec)
+	if err != nil {
+		return err
+	}
+
+	slug, _ := proxySpec["slug"].(string)
+	if slug == "" {
+		slug = urlInstanceSlug(instance)
+	}
+
+	endpoints, err := p.routeEndpoints(endpoint, proxySpec)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) > 1 {
+		strategy, _ := proxySpec["strategy"].(string)
+		if strategy == "" {
+			strategy = proxy.StrategyFailover
+		}
+		err = p.routes.AddMCPServiceEndpoints(ctx, slug, strategy, endpoints, opts)
+	} else {
+		err = p.routes.AddExternalService(ctx, slug, endpoint, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.slugsMu.Lock()
+	p.slugs[instance.InstanceID] = slug
+	p.slugsMu.Unlock()
+
+	p.logger.Info("URL-based MCP server is available through the proxy",
+		slog.String("instance_id", instance.InstanceID),
+		slog.String("slug", slug))
+
+	return nil
+}
+
+// routeOptions builds proxy options from json_spec, resolving secret references
+// in injected headers and the access token. Secret keys follow the env var
+// naming used for instance secrets (PROXY_HEADER_<NAME>, PROXY_ACCESS_TOKEN).
+func (p *URLProvider) routeOptions(instance *models.MCPServerInstance, proxySpec map[string]interface{}) (proxy.RouteOptions, error) {
+	values := make(map[string]string)
+	headerKeys := make(map[string]string) // secret key -> header name
+
+	addHeader := func(name, value string) {
+		key := "PROXY_HEADER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		headerKeys[key] = name
+		values[key] = value
+	}
+
+	if headers, ok := proxySpec["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			addHeader(name, fmt.Sprintf("%v", value))
+		}
+	}
+
+	// Reuse the bearer token used for validation as the upstream credential
+	if token := bearerToken(instance.JSONSpec); token != "" {
+		if _, exists := values["PROXY_HEADER_AUTHORIZATION"]; !exists {
+			addHeader("Authorization", "Bearer "+token)
+		}
+	}
+
+	if token, ok := proxySpec["access_token"].(string); ok && token != "" {
+		values["PROXY_ACCESS_TOKEN"] = token
+	}
+
+	workspaceID, err := instanceWorkspace(p.secretResolver, instance)
+	if err != nil {
+		return proxy.RouteOptions{}, err
+	}
+	if len(values) > 0 && p.secretResolver != nil {
+		resolved, err := p.secretResolver.ResolveSecrets(workspaceID, instance.InstanceID, values)
+		if err != nil {
+			return proxy.RouteOptions{}, fmt.Errorf("failed to resolve upstream credentials: %w", err)
+		}
+		values = resolved
+	}
+
+	opts := proxy.RouteOptions{
+		Headers:     make(map[string]string, len(headerKeys)),
+		AccessToken: values["PROXY_ACCESS_TOKEN"],
+		WorkspaceID: workspaceID,
+		InstanceID:  instance.InstanceID,
+	}
+	for key, name := range headerKeys {
+		opts.Headers[name] = values[key]
+	}
+	if limit, ok := proxySpec["rate_limit"].(float64); ok {
+		opts.RateLimit = limit
+	}
+	if burst, ok := proxySpec["rate_burst"].(float64); ok {
+		opts.RateBurst = int(burst)
+	}
+	if logRequests, ok := proxySpec["log_requests"].(bool); ok {
+		opts.LogRequests = logRequests
+	}
+	if compression, ok := proxySpec["compression"].(bool); ok {
+		opts.NoCompression = !compression
+	}
+
+	// Brokered OAuth tokens are minted per request so they never go stale
+	if oauthCfg, ok := oauthSpecFrom(instance); ok {
+		if p.tokenBroker == nil {
+			return proxy.RouteOptions{}, fmt.Errorf("instance requests OAuth provider %s but no token broker is configured", oauthCfg.grant.Provider)
+		}
+		oauthCfg.grant.WorkspaceID = workspaceID
+		opts.Credentials = p.tokenBroker.Source(oauthCfg.grant)
+	}
+
+	return opts, nil
+}
+
+// routeEndpoints returns the upstreams of a URL instance's route: its
+// endpoint, then json_spec.proxy.fallback_endpoints, which are not validated
+// up front; passive ejection skips those that fail
+func (p *URLProvider) routeEndpoints(endpoint string, proxySpec map[string]interface{}) ([]proxy.EndpointConfig, error) {
+	if err := p.checkSocket(endpoint); err != nil {
+		return nil, err
+	}
+	primary, err := proxy.ParseExternalURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []proxy.EndpointConfig{primary}
+	fallbacks, _ := proxySpec["fallback_endpoints"].([]interface{})
+	for _, value := range fallbacks {
+		rawURL, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid fallback endpoint %v: must be a URL", value)
+		}
+		if err := p.checkSocket(rawURL); err != nil {
+			return nil, fmt.Errorf("invalid fallback endpoint: %w", err)
+		}
+		fallback, err := proxy.ParseExternalURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback endpoint: %w", err)
+		}
+		endpoints = append(endpoints, fallback)
+	}
+	return endpoints, nil
+}
+
+// checkSocket refuses Unix socket endpoints the socket policy does not allow
+func (p *URLProvider) checkSocket(endpoint string) error {
+	if !proxy.IsSocketURL(endpoint) {
+		return nil
+	}
+	socket, _, err := proxy.ParseSocketURL(endpoint)
+	if err != nil {
+		return err
+	}
+	if p.sockets == nil {
+		return fmt.Errorf("socket endpoint %s refused: no host sockets are allow-listed (HOST_SOCKET_ALLOWLIST)", endpoint)
+	}
+	return p.sockets.CheckSocket(socket)
+}
+
+// proxySpecFrom returns the json_spec.proxy settings and whether proxying is enabled.
+// Both `"proxy": true` and `"proxy": {"enabled": true, ...}` are accepted.
+func proxySpecFrom(spec map[string]interface{}) (map[string]interface{}, bool) {
+	switch v := spec["proxy"].(type) {
+	case bool:
+		return map[string]interface{}{}, v
+	case map[string]interface{}:
+		enabled, ok := v["enabled"].(bool)
+		return v, !ok || enabled
+	default:
+		return nil, false
+	}
+}
+
+// bearerToken extracts json_spec.authentication.token for bearer auth
+func bearerToken(spec map[string]interface{}) string {
+	auth, ok := spec["authentication"].(map[string]interface{})
+	if !ok || auth["type"] != "bearer" {
+		return ""
+	}
+	token, _ := auth["token"].(string)
+	return token
+}
+
+// urlInstanceSlug derives a stable proxy slug for a URL instance
+func urlInstanceSlug(instance *models.MCPServerInstance) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(instance.Name), "-"), "-")
+	id := instance.InstanceID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	if slug == "" {
+		return "url-" + id
+	}
+	return slug + "-" + id
+}
+
 // DeleteInstance unregisters the URL-based MCP server
 func (p *URLProvider) DeleteInstance(ctx context.Context, instanceID, name string) error {
 	p.logger.Info("Unregistering URL-based MCP server",
 		slog.String("instance_id", instanceID),
 		slog.String("name", name))
 
-	// For URL-based servers, we just log the deletion
-	// In a more complex setup, we might need to remove from a registry
+	// Remove the proxy route if the instance was exposed through the proxy
+	p.slugsMu.Lock()
+	slug, proxied := p.slugs[instanceID]
+	delete(p.slugs, instanceID)
+	p.slugsMu.Unlock()
+
+	if proxied {
+		if err := p.routes.RemoveMCPService(ctx, slug); err != nil {
+			return fmt.Errorf("failed to remove proxy route: %w", err)
+		}
+	}
+	if p.tokenBroker != nil {
+		p.tokenBroker.ForgetInstance(instanceID)
+	}
 
 	p.logger.Info("Successfully unregistered URL-based MCP server",
 		slog.String("instance_id", instanceID),
@@ -109,10 +392,22 @@ func (p *URLProvider) validateEndpoint(ctx context.Context, endpoint string, spe
 		}
 	}
 
+	// Bridges on the manager host are checked over their socket
+	client := p.client
+	base := endpoint
+	if proxy.IsSocketURL(endpoint) {
+		socket, basePath, err := proxy.ParseSocketURL(endpoint)
+		if err != nil {
+			return err
+		}
+		client = socketClient(socket, p.client.Timeout)
+		base = "http://localhost" + basePath
+	}
+
 	// Construct the full health check URL
-	healthURL := endpoint
+	healthURL := base
 	if healthPath != "" && healthPath != "/" {
-		healthURL = endpoint + healthPath
+		healthURL = base + healthPath
 	}
 
 	p.logger.Debug("Validating endpoint",
@@ -138,7 +433,7 @@ func (p *URLProvider) validateEndpoint(ctx context.Context, endpoint string, spe
 		}
 	}
 
-	resp, err := p.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
@@ -150,3 +445,16 @@ func (p *URLProvider) validateEndpoint(ctx context.Context, endpoint string, spe
 
 	return nil
 }
+
+// socketClient returns a client whose requests go to a Unix domain socket
+func socketClient(socket string, timeout time.Duration) *http.Client {
+	var dialer net.Dialer
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/proxy"
+)
+
+// fakeRoutes records the routes registered by the URL provider
+type fakeRoutes struct {
+	external  map[string]string
+	strategy  string
+	endpoints []proxy.EndpointConfig
+	opts      proxy.RouteOptions
+}
+
+func (r *fakeRoutes) AddExternalService(_ context.Context, slug, rawURL string, opts proxy.RouteOptions) error {
+	r.external[slug] = rawURL
+	r.opts = opts
+	return nil
+}
+
+func (r *fakeRoutes) AddMCPServiceEndpoints(_ context.Context, _, strategy string, endpoints []proxy.EndpointConfig, opts proxy.RouteOptions) error {
+	r.strategy, r.endpoints, r.opts = strategy, endpoints, opts
+	return nil
+}
+
+func (r *fakeRoutes) RemoveMCPService(context.Context, string) error { return nil }
+
+// bridgeSockets allows the sockets under /run/bridges
+type bridgeSockets struct{}
+
+func (bridgeSockets) CheckSocket(socket string) error {
+	if !strings.HasPrefix(socket, "/run/bridges/") {
+		return fmt.Errorf("socket %s is not allowed", socket)
+	}
+	return nil
+}
+
+func TestURLInstanceFallbackEndpoints(t *testing.T) {
+	routes := &fakeRoutes{external: make(map[string]string)}
+	p := NewURLProvider(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetRouteRegistrar(routes, nil)
+	p.SetSocketPolicy(bridgeSockets{})
+	ctx := context.Background()
+
+	single := &models.MCPServerInstance{InstanceID: "inst-1", Name: "search", JSONSpec: map[string]interface{}{"proxy": true}}
+	if err := p.registerProxyRoute(ctx, single, "https://search.example.com/mcp"); err != nil {
+		t.Fatal(err)
+	}
+	if routes.external["search-inst-1"] != "https://search.example.com/mcp" || routes.endpoints != nil {
+		t.Errorf("single endpoint route = %v, %v", routes.external, routes.endpoints)
+	}
+
+	balanced := &models.MCPServerInstance{InstanceID: "inst-2", Name: "drive", JSONSpec: map[string]interface{}{"proxy": map[string]interface{}{
+		"fallback_endpoints": []interface{}{"https://drive-backup.example.com/mcp", "unix:///run/bridges/drive.sock"},
+	}}}
+	if err := p.registerProxyRoute(ctx, balanced, "https://drive.example.com/mcp"); err != nil {
+		t.Fatal(err)
+	}
+	if routes.strategy != proxy.StrategyFailover || len(routes.endpoints) != 3 || routes.opts.InstanceID != "inst-2" {
+		t.Fatalf("strategy %q, endpoints %+v, instance %q", routes.strategy, routes.endpoints, routes.opts.InstanceID)
+	}
+	if routes.endpoints[0].Host != "drive.example.com" || routes.endpoints[1].Host != "drive-backup.example.com" || routes.endpoints[2].Socket != "/run/bridges/drive.sock" {
+		t.Errorf("endpoints = %+v", routes.endpoints)
+	}
+
+	invalid := &models.MCPServerInstance{InstanceID: "inst-3", Name: "bad", JSONSpec: map[string]interface{}{"proxy": map[string]interface{}{
+		"fallback_endpoints": []interface{}{"ftp://files.example.com"},
+	}}}
+	if err := p.registerProxyRoute(ctx, invalid, "https://files.example.com/mcp"); err == nil {
+		t.Error("invalid fallback endpoint should be rejected")
+	}
+}
+
+func TestURLInstanceSocketPolicy(t *testing.T) {
+	routes := &fakeRoutes{external: make(map[string]string)}
+	p := NewURLProvider(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetRouteRegistrar(routes, nil)
+	ctx := context.Background()
+
+	// Without a policy no socket endpoint is accepted
+	bridge := &models.MCPServerInstance{InstanceID: "inst-1", Name: "bridge", JSONSpec: map[string]interface{}{
+		"endpoint": "unix:///run/bridges/github.sock", "proxy": true,
+	}}
+	if err := p.CreateInstance(ctx, bridge); err == nil {
+		t.Error("socket endpoint accepted without a policy")
+	}
+
+	// The runtime's API socket is refused as endpoint and as fallback
+	p.SetSocketPolicy(bridgeSockets{})
+	runtimeSocket := &models.MCPServerInstance{InstanceID: "inst-2", Name: "runtime", JSONSpec: map[string]interface{}{
+		"endpoint": "http+unix://%2Frun%2Fpodman%2Fpodman.sock/v4.0.0/libpod", "proxy": true,
+	}}
+	if err := p.CreateInstance(ctx, runtimeSocket); err == nil || !strings.Contains(err.Error(), "/run/podman/podman.sock") {
+		t.Errorf("runtime socket endpoint: err = %v", err)
+	}
+	fallback := &models.MCPServerInstance{InstanceID: "inst-3", Name: "search", JSONSpec: map[string]interface{}{"proxy": map[string]interface{}{
+		"fallback_endpoints": []interface{}{"unix:///var/run/docker.sock"},
+	}}}
+	if err := p.registerProxyRoute(ctx, fallback, "https://search.example.com/mcp"); err == nil {
+		t.Error("runtime socket fallback accepted")
+	}
+	if len(routes.external) != 0 || routes.endpoints != nil {
+		t.Errorf("routes registered: %v, %v", routes.external, routes.endpoints)
+	}
+}
@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Load balancing strategies for routes with multiple endpoints
+const (
+	StrategyRoundRobin       = "round_robin"
+	StrategyLeastConnections = "least_connections"
+	StrategyFailover         = "failover"
+)
+
+// Endpoint is a single upstream target of a route
+type Endpoint struct {
+	Scheme   string // "http" (default) or "https"
+	Host     string
+	Port     int
+	BasePath string // Prefix prepended to the forwarded path (external URLs)
+	Socket   string // Unix domain socket of a bridge on the manager host; Host and Port are unused
+
+	addr     string
+	dialHost string // Host the transport dials; maps to Socket for socket endpoints
+
+	// Passive health and load state, shared by all requests to the endpoint
+	active       atomic.Int64
+	failures     atomic.Uint32
+	ejectedUntil atomic.Int64 // unix nanos, 0 when healthy
+}
+
+// EndpointStats is a snapshot of an endpoint's load and health state
+type EndpointStats struct {
+	Address  string `json:"address"`
+	Active   int64  `json:"active"`
+	Failures uint32 `json:"failures"`
+	Ejected  bool   `json:"ejected"`
+}
+
+// Addr returns the host:port of the endpoint, or unix:<path> for a socket
+func (e *Endpoint) Addr() string {
+	if e.addr == "" {
+		return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+	}
+	return e.addr
+}
+
+// target returns the host requests to the endpoint are addressed to
+func (e *Endpoint) target() string {
+	if e.dialHost != "" {
+		return e.dialHost
+	}
+	return e.Addr()
+}
+
+// available reports whether the endpoint is not currently ejected
+func (e *Endpoint) available(now int64) bool {
+	until := e.ejectedUntil.Load()
+	return until == 0 || now >= until
+}
+
+// stats returns a snapshot of the endpoint state
+func (e *Endpoint) stats(now int64) EndpointStats {
+	return EndpointStats{
+		Address:  e.Addr(),
+		Active:   e.active.Load(),
+		Failures: e.failures.Load(),
+		Ejected:  !e.available(now),
+	}
+}
+
+// newEndpoint validates an endpoint definition and returns a copy with fresh state
+func newEndpoint(scheme, host string, port int) (*Endpoint, error) {
+	if scheme == "" {
+		scheme = "http"
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint scheme: %s", scheme)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("endpoint host cannot be empty")
+	}
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid endpoint port: %d", port)
+	}
+
+	return &Endpoint{
+		Scheme: scheme,
+		Host:   host,
+		Port:   port,
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+	}, nil
+}
+
+// newSocketEndpoint validates a Unix socket endpoint. Requests are addressed
+// to a host derived from the path, which the route transport dials as the
+// socket, so pooled connections are kept per socket.
+func newSocketEndpoint(scheme, path string) (*Endpoint, error) {
+	if scheme != "" && scheme != "http" {
+		return nil, fmt.Errorf("socket endpoints speak plain http: %s", scheme)
+	}
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("socket path must be absolute: %s", path)
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(path))
+	return &Endpoint{
+		Scheme:   "http",
+		Host:     "localhost",
+		Socket:   path,
+		addr:     "unix:" + path,
+		dialHost: fmt.Sprintf("unix-%x.localhost", hash.Sum64()),
+	}, nil
+}
+
+// dialSockets wraps dial so hosts of socket endpoints dial their socket
+func dialSockets(dial func(ctx context.Context, network, addr string) (net.Conn, error), endpoints []*Endpoint) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	sockets := make(map[string]string)
+	for _, e := range endpoints {
+		if e.Socket != "" {
+			sockets[e.dialHost] = e.Socket
+		}
+	}
+	if len(sockets) == 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if path, ok := sockets[host]; ok && err == nil {
+			return dial(ctx, "unix", path)
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// HealthPolicy controls passive ejection of failing endpoints
+type HealthPolicy struct {
+	MaxFailures   uint32        // Consecutive failures before ejection (0 disables ejection)
+	EjectDuration time.Duration // How long an ejected endpoint is skipped
+}
+
+// reportFailure records a failed request and ejects the endpoint once the
+// consecutive failure threshold is reached
+func (hp HealthPolicy) reportFailure(e *Endpoint) bool {
+	failures := e.failures.Add(1)
+	if hp.MaxFailures == 0 || failures < hp.MaxFailures {
+		return false
+	}
+
+	e.ejectedUntil.Store(time.Now().Add(hp.EjectDuration).UnixNano())
+	e.failures.Store(0)
+	return true
+}
+
+// reportSuccess clears the failure count of an endpoint
+func (hp HealthPolicy) reportSuccess(e *Endpoint) {
+	if e.failures.Load() != 0 {
+		e.failures.Store(0)
+	}
+	if e.ejectedUntil.Load() != 0 {
+		e.ejectedUntil.Store(0)
+	}
+}
+
+// Balancer picks an endpoint for each request
+type Balancer interface {
+	// Pick returns the index of the endpoint to use
+	Pick(endpoints []*Endpoint) int
+}
+
+// NewBalancer returns the balancer for a strategy name
+func NewBalancer(strategy string) (Balancer, error) {
+	switch strategy {
+	case "", StrategyRoundRobin:
+		return &roundRobinBalancer{}, nil
+	case StrategyLeastConnections:
+		return leastConnectionsBalancer{}, nil
+	case StrategyFailover:
+		return failoverBalancer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown load balancing strategy: %s", strategy)
+	}
+}
+
+// roundRobinBalancer cycles through available endpoints
+type roundRobinBalancer struct {
+	next atomic.Uint64
+}
+
+// Pick implements Balancer
+func (b *roundRobinBalancer) Pick(endpoints []*Endpoint) int {
+	n := uint64(len(endpoints))
+	start := b.next.Add(1) - 1
+	now := time.Now().UnixNano()
+
+	for i := uint64(0); i < n; i++ {
+		idx := int((start + i) % n)
+		if endpoints[idx].available(now) {
+			return idx
+		}
+	}
+
+	// Every endpoint is ejected - keep spreading load rather than failing outright
+	return int(start % n)
+}
+
+// leastConnectionsBalancer picks the available endpoint with the fewest in-flight requests
+type leastConnectionsBalancer struct{}
+
+// Pick implements Balancer
+func (leastConnectionsBalancer) Pick(endpoints []*Endpoint) int {
+	now := time.Now().UnixNano()
+	best := -1
+	var bestActive int64
+
+	for i, e := range endpoints {
+		if !e.available(now) {
+			continue
+		}
+		if active := e.active.Load(); best < 0 || active < bestActive {
+			best, bestActive = i, active
+		}
+	}
+
+	if best < 0 {
+		return 0
+	}
+	return best
+}
+
+// failoverBalancer always prefers the first available endpoint in order
+type failoverBalancer struct{}
+
+// Pick implements Balancer
+func (failoverBalancer) Pick(endpoints []*Endpoint) int {
+	now := time.Now().UnixNano()
+	for i, e := range endpoints {
+		if e.available(now) {
+			return i
+		}
+	}
+	return 0
+}
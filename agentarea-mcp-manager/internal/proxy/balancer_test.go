@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestEndpoints(t *testing.T, n int) []*Endpoint {
+	t.Helper()
+
+	endpoints := make([]*Endpoint, n)
+	for i := range endpoints {
+		e, err := newEndpoint("", "10.0.0.1", 8000+i)
+		if err != nil {
+			t.Fatalf("newEndpoint failed: %v", err)
+		}
+		endpoints[i] = e
+	}
+	return endpoints
+}
+
+func TestRoundRobinSkipsEjectedEndpoints(t *testing.T) {
+	endpoints := newTestEndpoints(t, 3)
+	policy := HealthPolicy{MaxFailures: 2, EjectDuration: time.Minute}
+	balancer, _ := NewBalancer(StrategyRoundRobin)
+
+	counts := make([]int, 3)
+	for i := 0; i < 30; i++ {
+		counts[balancer.Pick(endpoints)]++
+	}
+	for i, c := range counts {
+		if c != 10 {
+			t.Errorf("Expected endpoint %d to get 10 picks, got %d", i, c)
+		}
+	}
+
+	policy.reportFailure(endpoints[1])
+	if !policy.reportFailure(endpoints[1]) {
+		t.Fatal("Expected endpoint to be ejected after reaching the failure threshold")
+	}
+
+	for i := 0; i < 10; i++ {
+		if balancer.Pick(endpoints) == 1 {
+			t.Fatal("Expected ejected endpoint to be skipped")
+		}
+	}
+
+	policy.reportSuccess(endpoints[1])
+	if !endpoints[1].available(time.Now().UnixNano()) {
+		t.Error("Expected endpoint to be available after a success")
+	}
+}
+
+func TestLeastConnectionsPicksIdlestEndpoint(t *testing.T) {
+	endpoints := newTestEndpoints(t, 3)
+	endpoints[0].active.Store(5)
+	endpoints[1].active.Store(1)
+	endpoints[2].active.Store(3)
+
+	balancer, _ := NewBalancer(StrategyLeastConnections)
+	if got := balancer.Pick(endpoints); got != 1 {
+		t.Errorf("Expected endpoint 1, got %d", got)
+	}
+}
+
+func TestFailoverPrefersPrimary(t *testing.T) {
+	endpoints := newTestEndpoints(t, 2)
+	policy := HealthPolicy{MaxFailures: 1, EjectDuration: time.Minute}
+	balancer, _ := NewBalancer(StrategyFailover)
+
+	if got := balancer.Pick(endpoints); got != 0 {
+		t.Errorf("Expected primary endpoint, got %d", got)
+	}
+
+	policy.reportFailure(endpoints[0])
+	if got := balancer.Pick(endpoints); got != 1 {
+		t.Errorf("Expected fallback endpoint after ejection, got %d", got)
+	}
+
+	// With every endpoint ejected the primary is still used
+	policy.reportFailure(endpoints[1])
+	if got := balancer.Pick(endpoints); got != 0 {
+		t.Errorf("Expected primary endpoint when all are ejected, got %d", got)
+	}
+}
+
+func TestProxyFailsOverToHealthyEndpoint(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	ps := newTestProxyServer()
+	ps.config.Health = HealthPolicy{MaxFailures: 1, EjectDuration: time.Minute}
+
+	brokenAddr := endpointConfigForServer(t, broken)
+	healthyAddr := endpointConfigForServer(t, healthy)
+	if err := ps.AddRouteEndpoints("echo", StrategyFailover, []EndpointConfig{brokenAddr, healthyAddr}, RouteOptions{}); err != nil {
+		t.Fatalf("AddRouteEndpoints failed: %v", err)
+	}
+
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/mcp/echo/", nil))
+		codes = append(codes, rec.Code)
+	}
+
+	if codes[0] != http.StatusServiceUnavailable || codes[1] != http.StatusOK {
+		t.Errorf("Expected 503 then 200 after ejection, got %v", codes)
+	}
+}
+
+func TestAddRouteEndpointsValidation(t *testing.T) {
+	registry := NewRouteRegistry()
+
+	if err := registry.AddRouteEndpoints("echo", StrategyRoundRobin, nil, RouteOptions{}); err == nil {
+		t.Error("Expected error for route without endpoints")
+	}
+	if err := registry.AddRouteEndpoints("echo", "random", []EndpointConfig{{Host: "a", Port: 80}}, RouteOptions{}); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+	if err := registry.AddRouteEndpoints("echo", StrategyRoundRobin, []EndpointConfig{{Host: "a", Port: 0}}, RouteOptions{}); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func endpointConfigForServer(t *testing.T, server *httptest.Server) EndpointConfig {
+	t.Helper()
+
+	host, port := serverHostPort(t, server)
+	return EndpointConfig{Host: host, Port: port}
+}
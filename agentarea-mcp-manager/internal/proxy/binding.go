@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// BindingPolicy controls how the proxy rebinds after its listener fails
+type BindingPolicy struct {
+	FallbackPort  int           // Port used once the primary cannot be bound; 0 disables
+	FallbackAfter int           // Failed binds of the primary port before falling back; default 3
+	Backoff       time.Duration // First delay between attempts, doubled up to MaxBackoff; default 1s
+	MaxBackoff    time.Duration // Default 30s
+}
+
+// withDefaults fills in unset fields
+func (p BindingPolicy) withDefaults() BindingPolicy {
+	if p.FallbackAfter <= 0 {
+		p.FallbackAfter = 3
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	return p
+}
+
+// ListenFunc opens a listener, such as handoff.Listen with SO_REUSEPORT
+type ListenFunc func(ctx context.Context, addr string) (net.Listener, error)
+
+// Binding keeps the proxy listening. When serving stops (the accept loop
+// fails or panics) or the port cannot be bound, it binds again with
+// exponential backoff instead of leaving Docker-mode routing down until the
+// manager restarts. After FallbackAfter failed binds it moves to the
+// fallback port, if one is set, and tries the primary port again the next
+// time serving fails.
+type Binding struct {
+	primary  string
+	fallback string // Empty without a fallback port
+	listen   ListenFunc
+	serve    func(net.Listener) error
+	policy   BindingPolicy
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	initial net.Listener // Opened before Run, e.g. inherited in a handoff
+	status  models.ProxyStatus
+	onBind  []func(models.ProxyStatus)
+}
+
+// NewBinding creates a binding serving on addr. initial is an already open
+// listener on addr and may be nil.
+func NewBinding(addr string, initial net.Listener, listen ListenFunc, serve func(net.Listener) error, policy BindingPolicy, logger *slog.Logger) *Binding {
+	policy = policy.withDefaults()
+	b := &Binding{
+		primary: addr,
+		listen:  listen,
+		serve:   serve,
+		policy:  policy,
+		logger:  logger,
+		initial: initial,
+		status:  models.ProxyStatus{State: models.ProxyRebinding, Since: time.Now()},
+	}
+	if policy.FallbackPort > 0 {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = ""
+		}
+		b.fallback = net.JoinHostPort(host, strconv.Itoa(policy.FallbackPort))
+	}
+	return b
+}
+
+// OnRebind registers a callback run each time the proxy is bound again
+// after a failure, with the address it now serves on
+func (b *Binding) OnRebind(fn func(models.ProxyStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onBind = append(b.onBind, fn)
+}
+
+// Status reports where the proxy listens
+func (b *Binding) Status() models.ProxyStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.status
+}
+
+// Run serves until ctx is done or the proxy is shut down
+func (b *Binding) Run(ctx context.Context) error {
+	backoff := b.policy.Backoff
+	failures := 0 // Consecutive failed binds of the primary address
+	for {
+		addr := b.primary
+		if b.fallback != "" && failures >= b.policy.FallbackAfter {
+			addr = b.fallback
+		}
+
+		var err error
+		ln := b.takeInitial()
+		if ln == nil {
+			ln, err = b.listen(ctx, addr)
+		}
+		if err == nil {
+			failures = 0
+			started := time.Now()
+			b.bound(ln.Addr().String(), addr != b.primary)
+
+			err = b.serveRecovered(ln)
+			if ctx.Err() != nil || errors.Is(err, http.ErrServerClosed) {
+				b.setStopped()
+				return nil
+			}
+			if err == nil {
+				err = errors.New("proxy stopped serving")
+			}
+			if time.Since(started) > b.policy.MaxBackoff {
+				// A long healthy run starts the backoff over
+				backoff = b.policy.Backoff
+			}
+		} else if addr == b.primary {
+			failures++
+		}
+
+		b.logger.Warn("Proxy is not listening, rebinding",
+			slog.String("address", addr),
+			slog.String("error", err.Error()),
+			slog.Duration("backoff", backoff))
+		b.setRebinding(err)
+
+		select {
+		case <-ctx.Done():
+			b.setStopped()
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, b.policy.MaxBackoff)
+	}
+}
+
+// takeInitial returns the listener opened before Run, once
+func (b *Binding) takeInitial() net.Listener {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ln := b.initial
+	b.initial = nil
+	return ln
+}
+
+// serveRecovered serves on ln, turning a panic into an error
+func (b *Binding) serveRecovered(ln net.Listener) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ln.Close()
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return b.serve(ln)
+}
+
+// bound records that the proxy serves on address and runs the rebind
+// callbacks unless this is the first bind
+func (b *Binding) bound(address string, fallback bool) {
+	b.mu.Lock()
+	rebound := b.status.LastError != ""
+	if rebound {
+		b.status.Rebinds++
+	}
+	b.status.State = models.ProxyServing
+	b.status.Address = address
+	b.status.Fallback = fallback
+	b.status.Since = time.Now()
+	status := b.status
+	callbacks := b.onBind
+	b.mu.Unlock()
+
+	if !rebound {
+		return
+	}
+	b.logger.Info("Proxy listening again",
+		slog.String("address", address),
+		slog.Bool("fallback", fallback),
+		slog.Int("rebinds", status.Rebinds))
+	for _, fn := range callbacks {
+		fn(status)
+	}
+}
+
+func (b *Binding) setRebinding(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if b.status.State != models.ProxyRebinding {
+		b.status.Since = now
+	}
+	b.status.State = models.ProxyRebinding
+	b.status.Address = ""
+	b.status.Fallback = false
+	b.status.LastError = err.Error()
+	b.status.LastErrorAt = &now
+}
+
+func (b *Binding) setStopped() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.State = models.ProxyStopped
+	b.status.Address = ""
+	b.status.Since = time.Now()
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+func TestBindingRebindsAndFallsBack(t *testing.T) {
+	initial, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var listened []string
+	listen := func(ctx context.Context, addr string) (net.Listener, error) {
+		mu.Lock()
+		listened = append(listened, addr)
+		mu.Unlock()
+		if addr == "127.0.0.1:8080" {
+			return nil, errors.New("address already in use")
+		}
+		return net.Listen("tcp", "127.0.0.1:0")
+	}
+	serves := 0
+	serve := func(ln net.Listener) error {
+		serves++
+		if serves == 1 {
+			panic("accept loop")
+		}
+		<-ctx.Done()
+		ln.Close()
+		return http.ErrServerClosed
+	}
+
+	rebound := make(chan models.ProxyStatus, 1)
+	b := NewBinding("127.0.0.1:8080", initial, listen, serve,
+		BindingPolicy{FallbackPort: 9090, FallbackAfter: 2, Backoff: time.Millisecond},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.OnRebind(func(s models.ProxyStatus) { rebound <- s })
+
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+
+	select {
+	case status := <-rebound:
+		if status.State != models.ProxyServing || !status.Fallback || status.Rebinds != 1 ||
+			status.LastError != "address already in use" {
+			t.Errorf("rebound status = %+v", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy was not rebound")
+	}
+
+	// The panic is recovered, the primary port is tried FallbackAfter times,
+	// then the fallback port is bound
+	mu.Lock()
+	want := []string{"127.0.0.1:8080", "127.0.0.1:8080", "127.0.0.1:9090"}
+	if len(listened) != len(want) || listened[0] != want[0] || listened[1] != want[1] || listened[2] != want[2] {
+		t.Errorf("listened on %v, want %v", listened, want)
+	}
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if status := b.Status(); status.State != models.ProxyStopped {
+		t.Errorf("status after shutdown = %+v", status)
+	}
+}
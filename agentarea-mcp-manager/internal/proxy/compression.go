@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported response encodings
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// brotliLevel trades ratio for speed on dynamic responses
+const brotliLevel = 4
+
+// CompressionPolicy compresses proxied responses the upstream sent
+// uncompressed, for clients that accept it
+type CompressionPolicy struct {
+	Encodings []string // In order of preference; empty disables compression
+	MinSize   int64    // Responses of known length below this are sent as is
+}
+
+// Validate checks that every encoding is supported
+func (p CompressionPolicy) Validate() error {
+	for _, encoding := range p.Encodings {
+		if encoding != EncodingBrotli && encoding != EncodingGzip {
+			return fmt.Errorf("unsupported proxy compression %q: use br or gzip", encoding)
+		}
+	}
+	return nil
+}
+
+// compress replaces the body of a response with its compressed form when
+// the client accepts one of the policy's encodings. The compressed length is
+// unknown, so Content-Length is dropped and the response is sent chunked;
+// event streams are left alone so every event is flushed as it arrives.
+func (p CompressionPolicy) compress(resp *http.Response) {
+	if len(p.Encodings) == 0 || resp.Request == nil || !compressible(resp) {
+		return
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength < p.MinSize {
+		return
+	}
+	encoding := negotiateEncoding(resp.Request.Header.Get("Accept-Encoding"), p.Encodings)
+	if encoding == "" {
+		return
+	}
+
+	source := resp.Body
+	reader, writer := io.Pipe()
+	go func() {
+		var encoder io.WriteCloser
+		if encoding == EncodingBrotli {
+			encoder = brotli.NewWriterLevel(writer, brotliLevel)
+		} else {
+			encoder = gzip.NewWriter(writer)
+		}
+		_, err := io.Copy(encoder, source)
+		if closeErr := encoder.Close(); err == nil {
+			err = closeErr
+		}
+		writer.CloseWithError(err)
+	}()
+
+	resp.Body = &compressedBody{PipeReader: reader, source: source}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	resp.Header.Set("Content-Encoding", encoding)
+	resp.Header.Add("Vary", "Accept-Encoding")
+	// A strong validator no longer matches the transformed representation
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		resp.Header.Set("ETag", "W/"+etag)
+	}
+}
+
+// compressedBody closes the upstream body along with the compressed stream
+type compressedBody struct {
+	*io.PipeReader
+	source io.ReadCloser
+}
+
+// Close implements io.Closer
+func (b *compressedBody) Close() error {
+	b.PipeReader.Close()
+	return b.source.Close()
+}
+
+// compressible reports whether a response is worth compressing
+func compressible(resp *http.Response) bool {
+	if resp.Request.Method == http.MethodHead || resp.StatusCode < 200 ||
+		resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified ||
+		resp.StatusCode == http.StatusPartialContent {
+		return false
+	}
+	if resp.Header.Get("Content-Encoding") != "" || resp.Uncompressed ||
+		strings.Contains(resp.Header.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// negotiateEncoding returns the first offered encoding the Accept-Encoding
+// header allows, or "" for none
+func negotiateEncoding(accept string, offered []string) string {
+	if accept == "" {
+		return ""
+	}
+	quality := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		quality[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	for _, encoding := range offered {
+		q, ok := quality[encoding]
+		if !ok {
+			q, ok = quality["*"]
+		}
+		if ok && q > 0 {
+			return encoding
+		}
+	}
+	return ""
+}
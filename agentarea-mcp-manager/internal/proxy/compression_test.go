@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	offered := []string{EncodingBrotli, EncodingGzip}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br", EncodingBrotli},
+		{"br;q=0, gzip", EncodingGzip},
+		{"*", EncodingBrotli},
+		{"identity", ""},
+		{"gzip;q=bogus", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.accept, offered); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestResponseCompression(t *testing.T) {
+	payload := `{"jsonrpc":"2.0","result":{"tools":[` + strings.Repeat(`{"name":"search","description":"Search the web"},`, 40) + `{}]}}`
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ok":true}`)
+		case "/sse":
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: "+payload+"\n\n")
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, payload)
+		}
+	}))
+	defer backend.Close()
+
+	ps := NewProxyServer(ProxyConfig{Compression: CompressionPolicy{
+		Encodings: []string{EncodingBrotli, EncodingGzip},
+		MinSize:   1024,
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	host, port := serverHostPort(t, backend)
+	if err := ps.AddRoute("tools", host, port); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.AddRouteWithOptions("plain", host, port, RouteOptions{NoCompression: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, req)
+		return rec
+	}
+
+	for encoding, decode := range map[string]func(io.Reader) (io.Reader, error){
+		EncodingGzip:   func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		EncodingBrotli: func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	} {
+		rec := send("/mcp/tools/", encoding)
+		if got := rec.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+		}
+		if rec.Header().Get("Content-Length") != "" {
+			t.Error("Content-Length of the uncompressed body kept")
+		}
+		if rec.Body.Len() >= len(payload) {
+			t.Errorf("%s body not smaller: %d >= %d bytes", encoding, rec.Body.Len(), len(payload))
+		}
+		reader, err := decode(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, err := io.ReadAll(reader); err != nil || string(body) != payload {
+			t.Errorf("%s body does not round-trip: %v", encoding, err)
+		}
+	}
+
+	for _, tt := range []struct{ path, accept string }{
+		{"/mcp/tools/small", "gzip"},
+		{"/mcp/tools/sse", "gzip"},
+		{"/mcp/tools/", ""},
+		{"/mcp/plain/", "gzip"},
+	} {
+		rec := send(tt.path, tt.accept)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s with Accept-Encoding %q compressed with %s", tt.path, tt.accept, got)
+		}
+	}
+}
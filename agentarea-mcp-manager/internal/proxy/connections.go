@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether connections have finished
+const drainPollInterval = 100 * time.Millisecond
+
+// ConnectionInfo describes an in-flight proxied request or stream
+type ConnectionInfo struct {
+	ID        uint64    `json:"id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	ClientIP  string    `json:"client_ip"`
+	Stream    bool      `json:"stream"` // SSE or upgraded connection
+	StartedAt time.Time `json:"started_at"`
+}
+
+// DrainResult reports the outcome of draining a route
+type DrainResult struct {
+	Drained   bool          `json:"drained"`   // No connections were left when the drain ended
+	Remaining int           `json:"remaining"` // Connections still open when the timeout expired
+	Closed    int           `json:"closed"`    // Connections force-closed after the timeout
+	Waited    time.Duration `json:"waited"`
+}
+
+// liveConn is a tracked in-flight request
+type liveConn struct {
+	info   ConnectionInfo
+	cancel context.CancelFunc
+}
+
+// connTracker tracks the in-flight requests of one route
+type connTracker struct {
+	mu       sync.Mutex
+	conns    map[uint64]*liveConn
+	draining bool
+}
+
+// trackerFor returns the connection tracker for a slug, creating it if needed
+func (ps *ProxyServer) trackerFor(slug string) *connTracker {
+	ps.trackersMu.RLock()
+	t, ok := ps.trackers[slug]
+	ps.trackersMu.RUnlock()
+	if ok {
+		return t
+	}
+
+	ps.trackersMu.Lock()
+	defer ps.trackersMu.Unlock()
+	if t, ok = ps.trackers[slug]; !ok {
+		t = &connTracker{conns: make(map[uint64]*liveConn)}
+		ps.trackers[slug] = t
+	}
+	return t
+}
+
+// track registers a request. It returns false if the route is draining and
+// the request must be rejected; otherwise the caller must call done.
+func (ps *ProxyServer) track(t *connTracker, r *http.Request) (*http.Request, func(), bool) {
+	ctx, cancel := context.WithCancel(r.Context())
+	conn := &liveConn{
+		info: ConnectionInfo{
+			ID:        ps.connSeq.Add(1),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			ClientIP:  ps.getClientIP(r),
+			Stream:    isStreamRequest(r),
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	t.mu.Lock()
+	if t.draining {
+		t.mu.Unlock()
+		cancel()
+		return r, nil, false
+	}
+	t.conns[conn.info.ID] = conn
+	t.mu.Unlock()
+
+	done := func() {
+		t.mu.Lock()
+		delete(t.conns, conn.info.ID)
+		t.mu.Unlock()
+		cancel()
+	}
+	return r.WithContext(ctx), done, true
+}
+
+// isStreamRequest reports whether a request opens a long-lived stream
+func isStreamRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") || r.Header.Get("Upgrade") != ""
+}
+
+// count returns the number of in-flight requests
+func (t *connTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// list returns the in-flight requests, oldest first
+func (t *connTracker) list() []ConnectionInfo {
+	t.mu.Lock()
+	conns := make([]ConnectionInfo, 0, len(t.conns))
+	for _, c := range t.conns {
+		conns = append(conns, c.info)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(conns, func(i, j int) bool { return conns[i].ID < conns[j].ID })
+	return conns
+}
+
+// closeAll cancels every in-flight request and returns how many there were
+func (t *connTracker) closeAll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		c.cancel()
+	}
+	return len(t.conns)
+}
+
+// Connections returns the in-flight requests and streams of a route
+func (ps *ProxyServer) Connections(slug string) []ConnectionInfo {
+	ps.trackersMu.RLock()
+	t, ok := ps.trackers[slug]
+	ps.trackersMu.RUnlock()
+	if !ok {
+		return []ConnectionInfo{}
+	}
+	return t.list()
+}
+
+// Drain stops admitting new requests on a route and waits up to timeout for
+// in-flight requests to finish. With force, requests still open after the
+// timeout are closed. The route keeps rejecting requests until Resume is
+// called or the route is removed.
+func (ps *ProxyServer) Drain(ctx context.Context, slug string, timeout time.Duration, force bool) DrainResult {
+	t := ps.trackerFor(slug)
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+
+	start := time.Now()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for t.count() > 0 {
+		select {
+		case <-ctx.Done():
+		case <-deadline.C:
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+
+	result := DrainResult{Remaining: t.count(), Waited: time.Since(start)}
+	if result.Remaining > 0 && force {
+		result.Closed = t.closeAll()
+	}
+	result.Drained = result.Remaining == 0
+	return result
+}
+
+// Resume admits requests on a drained route again
+func (ps *ProxyServer) Resume(slug string) {
+	ps.trackersMu.RLock()
+	t, ok := ps.trackers[slug]
+	ps.trackersMu.RUnlock()
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.draining = false
+	t.mu.Unlock()
+}
+
+// dropTracker forgets the connection state of a removed route. Requests
+// still in flight keep their reference and finish normally.
+func (ps *ProxyServer) dropTracker(slug string) {
+	ps.trackersMu.Lock()
+	delete(ps.trackers, slug)
+	ps.trackersMu.Unlock()
+}
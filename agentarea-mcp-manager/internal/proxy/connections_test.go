@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainForceClosesStreams(t *testing.T) {
+	// The upstream holds /sse open like an MCP event stream
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Path == "/sse" {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	addRouteForServer(t, ps, "stream", backend)
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		req := httptest.NewRequest(http.MethodGet, "/mcp/stream/sse", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		ps.handleRequest(httptest.NewRecorder(), req)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ps.Connections("stream")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream was never tracked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if conns := ps.Connections("stream"); !conns[0].Stream || conns[0].Path != "/mcp/stream/sse" {
+		t.Fatalf("unexpected connection info: %+v", conns[0])
+	}
+
+	// Without force the stream is left open
+	result := ps.Drain(context.Background(), "stream", 50*time.Millisecond, false)
+	if result.Drained || result.Remaining != 1 || result.Closed != 0 {
+		t.Fatalf("unexpected drain result: %+v", result)
+	}
+
+	// New requests are rejected while draining
+	rec := httptest.NewRecorder()
+	ps.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/mcp/stream/sse", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while draining, got %d", rec.Code)
+	}
+
+	result = ps.Drain(context.Background(), "stream", 50*time.Millisecond, true)
+	if result.Closed != 1 {
+		t.Fatalf("expected the stream to be force-closed: %+v", result)
+	}
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("force-closed stream did not finish")
+	}
+	if n := len(ps.Connections("stream")); n != 0 {
+		t.Fatalf("expected no tracked connections, got %d", n)
+	}
+
+	ps.Resume("stream")
+	rec = httptest.NewRecorder()
+	ps.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/mcp/stream/short", nil))
+	if rec.Code == http.StatusServiceUnavailable {
+		t.Fatal("route still draining after Resume")
+	}
+}
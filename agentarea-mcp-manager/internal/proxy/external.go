@@ -0,0 +1,225 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RouteOptions configures optional per-route behaviour. It is mostly used for
+// external URL routes, which get the same /mcp/{slug} treatment as containers.
+type RouteOptions struct {
+	Headers     map[string]string // Injected into every upstream request (e.g. upstream credentials)
+	AccessToken string            // If set, clients must send "Authorization: Bearer <token>"
+	RateLimit   float64           // Requests per second (0 = unlimited)
+	RateBurst   int               // Burst size for the rate limiter (defaults to RateLimit)
+	LogRequests bool              // Log every proxied request at info level
+
+	// NoCompression sends responses as the upstream returned them even when
+	// proxy compression is enabled
+	NoCompression bool
+
+	// Identity of the instance behind the route, sent upstream as
+	// X-AgentArea-Workspace and X-AgentArea-Instance
+	WorkspaceID string
+	InstanceID  string
+
+	// Credentials supplies a bearer token for the upstream at request time
+	// (e.g. a brokered OAuth access token). It overrides Headers["Authorization"].
+	Credentials CredentialSource
+}
+
+// CredentialSource supplies upstream bearer tokens for proxied requests
+type CredentialSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// newLimiter returns the rate limiter for the options, or nil if unlimited
+func (o RouteOptions) newLimiter() *rate.Limiter {
+	if o.RateLimit <= 0 {
+		return nil
+	}
+	burst := o.RateBurst
+	if burst <= 0 {
+		burst = int(o.RateLimit)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return rate.NewLimiter(rate.Limit(o.RateLimit), burst)
+}
+
+// AddExternalRoute registers an external MCP server URL under /mcp/{slug}
+func (r *RouteRegistry) AddExternalRoute(slug, rawURL string, opts RouteOptions) error {
+	endpoint, err := ParseExternalURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	return r.addRoute(slug, StrategyFailover, []EndpointConfig{endpoint}, opts)
+}
+
+// Schemes of Unix socket URLs: unix:///run/bridge.sock, or
+// http+unix://%2Frun%2Fbridge.sock/base for a base path
+const (
+	unixScheme     = "unix://"
+	httpUnixScheme = "http+unix://"
+)
+
+// IsSocketURL reports whether rawURL addresses a Unix domain socket
+func IsSocketURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, unixScheme) || strings.HasPrefix(rawURL, httpUnixScheme)
+}
+
+// ParseSocketURL returns the socket path and base path of a Unix socket URL
+func ParseSocketURL(rawURL string) (socket, basePath string, err error) {
+	switch {
+	case strings.HasPrefix(rawURL, unixScheme):
+		socket = strings.TrimPrefix(rawURL, unixScheme)
+	case strings.HasPrefix(rawURL, httpUnixScheme):
+		host, path, _ := strings.Cut(strings.TrimPrefix(rawURL, httpUnixScheme), "/")
+		if socket, err = url.PathUnescape(host); err != nil {
+			return "", "", fmt.Errorf("invalid socket in URL %s: %w", rawURL, err)
+		}
+		if path != "" {
+			basePath = "/" + path
+		}
+	default:
+		return "", "", fmt.Errorf("not a Unix socket URL: %s", rawURL)
+	}
+	if !strings.HasPrefix(socket, "/") {
+		return "", "", fmt.Errorf("socket path must be absolute: %s", rawURL)
+	}
+	return socket, basePath, nil
+}
+
+// ParseExternalURL converts an external URL into an endpoint definition
+func ParseExternalURL(rawURL string) (EndpointConfig, error) {
+	if IsSocketURL(rawURL) {
+		socket, basePath, err := ParseSocketURL(rawURL)
+		if err != nil {
+			return EndpointConfig{}, err
+		}
+		return EndpointConfig{Socket: socket, BasePath: basePath}, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return EndpointConfig{}, fmt.Errorf("invalid external URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return EndpointConfig{}, fmt.Errorf("external URL must use http, https, unix or http+unix: %s", rawURL)
+	}
+	if u.Hostname() == "" {
+		return EndpointConfig{}, fmt.Errorf("external URL has no host: %s", rawURL)
+	}
+
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil {
+			return EndpointConfig{}, fmt.Errorf("invalid port in external URL: %s", rawURL)
+		}
+	}
+
+	return EndpointConfig{
+		Scheme:   u.Scheme,
+		Host:     u.Hostname(),
+		Port:     port,
+		BasePath: u.Path,
+	}, nil
+}
+
+// upstreamTokenHeader carries a brokered token from admit to the director,
+// which turns it into the upstream Authorization header
+const upstreamTokenHeader = "X-Mcp-Upstream-Token"
+
+// admit applies the route's auth, rate limit and quota checks. It writes the error
+// response and returns false if the request must not be forwarded.
+func (ps *ProxyServer) admit(w http.ResponseWriter, r *http.Request, route *ProxyRoute) bool {
+	// Never let clients supply the internal token header themselves
+	r.Header.Del(upstreamTokenHeader)
+
+	if token := route.Options.AccessToken; token != "" {
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return false
+		}
+	}
+
+	if route.limiter != nil && !route.limiter.Allow() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return false
+	}
+
+	if !ps.checkQuota(w, r, route) {
+		return false
+	}
+
+	if route.Options.Credentials != nil {
+		token, err := route.Options.Credentials.Token(r.Context())
+		if err != nil {
+			ps.logger.Error("Failed to obtain upstream credentials",
+				slog.String("slug", route.Slug),
+				slog.String("error", err.Error()))
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+			return false
+		}
+		r.Header.Set(upstreamTokenHeader, token)
+	}
+
+	return true
+}
+
+// statusRecorder captures the response status for request logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher so streaming (SSE) responses keep working
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to hijack it
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// logRequest wraps serve with an access log entry for the route
+func (ps *ProxyServer) logRequest(w http.ResponseWriter, r *http.Request, route *ProxyRoute, serve func(http.ResponseWriter)) {
+	start := time.Now()
+	method, path := r.Method, r.URL.Path
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	serve(rec)
+
+	ps.logger.Info("Proxied MCP request",
+		slog.String("slug", route.Slug),
+		slog.String("method", method),
+		slog.String("path", path),
+		slog.Int("status", rec.status),
+		slog.Duration("latency", time.Since(start)))
+}
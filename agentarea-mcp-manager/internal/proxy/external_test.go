@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExternalRouteInjectsCredentials(t *testing.T) {
+	var gotPath, gotAuth string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	err := ps.AddExternalRoute("remote", backend.URL+"/v1/mcp/", RouteOptions{
+		Headers:     map[string]string{"Authorization": "Bearer upstream-secret"},
+		AccessToken: "client-token",
+		RateLimit:   1,
+		RateBurst:   2,
+		LogRequests: true,
+	})
+	if err != nil {
+		t.Fatalf("AddExternalRoute failed: %v", err)
+	}
+
+	send := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/remote/messages", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, req)
+		return rec.Code
+	}
+
+	if code := send("wrong"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for bad access token, got %d", code)
+	}
+
+	if code := send("client-token"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if gotPath != "/v1/mcp/messages" {
+		t.Errorf("Expected upstream path /v1/mcp/messages, got %s", gotPath)
+	}
+	if gotAuth != "Bearer upstream-secret" {
+		t.Errorf("Expected injected upstream credential, got %q", gotAuth)
+	}
+
+	// Burst of 2: the second request is allowed, the third is limited
+	codes := []int{send("client-token"), send("client-token")}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Expected 200 then 429 from rate limiter, got %v", codes)
+	}
+}
+
+func TestParseExternalURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"https://mcp.example.com/sse", "https mcp.example.com 443 /sse", false},
+		{"http://10.0.0.5:9000", "http 10.0.0.5 9000 ", false},
+		{"ftp://example.com", "", true},
+		{"https:///nohost", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExternalURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseExternalURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if s := fmt.Sprintf("%s %s %d %s", got.Scheme, got.Host, got.Port, got.BasePath); s != tt.want {
+				t.Errorf("ParseExternalURL(%q) = %q, want %q", tt.url, s, tt.want)
+			}
+		}
+	}
+}
+
+func TestInstanceRouteRequiresAuthToken(t *testing.T) {
+	var gotAuth string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	rm := NewRouteManager(ps, nil, ps.logger)
+	host, port := serverHostPort(t, backend)
+	if err := rm.AddMCPInstance(context.Background(), "secured", host, port, "ws-1", "inst-1", "instance-token", true); err != nil {
+		t.Fatalf("AddMCPInstance failed: %v", err)
+	}
+
+	send := func(auth string) int {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/secured/messages", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, req)
+		return rec.Code
+	}
+
+	if code := send(""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", code)
+	}
+	if code := send("Bearer instance-token"); code != http.StatusOK {
+		t.Fatalf("Expected 200 with token, got %d", code)
+	}
+	if gotAuth != "Bearer instance-token" {
+		t.Errorf("Expected the instance token to reach the server, got %q", gotAuth)
+	}
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+)
+
+// RouteSnapshot is a route as handed over to a replacement manager
+type RouteSnapshot struct {
+	Slug          string            `json:"slug"`
+	Strategy      string            `json:"strategy"`
+	Endpoints     []EndpointConfig  `json:"endpoints"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	AccessToken   string            `json:"access_token,omitempty"`
+	RateLimit     float64           `json:"rate_limit,omitempty"`
+	RateBurst     int               `json:"rate_burst,omitempty"`
+	LogRequests   bool              `json:"log_requests,omitempty"`
+	NoCompression bool              `json:"no_compression,omitempty"`
+	WorkspaceID   string            `json:"workspace_id,omitempty"`
+	InstanceID    string            `json:"instance_id,omitempty"`
+}
+
+// ExportRoutes snapshots every route, sorted by slug. Routes whose upstream
+// credentials are brokered at request time cannot be serialized and are
+// returned as skipped; they are registered again when their instance is.
+func (ps *ProxyServer) ExportRoutes() (routes []RouteSnapshot, skipped []string) {
+	routes = []RouteSnapshot{}
+	for slug, route := range ps.registry.GetAllRoutes() {
+		if route.Options.Credentials != nil {
+			skipped = append(skipped, slug)
+			continue
+		}
+		snapshot := RouteSnapshot{
+			Slug:          slug,
+			Strategy:      route.Strategy,
+			Endpoints:     make([]EndpointConfig, 0, len(route.Endpoints)),
+			Headers:       route.Options.Headers,
+			AccessToken:   route.Options.AccessToken,
+			RateLimit:     route.Options.RateLimit,
+			RateBurst:     route.Options.RateBurst,
+			LogRequests:   route.Options.LogRequests,
+			NoCompression: route.Options.NoCompression,
+			WorkspaceID:   route.Options.WorkspaceID,
+			InstanceID:    route.Options.InstanceID,
+		}
+		for _, endpoint := range route.Endpoints {
+			snapshot.Endpoints = append(snapshot.Endpoints, EndpointConfig{
+				Scheme:   endpoint.Scheme,
+				Host:     endpoint.Host,
+				Port:     endpoint.Port,
+				BasePath: endpoint.BasePath,
+			})
+		}
+		routes = append(routes, snapshot)
+	}
+	slices.SortFunc(routes, func(a, b RouteSnapshot) int { return strings.Compare(a.Slug, b.Slug) })
+	slices.Sort(skipped)
+	return routes, skipped
+}
+
+// ImportRoutes registers routes handed over by a previous manager,
+// replacing routes of the same slug. Invalid routes are skipped and reported
+// together.
+func (ps *ProxyServer) ImportRoutes(routes []RouteSnapshot) error {
+	var errs []error
+	for _, snapshot := range routes {
+		opts := RouteOptions{
+			Headers:       snapshot.Headers,
+			AccessToken:   snapshot.AccessToken,
+			RateLimit:     snapshot.RateLimit,
+			RateBurst:     snapshot.RateBurst,
+			LogRequests:   snapshot.LogRequests,
+			NoCompression: snapshot.NoCompression,
+			WorkspaceID:   snapshot.WorkspaceID,
+			InstanceID:    snapshot.InstanceID,
+		}
+		if err := ps.registry.addRoute(snapshot.Slug, snapshot.Strategy, snapshot.Endpoints, opts); err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", snapshot.Slug, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Addr returns the address the proxy listens on
+func (ps *ProxyServer) Addr() string {
+	return ps.server.Addr
+}
+
+// Serve serves proxy requests on a listener opened by the caller, such as
+// one shared with the previous manager during a handoff
+func (ps *ProxyServer) Serve(ln net.Listener) error {
+	ps.logger.Info("Starting proxy server", slog.String("address", ln.Addr().String()))
+	return ps.server.Serve(ln)
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type staticToken string
+
+func (s staticToken) Token(context.Context) (string, error) { return string(s), nil }
+
+func TestRouteHandoff(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	old := NewProxyServer(ProxyConfig{}, logger)
+	if err := old.AddRouteWithOptions("github", "10.0.0.5", 8000, RouteOptions{
+		AccessToken:   "secret",
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+		NoCompression: true,
+		InstanceID:    "inst-1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.AddRouteEndpoints("search", StrategyFailover, []EndpointConfig{
+		{Host: "10.0.0.6", Port: 8000},
+		{Scheme: "https", Host: "search.example.com", Port: 443, BasePath: "/mcp"},
+	}, RouteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.AddExternalRoute("drive", "https://drive.example.com/mcp", RouteOptions{Credentials: staticToken("t")}); err != nil {
+		t.Fatal(err)
+	}
+
+	routes, skipped := old.ExportRoutes()
+	if len(routes) != 2 || routes[0].Slug != "github" || routes[1].Slug != "search" {
+		t.Fatalf("unexpected exported routes: %+v", routes)
+	}
+	if len(skipped) != 1 || skipped[0] != "drive" {
+		t.Errorf("expected the brokered route to be skipped, got %v", skipped)
+	}
+
+	replacement := NewProxyServer(ProxyConfig{}, logger)
+	if err := replacement.ImportRoutes(routes); err != nil {
+		t.Fatal(err)
+	}
+	github, err := replacement.GetRoute("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if github.Addr() != "10.0.0.5:8000" || github.Options.AccessToken != "secret" ||
+		!github.Options.NoCompression || github.Options.InstanceID != "inst-1" {
+		t.Errorf("unexpected imported route: %+v", github)
+	}
+	search, err := replacement.GetRoute("search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.Strategy != StrategyFailover || len(search.Endpoints) != 2 ||
+		search.Endpoints[1].Scheme != "https" || search.Endpoints[1].BasePath != "/mcp" {
+		t.Errorf("unexpected imported endpoints: %+v", search.Endpoints)
+	}
+
+	if err := replacement.ImportRoutes([]RouteSnapshot{{Slug: "empty"}}); err == nil {
+		t.Error("expected a route without endpoints to be rejected")
+	}
+}
@@ -9,15 +9,38 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/agentarea/mcp-manager/internal/metrics"
 )
 
+// mcpPathPrefix is the path prefix under which MCP services are exposed
+const mcpPathPrefix = "/mcp/"
+
 // ProxyServer serves as the HTTP reverse proxy for MCP containers
 type ProxyServer struct {
 	server   *http.Server
 	registry *RouteRegistry
 	logger   *slog.Logger
 	config   ProxyConfig
+
+	// Cached per-route transports and reverse proxies
+	upstreamsMu sync.RWMutex
+	upstreams   map[string]*upstream
+
+	// In-flight requests per route, for connection listing and draining
+	trackersMu sync.RWMutex
+	trackers   map[string]*connTracker
+	connSeq    atomic.Uint64
+
+	// Daily and monthly request quotas per workspace and API key
+	quotas *quotaTracker
+
+	// Cached reverse proxy for the manager service (nil if not configured)
+	managerProxy     *httputil.ReverseProxy
+	managerTransport *http.Transport
 }
 
 // ProxyConfig contains configuration for the proxy server
@@ -27,6 +50,26 @@ type ProxyConfig struct {
 	ReadTimeout       time.Duration // HTTP read timeout
 	WriteTimeout      time.Duration // HTTP write timeout
 	IdleTimeout       time.Duration // HTTP idle timeout
+
+	// Upstream connection pool settings
+	MaxIdleConns        int           // Maximum idle connections per route transport
+	MaxIdleConnsPerHost int           // Maximum idle keep-alive connections per upstream host
+	MaxConnsPerHost     int           // Maximum total connections per upstream host (0 = unlimited)
+	IdleConnTimeout     time.Duration // How long idle connections stay in the pool
+	DialTimeout         time.Duration // Upstream dial timeout
+	KeepAlive           time.Duration // TCP keep-alive period for upstream connections
+
+	// Passive health checking of route endpoints
+	Health HealthPolicy
+
+	// Default request quotas (zero limits = unlimited)
+	Quota QuotaPolicy
+
+	// Compression of uncompressed upstream responses
+	Compression CompressionPolicy
+
+	// Slow request and large payload warnings
+	Thresholds RequestThresholds
 }
 
 // NewProxyServer creates a new proxy server
@@ -43,15 +86,38 @@ func NewProxyServer(cfg ProxyConfig, logger *slog.Logger) *ProxyServer {
 	if cfg.IdleTimeout == 0 {
 		cfg.IdleTimeout = 60 * time.Second
 	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = 100
+	}
+	if cfg.MaxIdleConnsPerHost == 0 {
+		cfg.MaxIdleConnsPerHost = 32
+	}
+	if cfg.IdleConnTimeout == 0 {
+		cfg.IdleConnTimeout = 90 * time.Second
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 10 * time.Second
+	}
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = 30 * time.Second
+	}
+	if cfg.Health.EjectDuration == 0 {
+		cfg.Health.EjectDuration = 30 * time.Second
+	}
 
 	registry := NewRouteRegistry()
 
 	ps := &ProxyServer{
-		registry: registry,
-		logger:   logger,
-		config:   cfg,
+		registry:  registry,
+		logger:    logger,
+		config:    cfg,
+		upstreams: make(map[string]*upstream),
+		trackers:  make(map[string]*connTracker),
+		quotas:    newQuotaTracker(cfg.Quota),
 	}
 
+	ps.managerProxy = ps.newManagerProxy()
+
 	// Create the HTTP server with the proxy handler
 	ps.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -69,6 +135,11 @@ func (ps *ProxyServer) handler() http.Handler {
 	return http.HandlerFunc(ps.handleRequest)
 }
 
+// Handler returns the proxy handler, to serve the proxy on another listener
+func (ps *ProxyServer) Handler() http.Handler {
+	return ps.server.Handler
+}
+
 // handleRequest is the main request handler
 func (ps *ProxyServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 	// Extract slug from path
@@ -76,88 +147,155 @@ func (ps *ProxyServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	if hasSlug {
 		// Try to find route for MCP service
-		route, err := ps.registry.GetRoute(slug)
-		if err == nil {
+		if route, exists := ps.registry.lookup(slug); exists {
 			// Route found, forward to container
 			ps.forwardToContainer(w, r, route)
 			return
 		}
 		// Route not found, log and continue to default handling
-		ps.logger.Debug("Route not found for slug", slog.String("slug", slug))
+		if ps.debugEnabled(r) {
+			ps.logger.Debug("Route not found for slug", slog.String("slug", slug))
+		}
 	}
 
 	// No MCP route matched, forward to manager service
 	ps.forwardToManagerService(w, r)
 }
 
+// debugEnabled reports whether debug logging is on, so the request path can
+// skip building log attributes when it is not
+func (ps *ProxyServer) debugEnabled(r *http.Request) bool {
+	return ps.logger.Enabled(r.Context(), slog.LevelDebug)
+}
+
 // extractSlug extracts the slug from a /mcp/{slug}/... path
 // Returns (slug, found)
 func (ps *ProxyServer) extractSlug(path string) (string, bool) {
 	// Check if path starts with /mcp/
-	if !strings.HasPrefix(path, "/mcp/") {
+	if !strings.HasPrefix(path, mcpPathPrefix) {
 		return "", false
 	}
 
-	// Remove /mcp/ prefix
-	remaining := strings.TrimPrefix(path, "/mcp/")
-
 	// Extract slug (everything before the next /)
-	parts := strings.SplitN(remaining, "/", 2)
-	if len(parts) == 0 || parts[0] == "" {
+	remaining := path[len(mcpPathPrefix):]
+	if i := strings.IndexByte(remaining, '/'); i >= 0 {
+		remaining = remaining[:i]
+	}
+	if remaining == "" {
 		return "", false
 	}
 
-	return parts[0], true
+	return remaining, true
 }
 
-// forwardToContainer forwards the request to the MCP container
+// forwardToContainer forwards the request to one of the route's endpoints
 func (ps *ProxyServer) forwardToContainer(w http.ResponseWriter, r *http.Request, route *ProxyRoute) {
-	// Build target URL by removing /mcp/{slug} prefix and keeping the rest
-	targetPath := ps.stripMCPPrefix(r.URL.Path, route.Slug)
+	start, method := time.Now(), r.Method
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = rec
+	defer func() { metrics.ObserveProxyRequest(method, rec.status, time.Since(start)) }()
 
-	// Create target URL
-	targetURL := &url.URL{
-		Scheme:   "http",
-		Host:     fmt.Sprintf("%s:%d", route.ContainerIP, route.ContainerPort),
-		Path:     targetPath,
-		RawQuery: r.URL.RawQuery,
+	if !ps.admit(w, r, route) {
+		return
 	}
 
-	// Create reverse proxy
-	director := func(req *http.Request) {
-		req.URL = targetURL
-		req.RequestURI = ""
-		req.Host = targetURL.Host
-		req.Header.Set("X-Forwarded-For", ps.getClientIP(r))
-		req.Header.Set("X-Forwarded-Proto", "http")
-		req.Header.Set("X-Forwarded-Host", r.Host)
-		req.Header.Set("X-Forwarded-Path", r.URL.Path)
+	if ps.config.Thresholds.enabled() {
+		m, metered := ps.meterRequest(w, r)
+		w, r = m, metered
+		defer ps.checkThresholds(route, r, m)
 	}
 
-	proxy := &httputil.ReverseProxy{
-		Director:  director,
-		Transport: ps.createTransport(),
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			ps.logger.Error("Proxy error",
-				slog.String("slug", route.Slug),
-				slog.String("target", targetURL.String()),
-				slog.String("error", err.Error()))
-			http.Error(w, "Bad Gateway", http.StatusBadGateway)
-		},
+	if route.Options.LogRequests {
+		ps.logRequest(w, r, route, func(w http.ResponseWriter) {
+			ps.serveUpstream(w, r, route)
+		})
+		return
+	}
+
+	ps.serveUpstream(w, r, route)
+}
+
+// serveUpstream proxies the request to the endpoint picked by the route's balancer
+func (ps *ProxyServer) serveUpstream(w http.ResponseWriter, r *http.Request, route *ProxyRoute) {
+	r, done, ok := ps.track(ps.trackerFor(route.Slug), r)
+	if !ok {
+		// The instance is being restarted, updated or deleted
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	u := ps.upstreamFor(route)
+	u.counters.requests.Add(1)
+	u.counters.lastRequest.Store(time.Now().UnixNano())
+
+	idx := route.pick()
+	endpoint := route.Endpoints[idx]
+
+	if ps.debugEnabled(r) {
+		ps.logger.Debug("Forwarding request to container",
+			slog.String("slug", route.Slug),
+			slog.String("target", endpoint.Addr()),
+			slog.String("path", r.URL.Path))
+	}
+
+	endpoint.active.Add(1)
+	defer endpoint.active.Add(-1)
+
+	u.backends[idx].ServeHTTP(w, r)
+}
+
+// upstreamFor returns the cached upstream for a route, rebuilding it if the
+// route has been updated since it was built
+func (ps *ProxyServer) upstreamFor(route *ProxyRoute) *upstream {
+	ps.upstreamsMu.RLock()
+	u, exists := ps.upstreams[route.Slug]
+	ps.upstreamsMu.RUnlock()
+	if exists && u.route == route {
+		return u
+	}
+
+	ps.upstreamsMu.Lock()
+	defer ps.upstreamsMu.Unlock()
+
+	var counters *routeCounters
+	var transport *http.Transport
+	if u, exists := ps.upstreams[route.Slug]; exists {
+		if u.route == route {
+			return u
+		}
+		// Route updated - keep counters, and keep the pool unless the targets moved
+		counters = u.counters
+		if u.sameEndpoints(route) {
+			transport = u.transport
+		} else {
+			u.close()
+		}
 	}
 
-	ps.logger.Debug("Forwarding request to container",
-		slog.String("slug", route.Slug),
-		slog.String("target", targetURL.String()),
-		slog.String("path", r.URL.Path))
+	u = ps.newUpstream(route, transport, counters)
+	ps.upstreams[route.Slug] = u
+	return u
+}
+
+// dropUpstream removes the cached upstream for a slug and closes its idle connections
+func (ps *ProxyServer) dropUpstream(slug string) {
+	ps.upstreamsMu.Lock()
+	defer ps.upstreamsMu.Unlock()
 
-	proxy.ServeHTTP(w, r)
+	if u, exists := ps.upstreams[slug]; exists {
+		u.close()
+		delete(ps.upstreams, slug)
+	}
 }
 
 // stripMCPPrefix removes /mcp/{slug} from the path
 func (ps *ProxyServer) stripMCPPrefix(path string, slug string) string {
-	prefix := fmt.Sprintf("/mcp/%s", slug)
-	remaining := strings.TrimPrefix(path, prefix)
+	remaining := path
+	if strings.HasPrefix(path, mcpPathPrefix) && strings.HasPrefix(path[len(mcpPathPrefix):], slug) {
+		remaining = path[len(mcpPathPrefix)+len(slug):]
+	}
 
 	// If no remaining path, return /
 	if remaining == "" {
@@ -169,62 +307,59 @@ func (ps *ProxyServer) stripMCPPrefix(path string, slug string) string {
 
 // forwardToManagerService forwards non-MCP requests to the manager service
 func (ps *ProxyServer) forwardToManagerService(w http.ResponseWriter, r *http.Request) {
-	if ps.config.ManagerServiceURL == "" {
+	if ps.managerProxy == nil {
 		ps.logger.Error("Manager service URL not configured")
 		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
+	if ps.debugEnabled(r) {
+		ps.logger.Debug("Forwarding request to manager service",
+			slog.String("target", ps.config.ManagerServiceURL),
+			slog.String("path", r.URL.Path))
+	}
+
+	ps.managerProxy.ServeHTTP(w, r)
+}
+
+// newManagerProxy builds the cached reverse proxy for the manager service
+func (ps *ProxyServer) newManagerProxy() *httputil.ReverseProxy {
+	if ps.config.ManagerServiceURL == "" {
+		return nil
+	}
+
 	targetURL, err := url.Parse(ps.config.ManagerServiceURL)
 	if err != nil {
 		ps.logger.Error("Invalid manager service URL", slog.String("error", err.Error()))
-		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
-		return
+		return nil
 	}
 
-	// Preserve original path and query
-	targetURL.Path = r.URL.Path
-	targetURL.RawQuery = r.URL.RawQuery
+	ps.managerTransport = ps.createTransport()
 
 	director := func(req *http.Request) {
-		req.URL = targetURL
+		originalHost := req.Host
+		clientIP := ps.getClientIP(req)
+
+		// Preserve original path and query
+		req.URL.Scheme = targetURL.Scheme
+		req.URL.Host = targetURL.Host
 		req.RequestURI = ""
 		req.Host = targetURL.Host
-		req.Header.Set("X-Forwarded-For", ps.getClientIP(r))
+		req.Header.Set("X-Forwarded-For", clientIP)
 		req.Header.Set("X-Forwarded-Proto", "http")
-		req.Header.Set("X-Forwarded-Host", r.Host)
+		req.Header.Set("X-Forwarded-Host", originalHost)
 	}
 
-	proxy := &httputil.ReverseProxy{
+	return &httputil.ReverseProxy{
 		Director:  director,
-		Transport: ps.createTransport(),
+		Transport: ps.managerTransport,
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
 			ps.logger.Error("Manager service proxy error",
-				slog.String("target", targetURL.String()),
+				slog.String("target", r.URL.String()),
 				slog.String("error", err.Error()))
 			http.Error(w, "Bad Gateway", http.StatusBadGateway)
 		},
 	}
-
-	ps.logger.Debug("Forwarding request to manager service",
-		slog.String("target", targetURL.String()),
-		slog.String("path", r.URL.Path))
-
-	proxy.ServeHTTP(w, r)
-}
-
-// createTransport creates an HTTP transport with optimized settings
-func (ps *ProxyServer) createTransport() *http.Transport {
-	return &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-		IdleConnTimeout:     90 * time.Second,
-		DisableCompression:  false,
-	}
 }
 
 // getClientIP extracts the client IP from the request
@@ -232,8 +367,10 @@ func (ps *ProxyServer) getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For first (for proxied requests)
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		// Take the first IP in the chain
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
+		if i := strings.IndexByte(xff, ','); i >= 0 {
+			xff = xff[:i]
+		}
+		return strings.TrimSpace(xff)
 	}
 
 	// Check X-Real-IP
@@ -254,9 +391,26 @@ func (ps *ProxyServer) AddRoute(slug, containerIP string, containerPort int) err
 	return ps.registry.AddRoute(slug, containerIP, containerPort)
 }
 
+// AddRouteWithOptions adds a route to a container with per-route options
+func (ps *ProxyServer) AddRouteWithOptions(slug, containerIP string, containerPort int, opts RouteOptions) error {
+	return ps.registry.AddRouteWithOptions(slug, containerIP, containerPort, opts)
+}
+
+// AddRouteEndpoints adds a route that balances across several endpoints
+func (ps *ProxyServer) AddRouteEndpoints(slug, strategy string, endpoints []EndpointConfig, opts RouteOptions) error {
+	return ps.registry.AddRouteEndpoints(slug, strategy, endpoints, opts)
+}
+
+// AddExternalRoute exposes an external MCP server URL under /mcp/{slug}
+func (ps *ProxyServer) AddExternalRoute(slug, rawURL string, opts RouteOptions) error {
+	return ps.registry.AddExternalRoute(slug, rawURL, opts)
+}
+
 // RemoveRoute removes a route from the proxy
 func (ps *ProxyServer) RemoveRoute(slug string) {
 	ps.registry.RemoveRoute(slug)
+	ps.dropUpstream(slug)
+	ps.dropTracker(slug)
 }
 
 // GetRoute retrieves a route
@@ -269,6 +423,31 @@ func (ps *ProxyServer) GetAllRoutes() map[string]*ProxyRoute {
 	return ps.registry.GetAllRoutes()
 }
 
+// GetRouteStats returns connection reuse and request counters for all routes
+// that have served traffic
+func (ps *ProxyServer) GetRouteStats() []RouteStats {
+	ps.upstreamsMu.RLock()
+	defer ps.upstreamsMu.RUnlock()
+
+	stats := make([]RouteStats, 0, len(ps.upstreams))
+	for _, u := range ps.upstreams {
+		stats = append(stats, u.stats())
+	}
+	return stats
+}
+
+// LastRequestTime returns when a route last received a request. It reports
+// false if the route has not served any traffic since the proxy started.
+func (ps *ProxyServer) LastRequestTime(slug string) (time.Time, bool) {
+	ps.upstreamsMu.RLock()
+	u, ok := ps.upstreams[slug]
+	ps.upstreamsMu.RUnlock()
+	if !ok {
+		return time.Time{}, false
+	}
+	return u.counters.lastRequestTime()
+}
+
 // Start starts the proxy server
 func (ps *ProxyServer) Start() error {
 	ps.logger.Info("Starting proxy server", slog.Int("port", ps.config.Port))
@@ -278,5 +457,18 @@ func (ps *ProxyServer) Start() error {
 // Shutdown gracefully shuts down the proxy server
 func (ps *ProxyServer) Shutdown(ctx context.Context) error {
 	ps.logger.Info("Shutting down proxy server")
-	return ps.server.Shutdown(ctx)
+	err := ps.server.Shutdown(ctx)
+
+	ps.upstreamsMu.Lock()
+	for slug, u := range ps.upstreams {
+		u.close()
+		delete(ps.upstreams, slug)
+	}
+	ps.upstreamsMu.Unlock()
+
+	if ps.managerTransport != nil {
+		ps.managerTransport.CloseIdleConnections()
+	}
+
+	return err
 }
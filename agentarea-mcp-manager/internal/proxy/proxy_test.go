@@ -0,0 +1,366 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// maxRoutingNsPerOp is the routing budget for one request on a single core.
+// 20µs per request corresponds to 50k req/s.
+const maxRoutingNsPerOp = int64(time.Second / 50000)
+
+func newTestProxyServer() *ProxyServer {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	return NewProxyServer(ProxyConfig{}, logger)
+}
+
+func TestExtractSlug(t *testing.T) {
+	ps := newTestProxyServer()
+
+	tests := []struct {
+		path     string
+		wantSlug string
+		wantOK   bool
+	}{
+		{"/mcp/echo", "echo", true},
+		{"/mcp/echo/", "echo", true},
+		{"/mcp/echo/sse/messages", "echo", true},
+		{"/mcp/", "", false},
+		{"/mcp//sse", "", false},
+		{"/mcp", "", false},
+		{"/health", "", false},
+		{"/containers/mcp/echo", "", false},
+	}
+
+	for _, tt := range tests {
+		slug, ok := ps.extractSlug(tt.path)
+		if slug != tt.wantSlug || ok != tt.wantOK {
+			t.Errorf("extractSlug(%q) = (%q, %v), want (%q, %v)", tt.path, slug, ok, tt.wantSlug, tt.wantOK)
+		}
+	}
+}
+
+func TestStripMCPPrefix(t *testing.T) {
+	ps := newTestProxyServer()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/mcp/echo", "/"},
+		{"/mcp/echo/", "/"},
+		{"/mcp/echo/sse", "/sse"},
+		{"/mcp/echo/sse/messages?x=1", "/sse/messages?x=1"},
+	}
+
+	for _, tt := range tests {
+		if got := ps.stripMCPPrefix(tt.path, "echo"); got != tt.want {
+			t.Errorf("stripMCPPrefix(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryCopyOnWrite(t *testing.T) {
+	registry := NewRouteRegistry()
+	if err := registry.AddRoute("echo", "10.0.0.1", 8000); err != nil {
+		t.Fatalf("AddRoute failed: %v", err)
+	}
+
+	snapshot := registry.GetAllRoutes()
+	registry.RemoveRoute("echo")
+
+	if _, exists := snapshot["echo"]; !exists {
+		t.Error("Expected snapshot to be unaffected by later updates")
+	}
+	if _, err := registry.GetRoute("echo"); err == nil {
+		t.Error("Expected route to be removed")
+	}
+
+	if err := registry.AddRoute("echo", "10.0.0.2", 9000); err != nil {
+		t.Fatalf("AddRoute failed: %v", err)
+	}
+	route, err := registry.GetRoute("echo")
+	if err != nil {
+		t.Fatalf("GetRoute failed: %v", err)
+	}
+	if route.Addr() != "10.0.0.2:9000" {
+		t.Errorf("Expected addr 10.0.0.2:9000, got %s", route.Addr())
+	}
+}
+
+func TestRegistryKeepsRouteOwner(t *testing.T) {
+	registry := NewRouteRegistry()
+	if err := registry.AddRouteWithOptions("echo", "10.0.0.1", 8000, RouteOptions{InstanceID: "inst-a"}); err != nil {
+		t.Fatalf("AddRouteWithOptions failed: %v", err)
+	}
+
+	err := registry.AddExternalRoute("echo", "https://attacker.example.com", RouteOptions{InstanceID: "inst-b"})
+	if !errors.Is(err, ErrRouteTaken) {
+		t.Fatalf("Expected ErrRouteTaken, got %v", err)
+	}
+	if route, _ := registry.GetRoute("echo"); route.Addr() != "10.0.0.1:8000" {
+		t.Errorf("Expected the route to be unchanged, got %s", route.Addr())
+	}
+
+	if err := registry.AddRoute("echo", "10.0.0.3", 8000); !errors.Is(err, ErrRouteTaken) {
+		t.Fatalf("Expected a route without an instance to be rejected, got %v", err)
+	}
+
+	// The owner can still update its route
+	if err := registry.AddRouteWithOptions("echo", "10.0.0.2", 8000, RouteOptions{InstanceID: "inst-a"}); err != nil {
+		t.Fatalf("Expected the owner to update its route, got %v", err)
+	}
+}
+
+func TestProxyForwardsToRoute(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	addRouteForServer(t, ps, "echo", backend)
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/mcp/echo/sse", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("X-Path"); got != "/sse" {
+			t.Errorf("Expected upstream path /sse, got %s", got)
+		}
+	}
+
+	stats := ps.GetRouteStats()
+	if len(stats) != 1 || stats[0].Requests != 3 {
+		t.Fatalf("Expected 3 requests on one route, got %+v", stats)
+	}
+	if stats[0].ConnsReused == 0 {
+		t.Error("Expected upstream connections to be reused")
+	}
+}
+
+// TestRoutingHotPathPerformance enforces the allocation and throughput budget
+// of the routing hot path (slug extraction, route lookup, endpoint pick,
+// path rewrite).
+func TestRoutingHotPathPerformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping performance test in short mode")
+	}
+
+	ps := newTestProxyServer()
+	for i := 0; i < 1000; i++ {
+		if err := ps.AddRoute(fmt.Sprintf("service-%d", i), "10.0.0.1", 8000+i); err != nil {
+			t.Fatalf("AddRoute failed: %v", err)
+		}
+	}
+
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			routeRequest(ps, "/mcp/service-500/sse/messages")
+		}
+	})
+
+	if allocs := result.AllocsPerOp(); allocs != 0 {
+		t.Errorf("Expected routing hot path to be allocation free, got %d allocs/op", allocs)
+	}
+	if ns := result.NsPerOp(); ns > maxRoutingNsPerOp {
+		t.Errorf("Routing took %dns/op, budget is %dns/op (50k req/s)", ns, maxRoutingNsPerOp)
+	}
+}
+
+// routeRequest runs the per-request routing steps without forwarding
+func routeRequest(ps *ProxyServer, path string) (*ProxyRoute, string) {
+	slug, ok := ps.extractSlug(path)
+	if !ok {
+		return nil, ""
+	}
+	route, exists := ps.registry.lookup(slug)
+	if !exists {
+		return nil, ""
+	}
+	route.pick()
+	return route, ps.stripMCPPrefix(path, slug)
+}
+
+func addRouteForServer(tb testing.TB, ps *ProxyServer, slug string, server *httptest.Server) {
+	tb.Helper()
+
+	host, port := serverHostPort(tb, server)
+	if err := ps.AddRoute(slug, host, port); err != nil {
+		tb.Fatalf("AddRoute failed: %v", err)
+	}
+}
+
+func serverHostPort(tb testing.TB, server *httptest.Server) (string, int) {
+	tb.Helper()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		tb.Fatalf("Failed to parse server URL: %v", err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		tb.Fatalf("Failed to parse server port: %v", err)
+	}
+	return u.Hostname(), port
+}
+
+func BenchmarkExtractSlug(b *testing.B) {
+	ps := newTestProxyServer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ps.extractSlug("/mcp/service-500/sse/messages")
+	}
+}
+
+func BenchmarkRouteLookup(b *testing.B) {
+	ps := newTestProxyServer()
+	for i := 0; i < 1000; i++ {
+		_ = ps.AddRoute(fmt.Sprintf("service-%d", i), "10.0.0.1", 8000+i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		routeRequest(ps, "/mcp/service-500/sse/messages")
+	}
+}
+
+func BenchmarkRouteLookupParallel(b *testing.B) {
+	ps := newTestProxyServer()
+	for i := 0; i < 1000; i++ {
+		_ = ps.AddRoute(fmt.Sprintf("service-%d", i), "10.0.0.1", 8000+i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			routeRequest(ps, "/mcp/service-500/sse/messages")
+		}
+	})
+}
+
+func BenchmarkProxyRequest(b *testing.B) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	addRouteForServer(b, ps, "echo", backend)
+	req := httptest.NewRequest(http.MethodGet, "/mcp/echo/sse", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, req.Clone(req.Context()))
+	}
+}
+
+func TestProxyForwardsToIPv6Route(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		w.WriteHeader(http.StatusOK)
+	}))
+	backend.Listener = listener
+	backend.Start()
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	addRouteForServer(t, ps, "v6", backend)
+	rec := httptest.NewRecorder()
+	ps.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/mcp/v6/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-Host"); got != strings.TrimPrefix(backend.URL, "http://") {
+		t.Errorf("Expected bracketed upstream host, got %s", got)
+	}
+
+	// Default ports are omitted from the Host header, brackets are not
+	endpoint, err := newEndpoint("http", "fd00::5", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := ps.newEndpointProxy(&ProxyRoute{Slug: "v6"}, endpoint, http.DefaultTransport, &routeCounters{})
+	req := httptest.NewRequest(http.MethodGet, "/mcp/v6/", nil)
+	proxy.Director(req)
+	if req.Host != "[fd00::5]" || req.URL.Host != "[fd00::5]:80" {
+		t.Errorf("unexpected upstream host %q, URL host %q", req.Host, req.URL.Host)
+	}
+}
+
+func TestProxyForwardsToSocketRoute(t *testing.T) {
+	dir, err := os.MkdirTemp("", "bridge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "bridge.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	backend.Listener = listener
+	backend.Start()
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	if err := ps.AddRouteEndpoints("bridge", StrategyRoundRobin, []EndpointConfig{{Socket: socket}}, RouteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.AddExternalRoute("based", "http+unix://"+url.PathEscape(socket)+"/mcp", RouteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for slug, wantPath := range map[string]string{"bridge": "/tools", "based": "/mcp/tools"} {
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/mcp/"+slug+"/tools", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", slug, rec.Code)
+		}
+		if got := rec.Header().Get("X-Host"); got != "localhost" {
+			t.Errorf("%s: expected Host localhost, got %s", slug, got)
+		}
+		if got := rec.Header().Get("X-Path"); got != wantPath {
+			t.Errorf("%s: expected path %s, got %s", slug, wantPath, got)
+		}
+	}
+
+	route, _ := ps.GetRoute("bridge")
+	if route.Addr() != "unix:"+socket {
+		t.Errorf("unexpected route address %s", route.Addr())
+	}
+	if err := ps.AddExternalRoute("relative", "unix://bridge.sock", RouteOptions{}); err == nil {
+		t.Error("expected relative socket path to be rejected")
+	}
+}
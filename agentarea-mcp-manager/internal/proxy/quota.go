@@ -0,0 +1,313 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quota subjects
+const (
+	QuotaWorkspace = "workspace"
+	QuotaClient    = "client"
+)
+
+// QuotaLimits caps the proxied requests of a subject per UTC day and month
+type QuotaLimits struct {
+	Daily   int64 `json:"daily"`   // 0 = unlimited
+	Monthly int64 `json:"monthly"` // 0 = unlimited
+}
+
+// unlimited reports whether neither period is capped
+func (l QuotaLimits) unlimited() bool {
+	return l.Daily <= 0 && l.Monthly <= 0
+}
+
+// QuotaPolicy holds the default limits of workspaces and of the API keys
+// calling into them
+type QuotaPolicy struct {
+	Workspace QuotaLimits
+	Client    QuotaLimits
+}
+
+// QuotaUsage is the request count of one subject in the current periods
+type QuotaUsage struct {
+	Kind         string      `json:"kind"`
+	WorkspaceID  string      `json:"workspace_id"`
+	Client       string      `json:"client,omitempty"` // Fingerprint of the API key
+	Daily        int64       `json:"daily"`
+	Monthly      int64       `json:"monthly"`
+	Limits       QuotaLimits `json:"limits"`
+	DailyReset   time.Time   `json:"daily_reset"`
+	MonthlyReset time.Time   `json:"monthly_reset"`
+}
+
+// quotaKey identifies a counter; client counters are scoped to a workspace
+type quotaKey struct {
+	workspaceID string
+	client      string
+}
+
+// quotaCounter counts requests in the day and month they were last seen
+type quotaCounter struct {
+	day     int // Days since the epoch
+	month   int // Months since year 0
+	daily   int64
+	monthly int64
+}
+
+// quotaDecision is the outcome of counting a request
+type quotaDecision struct {
+	allowed   bool
+	limit     int64 // Tightest limit that applied; 0 if none did
+	remaining int64
+	reset     time.Time
+}
+
+// quotaTracker counts proxied requests per workspace and per API key.
+// Counts are kept in memory and start over when the manager restarts.
+type quotaTracker struct {
+	mu        sync.Mutex
+	policy    QuotaPolicy
+	overrides map[string]QuotaLimits // Per-workspace limits, e.g. from a plan
+	counters  map[quotaKey]*quotaCounter
+	pruned    int // Day of the last sweep of stale counters
+}
+
+func newQuotaTracker(policy QuotaPolicy) *quotaTracker {
+	return &quotaTracker{
+		policy:    policy,
+		overrides: make(map[string]QuotaLimits),
+		counters:  make(map[quotaKey]*quotaCounter),
+	}
+}
+
+// quotaPeriods returns the day and month numbers of t and when they end, in UTC
+func quotaPeriods(t time.Time) (day, month int, dayEnd, monthEnd time.Time) {
+	t = t.UTC()
+	y, m, d := t.Date()
+	day = int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+	month = y*12 + int(m) - 1
+	dayEnd = time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+	monthEnd = time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+	return day, month, dayEnd, monthEnd
+}
+
+// clientFingerprint identifies the API key of a request without keeping it:
+// X-API-Key if set, otherwise the bearer token. Returns "" for anonymous requests.
+func clientFingerprint(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimPrefix(auth, "Bearer ")
+		}
+	}
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// limitsLocked returns the limits of a counter (caller holds mu)
+func (q *quotaTracker) limitsLocked(key quotaKey) QuotaLimits {
+	if key.client != "" {
+		return q.policy.Client
+	}
+	if limits, ok := q.overrides[key.workspaceID]; ok {
+		return limits
+	}
+	return q.policy.Workspace
+}
+
+// enabledLocked reports whether any limit is configured (caller holds mu)
+func (q *quotaTracker) enabledLocked() bool {
+	return !q.policy.Workspace.unlimited() || !q.policy.Client.unlimited() || len(q.overrides) > 0
+}
+
+// consume counts a request against its workspace and API key. A request
+// over any limit is denied and not counted.
+func (q *quotaTracker) consume(workspaceID, client string, now time.Time) quotaDecision {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if workspaceID == "" || !q.enabledLocked() {
+		return quotaDecision{allowed: true}
+	}
+
+	day, month, dayEnd, monthEnd := quotaPeriods(now)
+	if day != q.pruned {
+		q.pruneLocked(month)
+		q.pruned = day
+	}
+
+	keys := []quotaKey{{workspaceID: workspaceID}}
+	if client != "" {
+		keys = append(keys, quotaKey{workspaceID: workspaceID, client: client})
+	}
+
+	decision := quotaDecision{allowed: true}
+	counters := make([]*quotaCounter, len(keys))
+	for i, key := range keys {
+		counter := q.counters[key]
+		if counter == nil {
+			counter = &quotaCounter{}
+			q.counters[key] = counter
+		}
+		counter.roll(day, month)
+		counters[i] = counter
+
+		limits := q.limitsLocked(key)
+		decision.tighten(limits.Daily, counter.daily, dayEnd)
+		decision.tighten(limits.Monthly, counter.monthly, monthEnd)
+	}
+
+	if decision.limit > 0 && decision.remaining <= 0 {
+		decision.allowed = false
+		return decision
+	}
+	for _, counter := range counters {
+		counter.daily++
+		counter.monthly++
+	}
+	if decision.limit > 0 {
+		decision.remaining--
+	}
+	return decision
+}
+
+// roll starts new periods when the day or month has changed
+func (c *quotaCounter) roll(day, month int) {
+	if c.day != day {
+		c.day = day
+		c.daily = 0
+	}
+	if c.month != month {
+		c.month = month
+		c.monthly = 0
+	}
+}
+
+// tighten records a limit if it leaves fewer requests than the current one
+func (d *quotaDecision) tighten(limit, used int64, reset time.Time) {
+	if limit <= 0 {
+		return
+	}
+	remaining := max(limit-used, 0)
+	if d.limit == 0 || remaining < d.remaining || (remaining == d.remaining && reset.After(d.reset)) {
+		d.limit = limit
+		d.remaining = remaining
+		d.reset = reset
+	}
+}
+
+// pruneLocked drops counters not used this month (caller holds mu)
+func (q *quotaTracker) pruneLocked(month int) {
+	for key, counter := range q.counters {
+		if counter.month != month {
+			delete(q.counters, key)
+		}
+	}
+}
+
+// setLimits overrides the limits of a workspace; nil restores the default
+func (q *quotaTracker) setLimits(workspaceID string, limits *QuotaLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if limits == nil {
+		delete(q.overrides, workspaceID)
+		return
+	}
+	q.overrides[workspaceID] = *limits
+}
+
+// usage returns the counters of a workspace and its API keys, or of every
+// workspace if workspaceID is empty
+func (q *quotaTracker) usage(workspaceID string, now time.Time) []QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	day, month, dayEnd, monthEnd := quotaPeriods(now)
+	var usage []QuotaUsage
+	for key, counter := range q.counters {
+		if workspaceID != "" && key.workspaceID != workspaceID {
+			continue
+		}
+		entry := QuotaUsage{
+			Kind:         QuotaWorkspace,
+			WorkspaceID:  key.workspaceID,
+			Client:       key.client,
+			Limits:       q.limitsLocked(key),
+			DailyReset:   dayEnd,
+			MonthlyReset: monthEnd,
+		}
+		if key.client != "" {
+			entry.Kind = QuotaClient
+		}
+		if counter.day == day {
+			entry.Daily = counter.daily
+		}
+		if counter.month == month {
+			entry.Monthly = counter.monthly
+		}
+		usage = append(usage, entry)
+	}
+
+	// A workspace without traffic still reports its limits
+	if workspaceID != "" && len(usage) == 0 {
+		usage = append(usage, QuotaUsage{
+			Kind:         QuotaWorkspace,
+			WorkspaceID:  workspaceID,
+			Limits:       q.limitsLocked(quotaKey{workspaceID: workspaceID}),
+			DailyReset:   dayEnd,
+			MonthlyReset: monthEnd,
+		})
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].WorkspaceID != usage[j].WorkspaceID {
+			return usage[i].WorkspaceID < usage[j].WorkspaceID
+		}
+		return usage[i].Client < usage[j].Client
+	})
+	return usage
+}
+
+// checkQuota counts a request against its workspace and API key quotas,
+// setting the X-Quota-* headers. It writes a 429 and returns false if a
+// quota is used up.
+func (ps *ProxyServer) checkQuota(w http.ResponseWriter, r *http.Request, route *ProxyRoute) bool {
+	now := time.Now()
+	decision := ps.quotas.consume(route.Options.WorkspaceID, clientFingerprint(r), now)
+	if decision.limit > 0 {
+		h := w.Header()
+		h.Set("X-Quota-Limit", strconv.FormatInt(decision.limit, 10))
+		h.Set("X-Quota-Remaining", strconv.FormatInt(decision.remaining, 10))
+		h.Set("X-Quota-Reset", strconv.FormatInt(decision.reset.Unix(), 10))
+	}
+	if decision.allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(decision.reset.Sub(now).Seconds())+1, 10))
+	http.Error(w, "Request quota exceeded", http.StatusTooManyRequests)
+	return false
+}
+
+// SetWorkspaceQuota overrides the request quota of a workspace; nil restores
+// the default
+func (ps *ProxyServer) SetWorkspaceQuota(workspaceID string, limits *QuotaLimits) {
+	ps.quotas.setLimits(workspaceID, limits)
+}
+
+// QuotaUsage returns the request counts of a workspace and its API keys, or
+// of all workspaces if workspaceID is empty
+func (ps *ProxyServer) QuotaUsage(workspaceID string) []QuotaUsage {
+	return ps.quotas.usage(workspaceID, time.Now())
+}
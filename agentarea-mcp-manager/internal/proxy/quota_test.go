@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQuotaTracker(t *testing.T) {
+	q := newQuotaTracker(QuotaPolicy{
+		Workspace: QuotaLimits{Daily: 3, Monthly: 4},
+		Client:    QuotaLimits{Daily: 2},
+	})
+	day1 := time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+
+	// The API key runs out first
+	if d := q.consume("ws", "key-a", day1); !d.allowed || d.limit != 2 || d.remaining != 1 {
+		t.Fatalf("Expected first request allowed with 1 remaining on the key, got %+v", d)
+	}
+	q.consume("ws", "key-a", day1)
+	if d := q.consume("ws", "key-a", day1); d.allowed {
+		t.Fatalf("Expected key-a to be over its daily quota, got %+v", d)
+	}
+
+	// Another key still counts against the workspace, which then runs out
+	if d := q.consume("ws", "key-b", day1); !d.allowed || d.remaining != 0 || d.limit != 3 {
+		t.Fatalf("Expected key-b allowed with the workspace exhausted, got %+v", d)
+	}
+	if d := q.consume("ws", "key-b", day1); d.allowed || !d.reset.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected workspace daily quota to reset at midnight, got %+v", d)
+	}
+
+	// Other workspaces and unscoped routes are unaffected
+	if d := q.consume("other", "", day1); !d.allowed {
+		t.Error("Expected another workspace to be allowed")
+	}
+	if d := q.consume("", "key-a", day1); !d.allowed || d.limit != 0 {
+		t.Errorf("Expected routes without a workspace to skip quotas, got %+v", d)
+	}
+
+	// A new day resets the daily counts; the month has rolled over too
+	day2 := day1.Add(2 * time.Hour)
+	if d := q.consume("ws", "key-a", day2); !d.allowed {
+		t.Fatalf("Expected a new day to reset the quota, got %+v", d)
+	}
+
+	// Overrides replace the workspace default
+	q.setLimits("ws", &QuotaLimits{Monthly: 1})
+	if d := q.consume("ws", "", day2); d.allowed || d.limit != 1 {
+		t.Errorf("Expected the override to deny the second request of the month, got %+v", d)
+	}
+
+	var usage QuotaUsage
+	for _, u := range q.usage("ws", day2) {
+		if u.Kind == QuotaWorkspace {
+			usage = u
+		}
+	}
+	if usage.Daily != 1 || usage.Monthly != 1 || usage.Limits.Monthly != 1 {
+		t.Errorf("Unexpected workspace usage: %+v", usage)
+	}
+}
+
+func TestQuotaHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	ps.SetWorkspaceQuota("ws", &QuotaLimits{Daily: 1})
+	if err := ps.AddExternalRoute("quota", backend.URL, RouteOptions{WorkspaceID: "ws"}); err != nil {
+		t.Fatalf("AddExternalRoute failed: %v", err)
+	}
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/quota/messages", nil)
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		ps.handleRequest(rec, req)
+		return rec
+	}
+
+	rec := send()
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Limit") != "1" || rec.Header().Get("X-Quota-Remaining") != "0" {
+		t.Fatalf("Expected 200 with quota headers, got %d %v", rec.Code, rec.Header())
+	}
+	rec = send()
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("X-Quota-Reset") == "" || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with reset headers, got %d %v", rec.Code, rec.Header())
+	}
+
+	usage := ps.QuotaUsage("ws")
+	if len(usage) != 2 || usage[1].Kind != QuotaClient || usage[1].Client == "secret" {
+		t.Errorf("Expected workspace and fingerprinted key usage, got %+v", usage)
+	}
+}
@@ -1,32 +1,87 @@
 package proxy
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
 )
 
+// ErrRouteTaken is returned when a slug is already routed to another instance
+var ErrRouteTaken = errors.New("route belongs to another instance")
+
 // ProxyRoute represents a route to an MCP container
 type ProxyRoute struct {
 	Slug          string
-	ContainerIP   string
-	ContainerPort int
+	ContainerIP   string // Primary (first) endpoint host; localhost for a socket
+	ContainerPort int    // Primary (first) endpoint port; 0 for a socket
+
+	// Endpoints the route balances across; always has at least one entry
+	Endpoints []*Endpoint
+	Strategy  string
+
+	// Optional auth, rate limiting, logging and header injection
+	Options RouteOptions
+
+	// addr is the precomputed host:port of the primary endpoint
+	addr     string
+	balancer Balancer
+	limiter  *rate.Limiter
 }
 
-// RouteRegistry manages all active proxy routes
+// EndpointConfig describes an upstream endpoint when registering a route
+type EndpointConfig struct {
+	Scheme   string `json:"scheme,omitempty"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	BasePath string `json:"base_path,omitempty"`
+	Socket   string `json:"socket,omitempty"` // Unix socket path instead of host and port
+}
+
+// Addr returns the host:port of the primary endpoint
+func (p *ProxyRoute) Addr() string {
+	if p.addr == "" {
+		return net.JoinHostPort(p.ContainerIP, strconv.Itoa(p.ContainerPort))
+	}
+	return p.addr
+}
+
+// pick returns the index of the endpoint for the next request
+func (p *ProxyRoute) pick() int {
+	if len(p.Endpoints) == 1 || p.balancer == nil {
+		return 0
+	}
+	return p.balancer.Pick(p.Endpoints)
+}
+
+// RouteRegistry manages all active proxy routes.
+// Reads are lock-free: writers copy the route map and atomically swap it in,
+// so the request path never contends with route updates.
 type RouteRegistry struct {
-	mu     sync.RWMutex
-	routes map[string]*ProxyRoute
+	mu     sync.Mutex // serializes writers
+	routes atomic.Pointer[map[string]*ProxyRoute]
 }
 
 // NewRouteRegistry creates a new route registry
 func NewRouteRegistry() *RouteRegistry {
-	return &RouteRegistry{
-		routes: make(map[string]*ProxyRoute),
-	}
+	r := &RouteRegistry{}
+	routes := make(map[string]*ProxyRoute)
+	r.routes.Store(&routes)
+	return r
 }
 
-// AddRoute adds or updates a route in the registry
+// AddRoute adds or updates a single-endpoint route in the registry
 func (r *RouteRegistry) AddRoute(slug, containerIP string, containerPort int) error {
+	return r.AddRouteWithOptions(slug, containerIP, containerPort, RouteOptions{})
+}
+
+// AddRouteWithOptions adds or updates a single-endpoint route with per-route options
+func (r *RouteRegistry) AddRouteWithOptions(slug, containerIP string, containerPort int, opts RouteOptions) error {
 	if slug == "" {
 		return fmt.Errorf("slug cannot be empty")
 	}
@@ -37,32 +92,85 @@ func (r *RouteRegistry) AddRoute(slug, containerIP string, containerPort int) er
 		return fmt.Errorf("invalid container port: %d", containerPort)
 	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	return r.addRoute(slug, StrategyRoundRobin, []EndpointConfig{
+		{Host: containerIP, Port: containerPort},
+	}, opts)
+}
+
+// AddRouteEndpoints adds or updates a route that balances across several
+// endpoints using the given strategy
+func (r *RouteRegistry) AddRouteEndpoints(slug, strategy string, endpoints []EndpointConfig, opts RouteOptions) error {
+	return r.addRoute(slug, strategy, endpoints, opts)
+}
 
-	r.routes[slug] = &ProxyRoute{
-		Slug:          slug,
-		ContainerIP:   containerIP,
-		ContainerPort: containerPort,
+// addRoute validates and publishes a route
+func (r *RouteRegistry) addRoute(slug, strategy string, endpoints []EndpointConfig, opts RouteOptions) error {
+	if slug == "" {
+		return fmt.Errorf("slug cannot be empty")
+	}
+	if len(endpoints) == 0 {
+		return fmt.Errorf("route %s must have at least one endpoint", slug)
+	}
+	if strategy == "" {
+		strategy = StrategyRoundRobin
+	}
+
+	balancer, err := NewBalancer(strategy)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	route := &ProxyRoute{
+		Slug:      slug,
+		Endpoints: make([]*Endpoint, 0, len(endpoints)),
+		Strategy:  strategy,
+		Options:   opts,
+		balancer:  balancer,
+		limiter:   opts.newLimiter(),
+	}
+	for _, cfg := range endpoints {
+		var endpoint *Endpoint
+		if cfg.Socket != "" {
+			endpoint, err = newSocketEndpoint(cfg.Scheme, cfg.Socket)
+		} else {
+			endpoint, err = newEndpoint(cfg.Scheme, cfg.Host, cfg.Port)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid endpoint for route %s: %w", slug, err)
+		}
+		endpoint.BasePath = strings.TrimSuffix(cfg.BasePath, "/")
+		route.Endpoints = append(route.Endpoints, endpoint)
+	}
+
+	primary := route.Endpoints[0]
+	route.ContainerIP = primary.Host
+	route.ContainerPort = primary.Port
+	route.addr = primary.addr
+
+	// A route is only replaced by a registration for the same instance, so
+	// a chosen slug cannot take over another instance's traffic
+	var taken error
+	r.update(func(routes map[string]*ProxyRoute) {
+		if existing, ok := routes[slug]; ok && existing.Options.InstanceID != opts.InstanceID {
+			taken = fmt.Errorf("%w: %s", ErrRouteTaken, slug)
+			return
+		}
+		routes[slug] = route
+	})
+
+	return taken
 }
 
 // RemoveRoute removes a route from the registry
 func (r *RouteRegistry) RemoveRoute(slug string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	delete(r.routes, slug)
+	r.update(func(routes map[string]*ProxyRoute) {
+		delete(routes, slug)
+	})
 }
 
 // GetRoute retrieves a route by slug
 func (r *RouteRegistry) GetRoute(slug string) (*ProxyRoute, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	route, exists := r.routes[slug]
+	route, exists := r.lookup(slug)
 	if !exists {
 		return nil, fmt.Errorf("route not found for slug: %s", slug)
 	}
@@ -70,14 +178,19 @@ func (r *RouteRegistry) GetRoute(slug string) (*ProxyRoute, error) {
 	return route, nil
 }
 
+// lookup retrieves a route by slug without allocating
+func (r *RouteRegistry) lookup(slug string) (*ProxyRoute, bool) {
+	route, exists := (*r.routes.Load())[slug]
+	return route, exists
+}
+
 // GetAllRoutes returns all registered routes
 func (r *RouteRegistry) GetAllRoutes() map[string]*ProxyRoute {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	current := *r.routes.Load()
 
 	// Return a copy to prevent external modification
-	routes := make(map[string]*ProxyRoute, len(r.routes))
-	for k, v := range r.routes {
+	routes := make(map[string]*ProxyRoute, len(current))
+	for k, v := range current {
 		routes[k] = v
 	}
 	return routes
@@ -88,5 +201,20 @@ func (r *RouteRegistry) Clear() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.routes = make(map[string]*ProxyRoute)
+	routes := make(map[string]*ProxyRoute)
+	r.routes.Store(&routes)
+}
+
+// update applies fn to a copy of the route map and publishes the result
+func (r *RouteRegistry) update(fn func(routes map[string]*ProxyRoute)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := *r.routes.Load()
+	next := make(map[string]*ProxyRoute, len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	fn(next)
+	r.routes.Store(&next)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/agentarea/mcp-manager/internal/config"
 )
@@ -49,6 +50,64 @@ func (rm *RouteManager) AddMCPService(ctx context.Context, slug, containerIP str
 	return nil
 }
 
+// AddMCPInstance adds the route for an MCP server instance container. The
+// workspace and instance ids are forwarded to the server on every request.
+// If authToken is set, clients must present it as a bearer token; it is also
+// passed on upstream so the server can verify it itself. compression false
+// opts the route out of response compression.
+func (rm *RouteManager) AddMCPInstance(ctx context.Context, slug, containerIP string, containerPort int, workspaceID, instanceID, authToken string, compression bool) error {
+	opts := RouteOptions{WorkspaceID: workspaceID, InstanceID: instanceID, NoCompression: !compression}
+	if authToken != "" {
+		opts.AccessToken = authToken
+		opts.Headers = map[string]string{"Authorization": "Bearer " + authToken}
+	}
+	if err := rm.proxy.AddRouteWithOptions(slug, containerIP, containerPort, opts); err != nil {
+		return fmt.Errorf("failed to add proxy route: %w", err)
+	}
+
+	rm.logger.Info("Added proxy route for MCP instance",
+		slog.String("slug", slug),
+		slog.String("instance_id", instanceID),
+		slog.String("container_ip", containerIP),
+		slog.Int("port", containerPort),
+		slog.Bool("auth", authToken != ""),
+		slog.Bool("compression", compression))
+
+	return nil
+}
+
+// AddMCPServiceEndpoints adds an MCP service route that balances across
+// several endpoints (replicas or a fallback URL instance)
+func (rm *RouteManager) AddMCPServiceEndpoints(ctx context.Context, slug, strategy string, endpoints []EndpointConfig, opts RouteOptions) error {
+	if err := rm.proxy.AddRouteEndpoints(slug, strategy, endpoints, opts); err != nil {
+		return fmt.Errorf("failed to add proxy route: %w", err)
+	}
+
+	rm.logger.Info("Added load balanced proxy route for MCP service",
+		slog.String("slug", slug),
+		slog.String("strategy", strategy),
+		slog.Int("endpoints", len(endpoints)))
+
+	return nil
+}
+
+// AddExternalService exposes an external MCP server URL through the proxy
+// so it gets the same /mcp/{slug} endpoint as container-backed services
+func (rm *RouteManager) AddExternalService(ctx context.Context, slug, rawURL string, opts RouteOptions) error {
+	if err := rm.proxy.AddExternalRoute(slug, rawURL, opts); err != nil {
+		return fmt.Errorf("failed to add external proxy route: %w", err)
+	}
+
+	rm.logger.Info("Added proxy route for external MCP service",
+		slog.String("slug", slug),
+		slog.String("url", rawURL),
+		slog.Bool("access_token", opts.AccessToken != ""),
+		slog.Float64("rate_limit", opts.RateLimit),
+		slog.Int("injected_headers", len(opts.Headers)))
+
+	return nil
+}
+
 // RemoveMCPService removes an MCP service route from the proxy
 func (rm *RouteManager) RemoveMCPService(ctx context.Context, slug string) error {
 	if slug == "" {
@@ -62,3 +121,86 @@ func (rm *RouteManager) RemoveMCPService(ctx context.Context, slug string) error
 
 	return nil
 }
+
+// RouteAddr returns the host:port of a single-endpoint route
+func (rm *RouteManager) RouteAddr(slug string) (string, bool) {
+	route, err := rm.proxy.GetRoute(slug)
+	if err != nil || len(route.Endpoints) != 1 {
+		return "", false
+	}
+	return route.Addr(), true
+}
+
+// LastRequestTime returns when an MCP service last received a request
+func (rm *RouteManager) LastRequestTime(slug string) (time.Time, bool) {
+	return rm.proxy.LastRequestTime(slug)
+}
+
+// GetRouteStats returns per-route request and connection reuse counters
+func (rm *RouteManager) GetRouteStats() []RouteStats {
+	return rm.proxy.GetRouteStats()
+}
+
+// Connections returns the in-flight requests and streams of an MCP service
+func (rm *RouteManager) Connections(slug string) []ConnectionInfo {
+	return rm.proxy.Connections(slug)
+}
+
+// ActiveConnections returns the number of in-flight requests of an MCP service
+func (rm *RouteManager) ActiveConnections(slug string) int {
+	return len(rm.proxy.Connections(slug))
+}
+
+// DrainMCPService stops new requests to an MCP service and waits for
+// in-flight ones, force-closing the rest after timeout if force is set. It
+// returns the connections left open and the number closed.
+func (rm *RouteManager) DrainMCPService(ctx context.Context, slug string, timeout time.Duration, force bool) (remaining, closed int) {
+	result := rm.proxy.Drain(ctx, slug, timeout, force)
+
+	rm.logger.Info("Drained proxy route for MCP service",
+		slog.String("slug", slug),
+		slog.Bool("drained", result.Drained),
+		slog.Int("remaining", result.Remaining),
+		slog.Int("closed", result.Closed),
+		slog.Duration("waited", result.Waited))
+
+	return result.Remaining, result.Closed
+}
+
+// ResumeMCPService admits requests to a drained MCP service again
+func (rm *RouteManager) ResumeMCPService(slug string) {
+	rm.proxy.Resume(slug)
+}
+
+// SetWorkspaceQuota overrides the proxied request quota of a workspace; nil
+// restores the default
+func (rm *RouteManager) SetWorkspaceQuota(workspaceID string, limits *QuotaLimits) {
+	rm.proxy.SetWorkspaceQuota(workspaceID, limits)
+
+	if limits == nil {
+		rm.logger.Info("Reset workspace request quota", slog.String("workspace_id", workspaceID))
+		return
+	}
+	rm.logger.Info("Set workspace request quota",
+		slog.String("workspace_id", workspaceID),
+		slog.Int64("daily", limits.Daily),
+		slog.Int64("monthly", limits.Monthly))
+}
+
+// QuotaUsage returns the proxied request counts of a workspace and its API
+// keys, or of all workspaces if workspaceID is empty
+func (rm *RouteManager) QuotaUsage(workspaceID string) []QuotaUsage {
+	return rm.proxy.QuotaUsage(workspaceID)
+}
+
+// ExportRoutes snapshots the proxy routes for a replacement manager
+func (rm *RouteManager) ExportRoutes() ([]RouteSnapshot, []string) {
+	return rm.proxy.ExportRoutes()
+}
+
+// ImportRoutes registers the routes handed over by a previous manager
+func (rm *RouteManager) ImportRoutes(routes []RouteSnapshot) error {
+	err := rm.proxy.ImportRoutes(routes)
+	rm.logger.Info("Imported proxy routes from previous manager", slog.Int("routes", len(routes)))
+	return err
+}
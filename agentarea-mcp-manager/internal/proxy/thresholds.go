@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rpcHeadSize is how much of a request body is kept to name its JSON-RPC
+// method in warnings
+const rpcHeadSize = 4096
+
+var (
+	rpcMethodPattern = regexp.MustCompile(`"method"\s*:\s*"([^"]{1,128})"`)
+	rpcToolPattern   = regexp.MustCompile(`"params"\s*:\s*\{[^{}]*?"name"\s*:\s*"([^"]{1,128})"`)
+)
+
+// RequestThresholds are the latency and size above which a proxied request
+// is logged as a warning and counted in the route stats
+type RequestThresholds struct {
+	Slow         time.Duration // Time to the end of the response; 0 disables
+	LargePayload int64         // Request or response body bytes; 0 disables
+}
+
+// enabled reports whether any threshold is set
+func (t RequestThresholds) enabled() bool {
+	return t.Slow > 0 || t.LargePayload > 0
+}
+
+// meteredBody counts the request body bytes read by the upstream transport,
+// which may still be reading when the handler returns
+type meteredBody struct {
+	io.ReadCloser
+	bytes atomic.Int64
+	mu    sync.Mutex
+	head  []byte
+}
+
+// Read implements io.Reader
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes.Add(int64(n))
+	b.mu.Lock()
+	if room := rpcHeadSize - len(b.head); room > 0 {
+		b.head = append(b.head, p[:min(n, room)]...)
+	}
+	b.mu.Unlock()
+	return n, err
+}
+
+// rpcCall returns the JSON-RPC method and, for tools/call, the tool name
+func (b *meteredBody) rpcCall() (method, tool string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if match := rpcMethodPattern.FindSubmatch(b.head); match != nil {
+		method = string(match[1])
+	}
+	if method == "tools/call" {
+		if match := rpcToolPattern.FindSubmatch(b.head); match != nil {
+			tool = string(match[1])
+		}
+	}
+	return method, tool
+}
+
+// meter records the status, content type and size of a response
+type meter struct {
+	http.ResponseWriter
+	start       time.Time
+	status      int
+	contentType string
+	bytes       int64
+	body        *meteredBody // nil for requests without a body
+}
+
+// WriteHeader implements http.ResponseWriter
+func (m *meter) WriteHeader(code int) {
+	if m.status == 0 {
+		m.status = code
+		m.contentType = m.Header().Get("Content-Type")
+	}
+	m.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (m *meter) Write(p []byte) (int, error) {
+	if m.status == 0 {
+		m.WriteHeader(http.StatusOK)
+	}
+	n, err := m.ResponseWriter.Write(p)
+	m.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher so streaming (SSE) responses keep working
+func (m *meter) Flush() {
+	if f, ok := m.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// meterRequest wraps the response writer and request body of a proxied
+// request for checkThresholds
+func (ps *ProxyServer) meterRequest(w http.ResponseWriter, r *http.Request) (*meter, *http.Request) {
+	m := &meter{ResponseWriter: w, start: time.Now()}
+	if r.Body != nil && r.Body != http.NoBody {
+		m.body = &meteredBody{ReadCloser: r.Body}
+		r = r.WithContext(r.Context())
+		r.Body = m.body
+	}
+	return m, r
+}
+
+// checkThresholds warns about and counts a request that was slow or carried
+// a large payload. Long-lived event streams opened with GET are only checked
+// for the request size, since they stay open and keep growing by design.
+func (ps *ProxyServer) checkThresholds(route *ProxyRoute, r *http.Request, m *meter) {
+	thresholds := ps.config.Thresholds
+	latency := time.Since(m.start)
+	var requestBytes int64
+	if m.body != nil {
+		requestBytes = m.body.bytes.Load()
+	}
+	stream := r.Method == http.MethodGet && isEventStream(m.contentType)
+
+	slow := thresholds.Slow > 0 && !stream && latency > thresholds.Slow
+	large := thresholds.LargePayload > 0 &&
+		(requestBytes > thresholds.LargePayload || (!stream && m.bytes > thresholds.LargePayload))
+	if !slow && !large {
+		return
+	}
+
+	counters := ps.upstreamFor(route).counters
+	message := "Large MCP payload"
+	if slow {
+		counters.slowRequests.Add(1)
+		message = "Slow MCP request"
+	}
+	if large {
+		counters.largePayloads.Add(1)
+	}
+
+	attrs := []any{
+		slog.String("slug", route.Slug),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", m.status),
+		slog.Duration("latency", latency),
+		slog.Int64("request_bytes", requestBytes),
+		slog.Int64("response_bytes", m.bytes),
+		slog.Bool("slow", slow),
+		slog.Bool("large", large),
+	}
+	if m.body != nil {
+		if method, tool := m.body.rpcCall(); method != "" {
+			attrs = append(attrs, slog.String("rpc_method", method))
+			if tool != "" {
+				attrs = append(attrs, slog.String("tool", tool))
+			}
+		}
+	}
+	if route.Options.InstanceID != "" {
+		attrs = append(attrs, slog.String("instance_id", route.Options.InstanceID))
+	}
+	ps.logger.Warn(message, attrs...)
+}
+
+// isEventStream reports whether a Content-Type is a server-sent event stream
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestThresholds(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(30 * time.Millisecond)
+			io.WriteString(w, `{"result":{}}`)
+		case "/large":
+			io.WriteString(w, `{"result":"`+strings.Repeat("x", 2048)+`"}`)
+		case "/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			time.Sleep(30 * time.Millisecond)
+			io.WriteString(w, "data: "+strings.Repeat("x", 2048)+"\n\n")
+		default:
+			io.WriteString(w, `{"result":{}}`)
+		}
+	}))
+	defer backend.Close()
+
+	var logs bytes.Buffer
+	ps := NewProxyServer(ProxyConfig{Thresholds: RequestThresholds{Slow: 20 * time.Millisecond, LargePayload: 1024}},
+		slog.New(slog.NewJSONHandler(&logs, nil)))
+	host, port := serverHostPort(t, backend)
+	if err := ps.AddRouteWithOptions("search", host, port, RouteOptions{InstanceID: "inst-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method, path, body string) map[string]interface{} {
+		logs.Reset()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		ps.handleRequest(httptest.NewRecorder(), req)
+		if logs.Len() == 0 {
+			return nil
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid log entry %q: %v", logs.String(), err)
+		}
+		return entry
+	}
+
+	call := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"web_search","arguments":{}}}`
+	entry := send(http.MethodPost, "/mcp/search/slow", call)
+	if entry == nil || entry["msg"] != "Slow MCP request" {
+		t.Fatalf("slow request not reported: %v", entry)
+	}
+	if entry["slug"] != "search" || entry["rpc_method"] != "tools/call" || entry["tool"] != "web_search" || entry["instance_id"] != "inst-1" {
+		t.Errorf("slow request warning lacks context: %v", entry)
+	}
+	if entry["request_bytes"] != float64(len(call)) {
+		t.Errorf("request_bytes = %v, want %d", entry["request_bytes"], len(call))
+	}
+
+	entry = send(http.MethodPost, "/mcp/search/large", `{"jsonrpc":"2.0","id":8,"method":"resources/read"}`)
+	if entry == nil || entry["msg"] != "Large MCP payload" || entry["rpc_method"] != "resources/read" || entry["response_bytes"].(float64) <= 1024 {
+		t.Fatalf("large response not reported: %v", entry)
+	}
+	entry = send(http.MethodPost, "/mcp/search/", `{"method":"tools/call","params":{"name":"upload","arguments":{"data":"`+strings.Repeat("y", 2048)+`"}}}`)
+	if entry == nil || entry["large"] != true || entry["tool"] != "upload" {
+		t.Fatalf("large request not reported: %v", entry)
+	}
+
+	if entry := send(http.MethodPost, "/mcp/search/", call); entry != nil {
+		t.Errorf("fast small request reported: %v", entry)
+	}
+	if entry := send(http.MethodGet, "/mcp/search/stream", ""); entry != nil {
+		t.Errorf("long-lived event stream reported: %v", entry)
+	}
+
+	stats := ps.GetRouteStats()
+	if len(stats) != 1 || stats[0].SlowRequests != 1 || stats[0].LargePayloads != 2 {
+		t.Errorf("route stats = %+v", stats)
+	}
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Headers carried on every proxied request so traces and logs on the MCP
+// server can be correlated with the agent run that made the call
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+	WorkspaceHeader   = "X-AgentArea-Workspace"
+	InstanceHeader    = "X-AgentArea-Instance"
+)
+
+// propagateTrace keeps a valid W3C traceparent from the caller so the MCP
+// server joins the agent's trace. Requests without one start a new trace.
+func propagateTrace(h http.Header) {
+	if validTraceparent(h.Get(TraceparentHeader)) {
+		return
+	}
+
+	// tracestate is meaningless without the traceparent it belongs to
+	h.Del(TracestateHeader)
+	h.Set(TraceparentHeader, newTraceparent())
+}
+
+// setIdentityHeaders sets the workspace and instance headers. Values known to
+// the route take precedence over anything the caller sent.
+func setIdentityHeaders(h http.Header, workspaceID, instanceID string) {
+	if workspaceID != "" {
+		h.Set(WorkspaceHeader, workspaceID)
+	}
+	if instanceID != "" {
+		h.Set(InstanceHeader, instanceID)
+	}
+}
+
+// validTraceparent reports whether value is a version 00 traceparent
+// ("00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>") with non-zero ids
+func validTraceparent(value string) bool {
+	if len(value) != 55 || value[:3] != "00-" || value[35] != '-' || value[52] != '-' {
+		return false
+	}
+
+	traceID, parentID, flags := value[3:35], value[36:52], value[53:]
+	return isLowerHex(traceID) && isLowerHex(parentID) && isLowerHex(flags) &&
+		!allZero(traceID) && !allZero(parentID)
+}
+
+// newTraceparent returns a sampled traceparent with random ids
+func newTraceparent() string {
+	var ids [24]byte // 16 byte trace id + 8 byte parent id
+	if _, err := rand.Read(ids[:]); err != nil {
+		// crypto/rand does not fail on supported platforms; a zero id would be invalid
+		ids[0] = 1
+		ids[16] = 1
+	}
+
+	return "00-" + hex.EncodeToString(ids[:16]) + "-" + hex.EncodeToString(ids[16:]) + "-01"
+}
+
+// isLowerHex reports whether s only contains lowercase hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// allZero reports whether s only contains '0'
+func allZero(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '0' {
+			return false
+		}
+	}
+	return true
+}
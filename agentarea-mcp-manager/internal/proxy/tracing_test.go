@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyPropagatesTraceAndIdentity(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ps := newTestProxyServer()
+	host, port := serverHostPort(t, backend)
+	opts := RouteOptions{WorkspaceID: "ws-1", InstanceID: "inst-1"}
+	if err := ps.AddRouteWithOptions("traced", host, port, opts); err != nil {
+		t.Fatalf("AddRouteWithOptions failed: %v", err)
+	}
+
+	send := func(traceparent string) {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/traced/messages", nil)
+		if traceparent != "" {
+			req.Header.Set(TraceparentHeader, traceparent)
+			req.Header.Set(TracestateHeader, "vendor=1")
+		}
+		// Callers must not be able to impersonate another instance
+		req.Header.Set(InstanceHeader, "spoofed")
+		ps.handleRequest(httptest.NewRecorder(), req)
+	}
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	send(incoming)
+	if got.Get(TraceparentHeader) != incoming || got.Get(TracestateHeader) != "vendor=1" {
+		t.Errorf("Expected trace context to be propagated, got %q / %q", got.Get(TraceparentHeader), got.Get(TracestateHeader))
+	}
+	if got.Get(WorkspaceHeader) != "ws-1" || got.Get(InstanceHeader) != "inst-1" {
+		t.Errorf("Expected identity headers, got %q / %q", got.Get(WorkspaceHeader), got.Get(InstanceHeader))
+	}
+
+	send("00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	if tp := got.Get(TraceparentHeader); !validTraceparent(tp) || tp == incoming {
+		t.Errorf("Expected a new traceparent for invalid input, got %q", tp)
+	}
+	if got.Get(TracestateHeader) != "" {
+		t.Errorf("Expected tracestate to be dropped with a new trace, got %q", got.Get(TracestateHeader))
+	}
+}
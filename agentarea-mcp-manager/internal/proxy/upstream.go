@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RouteStats contains connection and request counters for a single route
+type RouteStats struct {
+	Slug        string          `json:"slug"`
+	Target      string          `json:"target"`
+	Strategy    string          `json:"strategy"`
+	Requests    uint64          `json:"requests"`
+	Errors      uint64          `json:"errors"`
+	ConnsNew    uint64          `json:"conns_new"`
+	ConnsReused uint64          `json:"conns_reused"`
+	LastRequest *time.Time      `json:"last_request_at,omitempty"`
+	Endpoints   []EndpointStats `json:"endpoints"`
+
+	// Requests above PROXY_SLOW_REQUEST_THRESHOLD or PROXY_LARGE_PAYLOAD_BYTES
+	SlowRequests  uint64 `json:"slow_requests"`
+	LargePayloads uint64 `json:"large_payloads"`
+}
+
+// routeCounters holds the live counters behind RouteStats
+type routeCounters struct {
+	requests    atomic.Uint64
+	errors      atomic.Uint64
+	connsNew    atomic.Uint64
+	connsReused atomic.Uint64
+	lastRequest atomic.Int64 // unix nanos
+
+	slowRequests  atomic.Uint64
+	largePayloads atomic.Uint64
+}
+
+// lastRequestTime returns when the route last received a request
+func (c *routeCounters) lastRequestTime() (time.Time, bool) {
+	nanos := c.lastRequest.Load()
+	if nanos == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// upstream is the cached transport and reverse proxies for a single route.
+// Keeping one per route lets keep-alive connections to the container be reused
+// across requests instead of dialing a new connection every time.
+type upstream struct {
+	slug      string
+	addr      string
+	route     *ProxyRoute
+	transport *http.Transport
+	backends  []*httputil.ReverseProxy // one per route endpoint, same order
+	counters  *routeCounters
+}
+
+// stats returns a snapshot of the upstream counters
+func (u *upstream) stats() RouteStats {
+	now := time.Now().UnixNano()
+	endpoints := make([]EndpointStats, 0, len(u.route.Endpoints))
+	for _, e := range u.route.Endpoints {
+		endpoints = append(endpoints, e.stats(now))
+	}
+
+	var lastRequest *time.Time
+	if t, ok := u.counters.lastRequestTime(); ok {
+		lastRequest = &t
+	}
+
+	return RouteStats{
+		Slug:        u.slug,
+		Target:      u.addr,
+		Strategy:    u.route.Strategy,
+		Requests:    u.counters.requests.Load(),
+		Errors:      u.counters.errors.Load(),
+		ConnsNew:    u.counters.connsNew.Load(),
+		ConnsReused: u.counters.connsReused.Load(),
+		LastRequest: lastRequest,
+		Endpoints:   endpoints,
+
+		SlowRequests:  u.counters.slowRequests.Load(),
+		LargePayloads: u.counters.largePayloads.Load(),
+	}
+}
+
+// close releases idle connections held by the upstream transport
+func (u *upstream) close() {
+	u.transport.CloseIdleConnections()
+}
+
+// sameEndpoints reports whether the upstream already targets the route's endpoints
+func (u *upstream) sameEndpoints(route *ProxyRoute) bool {
+	if len(u.route.Endpoints) != len(route.Endpoints) {
+		return false
+	}
+	for i, e := range u.route.Endpoints {
+		other := route.Endpoints[i]
+		// Addr includes the socket path, which the transport dials
+		if e.Addr() != other.Addr() || e.Scheme != other.Scheme || e.BasePath != other.BasePath {
+			return false
+		}
+	}
+	return true
+}
+
+// tracingTransport records whether each outgoing request reused a pooled connection
+type tracingTransport struct {
+	base  http.RoundTripper
+	trace *httptrace.ClientTrace
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), t.trace)))
+}
+
+// newTracingTransport wraps base so connection reuse is counted in counters
+func newTracingTransport(base http.RoundTripper, counters *routeCounters) *tracingTransport {
+	return &tracingTransport{
+		base: base,
+		trace: &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if info.Reused {
+					counters.connsReused.Add(1)
+				} else {
+					counters.connsNew.Add(1)
+				}
+			},
+		},
+	}
+}
+
+// newUpstream builds the reverse proxies for a route. An existing transport
+// is reused so pooled connections survive route updates.
+func (ps *ProxyServer) newUpstream(route *ProxyRoute, transport *http.Transport, counters *routeCounters) *upstream {
+	if counters == nil {
+		counters = &routeCounters{}
+	}
+	if transport == nil {
+		transport = ps.createTransport()
+		transport.DialContext = dialSockets(transport.DialContext, route.Endpoints)
+	}
+
+	u := &upstream{
+		slug:      route.Slug,
+		addr:      route.Addr(),
+		route:     route,
+		transport: transport,
+		backends:  make([]*httputil.ReverseProxy, len(route.Endpoints)),
+		counters:  counters,
+	}
+
+	tracing := newTracingTransport(transport, counters)
+	for i, endpoint := range route.Endpoints {
+		u.backends[i] = ps.newEndpointProxy(route, endpoint, tracing, counters)
+	}
+
+	return u
+}
+
+// newEndpointProxy builds the reverse proxy for one endpoint of a route and
+// wires its outcomes into passive health tracking
+func (ps *ProxyServer) newEndpointProxy(route *ProxyRoute, endpoint *Endpoint, transport http.RoundTripper, counters *routeCounters) *httputil.ReverseProxy {
+	slug := route.Slug
+	addr := endpoint.target()
+	scheme := endpoint.Scheme
+	basePath := endpoint.BasePath
+	stripAuth := route.Options.AccessToken != ""
+	injected := route.Options.Headers
+	workspaceID := route.Options.WorkspaceID
+	instanceID := route.Options.InstanceID
+	compression := ps.config.Compression
+	if route.Options.NoCompression {
+		compression = CompressionPolicy{}
+	}
+
+	// Omit default ports from the Host header so virtual-hosted upstreams match
+	hostHeader := addr
+	if endpoint.Socket != "" {
+		hostHeader = endpoint.Host
+	} else if (scheme == "http" && endpoint.Port == 80) || (scheme == "https" && endpoint.Port == 443) {
+		hostHeader = endpoint.Host
+		if strings.Contains(hostHeader, ":") {
+			// IPv6 literals keep their brackets without a port
+			hostHeader = "[" + hostHeader + "]"
+		}
+	}
+
+	director := func(req *http.Request) {
+		originalPath := req.URL.Path
+		originalHost := req.Host
+		clientIP := ps.getClientIP(req)
+
+		path := ps.stripMCPPrefix(originalPath, slug)
+		if basePath != "" {
+			if path == "/" {
+				path = basePath
+			} else {
+				path = basePath + path
+			}
+		}
+
+		req.URL.Scheme = scheme
+		req.URL.Host = addr
+		req.URL.Path = path
+		req.URL.RawPath = ""
+		req.RequestURI = ""
+		req.Host = hostHeader
+		req.Header.Set("X-Forwarded-For", clientIP)
+		req.Header.Set("X-Forwarded-Proto", "http")
+		req.Header.Set("X-Forwarded-Host", originalHost)
+		req.Header.Set("X-Forwarded-Path", originalPath)
+		propagateTrace(req.Header)
+		setIdentityHeaders(req.Header, workspaceID, instanceID)
+
+		// The proxy access token is for us, not the upstream
+		if stripAuth {
+			req.Header.Del("Authorization")
+		}
+		for name, value := range injected {
+			req.Header.Set(name, value)
+		}
+		if token := req.Header.Get(upstreamTokenHeader); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Del(upstreamTokenHeader)
+	}
+
+	return &httputil.ReverseProxy{
+		Director:  director,
+		Transport: transport,
+		ModifyResponse: func(resp *http.Response) error {
+			switch resp.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				ps.reportEndpointFailure(slug, endpoint)
+			default:
+				ps.config.Health.reportSuccess(endpoint)
+			}
+			compression.compress(resp)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			counters.errors.Add(1)
+			ps.reportEndpointFailure(slug, endpoint)
+			ps.logger.Error("Proxy error",
+				slog.String("slug", slug),
+				slog.String("target", r.URL.String()),
+				slog.String("error", err.Error()))
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		},
+	}
+}
+
+// reportEndpointFailure records a failed request and logs if the endpoint was ejected
+func (ps *ProxyServer) reportEndpointFailure(slug string, endpoint *Endpoint) {
+	if ps.config.Health.reportFailure(endpoint) {
+		ps.logger.Warn("Ejected unhealthy endpoint",
+			slog.String("slug", slug),
+			slog.String("endpoint", endpoint.Addr()),
+			slog.Duration("duration", ps.config.Health.EjectDuration))
+	}
+}
+
+// createTransport creates an HTTP transport using the configured pool settings
+func (ps *ProxyServer) createTransport() *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   ps.config.DialTimeout,
+			KeepAlive: ps.config.KeepAlive,
+		}).DialContext,
+		MaxIdleConns:        ps.config.MaxIdleConns,
+		MaxIdleConnsPerHost: ps.config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     ps.config.MaxConnsPerHost,
+		IdleConnTimeout:     ps.config.IdleConnTimeout,
+		DisableCompression:  false,
+	}
+}
@@ -0,0 +1,222 @@
+// Package redisconn builds Redis clients for a standalone server, a
+// Sentinel-managed master or a cluster, with TLS and ACL authentication, and
+// checks their connection health.
+package redisconn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/agentarea/mcp-manager/internal/config"
+)
+
+// Connection modes
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// Options describe how to connect to Redis
+type Options struct {
+	Mode      string
+	Universal redis.UniversalOptions
+}
+
+// FromURL connects to the single server of a redis:// or rediss:// URL, or a
+// bare host:port
+func FromURL(redisURL string) *Options {
+	base := parseURL(redisURL)
+	return &Options{
+		Mode: ModeStandalone,
+		Universal: redis.UniversalOptions{
+			Addrs:     []string{base.Addr},
+			DB:        base.DB,
+			Username:  base.Username,
+			Password:  base.Password,
+			TLSConfig: base.TLSConfig,
+		},
+	}
+}
+
+// Parse builds connection options from the configuration
+func Parse(cfg config.RedisConfig) (*Options, error) {
+	opts := FromURL(cfg.URL)
+	u := &opts.Universal
+	if cfg.Mode != "" {
+		opts.Mode = cfg.Mode
+	}
+	if len(cfg.Addrs) > 0 {
+		u.Addrs = cfg.Addrs
+	}
+	if cfg.Username != "" {
+		u.Username = cfg.Username
+	}
+	if cfg.Password != "" {
+		u.Password = cfg.Password
+	}
+
+	switch opts.Mode {
+	case ModeStandalone:
+		if len(u.Addrs) != 1 {
+			return nil, fmt.Errorf("standalone mode takes one address, got %d; use sentinel or cluster mode", len(u.Addrs))
+		}
+	case ModeSentinel:
+		if cfg.SentinelMaster == "" {
+			return nil, fmt.Errorf("sentinel mode requires REDIS_SENTINEL_MASTER")
+		}
+		u.MasterName = cfg.SentinelMaster
+		u.SentinelUsername = cfg.SentinelUsername
+		u.SentinelPassword = cfg.SentinelPassword
+	case ModeCluster:
+		if u.DB != 0 {
+			return nil, fmt.Errorf("cluster mode only supports database 0, got %d", u.DB)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Redis mode %q, use %s, %s or %s", opts.Mode, ModeStandalone, ModeSentinel, ModeCluster)
+	}
+
+	tlsConfig, err := buildTLS(cfg, u.TLSConfig, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	u.TLSConfig = tlsConfig
+	return opts, nil
+}
+
+// NewClient creates a client; each caller owns and closes its own
+func (o *Options) NewClient() redis.UniversalClient {
+	switch o.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(o.Universal.Failover())
+	case ModeCluster:
+		return redis.NewClusterClient(o.Universal.Cluster())
+	default:
+		return redis.NewClient(o.Universal.Simple())
+	}
+}
+
+// parseURL parses a Redis URL, falling back to treating it as an address
+func parseURL(redisURL string) *redis.Options {
+	if parsed, err := redis.ParseURL(redisURL); err == nil {
+		return parsed
+	}
+	if addr, found := strings.CutPrefix(redisURL, "redis://"); found {
+		return &redis.Options{Addr: addr}
+	}
+	return &redis.Options{Addr: redisURL}
+}
+
+// buildTLS returns the TLS configuration, or nil when TLS is not enabled by
+// REDIS_TLS, a rediss:// URL or any TLS file
+func buildTLS(cfg config.RedisConfig, fromURL *tls.Config, mode string) (*tls.Config, error) {
+	if !cfg.TLS && fromURL == nil && cfg.TLSCAFile == "" && cfg.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	// A rediss:// URL names its host; with several nodes each is verified
+	// against the address it is dialed on
+	if fromURL != nil && mode == ModeStandalone {
+		tlsConfig.ServerName = fromURL.ServerName
+	}
+	if cfg.TLSServerName != "" {
+		tlsConfig.ServerName = cfg.TLSServerName
+	}
+	tlsConfig.InsecureSkipVerify = cfg.TLSInsecureSkipVerify
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in Redis CA file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Health is the state of a Redis connection
+type Health struct {
+	Mode        string   `json:"mode"`
+	Addrs       []string `json:"addrs"`
+	TLS         bool     `json:"tls"`
+	Auth        bool     `json:"auth"`
+	Connected   bool     `json:"connected"`
+	LatencyMs   float64  `json:"latency_ms,omitempty"`
+	Master      string   `json:"master,omitempty"`       // Sentinel: current master address
+	Nodes       int      `json:"nodes,omitempty"`        // Cluster: master nodes
+	FailedNodes int      `json:"failed_nodes,omitempty"` // Cluster: master nodes not answering
+	Error       string   `json:"error,omitempty"`
+}
+
+// Check pings Redis through client and reports the connection state
+func (o *Options) Check(ctx context.Context, client redis.UniversalClient) Health {
+	health := Health{
+		Mode:  o.Mode,
+		Addrs: o.Universal.Addrs,
+		TLS:   o.Universal.TLSConfig != nil,
+		Auth:  o.Universal.Password != "",
+	}
+
+	start := time.Now()
+	if err := client.Ping(ctx).Err(); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.Connected = true
+	health.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	switch o.Mode {
+	case ModeSentinel:
+		health.Master = o.sentinelMaster(ctx)
+	case ModeCluster:
+		if cluster, ok := client.(*redis.ClusterClient); ok {
+			_ = cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+				health.Nodes++
+				if err := node.Ping(ctx).Err(); err != nil {
+					health.FailedNodes++
+					health.Error = fmt.Sprintf("%s: %v", node.Options().Addr, err)
+				}
+				return nil
+			})
+		}
+	}
+	return health
+}
+
+// sentinelMaster asks the sentinels for the current master address
+func (o *Options) sentinelMaster(ctx context.Context) string {
+	failover := o.Universal.Failover()
+	for _, addr := range o.Universal.Addrs {
+		sentinel := redis.NewSentinelClient(&redis.Options{
+			Addr:      addr,
+			Username:  failover.SentinelUsername,
+			Password:  failover.SentinelPassword,
+			TLSConfig: failover.TLSConfig,
+		})
+		master, err := sentinel.GetMasterAddrByName(ctx, o.Universal.MasterName).Result()
+		sentinel.Close()
+		if err == nil && len(master) == 2 {
+			return master[0] + ":" + master[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,230 @@
+// Package registry is a client for public MCP server registries (the
+// registry.modelcontextprotocol.io v0 API) used to discover servers and turn
+// them into local templates.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// ErrNotFound is returned when the registry does not know a server or version
+var ErrNotFound = errors.New("server not found in registry")
+
+// ErrNotImportable is returned for servers without a container image
+var ErrNotImportable = errors.New("server has no OCI image package")
+
+// ociRegistryType marks container image packages; npm or pypi packages
+// cannot be run by mcp-manager
+const ociRegistryType = "oci"
+
+// maxResponseSize bounds registry responses
+const maxResponseSize = 4 << 20
+
+// Server is the registry description of one MCP server version
+type Server struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Version     string     `json:"version"`
+	Repository  Repository `json:"repository"`
+	Packages    []Package  `json:"packages"`
+}
+
+// Repository is the source repository of a server
+type Repository struct {
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
+// Package is one way of installing a server
+type Package struct {
+	RegistryType         string        `json:"registryType"`
+	Identifier           string        `json:"identifier"`
+	Version              string        `json:"version"`
+	Transport            Transport     `json:"transport"`
+	EnvironmentVariables []Environment `json:"environmentVariables"`
+}
+
+// Transport is how clients talk to a packaged server
+type Transport struct {
+	Type string `json:"type"`
+}
+
+// Environment describes an environment variable a package reads
+type Environment struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsRequired  bool   `json:"isRequired"`
+	IsSecret    bool   `json:"isSecret"`
+	Default     string `json:"default"`
+}
+
+// serverEntry wraps a server with registry metadata in v0 responses
+type serverEntry struct {
+	Server Server `json:"server"`
+}
+
+// Client queries an MCP server registry
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a registry client
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// URL returns the registry base URL
+func (c *Client) URL() string {
+	return c.baseURL
+}
+
+// Search returns registry servers matching the query
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]Server, error) {
+	params := url.Values{}
+	if query != "" {
+		params.Set("search", query)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var response struct {
+		Servers []serverEntry `json:"servers"`
+	}
+	if err := c.get(ctx, "/v0/servers?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	servers := make([]Server, 0, len(response.Servers))
+	for _, entry := range response.Servers {
+		servers = append(servers, entry.Server)
+	}
+	return servers, nil
+}
+
+// Get returns one version of a server; an empty version means the latest
+func (c *Client) Get(ctx context.Context, name, version string) (*Server, error) {
+	if version == "" {
+		version = "latest"
+	}
+
+	var entry serverEntry
+	path := "/v0/servers/" + url.PathEscape(name) + "/versions/" + url.PathEscape(version)
+	if err := c.get(ctx, path, &entry); err != nil {
+		return nil, err
+	}
+	return &entry.Server, nil
+}
+
+// get fetches a registry path and decodes the JSON response
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("registry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	return nil
+}
+
+// ImagePackage returns the OCI package of a server, if it has one
+func (s *Server) ImagePackage() (*Package, bool) {
+	for i := range s.Packages {
+		if s.Packages[i].RegistryType == ociRegistryType {
+			return &s.Packages[i], true
+		}
+	}
+	return nil, false
+}
+
+// Image returns the image reference of an OCI package, pinned to the package
+// version when the identifier carries no tag or digest
+func (p *Package) Image() string {
+	image := p.Identifier
+	if strings.Contains(image, "@") || p.Version == "" {
+		return image
+	}
+	if strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		return image
+	}
+	return image + ":" + p.Version
+}
+
+var nonKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// TemplateKey derives a catalog key from a registry server name, e.g.
+// "io.github.acme/weather-server" becomes "weather-server"
+func TemplateKey(name string) string {
+	key := strings.ToLower(name[strings.LastIndex(name, "/")+1:])
+	return strings.Trim(nonKeyChars.ReplaceAllString(key, "-"), "-")
+}
+
+// ToTemplate converts a registry server into a local template, recording
+// the registry, name and version it was imported from
+func ToTemplate(server *Server, key, registryURL string, now time.Time) (models.MCPProviderTemplate, error) {
+	pkg, ok := server.ImagePackage()
+	if !ok {
+		return models.MCPProviderTemplate{}, fmt.Errorf("%w: %s", ErrNotImportable, server.Name)
+	}
+
+	envVars := make([]models.MCPEnvVar, 0, len(pkg.EnvironmentVariables))
+	for _, env := range pkg.EnvironmentVariables {
+		envVars = append(envVars, models.MCPEnvVar{
+			Name:        env.Name,
+			Description: env.Description,
+			Required:    env.IsRequired,
+			Default:     env.Default,
+			Secret:      env.IsSecret,
+		})
+	}
+
+	return models.MCPProviderTemplate{
+		ID:           key,
+		Name:         server.Name,
+		Description:  server.Description,
+		DockerImage:  pkg.Image(),
+		EnvVars:      envVars,
+		Capabilities: []string{},
+		Version:      server.Version,
+		Source: &models.TemplateSource{
+			Registry:   registryURL,
+			Name:       server.Name,
+			Version:    server.Version,
+			Repository: server.Repository.URL,
+			ImportedAt: now.UTC(),
+		},
+	}, nil
+}
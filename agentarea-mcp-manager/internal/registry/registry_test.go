@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const weatherServer = `{
+  "server": {
+    "name": "io.github.acme/weather-server",
+    "description": "Weather forecasts",
+    "version": "1.2.0",
+    "repository": {"url": "https://github.com/acme/weather-server", "source": "github"},
+    "packages": [
+      {"registryType": "npm", "identifier": "@acme/weather", "version": "1.2.0"},
+      {
+        "registryType": "oci",
+        "identifier": "docker.io/acme/weather",
+        "version": "1.2.0",
+        "transport": {"type": "streamable-http"},
+        "environmentVariables": [
+          {"name": "WEATHER_API_KEY", "description": "API key", "isRequired": true, "isSecret": true}
+        ]
+      }
+    ]
+  },
+  "_meta": {}
+}`
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/v0/servers":
+			if r.URL.Query().Get("search") != "weather" {
+				t.Errorf("unexpected search query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"servers": [` + weatherServer + `], "metadata": {"count": 1}}`))
+		case "/v0/servers/io.github.acme%2Fweather-server/versions/latest":
+			w.Write([]byte(weatherServer))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, time.Second)
+
+	servers, err := client.Search(context.Background(), "weather", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(servers) != 1 || servers[0].Name != "io.github.acme/weather-server" {
+		t.Fatalf("unexpected search results: %+v", servers)
+	}
+
+	server, err := client.Get(context.Background(), "io.github.acme/weather-server", "")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	template, err := ToTemplate(server, TemplateKey(server.Name), srv.URL, now)
+	if err != nil {
+		t.Fatalf("ToTemplate failed: %v", err)
+	}
+	if template.ID != "weather-server" || template.DockerImage != "docker.io/acme/weather:1.2.0" {
+		t.Errorf("unexpected template: %+v", template)
+	}
+	if len(template.EnvVars) != 1 || !template.EnvVars[0].Required || !template.EnvVars[0].Secret {
+		t.Errorf("unexpected env vars: %+v", template.EnvVars)
+	}
+	if template.Source == nil || template.Source.Version != "1.2.0" || !template.Source.ImportedAt.Equal(now) {
+		t.Errorf("unexpected provenance: %+v", template.Source)
+	}
+
+	if _, err := client.Get(context.Background(), "io.github.acme/missing", "1.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestToTemplateRequiresImage(t *testing.T) {
+	server := &Server{Name: "npm-only", Packages: []Package{{RegistryType: "npm", Identifier: "x"}}}
+	if _, err := ToTemplate(server, "npm-only", "", time.Now()); !errors.Is(err, ErrNotImportable) {
+		t.Errorf("expected ErrNotImportable, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Package schemas publishes the versioned JSON Schemas accepted by the manager
+// and validates documents against them.
+package schemas
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema names
+const (
+	JSONSpec     = "json-spec"     // json_spec of MCPServerInstanceCreated events
+	InstanceSpec = "instance-spec" // POST /instances request body
+	Providers    = "providers"     // MCP_PROVIDERS_YAML files
+)
+
+// CurrentVersion is the schema version used for validation
+const CurrentVersion = "v1"
+
+//go:embed v1/*.json
+var files embed.FS
+
+// Info describes a published schema
+type Info struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Latest  bool   `json:"latest"`
+}
+
+// registry holds the parsed schemas keyed by name and version
+var registry = mustLoad()
+
+type entry struct {
+	raw    []byte
+	schema *Schema
+}
+
+// mustLoad parses the embedded schemas. They are part of the binary, so a
+// malformed schema is a programming error.
+func mustLoad() map[string]map[string]entry {
+	loaded := make(map[string]map[string]entry)
+
+	versions, err := files.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+	for _, version := range versions {
+		docs, err := files.ReadDir(version.Name())
+		if err != nil {
+			panic(err)
+		}
+		for _, doc := range docs {
+			raw, err := files.ReadFile(version.Name() + "/" + doc.Name())
+			if err != nil {
+				panic(err)
+			}
+			var schema Schema
+			if err := json.Unmarshal(raw, &schema); err != nil {
+				panic(fmt.Sprintf("invalid schema %s/%s: %v", version.Name(), doc.Name(), err))
+			}
+
+			name := strings.TrimSuffix(doc.Name(), ".json")
+			if loaded[name] == nil {
+				loaded[name] = make(map[string]entry)
+			}
+			loaded[name][version.Name()] = entry{raw: raw, schema: &schema}
+		}
+	}
+
+	return loaded
+}
+
+// List returns all published schemas
+func List() []Info {
+	infos := make([]Info, 0, len(registry))
+	for name, versions := range registry {
+		for version := range versions {
+			infos = append(infos, Info{
+				Name:    name,
+				Version: version,
+				URL:     "/schemas/" + name + "/" + version,
+				Latest:  version == CurrentVersion,
+			})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].Version < infos[j].Version
+	})
+	return infos
+}
+
+// Raw returns the JSON document of a schema version
+func Raw(name, version string) ([]byte, bool) {
+	e, ok := registry[name][version]
+	return e.raw, ok
+}
+
+// Validate checks a document against the current version of the named schema
+// and returns one error per violation
+func Validate(name string, doc interface{}) []error {
+	e, ok := registry[name][CurrentVersion]
+	if !ok {
+		return []error{fmt.Errorf("unknown schema %s/%s", name, CurrentVersion)}
+	}
+
+	normalized, err := normalize(doc)
+	if err != nil {
+		return []error{err}
+	}
+	return e.schema.Validate(normalized)
+}
+
+// normalize converts a Go value into the generic form produced by
+// encoding/json, so maps built in code (e.g. with int ports) validate the
+// same way as decoded payloads
+func normalize(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,74 @@
+package schemas
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateJSONSpec(t *testing.T) {
+	valid := []map[string]interface{}{
+		{"image": "mcp/echo:latest", "port": 8000, "environment": map[string]interface{}{"MODE": "fast"}},
+		{"type": "docker", "image": "mcp/echo", "port": float64(8080), "cmd": []interface{}{"serve"}},
+		{"image": "mcp/echo"}, // The port comes from the image
+		{"type": "url", "endpoint": "https://mcp.example.com", "proxy": true},
+		{"type": "url", "endpoint": "https://mcp.example.com", "proxy": map[string]interface{}{"rate_limit": 5.0}},
+	}
+	for _, spec := range valid {
+		if errs := Validate(JSONSpec, spec); len(errs) > 0 {
+			t.Errorf("Expected %v to be valid, got %v", spec, errs)
+		}
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"port: must be <=":                       {"image": "mcp/echo", "port": 70000},
+		"image":                                  {"image": "", "port": 8000},
+		"environment.X":                          {"image": "mcp/echo", "port": 8000, "environment": map[string]interface{}{"X": 1}},
+		"endpoint":                               {"type": "url", "endpoint": "not a url"},
+		"dns_servers: must have at most 3 items": {"image": "mcp/echo", "dns_servers": []interface{}{"1.1.1.1", "8.8.8.8", "9.9.9.9", "1.0.0.1"}},
+		"slo.objective: must be < 1":             {"image": "mcp/echo", "slo": map[string]interface{}{"objective": 1.0, "window": "30d"}},
+		"slo.objective: must be > 0":             {"image": "mcp/echo", "slo": map[string]interface{}{"objective": 0.0, "window": "30d"}},
+	}
+	for want, spec := range invalid {
+		errs := Validate(JSONSpec, spec)
+		if len(errs) == 0 {
+			t.Errorf("Expected %v to be rejected", spec)
+			continue
+		}
+		if !strings.HasPrefix(errs[0].Error(), want) {
+			t.Errorf("Expected error for %s, got %v", want, errs)
+		}
+	}
+}
+
+func TestValidateProvidersReportsUnknownFields(t *testing.T) {
+	doc := map[string]interface{}{
+		"apiVersion": "mcp-manager/v1",
+		"providers": map[string]interface{}{
+			"github": map[string]interface{}{
+				"name":         "GitHub",
+				"docker_image": "mcp/github",
+				"env_vars":     []interface{}{map[string]interface{}{"name": "GITHUB_TOKEN", "requird": true}},
+			},
+		},
+	}
+	errs := Validate(Providers, doc)
+	if len(errs) != 1 || errs[0].Error() != "providers.github.env_vars[0].requird: unknown field, did you mean required?" {
+		t.Fatalf("Expected the misspelt field to be reported, got %v", errs)
+	}
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) || verr.Path != "providers.github.env_vars[0].requird" {
+		t.Errorf("Expected a ValidationError with the path, got %#v", errs[0])
+	}
+}
+
+func TestListAndRaw(t *testing.T) {
+	for _, name := range []string{JSONSpec, InstanceSpec, Providers} {
+		if _, ok := Raw(name, CurrentVersion); !ok {
+			t.Errorf("Expected schema %s/%s to be published", name, CurrentVersion)
+		}
+	}
+	if len(List()) < 2 {
+		t.Errorf("Expected at least two schemas, got %+v", List())
+	}
+}
@@ -0,0 +1,324 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema used by the published schemas: type,
+// properties, required, additionalProperties (a schema or false), items,
+// maxItems, enum, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// minLength, pattern, format "uri", anyOf and local $ref to $defs.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+
+	pattern *regexp.Regexp
+	deny    bool // The false schema, which no value matches
+}
+
+// UnmarshalJSON implements json.Unmarshaler and compiles the pattern
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{deny: true}
+		return nil
+	}
+
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	return nil
+}
+
+// Validate checks a document decoded by encoding/json against the schema
+func (s *Schema) Validate(doc interface{}) []error {
+	v := &validator{root: s}
+	v.validate(s, doc, "")
+	return v.errs
+}
+
+// ValidationError is a violation of a schema at a path in the document,
+// such as providers.github.env_vars[0].name
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return path + ": " + e.Message
+}
+
+// validator collects errors while walking a document
+type validator struct {
+	root *Schema
+	errs []error
+}
+
+// fail records a violation at path
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolve follows a local "#/$defs/<name>" reference
+func (v *validator) resolve(s *Schema) *Schema {
+	for s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/$defs/")
+		def, ok := v.root.Defs[name]
+		if !ok {
+			return &Schema{}
+		}
+		s = def
+	}
+	return s
+}
+
+func (v *validator) validate(s *Schema, doc interface{}, path string) {
+	s = v.resolve(s)
+
+	if s.deny {
+		v.fail(path, "is not allowed")
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		v.validateAnyOf(s.AnyOf, doc, path)
+	}
+
+	if s.Type != "" && !hasType(doc, s.Type) {
+		v.fail(path, "must be of type %s, got %s", s.Type, typeName(doc))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(doc, s.Enum) {
+		v.fail(path, "must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		v.validateObject(s, value, path)
+	case []interface{}:
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			v.fail(path, "must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case string:
+		if s.MinLength != nil && len(value) < *s.MinLength {
+			if *s.MinLength == 1 {
+				v.fail(path, "must not be empty")
+			} else {
+				v.fail(path, "must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			v.fail(path, "must match %s", s.Pattern)
+		}
+		if s.Format == "uri" {
+			if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+				v.fail(path, "must be an absolute URL")
+			}
+		}
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			v.fail(path, "must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			v.fail(path, "must be <= %v", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && value <= *s.ExclusiveMinimum {
+			v.fail(path, "must be > %v", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && value >= *s.ExclusiveMaximum {
+			v.fail(path, "must be < %v", *s.ExclusiveMaximum)
+		}
+	}
+}
+
+func (v *validator) validateObject(s *Schema, obj map[string]interface{}, path string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(join(path, name), "is required")
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if prop, ok := s.Properties[key]; ok {
+			v.validate(prop, obj[key], join(path, key))
+		} else if s.AdditionalProperties != nil && v.resolve(s.AdditionalProperties).deny {
+			if suggestion := closestProperty(s.Properties, key); suggestion != "" {
+				v.fail(join(path, key), "unknown field, did you mean %s?", suggestion)
+			} else {
+				v.fail(join(path, key), "unknown field")
+			}
+		} else if s.AdditionalProperties != nil {
+			v.validate(s.AdditionalProperties, obj[key], join(path, key))
+		}
+	}
+}
+
+// validateAnyOf passes if any branch matches. Otherwise it reports the errors
+// of the closest branch, which is usually the one the author intended.
+func (v *validator) validateAnyOf(branches []*Schema, doc interface{}, path string) {
+	var closest []error
+	for _, branch := range branches {
+		sub := &validator{root: v.root}
+		sub.validate(branch, doc, path)
+		if len(sub.errs) == 0 {
+			return
+		}
+		if closest == nil || len(sub.errs) < len(closest) {
+			closest = sub.errs
+		}
+	}
+	v.errs = append(v.errs, closest...)
+}
+
+// hasType reports whether a decoded JSON value has the given schema type
+func hasType(doc interface{}, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "number":
+		_, ok := doc.(float64)
+		return ok
+	case "integer":
+		f, ok := doc.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return doc == nil
+	}
+	return true
+}
+
+// typeName returns the JSON type name of a decoded value
+func typeName(doc interface{}) string {
+	switch doc.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", doc)
+}
+
+func inEnum(doc interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(doc, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprintf("%v", value)
+	}
+	return strings.Join(values, ", ")
+}
+
+// closestProperty returns the property a misspelt key most likely meant:
+// the nearest within two edits, or "" if there is none
+func closestProperty(properties map[string]*Schema, key string) string {
+	best, bestDistance := "", 3
+	for name := range properties {
+		if d := editDistance(key, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// join builds a dotted path to a property
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
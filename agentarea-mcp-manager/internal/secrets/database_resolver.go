@@ -2,31 +2,101 @@ package secrets
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/hmac"
-	"crypto/sha256"
-	"database/sql"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/agentarea/mcp-manager/internal/fernet"
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+// getSecretsStatement is prepared on every pooled connection. It returns the
+// workspace of the instance with each of its workspace's secrets among the
+// names, or a single row without a secret when none match, and no rows when
+// the instance is unknown.
+const (
+	getSecretsStatement = "get_encrypted_secrets"
+	getSecretsQuery     = `SELECT i.workspace_id, s.secret_name, s.encrypted_value
+		FROM mcp_server_instances i
+		LEFT JOIN encrypted_secrets s ON s.workspace_id = i.workspace_id AND s.secret_name = ANY($2)
+		WHERE i.id::text = $1`
 )
 
+// retryBackoff is the delay before the first retry, doubled for each next one
+const retryBackoff = 100 * time.Millisecond
+
+// poolSettings size the connection pool and bound its queries
+type poolSettings struct {
+	maxConns        int32
+	minConns        int32
+	maxConnLifetime time.Duration
+	maxConnIdleTime time.Duration
+	queryTimeout    time.Duration
+	retries         int
+}
+
+// loadPoolSettings reads the POSTGRES_POOL_* and POSTGRES_QUERY_* variables
+func loadPoolSettings() poolSettings {
+	return poolSettings{
+		maxConns:        int32(envInt("POSTGRES_POOL_MAX_CONNS", 4)),
+		minConns:        int32(envInt("POSTGRES_POOL_MIN_CONNS", 0)),
+		maxConnLifetime: envDuration("POSTGRES_POOL_MAX_CONN_LIFETIME", 30*time.Minute),
+		maxConnIdleTime: envDuration("POSTGRES_POOL_MAX_CONN_IDLE_TIME", 5*time.Minute),
+		queryTimeout:    envDuration("POSTGRES_QUERY_TIMEOUT", 5*time.Second),
+		retries:         envInt("POSTGRES_QUERY_RETRIES", 2),
+	}
+}
+
 // DatabaseSecretResolver retrieves secrets from PostgreSQL database
 type DatabaseSecretResolver struct {
-	db     *sql.DB
-	logger *slog.Logger
-	key    []byte // Fernet encryption key
+	pool     *pgxpool.Pool
+	settings poolSettings
+	logger   *slog.Logger
+	key      *fernet.Key
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastError   string
+	lastErrorAt time.Time
 }
 
 // NewDatabaseSecretResolver creates a resolver that reads from PostgreSQL
 func NewDatabaseSecretResolver(logger *slog.Logger) (*DatabaseSecretResolver, error) {
+	// Get encryption key from environment
+	encryptionKey := os.Getenv("SECRET_MANAGER_ENCRYPTION_KEY")
+	if encryptionKey == "" {
+		logger.Warn("SECRET_MANAGER_ENCRYPTION_KEY not set, secrets will not be decryptable")
+		return nil, errors.New("SECRET_MANAGER_ENCRYPTION_KEY is required for database secret manager")
+	}
+
+	// Fernet key is already 32 bytes base64url-encoded, decode it
+	key, err := fernet.ParseKey(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := connectPlatformDatabase(logger)
+	if err != nil {
+		return nil, err
+	}
+	dr.key = key
+	return dr, nil
+}
+
+// connectPlatformDatabase connects to the platform database. Without a key
+// the resolver can only tell which workspace owns an instance.
+func connectPlatformDatabase(logger *slog.Logger) (*DatabaseSecretResolver, error) {
 	// Get database connection string from environment
 	dbHost := os.Getenv("POSTGRES_HOST")
 	if dbHost == "" {
@@ -44,224 +114,347 @@ func NewDatabaseSecretResolver(logger *slog.Logger) (*DatabaseSecretResolver, er
 	if dbPassword == "" {
 		return nil, errors.New("POSTGRES_PASSWORD environment variable is required")
 	}
+	// Connection errors may echo the DSN
+	redact.AddSecret(dbPassword)
 	dbName := os.Getenv("POSTGRES_DB")
 	if dbName == "" {
 		dbName = "aiagents"
 	}
 
-	// Get encryption key from environment
-	encryptionKey := os.Getenv("SECRET_MANAGER_ENCRYPTION_KEY")
-	if encryptionKey == "" {
-		logger.Warn("SECRET_MANAGER_ENCRYPTION_KEY not set, secrets will not be decryptable")
-		return nil, errors.New("SECRET_MANAGER_ENCRYPTION_KEY is required for database secret manager")
-	}
-
-	// Fernet key is already 32 bytes base64url-encoded, decode it
-	keyBytes, err := base64.URLEncoding.DecodeString(encryptionKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
-	}
-
-	// Verify key length (Fernet requires exactly 32 bytes)
-	if len(keyBytes) != 32 {
-		return nil, fmt.Errorf("invalid Fernet key length: got %d bytes, expected 32", len(keyBytes))
+	sslMode := os.Getenv("POSTGRES_SSLMODE")
+	if sslMode == "" {
+		sslMode = "disable"
 	}
 
 	// Build connection string
 	connStr := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		dbUser, dbPassword, dbHost, dbPort, dbName,
+		"postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		dbUser, dbPassword, dbHost, dbPort, dbName, sslMode,
 	)
 
+	settings := loadPoolSettings()
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+	poolConfig.MaxConns = settings.maxConns
+	poolConfig.MinConns = settings.minConns
+	poolConfig.MaxConnLifetime = settings.maxConnLifetime
+	poolConfig.MaxConnIdleTime = settings.maxConnIdleTime
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Prepare(ctx, getSecretsStatement, getSecretsQuery)
+		return err
+	}
+
 	// Connect to database
-	db, err := sql.Open("pgx", connStr)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), settings.queryTimeout)
 	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	logger.Info("Initialized database secret resolver",
 		slog.String("db_host", dbHost),
-		slog.String("db_name", dbName))
+		slog.String("db_name", dbName),
+		slog.Int("max_conns", int(settings.maxConns)))
 
 	return &DatabaseSecretResolver{
-		db:     db,
-		logger: logger,
-		key:    keyBytes,
+		pool:        pool,
+		settings:    settings,
+		logger:      logger,
+		lastSuccess: time.Now(),
 	}, nil
 }
 
-// ResolveSecrets resolves all secrets for an MCP instance from database
-func (dr *DatabaseSecretResolver) ResolveSecrets(instanceID string, envVars map[string]string) (map[string]string, error) {
-	resolved := make(map[string]string)
+// ResolveSecrets resolves all secrets for an MCP instance from database with
+// a single query. The instance must be registered in the platform and owned
+// by workspaceID, when given; only its workspace's secrets are visible.
+func (dr *DatabaseSecretResolver) ResolveSecrets(workspaceID, instanceID string, envVars map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(envVars))
 
+	// Use the same secret key pattern as Python:
+	// mcp_instance_{instance_id}_{env_name}
+	refs := make(map[string]string) // Environment key -> full secret name
+	var names []string
 	for key, value := range envVars {
-		// Check if this is a secret reference or a plain value
-		if strings.HasPrefix(value, "secret_ref:") {
-			// This is a secret reference, resolve it from database
-			secretName := strings.TrimPrefix(value, "secret_ref:")
-			secretValue, err := dr.getSecretFromDatabase(instanceID, secretName)
-			if err != nil {
-				dr.logger.Error("Failed to resolve secret from database",
-					slog.String("instance_id", instanceID),
-					slog.String("secret_key", key),
-					slog.String("secret_name", secretName),
-					slog.String("error", err.Error()))
-				return nil, fmt.Errorf("failed to resolve secret %s: %w", key, err)
-			}
-			resolved[key] = secretValue
-		} else {
+		secretName, isRef := strings.CutPrefix(value, "secret_ref:")
+		if !isRef {
 			// This is a plain value, use as-is
 			resolved[key] = value
+			continue
+		}
+		fullSecretName := fmt.Sprintf("mcp_instance_%s_%s", instanceID, secretName)
+		if !slices.Contains(names, fullSecretName) {
+			names = append(names, fullSecretName)
+		}
+		refs[key] = fullSecretName
+	}
+	if len(refs) == 0 {
+		return resolved, nil
+	}
+
+	dr.logger.Debug("Retrieving secrets from database",
+		slog.String("instance_id", instanceID),
+		slog.Int("secrets", len(names)))
+
+	encrypted, owner, queryErr := dr.queryEncryptedSecrets(instanceID, names)
+	if queryErr == nil {
+		queryErr = checkOwner(workspaceID, instanceID, owner)
+	}
+	failures := make(map[string]error)
+	for key, fullSecretName := range refs {
+		encryptedValue, found := encrypted[fullSecretName]
+		switch {
+		case queryErr != nil:
+			failures[key] = queryErr
+		case !found:
+			failures[key] = fmt.Errorf("%w: %s", ErrSecretNotFound, fullSecretName)
+		default:
+			// Decrypt the secret using Fernet
+			decryptedValue, err := dr.key.Decrypt(encryptedValue)
+			if err != nil {
+				failures[key] = fmt.Errorf("failed to decrypt secret: %w", err)
+				continue
+			}
+			redact.AddSecret(string(decryptedValue))
+			resolved[key] = string(decryptedValue)
 		}
 	}
 
+	if len(failures) > 0 {
+		err := &ResolveError{InstanceID: instanceID, Failures: failures}
+		dr.logger.Error("Failed to resolve secrets from database",
+			slog.String("instance_id", instanceID),
+			slog.Any("secret_keys", err.Keys()),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
 	dr.logger.Debug("Resolved secrets for instance from database",
 		slog.String("instance_id", instanceID),
 		slog.Int("total_vars", len(envVars)),
-		slog.Int("resolved_secrets", len(resolved)))
+		slog.Int("resolved_secrets", len(refs)))
 
 	return resolved, nil
 }
 
-// getSecretFromDatabase retrieves and decrypts a secret from PostgreSQL
-func (dr *DatabaseSecretResolver) getSecretFromDatabase(instanceID, secretName string) (string, error) {
-	// Use the same secret key pattern as Python:
-	// mcp_instance_{instance_id}_{env_name}
-	fullSecretName := fmt.Sprintf("mcp_instance_%s_%s", instanceID, secretName)
+// WorkspaceOf returns the workspace owning an instance as registered in the
+// platform database, ErrAccessDenied when the instance is unknown
+func (dr *DatabaseSecretResolver) WorkspaceOf(instanceID string) (string, error) {
+	_, owner, err := dr.queryEncryptedSecrets(instanceID, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := checkOwner("", instanceID, owner); err != nil {
+		return "", err
+	}
+	return owner, nil
+}
 
-	dr.logger.Debug("Retrieving secret from database",
-		slog.String("instance_id", instanceID),
-		slog.String("secret_name", secretName),
-		slog.String("full_secret_name", fullSecretName))
+// queryEncryptedSecrets reads encrypted secrets of the instance's workspace
+// by full name, with the workspace owning the instance, retrying transient
+// errors. Missing names are absent from the result and the owner is "" for
+// unknown instances; a database failure is ErrUnavailable.
+func (dr *DatabaseSecretResolver) queryEncryptedSecrets(instanceID string, names []string) (map[string]string, string, error) {
+	var err error
+	for attempt := 0; attempt <= dr.settings.retries; attempt++ {
+		if attempt > 0 {
+			dr.logger.Warn("Retrying secret query after transient database error",
+				slog.Int("attempt", attempt),
+				slog.String("error", err.Error()))
+			time.Sleep(retryBackoff << (attempt - 1))
+		}
 
-	// Query database for the encrypted secret by full secret name
-	var encryptedValue string
-	query := `SELECT encrypted_value FROM encrypted_secrets WHERE secret_name = $1 LIMIT 1`
+		var encrypted map[string]string
+		var owner string
+		encrypted, owner, err = dr.querySecretRows(instanceID, names)
+		switch {
+		case err == nil:
+			dr.recordSuccess()
+			return encrypted, owner, nil
+		case !isTransient(err):
+			dr.recordFailure(err)
+			return nil, "", fmt.Errorf("%w: database query failed: %v", ErrUnavailable, err)
+		}
+	}
+	dr.recordFailure(err)
+	return nil, "", fmt.Errorf("%w: database query failed after %d attempts: %v", ErrUnavailable, dr.settings.retries+1, err)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// querySecretRows runs the prepared lookup once and returns the secrets with
+// the workspace owning the instance, "" when the instance is unknown
+func (dr *DatabaseSecretResolver) querySecretRows(instanceID string, names []string) (map[string]string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dr.settings.queryTimeout)
 	defer cancel()
 
-	err := dr.db.QueryRowContext(ctx, query, fullSecretName).Scan(&encryptedValue)
-	if err == sql.ErrNoRows {
-		return "", fmt.Errorf("secret not found: %s", fullSecretName)
-	}
+	rows, err := dr.pool.Query(ctx, getSecretsStatement, instanceID, names)
 	if err != nil {
-		return "", fmt.Errorf("database query failed: %w", err)
+		return nil, "", err
 	}
-
-	// Decrypt the secret using Fernet
-	decryptedValue, err := dr.fernetDecrypt(encryptedValue)
-	if err != nil {
-		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	defer rows.Close()
+
+	encrypted := make(map[string]string, len(names))
+	var owner string
+	for rows.Next() {
+		var name, value *string
+		if err := rows.Scan(&owner, &name, &value); err != nil {
+			return nil, "", err
+		}
+		if name != nil && value != nil {
+			encrypted[*name] = *value
+		}
 	}
-
-	dr.logger.Info("Successfully retrieved secret from database",
-		slog.String("instance_id", instanceID),
-		slog.String("secret_name", secretName))
-
-	return decryptedValue, nil
+	return encrypted, owner, rows.Err()
 }
 
-// fernetDecrypt decrypts a Fernet-encrypted string
-// Fernet format: Version (1 byte) | Timestamp (8 bytes) | IV (16 bytes) | Ciphertext | HMAC (32 bytes)
-func (dr *DatabaseSecretResolver) fernetDecrypt(encryptedData string) (string, error) {
-	// Decode base64
-	data, err := base64.URLEncoding.DecodeString(encryptedData)
-	if err != nil {
-		return "", fmt.Errorf("base64 decode failed: %w", err)
+// UpdateSecret encrypts a new value for an existing secret of the instance's
+// workspace
+func (dr *DatabaseSecretResolver) UpdateSecret(workspaceID, instanceID, envKey, ref, value string) error {
+	secretName, isRef := strings.CutPrefix(ref, "secret_ref:")
+	if !isRef {
+		return fmt.Errorf("%s is not a secret reference", envKey)
 	}
-
-	// Validate minimum length
-	if len(data) < 57 { // 1 + 8 + 16 + 32 = 57 minimum
-		return "", errors.New("encrypted data too short")
+	if dr.key == nil {
+		return fmt.Errorf("%w: no encryption key to store secrets with", ErrUnavailable)
 	}
-
-	// Parse Fernet token structure
-	version := data[0]
-	if version != 0x80 {
-		return "", fmt.Errorf("unsupported Fernet version: %x", version)
+	owner, err := dr.WorkspaceOf(instanceID)
+	if err != nil {
+		return err
 	}
-
-	// Extract components
-	// timestamp := data[1:9]   // Not used for decryption
-	iv := data[9:25]
-	ciphertext := data[25 : len(data)-32]
-	mac := data[len(data)-32:]
-
-	// Verify HMAC
-	signingKey := dr.deriveSigningKey()
-	h := hmac.New(sha256.New, signingKey)
-	h.Write(data[:len(data)-32])
-	expectedMAC := h.Sum(nil)
-	if !hmac.Equal(mac, expectedMAC) {
-		return "", errors.New("HMAC verification failed")
+	if err := checkOwner(workspaceID, instanceID, owner); err != nil {
+		return err
 	}
 
-	// Decrypt using AES-128-CBC
-	encryptionKey := dr.deriveEncryptionKey()
-	block, err := aes.NewCipher(encryptionKey)
+	encrypted, err := dr.key.Encrypt([]byte(value))
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return fmt.Errorf("failed to encrypt secret: %w", err)
 	}
+	fullSecretName := fmt.Sprintf("mcp_instance_%s_%s", instanceID, secretName)
 
-	mode := cipher.NewCBCDecrypter(block, iv)
-	plaintext := make([]byte, len(ciphertext))
-	mode.CryptBlocks(plaintext, ciphertext)
-
-	// Remove PKCS7 padding
-	plaintext, err = dr.removePKCS7Padding(plaintext)
+	ctx, cancel := context.WithTimeout(context.Background(), dr.settings.queryTimeout)
+	defer cancel()
+	tag, err := dr.pool.Exec(ctx,
+		`UPDATE encrypted_secrets SET encrypted_value = $3 WHERE workspace_id::text = $1 AND secret_name = $2`,
+		owner, fullSecretName, encrypted)
 	if err != nil {
-		return "", fmt.Errorf("failed to remove padding: %w", err)
+		dr.recordFailure(err)
+		return fmt.Errorf("%w: database update failed: %v", ErrUnavailable, err)
+	}
+	dr.recordSuccess()
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, fullSecretName)
 	}
+	return nil
+}
+
+// checkOwner denies secrets to instances the platform does not know, or that
+// belong to another workspace than the caller expects
+func checkOwner(workspaceID, instanceID, owner string) error {
+	switch {
+	case owner == "":
+		return fmt.Errorf("%w: instance %s is not registered", ErrAccessDenied, instanceID)
+	case workspaceID != "" && workspaceID != owner:
+		return fmt.Errorf("%w: instance %s does not belong to workspace %s", ErrAccessDenied, instanceID, workspaceID)
+	}
+	return nil
+}
 
-	return string(plaintext), nil
+// isTransient reports whether a failed query may succeed when retried: lost
+// or refused connections, timeouts, failovers and serialization conflicts
+func isTransient(err error) bool {
+	if pgconn.SafeToRetry(err) || pgconn.Timeout(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01", "53300", "57P01", "57P02", "57P03":
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, "08") // Connection exceptions
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr)
 }
 
-// deriveSigningKey derives the signing key from Fernet key
-func (dr *DatabaseSecretResolver) deriveSigningKey() []byte {
-	// Fernet uses the first 16 bytes for signing
-	return dr.key[:16]
+func (dr *DatabaseSecretResolver) recordSuccess() {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.lastSuccess = time.Now()
 }
 
-// deriveEncryptionKey derives the encryption key from Fernet key
-func (dr *DatabaseSecretResolver) deriveEncryptionKey() []byte {
-	// Fernet uses the last 16 bytes for encryption
-	return dr.key[16:]
+func (dr *DatabaseSecretResolver) recordFailure(err error) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.lastError = err.Error()
+	dr.lastErrorAt = time.Now()
 }
 
-// removePKCS7Padding removes PKCS7 padding from decrypted data
-func (dr *DatabaseSecretResolver) removePKCS7Padding(data []byte) ([]byte, error) {
-	if len(data) == 0 {
-		return nil, errors.New("empty data")
-	}
+// Health pings the database and reports the pool and the last query results
+func (dr *DatabaseSecretResolver) Health(ctx context.Context) Health {
+	health := Health{Backend: "database"}
 
-	paddingLen := int(data[len(data)-1])
-	if paddingLen > len(data) || paddingLen == 0 {
-		return nil, errors.New("invalid padding")
+	ctx, cancel := context.WithTimeout(ctx, dr.settings.queryTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := dr.pool.Ping(ctx); err != nil {
+		health.Error = err.Error()
+	} else {
+		health.Available = true
+		health.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
 	}
 
-	// Verify padding
-	for i := len(data) - paddingLen; i < len(data); i++ {
-		if data[i] != byte(paddingLen) {
-			return nil, errors.New("invalid padding bytes")
-		}
+	stat := dr.pool.Stat()
+	health.Pool = &PoolStats{
+		MaxConns:          stat.MaxConns(),
+		TotalConns:        stat.TotalConns(),
+		IdleConns:         stat.IdleConns(),
+		AcquiredConns:     stat.AcquiredConns(),
+		AcquireCount:      stat.AcquireCount(),
+		EmptyAcquireCount: stat.EmptyAcquireCount(),
 	}
 
-	return data[:len(data)-paddingLen], nil
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if !dr.lastSuccess.IsZero() {
+		lastSuccess := dr.lastSuccess
+		health.LastSuccess = &lastSuccess
+	}
+	if dr.lastError != "" {
+		lastErrorAt := dr.lastErrorAt
+		health.LastError = dr.lastError
+		health.LastErrorAt = &lastErrorAt
+	}
+	return health
 }
 
-// Close closes the database connection
+// Close closes the connection pool
 func (dr *DatabaseSecretResolver) Close() error {
 	dr.logger.Info("Closing database secret resolver")
-	if dr.db != nil {
-		return dr.db.Close()
+	if dr.pool != nil {
+		dr.pool.Close()
 	}
 	return nil
 }
+
+func envInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
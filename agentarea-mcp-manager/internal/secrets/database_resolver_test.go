@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestIsTransient(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "08006"}, true},  // connection failure
+		{&pgconn.PgError{Code: "57P01"}, true},  // admin shutdown
+		{&pgconn.PgError{Code: "40001"}, true},  // serialization failure
+		{&pgconn.PgError{Code: "42P01"}, false}, // undefined table
+		{&pgconn.PgError{Code: "28P01"}, false}, // bad password
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), true},
+		{errors.New("decrypt failed"), false},
+	} {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestResolveErrorReportsEachKey(t *testing.T) {
+	err := &ResolveError{InstanceID: "i-1", Failures: map[string]error{
+		"B": fmt.Errorf("%w: mcp_instance_i-1_B", ErrSecretNotFound),
+		"A": errors.New("failed to decrypt secret"),
+	}}
+	want := "failed to resolve 2 secret(s) for instance i-1: A: failed to decrypt secret; B: secret not found: mcp_instance_i-1_B"
+	if err.Error() != want {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errors.Is(err, ErrSecretNotFound) || errors.Is(err, ErrUnavailable) {
+		t.Error("errors.Is does not see the per-key errors")
+	}
+}
+
+func TestResolveSecretsWithoutReferences(t *testing.T) {
+	dr := &DatabaseSecretResolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	resolved, err := dr.ResolveSecrets("ws-1", "i-1", map[string]string{"MODE": "plain"})
+	if err != nil || resolved["MODE"] != "plain" {
+		t.Errorf("plain values must not touch the database: %v %v", resolved, err)
+	}
+}
+
+func TestDatabaseDownIsUnavailable(t *testing.T) {
+	poolConfig, err := pgxpool.ParseConfig("postgres://user:pw@127.0.0.1:1/db?sslmode=disable&connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr := &DatabaseSecretResolver{
+		pool:     pool,
+		settings: poolSettings{queryTimeout: 2 * time.Second, retries: 1},
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	defer dr.Close()
+
+	_, err = dr.ResolveSecrets("ws-1", "i-1", map[string]string{"TOKEN": "secret_ref:TOKEN", "KEY": "secret_ref:KEY", "MODE": "plain"})
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) || !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("err = %v, want a ResolveError wrapping ErrUnavailable", err)
+	}
+	if keys := resolveErr.Keys(); len(keys) != 2 || keys[0] != "KEY" || keys[1] != "TOKEN" {
+		t.Errorf("failed keys = %v, want [KEY TOKEN]", keys)
+	}
+
+	health := dr.Health(context.Background())
+	if health.Available || health.Error == "" || health.LastError == "" || health.LastErrorAt == nil {
+		t.Errorf("unexpected health with the database down: %+v", health)
+	}
+	if health.Pool == nil {
+		t.Error("pool statistics missing")
+	}
+}
+
+func TestCheckOwner(t *testing.T) {
+	if err := checkOwner("ws-1", "i-1", "ws-1"); err != nil {
+		t.Errorf("owner workspace denied: %v", err)
+	}
+	if err := checkOwner("", "i-1", "ws-1"); err != nil {
+		t.Errorf("registered instance without a claimed workspace denied: %v", err)
+	}
+	for _, tt := range []struct{ workspace, owner string }{
+		{"ws-1", "ws-2"}, // Instance of another workspace
+		{"ws-1", ""},     // Instance unknown to the platform
+	} {
+		if err := checkOwner(tt.workspace, "i-1", tt.owner); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("checkOwner(%q, owner %q) = %v, want ErrAccessDenied", tt.workspace, tt.owner, err)
+		}
+	}
+}
@@ -1,11 +1,110 @@
 package secrets
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
 // SecretResolver is an interface for resolving secrets from different backends
 type SecretResolver interface {
 	// ResolveSecrets resolves all environment variables for an MCP instance
-	// replacing secret references (secret_ref:xxx) with actual secret values
-	ResolveSecrets(instanceID string, envVars map[string]string) (map[string]string, error)
+	// replacing secret references (secret_ref:xxx) with actual secret values.
+	// Only secrets of the instance's workspace are returned; workspaceID is
+	// the workspace the caller believes owns the instance.
+	ResolveSecrets(workspaceID, instanceID string, envVars map[string]string) (map[string]string, error)
+
+	// WorkspaceOf returns the workspace owning an instance according to the
+	// platform database. json_spec.workspace_id is set by whoever wrote the
+	// spec and must not be trusted for access decisions.
+	WorkspaceOf(instanceID string) (string, error)
 
 	// Close cleans up any resources used by the resolver
 	Close() error
 }
+
+// SecretWriter is implemented by resolvers that can store a new value behind
+// a secret reference, such as a rotated OAuth refresh token
+type SecretWriter interface {
+	// UpdateSecret replaces the secret that ResolveSecrets would resolve for
+	// the environment key envKey set to ref. The instance must belong to
+	// workspaceID, as for ResolveSecrets.
+	UpdateSecret(workspaceID, instanceID, envKey, ref, value string) error
+}
+
+// ErrSecretNotFound is returned when a referenced secret does not exist
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrUnavailable is returned when the secret backend cannot be reached or
+// fails, as opposed to a missing secret
+var ErrUnavailable = errors.New("secret backend unavailable")
+
+// ResolveError reports every secret reference of an instance that could not
+// be resolved, by environment key. Secrets are resolved in one batch, so a
+// single failure fails the whole call.
+type ResolveError struct {
+	InstanceID string
+	Failures   map[string]error
+}
+
+// Keys returns the environment keys that failed, sorted
+func (e *ResolveError) Keys() []string {
+	keys := make([]string, 0, len(e.Failures))
+	for key := range e.Failures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (e *ResolveError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, key := range e.Keys() {
+		parts = append(parts, fmt.Sprintf("%s: %v", key, e.Failures[key]))
+	}
+	return fmt.Sprintf("failed to resolve %d secret(s) for instance %s: %s", len(parts), e.InstanceID, strings.Join(parts, "; "))
+}
+
+// Unwrap lets errors.Is match ErrSecretNotFound or ErrUnavailable of any key
+func (e *ResolveError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, key := range e.Keys() {
+		errs = append(errs, e.Failures[key])
+	}
+	return errs
+}
+
+// ErrAccessDenied is returned when an instance is not known to belong to the
+// workspace it asks secrets for
+var ErrAccessDenied = errors.New("secret access denied")
+
+// HealthChecker is implemented by resolvers that can report whether their
+// backend is able to serve secrets
+type HealthChecker interface {
+	Health(ctx context.Context) Health
+}
+
+// Health reports the state of a secret backend
+type Health struct {
+	Backend     string     `json:"backend"`
+	Available   bool       `json:"available"`
+	Error       string     `json:"error,omitempty"`
+	LatencyMs   float64    `json:"latency_ms,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"` // Last query that reached the backend
+	LastError   string     `json:"last_error,omitempty"`   // Last query that failed to reach it
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	Pool        *PoolStats `json:"pool,omitempty"`
+}
+
+// PoolStats describes a database connection pool
+type PoolStats struct {
+	MaxConns          int32 `json:"max_conns"`
+	TotalConns        int32 `json:"total_conns"`
+	IdleConns         int32 `json:"idle_conns"`
+	AcquiredConns     int32 `json:"acquired_conns"`
+	AcquireCount      int64 `json:"acquire_count"`
+	EmptyAcquireCount int64 `json:"empty_acquire_count"` // Acquires that had to wait for a connection
+}
@@ -3,12 +3,17 @@ package secrets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"strings"
 
 	infisical "github.com/infisical/go-sdk"
+	infisicalerrors "github.com/infisical/go-sdk/packages/errors"
+
+	"github.com/agentarea/mcp-manager/internal/redact"
 )
 
 // InfisicalConfig represents the bootstrap configuration structure
@@ -50,6 +55,13 @@ type InfisicalSecretResolver struct {
 	logger      *slog.Logger
 	projectID   string
 	environment string
+
+	// owners looks up the workspace of instances in the platform database;
+	// without it no secret can be resolved
+	owners *DatabaseSecretResolver
+	// legacyRoot also reads secrets missing from the workspace folder at the
+	// project root, where they were stored before workspace folders
+	legacyRoot bool
 }
 
 // NewSecretResolver creates the appropriate secret resolver based on SECRET_MANAGER_TYPE
@@ -97,6 +109,7 @@ func newInfisicalSecretResolver(logger *slog.Logger) (*InfisicalSecretResolver,
 			logger:      logger,
 			projectID:   "default",
 			environment: "dev",
+			owners:      connectOwners(logger),
 		}, nil
 	}
 
@@ -109,6 +122,7 @@ func newInfisicalSecretResolver(logger *slog.Logger) (*InfisicalSecretResolver,
 			logger:      logger,
 			projectID:   "default",
 			environment: "dev",
+			owners:      connectOwners(logger),
 		}, nil
 	}
 
@@ -137,89 +151,225 @@ func newInfisicalSecretResolver(logger *slog.Logger) (*InfisicalSecretResolver,
 		slog.String("environment", environment),
 		slog.String("organization", config.Organization.Name))
 
+	legacyRoot := os.Getenv("INFISICAL_LEGACY_ROOT_FALLBACK") != "false"
+	if legacyRoot {
+		logger.Info("Secrets missing from workspace folders are read from the project root until migrated")
+	}
+
 	return &InfisicalSecretResolver{
 		client:      client,
 		logger:      logger,
 		projectID:   projectID,
 		environment: environment,
+		owners:      connectOwners(logger),
+		legacyRoot:  legacyRoot,
 	}, nil
 }
 
-// ResolveSecrets resolves all secrets for an MCP instance
-func (sr *InfisicalSecretResolver) ResolveSecrets(instanceID string, envVars map[string]string) (map[string]string, error) {
-	resolved := make(map[string]string)
+// connectOwners connects to the platform database to look up which workspace
+// owns an instance. Without it every secret is denied.
+func connectOwners(logger *slog.Logger) *DatabaseSecretResolver {
+	owners, err := connectPlatformDatabase(logger)
+	if err != nil {
+		logger.Error("Cannot verify instance workspaces, Infisical secrets will be denied",
+			slog.String("error", err.Error()))
+		return nil
+	}
+	return owners
+}
+
+// ResolveSecrets resolves all secrets for an MCP instance, retrieving only
+// the referenced keys from Infisical. Secrets are read from the folder of the
+// workspace owning the instance in the platform database, so an unregistered
+// instance or one claimed by another workspace cannot resolve any.
+func (sr *InfisicalSecretResolver) ResolveSecrets(workspaceID, instanceID string, envVars map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(envVars))
 
+	// Use the same secret key pattern as MCPEnvironmentService in Python:
+	// mcp_instance_{instance_id}_{env_name}
+	refs := make(map[string]string) // Environment key -> Infisical key
 	for key, value := range envVars {
 		// Check if this is a secret reference or a plain value
 		if strings.HasPrefix(value, "secret_ref:") {
-			// This is a secret reference, resolve it from Infisical
-			secretValue, err := sr.resolveSecretFromInfisical(instanceID, key)
-			if err != nil {
-				sr.logger.Error("Failed to resolve secret from Infisical",
-					slog.String("instance_id", instanceID),
-					slog.String("secret_key", key),
-					slog.String("error", err.Error()))
-				return nil, fmt.Errorf("failed to resolve secret %s: %w", key, err)
-			}
-			resolved[key] = secretValue
+			refs[key] = fmt.Sprintf("mcp_instance_%s_%s", instanceID, key)
 		} else {
 			// This is a plain value, use as-is
 			resolved[key] = value
 		}
 	}
+	if len(refs) == 0 {
+		return resolved, nil
+	}
+
+	secretPath, pathErr := sr.instanceSecretPath(workspaceID, instanceID)
+	failures := make(map[string]error)
+	for key, infisicalSecretKey := range refs {
+		if pathErr != nil {
+			failures[key] = pathErr
+			continue
+		}
+		secretValue, err := sr.retrieveSecret(instanceID, secretPath, infisicalSecretKey)
+		if err != nil {
+			failures[key] = err
+			continue
+		}
+		redact.AddSecret(secretValue)
+		resolved[key] = secretValue
+	}
+
+	if len(failures) > 0 {
+		err := &ResolveError{InstanceID: instanceID, Failures: failures}
+		sr.logger.Error("Failed to resolve secrets from Infisical",
+			slog.String("instance_id", instanceID),
+			slog.Any("secret_keys", err.Keys()),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
 
 	sr.logger.Debug("Resolved secrets for instance",
 		slog.String("instance_id", instanceID),
 		slog.Int("total_vars", len(envVars)),
-		slog.Int("resolved_secrets", len(resolved)))
+		slog.Int("resolved_secrets", len(refs)))
 
 	return resolved, nil
 }
 
-// resolveSecretFromInfisical retrieves a secret from Infisical using the same pattern as Python service
-func (sr *InfisicalSecretResolver) resolveSecretFromInfisical(instanceID, secretKey string) (string, error) {
-	// Use the same secret key pattern as MCPEnvironmentService in Python:
-	// mcp_instance_{instance_id}_{env_name}
-	infisicalSecretKey := fmt.Sprintf("mcp_instance_%s_%s", instanceID, secretKey)
+// WorkspaceOf returns the workspace owning an instance in the platform database
+func (sr *InfisicalSecretResolver) WorkspaceOf(instanceID string) (string, error) {
+	if sr.owners == nil {
+		return "", fmt.Errorf("%w: instance workspaces cannot be verified without the platform database", ErrAccessDenied)
+	}
+	return sr.owners.WorkspaceOf(instanceID)
+}
+
+// instanceSecretPath returns the folder of the workspace owning the instance,
+// denying callers that expect another workspace
+func (sr *InfisicalSecretResolver) instanceSecretPath(workspaceID, instanceID string) (string, error) {
+	owner, err := sr.WorkspaceOf(instanceID)
+	if err != nil {
+		return "", err
+	}
+	if err := checkOwner(workspaceID, instanceID, owner); err != nil {
+		return "", err
+	}
+	return workspaceSecretPath(owner)
+}
 
-	sr.logger.Debug("Retrieving secret from Infisical",
+// retrieveSecret reads a secret from the workspace folder. While the legacy
+// fallback is on, a secret missing there is read from the project root; keys
+// embed the instance ID, so only the instance's own secrets can be found.
+func (sr *InfisicalSecretResolver) retrieveSecret(instanceID, secretPath, secretKey string) (string, error) {
+	value, err := sr.getSecret(secretPath, secretKey)
+	if !sr.legacyRoot || !errors.Is(err, ErrSecretNotFound) {
+		return value, err
+	}
+
+	value, legacyErr := sr.getSecret(legacySecretPath, secretKey)
+	switch {
+	case errors.Is(legacyErr, ErrSecretNotFound):
+		return "", err
+	case legacyErr != nil:
+		return "", legacyErr
+	}
+	sr.logger.Warn("Secret read from the legacy project root, move it to the workspace folder",
 		slog.String("instance_id", instanceID),
 		slog.String("secret_key", secretKey),
-		slog.String("infisical_key", infisicalSecretKey))
+		slog.String("secret_path", secretPath))
+	return value, nil
+}
 
+// getSecret retrieves a single secret of a folder of the project environment
+func (sr *InfisicalSecretResolver) getSecret(secretPath, secretKey string) (string, error) {
 	// If client is not initialized (fallback mode), return error
 	if sr.client == nil {
-		return "", fmt.Errorf("Infisical client not initialized - secret resolution not available for: %s", infisicalSecretKey)
+		return "", fmt.Errorf("%w: Infisical client not initialized", ErrUnavailable)
 	}
 
-	// Retrieve secret from Infisical
 	secret, err := sr.client.Secrets().Retrieve(infisical.RetrieveSecretOptions{
-		SecretKey:   infisicalSecretKey,
+		SecretKey:   secretKey,
 		ProjectID:   sr.projectID,
 		Environment: sr.environment,
-		SecretPath:  "/", // Default path
+		SecretPath:  secretPath,
 	})
-
+	var apiErr *infisicalerrors.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s%s", ErrSecretNotFound, strings.TrimSuffix(secretPath, "/")+"/", secretKey)
+	}
 	if err != nil {
 		sr.logger.Error("Failed to retrieve secret from Infisical",
-			slog.String("infisical_key", infisicalSecretKey),
 			slog.String("project_id", sr.projectID),
 			slog.String("environment", sr.environment),
+			slog.String("secret_path", secretPath),
+			slog.String("secret_key", secretKey),
 			slog.String("error", err.Error()))
-		return "", fmt.Errorf("failed to retrieve secret from Infisical: %w", err)
+		return "", fmt.Errorf("%w: failed to retrieve secret from Infisical: %v", ErrUnavailable, err)
 	}
+	return secret.SecretValue, nil
+}
 
-	sr.logger.Info("Successfully retrieved secret from Infisical",
-		slog.String("instance_id", instanceID),
-		slog.String("secret_key", secretKey),
-		slog.String("infisical_key", infisicalSecretKey))
+// UpdateSecret stores a new value for a secret of the instance in its
+// workspace folder, creating it there when it still lives at the legacy root
+func (sr *InfisicalSecretResolver) UpdateSecret(workspaceID, instanceID, envKey, ref, value string) error {
+	if !strings.HasPrefix(ref, "secret_ref:") {
+		return fmt.Errorf("%s is not a secret reference", envKey)
+	}
+	secretPath, err := sr.instanceSecretPath(workspaceID, instanceID)
+	if err != nil {
+		return err
+	}
+	if sr.client == nil {
+		return fmt.Errorf("%w: Infisical client not initialized", ErrUnavailable)
+	}
 
-	return secret.SecretValue, nil
+	secretKey := fmt.Sprintf("mcp_instance_%s_%s", instanceID, envKey)
+	_, err = sr.client.Secrets().Update(infisical.UpdateSecretOptions{
+		SecretKey:      secretKey,
+		ProjectID:      sr.projectID,
+		Environment:    sr.environment,
+		SecretPath:     secretPath,
+		NewSecretValue: value,
+	})
+	var apiErr *infisicalerrors.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		_, err = sr.client.Secrets().Create(infisical.CreateSecretOptions{
+			SecretKey:   secretKey,
+			ProjectID:   sr.projectID,
+			Environment: sr.environment,
+			SecretPath:  secretPath,
+			SecretValue: value,
+		})
+	}
+	if err != nil {
+		sr.logger.Error("Failed to update secret in Infisical",
+			slog.String("secret_path", secretPath),
+			slog.String("secret_key", secretKey),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: failed to update secret in Infisical: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// legacySecretPath is where secrets were stored before workspace folders
+const legacySecretPath = "/"
+
+// workspaceSecretPath returns the Infisical folder holding a workspace's
+// secrets, /workspaces/{workspace_id}
+func workspaceSecretPath(workspaceID string) (string, error) {
+	if workspaceID == "" {
+		return "", fmt.Errorf("%w: instance has no workspace", ErrAccessDenied)
+	}
+	if strings.ContainsAny(workspaceID, "/\\") || workspaceID == "." || workspaceID == ".." {
+		return "", fmt.Errorf("%w: invalid workspace id %q", ErrAccessDenied, workspaceID)
+	}
+	return "/workspaces/" + workspaceID, nil
 }
 
 // Close closes the secret resolver
 func (sr *InfisicalSecretResolver) Close() error {
 	sr.logger.Info("Closing Infisical secret resolver")
 	// TODO: Close Infisical client if needed
+	if sr.owners != nil {
+		return sr.owners.Close()
+	}
 	return nil
 }
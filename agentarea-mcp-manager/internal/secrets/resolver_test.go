@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	infisical "github.com/infisical/go-sdk"
+	infisicalerrors "github.com/infisical/go-sdk/packages/errors"
+	"github.com/infisical/go-sdk/packages/models"
+)
+
+func TestWorkspaceSecretPath(t *testing.T) {
+	if path, err := workspaceSecretPath("ws-1"); err != nil || path != "/workspaces/ws-1" {
+		t.Errorf("workspaceSecretPath(ws-1) = %q, %v", path, err)
+	}
+	for _, workspaceID := range []string{"", "..", "ws-1/../ws-2", `ws\2`} {
+		if _, err := workspaceSecretPath(workspaceID); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("workspaceSecretPath(%q) = %v, want ErrAccessDenied", workspaceID, err)
+		}
+	}
+}
+
+func TestInfisicalRequiresWorkspace(t *testing.T) {
+	sr := &InfisicalSecretResolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	_, err := sr.ResolveSecrets("", "i-1", map[string]string{"TOKEN": "secret_ref:TOKEN"})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("err = %v, want ErrAccessDenied", err)
+	}
+}
+
+// fakeInfisical serves Retrieve from secrets keyed by path and key
+type fakeInfisical struct {
+	infisical.InfisicalClientInterface
+	infisical.SecretsInterface
+	secrets   map[string]string // path + "|" + key -> value
+	retrieved []string
+}
+
+func (f *fakeInfisical) Secrets() infisical.SecretsInterface { return f }
+
+func (f *fakeInfisical) Retrieve(options infisical.RetrieveSecretOptions) (models.Secret, error) {
+	f.retrieved = append(f.retrieved, options.SecretPath+"|"+options.SecretKey)
+	value, ok := f.secrets[options.SecretPath+"|"+options.SecretKey]
+	if !ok {
+		return models.Secret{}, &infisicalerrors.APIError{StatusCode: http.StatusNotFound}
+	}
+	return models.Secret{SecretKey: options.SecretKey, SecretValue: value}, nil
+}
+
+func TestInfisicalRetrievesReferencedKeys(t *testing.T) {
+	client := &fakeInfisical{secrets: map[string]string{
+		"/workspaces/ws-1|mcp_instance_i-1_TOKEN": "workspace-token",
+		"/|mcp_instance_i-1_LEGACY":               "legacy-token",
+		"/workspaces/ws-1|mcp_instance_i-2_OTHER": "unreferenced",
+	}}
+	sr := &InfisicalSecretResolver{client: client, logger: slog.New(slog.NewTextHandler(io.Discard, nil)), legacyRoot: true}
+
+	if value, err := sr.retrieveSecret("i-1", "/workspaces/ws-1", "mcp_instance_i-1_TOKEN"); err != nil || value != "workspace-token" {
+		t.Errorf("workspace secret = %q, %v", value, err)
+	}
+	if value, err := sr.retrieveSecret("i-1", "/workspaces/ws-1", "mcp_instance_i-1_LEGACY"); err != nil || value != "legacy-token" {
+		t.Errorf("legacy secret = %q, %v", value, err)
+	}
+	if _, err := sr.retrieveSecret("i-1", "/workspaces/ws-1", "mcp_instance_i-1_MISSING"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("missing secret: err = %v, want ErrSecretNotFound", err)
+	}
+	for _, retrieved := range client.retrieved {
+		if strings.Contains(retrieved, "i-2") {
+			t.Errorf("unreferenced secret retrieved: %s", retrieved)
+		}
+	}
+
+	sr.legacyRoot = false
+	if _, err := sr.retrieveSecret("i-1", "/workspaces/ws-1", "mcp_instance_i-1_LEGACY"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("legacy secret without fallback: err = %v, want ErrSecretNotFound", err)
+	}
+}
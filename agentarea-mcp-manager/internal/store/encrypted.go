@@ -0,0 +1,397 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/agentarea/mcp-manager/internal/fernet"
+	"github.com/agentarea/mcp-manager/internal/redact"
+)
+
+// encryptedPrefix marks spec values stored as Fernet tokens. Sensitive values
+// are always encrypted on write, so a value that merely looks like a token
+// is encrypted too and never stored as plaintext.
+const encryptedPrefix = "fernet:"
+
+// Encrypted wraps a Store so sensitive values in instance, container and
+// desired state specs are encrypted at rest: every value under "environment"
+// and every value whose key looks like a credential (token, secret,
+// password, ...).
+// Other fields stay readable so records can still be inspected and queried.
+// Records that cannot be decrypted are left out of listings and logged.
+type Encrypted struct {
+	Store
+	keys   *fernet.Keyring
+	logger *slog.Logger
+}
+
+// NewEncrypted wraps inner; values are encrypted with the primary key of
+// keys and decrypted with any of them
+func NewEncrypted(inner Store, keys *fernet.Keyring, logger *slog.Logger) *Encrypted {
+	return &Encrypted{Store: inner, keys: keys, logger: logger}
+}
+
+// IsEncrypted reports whether containers encrypts sensitive spec values at
+// rest
+func IsEncrypted(containers ContainerStore) bool {
+	_, ok := containers.(encryptedContainers)
+	return ok
+}
+
+// Instances returns the instance store
+func (e *Encrypted) Instances() InstanceStore {
+	return encryptedInstances{e.Store.Instances(), e}
+}
+
+// Containers returns the container store
+func (e *Encrypted) Containers() ContainerStore {
+	return encryptedContainers{e.Store.Containers(), e}
+}
+
+// DesiredState returns the desired state store
+func (e *Encrypted) DesiredState() DesiredStateStore {
+	return encryptedDesired{e.Store.DesiredState(), e}
+}
+
+// ReencryptResult counts the records rewritten by Reencrypt, and those
+// skipped because none of the keys decrypts them
+type ReencryptResult struct {
+	Instances    int `json:"instances"`
+	Containers   int `json:"containers"`
+	DesiredState int `json:"desired_state"`
+	Skipped      int `json:"skipped"`
+}
+
+// Reencrypt rewrites every record holding values that are unencrypted or
+// encrypted with a key other than the primary one. Run it after adding a
+// new primary key; the old key can be removed once it completes and nothing
+// was skipped. Desired state keeps its generation, so If-Match versions of
+// instances stay valid.
+func (e *Encrypted) Reencrypt(ctx context.Context) (ReencryptResult, error) {
+	var result ReencryptResult
+
+	instances, err := e.Store.Instances().List(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, instance := range instances {
+		spec, stale, err := e.decryptSpec(instance.Spec)
+		if err != nil {
+			e.skip("instance", instance.ID, err)
+			result.Skipped++
+			continue
+		}
+		if !stale {
+			continue
+		}
+		if instance.Spec, err = e.encryptSpec(spec); err != nil {
+			return result, fmt.Errorf("instance %s: %w", instance.ID, err)
+		}
+		if err := e.Store.Instances().Put(ctx, instance); err != nil {
+			return result, err
+		}
+		result.Instances++
+	}
+
+	containers, err := e.Store.Containers().List(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, container := range containers {
+		spec, stale, err := e.decryptSpec(container.Spec)
+		if err != nil {
+			e.skip("container", container.ServiceName, err)
+			result.Skipped++
+			continue
+		}
+		if !stale {
+			continue
+		}
+		if container.Spec, err = e.encryptSpec(spec); err != nil {
+			return result, fmt.Errorf("container %s: %w", container.ServiceName, err)
+		}
+		if err := e.Store.Containers().Put(ctx, container); err != nil {
+			return result, err
+		}
+		result.Containers++
+	}
+
+	states, err := e.Store.DesiredState().List(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, state := range states {
+		spec, stale, err := e.decryptSpec(state.Spec)
+		if err != nil {
+			e.skip("desired state", state.InstanceID, err)
+			result.Skipped++
+			continue
+		}
+		if !stale {
+			continue
+		}
+		encrypted, err := e.encryptSpec(spec)
+		if err != nil {
+			return result, fmt.Errorf("desired state %s: %w", state.InstanceID, err)
+		}
+		// A state changed since the listing was written with the primary key
+		err = e.Store.DesiredState().Rewrite(ctx, state.InstanceID, state.Generation, encrypted)
+		if errors.Is(err, ErrConflict) || errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		result.DesiredState++
+	}
+
+	return result, nil
+}
+
+// skip logs a record that none of the keys decrypts
+func (e *Encrypted) skip(kind, id string, err error) {
+	e.logger.Error("Skipping stored record that cannot be decrypted",
+		slog.String("kind", kind),
+		slog.String("id", id),
+		slog.String("error", err.Error()))
+}
+
+// encryptSpec encrypts the sensitive values of a JSON spec
+func (e *Encrypted) encryptSpec(spec json.RawMessage) (json.RawMessage, error) {
+	if len(spec) == 0 {
+		return spec, nil
+	}
+	var doc any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	doc, err := e.transform(doc, false, e.encryptValue)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// decryptSpec decrypts a JSON spec. stale reports whether any sensitive
+// value was unencrypted or encrypted with an old key.
+func (e *Encrypted) decryptSpec(spec json.RawMessage) (json.RawMessage, bool, error) {
+	if len(spec) == 0 {
+		return spec, false, nil
+	}
+	var doc any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, false, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	stale := false
+	doc, err := e.transform(doc, false, func(value any) (any, error) {
+		decrypted, primary, err := e.decryptValue(value)
+		if !primary {
+			stale = true
+		}
+		return decrypted, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	raw, err := json.Marshal(doc)
+	return raw, stale, err
+}
+
+// transform applies fn to every sensitive value. Values are sensitive below
+// an "environment" key or when their own key looks like a credential.
+func (e *Encrypted) transform(v any, sensitive bool, fn func(any) (any, error)) (any, error) {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, item := range value {
+			itemSensitive := sensitive || key == "environment" || redact.IsSecretKey(key)
+			transformed, err := e.transform(item, itemSensitive, fn)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = transformed
+		}
+		return value, nil
+	case []any:
+		for i, item := range value {
+			transformed, err := e.transform(item, sensitive, fn)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = transformed
+		}
+		return value, nil
+	case nil:
+		return nil, nil
+	default:
+		if !sensitive {
+			return v, nil
+		}
+		return fn(v)
+	}
+}
+
+// encryptValue replaces a scalar with a prefixed token of its JSON encoding,
+// so numbers and booleans come back with their type
+func (e *Encrypted) encryptValue(value any) (any, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	token, err := e.keys.Encrypt(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %w", err)
+	}
+	return encryptedPrefix + token, nil
+}
+
+// decryptValue reverses encryptValue. primary is false for plaintext values
+// and values encrypted with an old key.
+func (e *Encrypted) decryptValue(value any) (any, bool, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, encryptedPrefix) {
+		return value, false, nil
+	}
+	plaintext, primary, err := e.keys.Decrypt(strings.TrimPrefix(s, encryptedPrefix))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	var decoded any
+	if err := json.Unmarshal(plaintext, &decoded); err != nil {
+		return nil, false, fmt.Errorf("failed to decode decrypted value: %w", err)
+	}
+	return decoded, primary, nil
+}
+
+// decrypt returns spec with sensitive values decrypted
+func (e *Encrypted) decrypt(spec json.RawMessage) (json.RawMessage, error) {
+	decrypted, _, err := e.decryptSpec(spec)
+	return decrypted, err
+}
+
+type encryptedInstances struct {
+	InstanceStore
+	e *Encrypted
+}
+
+func (s encryptedInstances) Put(ctx context.Context, instance Instance) error {
+	spec, err := s.e.encryptSpec(instance.Spec)
+	if err != nil {
+		return err
+	}
+	instance.Spec = spec
+	return s.InstanceStore.Put(ctx, instance)
+}
+
+func (s encryptedInstances) Get(ctx context.Context, id string) (Instance, error) {
+	instance, err := s.InstanceStore.Get(ctx, id)
+	if err != nil {
+		return instance, err
+	}
+	instance.Spec, err = s.e.decrypt(instance.Spec)
+	return instance, err
+}
+
+func (s encryptedInstances) List(ctx context.Context) ([]Instance, error) {
+	instances, err := s.InstanceStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := instances[:0]
+	for _, instance := range instances {
+		if instance.Spec, err = s.e.decrypt(instance.Spec); err != nil {
+			s.e.skip("instance", instance.ID, err)
+			continue
+		}
+		decrypted = append(decrypted, instance)
+	}
+	return decrypted, nil
+}
+
+type encryptedContainers struct {
+	ContainerStore
+	e *Encrypted
+}
+
+func (s encryptedContainers) Put(ctx context.Context, container Container) error {
+	spec, err := s.e.encryptSpec(container.Spec)
+	if err != nil {
+		return err
+	}
+	container.Spec = spec
+	return s.ContainerStore.Put(ctx, container)
+}
+
+func (s encryptedContainers) Get(ctx context.Context, serviceName string) (Container, error) {
+	container, err := s.ContainerStore.Get(ctx, serviceName)
+	if err != nil {
+		return container, err
+	}
+	container.Spec, err = s.e.decrypt(container.Spec)
+	return container, err
+}
+
+func (s encryptedContainers) List(ctx context.Context) ([]Container, error) {
+	containers, err := s.ContainerStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := containers[:0]
+	for _, container := range containers {
+		if container.Spec, err = s.e.decrypt(container.Spec); err != nil {
+			s.e.skip("container", container.ServiceName, err)
+			continue
+		}
+		decrypted = append(decrypted, container)
+	}
+	return decrypted, nil
+}
+
+type encryptedDesired struct {
+	DesiredStateStore
+	e *Encrypted
+}
+
+func (s encryptedDesired) Put(ctx context.Context, instanceID string, spec json.RawMessage) (int64, error) {
+	encrypted, err := s.e.encryptSpec(spec)
+	if err != nil {
+		return 0, err
+	}
+	return s.DesiredStateStore.Put(ctx, instanceID, encrypted)
+}
+
+func (s encryptedDesired) Rewrite(ctx context.Context, instanceID string, generation int64, spec json.RawMessage) error {
+	encrypted, err := s.e.encryptSpec(spec)
+	if err != nil {
+		return err
+	}
+	return s.DesiredStateStore.Rewrite(ctx, instanceID, generation, encrypted)
+}
+
+func (s encryptedDesired) Get(ctx context.Context, instanceID string) (DesiredState, error) {
+	state, err := s.DesiredStateStore.Get(ctx, instanceID)
+	if err != nil {
+		return state, err
+	}
+	state.Spec, err = s.e.decrypt(state.Spec)
+	return state, err
+}
+
+func (s encryptedDesired) List(ctx context.Context) ([]DesiredState, error) {
+	states, err := s.DesiredStateStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := states[:0]
+	for _, state := range states {
+		if state.Spec, err = s.e.decrypt(state.Spec); err != nil {
+			s.e.skip("desired state", state.InstanceID, err)
+			continue
+		}
+		decrypted = append(decrypted, state)
+	}
+	return decrypted, nil
+}
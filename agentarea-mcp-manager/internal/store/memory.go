@@ -0,0 +1,353 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a Store kept in process memory; its contents are lost on restart
+type Memory struct {
+	mu         sync.RWMutex
+	instances  map[string]Instance
+	containers map[string]Container
+	routes     map[string]Route
+	desired    map[string]DesiredState
+	audit      []AuditEntry
+	health     map[string][]HealthSample
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		instances:  make(map[string]Instance),
+		containers: make(map[string]Container),
+		routes:     make(map[string]Route),
+		desired:    make(map[string]DesiredState),
+		health:     make(map[string][]HealthSample),
+	}
+}
+
+// Instances returns the instance store
+func (m *Memory) Instances() InstanceStore { return memoryInstances{m} }
+
+// Containers returns the container store
+func (m *Memory) Containers() ContainerStore { return memoryContainers{m} }
+
+// Routes returns the route store
+func (m *Memory) Routes() RouteStore { return memoryRoutes{m} }
+
+// DesiredState returns the desired state store
+func (m *Memory) DesiredState() DesiredStateStore { return memoryDesired{m} }
+
+// Audit returns the audit log
+func (m *Memory) Audit() AuditStore { return memoryAudit{m} }
+
+// Health returns the health history
+func (m *Memory) Health() HealthStore { return memoryHealth{m} }
+
+// Close does nothing for the in-memory store
+func (m *Memory) Close() error { return nil }
+
+// cloneRaw copies JSON so callers cannot modify stored records
+func cloneRaw(raw json.RawMessage) json.RawMessage {
+	if raw == nil {
+		return nil
+	}
+	return append(json.RawMessage(nil), raw...)
+}
+
+type memoryInstances struct{ m *Memory }
+
+func (s memoryInstances) Put(_ context.Context, instance Instance) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	now := time.Now().UTC()
+	if existing, ok := s.m.instances[instance.ID]; ok {
+		instance.CreatedAt = existing.CreatedAt
+	} else if instance.CreatedAt.IsZero() {
+		instance.CreatedAt = now
+	}
+	instance.UpdatedAt = now
+	instance.Spec = cloneRaw(instance.Spec)
+	s.m.instances[instance.ID] = instance
+	return nil
+}
+
+func (s memoryInstances) Get(_ context.Context, id string) (Instance, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	instance, ok := s.m.instances[id]
+	if !ok {
+		return Instance{}, ErrNotFound
+	}
+	instance.Spec = cloneRaw(instance.Spec)
+	return instance, nil
+}
+
+func (s memoryInstances) List(_ context.Context) ([]Instance, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	instances := make([]Instance, 0, len(s.m.instances))
+	for _, instance := range s.m.instances {
+		instance.Spec = cloneRaw(instance.Spec)
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+func (s memoryInstances) Delete(_ context.Context, id string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	delete(s.m.instances, id)
+	return nil
+}
+
+type memoryContainers struct{ m *Memory }
+
+func (s memoryContainers) Put(_ context.Context, container Container) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	now := time.Now().UTC()
+	if existing, ok := s.m.containers[container.ServiceName]; ok {
+		container.CreatedAt = existing.CreatedAt
+	} else if container.CreatedAt.IsZero() {
+		container.CreatedAt = now
+	}
+	container.UpdatedAt = now
+	container.Spec = cloneRaw(container.Spec)
+	s.m.containers[container.ServiceName] = container
+	return nil
+}
+
+func (s memoryContainers) Get(_ context.Context, serviceName string) (Container, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	container, ok := s.m.containers[serviceName]
+	if !ok {
+		return Container{}, ErrNotFound
+	}
+	container.Spec = cloneRaw(container.Spec)
+	return container, nil
+}
+
+func (s memoryContainers) List(_ context.Context) ([]Container, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	containers := make([]Container, 0, len(s.m.containers))
+	for _, container := range s.m.containers {
+		container.Spec = cloneRaw(container.Spec)
+		containers = append(containers, container)
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].ServiceName < containers[j].ServiceName })
+	return containers, nil
+}
+
+func (s memoryContainers) Delete(_ context.Context, serviceName string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	delete(s.m.containers, serviceName)
+	return nil
+}
+
+type memoryRoutes struct{ m *Memory }
+
+func (s memoryRoutes) Put(_ context.Context, route Route) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	route.UpdatedAt = time.Now().UTC()
+	s.m.routes[route.Slug] = route
+	return nil
+}
+
+func (s memoryRoutes) Get(_ context.Context, slug string) (Route, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	route, ok := s.m.routes[slug]
+	if !ok {
+		return Route{}, ErrNotFound
+	}
+	return route, nil
+}
+
+func (s memoryRoutes) List(_ context.Context) ([]Route, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	routes := make([]Route, 0, len(s.m.routes))
+	for _, route := range s.m.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Slug < routes[j].Slug })
+	return routes, nil
+}
+
+func (s memoryRoutes) Delete(_ context.Context, slug string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	delete(s.m.routes, slug)
+	return nil
+}
+
+type memoryDesired struct{ m *Memory }
+
+func (s memoryDesired) Put(_ context.Context, instanceID string, spec json.RawMessage) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	state := DesiredState{
+		InstanceID: instanceID,
+		Spec:       cloneRaw(spec),
+		Generation: s.m.desired[instanceID].Generation + 1,
+		UpdatedAt:  time.Now().UTC(),
+	}
+	s.m.desired[instanceID] = state
+	return state.Generation, nil
+}
+
+func (s memoryDesired) Get(_ context.Context, instanceID string) (DesiredState, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	state, ok := s.m.desired[instanceID]
+	if !ok {
+		return DesiredState{}, ErrNotFound
+	}
+	state.Spec = cloneRaw(state.Spec)
+	return state, nil
+}
+
+func (s memoryDesired) List(_ context.Context) ([]DesiredState, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	states := make([]DesiredState, 0, len(s.m.desired))
+	for _, state := range s.m.desired {
+		state.Spec = cloneRaw(state.Spec)
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].InstanceID < states[j].InstanceID })
+	return states, nil
+}
+
+func (s memoryDesired) Rewrite(_ context.Context, instanceID string, generation int64, spec json.RawMessage) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	state, ok := s.m.desired[instanceID]
+	switch {
+	case !ok:
+		return ErrNotFound
+	case state.Generation != generation:
+		return ErrConflict
+	}
+	state.Spec = cloneRaw(spec)
+	s.m.desired[instanceID] = state
+	return nil
+}
+
+func (s memoryDesired) Delete(_ context.Context, instanceID string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	delete(s.m.desired, instanceID)
+	return nil
+}
+
+type memoryAudit struct{ m *Memory }
+
+func (s memoryAudit) Append(_ context.Context, entry AuditEntry) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	entry.ID = int64(len(s.m.audit)) + 1
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	entry.Details = cloneRaw(entry.Details)
+	s.m.audit = append(s.m.audit, entry)
+	return entry.ID, nil
+}
+
+func (s memoryAudit) List(_ context.Context, query AuditQuery) ([]AuditEntry, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	var entries []AuditEntry
+	for i := len(s.m.audit) - 1; i >= 0; i-- {
+		entry := s.m.audit[i]
+		if query.Target != "" && entry.Target != query.Target {
+			continue
+		}
+		if !query.Since.IsZero() && entry.Time.Before(query.Since) {
+			continue
+		}
+		entry.Details = cloneRaw(entry.Details)
+		entries = append(entries, entry)
+		if query.Limit > 0 && len(entries) == query.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+type memoryHealth struct{ m *Memory }
+
+func (s memoryHealth) Record(_ context.Context, sample HealthSample) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	samples := append(s.m.health[sample.InstanceID], sample)
+	// Keep samples ordered even if a late sample arrives
+	for i := len(samples) - 1; i > 0 && samples[i].Time.Before(samples[i-1].Time); i-- {
+		samples[i], samples[i-1] = samples[i-1], samples[i]
+	}
+	s.m.health[sample.InstanceID] = samples
+	return nil
+}
+
+func (s memoryHealth) Range(_ context.Context, instanceID string, from, to time.Time) ([]HealthSample, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	var samples []HealthSample
+	for _, sample := range s.m.health[instanceID] {
+		if !sample.Time.Before(from) && sample.Time.Before(to) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+func (s memoryHealth) Prune(_ context.Context, before time.Time) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var removed int64
+	for id, samples := range s.m.health {
+		keep := samples[:0]
+		for _, sample := range samples {
+			if sample.Time.Before(before) {
+				removed++
+				continue
+			}
+			keep = append(keep, sample)
+		}
+		if len(keep) == 0 {
+			delete(s.m.health, id)
+		} else {
+			s.m.health[id] = keep
+		}
+	}
+	return removed, nil
+}
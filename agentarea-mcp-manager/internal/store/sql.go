@@ -0,0 +1,547 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Postgres driver
+	_ "modernc.org/sqlite"             // SQLite driver
+)
+
+// dialect captures the differences between the SQL databases
+type dialect struct {
+	name       string
+	driver     string
+	serialType string // Auto-incrementing primary key column type
+	numbered   bool   // Placeholders are $1, $2, ... instead of ?
+	maxConns   int    // 0 means no limit
+	setup      []string
+}
+
+var sqliteDialect = dialect{
+	name:       DriverSQLite,
+	driver:     "sqlite",
+	serialType: "INTEGER PRIMARY KEY AUTOINCREMENT",
+	// SQLite allows one writer; a single connection avoids "database is locked"
+	maxConns: 1,
+	setup:    []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000"},
+}
+
+var postgresDialect = dialect{
+	name:       DriverPostgres,
+	driver:     "pgx",
+	serialType: "BIGSERIAL PRIMARY KEY",
+	numbered:   true,
+}
+
+// schema creates the tables; %s is the serial column type
+const schema = `
+CREATE TABLE IF NOT EXISTS mcp_manager_instances (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	workspace_id TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	spec TEXT,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS mcp_manager_containers (
+	service_name TEXT PRIMARY KEY,
+	container_id TEXT NOT NULL,
+	instance_id TEXT NOT NULL DEFAULT '',
+	slug TEXT NOT NULL,
+	desired_state TEXT NOT NULL,
+	spec TEXT,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS mcp_manager_routes (
+	slug TEXT PRIMARY KEY,
+	instance_id TEXT NOT NULL,
+	target TEXT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS mcp_manager_desired_state (
+	instance_id TEXT PRIMARY KEY,
+	spec TEXT NOT NULL,
+	generation BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS mcp_manager_audit (
+	id %s,
+	time BIGINT NOT NULL,
+	actor TEXT NOT NULL,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL,
+	details TEXT
+);
+CREATE INDEX IF NOT EXISTS mcp_manager_audit_target ON mcp_manager_audit (target, id);
+CREATE TABLE IF NOT EXISTS mcp_manager_health (
+	instance_id TEXT NOT NULL,
+	time BIGINT NOT NULL,
+	healthy INTEGER NOT NULL,
+	latency_ns BIGINT NOT NULL,
+	message TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS mcp_manager_health_instance ON mcp_manager_health (instance_id, time);
+`
+
+// SQL is a Store backed by SQLite or Postgres
+type SQL struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// openSQL connects and creates the schema
+func openSQL(ctx context.Context, d dialect, dsn string) (*SQL, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("%s store requires a DSN", d.name)
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s store: %w", d.name, err)
+	}
+	if d.maxConns > 0 {
+		db.SetMaxOpenConns(d.maxConns)
+	}
+
+	s := &SQL{db: db, dialect: d}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies connection settings and creates missing tables
+func (s *SQL) migrate(ctx context.Context) error {
+	statements := append([]string(nil), s.dialect.setup...)
+	for _, statement := range strings.Split(fmt.Sprintf(schema, s.dialect.serialType), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to migrate %s store: %w", s.dialect.name, err)
+		}
+	}
+	return nil
+}
+
+// query rewrites ? placeholders for dialects with numbered placeholders
+func (s *SQL) query(q string) string {
+	if !s.dialect.numbered {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Instances returns the instance store
+func (s *SQL) Instances() InstanceStore { return sqlInstances{s} }
+
+// Containers returns the container store
+func (s *SQL) Containers() ContainerStore { return sqlContainers{s} }
+
+// Routes returns the route store
+func (s *SQL) Routes() RouteStore { return sqlRoutes{s} }
+
+// DesiredState returns the desired state store
+func (s *SQL) DesiredState() DesiredStateStore { return sqlDesired{s} }
+
+// Audit returns the audit log
+func (s *SQL) Audit() AuditStore { return sqlAudit{s} }
+
+// Health returns the health history
+func (s *SQL) Health() HealthStore { return sqlHealth{s} }
+
+// Close closes the database
+func (s *SQL) Close() error { return s.db.Close() }
+
+func toNanos(t time.Time) int64 { return t.UnixNano() }
+
+func fromNanos(n int64) time.Time { return time.Unix(0, n).UTC() }
+
+// nullableJSON stores empty JSON as NULL
+func nullableJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// rawJSON converts a scanned nullable column back to JSON
+func rawJSON(value sql.NullString) json.RawMessage {
+	if !value.Valid {
+		return nil
+	}
+	return json.RawMessage(value.String)
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type sqlInstances struct{ s *SQL }
+
+func (t sqlInstances) Put(ctx context.Context, instance Instance) error {
+	now := time.Now()
+	if instance.CreatedAt.IsZero() {
+		instance.CreatedAt = now
+	}
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`
+		INSERT INTO mcp_manager_instances (id, name, provider, workspace_id, status, spec, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name, provider = excluded.provider, workspace_id = excluded.workspace_id,
+			status = excluded.status, spec = excluded.spec, updated_at = excluded.updated_at`),
+		instance.ID, instance.Name, instance.Provider, instance.WorkspaceID, instance.Status,
+		nullableJSON(instance.Spec), toNanos(instance.CreatedAt), toNanos(now))
+	return err
+}
+
+const instanceColumns = `id, name, provider, workspace_id, status, spec, created_at, updated_at`
+
+func scanInstance(row interface{ Scan(...any) error }) (Instance, error) {
+	var instance Instance
+	var spec sql.NullString
+	var created, updated int64
+	if err := row.Scan(&instance.ID, &instance.Name, &instance.Provider, &instance.WorkspaceID,
+		&instance.Status, &spec, &created, &updated); err != nil {
+		return Instance{}, err
+	}
+	instance.Spec = rawJSON(spec)
+	instance.CreatedAt = fromNanos(created)
+	instance.UpdatedAt = fromNanos(updated)
+	return instance, nil
+}
+
+func (t sqlInstances) Get(ctx context.Context, id string) (Instance, error) {
+	row := t.s.db.QueryRowContext(ctx, t.s.query(`SELECT `+instanceColumns+` FROM mcp_manager_instances WHERE id = ?`), id)
+	instance, err := scanInstance(row)
+	return instance, notFound(err)
+}
+
+func (t sqlInstances) List(ctx context.Context) ([]Instance, error) {
+	rows, err := t.s.db.QueryContext(ctx, `SELECT `+instanceColumns+` FROM mcp_manager_instances ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := []Instance{}
+	for rows.Next() {
+		instance, err := scanInstance(rows)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, rows.Err()
+}
+
+func (t sqlInstances) Delete(ctx context.Context, id string) error {
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`DELETE FROM mcp_manager_instances WHERE id = ?`), id)
+	return err
+}
+
+type sqlContainers struct{ s *SQL }
+
+func (t sqlContainers) Put(ctx context.Context, container Container) error {
+	now := time.Now()
+	if container.CreatedAt.IsZero() {
+		container.CreatedAt = now
+	}
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`
+		INSERT INTO mcp_manager_containers (service_name, container_id, instance_id, slug, desired_state, spec, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (service_name) DO UPDATE SET
+			container_id = excluded.container_id, instance_id = excluded.instance_id, slug = excluded.slug,
+			desired_state = excluded.desired_state, spec = excluded.spec, updated_at = excluded.updated_at`),
+		container.ServiceName, container.ContainerID, container.InstanceID, container.Slug, container.DesiredState,
+		nullableJSON(container.Spec), toNanos(container.CreatedAt), toNanos(now))
+	return err
+}
+
+const containerColumns = `service_name, container_id, instance_id, slug, desired_state, spec, created_at, updated_at`
+
+func scanContainer(row interface{ Scan(...any) error }) (Container, error) {
+	var container Container
+	var spec sql.NullString
+	var created, updated int64
+	if err := row.Scan(&container.ServiceName, &container.ContainerID, &container.InstanceID, &container.Slug,
+		&container.DesiredState, &spec, &created, &updated); err != nil {
+		return Container{}, err
+	}
+	container.Spec = rawJSON(spec)
+	container.CreatedAt = fromNanos(created)
+	container.UpdatedAt = fromNanos(updated)
+	return container, nil
+}
+
+func (t sqlContainers) Get(ctx context.Context, serviceName string) (Container, error) {
+	row := t.s.db.QueryRowContext(ctx, t.s.query(`SELECT `+containerColumns+` FROM mcp_manager_containers WHERE service_name = ?`), serviceName)
+	container, err := scanContainer(row)
+	return container, notFound(err)
+}
+
+func (t sqlContainers) List(ctx context.Context) ([]Container, error) {
+	rows, err := t.s.db.QueryContext(ctx, `SELECT `+containerColumns+` FROM mcp_manager_containers ORDER BY service_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	containers := []Container{}
+	for rows.Next() {
+		container, err := scanContainer(rows)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, container)
+	}
+	return containers, rows.Err()
+}
+
+func (t sqlContainers) Delete(ctx context.Context, serviceName string) error {
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`DELETE FROM mcp_manager_containers WHERE service_name = ?`), serviceName)
+	return err
+}
+
+type sqlRoutes struct{ s *SQL }
+
+func (t sqlRoutes) Put(ctx context.Context, route Route) error {
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`
+		INSERT INTO mcp_manager_routes (slug, instance_id, target, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (slug) DO UPDATE SET
+			instance_id = excluded.instance_id, target = excluded.target, updated_at = excluded.updated_at`),
+		route.Slug, route.InstanceID, route.Target, toNanos(time.Now()))
+	return err
+}
+
+func scanRoute(row interface{ Scan(...any) error }) (Route, error) {
+	var route Route
+	var updated int64
+	if err := row.Scan(&route.Slug, &route.InstanceID, &route.Target, &updated); err != nil {
+		return Route{}, err
+	}
+	route.UpdatedAt = fromNanos(updated)
+	return route, nil
+}
+
+func (t sqlRoutes) Get(ctx context.Context, slug string) (Route, error) {
+	row := t.s.db.QueryRowContext(ctx, t.s.query(`SELECT slug, instance_id, target, updated_at FROM mcp_manager_routes WHERE slug = ?`), slug)
+	route, err := scanRoute(row)
+	return route, notFound(err)
+}
+
+func (t sqlRoutes) List(ctx context.Context) ([]Route, error) {
+	rows, err := t.s.db.QueryContext(ctx, `SELECT slug, instance_id, target, updated_at FROM mcp_manager_routes ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	routes := []Route{}
+	for rows.Next() {
+		route, err := scanRoute(rows)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, rows.Err()
+}
+
+func (t sqlRoutes) Delete(ctx context.Context, slug string) error {
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`DELETE FROM mcp_manager_routes WHERE slug = ?`), slug)
+	return err
+}
+
+type sqlDesired struct{ s *SQL }
+
+func (t sqlDesired) Put(ctx context.Context, instanceID string, spec json.RawMessage) (int64, error) {
+	var generation int64
+	err := t.s.db.QueryRowContext(ctx, t.s.query(`
+		INSERT INTO mcp_manager_desired_state (instance_id, spec, generation, updated_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (instance_id) DO UPDATE SET
+			spec = excluded.spec, generation = mcp_manager_desired_state.generation + 1, updated_at = excluded.updated_at
+		RETURNING generation`),
+		instanceID, string(spec), toNanos(time.Now())).Scan(&generation)
+	return generation, err
+}
+
+func scanDesired(row interface{ Scan(...any) error }) (DesiredState, error) {
+	var state DesiredState
+	var spec string
+	var updated int64
+	if err := row.Scan(&state.InstanceID, &spec, &state.Generation, &updated); err != nil {
+		return DesiredState{}, err
+	}
+	state.Spec = json.RawMessage(spec)
+	state.UpdatedAt = fromNanos(updated)
+	return state, nil
+}
+
+func (t sqlDesired) Get(ctx context.Context, instanceID string) (DesiredState, error) {
+	row := t.s.db.QueryRowContext(ctx, t.s.query(`SELECT instance_id, spec, generation, updated_at FROM mcp_manager_desired_state WHERE instance_id = ?`), instanceID)
+	state, err := scanDesired(row)
+	return state, notFound(err)
+}
+
+func (t sqlDesired) List(ctx context.Context) ([]DesiredState, error) {
+	rows, err := t.s.db.QueryContext(ctx, `SELECT instance_id, spec, generation, updated_at FROM mcp_manager_desired_state ORDER BY instance_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := []DesiredState{}
+	for rows.Next() {
+		state, err := scanDesired(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+func (t sqlDesired) Rewrite(ctx context.Context, instanceID string, generation int64, spec json.RawMessage) error {
+	result, err := t.s.db.ExecContext(ctx, t.s.query(`
+		UPDATE mcp_manager_desired_state SET spec = ? WHERE instance_id = ? AND generation = ?`),
+		string(spec), instanceID, generation)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows > 0 {
+		return err
+	}
+	if _, err := t.Get(ctx, instanceID); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (t sqlDesired) Delete(ctx context.Context, instanceID string) error {
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`DELETE FROM mcp_manager_desired_state WHERE instance_id = ?`), instanceID)
+	return err
+}
+
+type sqlAudit struct{ s *SQL }
+
+func (t sqlAudit) Append(ctx context.Context, entry AuditEntry) (int64, error) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	var id int64
+	err := t.s.db.QueryRowContext(ctx, t.s.query(`
+		INSERT INTO mcp_manager_audit (time, actor, action, target, details) VALUES (?, ?, ?, ?, ?)
+		RETURNING id`),
+		toNanos(entry.Time), entry.Actor, entry.Action, entry.Target, nullableJSON(entry.Details)).Scan(&id)
+	return id, err
+}
+
+func (t sqlAudit) List(ctx context.Context, query AuditQuery) ([]AuditEntry, error) {
+	q := `SELECT id, time, actor, action, target, details FROM mcp_manager_audit WHERE 1 = 1`
+	var args []any
+	if query.Target != "" {
+		q += ` AND target = ?`
+		args = append(args, query.Target)
+	}
+	if !query.Since.IsZero() {
+		q += ` AND time >= ?`
+		args = append(args, toNanos(query.Since))
+	}
+	q += ` ORDER BY id DESC`
+	if query.Limit > 0 {
+		q += ` LIMIT ` + strconv.Itoa(query.Limit)
+	}
+
+	rows, err := t.s.db.QueryContext(ctx, t.s.query(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var at int64
+		var details sql.NullString
+		if err := rows.Scan(&entry.ID, &at, &entry.Actor, &entry.Action, &entry.Target, &details); err != nil {
+			return nil, err
+		}
+		entry.Time = fromNanos(at)
+		entry.Details = rawJSON(details)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+type sqlHealth struct{ s *SQL }
+
+func (t sqlHealth) Record(ctx context.Context, sample HealthSample) error {
+	healthy := 0
+	if sample.Healthy {
+		healthy = 1
+	}
+	_, err := t.s.db.ExecContext(ctx, t.s.query(`
+		INSERT INTO mcp_manager_health (instance_id, time, healthy, latency_ns, message) VALUES (?, ?, ?, ?, ?)`),
+		sample.InstanceID, toNanos(sample.Time), healthy, int64(sample.Latency), sample.Message)
+	return err
+}
+
+func (t sqlHealth) Range(ctx context.Context, instanceID string, from, to time.Time) ([]HealthSample, error) {
+	rows, err := t.s.db.QueryContext(ctx, t.s.query(`
+		SELECT time, healthy, latency_ns, message FROM mcp_manager_health
+		WHERE instance_id = ? AND time >= ? AND time < ? ORDER BY time`),
+		instanceID, toNanos(from), toNanos(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []HealthSample
+	for rows.Next() {
+		sample := HealthSample{InstanceID: instanceID}
+		var at, latency int64
+		var healthy int
+		if err := rows.Scan(&at, &healthy, &latency, &sample.Message); err != nil {
+			return nil, err
+		}
+		sample.Time = fromNanos(at)
+		sample.Healthy = healthy != 0
+		sample.Latency = time.Duration(latency)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+func (t sqlHealth) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := t.s.db.ExecContext(ctx, t.s.query(`DELETE FROM mcp_manager_health WHERE time < ?`), toNanos(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
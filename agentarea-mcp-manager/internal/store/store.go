@@ -0,0 +1,179 @@
+// Package store persists manager metadata: instances, containers, routes,
+// desired state, audit entries and health history. Features build on the Store
+// interface instead of keeping their own maps; the backing implementation is
+// chosen at startup (in-memory, SQLite for a single node, or Postgres).
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Supported drivers
+const (
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a record changed since it was read
+var ErrConflict = errors.New("record changed")
+
+// Store groups the metadata stores
+type Store interface {
+	Instances() InstanceStore
+	Containers() ContainerStore
+	Routes() RouteStore
+	DesiredState() DesiredStateStore
+	Audit() AuditStore
+	Health() HealthStore
+	Close() error
+}
+
+// Instance is the persisted record of a managed instance
+type Instance struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Provider    string          `json:"provider"`
+	WorkspaceID string          `json:"workspace_id,omitempty"`
+	Status      string          `json:"status"`
+	Spec        json.RawMessage `json:"spec,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// InstanceStore persists instances by ID
+type InstanceStore interface {
+	Put(ctx context.Context, instance Instance) error
+	Get(ctx context.Context, id string) (Instance, error)
+	List(ctx context.Context) ([]Instance, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Desired states of a container
+const (
+	ContainerRunning = "running"
+	ContainerStopped = "stopped"
+)
+
+// Container is the persisted state of a managed container, so a restarted
+// manager knows its containers without re-deriving them from the runtime
+type Container struct {
+	ServiceName  string          `json:"service_name"`
+	ContainerID  string          `json:"container_id"`
+	InstanceID   string          `json:"instance_id,omitempty"`
+	Slug         string          `json:"slug"`
+	DesiredState string          `json:"desired_state"` // ContainerRunning or ContainerStopped
+	Spec         json.RawMessage `json:"spec,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// ContainerStore persists containers by service name
+type ContainerStore interface {
+	Put(ctx context.Context, container Container) error
+	Get(ctx context.Context, serviceName string) (Container, error)
+	List(ctx context.Context) ([]Container, error)
+	Delete(ctx context.Context, serviceName string) error
+}
+
+// Route maps a proxy slug to the instance and upstream serving it
+type Route struct {
+	Slug       string    `json:"slug"`
+	InstanceID string    `json:"instance_id"`
+	Target     string    `json:"target"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// RouteStore persists routes by slug
+type RouteStore interface {
+	Put(ctx context.Context, route Route) error
+	Get(ctx context.Context, slug string) (Route, error)
+	List(ctx context.Context) ([]Route, error)
+	Delete(ctx context.Context, slug string) error
+}
+
+// DesiredState is the spec an instance should be running. Generation
+// increases with every change so reconcilers can detect stale work.
+type DesiredState struct {
+	InstanceID string          `json:"instance_id"`
+	Spec       json.RawMessage `json:"spec"`
+	Generation int64           `json:"generation"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// DesiredStateStore persists desired state by instance ID
+type DesiredStateStore interface {
+	// Put stores the spec and returns the new generation
+	Put(ctx context.Context, instanceID string, spec json.RawMessage) (int64, error)
+	Get(ctx context.Context, instanceID string) (DesiredState, error)
+	List(ctx context.Context) ([]DesiredState, error)
+	Delete(ctx context.Context, instanceID string) error
+	// Rewrite replaces the spec of a generation without advancing it, for
+	// changes of encoding only. It returns ErrConflict if the generation is
+	// no longer current.
+	Rewrite(ctx context.Context, instanceID string, generation int64, spec json.RawMessage) error
+}
+
+// AuditEntry records an action taken on the manager
+type AuditEntry struct {
+	ID      int64           `json:"id"`
+	Time    time.Time       `json:"time"`
+	Actor   string          `json:"actor"`
+	Action  string          `json:"action"`
+	Target  string          `json:"target"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// AuditQuery filters audit entries; zero values match everything
+type AuditQuery struct {
+	Target string
+	Since  time.Time
+	Limit  int // Newest entries first; 0 means no limit
+}
+
+// AuditStore is an append-only audit log
+type AuditStore interface {
+	// Append stores an entry and returns its ID
+	Append(ctx context.Context, entry AuditEntry) (int64, error)
+	List(ctx context.Context, query AuditQuery) ([]AuditEntry, error)
+}
+
+// HealthSample is the result of one health check
+type HealthSample struct {
+	InstanceID string        `json:"instance_id"`
+	Time       time.Time     `json:"time"`
+	Healthy    bool          `json:"healthy"`
+	Latency    time.Duration `json:"latency"`
+	Message    string        `json:"message,omitempty"`
+}
+
+// HealthStore keeps health check history
+type HealthStore interface {
+	Record(ctx context.Context, sample HealthSample) error
+	// Range returns the samples of an instance in [from, to), oldest first
+	Range(ctx context.Context, instanceID string, from, to time.Time) ([]HealthSample, error)
+	// Prune deletes samples older than before and returns how many were removed
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Open opens a store. The DSN is a file path for SQLite and a connection
+// URL for Postgres; it is ignored for the in-memory store.
+func Open(ctx context.Context, driver, dsn string) (Store, error) {
+	switch driver {
+	case "", DriverMemory:
+		return NewMemory(), nil
+	case DriverSQLite:
+		return openSQL(ctx, sqliteDialect, dsn)
+	case DriverPostgres:
+		return openSQL(ctx, postgresDialect, dsn)
+	default:
+		return nil, fmt.Errorf("unsupported store driver %q (supported: memory, sqlite, postgres)", driver)
+	}
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/fernet"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+
+	sqlite, err := Open(ctx, DriverSQLite, filepath.Join(t.TempDir(), "manager.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	defer sqlite.Close()
+
+	key, _ := fernet.GenerateKey()
+	keys, _ := fernet.NewKeyring(key)
+
+	for name, s := range map[string]Store{"memory": NewMemory(), "sqlite": sqlite, "encrypted": NewEncrypted(NewMemory(), keys, testLogger)} {
+		t.Run(name, func(t *testing.T) { testStore(t, s) })
+	}
+}
+
+func TestEncryptedRotation(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemory()
+	oldKey, _ := fernet.GenerateKey()
+	oldKeys, _ := fernet.NewKeyring(oldKey)
+
+	spec := `{"environment":{"API_KEY":"abc123456","PORT":8080},"image":"ghcr.io/acme/mcp","oauth_token":"tok-987654"}`
+	if err := NewEncrypted(inner, oldKeys, testLogger).Instances().Put(ctx, Instance{ID: "i1", Spec: json.RawMessage(spec)}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := inner.Instances().Get(ctx, "i1")
+	if strings.Contains(string(raw.Spec), "abc123456") || strings.Contains(string(raw.Spec), "tok-987654") || !strings.Contains(string(raw.Spec), "ghcr.io/acme/mcp") {
+		t.Fatalf("expected only sensitive values to be encrypted: %s", raw.Spec)
+	}
+
+	newKey, _ := fernet.GenerateKey()
+	rotatedKeys, _ := fernet.NewKeyring(newKey, oldKey)
+	rotated := NewEncrypted(inner, rotatedKeys, testLogger)
+	instance, err := rotated.Instances().Get(ctx, "i1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want map[string]any
+	json.Unmarshal(instance.Spec, &got)
+	json.Unmarshal([]byte(spec), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decrypted spec %s, want %s", instance.Spec, spec)
+	}
+
+	result, err := rotated.Reencrypt(ctx)
+	if err != nil || result.Instances != 1 {
+		t.Fatalf("expected one re-encrypted instance, got %+v, %v", result, err)
+	}
+	if result, _ := rotated.Reencrypt(ctx); result.Instances != 0 {
+		t.Errorf("expected nothing left to re-encrypt, got %+v", result)
+	}
+
+	// The old key is no longer needed
+	newOnly, _ := fernet.NewKeyring(newKey)
+	if _, err := NewEncrypted(inner, newOnly, testLogger).Instances().Get(ctx, "i1"); err != nil {
+		t.Errorf("expected spec readable with the new key alone: %v", err)
+	}
+}
+
+func TestEncryptedTokenLookalikesAndUnreadableRecords(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemory()
+	key, _ := fernet.GenerateKey()
+	keys, _ := fernet.NewKeyring(key)
+	encrypted := NewEncrypted(inner, keys, testLogger)
+
+	// A value that looks like a token is encrypted like any other
+	spec := `{"environment":{"API_KEY":"fernet:plaintext-secret"}}`
+	if err := encrypted.Instances().Put(ctx, Instance{ID: "i1", Spec: json.RawMessage(spec)}); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := inner.Instances().Get(ctx, "i1"); strings.Contains(string(raw.Spec), "plaintext-secret") {
+		t.Fatalf("expected the value to be encrypted: %s", raw.Spec)
+	}
+	if instance, err := encrypted.Instances().Get(ctx, "i1"); err != nil || string(instance.Spec) != spec {
+		t.Fatalf("expected the value back, got %s, %v", instance.Spec, err)
+	}
+
+	// A record written with an unknown key is left out of listings
+	otherKey, _ := fernet.GenerateKey()
+	otherKeys, _ := fernet.NewKeyring(otherKey)
+	if err := NewEncrypted(inner, otherKeys, testLogger).Instances().Put(ctx, Instance{ID: "i2", Spec: json.RawMessage(spec)}); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := encrypted.Instances().List(ctx)
+	if err != nil || len(instances) != 1 || instances[0].ID != "i1" {
+		t.Fatalf("expected only the readable instance, got %+v, %v", instances, err)
+	}
+	if result, err := encrypted.Reencrypt(ctx); err != nil || result.Skipped != 1 {
+		t.Fatalf("expected the unreadable instance to be skipped, got %+v, %v", result, err)
+	}
+}
+
+func TestReencryptKeepsDesiredStateGeneration(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemory()
+	oldKey, _ := fernet.GenerateKey()
+	oldKeys, _ := fernet.NewKeyring(oldKey)
+
+	generation, err := NewEncrypted(inner, oldKeys, testLogger).DesiredState().Put(ctx, "i1", json.RawMessage(`{"environment":{"API_KEY":"abc"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, _ := fernet.GenerateKey()
+	rotatedKeys, _ := fernet.NewKeyring(newKey, oldKey)
+	rotated := NewEncrypted(inner, rotatedKeys, testLogger)
+	if result, err := rotated.Reencrypt(ctx); err != nil || result.DesiredState != 1 {
+		t.Fatalf("expected one re-encrypted desired state, got %+v, %v", result, err)
+	}
+	state, err := rotated.DesiredState().Get(ctx, "i1")
+	if err != nil || state.Generation != generation {
+		t.Fatalf("expected generation %d to be kept, got %+v, %v", generation, state, err)
+	}
+}
+
+// testStore checks the behavior every Store implementation must share
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	// Instances keep their creation time across updates
+	if err := s.Instances().Put(ctx, Instance{ID: "i1", Name: "github", Provider: "docker", Status: "running", Spec: json.RawMessage(`{"image":"a"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Instances().Get(ctx, "i1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Instances().Put(ctx, Instance{ID: "i1", Name: "github", Provider: "docker", Status: "stopped"}); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := s.Instances().Get(ctx, "i1")
+	if second.Status != "stopped" || !second.CreatedAt.Equal(first.CreatedAt) || string(first.Spec) != `{"image":"a"}` {
+		t.Errorf("unexpected instance after update: %+v (was %+v)", second, first)
+	}
+	if err := s.Instances().Delete(ctx, "i1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Instances().Get(ctx, "i1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	// Containers are keyed by service name
+	if err := s.Containers().Put(ctx, Container{ServiceName: "github", ContainerID: "c1", InstanceID: "i1", Slug: "github-1", DesiredState: ContainerRunning, Spec: json.RawMessage(`{"environment":{"TOKEN":"abc"},"port":8000}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Containers().Put(ctx, Container{ServiceName: "github", ContainerID: "c2", InstanceID: "i1", Slug: "github-1", DesiredState: ContainerStopped, Spec: json.RawMessage(`{"environment":{"TOKEN":"abc"},"port":8000}`)}); err != nil {
+		t.Fatal(err)
+	}
+	containers, err := s.Containers().List(ctx)
+	if err != nil || len(containers) != 1 || containers[0].ContainerID != "c2" || containers[0].DesiredState != ContainerStopped {
+		t.Fatalf("unexpected containers: %+v, %v", containers, err)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(containers[0].Spec, &spec); err != nil || spec["environment"].(map[string]any)["TOKEN"] != "abc" {
+		t.Errorf("unexpected container spec %s, %v", containers[0].Spec, err)
+	}
+	if err := s.Containers().Delete(ctx, "github"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Containers().Get(ctx, "github"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	// Routes
+	if err := s.Routes().Put(ctx, Route{Slug: "github-1", InstanceID: "i1", Target: "http://10.0.0.2:8000"}); err != nil {
+		t.Fatal(err)
+	}
+	if routes, err := s.Routes().List(ctx); err != nil || len(routes) != 1 || routes[0].Target != "http://10.0.0.2:8000" {
+		t.Errorf("unexpected routes: %+v, %v", routes, err)
+	}
+
+	// Desired state generations increase with each change
+	for want := int64(1); want <= 2; want++ {
+		generation, err := s.DesiredState().Put(ctx, "i1", json.RawMessage(`{"image":"b"}`))
+		if err != nil || generation != want {
+			t.Fatalf("Put generation = %d, %v; want %d", generation, err, want)
+		}
+	}
+
+	// Rewriting keeps the generation and refuses a stale one
+	if err := s.DesiredState().Rewrite(ctx, "i1", 2, json.RawMessage(`{"image":"c"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := s.DesiredState().Get(ctx, "i1"); err != nil || state.Generation != 2 || string(state.Spec) != `{"image":"c"}` {
+		t.Errorf("unexpected desired state after rewrite: %+v, %v", state, err)
+	}
+	if err := s.DesiredState().Rewrite(ctx, "i1", 1, json.RawMessage(`{"image":"d"}`)); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict for a stale generation, got %v", err)
+	}
+	if err := s.DesiredState().Rewrite(ctx, "missing", 1, json.RawMessage(`{}`)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Audit entries are listed newest first
+	for _, action := range []string{"create", "update", "delete"} {
+		if _, err := s.Audit().Append(ctx, AuditEntry{Actor: "api", Action: action, Target: "i1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Audit().Append(ctx, AuditEntry{Actor: "api", Action: "create", Target: "i2"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.Audit().List(ctx, AuditQuery{Target: "i1", Limit: 2})
+	if err != nil || len(entries) != 2 || entries[0].Action != "delete" || entries[1].Action != "update" {
+		t.Errorf("unexpected audit entries: %+v, %v", entries, err)
+	}
+
+	// Health history ranges and pruning
+	base := time.Date(2025, 7, 29, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		sample := HealthSample{InstanceID: "i1", Time: base.Add(time.Duration(i) * time.Minute), Healthy: i != 2, Latency: 5 * time.Millisecond}
+		if err := s.Health().Record(ctx, sample); err != nil {
+			t.Fatal(err)
+		}
+	}
+	samples, err := s.Health().Range(ctx, "i1", base.Add(time.Minute), base.Add(3*time.Minute))
+	if err != nil || len(samples) != 2 || samples[1].Healthy || samples[0].Latency != 5*time.Millisecond {
+		t.Errorf("unexpected health samples: %+v, %v", samples, err)
+	}
+	if removed, err := s.Health().Prune(ctx, base.Add(2*time.Minute)); err != nil || removed != 2 {
+		t.Errorf("Prune removed %d, %v; want 2", removed, err)
+	}
+}
@@ -0,0 +1,306 @@
+// Package supervisor runs the long-lived background components of the
+// manager (HTTP servers, event subscriber, health monitor, ...) with restart
+// policies. A panic or error is restarted with backoff instead of silently
+// ending the component; a critical component that keeps failing stops the
+// whole group so the process can exit and be restarted by its orchestrator.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+// Restart policies
+const (
+	RestartOnFailure = "on-failure" // Restart when run returns an error or panics (default)
+	RestartAlways    = "always"     // Also restart when run returns nil before shutdown
+	RestartNever     = "never"      // Never restart
+)
+
+// ErrEscalated is returned by Wait when a critical component gave up
+var ErrEscalated = errors.New("critical component failed")
+
+// ErrorPublisher announces component failures; *events.EventPublisher
+// implements it
+type ErrorPublisher interface {
+	PublishComponentError(ctx context.Context, component, errorMsg, stack string, restarting bool) error
+}
+
+// PanicError is a recovered panic with the stack it was raised on
+type PanicError struct {
+	Value any
+	Stack string
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Policy decides how a component is restarted
+type Policy struct {
+	Restart     string        // One of the Restart constants
+	Critical    bool          // Stop the group when the component gives up
+	MaxRestarts int           // Restarts allowed within Window before giving up; default 5, negative for no limit
+	Window      time.Duration // Default 5m
+	Backoff     time.Duration // First restart delay, doubled up to MaxBackoff; default 1s
+	MaxBackoff  time.Duration // Default 1m
+}
+
+// withDefaults fills in unset fields
+func (p Policy) withDefaults() Policy {
+	if p.Restart == "" {
+		p.Restart = RestartOnFailure
+	}
+	if p.MaxRestarts == 0 {
+		p.MaxRestarts = 5
+	}
+	if p.Window <= 0 {
+		p.Window = 5 * time.Minute
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Minute
+	}
+	return p
+}
+
+// Supervisor runs components in an errgroup until the parent context is
+// done or a critical component gives up
+type Supervisor struct {
+	ctx    context.Context
+	group  *errgroup.Group
+	logger *slog.Logger
+
+	mu         sync.RWMutex
+	components []*component
+	err        error // Why the group was stopped, if a component escalated
+	publisher  ErrorPublisher
+}
+
+// component is the state of one supervised component
+type component struct {
+	name     string
+	policy   Policy
+	run      func(ctx context.Context) error
+	status   models.ComponentStatus
+	failures []time.Time // Within the policy window
+}
+
+// New creates a supervisor whose components stop when ctx is done
+func New(ctx context.Context, logger *slog.Logger) *Supervisor {
+	group, groupCtx := errgroup.WithContext(ctx)
+	return &Supervisor{ctx: groupCtx, group: group, logger: logger}
+}
+
+// Go starts a supervised component. run must return when its context is done.
+func (s *Supervisor) Go(name string, policy Policy, run func(ctx context.Context) error) {
+	c := &component{
+		name:   name,
+		policy: policy.withDefaults(),
+		run:    run,
+		status: models.ComponentStatus{Name: name, Critical: policy.Critical},
+	}
+	s.mu.Lock()
+	s.components = append(s.components, c)
+	s.mu.Unlock()
+
+	s.group.Go(func() error { return s.supervise(c) })
+}
+
+// SetErrorPublisher publishes an event for every component failure
+func (s *Supervisor) SetErrorPublisher(publisher ErrorPublisher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publisher = publisher
+}
+
+// ReportPanic logs and publishes a panic recovered by a worker outside the
+// supervisor, such as an event handler that carries on with its next event
+func (s *Supervisor) ReportPanic(component string, value any, stack []byte) {
+	s.report(component, &PanicError{Value: value, Stack: string(stack)}, true)
+}
+
+// report logs and publishes a component failure
+func (s *Supervisor) report(component string, err error, restarting bool) {
+	var stack string
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		stack = panicErr.Stack
+		s.logger.Error("Component panicked",
+			slog.String("component", component),
+			slog.Any("panic", panicErr.Value),
+			slog.String("stack", stack))
+	}
+
+	s.mu.RLock()
+	publisher := s.publisher
+	s.mu.RUnlock()
+	if publisher == nil {
+		return
+	}
+	// The group context may be done when a critical component escalates
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = publisher.PublishComponentError(ctx, component, err.Error(), stack, restarting)
+}
+
+// Done is closed when the parent context is done or a critical component
+// gave up
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err reports why the group was stopped by a component, or nil
+func (s *Supervisor) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+// Wait waits for all components to return. It returns an error wrapping
+// ErrEscalated if a critical component gave up.
+func (s *Supervisor) Wait() error {
+	return s.group.Wait()
+}
+
+// Statuses reports every component in the order they were started
+func (s *Supervisor) Statuses() []models.ComponentStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]models.ComponentStatus, 0, len(s.components))
+	for _, c := range s.components {
+		statuses = append(statuses, c.status)
+	}
+	return statuses
+}
+
+// supervise runs a component until it stops for good
+func (s *Supervisor) supervise(c *component) error {
+	backoff := c.policy.Backoff
+	for {
+		started := time.Now()
+		s.setState(c, models.ComponentRunning, func(status *models.ComponentStatus) {
+			status.StartedAt = started
+		})
+		err := s.runRecovered(c)
+
+		if s.ctx.Err() != nil {
+			s.setState(c, models.ComponentStopped, nil)
+			return nil
+		}
+		if err == nil && c.policy.Restart != RestartAlways {
+			s.logger.Info("Component stopped", slog.String("component", c.name))
+			s.setState(c, models.ComponentStopped, nil)
+			return nil
+		}
+		if err == nil {
+			err = errors.New("returned before shutdown")
+		}
+
+		now := time.Now()
+		if now.Sub(started) > c.policy.MaxBackoff {
+			// A long healthy run starts the backoff over
+			backoff = c.policy.Backoff
+		}
+		if s.giveUp(c, now) {
+			s.setState(c, models.ComponentFailed, func(status *models.ComponentStatus) {
+				recordError(status, err, now)
+			})
+			s.report(c.name, err, false)
+			if !c.policy.Critical {
+				s.logger.Error("Component failed, not restarting",
+					slog.String("component", c.name),
+					slog.String("error", err.Error()))
+				return nil
+			}
+			escalated := fmt.Errorf("%w: %s: %v", ErrEscalated, c.name, err)
+			s.logger.Error("Critical component failed, stopping",
+				slog.String("component", c.name),
+				slog.String("error", err.Error()))
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = escalated
+			}
+			s.mu.Unlock()
+			return escalated
+		}
+
+		s.logger.Warn("Component failed, restarting",
+			slog.String("component", c.name),
+			slog.String("error", err.Error()),
+			slog.Duration("backoff", backoff))
+		s.setState(c, models.ComponentRestarting, func(status *models.ComponentStatus) {
+			status.Restarts++
+			recordError(status, err, now)
+		})
+		s.report(c.name, err, true)
+
+		select {
+		case <-s.ctx.Done():
+			s.setState(c, models.ComponentStopped, nil)
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, c.policy.MaxBackoff)
+	}
+}
+
+// giveUp records a failure and reports whether the component should stop
+func (s *Supervisor) giveUp(c *component, now time.Time) bool {
+	if c.policy.Restart == RestartNever {
+		return true
+	}
+	if c.policy.MaxRestarts < 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now.Add(-c.policy.Window)
+	recent := c.failures[:0]
+	for _, at := range c.failures {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	c.failures = append(recent, now)
+	return len(c.failures) > c.policy.MaxRestarts
+}
+
+// setState updates the reported status of a component
+func (s *Supervisor) setState(c *component, state models.ComponentState, update func(*models.ComponentStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.status.State = state
+	if update != nil {
+		update(&c.status)
+	}
+}
+
+func recordError(status *models.ComponentStatus, err error, at time.Time) {
+	status.LastError = err.Error()
+	status.LastErrorAt = &at
+}
+
+// runRecovered runs a component, turning a panic into a *PanicError
+func (s *Supervisor) runRecovered(c *component) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: string(debug.Stack())}
+		}
+	}()
+	return c.run(s.ctx)
+}
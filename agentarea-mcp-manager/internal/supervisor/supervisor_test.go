@@ -0,0 +1,196 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+var fastPolicy = Policy{Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestRestartsFailingComponent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(ctx, slog.New(slog.DiscardHandler))
+
+	var runs atomic.Int32
+	s.Go("flaky", fastPolicy, func(ctx context.Context) error {
+		switch runs.Add(1) {
+		case 1:
+			panic("boom")
+		case 2:
+			return errors.New("failed")
+		}
+		<-ctx.Done()
+		return nil
+	})
+
+	waitFor(t, func() bool { return runs.Load() == 3 })
+	status := s.Statuses()[0]
+	if status.State != models.ComponentRunning || status.Restarts != 2 || status.LastError != "failed" {
+		t.Errorf("status = %+v, want running after 2 restarts", status)
+	}
+
+	cancel()
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait = %v after shutdown", err)
+	}
+	if state := s.Statuses()[0].State; state != models.ComponentStopped {
+		t.Errorf("state after shutdown = %s, want stopped", state)
+	}
+}
+
+func TestCriticalComponentEscalates(t *testing.T) {
+	s := New(context.Background(), slog.New(slog.DiscardHandler))
+
+	policy := fastPolicy
+	policy.Critical = true
+	policy.MaxRestarts = 2
+	var runs atomic.Int32
+	s.Go("critical", policy, func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("failed")
+	})
+	// Other components are stopped by the escalation
+	s.Go("other", fastPolicy, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("critical component did not stop the group")
+	}
+	if err := s.Wait(); !errors.Is(err, ErrEscalated) {
+		t.Errorf("Wait = %v, want ErrEscalated", err)
+	}
+	if !errors.Is(s.Err(), ErrEscalated) {
+		t.Errorf("Err = %v, want ErrEscalated", s.Err())
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("critical component ran %d times, want 3", n)
+	}
+	statuses := s.Statuses()
+	if statuses[0].State != models.ComponentFailed || statuses[1].State != models.ComponentStopped {
+		t.Errorf("states = %s %s, want failed stopped", statuses[0].State, statuses[1].State)
+	}
+}
+
+func TestNonCriticalComponentGivesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(ctx, slog.New(slog.DiscardHandler))
+
+	policy := fastPolicy
+	policy.Restart = RestartNever
+	s.Go("once", policy, func(ctx context.Context) error {
+		return errors.New("failed")
+	})
+
+	waitFor(t, func() bool { return s.Statuses()[0].State == models.ComponentFailed })
+	select {
+	case <-s.Done():
+		t.Error("a non-critical component stopped the group")
+	default:
+	}
+}
+
+func TestRestartAlways(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(ctx, slog.New(slog.DiscardHandler))
+
+	policy := fastPolicy
+	policy.Restart = RestartAlways
+	policy.MaxRestarts = -1
+	var runs atomic.Int32
+	s.Go("loop", policy, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	waitFor(t, func() bool { return runs.Load() > 10 })
+	cancel()
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait = %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type publishedError struct {
+	component, message, stack string
+	restarting                bool
+}
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []publishedError
+}
+
+func (p *recordingPublisher) PublishComponentError(ctx context.Context, component, errorMsg, stack string, restarting bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, publishedError{component, errorMsg, stack, restarting})
+	return nil
+}
+
+func (p *recordingPublisher) published() []publishedError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]publishedError(nil), p.events...)
+}
+
+func TestPublishesComponentErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(ctx, slog.New(slog.DiscardHandler))
+	publisher := &recordingPublisher{}
+	s.SetErrorPublisher(publisher)
+
+	policy := fastPolicy
+	policy.MaxRestarts = 1
+	s.Go("health-monitor", policy, func(ctx context.Context) error {
+		panic("nil map")
+	})
+	s.ReportPanic("event-handler", "bad event", []byte("goroutine 1 [running]"))
+
+	waitFor(t, func() bool { return len(publisher.published()) == 3 })
+	var restarting, gaveUp, handler int
+	for _, event := range publisher.published() {
+		switch {
+		case event.component == "event-handler":
+			handler++
+			if event.message != "panic: bad event" || event.stack == "" || !event.restarting {
+				t.Errorf("event handler panic published as %+v", event)
+			}
+		case event.restarting:
+			restarting++
+		default:
+			gaveUp++
+		}
+		if event.component == "health-monitor" && (event.message != "panic: nil map" || !strings.Contains(event.stack, "supervisor")) {
+			t.Errorf("component panic published as %+v", event)
+		}
+	}
+	if restarting != 1 || gaveUp != 1 || handler != 1 {
+		t.Errorf("published %d restarting, %d gave up, %d handler events, want 1 each", restarting, gaveUp, handler)
+	}
+}
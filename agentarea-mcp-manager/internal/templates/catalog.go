@@ -0,0 +1,324 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+	"github.com/agentarea/mcp-manager/internal/schemas"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found in a providers file. Line and column are
+// 1-based and zero when unknown.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Field    string `json:"field,omitempty"` // e.g. providers.github.env_vars[0].name
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location += fmt.Sprintf(":%d:%d", d.Line, d.Column)
+	}
+	if d.Field != "" {
+		return fmt.Sprintf("%s: %s: %s", location, d.Field, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, d.Message)
+}
+
+// Report is the outcome of loading the catalog
+type Report struct {
+	LoadedAt    time.Time    `json:"loaded_at"`
+	Files       []string     `json:"files"`
+	Templates   int          `json:"templates"`
+	Applied     bool         `json:"applied"` // False if errors kept the previous catalog
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// err summarizes the errors of a report
+func (r Report) err() error {
+	for _, d := range r.Diagnostics {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if r.Errors > 1 {
+			return fmt.Errorf("%w: %s (and %d more errors)", ErrInvalidCatalog, d, r.Errors-1)
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidCatalog, d)
+	}
+	return nil
+}
+
+// yamlLine finds the line in yaml.v3 syntax errors
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// loadCatalog reads and validates every providers file, returning the
+// templates, the file each is defined in and the report
+func loadCatalog(paths []string) (map[string]models.MCPProviderTemplate, map[string]string, Report) {
+	report := Report{LoadedAt: time.Now().UTC(), Files: []string{}, Diagnostics: []Diagnostic{}}
+	templates := make(map[string]models.MCPProviderTemplate)
+	files := make(map[string]string)
+	defined := make(map[string]Diagnostic) // Where each key was first defined
+
+	for _, file := range expandPaths(paths, &report) {
+		report.Files = append(report.Files, file)
+		fileTemplates, keys, diagnostics := loadFile(file)
+		report.Diagnostics = append(report.Diagnostics, diagnostics...)
+
+		for key, template := range fileTemplates {
+			if first, exists := defined[key]; exists {
+				d := keys[key]
+				d.Severity = SeverityError
+				d.Message = fmt.Sprintf("template %q is already defined at %s:%d", key, first.File, first.Line)
+				report.Diagnostics = append(report.Diagnostics, d)
+				continue
+			}
+			defined[key] = keys[key]
+			templates[key] = template
+			files[key] = file
+		}
+	}
+
+	for _, d := range report.Diagnostics {
+		if d.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Applied = report.Errors == 0
+	report.Templates = len(templates)
+	return templates, files, report
+}
+
+// expandPaths lists the files to load: files as given, and the *.yaml and
+// *.yml files of directories in name order, skipping hidden files such as
+// those written by save
+func expandPaths(paths []string, report *Report) []string {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{
+				Severity: SeverityError, File: path, Message: fmt.Sprintf("failed to read templates: %v", err),
+			})
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{
+				Severity: SeverityError, File: path, Message: fmt.Sprintf("failed to read templates directory: %v", err),
+			})
+			continue
+		}
+		var found []string
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if entry.IsDir() || strings.HasPrefix(name, ".") || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			found = append(found, filepath.Join(path, name))
+		}
+		sort.Strings(found)
+		if len(found) == 0 {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{
+				Severity: SeverityWarning, File: path, Message: "directory has no .yaml or .yml files",
+			})
+		}
+		files = append(files, found...)
+	}
+	return files
+}
+
+// loadFile parses and validates one providers file. It returns its templates
+// only if it has no errors, along with the location of each template key.
+func loadFile(file string) (map[string]models.MCPProviderTemplate, map[string]Diagnostic, []Diagnostic) {
+	fail := func(line, column int, field, format string, args ...interface{}) Diagnostic {
+		return Diagnostic{Severity: SeverityError, File: file, Line: line, Column: column, Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, []Diagnostic{fail(0, 0, "", "failed to read templates file: %v", err)}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		line := 0
+		if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		return nil, nil, []Diagnostic{fail(line, 0, "", "%s", strings.TrimPrefix(err.Error(), "yaml: "))}
+	}
+	if len(root.Content) == 0 {
+		// An empty file is an empty catalog
+		return nil, nil, nil
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, nil, []Diagnostic{fail(doc.Line, doc.Column, "", "must be a mapping with apiVersion and providers")}
+	}
+
+	var diagnostics []Diagnostic
+	if _, version := mappingValue(doc, "apiVersion"); version == nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning, File: file, Line: doc.Line, Column: doc.Column, Field: "apiVersion",
+			Message: fmt.Sprintf("missing, read as %s; add apiVersion: %s", APIVersion, APIVersion),
+		})
+	} else if version.Value != APIVersion {
+		// Other versions may mean anything, so the file is not read at all
+		return nil, nil, []Diagnostic{fail(version.Line, version.Column, "apiVersion",
+			"unsupported version %q, this manager reads %s", version.Value, APIVersion)}
+	}
+
+	var generic interface{}
+	if err := doc.Decode(&generic); err != nil {
+		return nil, nil, append(diagnostics, fail(doc.Line, doc.Column, "", "%v", err))
+	}
+	errs := schemas.Validate(schemas.Providers, dropNulls(generic))
+	for _, err := range errs {
+		var verr *schemas.ValidationError
+		if !errors.As(err, &verr) {
+			diagnostics = append(diagnostics, fail(0, 0, "", "%v", err))
+			continue
+		}
+		node := locate(doc, verr.Path)
+		diagnostics = append(diagnostics, fail(node.Line, node.Column, verr.Path, "%s", verr.Message))
+	}
+	if len(errs) > 0 {
+		return nil, nil, diagnostics
+	}
+
+	var list models.MCPProviderList
+	if err := doc.Decode(&list); err != nil {
+		return nil, nil, append(diagnostics, fail(doc.Line, doc.Column, "", "%v", err))
+	}
+
+	keys := make(map[string]Diagnostic, len(list.Providers))
+	if _, providers := mappingValue(doc, "providers"); providers != nil && providers.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(providers.Content); i += 2 {
+			key := providers.Content[i]
+			keys[key.Value] = Diagnostic{File: file, Line: key.Line, Column: key.Column, Field: "providers." + key.Value}
+		}
+	}
+
+	// Environment variables are looked up by name, so duplicates are ambiguous
+	hasErrors := false
+	for key, template := range list.Providers {
+		seen := make(map[string]bool, len(template.EnvVars))
+		for i, env := range template.EnvVars {
+			if seen[env.Name] {
+				field := fmt.Sprintf("providers.%s.env_vars[%d].name", key, i)
+				node := locate(doc, field)
+				diagnostics = append(diagnostics, fail(node.Line, node.Column, field, "duplicate environment variable %s", env.Name))
+				hasErrors = true
+			}
+			seen[env.Name] = true
+		}
+	}
+	if hasErrors {
+		return nil, nil, diagnostics
+	}
+	return list.Providers, keys, diagnostics
+}
+
+// dropNulls removes null mapping values, which YAML decoding treats as absent
+func dropNulls(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if item == nil {
+				delete(value, key)
+				continue
+			}
+			value[key] = dropNulls(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = dropNulls(item)
+		}
+	}
+	return v
+}
+
+// mappingValue returns the key and value nodes of a mapping entry
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// locate finds the node a validation path such as
+// providers.github.env_vars[0].name refers to: the key of a mapping entry,
+// or the deepest existing parent for missing fields. Template keys may
+// contain dots, so the longest matching key is taken at each step.
+func locate(node *yaml.Node, path string) *yaml.Node {
+	found := node
+	for path != "" {
+		switch {
+		case node.Kind == yaml.SequenceNode && strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return found
+			}
+			index, err := strconv.Atoi(path[1:end])
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return found
+			}
+			node = node.Content[index]
+			found = node
+			path = path[end+1:]
+		case node.Kind == yaml.MappingNode:
+			var key, value *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				k := node.Content[i].Value
+				if strings.HasPrefix(path, k) && (len(path) == len(k) || path[len(k)] == '.' || path[len(k)] == '[') &&
+					(key == nil || len(k) > len(key.Value)) {
+					key, value = node.Content[i], node.Content[i+1]
+				}
+			}
+			if key == nil {
+				return found
+			}
+			node = value
+			found = key
+			path = path[len(key.Value):]
+		default:
+			return found
+		}
+		path = strings.TrimPrefix(path, ".")
+	}
+	return found
+}
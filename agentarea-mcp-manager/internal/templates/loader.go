@@ -1,46 +1,114 @@
 package templates
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"sync"
 
 	"github.com/agentarea/mcp-manager/internal/models"
 	yaml "gopkg.in/yaml.v3"
 )
 
-// Loader loads MCP templates from a YAML file
+// APIVersion is the version of the providers file format. Files without an
+// apiVersion are read as this version.
+const APIVersion = "mcp-manager/v1"
+
+// ErrTemplateExists is returned by Add when the key is already in the catalog
+var ErrTemplateExists = errors.New("template already exists")
+
+// ErrInvalidCatalog is returned by Load when a providers file has errors; the
+// previously loaded catalog is kept
+var ErrInvalidCatalog = errors.New("invalid template catalog")
+
+// Update describes a template whose version changed on reload or import
+type Update struct {
+	TemplateID      string
+	PreviousVersion string
+	Version         string
+}
+
+// Loader loads MCP templates from YAML files and directories of them
 type Loader struct {
-	path      string
+	paths     []string
 	templates map[string]models.MCPProviderTemplate
+	files     map[string]string // Template key to the file defining it
+	report    Report
 	mutex     sync.RWMutex
+
+	onUpdate []func(Update)
 }
 
-// NewLoader creates a new template loader
-func NewLoader(path string) *Loader {
-	return &Loader{
-		path:      path,
-		templates: make(map[string]models.MCPProviderTemplate),
+// invalidVersionChars are replaced so versions can be stored as label values
+var invalidVersionChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// VersionOf returns the version of a template in a form usable as a label
+// value. Templates without an explicit version are versioned by a digest of
+// their image and environment variables.
+func VersionOf(template models.MCPProviderTemplate) string {
+	version := template.Version
+	if version == "" {
+		data, _ := json.Marshal(struct {
+			Image   string             `json:"image"`
+			EnvVars []models.MCPEnvVar `json:"env_vars"`
+		}{template.DockerImage, template.EnvVars})
+		sum := sha256.Sum256(data)
+		return "sha-" + hex.EncodeToString(sum[:6])
 	}
+
+	version = invalidVersionChars.ReplaceAllString(version, "_")
+	if len(version) > 63 {
+		version = version[:63]
+	}
+	return version
 }
 
-// Load loads the templates from the YAML file
-func (l *Loader) Load() error {
+// OnUpdate registers a callback run for every template whose version changes
+// when the catalog is reloaded or a template is replaced
+func (l *Loader) OnUpdate(fn func(Update)) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
+	l.onUpdate = append(l.onUpdate, fn)
+}
 
-	data, err := os.ReadFile(l.path)
-	if err != nil {
-		return fmt.Errorf("failed to read templates file: %w", err)
+// NewLoader creates a template loader for YAML files and directories, whose
+// *.yaml and *.yml files are read in name order
+func NewLoader(paths ...string) *Loader {
+	return &Loader{
+		paths:     paths,
+		templates: make(map[string]models.MCPProviderTemplate),
+		files:     make(map[string]string),
 	}
+}
+
+// Load loads the templates from every file. Files are validated against the
+// providers schema; if any has errors, the previous catalog is kept and
+// ErrInvalidCatalog is returned, with details in Diagnostics. On reload,
+// update callbacks run for templates whose version changed; the changes are
+// also returned.
+func (l *Loader) Load() ([]Update, error) {
+	l.mutex.Lock()
 
-	var providerList models.MCPProviderList
-	if err := yaml.Unmarshal(data, &providerList); err != nil {
-		return fmt.Errorf("failed to unmarshal templates: %w", err)
+	templates, files, report := loadCatalog(l.paths)
+	l.report = report
+	if report.Errors > 0 {
+		l.mutex.Unlock()
+		return nil, report.err()
 	}
 
-	l.templates = providerList.Providers
-	return nil
+	updates := changedTemplates(l.templates, templates)
+	l.templates, l.files = templates, files
+	callbacks := l.onUpdate
+	l.mutex.Unlock()
+
+	notify(callbacks, updates)
+	return updates, nil
 }
 
 // List returns a list of all templates
@@ -55,6 +123,18 @@ func (l *Loader) List() []models.MCPProviderTemplate {
 	return templates
 }
 
+// ByKey returns a copy of the catalog keyed by template key
+func (l *Loader) ByKey() map[string]models.MCPProviderTemplate {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	templates := make(map[string]models.MCPProviderTemplate, len(l.templates))
+	for key, template := range l.templates {
+		templates[key] = template
+	}
+	return templates
+}
+
 // Get returns a specific template by key (provider name)
 func (l *Loader) Get(key string) (models.MCPProviderTemplate, bool) {
 	l.mutex.RLock()
@@ -63,3 +143,130 @@ func (l *Loader) Get(key string) (models.MCPProviderTemplate, bool) {
 	template, exists := l.templates[key]
 	return template, exists
 }
+
+// Diagnostics returns the report of the last load
+func (l *Loader) Diagnostics() Report {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.report
+}
+
+// Add adds a template to the catalog and writes it to the file defining the
+// template, or for new templates to the first configured file (imported.yaml
+// if that is a directory). Existing templates are only replaced if overwrite
+// is set.
+func (l *Loader) Add(key string, template models.MCPProviderTemplate, overwrite bool) error {
+	l.mutex.Lock()
+
+	if _, exists := l.templates[key]; exists && !overwrite {
+		l.mutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrTemplateExists, key)
+	}
+
+	file, ok := l.files[key]
+	if !ok {
+		file = l.importFile()
+	}
+	updated := make(map[string]models.MCPProviderTemplate, len(l.templates)+1)
+	inFile := make(map[string]models.MCPProviderTemplate)
+	for k, v := range l.templates {
+		updated[k] = v
+		if l.files[k] == file {
+			inFile[k] = v
+		}
+	}
+	updated[key] = template
+	inFile[key] = template
+
+	if err := save(file, inFile); err != nil {
+		l.mutex.Unlock()
+		return err
+	}
+	updates := changedTemplates(l.templates, updated)
+	l.templates = updated
+	l.files[key] = file
+	callbacks := l.onUpdate
+	l.mutex.Unlock()
+
+	// Callbacks may read the catalog, so they run without the lock
+	notify(callbacks, updates)
+	return nil
+}
+
+// Version returns the current version of a template
+func (l *Loader) Version(key string) (string, bool) {
+	template, exists := l.Get(key)
+	if !exists {
+		return "", false
+	}
+	return VersionOf(template), true
+}
+
+// changedTemplates lists templates present in both catalogs whose version differs
+func changedTemplates(previous, current map[string]models.MCPProviderTemplate) []Update {
+	var updates []Update
+	for key, template := range current {
+		old, exists := previous[key]
+		if !exists {
+			continue
+		}
+		if oldVersion, newVersion := VersionOf(old), VersionOf(template); oldVersion != newVersion {
+			updates = append(updates, Update{TemplateID: key, PreviousVersion: oldVersion, Version: newVersion})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].TemplateID < updates[j].TemplateID })
+	return updates
+}
+
+// notify runs the update callbacks for each change
+func notify(callbacks []func(Update), updates []Update) {
+	for _, update := range updates {
+		for _, fn := range callbacks {
+			fn(update)
+		}
+	}
+}
+
+// importFile is where templates not yet in any file are written
+func (l *Loader) importFile() string {
+	if len(l.paths) == 0 {
+		return ""
+	}
+	if info, err := os.Stat(l.paths[0]); err == nil && info.IsDir() {
+		return filepath.Join(l.paths[0], "imported.yaml")
+	}
+	return l.paths[0]
+}
+
+// save atomically replaces a YAML file with the given templates
+func save(path string, templates map[string]models.MCPProviderTemplate) error {
+	if path == "" {
+		return errors.New("no templates file configured")
+	}
+	data, err := yaml.Marshal(models.MCPProviderList{APIVersion: APIVersion, Providers: templates})
+	if err != nil {
+		return fmt.Errorf("failed to marshal templates: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".templates-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to write templates file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write templates file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write templates file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write templates file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace templates file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentarea/mcp-manager/internal/models"
+)
+
+func TestLoaderReportsVersionChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp_providers.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`providers:
+  weather:
+    name: Weather
+    docker_image: acme/weather
+    version: "1.0.0"
+  memory:
+    name: Memory
+    docker_image: mcp/memory
+`)
+
+	loader := NewLoader(path)
+	var notified []Update
+	loader.OnUpdate(func(u Update) { notified = append(notified, u) })
+
+	if updates, err := loader.Load(); err != nil || len(updates) != 0 {
+		t.Fatalf("initial load: updates=%v err=%v", updates, err)
+	}
+	memoryVersion, _ := loader.Version("memory")
+
+	// Explicit versions are compared as is, unversioned templates by content
+	write(`providers:
+  weather:
+    name: Weather
+    docker_image: acme/weather
+    version: "1.1.0+build.7"
+  memory:
+    name: Memory
+    docker_image: mcp/memory:2
+`)
+	updates, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 || len(notified) != 2 {
+		t.Fatalf("expected 2 updates, got %v (notified %v)", updates, notified)
+	}
+	if updates[0].TemplateID != "memory" || updates[0].PreviousVersion != memoryVersion {
+		t.Errorf("unexpected memory update: %+v", updates[0])
+	}
+	if updates[1] != (Update{TemplateID: "weather", PreviousVersion: "1.0.0", Version: "1.1.0_build.7"}) {
+		t.Errorf("unexpected weather update: %+v", updates[1])
+	}
+
+	// Imports persist and notify like reloads
+	if err := loader.Add("weather", models.MCPProviderTemplate{Name: "Weather", DockerImage: "acme/weather", Version: "2.0.0"}, false); err == nil {
+		t.Fatal("expected existing template to be kept without overwrite")
+	}
+	if err := loader.Add("weather", models.MCPProviderTemplate{Name: "Weather", DockerImage: "acme/weather", Version: "2.0.0"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 3 || notified[2].Version != "2.0.0" {
+		t.Fatalf("expected an update for the import, got %v", notified)
+	}
+
+	reloaded := NewLoader(path)
+	if _, err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := reloaded.Version("weather"); version != "2.0.0" {
+		t.Errorf("expected import to be persisted, got version %q", version)
+	}
+}
+
+func TestLoaderValidatesFilesAndDirectories(t *testing.T) {
+	dir := t.TempDir()
+	catalog := filepath.Join(dir, "catalog")
+	if err := os.Mkdir(catalog, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(dir, "legacy.yaml")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(legacy, `providers:
+  memory:
+    name: Memory
+    docker_image: mcp/memory
+`)
+	write(filepath.Join(catalog, "b.yml"), `apiVersion: mcp-manager/v1
+providers:
+  github.com:
+    name: GitHub
+    docker_image: mcp/github
+    env_vars:
+      - name: GITHUB_TOKEN
+        secret: true
+`)
+	write(filepath.Join(catalog, "notes.txt"), "not a catalog")
+
+	loader := NewLoader(legacy, catalog)
+	if _, err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	report := loader.Diagnostics()
+	if report.Templates != 2 || !report.Applied || len(report.Files) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.Warnings != 1 || report.Diagnostics[0].File != legacy || report.Diagnostics[0].Field != "apiVersion" {
+		t.Errorf("expected a missing apiVersion warning, got %+v", report.Diagnostics)
+	}
+
+	// Errors point at the line and field, and keep the previous catalog
+	write(filepath.Join(catalog, "a.yaml"), `apiVersion: mcp-manager/v1
+providers:
+  memory:
+    name: Memory again
+    docker_image: mcp/memory
+`)
+	write(filepath.Join(catalog, "b.yml"), `apiVersion: mcp-manager/v1
+providers:
+  github.com:
+    name: GitHub
+    docker_image: mcp/github
+    env_vars:
+      - name: GITHUB_TOKEN
+        secrte: true
+`)
+	if _, err := loader.Load(); !errors.Is(err, ErrInvalidCatalog) {
+		t.Fatalf("err = %v, want ErrInvalidCatalog", err)
+	}
+	if _, ok := loader.Get("github.com"); !ok {
+		t.Error("previous catalog was not kept")
+	}
+	report = loader.Diagnostics()
+	if report.Applied || report.Errors != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	var got []Diagnostic
+	for _, d := range report.Diagnostics {
+		if d.Severity == SeverityError {
+			got = append(got, d)
+		}
+	}
+	if d := got[0]; d.File != filepath.Join(catalog, "a.yaml") || d.Line != 3 || !strings.Contains(d.Message, legacy+":2") {
+		t.Errorf("duplicate = %+v", d)
+	}
+	if d := got[1]; d.Line != 8 || d.Column != 9 || d.Field != "providers.github.com.env_vars[0].secrte" ||
+		!strings.Contains(d.Message, "did you mean secret?") {
+		t.Errorf("unknown field = %+v", d)
+	}
+
+	write(legacy, "apiVersion: mcp-manager/v2\nproviders: {}\n")
+	if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "legacy.yaml:1:13: apiVersion: unsupported version") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Package variables manages workspace-level variable sets that instance
+// environments reference as ${workspace.NAME}.
+package variables
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/agentarea/mcp-manager/internal/redisconn"
+)
+
+// keyPrefix namespaces the Redis hash holding each workspace's variables
+const keyPrefix = "mcp-manager:workspace-vars:"
+
+var (
+	// namePattern is the allowed syntax for variable names
+	namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// referencePattern matches ${workspace.NAME} in environment values
+	referencePattern = regexp.MustCompile(`\$\{workspace\.([^}]*)\}`)
+)
+
+// Store persists workspace variable sets in Redis
+type Store struct {
+	redisClient redis.UniversalClient
+}
+
+// NewStore creates a variable store with its own Redis client
+func NewStore(redisOptions *redisconn.Options) *Store {
+	return &Store{redisClient: redisOptions.NewClient()}
+}
+
+// ValidateName checks a variable name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q: use letters, digits and underscores, not starting with a digit", name)
+	}
+	return nil
+}
+
+// List returns all variables of a workspace
+func (s *Store) List(ctx context.Context, workspaceID string) (map[string]string, error) {
+	vars, err := s.redisClient.HGetAll(ctx, keyPrefix+workspaceID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load variables for workspace %s: %w", workspaceID, err)
+	}
+	return vars, nil
+}
+
+// Set creates or updates a variable
+func (s *Store) Set(ctx context.Context, workspaceID, name, value string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if err := s.redisClient.HSet(ctx, keyPrefix+workspaceID, name, value).Err(); err != nil {
+		return fmt.Errorf("failed to set variable %s: %w", name, err)
+	}
+	return nil
+}
+
+// Replace atomically replaces the whole variable set of a workspace
+func (s *Store) Replace(ctx context.Context, workspaceID string, vars map[string]string) error {
+	for name := range vars {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+	}
+
+	key := keyPrefix + workspaceID
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(vars) > 0 {
+		pipe.HSet(ctx, key, vars)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to replace variables for workspace %s: %w", workspaceID, err)
+	}
+	return nil
+}
+
+// Delete removes a variable and reports whether it existed
+func (s *Store) Delete(ctx context.Context, workspaceID, name string) (bool, error) {
+	n, err := s.redisClient.HDel(ctx, keyPrefix+workspaceID, name).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete variable %s: %w", name, err)
+	}
+	return n > 0, nil
+}
+
+// Resolve expands ${workspace.NAME} references in env with the workspace's
+// variables. Environments without references are returned unchanged without
+// touching Redis.
+func (s *Store) Resolve(ctx context.Context, workspaceID string, env map[string]string) (map[string]string, error) {
+	if !HasReferences(env) {
+		return env, nil
+	}
+	if workspaceID == "" {
+		return nil, fmt.Errorf("environment references workspace variables but the instance has no workspace_id")
+	}
+
+	vars, err := s.List(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return Expand(env, vars)
+}
+
+// HasReferences reports whether any value references a workspace variable
+func HasReferences(env map[string]string) bool {
+	for _, value := range env {
+		if referencePattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand replaces ${workspace.NAME} references in env values. It returns an
+// error naming every undefined variable rather than provisioning with blanks.
+func Expand(env map[string]string, vars map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(env))
+	missing := make(map[string]bool)
+
+	for key, value := range env {
+		result[key] = referencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+			name := referencePattern.FindStringSubmatch(ref)[1]
+			resolved, ok := vars[name]
+			if !ok {
+				missing[name] = true
+				return ref
+			}
+			return resolved
+		})
+	}
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined workspace variable(s): %s", strings.Join(names, ", "))
+	}
+	return result, nil
+}
+
+// Close closes the Redis connection
+func (s *Store) Close() error {
+	return s.redisClient.Close()
+}
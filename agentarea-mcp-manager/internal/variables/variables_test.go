@@ -0,0 +1,48 @@
+package variables
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"API_BASE": "https://api.example.com", "REGION": "eu"}
+
+	env := map[string]string{
+		"SERVICE_URL": "${workspace.API_BASE}/v1?region=${workspace.REGION}",
+		"PLAIN":       "unchanged",
+		"SHELL_VAR":   "${HOME}",
+	}
+	got, err := Expand(env, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["SERVICE_URL"] != "https://api.example.com/v1?region=eu" {
+		t.Errorf("SERVICE_URL = %q", got["SERVICE_URL"])
+	}
+	if got["PLAIN"] != "unchanged" || got["SHELL_VAR"] != "${HOME}" {
+		t.Errorf("non-workspace values changed: %v", got)
+	}
+
+	_, err = Expand(map[string]string{"A": "${workspace.MISSING}", "B": "${workspace.ALSO_MISSING}"}, vars)
+	if err == nil || !strings.Contains(err.Error(), "ALSO_MISSING, MISSING") {
+		t.Fatalf("expected undefined variables to be reported, got %v", err)
+	}
+
+	if HasReferences(map[string]string{"A": "plain"}) {
+		t.Error("plain environment reported as templated")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"API_BASE", "_x", "a1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "1A", "A-B", "a.b"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) should fail", name)
+		}
+	}
+}
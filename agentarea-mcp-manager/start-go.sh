@@ -17,8 +17,7 @@ podman system info > /dev/null 2>&1 || {
     rm -rf /var/lib/containers/storage/overlay-* 2>/dev/null || true
 }
 
-# Create Podman network if it doesn't exist
-podman network exists podman 2>/dev/null || podman network create podman 2>/dev/null || true
+# The MCP Manager creates its container network (TRAEFIK_NETWORK) at startup
 
 echo "Podman initialization complete"
 podman info --format "Storage Driver: {{.Store.GraphDriverName}}" || echo "Warning: Could not get podman info"